@@ -0,0 +1,45 @@
+// Command encrypt-fields encrypts sensitive fields stored before FIELD_ENCRYPTION_KEY
+// was set. It is safe to run more than once; values already encrypted are skipped.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/mark-regan/wellf/internal/config"
+	"github.com/mark-regan/wellf/internal/database"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/pkg/fieldcrypt"
+)
+
+func main() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+	if cfg.Crypto.FieldKey == "" {
+		logger.Error("FIELD_ENCRYPTION_KEY is not set")
+		os.Exit(1)
+	}
+
+	db, err := database.New(cfg.Database.URL)
+	if err != nil {
+		logger.Error("failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	portfolioRepo := repository.NewPortfolioRepository(db.Pool, fieldcrypt.New(cfg.Crypto.FieldKey))
+
+	updated, err := portfolioRepo.EncryptExisting(context.Background())
+	if err != nil {
+		logger.Error("failed to encrypt portfolio account references", "updated", updated, "error", err)
+		os.Exit(1)
+	}
+
+	logger.Info("encrypted portfolio account references", "updated", updated)
+}
@@ -18,10 +18,13 @@ import (
 	"github.com/mark-regan/wellf/internal/config"
 	"github.com/mark-regan/wellf/internal/database"
 	"github.com/mark-regan/wellf/internal/handlers"
+	"github.com/mark-regan/wellf/internal/marketdata"
 	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/openbanking"
 	"github.com/mark-regan/wellf/internal/repository"
 	"github.com/mark-regan/wellf/internal/services"
 	"github.com/mark-regan/wellf/internal/yahoo"
+	"github.com/mark-regan/wellf/pkg/fieldcrypt"
 	"github.com/mark-regan/wellf/pkg/jwt"
 	"github.com/mark-regan/wellf/pkg/validator"
 )
@@ -66,7 +69,7 @@ func main() {
 	logger.Info("migrations completed successfully")
 
 	// Connect to Redis
-	redis, err := database.NewRedis(cfg.Redis.URL)
+	redis, err := database.NewRedis(cfg.Redis.URL, cfg.Redis.CacheVersion)
 	if err != nil {
 		logger.Error("failed to connect to redis", "error", err)
 		os.Exit(1)
@@ -83,44 +86,202 @@ func main() {
 	tokenBlacklist := services.NewTokenBlacklist(redis.Client)
 
 	// Initialize rate limiters (issue 3)
-	apiRateLimiter := middleware.NewRateLimiter(redis.Client, 100, time.Minute, "api")       // 100 requests per minute
-	loginRateLimiter := middleware.NewRateLimiter(redis.Client, 5, time.Minute, "login")    // 5 login attempts per minute
-	registerRateLimiter := middleware.NewRateLimiter(redis.Client, 3, time.Minute, "register") // 3 registrations per minute
+	// Limits are per minute and can be changed at runtime via /admin/settings
+	apiRateLimiter := middleware.NewRateLimiter(redis.Client, cfg.Runtime.APIRateLimit, time.Minute, "api")
+	loginRateLimiter := middleware.NewRateLimiter(redis.Client, cfg.Runtime.LoginRateLimit, time.Minute, "login")
+	registerRateLimiter := middleware.NewRateLimiter(redis.Client, cfg.Runtime.RegisterRateLimit, time.Minute, "register")
 
 	// Initialize repositories
+	fieldCipher := fieldcrypt.New(cfg.Crypto.FieldKey)
 	userRepo := repository.NewUserRepository(db.Pool)
-	portfolioRepo := repository.NewPortfolioRepository(db.Pool)
+	portfolioRepo := repository.NewPortfolioRepository(db.Pool, fieldCipher)
 	assetRepo := repository.NewAssetRepository(db.Pool)
 	holdingRepo := repository.NewHoldingRepository(db.Pool)
 	txRepo := repository.NewTransactionRepository(db.Pool)
+	lotRepo := repository.NewHoldingLotRepository(db.Pool)
 	cashRepo := repository.NewCashAccountRepository(db.Pool)
+	cashMovementRepo := repository.NewCashMovementRepository(db.Pool)
+	bankConnectionRepo := repository.NewBankConnectionRepository(db.Pool)
 	fixedAssetRepo := repository.NewFixedAssetRepository(db.Pool)
-
-	// Initialize Yahoo client and service
+	liabilityRepo := repository.NewLiabilityRepository(db.Pool)
+	snapshotRepo := repository.NewSnapshotRepository(db.Pool)
+	exchangeRateRepo := repository.NewExchangeRateRepository(db.Pool)
+	allocationTargetRepo := repository.NewAllocationTargetRepository(db.Pool)
+	templateRepo := repository.NewPortfolioTemplateRepository(db.Pool)
+	announcementRepo := repository.NewAnnouncementRepository(db.Pool)
+	currencyChangeRepo := repository.NewCurrencyChangeRepository(db.Pool)
+	settingsRepo := repository.NewSettingsRepository(db.Pool)
+	checkpointRepo := repository.NewJobCheckpointRepository(db.Pool)
+	goalRepo := repository.NewSavingsGoalRepository(db.Pool)
+	onboardingRepo := repository.NewOnboardingRepository(db.Pool)
+	reminderRepo := repository.NewReminderRepository(db.Pool)
+	webhookRepo := repository.NewWebhookRepository(db.Pool)
+	usageRepo := repository.NewUsageRepository(db.Pool)
+	viewRepo := repository.NewSavedViewRepository(db.Pool)
+	presetRepo := repository.NewImportPresetRepository(db.Pool)
+	actionRepo := repository.NewCorporateActionRepository(db.Pool)
+	noteRepo := repository.NewAssetNoteRepository(db.Pool)
+	syncRepo := repository.NewSyncRepository(db.Pool)
+	historyRepo := repository.NewChangeHistoryRepository(db.Pool, fieldCipher)
+	priceAlertRepo := repository.NewPriceAlertRepository(db.Pool)
+	standingOrderRepo := repository.NewStandingOrderRepository(db.Pool)
+	watchlistRepo := repository.NewWatchlistRepository(db.Pool)
+	tagRepo := repository.NewTagRepository(db.Pool)
+	apiTokenRepo := repository.NewAPITokenRepository(db.Pool)
+	incomeRepo := repository.NewIncomeRepository(db.Pool)
+	priceRouteRepo := repository.NewPriceSourceRouteRepository(db.Pool)
+
+	// Initialize Yahoo client, the market data providers in failover order, and the service
 	yahooClient := yahoo.NewClient()
-	yahooService := services.NewYahooService(yahooClient, assetRepo, redis, cfg.Yahoo.CacheTTL, logger)
+	priceProviders := []marketdata.PriceProvider{marketdata.NewYahooProvider(yahooClient)}
+	if cfg.Market.AlphaVantageKey != "" {
+		priceProviders = append(priceProviders, marketdata.NewAlphaVantageProvider(cfg.Market.AlphaVantageKey))
+	}
+	if cfg.Market.FinnhubKey != "" {
+		priceProviders = append(priceProviders, marketdata.NewFinnhubProvider(cfg.Market.FinnhubKey))
+	}
+	priceProviders = append(priceProviders, marketdata.NewCoinGeckoProvider(cfg.Market.CoinGeckoKey))
+	marketData := marketdata.NewRegistry(logger, priceProviders...)
+	yahooService := services.NewYahooService(yahooClient, marketData, assetRepo, redis, cfg.Yahoo.CacheTTL, logger)
+	priceRouteService := services.NewPriceRouteService(priceRouteRepo, yahooService, logger)
+	if err := priceRouteService.Reload(context.Background()); err != nil {
+		logger.Error("failed to load price source routes", "error", err)
+	}
 
 	// Initialize services
 	authService := services.NewAuthService(userRepo, portfolioRepo, jwtManager, v, tokenBlacklist)
+	jobManager := services.NewJobManager(logger)
+	onboardingService := services.NewOnboardingService(onboardingRepo, logger)
+	allowanceService := services.NewAllowanceService(portfolioRepo, txRepo, logger)
+	lotService := services.NewLotService(lotRepo, holdingRepo, txRepo, portfolioRepo, logger)
+	webhookService := services.NewWebhookService(webhookRepo, jobManager, logger)
+	reminderService := services.NewReminderService(reminderRepo, webhookService, jobManager, logger)
+	usageService := services.NewUsageService(redis.Client, usageRepo, logger)
+	taskService := services.NewTaskService(redis.Client, jobManager, logger)
+	fxService := services.NewCurrencyService(exchangeRateRepo, yahooService, logger)
+	currencyService := services.NewBaseCurrencyService(userRepo, snapshotRepo, currencyChangeRepo, yahooService)
+	performanceService := services.NewPerformanceService(holdingRepo, txRepo, yahooService, fxService)
+	marketCalendar := services.NewMarketCalendar()
+	netWorthService := services.NewNetWorthService(userRepo, portfolioRepo, holdingRepo, cashRepo, fixedAssetRepo, liabilityRepo, snapshotRepo, checkpointRepo, fxService, jobManager, logger)
+	catchUpService := services.NewCatchUpService(userRepo, holdingRepo, snapshotRepo, reminderRepo, txRepo, netWorthService, yahooService)
+	dashboardCache := services.NewDashboardCache(redis, cfg.Redis.DashboardCacheTTL)
+	priceRefresher := services.NewPriceRefresher(assetRepo, checkpointRepo, yahooService, marketCalendar, dashboardCache, jobManager, logger)
+	fireService := services.NewFireService(userRepo, portfolioRepo, txRepo, netWorthService, fxService)
+	healthService := services.NewHealthService(portfolioRepo, txRepo, incomeRepo, fixedAssetRepo, assetRepo, reminderRepo, netWorthService, fireService, allowanceService, fxService)
+	rebalanceService := services.NewRebalanceService(allocationTargetRepo, holdingRepo, userRepo, fxService)
+	portfolioDashboardService := services.NewPortfolioDashboardService(netWorthService, allowanceService, rebalanceService, txRepo, userRepo, fxService)
+	fxRateFetcher := services.NewFXRateFetcher(exchangeRateRepo, checkpointRepo, jobManager, logger)
+	fundPriceFetcher := services.NewFundPriceFetcher(assetRepo, checkpointRepo, yahooService, dashboardCache, jobManager, logger)
+	priceAlertService := services.NewPriceAlertService(priceAlertRepo, userRepo, yahooService, marketCalendar, webhookService, jobManager, logger)
+	standingOrderService := services.NewStandingOrderService(standingOrderRepo, txRepo, portfolioRepo, yahooService, fxService, marketCalendar, lotService, allowanceService, webhookService, dashboardCache, jobManager, logger)
+	cacheService := services.NewCacheService(redis, assetRepo, yahooService, logger)
+
+	// Open Banking is available when GoCardless credentials are configured
+	var bankClient *openbanking.Client
+	if cfg.Banking.SecretID != "" {
+		bankClient = openbanking.NewClient(cfg.Banking.SecretID, cfg.Banking.SecretKey)
+	}
+	bankSyncService := services.NewBankSyncService(bankConnectionRepo, portfolioRepo, cashRepo, cashMovementRepo, dashboardCache, bankClient, cfg.Banking.RedirectURL, cfg.Banking.SyncInterval, jobManager, logger)
+
+	// Runtime settings: env config provides defaults, DB overrides are applied on top
+	settingsService := services.NewSettingsService(settingsRepo, cfg.Runtime, logger)
+	if err := settingsService.Reload(context.Background()); err != nil {
+		logger.Error("failed to load runtime settings, using defaults", "error", err)
+	}
+	settingsService.OnChange(func(s config.RuntimeSettings) {
+		apiRateLimiter.SetLimit(s.APIRateLimit)
+		loginRateLimiter.SetLimit(s.LoginRateLimit)
+		registerRateLimiter.SetLimit(s.RegisterRateLimit)
+		yahooService.SetCacheTTLs(time.Duration(s.YahooCacheTTL), time.Duration(s.SearchCacheTTL))
+		priceRefresher.SetInterval(time.Duration(s.PriceRefreshInterval))
+	})
+
+	// Demo mode: make sure the shared demo user exists
+	if cfg.Demo.Enabled {
+		if _, err := authService.EnsureDemoUser(context.Background(), cfg.Demo.UserEmail); err != nil {
+			logger.Error("failed to create demo user", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("demo mode enabled, changes will not be persisted", "demo_user", cfg.Demo.UserEmail)
+	}
+
+	// Background workers
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	defer bgCancel()
+	go settingsService.Watch(bgCtx, 30*time.Second)
+	go priceRouteService.Watch(bgCtx, 30*time.Second)
+	go priceRefresher.Run(bgCtx)
+	go netWorthService.Run(bgCtx)
+	go webhookService.Run(bgCtx)
+	go fxRateFetcher.Run(bgCtx)
+	go fundPriceFetcher.Run(bgCtx)
+	go priceAlertService.Run(bgCtx)
+	go reminderService.Run(bgCtx)
+	go standingOrderService.Run(bgCtx)
+	go bankSyncService.Run(bgCtx)
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(authService)
-	portfolioHandler := handlers.NewPortfolioHandler(portfolioRepo, holdingRepo, txRepo)
-	holdingHandler := handlers.NewHoldingHandler(holdingRepo, portfolioRepo, yahooService)
-	txHandler := handlers.NewTransactionHandler(txRepo, holdingRepo, portfolioRepo, yahooService)
-	assetHandler := handlers.NewAssetHandler(assetRepo, yahooService)
-	cashHandler := handlers.NewCashAccountHandler(cashRepo, portfolioRepo)
-	fixedAssetHandler := handlers.NewFixedAssetHandler(fixedAssetRepo)
-	dashboardHandler := handlers.NewDashboardHandler(portfolioRepo, holdingRepo, txRepo, cashRepo, fixedAssetRepo, userRepo, yahooService)
+	authHandler := handlers.NewAuthHandler(authService, onboardingService, currencyService, watchlistRepo)
+	portfolioHandler := handlers.NewPortfolioHandler(portfolioRepo, holdingRepo, txRepo, lotService, netWorthService, allowanceService, allocationTargetRepo)
+	holdingHandler := handlers.NewHoldingHandler(holdingRepo, portfolioRepo, yahooService, lotService)
+	txHandler := handlers.NewTransactionHandler(txRepo, holdingRepo, portfolioRepo, yahooService, reminderService, lotService, allowanceService, presetRepo, taskService, webhookService, fxService, dashboardCache)
+	assetHandler := handlers.NewAssetHandler(assetRepo, yahooService, taskService, noteRepo)
+	cashHandler := handlers.NewCashAccountHandler(cashRepo, cashMovementRepo, portfolioRepo)
+	bankConnectionHandler := handlers.NewBankConnectionHandler(bankConnectionRepo, portfolioRepo, bankSyncService)
+	fixedAssetHandler := handlers.NewFixedAssetHandler(fixedAssetRepo, reminderService)
+	liabilityHandler := handlers.NewLiabilityHandler(liabilityRepo)
+	standingOrderHandler := handlers.NewStandingOrderHandler(standingOrderRepo, portfolioRepo, yahooService, standingOrderService)
+	dashboardHandler := handlers.NewDashboardHandler(portfolioRepo, holdingRepo, txRepo, cashRepo, cashMovementRepo, fixedAssetRepo, snapshotRepo, netWorthService, yahooService, marketCalendar, catchUpService, tagRepo, dashboardCache)
 	healthHandler := handlers.NewHealthHandler(db, redis)
+	statusHandler := handlers.NewStatusHandler(db, redis, jobManager, yahooClient)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(exchangeRateRepo)
+	fireHandler := handlers.NewFireHandler(fireService)
+	portfolioDashboardHandler := handlers.NewPortfolioDashboardHandler(portfolioRepo, portfolioDashboardService)
+	insightHandler := handlers.NewInsightHandler(healthService)
+	rebalanceHandler := handlers.NewRebalanceHandler(portfolioRepo, assetRepo, allocationTargetRepo, rebalanceService)
+	templateHandler := handlers.NewPortfolioTemplateHandler(templateRepo, portfolioRepo, assetRepo, allocationTargetRepo, rebalanceService)
 	adminHandler := handlers.NewAdminHandler(userRepo)
+	announcementHandler := handlers.NewAnnouncementHandler(announcementRepo)
+	settingsHandler := handlers.NewSettingsHandler(settingsService)
+	reportHandler := handlers.NewReportHandler(txRepo, holdingRepo, userRepo, portfolioRepo, cashRepo, cashMovementRepo, fixedAssetRepo, incomeRepo, fxService)
+	goalHandler := handlers.NewSavingsGoalHandler(goalRepo, cashRepo, portfolioRepo, reminderService)
+	childrenHandler := handlers.NewChildrenHandler(portfolioRepo, txRepo)
+	bedAndISAHandler := handlers.NewBedAndISAHandler(holdingRepo, portfolioRepo, txRepo, lotService, allowanceService, fxService)
+	contributionHandler := handlers.NewContributionHandler(portfolioRepo, txRepo)
+	onboardingHandler := handlers.NewOnboardingHandler(onboardingService)
+	demoHandler := handlers.NewDemoHandler(authService, cfg.Demo.UserEmail)
+	reminderHandler := handlers.NewReminderHandler(reminderRepo, webhookService)
+	webhookHandler := handlers.NewWebhookHandler(webhookRepo, webhookService)
+	usageHandler := handlers.NewUsageHandler(usageService)
+	noteHandler := handlers.NewAssetNoteHandler(noteRepo)
+	actionHandler := handlers.NewCorporateActionHandler(assetRepo, actionRepo, lotService, dashboardCache)
+	performanceHandler := handlers.NewPerformanceHandler(portfolioRepo, userRepo, performanceService)
+	viewHandler := handlers.NewSavedViewHandler(viewRepo, holdingRepo, cashRepo, fixedAssetRepo, portfolioRepo, txRepo)
+	presetHandler := handlers.NewImportPresetHandler(presetRepo)
+	syncHandler := handlers.NewSyncHandler(syncRepo)
+	historyHandler := handlers.NewChangeHistoryHandler(historyRepo)
+	taskHandler := handlers.NewTaskHandler(taskService)
+	priceAlertHandler := handlers.NewPriceAlertHandler(priceAlertRepo, yahooService)
+	watchlistHandler := handlers.NewWatchlistHandler(watchlistRepo, yahooService)
+	tagHandler := handlers.NewTagHandler(tagRepo, txRepo, holdingRepo)
+	apiTokenHandler := handlers.NewAPITokenHandler(apiTokenRepo)
+	marketDataHandler := handlers.NewMarketDataHandler(assetRepo, marketData, priceRouteService)
+	incomeHandler := handlers.NewIncomeHandler(incomeRepo)
+	cacheHandler := handlers.NewCacheHandler(cacheService, taskService)
 
 	// Setup router
 	r := chi.NewRouter()
 
+	batchHandler := handlers.NewBatchHandler(r)
+	hubHandler := handlers.NewHubHandler(batchHandler, reminderRepo, webhookRepo, priceAlertRepo, holdingRepo)
+
 	// Global middleware
 	r.Use(chimiddleware.RequestID)
-	r.Use(middleware.Logger(logger))
+	r.Use(middleware.Logger(logger, middleware.LoggerConfig{
+		SampleRate:    cfg.Logging.SampleRate,
+		SampledRoutes: cfg.Logging.SampledRoutes,
+		SlowThreshold: cfg.Logging.SlowRequestThreshold,
+	}))
 	r.Use(middleware.Recoverer(logger))
 	r.Use(middleware.JSON)
 	// CORS origins - defaults plus any from CORS_ORIGINS env var
@@ -144,40 +305,87 @@ func main() {
 		// Apply general API rate limiting (issue 3)
 		r.Use(apiRateLimiter.Limit)
 
+		// In demo mode only sign-in and batch requests reach the handlers; other writes are
+		// simulated (batch sub-requests pass through this check individually)
+		if cfg.Demo.Enabled {
+			r.Use(middleware.DemoMode("/api/v1/auth/login", "/api/v1/auth/refresh", "/api/v1/auth/logout", "/api/v1/auth/demo", "/api/v1/batch"))
+		}
+
 		// Public routes
 		r.Get("/health", healthHandler.Health)
 		r.Get("/health/ready", healthHandler.Ready)
+		r.Get("/status", statusHandler.Status)
 		r.Get("/config/currencies", healthHandler.Currencies)
 		r.Get("/config/asset-types", healthHandler.AssetTypes)
 		r.Get("/config/portfolio-types", healthHandler.PortfolioTypes)
 		r.Get("/config/transaction-types", healthHandler.TransactionTypes)
+		r.Get("/config/exchange-rates", exchangeRateHandler.List)
+		r.Get("/config/import-formats", txHandler.ImportFormats)
 
 		// Auth routes (public) with stricter rate limiting
 		r.Route("/auth", func(r chi.Router) {
-			r.With(registerRateLimiter.Limit).Post("/register", authHandler.Register)
+			r.With(middleware.RequireFeature(settingsService, config.FeatureRegistration), registerRateLimiter.Limit).Post("/register", authHandler.Register)
 			r.With(loginRateLimiter.Limit).Post("/login", authHandler.Login)
 			r.Post("/refresh", authHandler.Refresh)
+			if cfg.Demo.Enabled {
+				r.With(loginRateLimiter.Limit).Post("/demo", demoHandler.Login)
+			}
 		})
 
 		// Protected routes with token blacklist checking (issues 2 & 6)
 		r.Group(func(r chi.Router) {
-			r.Use(middleware.AuthWithBlacklist(jwtManager, tokenBlacklist))
+			r.Use(middleware.AuthWithAPITokens(jwtManager, tokenBlacklist, middleware.NewAPITokenAuth(apiTokenRepo, redis.Client, logger)))
+			r.Use(middleware.TrackUsage(usageService))
+			r.Use(middleware.InvalidateOnWrite(dashboardCache))
 
 			// Auth
 			r.Get("/auth/me", authHandler.Me)
 			r.Put("/auth/me", authHandler.UpdateMe)
+			r.Post("/auth/me/base-currency", authHandler.ChangeBaseCurrency)
+			r.Get("/auth/me/base-currency/history", authHandler.BaseCurrencyHistory)
 			r.Put("/auth/password", authHandler.ChangePassword)
 			r.Post("/auth/logout", authHandler.Logout)
 
+			// Batch (sub-requests are dispatched through this router and checked individually)
+			r.Post("/batch", batchHandler.Execute)
+
+			// Background task progress
+			r.Get("/tasks/{id}", taskHandler.Get)
+			r.Get("/tasks/{id}/events", taskHandler.Events)
+
+			// Change feed for incremental sync
+			r.Get("/changes", syncHandler.Changes)
+
+			// Usage statistics
+			r.Get("/usage", usageHandler.Get)
+
+			// Hub quick actions (each runs an ordinary API request)
+			r.Get("/hub/quick-actions", hubHandler.QuickActions)
+			r.Post("/hub/quick-actions/{id}/execute", hubHandler.Execute)
+
+			// Onboarding
+			r.Get("/onboarding", onboardingHandler.Get)
+
 			// Portfolios
 			r.Get("/portfolios", portfolioHandler.List)
 			r.Post("/portfolios", portfolioHandler.Create)
 			r.Get("/portfolios/{id}", portfolioHandler.Get)
 			r.Put("/portfolios/{id}", portfolioHandler.Update)
 			r.Delete("/portfolios/{id}", portfolioHandler.Delete)
+			r.Post("/portfolios/{id}/duplicate", portfolioHandler.Duplicate)
+			r.Get("/portfolios/{id}/history", historyHandler.Portfolio)
+			r.Get("/portfolios/performance", performanceHandler.Account)
 			r.Get("/portfolios/{id}/summary", portfolioHandler.Summary)
+			r.Get("/portfolios/{id}/dashboard", portfolioDashboardHandler.Get)
+			r.Get("/portfolios/{id}/performance", performanceHandler.Portfolio)
+			r.Get("/portfolios/{id}/targets", rebalanceHandler.Targets)
+			r.Put("/portfolios/{id}/targets", rebalanceHandler.SetTargets)
+			r.Get("/portfolios/{id}/rebalance", rebalanceHandler.Rebalance)
+			r.Post("/portfolios/{id}/apply-template", templateHandler.Apply)
+			r.Get("/portfolios/{id}/compare", templateHandler.Compare)
 			r.Get("/portfolios/{id}/holdings", holdingHandler.ListByPortfolio)
 			r.Post("/portfolios/{id}/holdings", holdingHandler.Create)
+			r.Put("/portfolios/{id}/holdings/bulk", holdingHandler.BulkUpdate)
 			r.Get("/portfolios/{id}/transactions", txHandler.List)
 			r.Post("/portfolios/{id}/transactions", txHandler.Create)
 			r.Post("/portfolios/{id}/transactions/import", txHandler.Import)
@@ -187,23 +395,55 @@ func main() {
 			// Holdings
 			r.Get("/holdings", holdingHandler.ListAll)
 			r.Get("/holdings/{holdingId}", holdingHandler.Get)
+			r.Get("/holdings/{holdingId}/lots", holdingHandler.Lots)
+			r.Get("/holdings/{holdingId}/history", historyHandler.Holding)
+			r.Get("/holdings/{holdingId}/tags", tagHandler.HoldingTags)
+			r.Put("/holdings/{holdingId}/tags", tagHandler.SetHoldingTags)
 			r.Put("/holdings/{holdingId}", holdingHandler.Update)
 			r.Delete("/holdings/{holdingId}", holdingHandler.Delete)
 
 			// Transactions
+			r.Get("/transactions/export", txHandler.Export)
 			r.Get("/transactions/{txId}", txHandler.Get)
+			r.Get("/transactions/{txId}/history", historyHandler.Transaction)
+			r.Put("/transactions/{txId}", txHandler.Update)
 			r.Delete("/transactions/{txId}", txHandler.Delete)
+			r.Put("/transactions/{txId}/withholding", txHandler.UpdateWithholding)
+			r.Post("/transactions/{txId}/voucher", txHandler.UploadVoucher)
+			r.Get("/transactions/{txId}/voucher", txHandler.GetVoucher)
+			r.Delete("/transactions/{txId}/voucher", txHandler.DeleteVoucher)
+			r.Get("/transactions/{txId}/tags", tagHandler.TransactionTags)
+			r.Put("/transactions/{txId}/tags", tagHandler.SetTransactionTags)
 
 			// Cash Accounts
 			r.Get("/cash-accounts", cashHandler.ListAll)
 			r.Put("/cash-accounts/{accountId}", cashHandler.Update)
 			r.Delete("/cash-accounts/{accountId}", cashHandler.Delete)
+			r.Get("/cash-accounts/{accountId}/movements", cashHandler.Movements)
+			r.Post("/cash-accounts/{accountId}/movements", cashHandler.RecordMovement)
+			r.Post("/cash-accounts/{accountId}/transfers", cashHandler.Transfer)
+
+			// Open Banking connections
+			r.Get("/bank-connections", bankConnectionHandler.List)
+			r.Post("/bank-connections", bankConnectionHandler.Create)
+			r.Get("/bank-connections/institutions", bankConnectionHandler.Institutions)
+			r.Get("/bank-connections/{id}", bankConnectionHandler.Get)
+			r.Delete("/bank-connections/{id}", bankConnectionHandler.Delete)
+			r.Post("/bank-connections/{id}/complete", bankConnectionHandler.Complete)
+			r.Post("/bank-connections/{id}/sync", bankConnectionHandler.Sync)
 
 			// Assets
 			r.Get("/assets/search", assetHandler.Search)
 			r.Get("/assets/quotes", assetHandler.GetQuotes)
+			r.Get("/assets/lookup", assetHandler.Lookup)
 			r.Get("/assets/{symbol}", assetHandler.GetDetails)
 			r.Get("/assets/{symbol}/history", assetHandler.GetHistory)
+			r.Get("/assets/{symbol}/notes", noteHandler.List)
+			r.Post("/assets/{symbol}/notes", noteHandler.Create)
+			r.Get("/assets/{symbol}/corporate-actions", actionHandler.List)
+			r.Get("/asset-notes/{id}", noteHandler.Get)
+			r.Put("/asset-notes/{id}", noteHandler.Update)
+			r.Delete("/asset-notes/{id}", noteHandler.Delete)
 			r.Post("/assets/refresh", assetHandler.RefreshPrices)
 			r.Get("/assets/historical-price", holdingHandler.GetHistoricalPrice)
 
@@ -214,11 +454,134 @@ func main() {
 			r.Put("/fixed-assets/{id}", fixedAssetHandler.Update)
 			r.Delete("/fixed-assets/{id}", fixedAssetHandler.Delete)
 
+			// Liabilities
+			r.Get("/liabilities", liabilityHandler.List)
+			r.Post("/liabilities", liabilityHandler.Create)
+			r.Get("/liabilities/{id}", liabilityHandler.Get)
+			r.Put("/liabilities/{id}", liabilityHandler.Update)
+			r.Delete("/liabilities/{id}", liabilityHandler.Delete)
+			r.Get("/liabilities/{id}/schedule", liabilityHandler.Schedule)
+
+			// Standing orders
+			r.Get("/standing-orders", standingOrderHandler.List)
+			r.Post("/standing-orders", standingOrderHandler.Create)
+			r.Get("/standing-orders/{id}", standingOrderHandler.Get)
+			r.Put("/standing-orders/{id}", standingOrderHandler.Update)
+			r.Delete("/standing-orders/{id}", standingOrderHandler.Delete)
+			r.Get("/standing-orders/{id}/preview", standingOrderHandler.Preview)
+			r.Post("/standing-orders/{id}/skip", standingOrderHandler.Skip)
+
+			// Saved Views
+			r.Get("/views", viewHandler.List)
+			r.Post("/views", viewHandler.Create)
+			r.Get("/views/{id}", viewHandler.Get)
+			r.Put("/views/{id}", viewHandler.Update)
+			r.Delete("/views/{id}", viewHandler.Delete)
+			r.Get("/views/{id}/results", viewHandler.Results)
+
+			// Portfolio templates
+			r.Get("/portfolio-templates", templateHandler.List)
+			r.Post("/portfolio-templates", templateHandler.Create)
+			r.Get("/portfolio-templates/{id}", templateHandler.Get)
+			r.Put("/portfolio-templates/{id}", templateHandler.Update)
+			r.Delete("/portfolio-templates/{id}", templateHandler.Delete)
+			r.Post("/portfolio-templates/{id}/clone", templateHandler.Clone)
+
+			// Import presets
+			r.Get("/import-presets", presetHandler.List)
+			r.Post("/import-presets", presetHandler.Create)
+			r.Get("/import-presets/{id}", presetHandler.Get)
+			r.Put("/import-presets/{id}", presetHandler.Update)
+			r.Delete("/import-presets/{id}", presetHandler.Delete)
+
+			// Children (JISA and child savings)
+			r.Get("/children", childrenHandler.List)
+			r.Post("/bed-and-isa", bedAndISAHandler.BedAndISA)
+			r.Get("/contributions/schedule", contributionHandler.Schedule)
+
+			// Savings Goals
+			r.Get("/goals", goalHandler.List)
+			r.Post("/goals", goalHandler.Create)
+			r.Get("/goals/{id}", goalHandler.Get)
+			r.Put("/goals/{id}", goalHandler.Update)
+			r.Delete("/goals/{id}", goalHandler.Delete)
+
+			// Reminders (parsed from "@YYYY-MM-DD text" tokens in notes)
+			r.Get("/reminders", reminderHandler.List)
+			r.Put("/reminders/{id}", reminderHandler.Update)
+			r.Get("/reminders/escalation-defaults", reminderHandler.EscalationDefaults)
+			r.Put("/reminders/escalation-defaults/{sourceType}", reminderHandler.SaveEscalationDefault)
+
+			// Recurring income
+			r.Get("/income", incomeHandler.List)
+			r.Post("/income", incomeHandler.Create)
+			r.Get("/income/{id}", incomeHandler.Get)
+			r.Put("/income/{id}", incomeHandler.Update)
+			r.Delete("/income/{id}", incomeHandler.Delete)
+
+			// Price alerts
+			r.Get("/alerts", priceAlertHandler.List)
+			r.Post("/alerts", priceAlertHandler.Create)
+			r.Get("/alerts/{id}", priceAlertHandler.Get)
+			r.Put("/alerts/{id}", priceAlertHandler.Update)
+			r.Delete("/alerts/{id}", priceAlertHandler.Delete)
+
+			// Watchlist
+			r.Get("/watchlist", watchlistHandler.List)
+			r.Post("/watchlist", watchlistHandler.Add)
+			r.Put("/watchlist/order", watchlistHandler.Reorder)
+			r.Get("/watchlist/quotes", watchlistHandler.Quotes)
+			r.Delete("/watchlist/{symbol}", watchlistHandler.Remove)
+
+			// Tags
+			r.Get("/tags", tagHandler.List)
+			r.Get("/tags/{tag}/items", tagHandler.Items)
+			r.Put("/tags/{tag}", tagHandler.Rename)
+			r.Post("/tags/{tag}/merge", tagHandler.Merge)
+			r.Delete("/tags/{tag}", tagHandler.Delete)
+
+			// Personal API tokens (managed with a session; tokens can only introspect)
+			r.Get("/tokens", apiTokenHandler.List)
+			r.Post("/tokens", apiTokenHandler.Create)
+			r.Get("/tokens/introspect", apiTokenHandler.Introspect)
+			r.Delete("/tokens/{id}", apiTokenHandler.Delete)
+
+			// Webhooks
+			r.Get("/webhooks", webhookHandler.List)
+			r.Post("/webhooks", webhookHandler.Create)
+			r.Get("/webhooks/deliveries", webhookHandler.Deliveries)
+			r.Post("/webhooks/deliveries/{id}/redrive", webhookHandler.Redrive)
+			r.Put("/webhooks/{id}", webhookHandler.Update)
+			r.Delete("/webhooks/{id}", webhookHandler.Delete)
+			r.Post("/webhooks/{id}/test", webhookHandler.Test)
+
 			// Dashboard
 			r.Get("/dashboard/summary", dashboardHandler.Summary)
 			r.Get("/dashboard/allocation", dashboardHandler.Allocation)
 			r.Get("/dashboard/top-movers", dashboardHandler.TopMovers)
+			r.Get("/dashboard/markets", dashboardHandler.Markets)
 			r.Get("/dashboard/performance", dashboardHandler.Performance)
+			r.Get("/dashboard/history", dashboardHandler.History)
+			r.Get("/dashboard/changes", dashboardHandler.Changes)
+			r.Get("/dashboard/cash-flow", dashboardHandler.CashFlow)
+			r.Get("/dashboard/fire-projection", fireHandler.Projection)
+			r.Get("/dashboard/goals", goalHandler.Dashboard)
+
+			// Insights
+			r.Get("/insights/health", insightHandler.Health)
+
+			// Reports
+			r.Get("/reports/cashflow", reportHandler.CashFlow)
+			r.Get("/reports/estate", reportHandler.Estate)
+			r.Get("/reports/foreign-tax-credit", reportHandler.ForeignTaxCredit)
+			r.Get("/reports/interest", reportHandler.Interest)
+			r.Get("/reports/savings-projection", reportHandler.SavingsProjection)
+			r.Get("/reports/income", reportHandler.Income)
+			r.Get("/reports/realised-gains", reportHandler.RealisedGains)
+
+			// Announcements
+			r.Get("/announcements", announcementHandler.Active)
+			r.Post("/announcements/{id}/dismiss", announcementHandler.Dismiss)
 
 			// Admin routes (requires admin privileges)
 			r.Route("/admin", func(r chi.Router) {
@@ -229,6 +592,23 @@ func main() {
 				r.Put("/users/{id}/unlock", adminHandler.UnlockUser)
 				r.Put("/users/{id}/admin", adminHandler.SetAdmin)
 				r.Post("/users/{id}/reset-password", adminHandler.ResetPassword)
+				r.Post("/corporate-actions", actionHandler.Create)
+				r.Delete("/corporate-actions/{id}", actionHandler.Revert)
+				r.Get("/market-data/providers", marketDataHandler.Providers)
+				r.Put("/assets/{symbol}/data-source", marketDataHandler.UpdateDataSource)
+				r.Get("/market-data/routes", marketDataHandler.Routes)
+				r.Put("/market-data/routes", marketDataHandler.SaveRoute)
+				r.Delete("/market-data/routes/{id}", marketDataHandler.DeleteRoute)
+				r.Get("/cache/namespaces", cacheHandler.Namespaces)
+				r.Post("/cache/rebuild", cacheHandler.Rebuild)
+				r.Delete("/cache/{namespace}", cacheHandler.Invalidate)
+				r.Get("/settings", settingsHandler.Get)
+				r.Put("/settings", settingsHandler.Update)
+				r.Delete("/settings/{key}", settingsHandler.Reset)
+				r.Get("/announcements", announcementHandler.List)
+				r.Post("/announcements", announcementHandler.Create)
+				r.Put("/announcements/{id}", announcementHandler.Update)
+				r.Delete("/announcements/{id}", announcementHandler.Delete)
 			})
 		})
 	})
@@ -243,7 +623,10 @@ func main() {
 	}
 
 	// Graceful shutdown
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
+
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
@@ -256,6 +639,12 @@ func main() {
 		if err := server.Shutdown(ctx); err != nil {
 			logger.Error("server shutdown failed", "error", err)
 		}
+
+		// Stop accepting new jobs and let in-flight ones finish or checkpoint
+		if err := jobManager.Shutdown(ctx); err != nil {
+			logger.Error("job drain incomplete", "error", err)
+		}
+		bgCancel()
 	}()
 
 	// Start server
@@ -267,5 +656,7 @@ func main() {
 		os.Exit(1)
 	}
 
+	// ListenAndServe returns as soon as shutdown starts; wait for jobs to drain
+	<-shutdownDone
 	logger.Info("server stopped")
 }
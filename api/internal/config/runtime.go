@@ -0,0 +1,122 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration that is represented as a string ("10m", "1h30m") in JSON
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10m\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Feature flags with a default. Flags that aren't listed here are off unless enabled.
+const (
+	// FeatureRegistration lets new users sign up
+	FeatureRegistration = "registration"
+)
+
+var defaultFeatureFlags = map[string]bool{
+	FeatureRegistration: true,
+}
+
+// RuntimeSettings are the settings operators can change while the server is running.
+// Values loaded from the environment act as defaults; overrides are persisted in the database.
+type RuntimeSettings struct {
+	YahooCacheTTL        Duration        `json:"yahoo_cache_ttl"`
+	SearchCacheTTL       Duration        `json:"search_cache_ttl"`
+	PriceRefreshInterval Duration        `json:"price_refresh_interval"`
+	APIRateLimit         int             `json:"api_rate_limit"`
+	LoginRateLimit       int             `json:"login_rate_limit"`
+	RegisterRateLimit    int             `json:"register_rate_limit"`
+	FeatureFlags         map[string]bool `json:"feature_flags"`
+}
+
+// Validate checks that the settings are usable
+func (s *RuntimeSettings) Validate() error {
+	if s.YahooCacheTTL <= 0 || s.SearchCacheTTL <= 0 {
+		return fmt.Errorf("cache TTLs must be positive")
+	}
+	// A zero refresh interval disables background price refreshes
+	if s.PriceRefreshInterval < 0 || (s.PriceRefreshInterval > 0 && s.PriceRefreshInterval < Duration(time.Minute)) {
+		return fmt.Errorf("price_refresh_interval must be 0 (disabled) or at least 1m")
+	}
+	if s.APIRateLimit <= 0 || s.LoginRateLimit <= 0 || s.RegisterRateLimit <= 0 {
+		return fmt.Errorf("rate limits must be positive")
+	}
+	return nil
+}
+
+// Clone returns a deep copy of the settings
+func (s RuntimeSettings) Clone() RuntimeSettings {
+	flags := make(map[string]bool, len(s.FeatureFlags))
+	for k, v := range s.FeatureFlags {
+		flags[k] = v
+	}
+	s.FeatureFlags = flags
+	return s
+}
+
+func loadRuntimeSettings(yahooCacheTTL time.Duration) RuntimeSettings {
+	searchCacheTTL, err := time.ParseDuration(getEnv("SEARCH_CACHE_TTL", "5m"))
+	if err != nil {
+		searchCacheTTL = 5 * time.Minute
+	}
+
+	priceRefreshInterval, err := time.ParseDuration(getEnv("PRICE_REFRESH_INTERVAL", "0s"))
+	if err != nil {
+		priceRefreshInterval = 0
+	}
+
+	// FEATURE_FLAGS is a comma-separated list of enabled flags, e.g. "beta_reports,fire_projection".
+	// A flag prefixed with "-" is disabled, e.g. "-registration".
+	flags := make(map[string]bool, len(defaultFeatureFlags))
+	for flag, enabled := range defaultFeatureFlags {
+		flags[flag] = enabled
+	}
+	for _, flag := range strings.Split(getEnv("FEATURE_FLAGS", ""), ",") {
+		flag = strings.TrimSpace(flag)
+		if name, disabled := strings.CutPrefix(flag, "-"); disabled {
+			if name != "" {
+				flags[name] = false
+			}
+		} else if flag != "" {
+			flags[flag] = true
+		}
+	}
+
+	return RuntimeSettings{
+		YahooCacheTTL:        Duration(yahooCacheTTL),
+		SearchCacheTTL:       Duration(searchCacheTTL),
+		PriceRefreshInterval: Duration(priceRefreshInterval),
+		APIRateLimit:         getEnvInt("RATE_LIMIT_API", 100),
+		LoginRateLimit:       getEnvInt("RATE_LIMIT_LOGIN", 5),
+		RegisterRateLimit:    getEnvInt("RATE_LIMIT_REGISTER", 3),
+		FeatureFlags:         flags,
+	}
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(getEnv(key, "")); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -2,32 +2,115 @@ package database
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 type RedisClient struct {
-	Client *redis.Client
+	Client redis.UniversalClient
+	// namespace prefixes cache keys so a deploy that changes cached payload
+	// shapes can bump the version instead of reading stale incompatible JSON
+	namespace string
 }
 
-func NewRedis(redisURL string) (*RedisClient, error) {
-	opts, err := redis.ParseURL(redisURL)
+// NewRedis connects to Redis. Besides plain redis:// and rediss:// URLs it accepts:
+//
+//	redis-sentinel://[:password@]host1:26379,host2:26379/0?master=mymaster
+//	redis-cluster://[user:password@]host1:6379?addr=host2:6379&addr=host3:6379
+//
+// Keys written through Get/Set/Delete are prefixed with the cache version.
+func NewRedis(redisURL, cacheVersion string) (*RedisClient, error) {
+	client, err := newUniversalClient(redisURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse redis URL: %w", err)
+		return nil, err
 	}
 
-	client := redis.NewClient(opts)
-
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to ping redis: %w", err)
 	}
 
-	return &RedisClient{Client: client}, nil
+	return &RedisClient{Client: client, namespace: "cache:v" + cacheVersion + ":"}, nil
+}
+
+func newUniversalClient(redisURL string) (redis.UniversalClient, error) {
+	scheme, _, _ := strings.Cut(redisURL, "://")
+
+	switch scheme {
+	case "redis-sentinel", "rediss-sentinel":
+		opts, err := parseSentinelURL(redisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse redis sentinel URL: %w", err)
+		}
+		return redis.NewFailoverClient(opts), nil
+	case "redis-cluster", "rediss-cluster":
+		opts, err := redis.ParseClusterURL(strings.Replace(redisURL, "-cluster://", "://", 1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse redis cluster URL: %w", err)
+		}
+		return redis.NewClusterClient(opts), nil
+	default:
+		opts, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse redis URL: %w", err)
+		}
+		return redis.NewClient(opts), nil
+	}
+}
+
+// parseSentinelURL parses redis-sentinel://[:password@]host:port[,host:port]/db?master=name.
+// The password is used for the Redis master; sentinel_password authenticates to the sentinels.
+func parseSentinelURL(redisURL string) (*redis.FailoverOptions, error) {
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	q := u.Query()
+	opts := &redis.FailoverOptions{
+		MasterName:       q.Get("master"),
+		SentinelPassword: q.Get("sentinel_password"),
+	}
+	if opts.MasterName == "" {
+		return nil, fmt.Errorf("missing master name")
+	}
+
+	for _, addr := range strings.Split(u.Host, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			opts.SentinelAddrs = append(opts.SentinelAddrs, addr)
+		}
+	}
+	if len(opts.SentinelAddrs) == 0 {
+		return nil, fmt.Errorf("no sentinel addresses")
+	}
+
+	if u.User != nil {
+		opts.Username = u.User.Username()
+		opts.Password, _ = u.User.Password()
+	}
+
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		opts.DB, err = strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("invalid database number %q", db)
+		}
+	}
+
+	if u.Scheme == "rediss-sentinel" {
+		opts.TLSConfig = &tls.Config{}
+	}
+
+	return opts, nil
 }
 
 func (r *RedisClient) Close() error {
@@ -39,13 +122,68 @@ func (r *RedisClient) Health(ctx context.Context) error {
 }
 
 func (r *RedisClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
-	return r.Client.Set(ctx, key, value, expiration).Err()
+	return r.Client.Set(ctx, r.namespace+key, value, expiration).Err()
 }
 
 func (r *RedisClient) Get(ctx context.Context, key string) (string, error) {
-	return r.Client.Get(ctx, key).Result()
+	return r.Client.Get(ctx, r.namespace+key).Result()
 }
 
 func (r *RedisClient) Delete(ctx context.Context, keys ...string) error {
-	return r.Client.Del(ctx, keys...).Err()
+	namespaced := make([]string, len(keys))
+	for i, key := range keys {
+		namespaced[i] = r.namespace + key
+	}
+	return r.Client.Del(ctx, namespaced...).Err()
+}
+
+// DeletePrefix removes every cache key starting with prefix (all cache keys when prefix
+// is empty) and returns how many were removed. Keys are found with SCAN so Redis isn't
+// blocked, and on a cluster each master is scanned in turn.
+func (r *RedisClient) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	pattern := r.namespace + prefix + "*"
+
+	if cluster, ok := r.Client.(*redis.ClusterClient); ok {
+		var mu sync.Mutex
+		total := 0
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			n, err := deleteMatching(ctx, node, pattern)
+			mu.Lock()
+			total += n
+			mu.Unlock()
+			return err
+		})
+		return total, err
+	}
+	return deleteMatching(ctx, r.Client, pattern)
+}
+
+func deleteMatching(ctx context.Context, client redis.UniversalClient, pattern string) (int, error) {
+	total := 0
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, 500).Result()
+		if err != nil {
+			return total, err
+		}
+		if len(keys) > 0 {
+			// One UNLINK per key keeps cluster keys in different slots apart
+			pipe := client.Pipeline()
+			cmds := make([]*redis.IntCmd, len(keys))
+			for i, key := range keys {
+				cmds[i] = pipe.Unlink(ctx, key)
+			}
+			if _, err := pipe.Exec(ctx); err != nil {
+				return total, err
+			}
+			// SCAN can return a key twice, so count what was actually removed
+			for _, cmd := range cmds {
+				total += int(cmd.Val())
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			return total, nil
+		}
+	}
 }
@@ -0,0 +1,322 @@
+// Package exporter writes transactions in the import formats of other portfolio tools,
+// so they can be run alongside wellf or moved to without re-keying. Each format maps
+// wellf's transaction types onto its own and skips those it has no equivalent for.
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats
+const (
+	Ghostfolio           = "ghostfolio"
+	GhostfolioCSV        = "ghostfolio_csv"
+	PortfolioPerformance = "portfolio_performance"
+)
+
+// wellf transaction types read by the exporter
+const (
+	typeBuy         = "BUY"
+	typeSell        = "SELL"
+	typeDividend    = "DIVIDEND"
+	typeInterest    = "INTEREST"
+	typeFee         = "FEE"
+	typeTransferIn  = "TRANSFER_IN"
+	typeTransferOut = "TRANSFER_OUT"
+	typeDeposit     = "DEPOSIT"
+	typeWithdrawal  = "WITHDRAWAL"
+	typeTaxRelief   = "TAX_RELIEF"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Account is a portfolio the activities belong to
+type Account struct {
+	ID       string
+	Name     string
+	Currency string
+}
+
+// Activity is one transaction to export. Symbol is empty for cash transactions, and
+// Amount is the total in Currency (net of tax for dividends).
+type Activity struct {
+	AccountID  string
+	Account    string
+	Date       time.Time
+	Type       string
+	Symbol     string
+	Name       string
+	ISIN       string
+	DataSource string
+	Currency   string
+	Quantity   float64
+	Price      float64
+	Amount     float64
+	Tax        float64
+	Note       string
+}
+
+// unitPrice is the price per unit, worked out from the total if no price was recorded
+func (a *Activity) unitPrice() float64 {
+	if a.Price == 0 && a.Quantity != 0 {
+		return a.Amount / a.Quantity
+	}
+	return a.Price
+}
+
+// File describes the download for a format
+type File struct {
+	ContentType string
+	Extension   string
+}
+
+// Formats lists the export formats
+var Formats = map[string]File{
+	Ghostfolio:           {ContentType: "application/json", Extension: "json"},
+	GhostfolioCSV:        {ContentType: "text/csv; charset=utf-8", Extension: "csv"},
+	PortfolioPerformance: {ContentType: "text/csv; charset=utf-8", Extension: "csv"},
+}
+
+// Write writes the activities in the named format and returns how many were skipped
+// because the format has no equivalent for their type
+func Write(w io.Writer, format string, accounts []Account, activities []Activity) (int, error) {
+	switch format {
+	case Ghostfolio:
+		return writeGhostfolio(w, accounts, activities)
+	case GhostfolioCSV:
+		return writeGhostfolioCSV(w, activities)
+	case PortfolioPerformance:
+		return writePortfolioPerformance(w, activities)
+	default:
+		return 0, ErrUnknownFormat
+	}
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// ghostfolioActivity is an activity in Ghostfolio's import schema
+type ghostfolioActivity struct {
+	AccountID  *string `json:"accountId"`
+	Comment    *string `json:"comment"`
+	Fee        float64 `json:"fee"`
+	Quantity   float64 `json:"quantity"`
+	Type       string  `json:"type"`
+	UnitPrice  float64 `json:"unitPrice"`
+	Currency   string  `json:"currency"`
+	DataSource string  `json:"dataSource"`
+	Date       string  `json:"date"`
+	Symbol     string  `json:"symbol"`
+}
+
+type ghostfolioAccount struct {
+	Balance    float64 `json:"balance"`
+	Comment    *string `json:"comment"`
+	Currency   string  `json:"currency"`
+	ID         string  `json:"id"`
+	IsExcluded bool    `json:"isExcluded"`
+	Name       string  `json:"name"`
+	PlatformID *string `json:"platformId"`
+}
+
+type ghostfolioExport struct {
+	Meta struct {
+		Date    string `json:"date"`
+		Version string `json:"version"`
+	} `json:"meta"`
+	Accounts   []ghostfolioAccount  `json:"accounts"`
+	Activities []ghostfolioActivity `json:"activities"`
+}
+
+// ghostfolioDataSources are the price sources Ghostfolio shares with wellf; other
+// symbols are looked up on Yahoo Finance
+var ghostfolioDataSources = map[string]bool{
+	"YAHOO":         true,
+	"ALPHA_VANTAGE": true,
+	"COINGECKO":     true,
+}
+
+// toGhostfolio maps an activity onto Ghostfolio's types. Ghostfolio keeps cash as an
+// account balance, so deposits, withdrawals and cash transfers have no equivalent;
+// transfers of holdings become buys and sells at the recorded price.
+func toGhostfolio(a *Activity) (ghostfolioActivity, bool) {
+	g := ghostfolioActivity{
+		Currency:   a.Currency,
+		DataSource: "YAHOO",
+		Date:       a.Date.UTC().Format("2006-01-02T15:04:05.000Z"),
+		Symbol:     a.Symbol,
+	}
+	if ghostfolioDataSources[a.DataSource] {
+		g.DataSource = a.DataSource
+	}
+	if a.AccountID != "" {
+		accountID := a.AccountID
+		g.AccountID = &accountID
+	}
+	if a.Note != "" {
+		note := a.Note
+		g.Comment = &note
+	}
+
+	switch a.Type {
+	case typeBuy, typeSell, typeTransferIn, typeTransferOut:
+		if a.Symbol == "" || a.Quantity == 0 {
+			return g, false
+		}
+		g.Type = "BUY"
+		if a.Type == typeSell || a.Type == typeTransferOut {
+			g.Type = "SELL"
+		}
+		g.Quantity = a.Quantity
+		g.UnitPrice = a.unitPrice()
+	case typeDividend, typeInterest, typeFee:
+		g.Type = a.Type
+		g.Quantity = 1
+		g.UnitPrice = a.Amount
+		if a.Type == typeFee {
+			g.UnitPrice, g.Fee = 0, a.Amount
+		}
+		if a.Symbol == "" {
+			// Cash interest and fees aren't on a security; Ghostfolio takes them as
+			// manual entries named by type
+			g.DataSource = "MANUAL"
+			g.Symbol = a.Type
+		}
+	default:
+		return g, false
+	}
+
+	return g, true
+}
+
+func writeGhostfolio(w io.Writer, accounts []Account, activities []Activity) (int, error) {
+	var export ghostfolioExport
+	export.Meta.Date = time.Now().UTC().Format(time.RFC3339)
+	export.Meta.Version = "wellf"
+	export.Accounts = make([]ghostfolioAccount, 0, len(accounts))
+	export.Activities = make([]ghostfolioActivity, 0, len(activities))
+
+	for _, a := range accounts {
+		export.Accounts = append(export.Accounts, ghostfolioAccount{ID: a.ID, Name: a.Name, Currency: a.Currency})
+	}
+
+	skipped := 0
+	for i := range activities {
+		g, ok := toGhostfolio(&activities[i])
+		if !ok {
+			skipped++
+			continue
+		}
+		export.Activities = append(export.Activities, g)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return skipped, enc.Encode(export)
+}
+
+func writeGhostfolioCSV(w io.Writer, activities []Activity) (int, error) {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"Date", "Code", "DataSource", "Currency", "Price", "Quantity", "Action", "Fee", "Note"})
+
+	skipped := 0
+	for i := range activities {
+		g, ok := toGhostfolio(&activities[i])
+		if !ok {
+			skipped++
+			continue
+		}
+		note := ""
+		if g.Comment != nil {
+			note = *g.Comment
+		}
+		_ = cw.Write([]string{
+			activities[i].Date.Format("2006-01-02"),
+			g.Symbol,
+			g.DataSource,
+			g.Currency,
+			formatNumber(g.UnitPrice),
+			formatNumber(g.Quantity),
+			strings.ToLower(g.Type),
+			formatNumber(g.Fee),
+			note,
+		})
+	}
+
+	cw.Flush()
+	return skipped, cw.Error()
+}
+
+// portfolioPerformanceTypes maps transaction types onto Portfolio Performance's account
+// transaction types
+var portfolioPerformanceTypes = map[string]string{
+	typeBuy:         "Buy",
+	typeSell:        "Sell",
+	typeDividend:    "Dividend",
+	typeInterest:    "Interest",
+	typeFee:         "Fees",
+	typeDeposit:     "Deposit",
+	typeWithdrawal:  "Removal",
+	typeTaxRelief:   "Deposit",
+	typeTransferIn:  "Transfer (Inbound)",
+	typeTransferOut: "Transfer (Outbound)",
+}
+
+// writePortfolioPerformance writes an account transactions CSV for Portfolio
+// Performance's CSV import, with each portfolio as both the cash and securities
+// account. Transfers of holdings are deliveries in Portfolio Performance, which this
+// import doesn't take, so they are skipped.
+func writePortfolioPerformance(w io.Writer, activities []Activity) (int, error) {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{
+		"Date", "Type", "Value", "Transaction Currency", "Shares", "Security Name", "ISIN",
+		"Ticker Symbol", "Fees", "Taxes", "Note", "Cash Account", "Securities Account",
+	})
+
+	skipped := 0
+	for i := range activities {
+		a := &activities[i]
+		ppType, ok := portfolioPerformanceTypes[a.Type]
+		isTransfer := a.Type == typeTransferIn || a.Type == typeTransferOut
+		if !ok || (isTransfer && a.Symbol != "") || ((a.Type == typeBuy || a.Type == typeSell) && a.Symbol == "") {
+			skipped++
+			continue
+		}
+
+		shares, securitiesAccount := "", ""
+		if a.Type == typeBuy || a.Type == typeSell {
+			shares = formatNumber(a.Quantity)
+			securitiesAccount = a.Account
+		}
+		taxes := ""
+		if a.Tax != 0 {
+			taxes = formatNumber(a.Tax)
+		}
+
+		_ = cw.Write([]string{
+			a.Date.Format("2006-01-02"),
+			ppType,
+			formatNumber(a.Amount),
+			a.Currency,
+			shares,
+			a.Name,
+			a.ISIN,
+			a.Symbol,
+			"",
+			taxes,
+			a.Note,
+			a.Account,
+			securitiesAccount,
+		})
+	}
+
+	cw.Flush()
+	return skipped, cw.Error()
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+const (
+	maxAnnouncementTitleLength   = 200
+	maxAnnouncementMessageLength = 2000
+)
+
+type AnnouncementHandler struct {
+	repo *repository.AnnouncementRepository
+}
+
+func NewAnnouncementHandler(repo *repository.AnnouncementRepository) *AnnouncementHandler {
+	return &AnnouncementHandler{repo: repo}
+}
+
+// SaveAnnouncementRequest creates or replaces an announcement. StartsAt defaults to now
+// on create and is left unchanged on update; a nil EndsAt runs until it is deleted.
+type SaveAnnouncementRequest struct {
+	Title       string     `json:"title"`
+	Message     string     `json:"message"`
+	Severity    string     `json:"severity"`
+	StartsAt    *time.Time `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at"`
+	Dismissible *bool      `json:"dismissible"`
+}
+
+// validate checks the request, returning an error message if it is invalid
+func (req *SaveAnnouncementRequest) validate() string {
+	req.Title = strings.TrimSpace(req.Title)
+	req.Message = strings.TrimSpace(req.Message)
+	req.Severity = strings.ToUpper(strings.TrimSpace(req.Severity))
+	if req.Severity == "" {
+		req.Severity = models.AnnouncementInfo
+	}
+
+	if req.Title == "" {
+		return "Title is required"
+	}
+	if len(req.Title) > maxAnnouncementTitleLength {
+		return "Title is too long"
+	}
+	if req.Message == "" {
+		return "Message is required"
+	}
+	if len(req.Message) > maxAnnouncementMessageLength {
+		return "Message is too long"
+	}
+	switch req.Severity {
+	case models.AnnouncementInfo, models.AnnouncementWarning, models.AnnouncementCritical:
+	default:
+		return "Invalid severity (use INFO, WARNING or CRITICAL)"
+	}
+	return ""
+}
+
+// apply copies the request onto the announcement and checks its window
+func (req *SaveAnnouncementRequest) apply(a *models.Announcement) string {
+	a.Title = req.Title
+	a.Message = req.Message
+	a.Severity = req.Severity
+	if req.StartsAt != nil {
+		a.StartsAt = *req.StartsAt
+	}
+	a.EndsAt = req.EndsAt
+	a.Dismissible = req.Dismissible == nil || *req.Dismissible
+
+	if a.EndsAt != nil && !a.EndsAt.After(a.StartsAt) {
+		return "End time must be after the start time"
+	}
+	return ""
+}
+
+// Active lists the announcements showing now that the user hasn't dismissed
+func (h *AnnouncementHandler) Active(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	announcements, err := h.repo.GetActiveForUser(r.Context(), userID, time.Now())
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch announcements")
+		return
+	}
+	if announcements == nil {
+		announcements = []*models.Announcement{}
+	}
+
+	JSON(w, http.StatusOK, announcements)
+}
+
+// Dismiss hides the announcement from the user
+func (h *AnnouncementHandler) Dismiss(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	a, ok := h.announcement(w, r)
+	if !ok {
+		return
+	}
+	if !a.Dismissible {
+		Error(w, http.StatusBadRequest, "This announcement cannot be dismissed")
+		return
+	}
+
+	if err := h.repo.Dismiss(r.Context(), a.ID, userID); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to dismiss announcement")
+		return
+	}
+
+	NoContent(w)
+}
+
+// List returns every announcement for admins, including scheduled and ended ones
+func (h *AnnouncementHandler) List(w http.ResponseWriter, r *http.Request) {
+	announcements, err := h.repo.GetAll(r.Context())
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch announcements")
+		return
+	}
+	if announcements == nil {
+		announcements = []*models.Announcement{}
+	}
+
+	JSON(w, http.StatusOK, announcements)
+}
+
+func (h *AnnouncementHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req SaveAnnouncementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	a := &models.Announcement{
+		StartsAt:  time.Now(),
+		CreatedBy: &userID,
+	}
+	if msg := req.apply(a); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := h.repo.Create(r.Context(), a); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to create announcement")
+		return
+	}
+
+	JSON(w, http.StatusCreated, a)
+}
+
+func (h *AnnouncementHandler) Update(w http.ResponseWriter, r *http.Request) {
+	a, ok := h.announcement(w, r)
+	if !ok {
+		return
+	}
+
+	var req SaveAnnouncementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := req.apply(a); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := h.repo.Update(r.Context(), a); err != nil {
+		if errors.Is(err, repository.ErrAnnouncementNotFound) {
+			Error(w, http.StatusNotFound, "Announcement not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to update announcement")
+		return
+	}
+
+	JSON(w, http.StatusOK, a)
+}
+
+func (h *AnnouncementHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid announcement ID")
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrAnnouncementNotFound) {
+			Error(w, http.StatusNotFound, "Announcement not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to delete announcement")
+		return
+	}
+
+	NoContent(w)
+}
+
+func (h *AnnouncementHandler) announcement(w http.ResponseWriter, r *http.Request) (*models.Announcement, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid announcement ID")
+		return nil, false
+	}
+
+	a, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrAnnouncementNotFound) {
+			Error(w, http.StatusNotFound, "Announcement not found")
+			return nil, false
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch announcement")
+		return nil, false
+	}
+
+	return a, true
+}
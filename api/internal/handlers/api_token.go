@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+const (
+	maxAPITokens          = 20
+	defaultAPITokenLimit  = 60
+	maxAPITokenRateLimit  = 1000
+	maxAPITokenNameLength = 100
+
+	// Kiosk tokens sit on a device for a long time, so they last a year unless told
+	// otherwise, and at most five
+	defaultKioskTokenLifetime = 365 * 24 * time.Hour
+	maxKioskTokenLifetime     = 5 * 365 * 24 * time.Hour
+)
+
+// APITokenHandler manages the personal API tokens third-party tools use
+type APITokenHandler struct {
+	apiTokenRepo *repository.APITokenRepository
+}
+
+func NewAPITokenHandler(apiTokenRepo *repository.APITokenRepository) *APITokenHandler {
+	return &APITokenHandler{apiTokenRepo: apiTokenRepo}
+}
+
+type CreateAPITokenRequest struct {
+	Name        string     `json:"name"`
+	Kind        string     `json:"kind"`
+	DeviceLabel string     `json:"device_label"`
+	Scopes      []string   `json:"scopes"`
+	RateLimit   int        `json:"rate_limit"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// validate checks the request, returning an error message if it is invalid
+func (req *CreateAPITokenRequest) validate() string {
+	req.Kind = strings.ToUpper(strings.TrimSpace(req.Kind))
+	if req.Kind == "" {
+		req.Kind = models.APITokenKindPersonal
+	}
+	if req.Kind == models.APITokenKindKiosk {
+		return req.validateKiosk()
+	}
+	if req.Kind != models.APITokenKindPersonal {
+		return "Invalid kind (use PERSONAL or KIOSK)"
+	}
+	if req.DeviceLabel != "" {
+		return "Device label only applies to kiosk tokens"
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return "Name is required"
+	}
+	if len(req.Name) > maxAPITokenNameLength {
+		return "Name is too long"
+	}
+	if len(req.Scopes) == 0 {
+		return "At least one scope is required"
+	}
+	seen := make(map[string]bool, len(req.Scopes))
+	scopes := req.Scopes[:0]
+	for _, scope := range req.Scopes {
+		if !middleware.APITokenScopes[scope] {
+			return "Unknown scope: " + scope
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	req.Scopes = scopes
+	if req.RateLimit == 0 {
+		req.RateLimit = defaultAPITokenLimit
+	}
+	if req.RateLimit < 1 || req.RateLimit > maxAPITokenRateLimit {
+		return "Rate limit must be between 1 and 1000 requests per minute"
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return "Expiry must be in the future"
+	}
+	return ""
+}
+
+// validateKiosk checks a kiosk token request. Kiosk tokens have no scopes and always
+// expire; the name defaults to the device label.
+func (req *CreateAPITokenRequest) validateKiosk() string {
+	req.DeviceLabel = strings.TrimSpace(req.DeviceLabel)
+	if req.DeviceLabel == "" {
+		return "Device label is required for kiosk tokens"
+	}
+	if len(req.DeviceLabel) > maxAPITokenNameLength {
+		return "Device label is too long"
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		req.Name = req.DeviceLabel
+	}
+	if len(req.Name) > maxAPITokenNameLength {
+		return "Name is too long"
+	}
+	if len(req.Scopes) > 0 {
+		return "Kiosk tokens can't be given scopes"
+	}
+	req.Scopes = []string{}
+	if req.RateLimit == 0 {
+		req.RateLimit = defaultAPITokenLimit
+	}
+	if req.RateLimit < 1 || req.RateLimit > maxAPITokenRateLimit {
+		return "Rate limit must be between 1 and 1000 requests per minute"
+	}
+
+	now := time.Now()
+	if req.ExpiresAt == nil {
+		expires := now.Add(defaultKioskTokenLifetime)
+		req.ExpiresAt = &expires
+	}
+	if !req.ExpiresAt.After(now) {
+		return "Expiry must be in the future"
+	}
+	if req.ExpiresAt.After(now.Add(maxKioskTokenLifetime)) {
+		return "Kiosk tokens can last at most five years"
+	}
+	return ""
+}
+
+// APITokenIntrospection describes the token a request was made with
+type APITokenIntrospection struct {
+	Active bool `json:"active"`
+	*models.APIToken
+}
+
+func (h *APITokenHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	tokens, err := h.apiTokenRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch API tokens")
+		return
+	}
+	if tokens == nil {
+		tokens = []*models.APIToken{}
+	}
+
+	JSON(w, http.StatusOK, tokens)
+}
+
+// Create issues a new token. The response is the only time the token itself is shown.
+func (h *APITokenHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	count, err := h.apiTokenRepo.CountByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to create API token")
+		return
+	}
+	if count >= maxAPITokens {
+		Error(w, http.StatusBadRequest, "Too many API tokens (20); revoke one first")
+		return
+	}
+
+	token := &models.APIToken{
+		UserID:      userID,
+		Name:        req.Name,
+		Kind:        req.Kind,
+		DeviceLabel: req.DeviceLabel,
+		Scopes:      req.Scopes,
+		RateLimit:   req.RateLimit,
+		ExpiresAt:   req.ExpiresAt,
+	}
+	if err := h.apiTokenRepo.Create(r.Context(), token); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to create API token")
+		return
+	}
+
+	JSON(w, http.StatusCreated, token)
+}
+
+// Delete revokes a token; requests made with it are refused straight away
+func (h *APITokenHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid token ID")
+		return
+	}
+
+	if err := h.apiTokenRepo.Delete(r.Context(), userID, id); err != nil {
+		if errors.Is(err, repository.ErrAPITokenNotFound) {
+			Error(w, http.StatusNotFound, "API token not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to revoke API token")
+		return
+	}
+
+	NoContent(w)
+}
+
+// Introspect describes the API token the request was made with, so a tool can check
+// which scopes it has been granted
+func (h *APITokenHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	token, ok := middleware.GetAPIToken(r.Context())
+	if !ok {
+		Error(w, http.StatusBadRequest, "Request was not made with an API token")
+		return
+	}
+
+	JSON(w, http.StatusOK, APITokenIntrospection{Active: true, APIToken: token})
+}
@@ -1,26 +1,51 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
 	"github.com/mark-regan/wellf/internal/repository"
 	"github.com/mark-regan/wellf/internal/services"
 )
 
+const (
+	// refreshBatchSize is how many symbols are refreshed per step of an async refresh
+	refreshBatchSize = 50
+
+	// Bounds for the points parameter of chart-sized history
+	minChartPoints = 10
+	maxChartPoints = 2000
+)
+
 type AssetHandler struct {
 	assetRepo    *repository.AssetRepository
 	yahooService *services.YahooService
+	taskService  *services.TaskService
+	noteRepo     *repository.AssetNoteRepository
 }
 
-func NewAssetHandler(assetRepo *repository.AssetRepository, yahooService *services.YahooService) *AssetHandler {
+func NewAssetHandler(assetRepo *repository.AssetRepository, yahooService *services.YahooService, taskService *services.TaskService, noteRepo *repository.AssetNoteRepository) *AssetHandler {
 	return &AssetHandler{
 		assetRepo:    assetRepo,
 		yahooService: yahooService,
+		taskService:  taskService,
+		noteRepo:     noteRepo,
 	}
 }
 
+// AssetDetailsResponse is the quote with the user's latest research note on the asset
+type AssetDetailsResponse struct {
+	*services.AssetDetails
+	LatestNote *models.AssetNote `json:"latest_note,omitempty"`
+}
+
 func (h *AssetHandler) Search(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 	if query == "" {
@@ -42,6 +67,34 @@ func (h *AssetHandler) Search(w http.ResponseWriter, r *http.Request) {
 	JSON(w, http.StatusOK, results)
 }
 
+// Lookup resolves a fund by ISIN or SEDOL (isin= or sedol=), adding it to the known
+// assets with its identifiers if it hasn't been seen before
+func (h *AssetHandler) Lookup(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("isin")
+	if code == "" {
+		code = r.URL.Query().Get("sedol")
+	}
+	if code == "" {
+		Error(w, http.StatusBadRequest, "isin or sedol parameter is required")
+		return
+	}
+
+	asset, err := h.yahooService.ResolveIdentifier(r.Context(), code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidIdentifier):
+			Error(w, http.StatusBadRequest, "Invalid ISIN or SEDOL")
+		case errors.Is(err, services.ErrIdentifierNotFound):
+			Error(w, http.StatusNotFound, "No asset found for this ISIN or SEDOL")
+		default:
+			Error(w, http.StatusBadGateway, "Failed to look up asset")
+		}
+		return
+	}
+
+	JSON(w, http.StatusOK, asset)
+}
+
 func (h *AssetHandler) GetDetails(w http.ResponseWriter, r *http.Request) {
 	symbol := chi.URLParam(r, "symbol")
 	if symbol == "" {
@@ -55,9 +108,21 @@ func (h *AssetHandler) GetDetails(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	JSON(w, http.StatusOK, details)
+	resp := AssetDetailsResponse{AssetDetails: details}
+	if userID, ok := middleware.GetUserID(r.Context()); ok {
+		note, err := h.noteRepo.GetLatest(r.Context(), userID, strings.ToUpper(symbol))
+		if err != nil {
+			Error(w, http.StatusInternalServerError, "Failed to fetch notes")
+			return
+		}
+		resp.LatestNote = note
+	}
+
+	JSON(w, http.StatusOK, resp)
 }
 
+// GetHistory returns price history for a period (period or range, default 1y). With
+// points=N the series is downsampled to at most N points for charting.
 func (h *AssetHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 	symbol := chi.URLParam(r, "symbol")
 	if symbol == "" {
@@ -66,6 +131,9 @@ func (h *AssetHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = r.URL.Query().Get("range")
+	}
 	if period == "" {
 		period = "1y"
 	}
@@ -80,6 +148,23 @@ func (h *AssetHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if v := r.URL.Query().Get("points"); v != "" {
+		points, err := strconv.Atoi(v)
+		if err != nil || points < minChartPoints || points > maxChartPoints {
+			Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid points (use %d-%d)", minChartPoints, maxChartPoints))
+			return
+		}
+
+		history, err := h.yahooService.GetChartHistory(r.Context(), symbol, period, points)
+		if err != nil {
+			Error(w, http.StatusInternalServerError, "Failed to fetch history")
+			return
+		}
+
+		JSON(w, http.StatusOK, history)
+		return
+	}
+
 	history, err := h.yahooService.GetHistory(r.Context(), symbol, period)
 	if err != nil {
 		Error(w, http.StatusInternalServerError, "Failed to fetch history")
@@ -106,6 +191,34 @@ func (h *AssetHandler) RefreshPrices(w http.ResponseWriter, r *http.Request) {
 		symbols[i] = a.Symbol
 	}
 
+	// With async=true the refresh runs in the background; poll /tasks/{id} for progress
+	if r.URL.Query().Get("async") == "true" {
+		userID, ok := middleware.GetUserID(r.Context())
+		if !ok {
+			Error(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		task, err := h.taskService.Start(r.Context(), userID, "price_refresh", func(ctx context.Context, progress *services.TaskProgress) (interface{}, error) {
+			for start := 0; start < len(symbols); start += refreshBatchSize {
+				end := min(start+refreshBatchSize, len(symbols))
+				progress.Step(ctx, "Refreshing prices", start, len(symbols))
+				if err := h.yahooService.RefreshPrices(ctx, symbols[start:end]); err != nil {
+					return nil, err
+				}
+			}
+			progress.Step(ctx, "Prices refreshed", len(symbols), len(symbols))
+			return map[string]int{"count": len(symbols)}, nil
+		})
+		if err != nil {
+			Error(w, http.StatusServiceUnavailable, "Unable to start refresh")
+			return
+		}
+
+		JSON(w, http.StatusAccepted, task)
+		return
+	}
+
 	if err := h.yahooService.RefreshPrices(r.Context(), symbols); err != nil {
 		Error(w, http.StatusInternalServerError, "Failed to refresh prices")
 		return
@@ -144,11 +257,24 @@ func (h *AssetHandler) GetQuotes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	sparkline := r.URL.Query().Get("sparkline")
+	if sparkline != "" && sparkline != "1d" && sparkline != "5d" {
+		Error(w, http.StatusBadRequest, "Invalid sparkline period (use 1d or 5d)")
+		return
+	}
+
 	quotes, err := h.yahooService.GetQuotes(r.Context(), cleanSymbols)
 	if err != nil {
 		Error(w, http.StatusInternalServerError, "Failed to fetch quotes")
 		return
 	}
 
+	// A missing sparkline shouldn't fail the quotes themselves
+	if sparkline != "" {
+		for i := range quotes {
+			quotes[i].Sparkline, _ = h.yahooService.GetSparkline(r.Context(), quotes[i].Symbol, sparkline)
+		}
+	}
+
 	JSON(w, http.StatusOK, quotes)
 }
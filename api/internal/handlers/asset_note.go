@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/pkg/validator"
+)
+
+const maxNoteLinks = 20
+
+type AssetNoteHandler struct {
+	noteRepo *repository.AssetNoteRepository
+}
+
+func NewAssetNoteHandler(noteRepo *repository.AssetNoteRepository) *AssetNoteHandler {
+	return &AssetNoteHandler{noteRepo: noteRepo}
+}
+
+type AssetNoteRequest struct {
+	EntryDate string   `json:"entry_date"` // YYYY-MM-DD, defaults to today
+	Title     string   `json:"title"`
+	Body      string   `json:"body"`
+	Links     []string `json:"links"`
+	Sentiment string   `json:"sentiment"` // BULLISH, NEUTRAL or BEARISH (default NEUTRAL)
+}
+
+// validate checks the request and fills in defaults, returning an error message if it
+// is invalid
+func (req *AssetNoteRequest) validate() (time.Time, string) {
+	req.Title = strings.TrimSpace(req.Title)
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Title == "" && req.Body == "" {
+		return time.Time{}, "Title or body is required"
+	}
+	if len(req.Title) > 255 {
+		return time.Time{}, "Title must be at most 255 characters"
+	}
+
+	entryDate := time.Now().UTC().Truncate(24 * time.Hour)
+	if req.EntryDate != "" {
+		d, err := parseDate(req.EntryDate)
+		if err != nil {
+			return time.Time{}, "Invalid entry date (use YYYY-MM-DD)"
+		}
+		entryDate = d
+	}
+
+	req.Sentiment = strings.ToUpper(strings.TrimSpace(req.Sentiment))
+	if req.Sentiment == "" {
+		req.Sentiment = models.NoteSentimentNeutral
+	}
+	if !validator.IsValidNoteSentiment(req.Sentiment) {
+		return time.Time{}, "Invalid sentiment (use BULLISH, NEUTRAL or BEARISH)"
+	}
+
+	if len(req.Links) > maxNoteLinks {
+		return time.Time{}, "Too many links"
+	}
+	links := []string{}
+	for _, link := range req.Links {
+		link = strings.TrimSpace(link)
+		if link == "" {
+			continue
+		}
+		u, err := url.ParseRequestURI(link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return time.Time{}, "Invalid link: " + link
+		}
+		links = append(links, link)
+	}
+	req.Links = links
+
+	return entryDate, ""
+}
+
+// List returns the user's journal for an asset, newest entry first
+func (h *AssetNoteHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	symbol := strings.ToUpper(chi.URLParam(r, "symbol"))
+	if symbol == "" {
+		Error(w, http.StatusBadRequest, "Symbol is required")
+		return
+	}
+
+	notes, err := h.noteRepo.GetBySymbol(r.Context(), userID, symbol)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch notes")
+		return
+	}
+
+	if notes == nil {
+		notes = []*models.AssetNote{}
+	}
+
+	JSON(w, http.StatusOK, notes)
+}
+
+func (h *AssetNoteHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	symbol := strings.ToUpper(chi.URLParam(r, "symbol"))
+	if symbol == "" || len(symbol) > 20 {
+		Error(w, http.StatusBadRequest, "Invalid symbol")
+		return
+	}
+
+	var req AssetNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	entryDate, msg := req.validate()
+	if msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	note := &models.AssetNote{
+		UserID:    userID,
+		Symbol:    symbol,
+		EntryDate: entryDate,
+		Title:     req.Title,
+		Body:      req.Body,
+		Links:     req.Links,
+		Sentiment: req.Sentiment,
+	}
+
+	if err := h.noteRepo.Create(r.Context(), note); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to create note")
+		return
+	}
+
+	JSON(w, http.StatusCreated, note)
+}
+
+func (h *AssetNoteHandler) Get(w http.ResponseWriter, r *http.Request) {
+	note, ok := h.ownedNote(w, r)
+	if !ok {
+		return
+	}
+
+	JSON(w, http.StatusOK, note)
+}
+
+// Update replaces a note's contents. The asset it is filed under cannot change.
+func (h *AssetNoteHandler) Update(w http.ResponseWriter, r *http.Request) {
+	note, ok := h.ownedNote(w, r)
+	if !ok {
+		return
+	}
+
+	var req AssetNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.EntryDate == "" {
+		req.EntryDate = note.EntryDate.Format("2006-01-02")
+	}
+	entryDate, msg := req.validate()
+	if msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	note.EntryDate = entryDate
+	note.Title = req.Title
+	note.Body = req.Body
+	note.Links = req.Links
+	note.Sentiment = req.Sentiment
+
+	if err := h.noteRepo.Update(r.Context(), note); err != nil {
+		if errors.Is(err, repository.ErrAssetNoteNotFound) {
+			Error(w, http.StatusNotFound, "Note not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to update note")
+		return
+	}
+
+	JSON(w, http.StatusOK, note)
+}
+
+func (h *AssetNoteHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	note, ok := h.ownedNote(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.noteRepo.Delete(r.Context(), note.ID); err != nil {
+		if errors.Is(err, repository.ErrAssetNoteNotFound) {
+			Error(w, http.StatusNotFound, "Note not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to delete note")
+		return
+	}
+
+	NoContent(w)
+}
+
+// ownedNote loads the note from the URL, writing an error response if it is missing or not the user's
+func (h *AssetNoteHandler) ownedNote(w http.ResponseWriter, r *http.Request) (*models.AssetNote, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	noteID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid note ID")
+		return nil, false
+	}
+
+	note, err := h.noteRepo.GetByID(r.Context(), noteID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAssetNoteNotFound) {
+			Error(w, http.StatusNotFound, "Note not found")
+			return nil, false
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch note")
+		return nil, false
+	}
+
+	if note.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return nil, false
+	}
+
+	return note, true
+}
@@ -7,8 +7,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
 	"github.com/mark-regan/wellf/internal/services"
+	"github.com/mark-regan/wellf/pkg/taxyear"
 )
 
 // parseDate parses a date string in YYYY-MM-DD format
@@ -17,11 +21,28 @@ func parseDate(s string) (time.Time, error) {
 }
 
 type AuthHandler struct {
-	authService *services.AuthService
+	authService       *services.AuthService
+	onboardingService *services.OnboardingService
+	currencyService   *services.BaseCurrencyService
+	watchlistRepo     *repository.WatchlistRepository
 }
 
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService *services.AuthService, onboardingService *services.OnboardingService, currencyService *services.BaseCurrencyService, watchlistRepo *repository.WatchlistRepository) *AuthHandler {
+	return &AuthHandler{authService: authService, onboardingService: onboardingService, currencyService: currencyService, watchlistRepo: watchlistRepo}
+}
+
+// watchlistString returns the user's watchlist as comma-separated symbols, the form
+// the profile used before the watchlist had its own endpoints
+func (h *AuthHandler) watchlistString(r *http.Request, userID uuid.UUID) string {
+	items, err := h.watchlistRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		return ""
+	}
+	symbols := make([]string, len(items))
+	for i, item := range items {
+		symbols[i] = item.Symbol
+	}
+	return strings.Join(symbols, ",")
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -46,6 +67,10 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.BaseCurrency != "" && user.BaseCurrency == req.BaseCurrency {
+		h.onboardingService.CompleteStep(r.Context(), user.ID, models.OnboardingStepSetBaseCurrency)
+	}
+
 	JSON(w, http.StatusCreated, map[string]interface{}{
 		"message": "Account created successfully",
 		"user": map[string]interface{}{
@@ -139,8 +164,9 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 		"notify_price_alerts": user.NotifyPriceAlerts,
 		"notify_weekly":       user.NotifyWeekly,
 		"notify_monthly":      user.NotifyMonthly,
-		"watchlist":           user.Watchlist,
+		"watchlist":           h.watchlistString(r, user.ID),
 		"provider_lists":      user.ProviderLists,
+		"tax_jurisdiction":    user.TaxJurisdiction,
 		"is_admin":            user.IsAdmin,
 		"created_at":          user.CreatedAt,
 		"last_login_at":       user.LastLoginAt,
@@ -170,11 +196,25 @@ func (h *AuthHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 		NotifyMonthly     *bool    `json:"notify_monthly"`
 		Watchlist         *string  `json:"watchlist"`
 		ProviderLists     *string  `json:"provider_lists"`
+		TaxJurisdiction   string   `json:"tax_jurisdiction"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	if req.TaxJurisdiction != "" && !taxyear.IsValid(req.TaxJurisdiction) {
+		Error(w, http.StatusBadRequest, "Invalid tax jurisdiction (use "+strings.Join(taxyear.Codes(), ", ")+")")
+		return
+	}
+
+	// A new base currency goes through the full revaluation before the other fields
+	// are applied to the updated user
+	if req.BaseCurrency != "" {
+		if _, err := h.currencyService.Change(r.Context(), userID, req.BaseCurrency); err != nil && !errors.Is(err, services.ErrSameCurrency) {
+			baseCurrencyError(w, err)
+			return
+		}
+	}
 
 	user, err := h.authService.GetUser(r.Context(), userID)
 	if err != nil {
@@ -185,9 +225,6 @@ func (h *AuthHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	if req.DisplayName != "" {
 		user.DisplayName = req.DisplayName
 	}
-	if req.BaseCurrency != "" {
-		user.BaseCurrency = req.BaseCurrency
-	}
 	if req.DateFormat != "" {
 		user.DateFormat = req.DateFormat
 	}
@@ -228,17 +265,27 @@ func (h *AuthHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 		user.NotifyMonthly = *req.NotifyMonthly
 	}
 	if req.Watchlist != nil {
-		user.Watchlist = *req.Watchlist
+		if err := h.watchlistRepo.Replace(r.Context(), userID, parseWatchlist(*req.Watchlist)); err != nil {
+			Error(w, http.StatusInternalServerError, "Failed to update watchlist")
+			return
+		}
 	}
 	if req.ProviderLists != nil {
 		user.ProviderLists = *req.ProviderLists
 	}
+	if req.TaxJurisdiction != "" {
+		user.TaxJurisdiction = strings.ToUpper(req.TaxJurisdiction)
+	}
 
 	if err := h.authService.UpdateUser(r.Context(), user); err != nil {
 		Error(w, http.StatusInternalServerError, "Failed to update user")
 		return
 	}
 
+	if req.BaseCurrency != "" {
+		h.onboardingService.CompleteStep(r.Context(), userID, models.OnboardingStepSetBaseCurrency)
+	}
+
 	JSON(w, http.StatusOK, map[string]interface{}{
 		"id":                  user.ID,
 		"email":               user.Email,
@@ -255,8 +302,9 @@ func (h *AuthHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 		"notify_price_alerts": user.NotifyPriceAlerts,
 		"notify_weekly":       user.NotifyWeekly,
 		"notify_monthly":      user.NotifyMonthly,
-		"watchlist":           user.Watchlist,
+		"watchlist":           h.watchlistString(r, user.ID),
 		"provider_lists":      user.ProviderLists,
+		"tax_jurisdiction":    user.TaxJurisdiction,
 	})
 }
 
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/openbanking"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+type BankConnectionHandler struct {
+	connRepo      *repository.BankConnectionRepository
+	portfolioRepo *repository.PortfolioRepository
+	banking       *services.BankSyncService
+}
+
+func NewBankConnectionHandler(connRepo *repository.BankConnectionRepository, portfolioRepo *repository.PortfolioRepository, banking *services.BankSyncService) *BankConnectionHandler {
+	return &BankConnectionHandler{
+		connRepo:      connRepo,
+		portfolioRepo: portfolioRepo,
+		banking:       banking,
+	}
+}
+
+type CreateBankConnectionRequest struct {
+	PortfolioID   uuid.UUID `json:"portfolio_id"`
+	InstitutionID string    `json:"institution_id"`
+}
+
+// CreateBankConnectionResponse includes the link to the bank, where the user gives
+// consent before being sent back to complete the connection
+type CreateBankConnectionResponse struct {
+	Connection *models.BankConnection `json:"connection"`
+	Link       string                 `json:"link"`
+}
+
+// Institutions lists the banks that can be connected in ?country= (default GB)
+func (h *BankConnectionHandler) Institutions(w http.ResponseWriter, r *http.Request) {
+	if _, ok := middleware.GetUserID(r.Context()); !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	country := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("country")))
+	if country == "" {
+		country = "GB"
+	}
+	if len(country) != 2 {
+		Error(w, http.StatusBadRequest, "Invalid country (use a two-letter code, e.g. GB)")
+		return
+	}
+
+	institutions, err := h.banking.Institutions(r.Context(), country)
+	if err != nil {
+		bankError(w, err, "Failed to fetch banks")
+		return
+	}
+	if institutions == nil {
+		institutions = []openbanking.Institution{}
+	}
+
+	JSON(w, http.StatusOK, institutions)
+}
+
+func (h *BankConnectionHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	conns, err := h.connRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch bank connections")
+		return
+	}
+	if conns == nil {
+		conns = []*models.BankConnection{}
+	}
+
+	JSON(w, http.StatusOK, conns)
+}
+
+// Create starts connecting a bank to a CASH or SAVINGS portfolio
+func (h *BankConnectionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateBankConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.InstitutionID = strings.TrimSpace(req.InstitutionID)
+	if req.InstitutionID == "" {
+		Error(w, http.StatusBadRequest, "Institution ID is required")
+		return
+	}
+
+	portfolio, err := h.portfolioRepo.GetByID(r.Context(), req.PortfolioID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPortfolioNotFound) {
+			Error(w, http.StatusNotFound, "Portfolio not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+	if portfolio.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return
+	}
+	if portfolio.Type != models.PortfolioTypeCash && portfolio.Type != models.PortfolioTypeSavings {
+		Error(w, http.StatusBadRequest, "Banks can only be connected to CASH or SAVINGS portfolios")
+		return
+	}
+
+	conn, link, err := h.banking.Connect(r.Context(), portfolio, req.InstitutionID)
+	if err != nil {
+		if errors.Is(err, openbanking.ErrNotFound) {
+			Error(w, http.StatusBadRequest, "Bank not found")
+			return
+		}
+		bankError(w, err, "Failed to connect to the bank")
+		return
+	}
+
+	JSON(w, http.StatusCreated, CreateBankConnectionResponse{Connection: conn, Link: link})
+}
+
+func (h *BankConnectionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	conn, ok := h.ownedConnection(w, r)
+	if !ok {
+		return
+	}
+
+	JSON(w, http.StatusOK, conn)
+}
+
+// Complete links the bank's accounts once the user is back from giving consent,
+// creating a cash account for each and running the first sync
+func (h *BankConnectionHandler) Complete(w http.ResponseWriter, r *http.Request) {
+	conn, ok := h.ownedConnection(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.banking.Complete(r.Context(), conn); err != nil {
+		switch {
+		case errors.Is(err, services.ErrBankConsentPending):
+			Error(w, http.StatusConflict, "Consent has not been given at the bank yet")
+		case errors.Is(err, services.ErrBankConsentRefused):
+			Error(w, http.StatusConflict, "Consent was refused at the bank; connect again to retry")
+		default:
+			bankError(w, err, "Failed to complete the bank connection")
+		}
+		return
+	}
+
+	JSON(w, http.StatusOK, conn)
+}
+
+// Sync reads the connection's accounts now instead of waiting for the background sync
+func (h *BankConnectionHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	conn, ok := h.ownedConnection(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.banking.Sync(r.Context(), conn); err != nil {
+		if errors.Is(err, services.ErrBankConsentPending) {
+			Error(w, http.StatusConflict, "Complete the bank connection before syncing")
+			return
+		}
+		bankError(w, err, "Failed to sync with the bank")
+		return
+	}
+
+	JSON(w, http.StatusOK, conn)
+}
+
+// Delete withdraws consent and removes the connection. Its cash accounts are kept.
+func (h *BankConnectionHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	conn, ok := h.ownedConnection(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.banking.Disconnect(r.Context(), conn); err != nil {
+		if errors.Is(err, repository.ErrBankConnectionNotFound) {
+			Error(w, http.StatusNotFound, "Bank connection not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to delete bank connection")
+		return
+	}
+
+	NoContent(w)
+}
+
+// bankError writes the response for a failed call to the Open Banking provider
+func bankError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrOpenBankingDisabled):
+		Error(w, http.StatusServiceUnavailable, "Open Banking is not configured")
+	case errors.Is(err, services.ErrBankConnectionClosed):
+		Error(w, http.StatusConflict, "Bank access has expired; connect the bank again")
+	case errors.Is(err, openbanking.ErrRateLimited):
+		Error(w, http.StatusTooManyRequests, "The bank's daily limit on updates has been reached; try again tomorrow")
+	default:
+		Error(w, http.StatusBadGateway, message+"; try again later")
+	}
+}
+
+func (h *BankConnectionHandler) ownedConnection(w http.ResponseWriter, r *http.Request) (*models.BankConnection, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	connID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid bank connection ID")
+		return nil, false
+	}
+
+	conn, err := h.connRepo.GetByID(r.Context(), connID)
+	if err != nil {
+		if errors.Is(err, repository.ErrBankConnectionNotFound) {
+			Error(w, http.StatusNotFound, "Bank connection not found")
+			return nil, false
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch bank connection")
+		return nil, false
+	}
+
+	if conn.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return nil, false
+	}
+
+	return conn, true
+}
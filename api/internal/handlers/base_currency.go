@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+// ChangeBaseCurrency switches the user's base currency, revaluing their net worth
+// snapshots at historical FX rates and converting the FIRE target, and records the change
+func (h *AuthHandler) ChangeBaseCurrency(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		Currency string `json:"currency"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	change, err := h.currencyService.Change(r.Context(), userID, strings.ToUpper(strings.TrimSpace(req.Currency)))
+	if err != nil {
+		baseCurrencyError(w, err)
+		return
+	}
+
+	h.onboardingService.CompleteStep(r.Context(), userID, models.OnboardingStepSetBaseCurrency)
+
+	JSON(w, http.StatusOK, change)
+}
+
+// BaseCurrencyHistory lists the user's base currency changes, most recent first
+func (h *AuthHandler) BaseCurrencyHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	changes, err := h.currencyService.History(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch currency changes")
+		return
+	}
+
+	if changes == nil {
+		changes = []*models.BaseCurrencyChange{}
+	}
+
+	JSON(w, http.StatusOK, changes)
+}
+
+func baseCurrencyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidCurrency):
+		Error(w, http.StatusBadRequest, "Invalid currency")
+	case errors.Is(err, services.ErrSameCurrency):
+		Error(w, http.StatusBadRequest, "Currency is already your base currency")
+	case errors.Is(err, services.ErrFXUnavailable):
+		Error(w, http.StatusBadGateway, "Exchange rates are unavailable; try again later")
+	default:
+		Error(w, http.StatusInternalServerError, "Failed to change base currency")
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxBatchRequests = 50
+	batchPathPrefix  = "/api/v1/"
+	batchPath        = "/api/v1/batch"
+)
+
+type BatchHandler struct {
+	router http.Handler
+}
+
+// NewBatchHandler dispatches sub-requests through router, so each one goes through the
+// same middleware (auth, rate limiting, logging) as a normal request
+func NewBatchHandler(router http.Handler) *BatchHandler {
+	return &BatchHandler{router: router}
+}
+
+// BatchItem is one sub-request, e.g. {"method": "POST", "path": "/api/v1/goals", "body": {...}}
+type BatchItem struct {
+	ID     string          `json:"id,omitempty"` // echoed back to help clients match results
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+type BatchRequest struct {
+	Requests    []BatchItem `json:"requests"`
+	StopOnError bool        `json:"stop_on_error"`
+}
+
+// BatchResult is the outcome of one sub-request. Skipped is set for requests after a
+// failure when stop_on_error is true.
+type BatchResult struct {
+	ID      string          `json:"id,omitempty"`
+	Status  int             `json:"status"`
+	Body    json.RawMessage `json:"body,omitempty"`
+	Skipped bool            `json:"skipped,omitempty"`
+}
+
+type BatchResponse struct {
+	Results   []BatchResult `json:"results"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+}
+
+// Execute runs the sub-requests in order with the caller's credentials and returns the
+// status and body of each one
+func (h *BatchHandler) Execute(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.Requests) == 0 {
+		Error(w, http.StatusBadRequest, "At least one request is required")
+		return
+	}
+	if len(req.Requests) > maxBatchRequests {
+		Error(w, http.StatusBadRequest, "Too many requests in batch (maximum 50)")
+		return
+	}
+
+	for _, item := range req.Requests {
+		switch item.Method {
+		case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete:
+		default:
+			Error(w, http.StatusBadRequest, "Invalid method: "+item.Method)
+			return
+		}
+		if !strings.HasPrefix(item.Path, batchPathPrefix) || strings.HasPrefix(item.Path, batchPath) {
+			Error(w, http.StatusBadRequest, "Invalid path: "+item.Path)
+			return
+		}
+	}
+
+	// Sub-requests must be routed from scratch, so drop the outer request's route context
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, nil)
+
+	resp := BatchResponse{Results: make([]BatchResult, 0, len(req.Requests))}
+	failed := false
+	for _, item := range req.Requests {
+		if failed && req.StopOnError {
+			resp.Results = append(resp.Results, BatchResult{ID: item.ID, Skipped: true})
+			continue
+		}
+
+		result := h.dispatch(ctx, r, item)
+		if result.Status >= 400 {
+			resp.Failed++
+			failed = true
+		} else {
+			resp.Succeeded++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	JSON(w, http.StatusOK, resp)
+}
+
+func (h *BatchHandler) dispatch(ctx context.Context, outer *http.Request, item BatchItem) BatchResult {
+	sub, err := http.NewRequestWithContext(ctx, item.Method, item.Path, bytes.NewReader(item.Body))
+	if err != nil {
+		return BatchResult{ID: item.ID, Status: http.StatusBadRequest, Body: errorBody("Invalid request")}
+	}
+	sub.RemoteAddr = outer.RemoteAddr
+	sub.Header.Set("Authorization", outer.Header.Get("Authorization"))
+	sub.Header.Set("Content-Type", "application/json")
+	sub.Header.Set("Accept", "application/json")
+
+	rec := &batchRecorder{header: make(http.Header), status: http.StatusOK}
+	h.router.ServeHTTP(rec, sub)
+
+	result := BatchResult{ID: item.ID, Status: rec.status}
+	if body := bytes.TrimSpace(rec.body.Bytes()); len(body) > 0 {
+		if json.Valid(body) {
+			result.Body = body
+		} else {
+			// e.g. plain-text errors from the router
+			result.Body, _ = json.Marshal(string(body))
+		}
+	}
+	return result
+}
+
+func errorBody(message string) json.RawMessage {
+	body, _ := json.Marshal(ErrorResponse{Error: message})
+	return body
+}
+
+// batchRecorder captures a sub-request's response in memory
+type batchRecorder struct {
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+}
+
+func (rec *batchRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *batchRecorder) WriteHeader(code int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.status = code
+	rec.wroteHeader = true
+}
+
+func (rec *batchRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.body.Write(b)
+}
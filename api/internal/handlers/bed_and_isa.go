@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+	"github.com/mark-regan/wellf/pkg/taxyear"
+)
+
+const (
+	cgtAnnualExemptAmount = 3000 // 2024/25
+)
+
+// CGT rates on shares by income tax band (from 30 October 2024)
+var cgtRates = map[string]float64{
+	"basic":      18,
+	"higher":     24,
+	"additional": 24,
+}
+
+type BedAndISAHandler struct {
+	holdingRepo   *repository.HoldingRepository
+	portfolioRepo *repository.PortfolioRepository
+	txRepo        *repository.TransactionRepository
+	lots          *services.LotService
+	allowances    *services.AllowanceService
+	fx            *services.CurrencyService
+}
+
+func NewBedAndISAHandler(
+	holdingRepo *repository.HoldingRepository,
+	portfolioRepo *repository.PortfolioRepository,
+	txRepo *repository.TransactionRepository,
+	lots *services.LotService,
+	allowances *services.AllowanceService,
+	fx *services.CurrencyService,
+) *BedAndISAHandler {
+	return &BedAndISAHandler{
+		holdingRepo:   holdingRepo,
+		portfolioRepo: portfolioRepo,
+		txRepo:        txRepo,
+		lots:          lots,
+		allowances:    allowances,
+		fx:            fx,
+	}
+}
+
+type BedAndISARequest struct {
+	HoldingID      uuid.UUID `json:"holding_id"`
+	ISAPortfolioID uuid.UUID `json:"isa_portfolio_id"`
+	Quantity       *float64  `json:"quantity"` // defaults to the whole holding
+	Price          *float64  `json:"price"`    // defaults to the asset's last price
+	TaxBand        string    `json:"tax_band"` // basic, higher or additional (default basic)
+	OtherGains     float64   `json:"other_gains"`
+	Confirm        bool      `json:"confirm"`
+}
+
+type BedAndISAResponse struct {
+	Symbol                string  `json:"symbol"`
+	Name                  string  `json:"name"`
+	Quantity              float64 `json:"quantity"`
+	Price                 float64 `json:"price"`        // in the asset's currency
+	Proceeds              float64 `json:"proceeds"`     // in the GIA's currency, like the cost and gain
+	Subscription          float64 `json:"subscription"` // the repurchase in the ISA's currency
+	CostBasis             float64 `json:"cost_basis"`
+	Gain                  float64 `json:"gain"`
+	OtherGains            float64 `json:"other_gains"`
+	AnnualExemptAmount    float64 `json:"annual_exempt_amount"`
+	TaxableGain           float64 `json:"taxable_gain"`
+	CGTRate               float64 `json:"cgt_rate"`
+	EstimatedCGT          float64 `json:"estimated_cgt"`
+	TaxYear               string  `json:"tax_year"`
+	ISAAllowance          float64 `json:"isa_allowance"`
+	ISAContributed        float64 `json:"isa_contributed"`
+	ISAAllowanceRemaining float64 `json:"isa_allowance_remaining"`
+	MaxQuantity           float64 `json:"max_quantity"` // largest sale that fits the remaining allowance
+	FitsAllowance         bool    `json:"fits_allowance"`
+	Confirmed             bool    `json:"confirmed"`
+
+	// The SELL (GIA), TRANSFER_IN and BUY (ISA) transactions, planned or created
+	Transactions []*models.Transaction `json:"transactions"`
+}
+
+// BedAndISA previews selling a GIA holding and rebuying it inside an ISA: the gain and
+// estimated CGT on disposal (after other_gains already realised this tax year and the
+// annual exempt amount) and the ISA allowance left. With confirm=true the paired
+// SELL, TRANSFER_IN and BUY transactions are recorded and both holdings updated in one
+// database transaction.
+func (h *BedAndISAHandler) BedAndISA(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req BedAndISARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.TaxBand == "" {
+		req.TaxBand = "basic"
+	}
+	cgtRate, ok := cgtRates[req.TaxBand]
+	if !ok {
+		Error(w, http.StatusBadRequest, "Invalid tax band (use basic, higher or additional)")
+		return
+	}
+	if req.OtherGains < 0 {
+		Error(w, http.StatusBadRequest, "Other gains cannot be negative")
+		return
+	}
+
+	belongs, err := h.holdingRepo.BelongsToUser(r.Context(), req.HoldingID, userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to verify ownership")
+		return
+	}
+	if !belongs {
+		Error(w, http.StatusForbidden, "Access denied")
+		return
+	}
+	belongs, err = h.portfolioRepo.BelongsToUser(r.Context(), req.ISAPortfolioID, userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to verify ownership")
+		return
+	}
+	if !belongs {
+		Error(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	holding, err := h.holdingRepo.GetByID(r.Context(), req.HoldingID)
+	if err != nil {
+		if errors.Is(err, repository.ErrHoldingNotFound) {
+			Error(w, http.StatusNotFound, "Holding not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch holding")
+		return
+	}
+
+	gia, err := h.portfolioRepo.GetByID(r.Context(), holding.PortfolioID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+	if gia.Type != models.PortfolioTypeGIA {
+		Error(w, http.StatusBadRequest, "Holding must be in a GIA portfolio")
+		return
+	}
+
+	isa, err := h.portfolioRepo.GetByID(r.Context(), req.ISAPortfolioID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+	if isa.Type != models.PortfolioTypeISA && isa.Type != models.PortfolioTypeLISA {
+		Error(w, http.StatusBadRequest, "Target portfolio must be an ISA or LISA")
+		return
+	}
+
+	quantity := holding.Quantity
+	if req.Quantity != nil {
+		quantity = *req.Quantity
+	}
+	if quantity <= 0 || quantity > holding.Quantity {
+		Error(w, http.StatusBadRequest, "Quantity must be positive and no more than the holding")
+		return
+	}
+
+	var price float64
+	switch {
+	case req.Price != nil:
+		price = *req.Price
+	case holding.Asset.LastPrice != nil:
+		price = *holding.Asset.LastPrice
+	}
+	if price <= 0 {
+		Error(w, http.StatusBadRequest, "No current price for this asset; provide a price")
+		return
+	}
+
+	// The sale and repurchase are at the asset's price, converted at today's rate into
+	// the GIA's currency for the gain and the ISA's for the subscription
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	assetID := holding.AssetID
+	sell := &models.Transaction{
+		PortfolioID:     gia.ID,
+		AssetID:         &assetID,
+		TransactionType: models.TransactionTypeSell,
+		Quantity:        &quantity,
+		Price:           &price,
+		TotalAmount:     roundMoney(quantity * price),
+		Currency:        holding.Asset.Currency,
+		TransactionDate: today,
+		Notes:           "Bed and ISA: sold to rebuy in " + isa.Name,
+	}
+	buy := &models.Transaction{
+		PortfolioID:     isa.ID,
+		AssetID:         &assetID,
+		TransactionType: models.TransactionTypeBuy,
+		Quantity:        &quantity,
+		Price:           &price,
+		TotalAmount:     roundMoney(quantity * price),
+		Currency:        holding.Asset.Currency,
+		TransactionDate: today,
+		Notes:           "Bed and ISA: rebought from " + gia.Name,
+	}
+	if err := h.fx.ConvertTransaction(r.Context(), sell, gia.Currency); err != nil {
+		Error(w, http.StatusBadGateway, fmt.Sprintf("No %s to %s exchange rate; try again later", sell.Currency, gia.Currency))
+		return
+	}
+	if err := h.fx.ConvertTransaction(r.Context(), buy, isa.Currency); err != nil {
+		Error(w, http.StatusBadGateway, fmt.Sprintf("No %s to %s exchange rate; try again later", buy.Currency, isa.Currency))
+		return
+	}
+	proceeds := sell.PortfolioTotal()
+	subscription := buy.PortfolioTotal()
+	transfer := &models.Transaction{
+		PortfolioID:     isa.ID,
+		TransactionType: models.TransactionTypeTransferIn,
+		TotalAmount:     subscription,
+		Currency:        isa.Currency,
+		TransactionDate: today,
+		Notes:           "Bed and ISA: subscription from " + gia.Name,
+	}
+
+	// ISA subscriptions so far this tax year, across all ISAs (LISAs also have their own cap)
+	taxYear := taxyear.UKOf(now)
+
+	allowance, err := h.allowances.Status(r.Context(), isa, taxYear)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch contributions")
+		return
+	}
+	remaining := allowance.Remaining
+
+	costBasis := roundMoney(quantity * holding.AverageCost)
+	gain := proceeds - costBasis
+	taxableGain := math.Max(gain+req.OtherGains-cgtAnnualExemptAmount, 0)
+	// Only the part of the taxable gain arising from this sale
+	taxableGain = math.Min(taxableGain, math.Max(gain, 0))
+
+	maxQuantity := holding.Quantity
+	if unitPrice := buy.PortfolioUnitPrice(); unitPrice > 0 {
+		maxQuantity = math.Min(math.Floor(remaining/unitPrice*10000)/10000, holding.Quantity)
+	}
+
+	resp := BedAndISAResponse{
+		Symbol:                holding.Asset.Symbol,
+		Name:                  holding.Asset.Name,
+		Quantity:              quantity,
+		Price:                 price,
+		Proceeds:              proceeds,
+		Subscription:          subscription,
+		CostBasis:             costBasis,
+		Gain:                  roundMoney(gain),
+		OtherGains:            req.OtherGains,
+		AnnualExemptAmount:    cgtAnnualExemptAmount,
+		TaxableGain:           roundMoney(taxableGain),
+		CGTRate:               cgtRate,
+		EstimatedCGT:          roundMoney(taxableGain * cgtRate / 100),
+		TaxYear:               taxYear,
+		ISAAllowance:          allowance.Allowance,
+		ISAContributed:        allowance.Contributed,
+		ISAAllowanceRemaining: remaining,
+		MaxQuantity:           maxQuantity,
+		FitsAllowance:         subscription <= remaining,
+		Transactions:          []*models.Transaction{sell, transfer, buy},
+	}
+
+	if !req.Confirm {
+		JSON(w, http.StatusOK, resp)
+		return
+	}
+
+	if !resp.FitsAllowance {
+		Error(w, http.StatusBadRequest, "The repurchase exceeds the remaining ISA allowance; reduce the quantity")
+		return
+	}
+
+	if err := h.txRepo.CreateBedAndISA(r.Context(), sell, transfer, buy); err != nil {
+		if errors.Is(err, repository.ErrInsufficientHoldings) {
+			Error(w, http.StatusBadRequest, "Insufficient holdings: you don't have enough units to sell")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to record bed and ISA")
+		return
+	}
+	h.lots.Sync(r.Context(), gia.ID, assetID)
+	h.lots.Sync(r.Context(), isa.ID, assetID)
+
+	// The repurchase counts towards the ISA allowance
+	h.allowances.Sync(r.Context(), isa)
+
+	resp.Confirmed = true
+	JSON(w, http.StatusCreated, resp)
+}
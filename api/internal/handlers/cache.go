@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+// CacheHandler lets admins clear cached market data without restarting or flushing Redis
+type CacheHandler struct {
+	cacheService *services.CacheService
+	taskService  *services.TaskService
+}
+
+func NewCacheHandler(cacheService *services.CacheService, taskService *services.TaskService) *CacheHandler {
+	return &CacheHandler{
+		cacheService: cacheService,
+		taskService:  taskService,
+	}
+}
+
+// Namespaces lists the cache namespaces that can be invalidated
+func (h *CacheHandler) Namespaces(w http.ResponseWriter, r *http.Request) {
+	JSON(w, http.StatusOK, map[string][]string{"namespaces": services.CacheNamespaces()})
+}
+
+// Rebuild clears the whole cache and refills it in the background; poll /tasks/{id}
+// for progress
+func (h *CacheHandler) Rebuild(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.GetUserID(r.Context())
+
+	task, err := h.taskService.Start(r.Context(), userID, "cache_rebuild", h.cacheService.Rebuild)
+	if err != nil {
+		Error(w, http.StatusServiceUnavailable, "Unable to start cache rebuild")
+		return
+	}
+
+	JSON(w, http.StatusAccepted, task)
+}
+
+// Invalidate removes every cached entry in one namespace
+func (h *CacheHandler) Invalidate(w http.ResponseWriter, r *http.Request) {
+	namespace := strings.ToLower(chi.URLParam(r, "namespace"))
+
+	removed, err := h.cacheService.Invalidate(r.Context(), namespace)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownCacheNamespace) {
+			Error(w, http.StatusBadRequest, "Unknown cache namespace (use one of "+strings.Join(services.CacheNamespaces(), ", ")+")")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to invalidate cache")
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"namespace": namespace,
+		"removed":   removed,
+	})
+}
@@ -15,12 +15,14 @@ import (
 
 type CashAccountHandler struct {
 	cashRepo      *repository.CashAccountRepository
+	movementRepo  *repository.CashMovementRepository
 	portfolioRepo *repository.PortfolioRepository
 }
 
-func NewCashAccountHandler(cashRepo *repository.CashAccountRepository, portfolioRepo *repository.PortfolioRepository) *CashAccountHandler {
+func NewCashAccountHandler(cashRepo *repository.CashAccountRepository, movementRepo *repository.CashMovementRepository, portfolioRepo *repository.PortfolioRepository) *CashAccountHandler {
 	return &CashAccountHandler{
 		cashRepo:      cashRepo,
+		movementRepo:  movementRepo,
 		portfolioRepo: portfolioRepo,
 	}
 }
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+const maxCashMovementDescription = 255
+
+// cashMovementSigns gives the direction of the movement types that can be recorded
+// directly; transfers and adjustments are made through their own endpoints
+var cashMovementSigns = map[string]float64{
+	models.CashMovementDeposit:    1,
+	models.CashMovementWithdrawal: -1,
+	models.CashMovementInterest:   1,
+}
+
+type RecordCashMovementRequest struct {
+	MovementType string  `json:"movement_type"`
+	Amount       float64 `json:"amount"`
+	MovementDate string  `json:"movement_date"`
+	Description  string  `json:"description"`
+}
+
+// validate checks the request, returning an error message if it is invalid
+func (req *RecordCashMovementRequest) validate() string {
+	req.MovementType = strings.ToUpper(strings.TrimSpace(req.MovementType))
+	if _, ok := cashMovementSigns[req.MovementType]; !ok {
+		return "Invalid movement type (use DEPOSIT, WITHDRAWAL or INTEREST)"
+	}
+	return validateCashAmount(req.Amount, req.Description)
+}
+
+type CashTransferRequest struct {
+	ToAccountID  uuid.UUID `json:"to_account_id"`
+	Amount       float64   `json:"amount"`
+	MovementDate string    `json:"movement_date"`
+	Description  string    `json:"description"`
+}
+
+func validateCashAmount(amount float64, description string) string {
+	if amount <= 0 {
+		return "Amount must be positive"
+	}
+	if len(description) > maxCashMovementDescription {
+		return "Description is too long"
+	}
+	return ""
+}
+
+// parseMovementDate reads a YYYY-MM-DD date, defaulting to today. Future dates are refused.
+func parseMovementDate(value string) (time.Time, string) {
+	if value == "" {
+		return time.Now().UTC().Truncate(24 * time.Hour), ""
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, "Invalid date format (use YYYY-MM-DD)"
+	}
+	if date.After(time.Now()) {
+		return time.Time{}, "Movement date cannot be in the future"
+	}
+	return date, ""
+}
+
+// ownedCashAccount parses the accountId URL parameter and checks the account belongs
+// to the user, writing the error response if not
+func (h *CashAccountHandler) ownedCashAccount(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return uuid.Nil, false
+	}
+
+	accountID, err := uuid.Parse(chi.URLParam(r, "accountId"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid account ID")
+		return uuid.Nil, false
+	}
+
+	belongs, err := h.cashRepo.BelongsToUser(r.Context(), accountID, userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to verify ownership")
+		return uuid.Nil, false
+	}
+	if !belongs {
+		Error(w, http.StatusForbidden, "Access denied")
+		return uuid.Nil, false
+	}
+
+	return accountID, true
+}
+
+// Movements lists an account's ledger, newest first
+func (h *CashAccountHandler) Movements(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := h.ownedCashAccount(w, r)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	movements, total, err := h.movementRepo.GetByAccountID(r.Context(), accountID, perPage, (page-1)*perPage)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch movements")
+		return
+	}
+	if movements == nil {
+		movements = []*models.CashMovement{}
+	}
+
+	Paginated(w, movements, total, page, perPage)
+}
+
+// RecordMovement records a deposit, withdrawal or interest payment and updates the
+// account balance
+func (h *CashAccountHandler) RecordMovement(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := h.ownedCashAccount(w, r)
+	if !ok {
+		return
+	}
+
+	var req RecordCashMovementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+	date, msg := parseMovementDate(req.MovementDate)
+	if msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	movement := &models.CashMovement{
+		CashAccountID: accountID,
+		MovementType:  req.MovementType,
+		Amount:        cashMovementSigns[req.MovementType] * roundMoney(req.Amount),
+		MovementDate:  date,
+		Description:   strings.TrimSpace(req.Description),
+	}
+	if err := h.movementRepo.Record(r.Context(), movement); err != nil {
+		h.movementError(w, err)
+		return
+	}
+
+	JSON(w, http.StatusCreated, movement)
+}
+
+// Transfer moves money to another of the user's accounts in the same currency
+func (h *CashAccountHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := h.ownedCashAccount(w, r)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(r.Context())
+
+	var req CashTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := validateCashAmount(req.Amount, req.Description); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+	if req.ToAccountID == accountID {
+		Error(w, http.StatusBadRequest, "Cannot transfer to the same account")
+		return
+	}
+	date, msg := parseMovementDate(req.MovementDate)
+	if msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	belongs, err := h.cashRepo.BelongsToUser(r.Context(), req.ToAccountID, userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to verify ownership")
+		return
+	}
+	if !belongs {
+		Error(w, http.StatusBadRequest, "Destination account not found")
+		return
+	}
+
+	amount := roundMoney(req.Amount)
+	description := strings.TrimSpace(req.Description)
+	out := &models.CashMovement{
+		CashAccountID: accountID,
+		MovementType:  models.CashMovementTransferOut,
+		Amount:        -amount,
+		MovementDate:  date,
+		Description:   description,
+	}
+	in := &models.CashMovement{
+		CashAccountID: req.ToAccountID,
+		MovementType:  models.CashMovementTransferIn,
+		Amount:        amount,
+		MovementDate:  date,
+		Description:   description,
+	}
+	if err := h.movementRepo.Transfer(r.Context(), out, in); err != nil {
+		h.movementError(w, err)
+		return
+	}
+
+	JSON(w, http.StatusCreated, []*models.CashMovement{out, in})
+}
+
+func (h *CashAccountHandler) movementError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrInsufficientCash):
+		Error(w, http.StatusBadRequest, "Insufficient balance")
+	case errors.Is(err, repository.ErrCashCurrencyMismatch):
+		Error(w, http.StatusBadRequest, "Both accounts must be in the same currency")
+	case errors.Is(err, repository.ErrCashAccountNotFound):
+		Error(w, http.StatusNotFound, "Cash account not found")
+	default:
+		Error(w, http.StatusInternalServerError, "Failed to record movement")
+	}
+}
+
+// CashFlowMovementMonth sums one month of cash account movements in one currency.
+// Inflow is deposits and interest and Outflow withdrawals; Net is the change in the
+// balances, including transfers and adjustments.
+type CashFlowMovementMonth struct {
+	Month        string  `json:"month"` // YYYY-MM
+	Currency     string  `json:"currency"`
+	Deposits     float64 `json:"deposits"`
+	Withdrawals  float64 `json:"withdrawals"`
+	Interest     float64 `json:"interest"`
+	TransfersIn  float64 `json:"transfers_in"`
+	TransfersOut float64 `json:"transfers_out"`
+	Adjustments  float64 `json:"adjustments"`
+	Inflow       float64 `json:"inflow"`
+	Outflow      float64 `json:"outflow"`
+	Net          float64 `json:"net"`
+}
+
+type CashAccountFlowResponse struct {
+	Range  string                  `json:"range"`
+	From   string                  `json:"from"`
+	Months []CashFlowMovementMonth `json:"months"`
+}
+
+// CashFlow sums the cash account ledgers month by month over range (default 12m), in
+// each account's currency
+func (h *DashboardHandler) CashFlow(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	rangeStr := r.URL.Query().Get("range")
+	if rangeStr == "" {
+		rangeStr = "12m"
+	}
+	months, ok := parseReportRange(rangeStr)
+	if !ok {
+		Error(w, http.StatusBadRequest, "Invalid range (use e.g. 6m, 12m or 2y, up to 10y)")
+		return
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -(months - 1), 0)
+
+	totals, err := h.movementRepo.GetMonthlyTotalsByUserID(r.Context(), userID, from)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch cash movements")
+		return
+	}
+
+	rows := make(map[incomeMonthKey]*CashFlowMovementMonth)
+	for _, t := range totals {
+		key := incomeMonthKey{month: t.Month.Format("2006-01"), currency: t.Currency}
+		row, ok := rows[key]
+		if !ok {
+			row = &CashFlowMovementMonth{Month: key.month, Currency: key.currency}
+			rows[key] = row
+		}
+		switch t.MovementType {
+		case models.CashMovementDeposit:
+			row.Deposits += t.Total
+		case models.CashMovementWithdrawal:
+			// Money out is stored negative but reported as a positive amount
+			row.Withdrawals -= t.Total
+		case models.CashMovementInterest:
+			row.Interest += t.Total
+		case models.CashMovementTransferIn:
+			row.TransfersIn += t.Total
+		case models.CashMovementTransferOut:
+			row.TransfersOut -= t.Total
+		case models.CashMovementAdjustment:
+			row.Adjustments += t.Total
+		}
+		row.Net += t.Total
+	}
+
+	resp := CashAccountFlowResponse{
+		Range:  rangeStr,
+		From:   from.Format("2006-01-02"),
+		Months: make([]CashFlowMovementMonth, 0, len(rows)),
+	}
+	for _, row := range rows {
+		row.Inflow = roundMoney(row.Deposits + row.Interest)
+		row.Outflow = roundMoney(row.Withdrawals)
+		row.Deposits = roundMoney(row.Deposits)
+		row.Withdrawals = roundMoney(row.Withdrawals)
+		row.Interest = roundMoney(row.Interest)
+		row.TransfersIn = roundMoney(row.TransfersIn)
+		row.TransfersOut = roundMoney(row.TransfersOut)
+		row.Adjustments = roundMoney(row.Adjustments)
+		row.Net = roundMoney(row.Net)
+		resp.Months = append(resp.Months, *row)
+	}
+	sort.Slice(resp.Months, func(i, j int) bool {
+		a, b := resp.Months[i], resp.Months[j]
+		if a.Month != b.Month {
+			return a.Month < b.Month
+		}
+		return a.Currency < b.Currency
+	})
+
+	JSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// ChangeHistoryHandler serves the audit trail of portfolios, holdings and transactions.
+// History is kept after a record is deleted, so it can still be read to restore it.
+type ChangeHistoryHandler struct {
+	historyRepo *repository.ChangeHistoryRepository
+}
+
+func NewChangeHistoryHandler(historyRepo *repository.ChangeHistoryRepository) *ChangeHistoryHandler {
+	return &ChangeHistoryHandler{historyRepo: historyRepo}
+}
+
+func (h *ChangeHistoryHandler) Portfolio(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, repository.HistoryPortfolios, chi.URLParam(r, "id"))
+}
+
+func (h *ChangeHistoryHandler) Holding(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, repository.HistoryHoldings, chi.URLParam(r, "holdingId"))
+}
+
+func (h *ChangeHistoryHandler) Transaction(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, repository.HistoryTransactions, chi.URLParam(r, "txId"))
+}
+
+// list returns the record's changes newest first (limit=N, default 50). Only the
+// user's own changes are returned, so another user's record has an empty history.
+func (h *ChangeHistoryHandler) list(w http.ResponseWriter, r *http.Request, entityType, id string) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	entityID, err := uuid.Parse(id)
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	limit := defaultHistoryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxHistoryLimit {
+			Error(w, http.StatusBadRequest, "Invalid limit (use 1 to 500)")
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.historyRepo.GetByEntity(r.Context(), userID, entityType, entityID, limit)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch history")
+		return
+	}
+	if entries == nil {
+		entries = []*models.ChangeHistoryEntry{}
+	}
+
+	JSON(w, http.StatusOK, entries)
+}
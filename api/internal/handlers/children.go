@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+	"github.com/mark-regan/wellf/pkg/taxyear"
+)
+
+const (
+	defaultChildGrowthRate = 5.0 // % per year
+	childMaturityAge       = 18
+)
+
+type ChildrenHandler struct {
+	portfolioRepo *repository.PortfolioRepository
+	txRepo        *repository.TransactionRepository
+}
+
+func NewChildrenHandler(portfolioRepo *repository.PortfolioRepository, txRepo *repository.TransactionRepository) *ChildrenHandler {
+	return &ChildrenHandler{
+		portfolioRepo: portfolioRepo,
+		txRepo:        txRepo,
+	}
+}
+
+type ChildPortfolio struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Type  string    `json:"type"`
+	Value float64   `json:"value"`
+}
+
+// ChildTaxYear is the total paid in for a child in one tax year. The allowance only
+// applies to JISA contributions.
+type ChildTaxYear struct {
+	TaxYear            string   `json:"tax_year"`
+	Contributions      float64  `json:"contributions"`
+	JISAContributions  float64  `json:"jisa_contributions"`
+	JISAAllowance      float64  `json:"jisa_allowance,omitempty"`
+	AllowanceRemaining *float64 `json:"allowance_remaining,omitempty"`
+}
+
+type ChildProjection struct {
+	GrowthRate         float64 `json:"growth_rate"`
+	AnnualContribution float64 `json:"annual_contribution"`
+	MaturityDate       string  `json:"maturity_date"`
+	YearsRemaining     float64 `json:"years_remaining"`
+	ValueAtMaturity    float64 `json:"value_at_maturity"`
+	FutureContributed  float64 `json:"future_contributed"`
+}
+
+type ChildSummary struct {
+	Name          string           `json:"name"`
+	DateOfBirth   string           `json:"date_of_birth,omitempty"`
+	Age           *int             `json:"age,omitempty"`
+	TotalValue    float64          `json:"total_value"`
+	Portfolios    []ChildPortfolio `json:"portfolios"`
+	Contributions []ChildTaxYear   `json:"contributions"`
+	Projection    *ChildProjection `json:"projection,omitempty"`
+}
+
+// List groups JISA and other child savings portfolios (any portfolio with a child name)
+// per child, with contributions per tax year and a projected pot value at 18.
+// Query params: growth_rate (% per year, default 5) and annual_contribution (defaults
+// to the child's contributions in the last complete tax year).
+func (h *ChildrenHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	growthRate := defaultChildGrowthRate
+	if v := r.URL.Query().Get("growth_rate"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(rate) || rate < -50 || rate > 50 {
+			Error(w, http.StatusBadRequest, "Invalid growth rate")
+			return
+		}
+		growthRate = rate
+	}
+
+	var annualOverride *float64
+	if v := r.URL.Query().Get("annual_contribution"); v != "" {
+		amount, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) || amount < 0 {
+			Error(w, http.StatusBadRequest, "Invalid annual contribution")
+			return
+		}
+		annualOverride = &amount
+	}
+
+	portfolios, err := h.portfolioRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolios")
+		return
+	}
+
+	// Group child portfolios by child name; JISAs without a name share one group
+	children := make(map[string]*ChildSummary)
+	var order []string
+	childOf := make(map[uuid.UUID]*ChildSummary)
+	portfolioTypes := make(map[uuid.UUID]string)
+	var portfolioIDs []uuid.UUID
+
+	for _, p := range portfolios {
+		var name, dob string
+		if p.Metadata != nil {
+			name = strings.TrimSpace(p.Metadata.ChildName)
+			dob = p.Metadata.ChildDOB
+		}
+		if name == "" && p.Type != models.PortfolioTypeJISA {
+			continue
+		}
+		if name == "" {
+			name = "Unnamed child"
+		}
+
+		key := strings.ToLower(name)
+		child, exists := children[key]
+		if !exists {
+			child = &ChildSummary{Name: name, Portfolios: []ChildPortfolio{}, Contributions: []ChildTaxYear{}}
+			children[key] = child
+			order = append(order, key)
+		}
+		if child.DateOfBirth == "" && dob != "" {
+			child.DateOfBirth = dob
+		}
+
+		var value float64
+		if summary, err := h.portfolioRepo.GetSummary(r.Context(), p.ID); err == nil {
+			value = summary.TotalValue
+		}
+		child.Portfolios = append(child.Portfolios, ChildPortfolio{ID: p.ID, Name: p.Name, Type: p.Type, Value: value})
+		child.TotalValue += value
+
+		childOf[p.ID] = child
+		portfolioTypes[p.ID] = p.Type
+		portfolioIDs = append(portfolioIDs, p.ID)
+	}
+
+	contributions, err := h.txRepo.GetContributionsByTaxYear(r.Context(), portfolioIDs)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch contributions")
+		return
+	}
+
+	byTaxYear := make(map[*ChildSummary]map[string]*ChildTaxYear)
+	for _, c := range contributions {
+		child := childOf[c.PortfolioID]
+		if byTaxYear[child] == nil {
+			byTaxYear[child] = make(map[string]*ChildTaxYear)
+		}
+		year, ok := byTaxYear[child][c.TaxYear]
+		if !ok {
+			year = &ChildTaxYear{TaxYear: c.TaxYear}
+			byTaxYear[child][c.TaxYear] = year
+		}
+		year.Contributions += c.Total
+		if portfolioTypes[c.PortfolioID] == models.PortfolioTypeJISA {
+			year.JISAContributions += c.Total
+		}
+	}
+
+	now := time.Now()
+	currentTaxYear := taxyear.UK.Of(now).String()
+	previousTaxYear := taxyear.UK.Of(now).Prev().String()
+
+	result := make([]ChildSummary, 0, len(order))
+	for _, key := range order {
+		child := children[key]
+
+		for _, year := range byTaxYear[child] {
+			if year.JISAContributions > 0 || year.TaxYear == currentTaxYear {
+				year.JISAAllowance = services.JISAAnnualAllowance
+				remaining := math.Max(services.JISAAnnualAllowance-year.JISAContributions, 0)
+				year.AllowanceRemaining = &remaining
+			}
+			child.Contributions = append(child.Contributions, *year)
+		}
+		sort.Slice(child.Contributions, func(i, j int) bool {
+			return child.Contributions[i].TaxYear < child.Contributions[j].TaxYear
+		})
+
+		if dob, err := time.Parse("2006-01-02", child.DateOfBirth); err == nil {
+			age := ageOn(dob, now)
+			child.Age = &age
+
+			annual := 0.0
+			if annualOverride != nil {
+				annual = *annualOverride
+			} else if year, ok := byTaxYear[child][previousTaxYear]; ok {
+				annual = year.Contributions
+			} else if year, ok := byTaxYear[child][currentTaxYear]; ok {
+				annual = year.Contributions
+			}
+
+			child.Projection = projectChildPot(child.TotalValue, annual, growthRate, dob.AddDate(childMaturityAge, 0, 0), now)
+		}
+
+		result = append(result, *child)
+	}
+
+	JSON(w, http.StatusOK, result)
+}
+
+// projectChildPot compounds the current value and annual contributions until the maturity date
+func projectChildPot(currentValue, annualContribution, growthRate float64, maturity, now time.Time) *ChildProjection {
+	years := math.Max(maturity.Sub(now).Hours()/24/365.25, 0)
+	rate := growthRate / 100
+
+	value := currentValue * math.Pow(1+rate, years)
+	if rate == 0 {
+		value += annualContribution * years
+	} else {
+		value += annualContribution * (math.Pow(1+rate, years) - 1) / rate
+	}
+
+	return &ChildProjection{
+		GrowthRate:         growthRate,
+		AnnualContribution: annualContribution,
+		MaturityDate:       maturity.Format("2006-01-02"),
+		YearsRemaining:     math.Round(years*10) / 10,
+		ValueAtMaturity:    math.Round(value*100) / 100,
+		FutureContributed:  math.Round(annualContribution*years*100) / 100,
+	}
+}
+
+// ageOn returns the age in whole years of someone born on dob at time t
+func ageOn(dob, t time.Time) int {
+	age := t.Year() - dob.Year()
+	if t.Month() < dob.Month() || (t.Month() == dob.Month() && t.Day() < dob.Day()) {
+		age--
+	}
+	return age
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+	"github.com/mark-regan/wellf/pkg/taxyear"
+)
+
+// recurringLookback is how many complete months a contribution must repeat in to count
+// as recurring
+const recurringLookback = 3
+
+type ContributionHandler struct {
+	portfolioRepo *repository.PortfolioRepository
+	txRepo        *repository.TransactionRepository
+}
+
+func NewContributionHandler(portfolioRepo *repository.PortfolioRepository, txRepo *repository.TransactionRepository) *ContributionHandler {
+	return &ContributionHandler{
+		portfolioRepo: portfolioRepo,
+		txRepo:        txRepo,
+	}
+}
+
+// ScheduledPortfolio is one portfolio's regular contribution within a wrapper
+type ScheduledPortfolio struct {
+	ID               uuid.UUID `json:"id"`
+	Name             string    `json:"name"`
+	Type             string    `json:"type"`
+	Contributed      float64   `json:"contributed"`
+	RecurringMonthly float64   `json:"recurring_monthly"`
+}
+
+// WrapperSchedule suggests how much to pay into a tax wrapper each month to use the rest
+// of its allowance. SuggestedMonthly is the top-up on top of recurring contributions.
+type WrapperSchedule struct {
+	Wrapper            string               `json:"wrapper"` // ISA, LISA or SIPP
+	Allowance          float64              `json:"allowance"`
+	Contributed        float64              `json:"contributed"`
+	Remaining          float64              `json:"remaining"`
+	RecurringMonthly   float64              `json:"recurring_monthly"`
+	ProjectedRecurring float64              `json:"projected_recurring"`
+	SuggestedMonthly   float64              `json:"suggested_monthly"`
+	TotalMonthly       float64              `json:"total_monthly"`
+	OverAllowance      bool                 `json:"over_allowance"` // recurring contributions alone exceed the allowance
+	Portfolios         []ScheduledPortfolio `json:"portfolios"`
+}
+
+type ContributionScheduleResponse struct {
+	TaxYear    string            `json:"tax_year"`
+	TaxYearEnd string            `json:"tax_year_end"`
+	MonthsLeft int               `json:"months_left"`
+	Wrappers   []WrapperSchedule `json:"wrappers"`
+}
+
+// Schedule suggests a monthly contribution per wrapper (ISA, LISA and SIPP) that uses
+// the allowance remaining by the end of the tax year. Contributions repeated in each of
+// the last three complete months are treated as recurring and assumed to continue. The
+// ISA suggestion leaves room for the LISA allowance, which counts towards it.
+func (h *ContributionHandler) Schedule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	portfolios, err := h.portfolioRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolios")
+		return
+	}
+
+	now := time.Now().UTC()
+	current := taxyear.UK.Of(now)
+	taxYear, taxYearEnd := current.String(), current.To()
+	monthsLeft := int(math.Ceil(taxYearEnd.AddDate(0, 0, 1).Sub(now).Hours() / 24 / 30.44))
+	if monthsLeft < 1 {
+		monthsLeft = 1
+	}
+
+	var ids []uuid.UUID
+	for _, p := range portfolios {
+		if p.Type == models.PortfolioTypeISA || p.Type == models.PortfolioTypeLISA || p.Type == models.PortfolioTypeSIPP {
+			ids = append(ids, p.ID)
+		}
+	}
+
+	yearly, err := h.txRepo.GetContributionsByTaxYear(r.Context(), ids)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch contributions")
+		return
+	}
+	contributed := make(map[uuid.UUID]float64)
+	for _, c := range yearly {
+		if c.TaxYear == taxYear {
+			contributed[c.PortfolioID] += c.Total
+		}
+	}
+
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	monthly, err := h.txRepo.GetMonthlyContributions(r.Context(), ids, thisMonth.AddDate(0, -recurringLookback, 0))
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch contributions")
+		return
+	}
+	byMonth := make(map[uuid.UUID]map[string]float64)
+	for _, c := range monthly {
+		if byMonth[c.PortfolioID] == nil {
+			byMonth[c.PortfolioID] = make(map[string]float64)
+		}
+		byMonth[c.PortfolioID][c.Month.Format("2006-01")] += c.Total
+	}
+
+	wrappers := map[string]*WrapperSchedule{
+		models.PortfolioTypeISA:  {Wrapper: models.PortfolioTypeISA, Allowance: services.ISAAnnualAllowance},
+		models.PortfolioTypeLISA: {Wrapper: models.PortfolioTypeLISA, Allowance: services.LISAAnnualAllowance},
+		models.PortfolioTypeSIPP: {Wrapper: models.PortfolioTypeSIPP, Allowance: services.SIPPAnnualAllowance},
+	}
+	// Future recurring payments per wrapper; this month's is skipped if already paid
+	projected := make(map[string]float64)
+
+	for _, p := range portfolios {
+		wrapper, ok := wrappers[p.Type]
+		if !ok {
+			continue
+		}
+
+		// Recurring is the smallest monthly amount paid in each of the lookback months
+		recurring := 0.0
+		for i := 1; i <= recurringLookback; i++ {
+			amount := byMonth[p.ID][thisMonth.AddDate(0, -i, 0).Format("2006-01")]
+			if i == 1 || amount < recurring {
+				recurring = amount
+			}
+		}
+
+		payments := monthsLeft
+		if recurring > 0 && byMonth[p.ID][thisMonth.Format("2006-01")] >= recurring {
+			payments--
+		}
+		projected[p.Type] += recurring * float64(payments)
+
+		wrapper.Contributed += contributed[p.ID]
+		wrapper.RecurringMonthly += recurring
+		wrapper.Portfolios = append(wrapper.Portfolios, ScheduledPortfolio{
+			ID:               p.ID,
+			Name:             p.Name,
+			Type:             p.Type,
+			Contributed:      roundMoney(contributed[p.ID]),
+			RecurringMonthly: roundMoney(recurring),
+		})
+	}
+
+	isa, lisa, sipp := wrappers[models.PortfolioTypeISA], wrappers[models.PortfolioTypeLISA], wrappers[models.PortfolioTypeSIPP]
+
+	// LISA subscriptions use up the overall ISA allowance too
+	lisa.Remaining = math.Max(math.Min(lisa.Allowance-lisa.Contributed, isa.Allowance-isa.Contributed-lisa.Contributed), 0)
+	isa.Remaining = math.Max(isa.Allowance-isa.Contributed-lisa.Contributed, 0)
+	sipp.Remaining = math.Max(sipp.Allowance-sipp.Contributed, 0)
+
+	resp := ContributionScheduleResponse{
+		TaxYear:    taxYear,
+		TaxYearEnd: taxYearEnd.Format("2006-01-02"),
+		MonthsLeft: monthsLeft,
+		Wrappers:   []WrapperSchedule{},
+	}
+
+	for _, wrapper := range []*WrapperSchedule{isa, lisa, sipp} {
+		if len(wrapper.Portfolios) == 0 {
+			continue
+		}
+
+		target := wrapper.Remaining
+		if wrapper == isa && len(lisa.Portfolios) > 0 {
+			target = math.Max(target-lisa.Remaining, 0)
+		}
+
+		wrapper.ProjectedRecurring = roundMoney(projected[wrapper.Wrapper])
+		wrapper.OverAllowance = wrapper.ProjectedRecurring > target
+		wrapper.SuggestedMonthly = roundMoney(math.Max(target-wrapper.ProjectedRecurring, 0) / float64(monthsLeft))
+		wrapper.TotalMonthly = roundMoney(wrapper.RecurringMonthly + wrapper.SuggestedMonthly)
+		wrapper.Contributed = roundMoney(wrapper.Contributed)
+		wrapper.Remaining = roundMoney(wrapper.Remaining)
+		wrapper.RecurringMonthly = roundMoney(wrapper.RecurringMonthly)
+
+		resp.Wrappers = append(resp.Wrappers, *wrapper)
+	}
+
+	JSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+type CorporateActionHandler struct {
+	assetRepo  *repository.AssetRepository
+	actionRepo *repository.CorporateActionRepository
+	lots       *services.LotService
+	cache      *services.DashboardCache
+}
+
+func NewCorporateActionHandler(
+	assetRepo *repository.AssetRepository,
+	actionRepo *repository.CorporateActionRepository,
+	lots *services.LotService,
+	cache *services.DashboardCache,
+) *CorporateActionHandler {
+	return &CorporateActionHandler{
+		assetRepo:  assetRepo,
+		actionRepo: actionRepo,
+		lots:       lots,
+		cache:      cache,
+	}
+}
+
+type CreateCorporateActionRequest struct {
+	Symbol        string  `json:"symbol"`
+	ActionType    string  `json:"action_type"`
+	EffectiveDate string  `json:"effective_date"`
+	RatioFrom     float64 `json:"ratio_from"`
+	RatioTo       float64 `json:"ratio_to"`
+	NewSymbol     string  `json:"new_symbol"`
+	Notes         string  `json:"notes"`
+}
+
+// validate checks the request, returning an error message if it is invalid
+func (req *CreateCorporateActionRequest) validate() string {
+	req.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+	req.NewSymbol = strings.ToUpper(strings.TrimSpace(req.NewSymbol))
+	req.ActionType = strings.ToUpper(req.ActionType)
+
+	if req.Symbol == "" {
+		return "Symbol is required"
+	}
+	if req.EffectiveDate == "" {
+		return "Effective date is required"
+	}
+
+	switch req.ActionType {
+	case models.CorporateActionSplit, models.CorporateActionConsolidation:
+		if req.RatioFrom <= 0 || req.RatioTo <= 0 {
+			return "Ratio from and ratio to must be positive"
+		}
+		if req.ActionType == models.CorporateActionSplit && req.RatioTo <= req.RatioFrom {
+			return "A split must increase the number of units (ratio to greater than ratio from)"
+		}
+		if req.ActionType == models.CorporateActionConsolidation && req.RatioTo >= req.RatioFrom {
+			return "A consolidation must reduce the number of units (ratio to less than ratio from)"
+		}
+	case models.CorporateActionTickerChange:
+		if req.NewSymbol == "" {
+			return "New symbol is required"
+		}
+		if len(req.NewSymbol) > 20 {
+			return "New symbol must be 20 characters or fewer"
+		}
+		if req.NewSymbol == req.Symbol {
+			return "New symbol must differ from the current symbol"
+		}
+	default:
+		return "Invalid action type (use SPLIT, CONSOLIDATION or TICKER_CHANGE)"
+	}
+	return ""
+}
+
+// List returns the corporate actions recorded against an asset
+func (h *CorporateActionHandler) List(w http.ResponseWriter, r *http.Request) {
+	asset, err := h.assetRepo.GetBySymbol(r.Context(), strings.ToUpper(chi.URLParam(r, "symbol")))
+	if err != nil {
+		if errors.Is(err, repository.ErrAssetNotFound) {
+			Error(w, http.StatusNotFound, "Asset not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch asset")
+		return
+	}
+
+	actions, err := h.actionRepo.GetByAssetID(r.Context(), asset.ID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch corporate actions")
+		return
+	}
+
+	if actions == nil {
+		actions = []*models.CorporateAction{}
+	}
+
+	JSON(w, http.StatusOK, actions)
+}
+
+// Create records a corporate action and applies it to every user's holdings,
+// transactions and stored prices for the asset
+func (h *CorporateActionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateCorporateActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	effective, err := time.Parse("2006-01-02", req.EffectiveDate)
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid effective date format (use YYYY-MM-DD)")
+		return
+	}
+
+	asset, err := h.assetRepo.GetBySymbol(r.Context(), req.Symbol)
+	if err != nil {
+		if errors.Is(err, repository.ErrAssetNotFound) {
+			Error(w, http.StatusNotFound, "Asset not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch asset")
+		return
+	}
+
+	action := &models.CorporateAction{
+		AssetID:       asset.ID,
+		ActionType:    req.ActionType,
+		EffectiveDate: effective,
+		CreatedBy:     &userID,
+	}
+	if req.ActionType == models.CorporateActionTickerChange {
+		action.OldSymbol = &asset.Symbol
+		action.NewSymbol = &req.NewSymbol
+	} else {
+		action.RatioFrom = &req.RatioFrom
+		action.RatioTo = &req.RatioTo
+	}
+	if notes := strings.TrimSpace(req.Notes); notes != "" {
+		action.Notes = &notes
+	}
+
+	result, err := h.actionRepo.Apply(r.Context(), action)
+	if err != nil {
+		if errors.Is(err, repository.ErrSymbolTaken) {
+			Error(w, http.StatusConflict, "An asset with the new symbol already exists")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to apply corporate action")
+		return
+	}
+
+	h.syncLots(r, asset.ID, result)
+	h.cache.InvalidateAll(r.Context())
+
+	JSON(w, http.StatusCreated, result)
+}
+
+// Revert undoes the latest corporate action on an asset and deletes it. Like Create, it
+// changes other users' holdings, so every cached dashboard is dropped.
+func (h *CorporateActionHandler) Revert(w http.ResponseWriter, r *http.Request) {
+	actionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid corporate action ID")
+		return
+	}
+
+	action, err := h.actionRepo.GetByID(r.Context(), actionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrCorporateActionNotFound) {
+			Error(w, http.StatusNotFound, "Corporate action not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch corporate action")
+		return
+	}
+
+	result, err := h.actionRepo.Revert(r.Context(), action)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrCorporateActionNotFound):
+			Error(w, http.StatusNotFound, "Corporate action not found")
+		case errors.Is(err, repository.ErrCorporateActionNotLatest):
+			Error(w, http.StatusConflict, "Only the latest corporate action on an asset can be reverted")
+		case errors.Is(err, repository.ErrSymbolTaken):
+			Error(w, http.StatusConflict, "An asset with the old symbol already exists")
+		default:
+			Error(w, http.StatusInternalServerError, "Failed to revert corporate action")
+		}
+		return
+	}
+
+	h.syncLots(r, action.AssetID, result)
+	h.cache.InvalidateAll(r.Context())
+
+	JSON(w, http.StatusOK, result)
+}
+
+// syncLots rebuilds the lots of every holding the action rescaled
+func (h *CorporateActionHandler) syncLots(r *http.Request, assetID uuid.UUID, result *models.CorporateActionResult) {
+	for _, portfolioID := range result.Portfolios {
+		h.lots.Sync(r.Context(), portfolioID, assetID)
+	}
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"math"
 	"net/http"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,9 +20,15 @@ type DashboardHandler struct {
 	holdingRepo     *repository.HoldingRepository
 	transactionRepo *repository.TransactionRepository
 	cashRepo        *repository.CashAccountRepository
+	movementRepo    *repository.CashMovementRepository
 	fixedAssetRepo  *repository.FixedAssetRepository
-	userRepo        *repository.UserRepository
+	snapshotRepo    *repository.SnapshotRepository
+	netWorthService *services.NetWorthService
 	yahooService    *services.YahooService
+	calendar        *services.MarketCalendar
+	catchUp         *services.CatchUpService
+	tagRepo         *repository.TagRepository
+	cache           *services.DashboardCache
 }
 
 func NewDashboardHandler(
@@ -27,18 +36,30 @@ func NewDashboardHandler(
 	holdingRepo *repository.HoldingRepository,
 	transactionRepo *repository.TransactionRepository,
 	cashRepo *repository.CashAccountRepository,
+	movementRepo *repository.CashMovementRepository,
 	fixedAssetRepo *repository.FixedAssetRepository,
-	userRepo *repository.UserRepository,
+	snapshotRepo *repository.SnapshotRepository,
+	netWorthService *services.NetWorthService,
 	yahooService *services.YahooService,
+	calendar *services.MarketCalendar,
+	catchUp *services.CatchUpService,
+	tagRepo *repository.TagRepository,
+	cache *services.DashboardCache,
 ) *DashboardHandler {
 	return &DashboardHandler{
 		portfolioRepo:   portfolioRepo,
 		holdingRepo:     holdingRepo,
 		transactionRepo: transactionRepo,
 		cashRepo:        cashRepo,
+		movementRepo:    movementRepo,
 		fixedAssetRepo:  fixedAssetRepo,
-		userRepo:        userRepo,
+		snapshotRepo:    snapshotRepo,
+		netWorthService: netWorthService,
 		yahooService:    yahooService,
+		calendar:        calendar,
+		catchUp:         catchUp,
+		tagRepo:         tagRepo,
+		cache:           cache,
 	}
 }
 
@@ -49,62 +70,87 @@ func (h *DashboardHandler) Summary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userRepo.GetByID(r.Context(), userID)
-	if err != nil {
-		Error(w, http.StatusInternalServerError, "Failed to fetch user")
+	var cached models.NetWorthSummary
+	if h.cache.Get(r.Context(), userID, services.DashboardViewSummary, &cached) {
+		JSON(w, http.StatusOK, &cached)
 		return
 	}
 
-	// Get all portfolios
-	portfolios, err := h.portfolioRepo.GetByUserID(r.Context(), userID)
+	summary, err := h.netWorthService.Summary(r.Context(), userID)
 	if err != nil {
-		Error(w, http.StatusInternalServerError, "Failed to fetch portfolios")
+		Error(w, http.StatusInternalServerError, "Failed to calculate net worth")
 		return
 	}
 
-	var investments float64
-	var cashFromPortfolios float64
-	var portfolioSummaries []models.PortfolioSummary
+	// Record today's valuation so performance charts can be served from snapshots.
+	// Failures are ignored as the snapshot is secondary to serving the summary.
+	_ = h.netWorthService.RecordSnapshot(r.Context(), userID, summary)
+	h.cache.Set(r.Context(), userID, services.DashboardViewSummary, summary)
 
-	for _, p := range portfolios {
-		summary, err := h.portfolioRepo.GetSummary(r.Context(), p.ID)
-		if err != nil {
-			continue
-		}
-		// CASH and SAVINGS portfolio values go to cash, not investments
-		if p.Type == models.PortfolioTypeCash || p.Type == models.PortfolioTypeSavings {
-			cashFromPortfolios += summary.TotalValue
-		} else {
-			investments += summary.TotalValue
+	JSON(w, http.StatusOK, summary)
+}
+
+// Changes summarises what changed since the user last caught up: net worth, holdings
+// whose price moved beyond threshold (percent, default 5), reminders that fell due and
+// transactions added. since (YYYY-MM-DD or RFC 3339) overrides the last view. The view
+// is then recorded unless mark_viewed=false.
+func (h *DashboardHandler) Changes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	threshold := services.DefaultCatchUpThresholdPct
+	if v := query.Get("threshold"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(t) || t <= 0 || t > 100 {
+			Error(w, http.StatusBadRequest, "Invalid threshold (use a percentage between 0 and 100)")
+			return
 		}
-		portfolioSummaries = append(portfolioSummaries, *summary)
+		threshold = t
 	}
 
-	// Get cash from cash_accounts (within investment portfolios)
-	cashFromAccounts, err := h.cashRepo.GetTotalByUserID(r.Context(), userID)
-	if err != nil {
-		cashFromAccounts = 0
+	var since time.Time
+	var firstView bool
+	if v := query.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", v)
+		}
+		if err != nil || t.After(time.Now()) {
+			Error(w, http.StatusBadRequest, "Invalid since (use a past YYYY-MM-DD date or RFC 3339 time)")
+			return
+		}
+		since = t
+	} else {
+		var err error
+		since, firstView, err = h.catchUp.LastViewed(r.Context(), userID)
+		if err != nil {
+			Error(w, http.StatusInternalServerError, "Failed to fetch last dashboard view")
+			return
+		}
 	}
-	cashTotal := cashFromPortfolios + cashFromAccounts
 
-	// Get fixed assets total
-	fixedAssetsTotal, err := h.fixedAssetRepo.GetTotalByUserID(r.Context(), userID)
+	changes, err := h.catchUp.Changes(r.Context(), userID, since, threshold)
 	if err != nil {
-		fixedAssetsTotal = 0
+		Error(w, http.StatusInternalServerError, "Failed to summarise changes")
+		return
 	}
+	changes.FirstView = firstView
 
-	summary := models.NetWorthSummary{
-		TotalNetWorth:    investments + cashTotal + fixedAssetsTotal,
-		Investments:      investments,
-		Cash:             cashTotal,
-		FixedAssets:      fixedAssetsTotal,
-		Currency:         user.BaseCurrency,
-		PortfolioSummary: portfolioSummaries,
+	if markViewed, err := strconv.ParseBool(query.Get("mark_viewed")); err != nil || markViewed {
+		// The summary has been served; failing to record the view only repeats it next time
+		_ = h.catchUp.MarkViewed(r.Context(), userID)
 	}
 
-	JSON(w, http.StatusOK, summary)
+	JSON(w, http.StatusOK, changes)
 }
 
+// Allocation breaks net worth down by asset type, currency and portfolio, and holdings
+// by tag. Values are in the user's base currency; the currency breakdown is by each
+// item's own currency.
 func (h *DashboardHandler) Allocation(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
@@ -112,81 +158,76 @@ func (h *DashboardHandler) Allocation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	portfolios, err := h.portfolioRepo.GetByUserID(r.Context(), userID)
+	var cached models.AssetAllocation
+	if h.cache.Get(r.Context(), userID, services.DashboardViewAllocation, &cached) {
+		JSON(w, http.StatusOK, cached)
+		return
+	}
+
+	summary, err := h.netWorthService.Summary(r.Context(), userID)
 	if err != nil {
 		Error(w, http.StatusInternalServerError, "Failed to fetch portfolios")
 		return
 	}
+	holdingTags, err := h.tagRepo.GetDomainTags(r.Context(), userID, models.TagDomainHolding)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch tags")
+		return
+	}
 
-	// Aggregate by asset type, currency, and portfolio
+	// Aggregate by asset type, currency, portfolio and holding tag
 	byType := make(map[string]float64)
 	byCurrency := make(map[string]float64)
 	byPortfolio := make(map[string]float64)
-
-	var totalValue float64
-
-	var cashTotal float64
-
-	for _, p := range portfolios {
-		// For CASH/SAVINGS portfolios, get balance from transactions
-		if p.Type == models.PortfolioTypeCash || p.Type == models.PortfolioTypeSavings {
-			summary, err := h.portfolioRepo.GetSummary(r.Context(), p.ID)
-			if err == nil && summary.TotalValue > 0 {
-				cashTotal += summary.TotalValue
-				totalValue += summary.TotalValue
-				byPortfolio[p.Name] = summary.TotalValue
-				byCurrency[p.Currency] += summary.TotalValue
+	byTag := make(map[string]float64)
+
+	for _, ps := range summary.PortfolioSummary {
+		switch ps.Type {
+		case models.PortfolioTypeFixedAssets:
+			// Fixed assets are broken down by category instead
+		case models.PortfolioTypeCash, models.PortfolioTypeSavings:
+			if ps.TotalValue > 0 {
+				byPortfolio[ps.Name] = ps.TotalValue
 			}
-			continue
+		default:
+			byPortfolio[ps.Name] = ps.TotalValue
 		}
+	}
 
-		// For investment portfolios, get holdings
-		holdings, err := h.holdingRepo.GetByPortfolioID(r.Context(), p.ID)
-		if err != nil {
+	var totalValue, holdingsValue float64
+	for _, item := range summary.Items {
+		value := item.Value.BaseAmount
+		if value <= 0 || item.Kind == models.ValuedItemLiability {
 			continue
 		}
 
-		var portfolioValue float64
-		for _, holding := range holdings {
-			if holding.CurrentValue != nil {
-				value := *holding.CurrentValue
-				totalValue += value
-				portfolioValue += value
-
-				if holding.Asset != nil {
-					byType[holding.Asset.AssetType] += value
-					byCurrency[holding.Asset.Currency] += value
-				}
+		switch item.Kind {
+		case models.ValuedItemHolding:
+			byType[item.Category] += value
+			holdingsValue += value
+			tags := holdingTags[item.ID]
+			if len(tags) == 0 {
+				tags = []string{"untagged"}
 			}
+			for _, tag := range tags {
+				byTag[tag] += value
+			}
+		case models.ValuedItemFixedAsset:
+			byType[item.Category] += value
+		default:
+			byType["CASH"] += value
 		}
-		byPortfolio[p.Name] = portfolioValue
-	}
-
-	// Add cash from cash_accounts (within investment portfolios)
-	cashFromAccounts, _ := h.cashRepo.GetTotalByUserID(r.Context(), userID)
-	if cashFromAccounts > 0 {
-		cashTotal += cashFromAccounts
-		totalValue += cashFromAccounts
-	}
-
-	// Add total cash to byType
-	if cashTotal > 0 {
-		byType["CASH"] = cashTotal
-	}
-
-	// Add fixed assets
-	fixedAssets, _ := h.fixedAssetRepo.GetByUserID(r.Context(), userID)
-	for _, fa := range fixedAssets {
-		byType[fa.Category] += fa.CurrentValue
-		byCurrency[fa.Currency] += fa.CurrentValue
-		totalValue += fa.CurrentValue
+		byCurrency[item.Value.Currency] += value
+		totalValue += value
 	}
 
 	allocation := models.AssetAllocation{
 		ByType:      mapToAllocationItems(byType, totalValue),
 		ByCurrency:  mapToAllocationItems(byCurrency, totalValue),
 		ByPortfolio: mapToAllocationItems(byPortfolio, totalValue),
+		ByTag:       mapToAllocationItems(byTag, holdingsValue),
 	}
+	h.cache.Set(r.Context(), userID, services.DashboardViewAllocation, allocation)
 
 	JSON(w, http.StatusOK, allocation)
 }
@@ -286,6 +327,60 @@ func (h *DashboardHandler) TopMovers(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// MarketState is the open/closed state of a market the user holds assets on
+type MarketState struct {
+	services.MarketStatus
+	Symbols []string `json:"symbols"`
+}
+
+// Markets returns whether each market the user's holdings trade on is open, with the
+// next open or close time. Holdings on exchanges without a calendar are listed under
+// unknown.
+func (h *DashboardHandler) Markets(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	holdings, err := h.holdingRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch holdings")
+		return
+	}
+
+	now := time.Now()
+	markets := []*MarketState{}
+	byCode := make(map[string]*MarketState)
+	seen := make(map[string]bool)
+	unknown := []string{}
+
+	for _, holding := range holdings {
+		if holding.Asset == nil || holding.Quantity <= 0 || seen[holding.Asset.Symbol] {
+			continue
+		}
+		seen[holding.Asset.Symbol] = true
+
+		market, ok := h.calendar.MarketFor(holding.Asset.Exchange)
+		if !ok {
+			unknown = append(unknown, holding.Asset.Symbol)
+			continue
+		}
+		state, exists := byCode[market.Code]
+		if !exists {
+			state = &MarketState{MarketStatus: market.Status(now), Symbols: []string{}}
+			byCode[market.Code] = state
+			markets = append(markets, state)
+		}
+		state.Symbols = append(state.Symbols, holding.Asset.Symbol)
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"markets": markets,
+		"unknown": unknown,
+	})
+}
+
 func sortTopMovers(movers []TopMover, ascending bool) {
 	for i := 0; i < len(movers); i++ {
 		for j := i + 1; j < len(movers); j++ {
@@ -319,9 +414,17 @@ type PortfolioPerformance struct {
 	ChangePct  float64                `json:"change_pct"`
 }
 
+// Performance data sources
+const (
+	PerformanceSourceSnapshots = "snapshots"
+	PerformanceSourcePrices    = "prices"
+)
+
 // PerformanceResponse contains the performance data for charting
 type PerformanceResponse struct {
 	Period     string                 `json:"period"`
+	Range      string                 `json:"range,omitempty"`
+	Source     string                 `json:"source"`
 	DataPoints []PerformanceDataPoint `json:"data_points"`
 	StartValue float64                `json:"start_value"`
 	EndValue   float64                `json:"end_value"`
@@ -338,14 +441,51 @@ func (h *DashboardHandler) Performance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	period := r.URL.Query().Get("period")
+	query := r.URL.Query()
+
+	// interval, granularity, from and to take precedence over the original period, start_date and end_date parameters
+	period := query.Get("interval")
+	if period == "" {
+		period = query.Get("granularity")
+	}
+	if period == "" {
+		period = query.Get("period")
+	}
 	if period == "" {
 		period = "daily"
 	}
 
-	portfolioIDStr := r.URL.Query().Get("portfolio_id")
-	startDateStr := r.URL.Query().Get("start_date")
-	endDateStr := r.URL.Query().Get("end_date")
+	// Errors name whichever of the two spellings was sent
+	startParam, endParam := "from", "to"
+	startDateStr := query.Get(startParam)
+	if startDateStr == "" {
+		startParam = "start_date"
+		startDateStr = query.Get(startParam)
+	}
+	endDateStr := query.Get(endParam)
+	if endDateStr == "" {
+		endParam = "end_date"
+		endDateStr = query.Get(endParam)
+	}
+
+	// Portfolio filter accepts a single portfolio_id or a comma-separated portfolio_ids list
+	var portfolioFilter []uuid.UUID
+	portfolioIDsParam := query.Get("portfolio_ids")
+	if portfolioIDStr := query.Get("portfolio_id"); portfolioIDStr != "" {
+		portfolioIDsParam = portfolioIDStr
+	}
+	for _, idStr := range strings.Split(portfolioIDsParam, ",") {
+		idStr = strings.TrimSpace(idStr)
+		if idStr == "" {
+			continue
+		}
+		portfolioID, err := uuid.Parse(idStr)
+		if err != nil {
+			Error(w, http.StatusBadRequest, "Invalid portfolio ID")
+			return
+		}
+		portfolioFilter = append(portfolioFilter, portfolioID)
+	}
 
 	// Determine date range and interval based on period
 	var startDate, endDate time.Time
@@ -356,7 +496,7 @@ func (h *DashboardHandler) Performance(w http.ResponseWriter, r *http.Request) {
 	if endDateStr != "" {
 		parsedEnd, err := time.Parse("2006-01-02", endDateStr)
 		if err != nil {
-			Error(w, http.StatusBadRequest, "Invalid end_date format. Use YYYY-MM-DD")
+			Error(w, http.StatusBadRequest, "Invalid "+endParam+" format. Use YYYY-MM-DD")
 			return
 		}
 		endDate = parsedEnd
@@ -364,14 +504,23 @@ func (h *DashboardHandler) Performance(w http.ResponseWriter, r *http.Request) {
 		endDate = now
 	}
 
-	// Parse custom start date if provided, otherwise calculate from period
+	// Parse custom start date if provided, otherwise calculate from range or period
+	rangeStr := query.Get("range")
 	if startDateStr != "" {
 		parsedStart, err := time.Parse("2006-01-02", startDateStr)
 		if err != nil {
-			Error(w, http.StatusBadRequest, "Invalid start_date format. Use YYYY-MM-DD")
+			Error(w, http.StatusBadRequest, "Invalid "+startParam+" format. Use YYYY-MM-DD")
 			return
 		}
 		startDate = parsedStart
+		rangeStr = ""
+	} else if rangeStr != "" {
+		months, ok := parseReportRange(rangeStr)
+		if !ok {
+			Error(w, http.StatusBadRequest, "Invalid range (use e.g. 1m, 6m, 1y or 5y, up to 10y)")
+			return
+		}
+		startDate = endDate.AddDate(0, -months, 0)
 	} else {
 		switch period {
 		case "daily":
@@ -421,25 +570,36 @@ func (h *DashboardHandler) Performance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Filter to specific portfolio if requested
-	if portfolioIDStr != "" {
-		portfolioID, err := uuid.Parse(portfolioIDStr)
-		if err != nil {
-			Error(w, http.StatusBadRequest, "Invalid portfolio ID")
-			return
+	// Filter to specific portfolios if requested
+	if len(portfolioFilter) > 0 {
+		owned := make(map[uuid.UUID]*models.Portfolio, len(portfolios))
+		for _, p := range portfolios {
+			owned[p.ID] = p
 		}
 		var filtered []*models.Portfolio
-		for _, p := range portfolios {
-			if p.ID == portfolioID {
-				filtered = append(filtered, p)
-				break
+		for _, id := range portfolioFilter {
+			p, ok := owned[id]
+			if !ok {
+				Error(w, http.StatusForbidden, "Access denied")
+				return
 			}
+			filtered = append(filtered, p)
 		}
-		if len(filtered) == 0 {
-			Error(w, http.StatusForbidden, "Access denied")
+		portfolios = filtered
+	}
+
+	// Prefer recorded snapshots when they cover the whole requested range
+	if earliest, err := h.snapshotRepo.GetEarliestDate(r.Context(), userID); err == nil && earliest != nil && !earliest.After(startDate) {
+		snapshots, err := h.snapshotRepo.GetByUserIDInRange(r.Context(), userID, startDate, endDate)
+		if err == nil && len(snapshots) > 0 {
+			response := performanceFromSnapshots(snapshots, portfolios, period)
+			response.Range = rangeStr
+			if len(portfolioFilter) == 1 {
+				response.Portfolios = nil
+			}
+			JSON(w, http.StatusOK, response)
 			return
 		}
-		portfolios = filtered
 	}
 
 	// Collect all holdings grouped by portfolio
@@ -480,6 +640,8 @@ func (h *DashboardHandler) Performance(w http.ResponseWriter, r *http.Request) {
 	if len(allPortfolioHoldings) == 0 {
 		JSON(w, http.StatusOK, PerformanceResponse{
 			Period:     period,
+			Range:      rangeStr,
+			Source:     PerformanceSourcePrices,
 			DataPoints: []PerformanceDataPoint{},
 		})
 		return
@@ -690,6 +852,8 @@ func (h *DashboardHandler) Performance(w http.ResponseWriter, r *http.Request) {
 
 	response := PerformanceResponse{
 		Period:     period,
+		Range:      rangeStr,
+		Source:     PerformanceSourcePrices,
 		DataPoints: totalDataPoints,
 		StartValue: startValue,
 		EndValue:   endValue,
@@ -697,8 +861,8 @@ func (h *DashboardHandler) Performance(w http.ResponseWriter, r *http.Request) {
 		ChangePct:  changePct,
 	}
 
-	// Only include individual portfolios when viewing all (no specific portfolio selected)
-	if portfolioIDStr == "" && len(portfolioPerformances) > 1 {
+	// Only include individual portfolios when viewing more than one portfolio
+	if len(portfolioFilter) != 1 && len(portfolioPerformances) > 1 {
 		response.Portfolios = portfolioPerformances
 	}
 
@@ -728,3 +892,162 @@ func getDateKey(t time.Time, period string) string {
 		return t.Format("2006-01-02")
 	}
 }
+
+// performanceFromSnapshots builds the chart series from recorded net worth snapshots.
+// Snapshots are ordered oldest first so the last snapshot in each bucket wins (end of period value).
+func performanceFromSnapshots(snapshots []*models.NetWorthSnapshot, portfolios []*models.Portfolio, period string) PerformanceResponse {
+	totalValues := make(map[string]float64)
+	portfolioValues := make(map[uuid.UUID]map[string]float64, len(portfolios))
+	for _, p := range portfolios {
+		portfolioValues[p.ID] = make(map[string]float64)
+	}
+
+	for _, s := range snapshots {
+		dateKey := getDateKey(s.SnapshotDate, period)
+		var total float64
+		for _, p := range portfolios {
+			value := s.PortfolioValues[p.ID]
+			portfolioValues[p.ID][dateKey] = value
+			total += value
+		}
+		totalValues[dateKey] = total
+	}
+
+	response := PerformanceResponse{
+		Period:     period,
+		Source:     PerformanceSourceSnapshots,
+		DataPoints: buildDataPoints(totalValues),
+	}
+	response.StartValue, response.EndValue, response.Change, response.ChangePct = summarisePoints(response.DataPoints)
+
+	if len(portfolios) > 1 {
+		for _, p := range portfolios {
+			pp := PortfolioPerformance{
+				ID:         p.ID.String(),
+				Name:       p.Name,
+				DataPoints: buildDataPoints(portfolioValues[p.ID]),
+			}
+			pp.StartValue, pp.EndValue, pp.Change, pp.ChangePct = summarisePoints(pp.DataPoints)
+			response.Portfolios = append(response.Portfolios, pp)
+		}
+	}
+
+	return response
+}
+
+// buildDataPoints converts a date key -> value map into date-sorted data points
+func buildDataPoints(values map[string]float64) []PerformanceDataPoint {
+	points := make([]PerformanceDataPoint, 0, len(values))
+	for dateKey, value := range values {
+		points = append(points, PerformanceDataPoint{Date: dateKey, Value: value})
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Date < points[j].Date
+	})
+	return points
+}
+
+// summarisePoints returns the start value, end value, change and change percentage of a series
+func summarisePoints(points []PerformanceDataPoint) (startValue, endValue, change, changePct float64) {
+	if len(points) == 0 {
+		return 0, 0, 0, 0
+	}
+	startValue = points[0].Value
+	endValue = points[len(points)-1].Value
+	change = endValue - startValue
+	if startValue > 0 {
+		changePct = (change / startValue) * 100
+	}
+	return startValue, endValue, change, changePct
+}
+
+// NetWorthHistoryPoint is one day's recorded net worth
+type NetWorthHistoryPoint struct {
+	Date        string                `json:"date"`
+	Total       float64               `json:"total"`
+	Investments float64               `json:"investments"`
+	Cash        float64               `json:"cash"`
+	FixedAssets float64               `json:"fixed_assets"`
+	Liabilities float64               `json:"liabilities"`
+	Portfolios  map[uuid.UUID]float64 `json:"portfolios"`
+}
+
+type NetWorthHistoryResponse struct {
+	Range      string                 `json:"range"`
+	From       string                 `json:"from"`
+	To         string                 `json:"to"`
+	Currency   string                 `json:"currency"`
+	Points     []NetWorthHistoryPoint `json:"points"`
+	StartValue float64                `json:"start_value"`
+	EndValue   float64                `json:"end_value"`
+	Change     float64                `json:"change"`
+	ChangePct  float64                `json:"change_pct"`
+}
+
+// History returns the recorded daily net worth snapshots for a range (e.g. 6m, 1y or
+// 5y, up to 10y, or max; default 1y), oldest first
+func (h *DashboardHandler) History(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	rangeStr := r.URL.Query().Get("range")
+	if rangeStr == "" {
+		rangeStr = "1y"
+	}
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var from time.Time
+	if rangeStr != "max" {
+		months, ok := parseReportRange(rangeStr)
+		if !ok {
+			Error(w, http.StatusBadRequest, "Invalid range (use e.g. 6m, 1y or 5y, up to 10y, or max)")
+			return
+		}
+		from = to.AddDate(0, -months, 0)
+	}
+
+	snapshots, err := h.snapshotRepo.GetByUserIDInRange(r.Context(), userID, from, to)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch snapshots")
+		return
+	}
+
+	resp := NetWorthHistoryResponse{
+		Range:  rangeStr,
+		To:     to.Format("2006-01-02"),
+		Points: make([]NetWorthHistoryPoint, 0, len(snapshots)),
+	}
+	if !from.IsZero() {
+		resp.From = from.Format("2006-01-02")
+	} else if len(snapshots) > 0 {
+		resp.From = snapshots[0].SnapshotDate.Format("2006-01-02")
+	}
+
+	for _, s := range snapshots {
+		resp.Points = append(resp.Points, NetWorthHistoryPoint{
+			Date:        s.SnapshotDate.Format("2006-01-02"),
+			Total:       s.TotalNetWorth,
+			Investments: s.Investments,
+			Cash:        s.Cash,
+			FixedAssets: s.FixedAssets,
+			Liabilities: s.Liabilities,
+			Portfolios:  s.PortfolioValues,
+		})
+		resp.Currency = s.Currency
+	}
+
+	if n := len(resp.Points); n > 0 {
+		resp.StartValue = resp.Points[0].Total
+		resp.EndValue = resp.Points[n-1].Total
+		resp.Change = resp.EndValue - resp.StartValue
+		if resp.StartValue > 0 {
+			resp.ChangePct = resp.Change / resp.StartValue * 100
+		}
+	}
+
+	JSON(w, http.StatusOK, resp)
+}
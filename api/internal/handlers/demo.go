@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+type DemoHandler struct {
+	authService *services.AuthService
+	userEmail   string
+}
+
+func NewDemoHandler(authService *services.AuthService, userEmail string) *DemoHandler {
+	return &DemoHandler{authService: authService, userEmail: userEmail}
+}
+
+// Login signs the caller in as the shared demo user
+func (h *DemoHandler) Login(w http.ResponseWriter, r *http.Request) {
+	tokens, user, err := h.authService.DemoLogin(r.Context(), h.userEmail)
+	if err != nil {
+		Error(w, http.StatusServiceUnavailable, "Demo is not available")
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"token_type":    tokens.TokenType,
+		"demo":          true,
+		"user": map[string]interface{}{
+			"id":            user.ID,
+			"email":         user.Email,
+			"display_name":  user.DisplayName,
+			"base_currency": user.BaseCurrency,
+		},
+	})
+}
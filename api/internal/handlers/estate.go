@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mark-regan/wellf/internal/middleware"
+)
+
+// EstateAccount is one line of the estate summary
+type EstateAccount struct {
+	Category         string  `json:"category"` // PORTFOLIO, CASH_ACCOUNT or FIXED_ASSET
+	Name             string  `json:"name"`
+	Type             string  `json:"type"`
+	Provider         string  `json:"provider,omitempty"`
+	AccountReference string  `json:"account_reference,omitempty"`
+	Contact          string  `json:"contact,omitempty"`
+	Value            float64 `json:"value"`
+	Currency         string  `json:"currency"`
+	Notes            string  `json:"notes,omitempty"`
+}
+
+// EstateSummary is the "in case of emergency" overview of everything the user holds
+type EstateSummary struct {
+	Owner       string          `json:"owner"`
+	Email       string          `json:"email"`
+	GeneratedAt time.Time       `json:"generated_at"`
+	Currency    string          `json:"currency"`
+	Masked      bool            `json:"masked"`
+	TotalValue  float64         `json:"total_value"`
+	Accounts    []EstateAccount `json:"accounts"`
+}
+
+// Estate generates the estate summary. Use format=html for a printable page and
+// mask=true to hide all but the last four characters of account references.
+func (h *ReportHandler) Estate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "html" {
+		Error(w, http.StatusBadRequest, "Invalid format (use json or html)")
+		return
+	}
+	mask := r.URL.Query().Get("mask") == "true"
+
+	user, err := h.userRepo.GetByID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+
+	portfolios, err := h.portfolioRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolios")
+		return
+	}
+
+	cashAccounts, err := h.cashRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch cash accounts")
+		return
+	}
+
+	fixedAssets, err := h.fixedAssetRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch fixed assets")
+		return
+	}
+
+	owner := user.DisplayName
+	if owner == "" {
+		owner = user.Email
+	}
+
+	summary := EstateSummary{
+		Owner:       owner,
+		Email:       user.Email,
+		GeneratedAt: time.Now().UTC(),
+		Currency:    user.BaseCurrency,
+		Masked:      mask,
+		Accounts:    []EstateAccount{},
+	}
+
+	portfolioNames := make(map[string]string, len(portfolios))
+	for _, p := range portfolios {
+		portfolioNames[p.ID.String()] = p.Name
+
+		account := EstateAccount{
+			Category: "PORTFOLIO",
+			Name:     p.Name,
+			Type:     p.Type,
+			Currency: p.Currency,
+			Notes:    p.Description,
+		}
+		if m := p.Metadata; m != nil {
+			account.Provider = firstNonEmpty(m.Provider, m.BankName, m.WalletName)
+			account.AccountReference = m.AccountReference
+			account.Contact = m.ContactName
+		}
+		if ps, err := h.portfolioRepo.GetSummary(r.Context(), p.ID); err == nil {
+			account.Value = ps.TotalValue
+		}
+		summary.Accounts = append(summary.Accounts, account)
+	}
+
+	for _, ca := range cashAccounts {
+		summary.Accounts = append(summary.Accounts, EstateAccount{
+			Category: "CASH_ACCOUNT",
+			Name:     ca.AccountName,
+			Type:     ca.AccountType,
+			Provider: ca.Institution,
+			Value:    ca.Balance,
+			Currency: ca.Currency,
+			Notes:    "Held in " + portfolioNames[ca.PortfolioID.String()],
+		})
+	}
+
+	for _, fa := range fixedAssets {
+		account := EstateAccount{
+			Category: "FIXED_ASSET",
+			Name:     fa.Name,
+			Type:     fa.Category,
+			Value:    fa.CurrentValue,
+			Currency: fa.Currency,
+			Notes:    fa.Description,
+		}
+		if fa.ValuationDate != nil {
+			account.Notes = strings.TrimSpace(account.Notes + " (valued " + fa.ValuationDate.Format("2006-01-02") + ")")
+		}
+		summary.Accounts = append(summary.Accounts, account)
+	}
+
+	for i := range summary.Accounts {
+		summary.TotalValue += summary.Accounts[i].Value
+		if mask {
+			summary.Accounts[i].AccountReference = maskReference(summary.Accounts[i].AccountReference)
+		}
+	}
+
+	if format == "json" {
+		JSON(w, http.StatusOK, summary)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = estateTemplate.Execute(w, summary)
+}
+
+// maskReference hides all but the last four characters of an account reference
+func maskReference(ref string) string {
+	if len(ref) <= 4 {
+		return strings.Repeat("*", len(ref))
+	}
+	return strings.Repeat("*", len(ref)-4) + ref[len(ref)-4:]
+}
+
+// formatMoney formats an amount with thousands separators, e.g. 12,345.67
+func formatMoney(v float64) string {
+	s := fmt.Sprintf("%.2f", v)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac := s[:len(s)-3], s[len(s)-3:]
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	return sign + whole + frac
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+var estateTemplate = template.Must(template.New("estate").Funcs(template.FuncMap{
+	"money": formatMoney,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Estate summary - {{.Owner}}</title>
+<style>
+body { font-family: Georgia, serif; margin: 2rem; color: #111; }
+h1 { margin-bottom: 0; }
+.meta { color: #555; margin-bottom: 1.5rem; }
+table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+th, td { border-bottom: 1px solid #ccc; padding: 0.4rem; text-align: left; vertical-align: top; }
+td.value, th.value { text-align: right; white-space: nowrap; }
+tfoot td { font-weight: bold; border-top: 2px solid #111; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>Estate summary</h1>
+<p class="meta">{{.Owner}} &lt;{{.Email}}&gt; &middot; generated {{.GeneratedAt.Format "2 January 2006 15:04 MST"}}{{if .Masked}} &middot; account references masked{{end}}</p>
+<table>
+<thead>
+<tr><th>Category</th><th>Name</th><th>Type</th><th>Provider</th><th>Reference</th><th>Contact</th><th>Notes</th><th class="value">Approx. value</th></tr>
+</thead>
+<tbody>
+{{range .Accounts}}<tr><td>{{.Category}}</td><td>{{.Name}}</td><td>{{.Type}}</td><td>{{.Provider}}</td><td>{{.AccountReference}}</td><td>{{.Contact}}</td><td>{{.Notes}}</td><td class="value">{{.Currency}} {{money .Value}}</td></tr>
+{{end}}</tbody>
+<tfoot>
+<tr><td colspan="7">Total (approximate, mixed currencies not converted)</td><td class="value">{{.Currency}} {{money .TotalValue}}</td></tr>
+</tfoot>
+</table>
+</body>
+</html>
+`))
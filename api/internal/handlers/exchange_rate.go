@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/pkg/validator"
+)
+
+type ExchangeRateHandler struct {
+	rateRepo *repository.ExchangeRateRepository
+}
+
+func NewExchangeRateHandler(rateRepo *repository.ExchangeRateRepository) *ExchangeRateHandler {
+	return &ExchangeRateHandler{rateRepo: rateRepo}
+}
+
+type ExchangeRatesResponse struct {
+	Base  string                 `json:"base"`
+	Rates []*models.ExchangeRate `json:"rates"`
+}
+
+// List returns the latest stored rate from the base currency (default GBP) into each
+// other currency
+func (h *ExchangeRateHandler) List(w http.ResponseWriter, r *http.Request) {
+	base := strings.ToUpper(r.URL.Query().Get("base"))
+	if base == "" {
+		base = "GBP"
+	}
+	if !validator.IsValidCurrency(base) {
+		Error(w, http.StatusBadRequest, "Invalid base currency")
+		return
+	}
+
+	rates, err := h.rateRepo.GetLatestFrom(r.Context(), base)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to get exchange rates")
+		return
+	}
+	if rates == nil {
+		rates = []*models.ExchangeRate{}
+	}
+
+	JSON(w, http.StatusOK, ExchangeRatesResponse{Base: base, Rates: rates})
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+const (
+	maxFireYears       = 60
+	maxFireSimulations = 10000
+)
+
+type FireHandler struct {
+	fire *services.FireService
+}
+
+func NewFireHandler(fire *services.FireService) *FireHandler {
+	return &FireHandler{fire: fire}
+}
+
+// Projection projects when investable net worth reaches the FIRE target. Query params
+// override the defaults: target, starting_value, monthly_contribution, expected_return
+// and volatility (% per year, real), years and simulations.
+func (h *FireHandler) Projection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	a, currency, err := h.fire.Defaults(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to calculate FIRE projection")
+		return
+	}
+
+	q := r.URL.Query()
+	floats := []struct {
+		param    string
+		dst      *float64
+		min, max float64
+		msg      string
+	}{
+		{"target", &a.Target, 0, 1e12, "Invalid target"},
+		{"starting_value", &a.StartingValue, 0, 1e12, "Invalid starting value"},
+		{"monthly_contribution", &a.MonthlyContribution, 0, 1e9, "Invalid monthly contribution"},
+		{"expected_return", &a.ExpectedReturn, -50, 50, "Invalid expected return"},
+		{"volatility", &a.Volatility, 0, 100, "Invalid volatility"},
+	}
+	for _, f := range floats {
+		if v := q.Get(f.param); v != "" {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil || math.IsNaN(n) || math.IsInf(n, 0) || n < f.min || n > f.max {
+				Error(w, http.StatusBadRequest, f.msg)
+				return
+			}
+			*f.dst = n
+		}
+	}
+	if v := q.Get("years"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxFireYears {
+			Error(w, http.StatusBadRequest, "Years must be between 1 and 60")
+			return
+		}
+		a.Years = n
+	}
+	if v := q.Get("simulations"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxFireSimulations {
+			Error(w, http.StatusBadRequest, "Simulations must be between 1 and 10000")
+			return
+		}
+		a.Simulations = n
+	}
+
+	if a.Target <= 0 {
+		Error(w, http.StatusBadRequest, "Set a FIRE target on your profile or pass target")
+		return
+	}
+
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	JSON(w, http.StatusOK, services.ProjectFire(a, currency, start, services.FireSeed(userID)))
+}
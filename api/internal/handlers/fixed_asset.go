@@ -11,15 +11,17 @@ import (
 	"github.com/mark-regan/wellf/internal/middleware"
 	"github.com/mark-regan/wellf/internal/models"
 	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
 	"github.com/mark-regan/wellf/pkg/validator"
 )
 
 type FixedAssetHandler struct {
 	fixedAssetRepo *repository.FixedAssetRepository
+	reminders      *services.ReminderService
 }
 
-func NewFixedAssetHandler(fixedAssetRepo *repository.FixedAssetRepository) *FixedAssetHandler {
-	return &FixedAssetHandler{fixedAssetRepo: fixedAssetRepo}
+func NewFixedAssetHandler(fixedAssetRepo *repository.FixedAssetRepository, reminders *services.ReminderService) *FixedAssetHandler {
+	return &FixedAssetHandler{fixedAssetRepo: fixedAssetRepo, reminders: reminders}
 }
 
 type CreateFixedAssetRequest struct {
@@ -109,6 +111,7 @@ func (h *FixedAssetHandler) Create(w http.ResponseWriter, r *http.Request) {
 		Error(w, http.StatusInternalServerError, "Failed to create fixed asset")
 		return
 	}
+	h.reminders.Sync(r.Context(), userID, models.ReminderSourceFixedAsset, asset.ID, asset.Description, asset.ValuationNotes)
 
 	JSON(w, http.StatusCreated, asset)
 }
@@ -243,6 +246,7 @@ func (h *FixedAssetHandler) Update(w http.ResponseWriter, r *http.Request) {
 		Error(w, http.StatusInternalServerError, "Failed to update fixed asset")
 		return
 	}
+	h.reminders.Sync(r.Context(), userID, models.ReminderSourceFixedAsset, asset.ID, asset.Description, asset.ValuationNotes)
 
 	JSON(w, http.StatusOK, asset)
 }
@@ -278,6 +282,7 @@ func (h *FixedAssetHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		Error(w, http.StatusInternalServerError, "Failed to delete fixed asset")
 		return
 	}
+	h.reminders.RemoveSource(r.Context(), models.ReminderSourceFixedAsset, assetID)
 
 	NoContent(w)
 }
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/pkg/taxyear"
+)
+
+// UK dividend tax rates (2024/25) used to cap foreign tax credit relief
+var dividendTaxRates = map[string]float64{
+	"basic":      8.75,
+	"higher":     33.75,
+	"additional": 39.35,
+}
+
+// Dividend withholding rates allowed under the UK's double taxation treaties for
+// portfolio investors. Countries not listed use defaultTreatyRate.
+var treatyWithholdingRates = map[string]float64{
+	"US": 15, "CA": 15, "FR": 15, "DE": 15, "NL": 15, "CH": 15, "IE": 15,
+	"BE": 10, "ES": 10, "IT": 10, "JP": 10, "DK": 15, "SE": 5, "NO": 15,
+	"FI": 0, "AU": 15, "NZ": 15, "HK": 0, "SG": 0,
+}
+
+const defaultTreatyRate = 15.0
+
+// ForeignTaxCountry is the foreign tax credit position for dividends from one country
+// in one currency, held outside tax wrappers
+type ForeignTaxCountry struct {
+	Country        string  `json:"country"`
+	Currency       string  `json:"currency"`
+	Dividends      int     `json:"dividends"`
+	GrossAmount    float64 `json:"gross_amount"`
+	WithholdingTax float64 `json:"withholding_tax"`
+	NetAmount      float64 `json:"net_amount"`
+	TreatyRate     float64 `json:"treaty_rate"`
+	CreditRelief   float64 `json:"credit_relief"`
+	ExcessTax      float64 `json:"excess_tax"` // withheld above the treaty rate; reclaim from the foreign tax authority
+}
+
+// ForeignTaxCreditResponse summarises foreign tax withheld on dividends for the
+// self-assessment foreign pages. Amounts are in each row's currency.
+type ForeignTaxCreditResponse struct {
+	TaxYear         string              `json:"tax_year"`
+	From            string              `json:"from"`
+	To              string              `json:"to"`
+	DividendTaxRate float64             `json:"dividend_tax_rate"`
+	Countries       []ForeignTaxCountry `json:"countries"`
+	// Tax withheld inside ISAs, LISAs, JISAs and SIPPs cannot be credited in the UK
+	ShelteredWithholdingTax float64 `json:"sheltered_withholding_tax"`
+	TotalGross              float64 `json:"total_gross"`
+	TotalWithholdingTax     float64 `json:"total_withholding_tax"`
+	TotalCreditRelief       float64 `json:"total_credit_relief"`
+	TotalExcessTax          float64 `json:"total_excess_tax"`
+}
+
+// parseTaxYear reads the tax_year query param (default current) in the user's tax
+// jurisdiction, writing an error response if it is invalid
+func parseTaxYear(w http.ResponseWriter, r *http.Request, user *models.User) (taxyear.Year, bool) {
+	return parseTaxYearParam(w, r, user, "tax_year")
+}
+
+// parseTaxYearParam is parseTaxYear for a differently named query param
+func parseTaxYearParam(w http.ResponseWriter, r *http.Request, user *models.User, param string) (taxyear.Year, bool) {
+	jurisdiction := taxyear.For(user.TaxJurisdiction)
+	year, ok := jurisdiction.Parse(r.URL.Query().Get(param))
+	if !ok {
+		Error(w, http.StatusBadRequest, "Invalid tax year (use e.g. "+jurisdiction.Example()+")")
+		return taxyear.Year{}, false
+	}
+	return year, true
+}
+
+// ForeignTaxCredit returns dividends taxed abroad in a tax year (tax_year=2024/25,
+// default current) with the foreign tax credit relief available on each. Relief is
+// the lowest of the tax withheld, the treaty rate and the UK dividend tax rate
+// (rate=basic, higher or additional; default basic) applied to the gross dividend.
+func (h *ReportHandler) ForeignTaxCredit(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+
+	year, ok := parseTaxYear(w, r, user)
+	if !ok {
+		return
+	}
+	from, to := year.From(), year.To()
+
+	band := r.URL.Query().Get("rate")
+	if band == "" {
+		band = "basic"
+	}
+	ukRate, ok := dividendTaxRates[band]
+	if !ok {
+		Error(w, http.StatusBadRequest, "Invalid rate (use basic, higher or additional)")
+		return
+	}
+
+	totals, err := h.txRepo.GetForeignDividendTotals(r.Context(), userID, from, to)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch dividends")
+		return
+	}
+
+	resp := ForeignTaxCreditResponse{
+		TaxYear:         year.String(),
+		From:            from.Format("2006-01-02"),
+		To:              to.Format("2006-01-02"),
+		DividendTaxRate: ukRate,
+		Countries:       []ForeignTaxCountry{},
+	}
+
+	for _, t := range totals {
+		if t.Sheltered {
+			resp.ShelteredWithholdingTax += t.WithholdingTax
+			continue
+		}
+
+		treatyRate, ok := treatyWithholdingRates[t.Country]
+		if !ok {
+			treatyRate = defaultTreatyRate
+		}
+		treatyTax := t.GrossAmount * treatyRate / 100
+		relief := math.Min(t.WithholdingTax, math.Min(treatyTax, t.GrossAmount*ukRate/100))
+
+		row := ForeignTaxCountry{
+			Country:        t.Country,
+			Currency:       t.Currency,
+			Dividends:      t.Count,
+			GrossAmount:    roundMoney(t.GrossAmount),
+			WithholdingTax: roundMoney(t.WithholdingTax),
+			NetAmount:      roundMoney(t.NetAmount),
+			TreatyRate:     treatyRate,
+			CreditRelief:   roundMoney(relief),
+			ExcessTax:      roundMoney(math.Max(t.WithholdingTax-treatyTax, 0)),
+		}
+		resp.Countries = append(resp.Countries, row)
+
+		resp.TotalGross += row.GrossAmount
+		resp.TotalWithholdingTax += row.WithholdingTax
+		resp.TotalCreditRelief += row.CreditRelief
+		resp.TotalExcessTax += row.ExcessTax
+	}
+
+	resp.ShelteredWithholdingTax = roundMoney(resp.ShelteredWithholdingTax)
+	resp.TotalGross = roundMoney(resp.TotalGross)
+	resp.TotalWithholdingTax = roundMoney(resp.TotalWithholdingTax)
+	resp.TotalCreditRelief = roundMoney(resp.TotalCreditRelief)
+	resp.TotalExcessTax = roundMoney(resp.TotalExcessTax)
+
+	JSON(w, http.StatusOK, resp)
+}
+
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -3,7 +3,10 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -14,21 +17,27 @@ import (
 	"github.com/mark-regan/wellf/internal/services"
 )
 
+// maxHoldingNotesLength caps the free-text notes kept on a holding
+const maxHoldingNotesLength = 500
+
 type HoldingHandler struct {
 	holdingRepo   *repository.HoldingRepository
 	portfolioRepo *repository.PortfolioRepository
 	yahooService  *services.YahooService
+	lots          *services.LotService
 }
 
 func NewHoldingHandler(
 	holdingRepo *repository.HoldingRepository,
 	portfolioRepo *repository.PortfolioRepository,
 	yahooService *services.YahooService,
+	lots *services.LotService,
 ) *HoldingHandler {
 	return &HoldingHandler{
 		holdingRepo:   holdingRepo,
 		portfolioRepo: portfolioRepo,
 		yahooService:  yahooService,
+		lots:          lots,
 	}
 }
 
@@ -118,6 +127,8 @@ func (h *HoldingHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.lots.Sync(r.Context(), portfolioID, asset.ID)
+
 	// Fetch the updated holding
 	holding, err := h.holdingRepo.GetByPortfolioAndAsset(r.Context(), portfolioID, asset.ID)
 	if err != nil {
@@ -156,11 +167,20 @@ func (h *HoldingHandler) Update(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Quantity    *float64 `json:"quantity"`
 		AverageCost *float64 `json:"average_cost"`
+		Notes       *string  `json:"notes"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	if req.Notes != nil {
+		notes := strings.TrimSpace(*req.Notes)
+		if len(notes) > maxHoldingNotesLength {
+			Error(w, http.StatusBadRequest, fmt.Sprintf("Notes must be at most %d characters", maxHoldingNotesLength))
+			return
+		}
+		req.Notes = &notes
+	}
 
 	holding, err := h.holdingRepo.GetByID(r.Context(), holdingID)
 	if err != nil {
@@ -191,10 +211,196 @@ func (h *HoldingHandler) Update(w http.ResponseWriter, r *http.Request) {
 		Error(w, http.StatusInternalServerError, "Failed to update holding")
 		return
 	}
+	if req.Notes != nil {
+		if err := h.holdingRepo.SetNotes(r.Context(), holding.ID, *req.Notes); err != nil {
+			Error(w, http.StatusInternalServerError, "Failed to update holding")
+			return
+		}
+		holding.Notes = *req.Notes
+	}
+	h.lots.Sync(r.Context(), holding.PortfolioID, holding.AssetID)
 
 	JSON(w, http.StatusOK, holding)
 }
 
+// BulkHoldingUpdate identifies an existing holding by ID or symbol. A quantity of
+// zero removes the holding.
+type BulkHoldingUpdate struct {
+	HoldingID   *uuid.UUID `json:"holding_id,omitempty"`
+	Symbol      string     `json:"symbol,omitempty"`
+	Quantity    *float64   `json:"quantity,omitempty"`
+	AverageCost *float64   `json:"average_cost,omitempty"`
+}
+
+type BulkUpdateHoldingsRequest struct {
+	Holdings []BulkHoldingUpdate `json:"holdings"`
+}
+
+type ValueChange struct {
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+}
+
+// HoldingChange describes what the bulk update did to a single holding
+type HoldingChange struct {
+	HoldingID   uuid.UUID    `json:"holding_id"`
+	Symbol      string       `json:"symbol"`
+	Action      string       `json:"action"` // updated, removed or unchanged
+	Quantity    *ValueChange `json:"quantity,omitempty"`
+	AverageCost *ValueChange `json:"average_cost,omitempty"`
+}
+
+type BulkUpdateHoldingsResponse struct {
+	Changes   []HoldingChange `json:"changes"`
+	Updated   int             `json:"updated"`
+	Removed   int             `json:"removed"`
+	Unchanged int             `json:"unchanged"`
+}
+
+// BulkUpdate applies a set of holding updates to a portfolio, e.g. when reconciling
+// against a broker statement. Either every update is applied or none are.
+func (h *HoldingHandler) BulkUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	portfolioID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid portfolio ID")
+		return
+	}
+
+	belongs, err := h.portfolioRepo.BelongsToUser(r.Context(), portfolioID, userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to verify ownership")
+		return
+	}
+	if !belongs {
+		Error(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	var req BulkUpdateHoldingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.Holdings) == 0 {
+		Error(w, http.StatusBadRequest, "No holdings provided")
+		return
+	}
+
+	existing, err := h.holdingRepo.GetByPortfolioID(r.Context(), portfolioID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch holdings")
+		return
+	}
+
+	byID := make(map[uuid.UUID]*models.Holding, len(existing))
+	bySymbol := make(map[string]*models.Holding, len(existing))
+	for _, holding := range existing {
+		byID[holding.ID] = holding
+		if holding.Asset != nil {
+			bySymbol[strings.ToUpper(holding.Asset.Symbol)] = holding
+		}
+	}
+
+	resp := BulkUpdateHoldingsResponse{Changes: make([]HoldingChange, 0, len(req.Holdings))}
+	var updates []*models.Holding
+	var removals []uuid.UUID
+	var changedAssets []uuid.UUID
+	seen := make(map[uuid.UUID]bool, len(req.Holdings))
+
+	for i, u := range req.Holdings {
+		var holding *models.Holding
+		switch {
+		case u.HoldingID != nil:
+			holding = byID[*u.HoldingID]
+		case u.Symbol != "":
+			holding = bySymbol[strings.ToUpper(u.Symbol)]
+		default:
+			ErrorWithDetails(w, http.StatusBadRequest, "Holding ID or symbol is required", fmt.Sprintf("holdings[%d]", i))
+			return
+		}
+		if holding == nil {
+			ErrorWithDetails(w, http.StatusBadRequest, "Holding not found in portfolio", fmt.Sprintf("holdings[%d]", i))
+			return
+		}
+		if seen[holding.ID] {
+			ErrorWithDetails(w, http.StatusBadRequest, "Holding appears more than once", fmt.Sprintf("holdings[%d]", i))
+			return
+		}
+		seen[holding.ID] = true
+
+		if u.Quantity == nil && u.AverageCost == nil {
+			ErrorWithDetails(w, http.StatusBadRequest, "Quantity or average cost is required", fmt.Sprintf("holdings[%d]", i))
+			return
+		}
+		if u.Quantity != nil && *u.Quantity < 0 {
+			ErrorWithDetails(w, http.StatusBadRequest, "Quantity cannot be negative", fmt.Sprintf("holdings[%d]", i))
+			return
+		}
+		if u.AverageCost != nil && *u.AverageCost < 0 {
+			ErrorWithDetails(w, http.StatusBadRequest, "Average cost cannot be negative", fmt.Sprintf("holdings[%d]", i))
+			return
+		}
+
+		change := HoldingChange{HoldingID: holding.ID, Action: "unchanged"}
+		if holding.Asset != nil {
+			change.Symbol = holding.Asset.Symbol
+		}
+
+		quantity, averageCost := holding.Quantity, holding.AverageCost
+		if u.Quantity != nil {
+			quantity = *u.Quantity
+		}
+		if u.AverageCost != nil {
+			averageCost = *u.AverageCost
+		}
+		if quantity != holding.Quantity {
+			change.Quantity = &ValueChange{Before: holding.Quantity, After: quantity}
+		}
+		if averageCost != holding.AverageCost {
+			change.AverageCost = &ValueChange{Before: holding.AverageCost, After: averageCost}
+		}
+
+		switch {
+		case quantity == 0:
+			change.Action = "removed"
+			removals = append(removals, holding.ID)
+			changedAssets = append(changedAssets, holding.AssetID)
+			resp.Removed++
+		case change.Quantity != nil || change.AverageCost != nil:
+			change.Action = "updated"
+			holding.Quantity = quantity
+			holding.AverageCost = averageCost
+			updates = append(updates, holding)
+			changedAssets = append(changedAssets, holding.AssetID)
+			resp.Updated++
+		default:
+			resp.Unchanged++
+		}
+
+		resp.Changes = append(resp.Changes, change)
+	}
+
+	if err := h.holdingRepo.BulkUpdate(r.Context(), portfolioID, updates, removals); err != nil {
+		if errors.Is(err, repository.ErrHoldingNotFound) {
+			Error(w, http.StatusConflict, "Holdings changed during update, please retry")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to update holdings")
+		return
+	}
+	for _, assetID := range changedAssets {
+		h.lots.Sync(r.Context(), portfolioID, assetID)
+	}
+
+	JSON(w, http.StatusOK, resp)
+}
+
 func (h *HoldingHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
@@ -218,6 +424,16 @@ func (h *HoldingHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	holding, err := h.holdingRepo.GetByID(r.Context(), holdingID)
+	if err != nil {
+		if errors.Is(err, repository.ErrHoldingNotFound) {
+			Error(w, http.StatusNotFound, "Holding not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch holding")
+		return
+	}
+
 	if err := h.holdingRepo.Delete(r.Context(), holdingID); err != nil {
 		if errors.Is(err, repository.ErrHoldingNotFound) {
 			Error(w, http.StatusNotFound, "Holding not found")
@@ -226,6 +442,7 @@ func (h *HoldingHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		Error(w, http.StatusInternalServerError, "Failed to delete holding")
 		return
 	}
+	h.lots.Sync(r.Context(), holding.PortfolioID, holding.AssetID)
 
 	NoContent(w)
 }
@@ -266,6 +483,103 @@ func (h *HoldingHandler) Get(w http.ResponseWriter, r *http.Request) {
 	JSON(w, http.StatusOK, holding)
 }
 
+// HoldingLotsResponse lists a holding's purchase lots with per-lot gain/loss
+type HoldingLotsResponse struct {
+	HoldingID       uuid.UUID            `json:"holding_id"`
+	CostBasisMethod string               `json:"cost_basis_method"`
+	Lots            []*models.HoldingLot `json:"lots"`
+	Quantity        float64              `json:"quantity"`
+	CostBasis       float64              `json:"cost_basis"`
+	UnrealisedGain  *float64             `json:"unrealised_gain,omitempty"`
+	RealisedGain    float64              `json:"realised_gain"`
+}
+
+// Lots builds the holding's lots from its transactions under the portfolio's cost basis
+// method and returns them, oldest first, including fully sold lots. Nothing is stored.
+// Gain/loss is valued at the asset's last price converted into the portfolio's currency.
+func (h *HoldingHandler) Lots(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	holdingID, err := uuid.Parse(chi.URLParam(r, "holdingId"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid holding ID")
+		return
+	}
+
+	belongs, err := h.holdingRepo.BelongsToUser(r.Context(), holdingID, userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to verify ownership")
+		return
+	}
+	if !belongs {
+		Error(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	holding, err := h.holdingRepo.GetByID(r.Context(), holdingID)
+	if err != nil {
+		if errors.Is(err, repository.ErrHoldingNotFound) {
+			Error(w, http.StatusNotFound, "Holding not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch holding")
+		return
+	}
+
+	lots, method, err := h.lots.Build(r.Context(), holding.PortfolioID, holding.AssetID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to build lots")
+		return
+	}
+
+	// lot costs are in the portfolio's currency, which the holding is valued in
+	var price *float64
+	if holding.CurrentValue != nil && holding.Quantity > 0 {
+		unitValue := *holding.CurrentValue / holding.Quantity
+		price = &unitValue
+	}
+
+	resp := HoldingLotsResponse{
+		HoldingID:       holding.ID,
+		CostBasisMethod: method,
+		Lots:            lots,
+	}
+	var unrealised float64
+	for _, lot := range lots {
+		lot.CostBasis = roundMoney(lot.RemainingQuantity * lot.UnitCost)
+		resp.Quantity += lot.RemainingQuantity
+		resp.CostBasis += lot.CostBasis
+		resp.RealisedGain += lot.RealisedGain
+
+		if price != nil && lot.RemainingQuantity > 0 {
+			value := roundMoney(lot.RemainingQuantity * *price)
+			gain := roundMoney(value - lot.CostBasis)
+			lot.CurrentValue = &value
+			lot.GainLoss = &gain
+			if lot.CostBasis > 0 {
+				pct := roundMoney(gain / lot.CostBasis * 100)
+				lot.GainLossPct = &pct
+			}
+			unrealised += gain
+		}
+	}
+	resp.CostBasis = roundMoney(resp.CostBasis)
+	resp.RealisedGain = roundMoney(resp.RealisedGain)
+	if price != nil {
+		unrealised = roundMoney(unrealised)
+		resp.UnrealisedGain = &unrealised
+	}
+	if resp.Lots == nil {
+		resp.Lots = []*models.HoldingLot{}
+	}
+
+	JSON(w, http.StatusOK, resp)
+}
+
 func (h *HoldingHandler) ListByPortfolio(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
@@ -295,6 +609,15 @@ func (h *HoldingHandler) ListByPortfolio(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if tag := normaliseTag(r.URL.Query().Get("tag")); tag != "" {
+		filtered := []*models.Holding{}
+		for _, holding := range holdings {
+			if slices.Contains(holding.Tags, tag) {
+				filtered = append(filtered, holding)
+			}
+		}
+		holdings = filtered
+	}
 	if holdings == nil {
 		holdings = []*models.Holding{}
 	}
@@ -302,7 +625,8 @@ func (h *HoldingHandler) ListByPortfolio(w http.ResponseWriter, r *http.Request)
 	JSON(w, http.StatusOK, holdings)
 }
 
-// ListAll returns all holdings for the authenticated user across all portfolios
+// ListAll returns all holdings for the authenticated user across all portfolios,
+// optionally only those with the given tag
 func (h *HoldingHandler) ListAll(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
@@ -316,6 +640,15 @@ func (h *HoldingHandler) ListAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if tag := normaliseTag(r.URL.Query().Get("tag")); tag != "" {
+		filtered := []*models.HoldingWithPortfolio{}
+		for _, holding := range holdings {
+			if slices.Contains(holding.Tags, tag) {
+				filtered = append(filtered, holding)
+			}
+		}
+		holdings = filtered
+	}
 	if holdings == nil {
 		holdings = []*models.HoldingWithPortfolio{}
 	}
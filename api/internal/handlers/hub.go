@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+const (
+	// maxQuickActionsPerKind keeps one busy source from crowding out the others
+	maxQuickActionsPerKind = 5
+	// stalePriceAge is how old a held asset's price can get before a refresh is offered
+	stalePriceAge = 24 * time.Hour
+)
+
+// HubHandler suggests one-tap actions worked out from the user's current data. Each
+// action is an ordinary API request, run through the router when executed.
+type HubHandler struct {
+	batch        *BatchHandler
+	reminderRepo *repository.ReminderRepository
+	webhookRepo  *repository.WebhookRepository
+	alertRepo    *repository.PriceAlertRepository
+	holdingRepo  *repository.HoldingRepository
+}
+
+func NewHubHandler(
+	batch *BatchHandler,
+	reminderRepo *repository.ReminderRepository,
+	webhookRepo *repository.WebhookRepository,
+	alertRepo *repository.PriceAlertRepository,
+	holdingRepo *repository.HoldingRepository,
+) *HubHandler {
+	return &HubHandler{
+		batch:        batch,
+		reminderRepo: reminderRepo,
+		webhookRepo:  webhookRepo,
+		alertRepo:    alertRepo,
+		holdingRepo:  holdingRepo,
+	}
+}
+
+// QuickAction is a suggested operation. Method, Path and Body are the request it makes;
+// Body is a prefilled payload a client may adjust before executing.
+type QuickAction struct {
+	ID          string          `json:"id"`
+	Title       string          `json:"title"`
+	Description string          `json:"description,omitempty"`
+	Method      string          `json:"method"`
+	Path        string          `json:"path"`
+	Body        json.RawMessage `json:"body,omitempty"`
+}
+
+// QuickActionResult is the outcome of executing an action
+type QuickActionResult struct {
+	Action QuickAction     `json:"action"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// QuickActions lists the actions that apply to the user right now
+func (h *HubHandler) QuickActions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	actions, err := h.quickActions(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to work out quick actions")
+		return
+	}
+
+	JSON(w, http.StatusOK, actions)
+}
+
+// Execute performs an action from the current list. A JSON body, if sent, replaces the
+// action's prefilled body. Actions that no longer apply are not found.
+func (h *HubHandler) Execute(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	actions, err := h.quickActions(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to work out quick actions")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	var action *QuickAction
+	for i := range actions {
+		if actions[i].ID == id {
+			action = &actions[i]
+			break
+		}
+	}
+	if action == nil {
+		Error(w, http.StatusNotFound, "Quick action not found or no longer applies")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(body) > 0 {
+		if !json.Valid(body) {
+			Error(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		action.Body = body
+	}
+
+	// The action must be routed from scratch, so drop this request's route context
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, nil)
+	result := h.batch.dispatch(ctx, r, BatchItem{Method: action.Method, Path: action.Path, Body: action.Body})
+
+	JSON(w, http.StatusOK, QuickActionResult{
+		Action: *action,
+		Status: result.Status,
+		Body:   result.Body,
+	})
+}
+
+// quickActions works out the user's actions: reminders that are due, dead webhook
+// deliveries, alerts switched off after triggering, and stale prices
+func (h *HubHandler) quickActions(ctx context.Context, userID uuid.UUID) ([]QuickAction, error) {
+	actions := []QuickAction{}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	reminders, err := h.reminderRepo.GetByUserID(ctx, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	n := 0
+	for _, rem := range reminders {
+		if rem.Completed || rem.DueDate.After(today) || n == maxQuickActionsPerKind {
+			continue
+		}
+		actions = append(actions, QuickAction{
+			ID:          "complete-reminder-" + rem.ID.String(),
+			Title:       "Done: " + rem.Text,
+			Description: "Due " + rem.DueDate.Format("2 Jan 2006"),
+			Method:      http.MethodPut,
+			Path:        batchPathPrefix + "reminders/" + rem.ID.String(),
+			Body:        json.RawMessage(`{"completed":true}`),
+		})
+		n++
+	}
+
+	deliveries, err := h.webhookRepo.GetDeliveriesByUserID(ctx, userID, models.WebhookDeliveryDead, maxQuickActionsPerKind)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deliveries {
+		actions = append(actions, QuickAction{
+			ID:          "redrive-delivery-" + d.ID.String(),
+			Title:       "Retry " + d.Event + " webhook",
+			Description: "Failed after " + plural(d.Attempts, "attempt"),
+			Method:      http.MethodPost,
+			Path:        batchPathPrefix + "webhooks/deliveries/" + d.ID.String() + "/redrive",
+		})
+	}
+
+	alerts, err := h.alertRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	n = 0
+	for _, a := range alerts {
+		if a.IsActive || a.LastTriggeredAt == nil || a.Condition == models.PriceAlertDailyMove || n == maxQuickActionsPerKind {
+			continue
+		}
+		symbol := a.AssetID.String()
+		if a.Asset != nil {
+			symbol = a.Asset.Symbol
+		}
+		actions = append(actions, QuickAction{
+			ID:          "rearm-alert-" + a.ID.String(),
+			Title:       fmt.Sprintf("Re-arm %s alert %s %g", symbol, a.Condition, a.Threshold),
+			Description: "Triggered " + a.LastTriggeredAt.Format("2 Jan 2006"),
+			Method:      http.MethodPut,
+			Path:        batchPathPrefix + "alerts/" + a.ID.String(),
+			Body:        json.RawMessage(`{"is_active":true}`),
+		})
+		n++
+	}
+
+	holdings, err := h.holdingRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	stale := make(map[string]bool)
+	for _, hold := range holdings {
+		a := hold.Asset
+		if a != nil && (a.LastPriceUpdatedAt == nil || time.Since(*a.LastPriceUpdatedAt) > stalePriceAge) {
+			stale[a.Symbol] = true
+		}
+	}
+	if len(stale) > 0 {
+		actions = append(actions, QuickAction{
+			ID:          "refresh-prices",
+			Title:       "Refresh prices",
+			Description: plural(len(stale), "holding") + " not priced in the last day",
+			Method:      http.MethodPost,
+			Path:        batchPathPrefix + "assets/refresh?async=true",
+		})
+	}
+
+	return actions, nil
+}
+
+// plural formats a count with its noun, e.g. "1 attempt" or "3 attempts"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
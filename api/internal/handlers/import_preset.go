@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/importer"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+type ImportPresetHandler struct {
+	presetRepo *repository.ImportPresetRepository
+}
+
+func NewImportPresetHandler(presetRepo *repository.ImportPresetRepository) *ImportPresetHandler {
+	return &ImportPresetHandler{presetRepo: presetRepo}
+}
+
+type ImportPresetRequest struct {
+	Name    string               `json:"name"`
+	Mapping models.ImportMapping `json:"mapping"`
+}
+
+// validate checks the request, returning an error message if it is invalid
+func (req *ImportPresetRequest) validate() string {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return "Name is required"
+	}
+	if len(req.Name) > 255 {
+		return "Name must be 255 characters or fewer"
+	}
+	return importer.ValidateMapping(req.Mapping)
+}
+
+// ImportPresetList is the built-in broker formats alongside the user's own presets
+type ImportPresetList struct {
+	BuiltIn []importer.Format      `json:"built_in"`
+	Custom  []*models.ImportPreset `json:"custom"`
+}
+
+func (h *ImportPresetHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	presets, err := h.presetRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch import presets")
+		return
+	}
+
+	if presets == nil {
+		presets = []*models.ImportPreset{}
+	}
+
+	JSON(w, http.StatusOK, ImportPresetList{BuiltIn: importer.Formats(), Custom: presets})
+}
+
+func (h *ImportPresetHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req ImportPresetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	preset := &models.ImportPreset{
+		UserID:  userID,
+		Name:    req.Name,
+		Mapping: req.Mapping,
+	}
+
+	if err := h.presetRepo.Create(r.Context(), preset); err != nil {
+		if errors.Is(err, repository.ErrImportPresetAlreadyExists) {
+			Error(w, http.StatusConflict, "An import preset with this name already exists")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to create import preset")
+		return
+	}
+
+	JSON(w, http.StatusCreated, preset)
+}
+
+func (h *ImportPresetHandler) Get(w http.ResponseWriter, r *http.Request) {
+	preset, ok := h.ownedPreset(w, r)
+	if !ok {
+		return
+	}
+
+	JSON(w, http.StatusOK, preset)
+}
+
+func (h *ImportPresetHandler) Update(w http.ResponseWriter, r *http.Request) {
+	preset, ok := h.ownedPreset(w, r)
+	if !ok {
+		return
+	}
+
+	var req ImportPresetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	preset.Name = req.Name
+	preset.Mapping = req.Mapping
+
+	if err := h.presetRepo.Update(r.Context(), preset); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrImportPresetNotFound):
+			Error(w, http.StatusNotFound, "Import preset not found")
+		case errors.Is(err, repository.ErrImportPresetAlreadyExists):
+			Error(w, http.StatusConflict, "An import preset with this name already exists")
+		default:
+			Error(w, http.StatusInternalServerError, "Failed to update import preset")
+		}
+		return
+	}
+
+	JSON(w, http.StatusOK, preset)
+}
+
+func (h *ImportPresetHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	preset, ok := h.ownedPreset(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.presetRepo.Delete(r.Context(), preset.ID); err != nil {
+		if errors.Is(err, repository.ErrImportPresetNotFound) {
+			Error(w, http.StatusNotFound, "Import preset not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to delete import preset")
+		return
+	}
+
+	NoContent(w)
+}
+
+func (h *ImportPresetHandler) ownedPreset(w http.ResponseWriter, r *http.Request) (*models.ImportPreset, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	presetID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid preset ID")
+		return nil, false
+	}
+
+	preset, err := h.presetRepo.GetByID(r.Context(), presetID)
+	if err != nil {
+		if errors.Is(err, repository.ErrImportPresetNotFound) {
+			Error(w, http.StatusNotFound, "Import preset not found")
+			return nil, false
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch import preset")
+		return nil, false
+	}
+
+	if preset.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return nil, false
+	}
+
+	return preset, true
+}
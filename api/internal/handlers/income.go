@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/pkg/validator"
+)
+
+type IncomeHandler struct {
+	incomeRepo *repository.IncomeRepository
+}
+
+func NewIncomeHandler(incomeRepo *repository.IncomeRepository) *IncomeHandler {
+	return &IncomeHandler{incomeRepo: incomeRepo}
+}
+
+type IncomeRequest struct {
+	Source      string  `json:"source"`
+	IncomeType  string  `json:"income_type"`
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+	Frequency   string  `json:"frequency"`
+	NextPayDate string  `json:"next_pay_date"`
+	StartDate   string  `json:"start_date"`
+	EndDate     string  `json:"end_date"`
+	IsActive    *bool   `json:"is_active"`
+	Notes       string  `json:"notes"`
+
+	nextPayDate time.Time
+	startDate   *time.Time
+	endDate     *time.Time
+}
+
+// validate checks the request and parses its dates, returning an error message if it
+// is invalid
+func (req *IncomeRequest) validate() string {
+	req.Source = strings.TrimSpace(req.Source)
+	req.IncomeType = strings.ToUpper(req.IncomeType)
+	req.Currency = strings.ToUpper(strings.TrimSpace(req.Currency))
+	req.Frequency = strings.ToUpper(req.Frequency)
+
+	if req.Source == "" {
+		return "Source is required"
+	}
+	if len(req.Source) > 255 {
+		return "Source must be 255 characters or fewer"
+	}
+	switch req.IncomeType {
+	case "":
+		req.IncomeType = models.IncomeTypeOther
+	case models.IncomeTypeSalary, models.IncomeTypeRental, models.IncomeTypePension, models.IncomeTypeOther:
+	default:
+		return "Invalid income type (use SALARY, RENTAL, PENSION or OTHER)"
+	}
+	if req.Amount <= 0 {
+		return "Amount must be positive"
+	}
+	if req.Currency == "" {
+		req.Currency = "GBP"
+	}
+	if !validator.IsValidCurrency(req.Currency) {
+		return "Invalid currency"
+	}
+	switch req.Frequency {
+	case models.IncomeFrequencyWeekly, models.IncomeFrequencyFortnightly, models.IncomeFrequencyFourWeekly,
+		models.IncomeFrequencyMonthly, models.IncomeFrequencyQuarterly, models.IncomeFrequencyAnnually:
+	default:
+		return "Invalid frequency (use WEEKLY, FORTNIGHTLY, FOUR_WEEKLY, MONTHLY, QUARTERLY or ANNUALLY)"
+	}
+
+	if req.NextPayDate == "" {
+		return "Next pay date is required"
+	}
+	nextPayDate, err := time.Parse("2006-01-02", req.NextPayDate)
+	if err != nil {
+		return "Invalid next pay date format (use YYYY-MM-DD)"
+	}
+	req.nextPayDate = nextPayDate
+
+	if req.StartDate != "" {
+		startDate, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			return "Invalid start date format (use YYYY-MM-DD)"
+		}
+		if startDate.After(nextPayDate) {
+			return "Start date cannot be after the next pay date"
+		}
+		req.startDate = &startDate
+	}
+	if req.EndDate != "" {
+		endDate, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			return "Invalid end date format (use YYYY-MM-DD)"
+		}
+		if endDate.Before(nextPayDate) {
+			return "End date cannot be before the next pay date"
+		}
+		req.endDate = &endDate
+	}
+	return ""
+}
+
+// apply copies the validated request onto the income
+func (req *IncomeRequest) apply(income *models.Income) {
+	income.Source = req.Source
+	income.IncomeType = req.IncomeType
+	income.Amount = req.Amount
+	income.Currency = req.Currency
+	income.Frequency = req.Frequency
+	income.NextPayDate = req.nextPayDate
+	income.StartDate = req.startDate
+	income.EndDate = req.endDate
+	income.Notes = strings.TrimSpace(req.Notes)
+	if req.IsActive != nil {
+		income.IsActive = *req.IsActive
+	}
+}
+
+// List returns the user's incomes. Query param active=true leaves out paused ones.
+func (h *IncomeHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	incomes, err := h.incomeRepo.GetByUserID(r.Context(), userID, r.URL.Query().Get("active") == "true")
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch incomes")
+		return
+	}
+
+	for _, income := range incomes {
+		computeIncome(income)
+	}
+	if incomes == nil {
+		incomes = []*models.Income{}
+	}
+
+	JSON(w, http.StatusOK, incomes)
+}
+
+func (h *IncomeHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req IncomeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	income := &models.Income{UserID: userID, IsActive: true}
+	req.apply(income)
+
+	if err := h.incomeRepo.Create(r.Context(), income); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to create income")
+		return
+	}
+
+	computeIncome(income)
+	JSON(w, http.StatusCreated, income)
+}
+
+func (h *IncomeHandler) Get(w http.ResponseWriter, r *http.Request) {
+	income, ok := h.ownedIncome(w, r)
+	if !ok {
+		return
+	}
+
+	computeIncome(income)
+	JSON(w, http.StatusOK, income)
+}
+
+func (h *IncomeHandler) Update(w http.ResponseWriter, r *http.Request) {
+	income, ok := h.ownedIncome(w, r)
+	if !ok {
+		return
+	}
+
+	var req IncomeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+	req.apply(income)
+
+	if err := h.incomeRepo.Update(r.Context(), income); err != nil {
+		if errors.Is(err, repository.ErrIncomeNotFound) {
+			Error(w, http.StatusNotFound, "Income not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to update income")
+		return
+	}
+
+	computeIncome(income)
+	JSON(w, http.StatusOK, income)
+}
+
+func (h *IncomeHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	income, ok := h.ownedIncome(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.incomeRepo.Delete(r.Context(), income.ID); err != nil {
+		if errors.Is(err, repository.ErrIncomeNotFound) {
+			Error(w, http.StatusNotFound, "Income not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to delete income")
+		return
+	}
+
+	NoContent(w)
+}
+
+// computeIncome fills in the income's monthly equivalent and its next payment from today
+func computeIncome(income *models.Income) {
+	income.MonthlyAmount = roundMoney(income.Amount * income.PaymentsPerYear() / 12)
+	now := time.Now()
+	income.UpcomingPayDate = income.NextPayment(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+}
+
+// ownedIncome loads the income from the URL, writing an error response if it is missing or not the user's
+func (h *IncomeHandler) ownedIncome(w http.ResponseWriter, r *http.Request) (*models.Income, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	incomeID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid income ID")
+		return nil, false
+	}
+
+	income, err := h.incomeRepo.GetByID(r.Context(), incomeID)
+	if err != nil {
+		if errors.Is(err, repository.ErrIncomeNotFound) {
+			Error(w, http.StatusNotFound, "Income not found")
+			return nil, false
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch income")
+		return nil, false
+	}
+
+	if income.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return nil, false
+	}
+
+	return income, true
+}
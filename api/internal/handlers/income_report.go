@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/pkg/taxyear"
+)
+
+// maxIncomeReportMonths caps the months an income report can span
+const maxIncomeReportMonths = 120
+
+// IncomeMonth is the investment income received in one currency during one calendar
+// month. Dividends are net of tax withheld at source and GrossDividends before it;
+// CashInterest is estimated from each cash account's current balance and rate.
+type IncomeMonth struct {
+	Month          string  `json:"month,omitempty"` // YYYY-MM
+	Currency       string  `json:"currency"`
+	Dividends      float64 `json:"dividends"`
+	GrossDividends float64 `json:"gross_dividends"`
+	TaxWithheld    float64 `json:"tax_withheld"`
+	Interest       float64 `json:"interest"`
+	CashInterest   float64 `json:"cash_interest"`
+	Total          float64 `json:"total"`     // gross dividends plus interest
+	Sheltered      float64 `json:"sheltered"` // the part of Total earned inside tax wrappers
+	Taxable        float64 `json:"taxable"`
+}
+
+type IncomeReportResponse struct {
+	From   string        `json:"from"`
+	To     string        `json:"to"`
+	Months []IncomeMonth `json:"months"`
+	Totals []IncomeMonth `json:"totals"`
+}
+
+// incomeMonthKey identifies one month of income in one currency
+type incomeMonthKey struct {
+	month    string
+	currency string
+}
+
+// Income breaks dividends, interest and cash account interest down by month between
+// from and to (YYYY-MM-DD, default the current tax year) for self-assessment. Each
+// currency has its own rows; nothing is converted.
+func (h *ReportHandler) Income(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+
+	year := taxyear.For(user.TaxJurisdiction).Current()
+	from, to := year.From(), year.To()
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse("2006-01-02", v); err != nil {
+			Error(w, http.StatusBadRequest, "Invalid from date (use YYYY-MM-DD)")
+			return
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse("2006-01-02", v); err != nil {
+			Error(w, http.StatusBadRequest, "Invalid to date (use YYYY-MM-DD)")
+			return
+		}
+	}
+	if to.Before(from) {
+		Error(w, http.StatusBadRequest, "from must not be after to")
+		return
+	}
+	first := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	months := (last.Year()-first.Year())*12 + int(last.Month()-first.Month()) + 1
+	if months > maxIncomeReportMonths {
+		Error(w, http.StatusBadRequest, "Range is too long (10 years maximum)")
+		return
+	}
+
+	portfolios, err := h.portfolioRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolios")
+		return
+	}
+	portfolioByID := make(map[uuid.UUID]*models.Portfolio, len(portfolios))
+	for _, p := range portfolios {
+		portfolioByID[p.ID] = p
+	}
+
+	totals, err := h.txRepo.GetMonthlyIncomeTotals(r.Context(), userID, from, to)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch transactions")
+		return
+	}
+
+	cashAccounts, err := h.cashRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch cash accounts")
+		return
+	}
+
+	rows := make(map[incomeMonthKey]*IncomeMonth)
+	row := func(month time.Time, currency string) *IncomeMonth {
+		key := incomeMonthKey{month: month.Format("2006-01"), currency: currency}
+		if rows[key] == nil {
+			rows[key] = &IncomeMonth{Month: key.month, Currency: currency}
+		}
+		return rows[key]
+	}
+
+	for _, t := range totals {
+		p, ok := portfolioByID[t.PortfolioID]
+		if !ok {
+			continue
+		}
+		m := row(t.Month, t.Currency)
+		switch t.TransactionType {
+		case models.TransactionTypeDividend:
+			m.Dividends += t.Net
+			m.GrossDividends += t.Gross
+			m.TaxWithheld += t.Gross - t.Net
+		case models.TransactionTypeInterest:
+			m.Interest += t.Net
+		}
+		if repository.IsTaxSheltered(p.Type) {
+			m.Sheltered += t.Gross
+		}
+	}
+
+	// Accrue cash account interest day by day up to today
+	end := to.AddDate(0, 0, 1)
+	if now := time.Now().UTC(); now.Before(end) {
+		end = now
+	}
+	for _, ca := range cashAccounts {
+		if ca.InterestRate == nil || *ca.InterestRate <= 0 || ca.Balance <= 0 {
+			continue
+		}
+		p, ok := portfolioByID[ca.PortfolioID]
+		if !ok {
+			continue
+		}
+		perDay := ca.Balance * *ca.InterestRate / 100 / 365
+		for month := first; month.Before(end); month = month.AddDate(0, 1, 0) {
+			start, stop := month, month.AddDate(0, 1, 0)
+			if start.Before(from) {
+				start = from
+			}
+			if stop.After(end) {
+				stop = end
+			}
+			days := stop.Sub(start).Hours() / 24
+			if days <= 0 {
+				continue
+			}
+			m := row(month, ca.Currency)
+			m.CashInterest += perDay * days
+			if repository.IsTaxSheltered(p.Type) {
+				m.Sheltered += perDay * days
+			}
+		}
+	}
+
+	// Every month in the range is listed for each currency with income
+	currencies := make(map[string]bool)
+	for key := range rows {
+		currencies[key.currency] = true
+	}
+	if len(currencies) == 0 {
+		currencies[user.BaseCurrency] = true
+	}
+	for currency := range currencies {
+		for i := 0; i < months; i++ {
+			row(first.AddDate(0, i, 0), currency)
+		}
+	}
+
+	resp := IncomeReportResponse{
+		From:   from.Format("2006-01-02"),
+		To:     to.Format("2006-01-02"),
+		Months: make([]IncomeMonth, 0, len(rows)),
+		Totals: []IncomeMonth{},
+	}
+	sums := make(map[string]*IncomeMonth)
+	for _, m := range rows {
+		m.Total = m.GrossDividends + m.Interest + m.CashInterest
+		m.Taxable = m.Total - m.Sheltered
+
+		sum, ok := sums[m.Currency]
+		if !ok {
+			sum = &IncomeMonth{Currency: m.Currency}
+			sums[m.Currency] = sum
+		}
+		addIncome(sum, m)
+
+		roundIncome(m)
+		resp.Months = append(resp.Months, *m)
+	}
+	for _, sum := range sums {
+		roundIncome(sum)
+		resp.Totals = append(resp.Totals, *sum)
+	}
+
+	sort.Slice(resp.Months, func(i, j int) bool {
+		a, b := resp.Months[i], resp.Months[j]
+		if a.Month != b.Month {
+			return a.Month < b.Month
+		}
+		return a.Currency < b.Currency
+	})
+	sort.Slice(resp.Totals, func(i, j int) bool { return resp.Totals[i].Currency < resp.Totals[j].Currency })
+
+	JSON(w, http.StatusOK, resp)
+}
+
+func addIncome(sum, m *IncomeMonth) {
+	sum.Dividends += m.Dividends
+	sum.GrossDividends += m.GrossDividends
+	sum.TaxWithheld += m.TaxWithheld
+	sum.Interest += m.Interest
+	sum.CashInterest += m.CashInterest
+	sum.Total += m.Total
+	sum.Sheltered += m.Sheltered
+	sum.Taxable += m.Taxable
+}
+
+func roundIncome(m *IncomeMonth) {
+	m.Dividends = roundMoney(m.Dividends)
+	m.GrossDividends = roundMoney(m.GrossDividends)
+	m.TaxWithheld = roundMoney(m.TaxWithheld)
+	m.Interest = roundMoney(m.Interest)
+	m.CashInterest = roundMoney(m.CashInterest)
+	m.Total = roundMoney(m.Total)
+	m.Sheltered = roundMoney(m.Sheltered)
+	m.Taxable = roundMoney(m.Taxable)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+type InsightHandler struct {
+	health *services.HealthService
+}
+
+func NewInsightHandler(health *services.HealthService) *InsightHandler {
+	return &InsightHandler{health: health}
+}
+
+// Health scores the user's financial hygiene out of 100, with each signal's score,
+// explanation and suggested action
+func (h *InsightHandler) Health(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	score, err := h.health.Score(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to calculate health score")
+		return
+	}
+
+	JSON(w, http.StatusOK, score)
+}
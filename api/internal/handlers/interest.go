@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+// Personal savings allowance by income tax band
+var personalSavingsAllowances = map[string]float64{
+	"basic":      1000,
+	"higher":     500,
+	"additional": 0,
+}
+
+// InterestAccount is the interest earned by one portfolio (from INTEREST transactions)
+// or one cash account (accrued from its balance and rate)
+type InterestAccount struct {
+	Source        string     `json:"source"` // TRANSACTIONS or ACCRUED
+	PortfolioID   uuid.UUID  `json:"portfolio_id"`
+	PortfolioName string     `json:"portfolio_name"`
+	PortfolioType string     `json:"portfolio_type"`
+	CashAccountID *uuid.UUID `json:"cash_account_id,omitempty"`
+	AccountName   string     `json:"account_name,omitempty"`
+	Institution   string     `json:"institution,omitempty"`
+	InterestRate  *float64   `json:"interest_rate,omitempty"`
+	Currency      string     `json:"currency"`
+	Sheltered     bool       `json:"sheltered"`
+	Payments      int        `json:"payments,omitempty"`
+	Interest      float64    `json:"interest"`
+}
+
+type InterestTotals struct {
+	Received float64 `json:"received"`
+	Accrued  float64 `json:"accrued"`
+	Total    float64 `json:"total"`
+}
+
+// InterestReportResponse totals interest for a tax year. Accrued interest is an estimate
+// from each cash account's current balance and rate over the elapsed part of the year.
+type InterestReportResponse struct {
+	TaxYear                  string            `json:"tax_year"`
+	From                     string            `json:"from"`
+	To                       string            `json:"to"`
+	Currency                 string            `json:"currency"`
+	Accounts                 []InterestAccount `json:"accounts"`
+	Sheltered                InterestTotals    `json:"sheltered"`
+	Taxable                  InterestTotals    `json:"taxable"`
+	PersonalSavingsAllowance float64           `json:"personal_savings_allowance"`
+	AllowanceRemaining       float64           `json:"allowance_remaining"`
+	ExceedsAllowance         bool              `json:"exceeds_allowance"`
+}
+
+// Interest totals INTEREST transactions and accrued cash account interest for a tax
+// year (tax_year=2024/25, default current), split between tax-free wrappers (ISA, LISA,
+// JISA, SIPP) and taxable accounts. Taxable interest is checked against the personal
+// savings allowance for rate=basic, higher or additional (default basic).
+func (h *ReportHandler) Interest(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+
+	year, ok := parseTaxYear(w, r, user)
+	if !ok {
+		return
+	}
+	from, to := year.From(), year.To()
+
+	band := r.URL.Query().Get("rate")
+	if band == "" {
+		band = "basic"
+	}
+	allowance, ok := personalSavingsAllowances[band]
+	if !ok {
+		Error(w, http.StatusBadRequest, "Invalid rate (use basic, higher or additional)")
+		return
+	}
+
+	portfolios, err := h.portfolioRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolios")
+		return
+	}
+	portfolioByID := make(map[uuid.UUID]*models.Portfolio, len(portfolios))
+	for _, p := range portfolios {
+		portfolioByID[p.ID] = p
+	}
+
+	totals, err := h.txRepo.GetInterestTotals(r.Context(), userID, from, to)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch transactions")
+		return
+	}
+
+	cashAccounts, err := h.cashRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch cash accounts")
+		return
+	}
+
+	resp := InterestReportResponse{
+		TaxYear:                  year.String(),
+		From:                     from.Format("2006-01-02"),
+		To:                       to.Format("2006-01-02"),
+		Currency:                 user.BaseCurrency,
+		Accounts:                 []InterestAccount{},
+		PersonalSavingsAllowance: allowance,
+	}
+
+	for _, t := range totals {
+		p, ok := portfolioByID[t.PortfolioID]
+		if !ok {
+			continue
+		}
+		resp.Accounts = append(resp.Accounts, InterestAccount{
+			Source:        "TRANSACTIONS",
+			PortfolioID:   p.ID,
+			PortfolioName: p.Name,
+			PortfolioType: p.Type,
+			Currency:      t.Currency,
+			Sheltered:     repository.IsTaxSheltered(p.Type),
+			Payments:      t.Count,
+			Interest:      roundMoney(t.Total),
+		})
+	}
+
+	// Accrue from the start of the tax year to today, or to its end for past years
+	accrualEnd := time.Now().UTC()
+	if accrualEnd.After(to) {
+		accrualEnd = to.AddDate(0, 0, 1)
+	}
+	years := math.Max(accrualEnd.Sub(from).Hours()/24/365, 0)
+
+	for _, ca := range cashAccounts {
+		if ca.InterestRate == nil || *ca.InterestRate <= 0 || ca.Balance <= 0 || years == 0 {
+			continue
+		}
+		p, ok := portfolioByID[ca.PortfolioID]
+		if !ok {
+			continue
+		}
+		accountID := ca.ID
+		resp.Accounts = append(resp.Accounts, InterestAccount{
+			Source:        "ACCRUED",
+			PortfolioID:   p.ID,
+			PortfolioName: p.Name,
+			PortfolioType: p.Type,
+			CashAccountID: &accountID,
+			AccountName:   ca.AccountName,
+			Institution:   ca.Institution,
+			InterestRate:  ca.InterestRate,
+			Currency:      ca.Currency,
+			Sheltered:     repository.IsTaxSheltered(p.Type),
+			Interest:      roundMoney(ca.Balance * *ca.InterestRate / 100 * years),
+		})
+	}
+
+	for _, a := range resp.Accounts {
+		sum := &resp.Taxable
+		if a.Sheltered {
+			sum = &resp.Sheltered
+		}
+		if a.Source == "ACCRUED" {
+			sum.Accrued += a.Interest
+		} else {
+			sum.Received += a.Interest
+		}
+	}
+	for _, sum := range []*InterestTotals{&resp.Sheltered, &resp.Taxable} {
+		sum.Received = roundMoney(sum.Received)
+		sum.Accrued = roundMoney(sum.Accrued)
+		sum.Total = roundMoney(sum.Received + sum.Accrued)
+	}
+
+	resp.AllowanceRemaining = roundMoney(math.Max(allowance-resp.Taxable.Total, 0))
+	resp.ExceedsAllowance = resp.Taxable.Total > allowance
+
+	JSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+	"github.com/mark-regan/wellf/pkg/validator"
+)
+
+type LiabilityHandler struct {
+	liabilityRepo *repository.LiabilityRepository
+}
+
+func NewLiabilityHandler(liabilityRepo *repository.LiabilityRepository) *LiabilityHandler {
+	return &LiabilityHandler{liabilityRepo: liabilityRepo}
+}
+
+type CreateLiabilityRequest struct {
+	Name           string   `json:"name"`
+	LiabilityType  string   `json:"liability_type"`
+	Lender         string   `json:"lender"`
+	Balance        float64  `json:"balance"`
+	Currency       string   `json:"currency"`
+	InterestRate   float64  `json:"interest_rate"`
+	MonthlyPayment *float64 `json:"monthly_payment"`
+	PaymentDay     int      `json:"payment_day"`
+	EndDate        string   `json:"end_date"`
+	Notes          string   `json:"notes"`
+}
+
+func (req *CreateLiabilityRequest) validate() string {
+	if req.Name == "" {
+		return "Name is required"
+	}
+	switch req.LiabilityType {
+	case models.LiabilityTypeMortgage, models.LiabilityTypeLoan, models.LiabilityTypeCreditCard:
+	default:
+		return "Invalid liability type (use MORTGAGE, LOAN or CREDIT_CARD)"
+	}
+	if req.Balance < 0 {
+		return "Balance cannot be negative"
+	}
+	if req.InterestRate < 0 || req.InterestRate > 100 {
+		return "Interest rate must be a percentage between 0 and 100"
+	}
+	if req.MonthlyPayment != nil && *req.MonthlyPayment < 0 {
+		return "Monthly payment cannot be negative"
+	}
+	if req.PaymentDay == 0 {
+		req.PaymentDay = 1
+	}
+	if req.PaymentDay < 1 || req.PaymentDay > 28 {
+		return "Payment day must be between 1 and 28"
+	}
+	if req.Currency == "" {
+		req.Currency = "GBP"
+	}
+	if !validator.IsValidCurrency(req.Currency) {
+		return "Invalid currency"
+	}
+	if req.EndDate != "" {
+		if _, err := time.Parse("2006-01-02", req.EndDate); err != nil {
+			return "Invalid end date format (use YYYY-MM-DD)"
+		}
+	}
+	return ""
+}
+
+// apply copies the request on to the liability
+func (req *CreateLiabilityRequest) apply(l *models.Liability) {
+	l.Name = req.Name
+	l.LiabilityType = req.LiabilityType
+	l.Lender = req.Lender
+	l.Balance = req.Balance
+	l.Currency = req.Currency
+	l.InterestRate = req.InterestRate
+	l.MonthlyPayment = req.MonthlyPayment
+	l.PaymentDay = req.PaymentDay
+	l.Notes = req.Notes
+	l.EndDate = nil
+	if req.EndDate != "" {
+		endDate, _ := time.Parse("2006-01-02", req.EndDate)
+		l.EndDate = &endDate
+	}
+}
+
+// ownedLiability fetches the liability in the id URL param, writing the error response
+// if it doesn't exist or belongs to someone else
+func (h *LiabilityHandler) ownedLiability(w http.ResponseWriter, r *http.Request) (*models.Liability, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	liabilityID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid liability ID")
+		return nil, false
+	}
+
+	liability, err := h.liabilityRepo.GetByID(r.Context(), liabilityID)
+	if err != nil {
+		if errors.Is(err, repository.ErrLiabilityNotFound) {
+			Error(w, http.StatusNotFound, "Liability not found")
+			return nil, false
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch liability")
+		return nil, false
+	}
+
+	if liability.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return nil, false
+	}
+
+	return liability, true
+}
+
+func (h *LiabilityHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateLiabilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	liability := &models.Liability{UserID: userID}
+	req.apply(liability)
+
+	if err := h.liabilityRepo.Create(r.Context(), liability); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to create liability")
+		return
+	}
+
+	JSON(w, http.StatusCreated, liability)
+}
+
+func (h *LiabilityHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	liabilities, err := h.liabilityRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch liabilities")
+		return
+	}
+
+	if liabilities == nil {
+		liabilities = []*models.Liability{}
+	}
+
+	JSON(w, http.StatusOK, liabilities)
+}
+
+func (h *LiabilityHandler) Get(w http.ResponseWriter, r *http.Request) {
+	liability, ok := h.ownedLiability(w, r)
+	if !ok {
+		return
+	}
+
+	JSON(w, http.StatusOK, liability)
+}
+
+// Update replaces the liability's details; fields left out are cleared or defaulted as
+// on create
+func (h *LiabilityHandler) Update(w http.ResponseWriter, r *http.Request) {
+	liability, ok := h.ownedLiability(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateLiabilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+	req.apply(liability)
+
+	if err := h.liabilityRepo.Update(r.Context(), liability); err != nil {
+		if errors.Is(err, repository.ErrLiabilityNotFound) {
+			Error(w, http.StatusNotFound, "Liability not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to update liability")
+		return
+	}
+
+	JSON(w, http.StatusOK, liability)
+}
+
+func (h *LiabilityHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	liability, ok := h.ownedLiability(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.liabilityRepo.Delete(r.Context(), liability.ID); err != nil {
+		if errors.Is(err, repository.ErrLiabilityNotFound) {
+			Error(w, http.StatusNotFound, "Liability not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to delete liability")
+		return
+	}
+
+	NoContent(w)
+}
+
+// Schedule projects the liability's monthly payments until it is paid off, or for the
+// number of months asked for (up to 50 years)
+func (h *LiabilityHandler) Schedule(w http.ResponseWriter, r *http.Request) {
+	liability, ok := h.ownedLiability(w, r)
+	if !ok {
+		return
+	}
+
+	months := services.MaxRepaymentMonths
+	if v := r.URL.Query().Get("months"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > services.MaxRepaymentMonths {
+			Error(w, http.StatusBadRequest, "Invalid months (1-600)")
+			return
+		}
+		months = n
+	}
+
+	JSON(w, http.StatusOK, services.RepaymentSchedule(liability, time.Now(), months))
+}
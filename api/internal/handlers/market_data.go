@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/marketdata"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+	"github.com/mark-regan/wellf/pkg/validator"
+)
+
+// MarketDataHandler lets admins inspect the price providers and choose which ones each
+// asset is priced from
+type MarketDataHandler struct {
+	assetRepo *repository.AssetRepository
+	providers *marketdata.Registry
+	routes    *services.PriceRouteService
+}
+
+func NewMarketDataHandler(assetRepo *repository.AssetRepository, providers *marketdata.Registry, routes *services.PriceRouteService) *MarketDataHandler {
+	return &MarketDataHandler{
+		assetRepo: assetRepo,
+		providers: providers,
+		routes:    routes,
+	}
+}
+
+type UpdateDataSourceRequest struct {
+	DataSource string `json:"data_source"`
+}
+
+// Providers lists the configured providers in failover order with their recent health
+func (h *MarketDataHandler) Providers(w http.ResponseWriter, r *http.Request) {
+	JSON(w, http.StatusOK, h.providers.Health())
+}
+
+// UpdateDataSource sets the provider an asset is priced from first when no price source
+// route matches it. Other providers are still used if it fails.
+func (h *MarketDataHandler) UpdateDataSource(w http.ResponseWriter, r *http.Request) {
+	var req UpdateDataSourceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	source := strings.ToUpper(strings.TrimSpace(req.DataSource))
+	if !h.providers.Has(source) {
+		Error(w, http.StatusBadRequest, "Unknown or unconfigured data source (use one of "+strings.Join(h.providers.Names(), ", ")+")")
+		return
+	}
+
+	symbol := strings.ToUpper(chi.URLParam(r, "symbol"))
+	if err := h.assetRepo.UpdateDataSource(r.Context(), symbol, source); err != nil {
+		if errors.Is(err, repository.ErrAssetNotFound) {
+			Error(w, http.StatusNotFound, "Asset not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to update data source")
+		return
+	}
+
+	asset, err := h.assetRepo.GetBySymbol(r.Context(), symbol)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch asset")
+		return
+	}
+
+	JSON(w, http.StatusOK, asset)
+}
+
+// priceRouteMatchTypes are the things a price source route can match
+var priceRouteMatchTypes = map[string]bool{
+	models.PriceRouteMatchSymbol:    true,
+	models.PriceRouteMatchExchange:  true,
+	models.PriceRouteMatchAssetType: true,
+}
+
+type SavePriceRouteRequest struct {
+	MatchType  string   `json:"match_type"`
+	MatchValue string   `json:"match_value"`
+	Sources    []string `json:"sources"`
+}
+
+// validate checks the request against the configured providers, returning an error
+// message if it is invalid
+func (req *SavePriceRouteRequest) validate(providers *marketdata.Registry) string {
+	req.MatchType = strings.ToUpper(strings.TrimSpace(req.MatchType))
+	req.MatchValue = strings.ToUpper(strings.TrimSpace(req.MatchValue))
+	if !priceRouteMatchTypes[req.MatchType] {
+		return "Invalid match type (use SYMBOL, EXCHANGE or ASSET_TYPE)"
+	}
+	if req.MatchValue == "" || len(req.MatchValue) > 50 {
+		return "Match value is required (up to 50 characters)"
+	}
+	if req.MatchType == models.PriceRouteMatchAssetType && !validator.IsValidAssetType(req.MatchValue) {
+		return "Invalid asset type"
+	}
+	if len(req.Sources) == 0 {
+		return "At least one data source is required"
+	}
+	seen := make(map[string]bool, len(req.Sources))
+	sources := make([]string, 0, len(req.Sources))
+	for _, source := range req.Sources {
+		source = strings.ToUpper(strings.TrimSpace(source))
+		if !providers.Has(source) {
+			return "Unknown or unconfigured data source " + source + " (use " + strings.Join(providers.Names(), ", ") + ")"
+		}
+		if !seen[source] {
+			seen[source] = true
+			sources = append(sources, source)
+		}
+	}
+	req.Sources = sources
+	return ""
+}
+
+// Routes lists the price source routes
+func (h *MarketDataHandler) Routes(w http.ResponseWriter, r *http.Request) {
+	routes, err := h.routes.List(r.Context())
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch price source routes")
+		return
+	}
+	if routes == nil {
+		routes = []*models.PriceSourceRoute{}
+	}
+
+	JSON(w, http.StatusOK, routes)
+}
+
+// SaveRoute creates a route, or replaces the sources of the route with the same match.
+// Routes take precedence over each asset's own data source.
+func (h *MarketDataHandler) SaveRoute(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req SavePriceRouteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(h.providers); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	route := &models.PriceSourceRoute{
+		MatchType:  req.MatchType,
+		MatchValue: req.MatchValue,
+		Sources:    req.Sources,
+		UpdatedBy:  &userID,
+	}
+	if err := h.routes.Save(r.Context(), route); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to save price source route")
+		return
+	}
+
+	JSON(w, http.StatusOK, route)
+}
+
+func (h *MarketDataHandler) DeleteRoute(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid route ID")
+		return
+	}
+
+	if err := h.routes.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrPriceSourceRouteNotFound) {
+			Error(w, http.StatusNotFound, "Price source route not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to delete price source route")
+		return
+	}
+
+	NoContent(w)
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+type PerformanceHandler struct {
+	portfolioRepo      *repository.PortfolioRepository
+	userRepo           *repository.UserRepository
+	performanceService *services.PerformanceService
+}
+
+func NewPerformanceHandler(portfolioRepo *repository.PortfolioRepository, userRepo *repository.UserRepository, performanceService *services.PerformanceService) *PerformanceHandler {
+	return &PerformanceHandler{
+		portfolioRepo:      portfolioRepo,
+		userRepo:           userRepo,
+		performanceService: performanceService,
+	}
+}
+
+// Portfolio returns a portfolio's time-weighted or money-weighted return
+// (method=twr|xirr, default twr) over a period (1m, 3m, 6m, ytd, 1y, 3y, 5y or max;
+// default 1y), in the portfolio's currency
+func (h *PerformanceHandler) Portfolio(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	portfolioID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid portfolio ID")
+		return
+	}
+
+	belongs, err := h.portfolioRepo.BelongsToUser(r.Context(), portfolioID, userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to verify ownership")
+		return
+	}
+	if !belongs {
+		Error(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	portfolio, err := h.portfolioRepo.GetByID(r.Context(), portfolioID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPortfolioNotFound) {
+			Error(w, http.StatusNotFound, "Portfolio not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+
+	h.respond(w, r, []*models.Portfolio{portfolio}, portfolio.Currency)
+}
+
+// Account returns the return across all of the user's portfolios in their base
+// currency, with the same parameters as Portfolio
+func (h *PerformanceHandler) Account(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	portfolios, err := h.portfolioRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolios")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+
+	h.respond(w, r, portfolios, user.BaseCurrency)
+}
+
+func (h *PerformanceHandler) respond(w http.ResponseWriter, r *http.Request, portfolios []*models.Portfolio, currency string) {
+	method := r.URL.Query().Get("method")
+	if method == "" {
+		method = services.PerformanceMethodTWR
+	}
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "1y"
+	}
+
+	result, err := h.performanceService.Calculate(r.Context(), portfolios, currency, method, period)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPerformanceMethod):
+			Error(w, http.StatusBadRequest, "Invalid method (use twr or xirr)")
+		case errors.Is(err, services.ErrInvalidPerformancePeriod):
+			Error(w, http.StatusBadRequest, "Invalid period (use 1m, 3m, 6m, ytd, 1y, 3y, 5y or max)")
+		case errors.Is(err, services.ErrFXUnavailable):
+			Error(w, http.StatusBadGateway, "Exchange rates are unavailable; try again later")
+		default:
+			Error(w, http.StatusInternalServerError, "Failed to calculate performance")
+		}
+		return
+	}
+
+	JSON(w, http.StatusOK, result)
+}
@@ -4,12 +4,16 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/mark-regan/wellf/internal/middleware"
 	"github.com/mark-regan/wellf/internal/models"
 	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+	"github.com/mark-regan/wellf/pkg/taxyear"
 	"github.com/mark-regan/wellf/pkg/validator"
 )
 
@@ -17,13 +21,21 @@ type PortfolioHandler struct {
 	portfolioRepo   *repository.PortfolioRepository
 	holdingRepo     *repository.HoldingRepository
 	transactionRepo *repository.TransactionRepository
+	lots            *services.LotService
+	netWorthService *services.NetWorthService
+	allowances      *services.AllowanceService
+	targetRepo      *repository.AllocationTargetRepository
 }
 
-func NewPortfolioHandler(portfolioRepo *repository.PortfolioRepository, holdingRepo *repository.HoldingRepository, transactionRepo *repository.TransactionRepository) *PortfolioHandler {
+func NewPortfolioHandler(portfolioRepo *repository.PortfolioRepository, holdingRepo *repository.HoldingRepository, transactionRepo *repository.TransactionRepository, lots *services.LotService, netWorthService *services.NetWorthService, allowances *services.AllowanceService, targetRepo *repository.AllocationTargetRepository) *PortfolioHandler {
 	return &PortfolioHandler{
 		portfolioRepo:   portfolioRepo,
 		holdingRepo:     holdingRepo,
 		transactionRepo: transactionRepo,
+		lots:            lots,
+		netWorthService: netWorthService,
+		allowances:      allowances,
+		targetRepo:      targetRepo,
 	}
 }
 
@@ -118,6 +130,15 @@ func (h *PortfolioHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Metadata != nil && req.Metadata.CostBasisMethod != "" && !validator.IsValidCostBasisMethod(req.Metadata.CostBasisMethod) {
+		Error(w, http.StatusBadRequest, "Invalid cost basis method (use FIFO, LIFO or AVERAGE)")
+		return
+	}
+	if req.Metadata != nil && !validTaxYear(req.Metadata.TaxYear) {
+		Error(w, http.StatusBadRequest, "Invalid tax year (use e.g. 2024/25)")
+		return
+	}
+
 	portfolio := &models.Portfolio{
 		UserID:      userID,
 		Name:        req.Name,
@@ -131,14 +152,7 @@ func (h *PortfolioHandler) Create(w http.ResponseWriter, r *http.Request) {
 	if portfolio.Metadata == nil {
 		portfolio.Metadata = &models.PortfolioMetadata{}
 	}
-	switch req.Type {
-	case models.PortfolioTypeISA:
-		portfolio.Metadata.ContributionLimit = 20000 // 2024/25 limit
-	case models.PortfolioTypeLISA:
-		portfolio.Metadata.ContributionLimit = 4000 // LISA limit
-	case models.PortfolioTypeJISA:
-		portfolio.Metadata.ContributionLimit = 9000 // 2024/25 limit
-	}
+	portfolio.Metadata.ContributionLimit = services.AnnualAllowance(req.Type)
 
 	if err := h.portfolioRepo.Create(r.Context(), portfolio); err != nil {
 		if errors.Is(err, repository.ErrPortfolioAlreadyExists) {
@@ -237,7 +251,16 @@ func (h *PortfolioHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.Description != "" {
 		portfolio.Description = req.Description
 	}
+	previousMethod := services.CostBasisMethod(portfolio)
 	if req.Metadata != nil {
+		if req.Metadata.CostBasisMethod != "" && !validator.IsValidCostBasisMethod(req.Metadata.CostBasisMethod) {
+			Error(w, http.StatusBadRequest, "Invalid cost basis method (use FIFO, LIFO or AVERAGE)")
+			return
+		}
+		if !validTaxYear(req.Metadata.TaxYear) {
+			Error(w, http.StatusBadRequest, "Invalid tax year (use e.g. 2024/25)")
+			return
+		}
 		portfolio.Metadata = req.Metadata
 	}
 
@@ -249,6 +272,9 @@ func (h *PortfolioHandler) Update(w http.ResponseWriter, r *http.Request) {
 		Error(w, http.StatusInternalServerError, "Failed to update portfolio")
 		return
 	}
+	if services.CostBasisMethod(portfolio) != previousMethod {
+		h.lots.SyncPortfolio(r.Context(), portfolio.ID)
+	}
 
 	JSON(w, http.StatusOK, portfolio)
 }
@@ -300,6 +326,136 @@ func (h *PortfolioHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	NoContent(w)
 }
 
+type DuplicatePortfolioRequest struct {
+	Name            string `json:"name"`
+	Provider        string `json:"provider"`
+	IncludeHoldings bool   `json:"include_holdings"`
+}
+
+// Duplicate copies the portfolio's type, currency, settings and target allocation into a
+// new portfolio, optionally with its holdings. Transactions, the account reference and
+// contribution tracking are not copied.
+func (h *PortfolioHandler) Duplicate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	portfolioID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid portfolio ID")
+		return
+	}
+
+	source, err := h.portfolioRepo.GetByID(r.Context(), portfolioID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPortfolioNotFound) {
+			Error(w, http.StatusNotFound, "Portfolio not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+
+	if source.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	if source.Type == models.PortfolioTypeFixedAssets {
+		Error(w, http.StatusBadRequest, "Fixed Assets portfolio cannot be duplicated")
+		return
+	}
+
+	var req DuplicatePortfolioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		req.Name = source.Name + " (copy)"
+	}
+
+	metadata := models.PortfolioMetadata{}
+	if source.Metadata != nil {
+		metadata = *source.Metadata
+	}
+	metadata.AccountReference = ""
+	metadata.ContributionsThisYear = 0
+	metadata.ContributionLimit = services.AnnualAllowance(source.Type)
+	if req.Provider != "" {
+		metadata.Provider = req.Provider
+	}
+
+	portfolio := &models.Portfolio{
+		UserID:      userID,
+		Name:        req.Name,
+		Type:        source.Type,
+		Currency:    source.Currency,
+		Description: source.Description,
+		Metadata:    &metadata,
+	}
+
+	if err := h.portfolioRepo.Create(r.Context(), portfolio); err != nil {
+		if errors.Is(err, repository.ErrPortfolioAlreadyExists) {
+			Error(w, http.StatusConflict, "Portfolio name already exists")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to create portfolio")
+		return
+	}
+
+	if err := h.copyPortfolioContents(r, source.ID, portfolio.ID, req.IncludeHoldings); err != nil {
+		// Don't leave a half-copied portfolio behind
+		_ = h.portfolioRepo.Delete(r.Context(), portfolio.ID)
+		Error(w, http.StatusInternalServerError, "Failed to duplicate portfolio")
+		return
+	}
+
+	JSON(w, http.StatusCreated, portfolio)
+}
+
+// copyPortfolioContents copies the target allocation and, if asked, the holdings
+func (h *PortfolioHandler) copyPortfolioContents(r *http.Request, fromID, toID uuid.UUID, includeHoldings bool) error {
+	targets, err := h.targetRepo.GetByPortfolioID(r.Context(), fromID)
+	if err != nil {
+		return err
+	}
+	if len(targets) > 0 {
+		if err := h.targetRepo.Replace(r.Context(), toID, targets); err != nil {
+			return err
+		}
+	}
+
+	if !includeHoldings {
+		return nil
+	}
+
+	holdings, err := h.holdingRepo.GetByPortfolioID(r.Context(), fromID)
+	if err != nil {
+		return err
+	}
+	for _, holding := range holdings {
+		copied := &models.Holding{
+			PortfolioID: toID,
+			AssetID:     holding.AssetID,
+			Quantity:    holding.Quantity,
+			AverageCost: holding.AverageCost,
+			PurchasedAt: holding.PurchasedAt,
+		}
+		if err := h.holdingRepo.Create(r.Context(), copied); err != nil {
+			return err
+		}
+	}
+	if len(holdings) > 0 {
+		h.lots.SyncPortfolio(r.Context(), toID)
+	}
+
+	return nil
+}
+
 func (h *PortfolioHandler) Summary(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
@@ -323,7 +479,7 @@ func (h *PortfolioHandler) Summary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	summary, err := h.portfolioRepo.GetSummary(r.Context(), portfolioID)
+	portfolio, err := h.portfolioRepo.GetByID(r.Context(), portfolioID)
 	if err != nil {
 		if errors.Is(err, repository.ErrPortfolioNotFound) {
 			Error(w, http.StatusNotFound, "Portfolio not found")
@@ -333,6 +489,22 @@ func (h *PortfolioHandler) Summary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	summary, err := h.netWorthService.PortfolioSummary(r.Context(), userID, portfolio)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to get summary")
+		return
+	}
+	summary.Allowance, err = h.allowances.Status(r.Context(), portfolio, taxyear.UKOf(time.Now()))
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to get summary")
+		return
+	}
+	summary.Pension, err = h.allowances.PensionStatus(r.Context(), portfolio, taxyear.UK.Current())
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to get summary")
+		return
+	}
+
 	JSON(w, http.StatusOK, summary)
 }
 
@@ -371,3 +543,13 @@ func (h *PortfolioHandler) Holdings(w http.ResponseWriter, r *http.Request) {
 
 	JSON(w, http.StatusOK, holdings)
 }
+
+// validTaxYear reports whether a portfolio's metadata tax year is empty or a UK tax
+// year such as 2024/25. ISA and JISA subscriptions always follow the UK tax year.
+func validTaxYear(value string) bool {
+	if value == "" {
+		return true
+	}
+	_, ok := taxyear.UK.Parse(value)
+	return ok
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+type PortfolioDashboardHandler struct {
+	portfolioRepo *repository.PortfolioRepository
+	dashboards    *services.PortfolioDashboardService
+}
+
+func NewPortfolioDashboardHandler(portfolioRepo *repository.PortfolioRepository, dashboards *services.PortfolioDashboardService) *PortfolioDashboardHandler {
+	return &PortfolioDashboardHandler{
+		portfolioRepo: portfolioRepo,
+		dashboards:    dashboards,
+	}
+}
+
+// Get returns the panels for the portfolio's type, e.g. the retirement projection for a
+// SIPP, interest and maturity for savings or the coin breakdown for crypto. growth_rate
+// (% per year, default 5) sets the return assumed by projections.
+func (h *PortfolioDashboardHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	portfolioID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid portfolio ID")
+		return
+	}
+
+	growthRate := services.DefaultRetirementGrowthRate
+	if v := r.URL.Query().Get("growth_rate"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(rate) || rate < -50 || rate > 50 {
+			Error(w, http.StatusBadRequest, "Invalid growth rate")
+			return
+		}
+		growthRate = rate
+	}
+
+	portfolio, err := h.portfolioRepo.GetByID(r.Context(), portfolioID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPortfolioNotFound) {
+			Error(w, http.StatusNotFound, "Portfolio not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+	if portfolio.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	dashboard, err := h.dashboards.Build(r.Context(), userID, portfolio, growthRate)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to build portfolio dashboard")
+		return
+	}
+
+	JSON(w, http.StatusOK, dashboard)
+}
@@ -0,0 +1,541 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+	"github.com/mark-regan/wellf/pkg/validator"
+)
+
+type PortfolioTemplateHandler struct {
+	templateRepo  *repository.PortfolioTemplateRepository
+	portfolioRepo *repository.PortfolioRepository
+	assetRepo     *repository.AssetRepository
+	targetRepo    *repository.AllocationTargetRepository
+	rebalance     *services.RebalanceService
+}
+
+func NewPortfolioTemplateHandler(
+	templateRepo *repository.PortfolioTemplateRepository,
+	portfolioRepo *repository.PortfolioRepository,
+	assetRepo *repository.AssetRepository,
+	targetRepo *repository.AllocationTargetRepository,
+	rebalance *services.RebalanceService,
+) *PortfolioTemplateHandler {
+	return &PortfolioTemplateHandler{
+		templateRepo:  templateRepo,
+		portfolioRepo: portfolioRepo,
+		assetRepo:     assetRepo,
+		targetRepo:    targetRepo,
+		rebalance:     rebalance,
+	}
+}
+
+// PortfolioTemplateRequest creates or updates a template. On create, portfolio_id saves
+// that portfolio's current targets; otherwise targets are given directly.
+type PortfolioTemplateRequest struct {
+	Name        string                    `json:"name"`
+	Description string                    `json:"description"`
+	PortfolioID *uuid.UUID                `json:"portfolio_id,omitempty"`
+	Targets     []AllocationTargetRequest `json:"targets"`
+}
+
+// validate checks the request, returning an error message if it is invalid
+func (req *PortfolioTemplateRequest) validate() string {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return "Name is required"
+	}
+	if len(req.Name) > 255 {
+		return "Name must be 255 characters or fewer"
+	}
+	if req.PortfolioID != nil && len(req.Targets) > 0 {
+		return "Give either portfolio_id or targets, not both"
+	}
+	targets := AllocationTargetsRequest{Targets: req.Targets}
+	return targets.validate()
+}
+
+type ApplyTemplateRequest struct {
+	TemplateID uuid.UUID `json:"template_id"`
+}
+
+// CloneTemplateRequest opens a new portfolio following a template. The name defaults to
+// the template's, the type to GIA and the currency to GBP.
+type CloneTemplateRequest struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Currency    string `json:"currency"`
+	Description string `json:"description"`
+	Provider    string `json:"provider"`
+}
+
+func (h *PortfolioTemplateHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	templates, err := h.templateRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolio templates")
+		return
+	}
+
+	if templates == nil {
+		templates = []*models.PortfolioTemplate{}
+	}
+
+	JSON(w, http.StatusOK, templates)
+}
+
+func (h *PortfolioTemplateHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req PortfolioTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+	if req.PortfolioID == nil && len(req.Targets) == 0 {
+		Error(w, http.StatusBadRequest, "A template needs portfolio_id or at least one target")
+		return
+	}
+
+	var targets []models.TemplateTarget
+	if req.PortfolioID != nil {
+		portfolio, err := h.portfolioRepo.GetByID(r.Context(), *req.PortfolioID)
+		if err != nil {
+			if errors.Is(err, repository.ErrPortfolioNotFound) {
+				Error(w, http.StatusNotFound, "Portfolio not found")
+				return
+			}
+			Error(w, http.StatusInternalServerError, "Failed to fetch portfolio")
+			return
+		}
+		if portfolio.UserID != userID {
+			Error(w, http.StatusForbidden, "Access denied")
+			return
+		}
+
+		saved, err := h.targetRepo.GetByPortfolioID(r.Context(), portfolio.ID)
+		if err != nil {
+			Error(w, http.StatusInternalServerError, "Failed to get allocation targets")
+			return
+		}
+		if len(saved) == 0 {
+			Error(w, http.StatusBadRequest, "The portfolio has no target allocation to save")
+			return
+		}
+		for _, t := range saved {
+			target := models.TemplateTarget{AssetID: t.AssetID, AssetType: t.AssetType, TargetPct: t.TargetPct}
+			if t.Asset != nil {
+				target.Symbol = t.Asset.Symbol
+			}
+			targets = append(targets, target)
+		}
+	} else {
+		var err error
+		targets, err = h.templateTargets(r, req.Targets)
+		if err != nil {
+			if errors.Is(err, repository.ErrAssetNotFound) {
+				Error(w, http.StatusBadRequest, "Asset not found")
+				return
+			}
+			Error(w, http.StatusInternalServerError, "Failed to verify asset")
+			return
+		}
+	}
+
+	template := &models.PortfolioTemplate{
+		UserID:      userID,
+		Name:        req.Name,
+		Description: strings.TrimSpace(req.Description),
+		Targets:     targets,
+	}
+
+	if err := h.templateRepo.Create(r.Context(), template); err != nil {
+		if errors.Is(err, repository.ErrPortfolioTemplateAlreadyExists) {
+			Error(w, http.StatusConflict, "A portfolio template with this name already exists")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to create portfolio template")
+		return
+	}
+
+	JSON(w, http.StatusCreated, template)
+}
+
+func (h *PortfolioTemplateHandler) Get(w http.ResponseWriter, r *http.Request) {
+	template, ok := h.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	JSON(w, http.StatusOK, template)
+}
+
+// Update renames the template and, if targets are given, replaces them
+func (h *PortfolioTemplateHandler) Update(w http.ResponseWriter, r *http.Request) {
+	template, ok := h.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	var req PortfolioTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.PortfolioID != nil {
+		Error(w, http.StatusBadRequest, "portfolio_id can only be given when creating a template")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if len(req.Targets) > 0 {
+		targets, err := h.templateTargets(r, req.Targets)
+		if err != nil {
+			if errors.Is(err, repository.ErrAssetNotFound) {
+				Error(w, http.StatusBadRequest, "Asset not found")
+				return
+			}
+			Error(w, http.StatusInternalServerError, "Failed to verify asset")
+			return
+		}
+		template.Targets = targets
+	}
+	template.Name = req.Name
+	template.Description = strings.TrimSpace(req.Description)
+
+	if err := h.templateRepo.Update(r.Context(), template); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrPortfolioTemplateNotFound):
+			Error(w, http.StatusNotFound, "Portfolio template not found")
+		case errors.Is(err, repository.ErrPortfolioTemplateAlreadyExists):
+			Error(w, http.StatusConflict, "A portfolio template with this name already exists")
+		default:
+			Error(w, http.StatusInternalServerError, "Failed to update portfolio template")
+		}
+		return
+	}
+
+	JSON(w, http.StatusOK, template)
+}
+
+func (h *PortfolioTemplateHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	template, ok := h.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.templateRepo.Delete(r.Context(), template.ID); err != nil {
+		if errors.Is(err, repository.ErrPortfolioTemplateNotFound) {
+			Error(w, http.StatusNotFound, "Portfolio template not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to delete portfolio template")
+		return
+	}
+
+	NoContent(w)
+}
+
+// Apply replaces a portfolio's target allocation with a template's
+func (h *PortfolioTemplateHandler) Apply(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	portfolioID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid portfolio ID")
+		return
+	}
+
+	portfolio, err := h.portfolioRepo.GetByID(r.Context(), portfolioID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPortfolioNotFound) {
+			Error(w, http.StatusNotFound, "Portfolio not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+	if portfolio.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return
+	}
+	switch portfolio.Type {
+	case models.PortfolioTypeCash, models.PortfolioTypeSavings, models.PortfolioTypeFixedAssets:
+		Error(w, http.StatusBadRequest, "Target allocations are only available for investment portfolios")
+		return
+	}
+
+	var req ApplyTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	template, err := h.templateRepo.GetByID(r.Context(), req.TemplateID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPortfolioTemplateNotFound) {
+			Error(w, http.StatusNotFound, "Portfolio template not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolio template")
+		return
+	}
+	if template.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	if err := h.targetRepo.Replace(r.Context(), portfolio.ID, allocationTargets(template)); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to save allocation targets")
+		return
+	}
+
+	saved, err := h.targetRepo.GetByPortfolioID(r.Context(), portfolio.ID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to get allocation targets")
+		return
+	}
+	if saved == nil {
+		saved = []*models.AllocationTarget{}
+	}
+
+	JSON(w, http.StatusOK, saved)
+}
+
+// Clone creates a new, empty portfolio with the template's target allocation, ready for
+// its first deposit to be invested using the rebalance report
+func (h *PortfolioTemplateHandler) Clone(w http.ResponseWriter, r *http.Request) {
+	template, ok := h.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	var req CloneTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		req.Name = template.Name
+	}
+	if req.Type == "" {
+		req.Type = models.PortfolioTypeGIA
+	}
+	if req.Currency == "" {
+		req.Currency = "GBP"
+	}
+	req.Description = strings.TrimSpace(req.Description)
+	if req.Description == "" {
+		req.Description = template.Description
+	}
+
+	if !validator.IsValidPortfolioType(req.Type) {
+		Error(w, http.StatusBadRequest, "Invalid portfolio type")
+		return
+	}
+	switch req.Type {
+	case models.PortfolioTypeCash, models.PortfolioTypeSavings, models.PortfolioTypeFixedAssets:
+		Error(w, http.StatusBadRequest, "Target allocations are only available for investment portfolios")
+		return
+	}
+	if !validator.IsValidCurrency(req.Currency) {
+		Error(w, http.StatusBadRequest, "Invalid currency")
+		return
+	}
+
+	portfolio := &models.Portfolio{
+		UserID:      template.UserID,
+		Name:        req.Name,
+		Type:        req.Type,
+		Currency:    req.Currency,
+		Description: req.Description,
+		Metadata: &models.PortfolioMetadata{
+			Provider:          req.Provider,
+			ContributionLimit: services.AnnualAllowance(req.Type),
+		},
+	}
+
+	if err := h.portfolioRepo.Create(r.Context(), portfolio); err != nil {
+		if errors.Is(err, repository.ErrPortfolioAlreadyExists) {
+			Error(w, http.StatusConflict, "Portfolio name already exists")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to create portfolio")
+		return
+	}
+
+	if err := h.targetRepo.Replace(r.Context(), portfolio.ID, allocationTargets(template)); err != nil {
+		// Don't leave a portfolio without its targets behind
+		_ = h.portfolioRepo.Delete(r.Context(), portfolio.ID)
+		Error(w, http.StatusInternalServerError, "Failed to save allocation targets")
+		return
+	}
+
+	JSON(w, http.StatusCreated, portfolio)
+}
+
+// Compare reports how far a portfolio is from a template's target allocation, with the
+// deviation of each asset or asset type and the trades that would close it. The
+// portfolio's own targets are left alone.
+func (h *PortfolioTemplateHandler) Compare(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	portfolioID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid portfolio ID")
+		return
+	}
+	templateID, err := uuid.Parse(r.URL.Query().Get("template_id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid template ID")
+		return
+	}
+
+	portfolio, err := h.portfolioRepo.GetByID(r.Context(), portfolioID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPortfolioNotFound) {
+			Error(w, http.StatusNotFound, "Portfolio not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+	if portfolio.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	template, err := h.templateRepo.GetByID(r.Context(), templateID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPortfolioTemplateNotFound) {
+			Error(w, http.StatusNotFound, "Portfolio template not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolio template")
+		return
+	}
+	if template.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	// The report names asset lines and sizes trades in units from the joined asset
+	targets := allocationTargets(template)
+	for _, t := range targets {
+		if t.AssetID == nil {
+			continue
+		}
+		asset, err := h.assetRepo.GetByID(r.Context(), *t.AssetID)
+		if err != nil {
+			if errors.Is(err, repository.ErrAssetNotFound) {
+				continue
+			}
+			Error(w, http.StatusInternalServerError, "Failed to fetch asset")
+			return
+		}
+		t.Asset = asset
+	}
+
+	report, err := h.rebalance.Compare(r.Context(), userID, portfolio, targets)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to compare portfolio")
+		return
+	}
+
+	JSON(w, http.StatusOK, report)
+}
+
+// allocationTargets converts the template's targets into targets for a portfolio
+func allocationTargets(template *models.PortfolioTemplate) []*models.AllocationTarget {
+	targets := make([]*models.AllocationTarget, 0, len(template.Targets))
+	for _, t := range template.Targets {
+		targets = append(targets, &models.AllocationTarget{
+			AssetID:   t.AssetID,
+			AssetType: t.AssetType,
+			TargetPct: t.TargetPct,
+		})
+	}
+	return targets
+}
+
+// templateTargets resolves requested targets, looking up the symbol of each asset
+func (h *PortfolioTemplateHandler) templateTargets(r *http.Request, reqTargets []AllocationTargetRequest) ([]models.TemplateTarget, error) {
+	targets := make([]models.TemplateTarget, 0, len(reqTargets))
+	for _, t := range reqTargets {
+		target := models.TemplateTarget{AssetID: t.AssetID, AssetType: t.AssetType, TargetPct: t.TargetPct}
+		if t.AssetID != nil {
+			asset, err := h.assetRepo.GetByID(r.Context(), *t.AssetID)
+			if err != nil {
+				return nil, err
+			}
+			target.Symbol = asset.Symbol
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+func (h *PortfolioTemplateHandler) ownedTemplate(w http.ResponseWriter, r *http.Request) (*models.PortfolioTemplate, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	templateID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid template ID")
+		return nil, false
+	}
+
+	template, err := h.templateRepo.GetByID(r.Context(), templateID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPortfolioTemplateNotFound) {
+			Error(w, http.StatusNotFound, "Portfolio template not found")
+			return nil, false
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolio template")
+		return nil, false
+	}
+
+	if template.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return nil, false
+	}
+
+	return template, true
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+type PriceAlertHandler struct {
+	alertRepo    *repository.PriceAlertRepository
+	yahooService *services.YahooService
+}
+
+func NewPriceAlertHandler(alertRepo *repository.PriceAlertRepository, yahooService *services.YahooService) *PriceAlertHandler {
+	return &PriceAlertHandler{
+		alertRepo:    alertRepo,
+		yahooService: yahooService,
+	}
+}
+
+type CreatePriceAlertRequest struct {
+	Symbol    string  `json:"symbol"`
+	Condition string  `json:"condition"`
+	Threshold float64 `json:"threshold"`
+	Note      string  `json:"note"`
+}
+
+// validate checks the request, returning an error message if it is invalid
+func (req *CreatePriceAlertRequest) validate() string {
+	req.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+	req.Condition = strings.ToUpper(req.Condition)
+	req.Note = strings.TrimSpace(req.Note)
+
+	if req.Symbol == "" {
+		return "Symbol is required"
+	}
+	if len(req.Note) > 500 {
+		return "Note must be 500 characters or fewer"
+	}
+	return services.IsValidPriceAlert(req.Condition, req.Threshold)
+}
+
+type UpdatePriceAlertRequest struct {
+	Threshold *float64 `json:"threshold"`
+	Note      *string  `json:"note"`
+	IsActive  *bool    `json:"is_active"`
+}
+
+func (h *PriceAlertHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	alerts, err := h.alertRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch price alerts")
+		return
+	}
+
+	if alerts == nil {
+		alerts = []*models.PriceAlert{}
+	}
+
+	JSON(w, http.StatusOK, alerts)
+}
+
+func (h *PriceAlertHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreatePriceAlertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	asset, err := h.yahooService.GetOrCreateAsset(r.Context(), req.Symbol)
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Failed to find asset: "+err.Error())
+		return
+	}
+
+	alert := &models.PriceAlert{
+		UserID:    userID,
+		AssetID:   asset.ID,
+		Condition: req.Condition,
+		Threshold: req.Threshold,
+		Note:      req.Note,
+		IsActive:  true,
+		Asset:     asset,
+	}
+
+	if err := h.alertRepo.Create(r.Context(), alert); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to create price alert")
+		return
+	}
+
+	JSON(w, http.StatusCreated, alert)
+}
+
+func (h *PriceAlertHandler) Get(w http.ResponseWriter, r *http.Request) {
+	alert, ok := h.ownedAlert(w, r)
+	if !ok {
+		return
+	}
+
+	JSON(w, http.StatusOK, alert)
+}
+
+// Update changes an alert's threshold or note, or switches it on or off. Switching a
+// triggered level alert back on re-arms it.
+func (h *PriceAlertHandler) Update(w http.ResponseWriter, r *http.Request) {
+	alert, ok := h.ownedAlert(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdatePriceAlertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Threshold != nil {
+		if msg := services.IsValidPriceAlert(alert.Condition, *req.Threshold); msg != "" {
+			Error(w, http.StatusBadRequest, msg)
+			return
+		}
+		alert.Threshold = *req.Threshold
+	}
+	if req.Note != nil {
+		note := strings.TrimSpace(*req.Note)
+		if len(note) > 500 {
+			Error(w, http.StatusBadRequest, "Note must be 500 characters or fewer")
+			return
+		}
+		alert.Note = note
+	}
+	if req.IsActive != nil {
+		alert.IsActive = *req.IsActive
+	}
+
+	if err := h.alertRepo.Update(r.Context(), alert); err != nil {
+		if errors.Is(err, repository.ErrPriceAlertNotFound) {
+			Error(w, http.StatusNotFound, "Price alert not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to update price alert")
+		return
+	}
+
+	JSON(w, http.StatusOK, alert)
+}
+
+func (h *PriceAlertHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	alert, ok := h.ownedAlert(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.alertRepo.Delete(r.Context(), alert.ID); err != nil {
+		if errors.Is(err, repository.ErrPriceAlertNotFound) {
+			Error(w, http.StatusNotFound, "Price alert not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to delete price alert")
+		return
+	}
+
+	NoContent(w)
+}
+
+// ownedAlert loads the alert from the URL, writing an error response if it is missing or not the user's
+func (h *PriceAlertHandler) ownedAlert(w http.ResponseWriter, r *http.Request) (*models.PriceAlert, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	alertID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid price alert ID")
+		return nil, false
+	}
+
+	alert, err := h.alertRepo.GetByID(r.Context(), alertID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPriceAlertNotFound) {
+			Error(w, http.StatusNotFound, "Price alert not found")
+			return nil, false
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch price alert")
+		return nil, false
+	}
+
+	if alert.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return nil, false
+	}
+
+	return alert, true
+}
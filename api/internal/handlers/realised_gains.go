@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+// RealisedGainRow is the gain or loss realised on one asset in one portfolio, in the
+// portfolio's currency, with sales in other currencies converted at the rate on their date
+type RealisedGainRow struct {
+	PortfolioID     uuid.UUID `json:"portfolio_id"`
+	PortfolioName   string    `json:"portfolio_name"`
+	PortfolioType   string    `json:"portfolio_type"`
+	Sheltered       bool      `json:"sheltered"`
+	CostBasisMethod string    `json:"cost_basis_method"`
+	AssetID         uuid.UUID `json:"asset_id"`
+	Symbol          string    `json:"symbol"`
+	Name            string    `json:"name"`
+	Currency        string    `json:"currency"`
+	Disposals       int       `json:"disposals"`
+	Quantity        float64   `json:"quantity"`
+	Proceeds        float64   `json:"proceeds"`
+	CostBasis       float64   `json:"cost_basis"`
+	Gain            float64   `json:"gain"`
+}
+
+// RealisedGainTotal sums the rows outside tax wrappers in one currency. Gains and
+// Losses add up the individual sales, so losses aren't netted against gains.
+type RealisedGainTotal struct {
+	Currency  string  `json:"currency"`
+	Proceeds  float64 `json:"proceeds"`
+	CostBasis float64 `json:"cost_basis"`
+	Gains     float64 `json:"gains"`
+	Losses    float64 `json:"losses"`
+	Net       float64 `json:"net"`
+}
+
+type RealisedGainsResponse struct {
+	TaxYear string              `json:"tax_year"`
+	From    string              `json:"from"`
+	To      string              `json:"to"`
+	Rows    []RealisedGainRow   `json:"rows"`
+	Totals  []RealisedGainTotal `json:"totals"`
+}
+
+// realisedGainKey identifies one asset in one portfolio
+type realisedGainKey struct {
+	portfolioID uuid.UUID
+	assetID     uuid.UUID
+}
+
+// RealisedGains returns the profit or loss on SELL transactions in a tax year
+// (year=2024/25, default current), matched against cost basis under each portfolio's
+// cost basis method and grouped by portfolio and asset. format=csv downloads the rows.
+func (h *ReportHandler) RealisedGains(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+
+	year, ok := parseTaxYearParam(w, r, user, "year")
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		Error(w, http.StatusBadRequest, "Invalid format (use json or csv)")
+		return
+	}
+
+	portfolios, err := h.portfolioRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolios")
+		return
+	}
+	portfolioByID := make(map[uuid.UUID]*models.Portfolio, len(portfolios))
+	ids := make([]uuid.UUID, len(portfolios))
+	for i, p := range portfolios {
+		portfolioByID[p.ID] = p
+		ids[i] = p.ID
+	}
+
+	txs, err := h.txRepo.GetByPortfolioIDs(r.Context(), ids)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch transactions")
+		return
+	}
+
+	// The whole history of an asset is needed to know the cost of what was sold, but
+	// only assets sold during the year are replayed
+	byAsset := make(map[realisedGainKey][]*models.Transaction)
+	var sold []realisedGainKey
+	isSold := make(map[realisedGainKey]bool)
+	for _, tx := range txs {
+		if tx.AssetID == nil {
+			continue
+		}
+		key := realisedGainKey{portfolioID: tx.PortfolioID, assetID: *tx.AssetID}
+		if tx.TransactionType == models.TransactionTypeSell && year.Contains(tx.TransactionDate) && !isSold[key] {
+			isSold[key] = true
+			sold = append(sold, key)
+		}
+		byAsset[key] = append(byAsset[key], tx)
+	}
+
+	resp := RealisedGainsResponse{
+		TaxYear: year.String(),
+		From:    year.From().Format("2006-01-02"),
+		To:      year.To().Format("2006-01-02"),
+		Rows:    []RealisedGainRow{},
+		Totals:  []RealisedGainTotal{},
+	}
+	totals := make(map[string]*RealisedGainTotal)
+
+	for _, key := range sold {
+		portfolio := portfolioByID[key.portfolioID]
+		method := services.CostBasisMethod(portfolio)
+
+		holding, err := h.holdingRepo.GetByPortfolioAndAsset(r.Context(), key.portfolioID, key.assetID)
+		if err != nil && !errors.Is(err, repository.ErrHoldingNotFound) {
+			Error(w, http.StatusInternalServerError, "Failed to fetch holding")
+			return
+		}
+
+		assetTxs := byAsset[key]
+		row := RealisedGainRow{
+			PortfolioID:     portfolio.ID,
+			PortfolioName:   portfolio.Name,
+			PortfolioType:   portfolio.Type,
+			Sheltered:       repository.IsTaxSheltered(portfolio.Type),
+			CostBasisMethod: method,
+			AssetID:         key.assetID,
+			Currency:        portfolio.Currency,
+		}
+		if asset := assetTxs[0].Asset; asset != nil {
+			row.Symbol = asset.Symbol
+			row.Name = asset.Name
+		}
+
+		var gains, losses float64
+		for _, d := range services.BuildDisposals(assetTxs, holding, method) {
+			if !year.Contains(d.Transaction.TransactionDate) {
+				continue
+			}
+			row.Disposals++
+			row.Quantity += d.Quantity
+			row.Proceeds += d.Proceeds
+			row.CostBasis += d.CostBasis
+			row.Gain += d.Gain
+			if d.Gain >= 0 {
+				gains += d.Gain
+			} else {
+				losses -= d.Gain
+			}
+		}
+		row.Proceeds = roundMoney(row.Proceeds)
+		row.CostBasis = roundMoney(row.CostBasis)
+		row.Gain = roundMoney(row.Gain)
+		resp.Rows = append(resp.Rows, row)
+
+		if row.Sheltered {
+			continue
+		}
+		t, ok := totals[row.Currency]
+		if !ok {
+			t = &RealisedGainTotal{Currency: row.Currency}
+			totals[row.Currency] = t
+		}
+		t.Proceeds += row.Proceeds
+		t.CostBasis += row.CostBasis
+		t.Gains += gains
+		t.Losses += losses
+	}
+
+	sort.Slice(resp.Rows, func(i, j int) bool {
+		a, b := resp.Rows[i], resp.Rows[j]
+		if a.PortfolioName != b.PortfolioName {
+			return a.PortfolioName < b.PortfolioName
+		}
+		return a.Symbol < b.Symbol
+	})
+
+	for _, t := range totals {
+		t.Proceeds = roundMoney(t.Proceeds)
+		t.CostBasis = roundMoney(t.CostBasis)
+		t.Gains = roundMoney(t.Gains)
+		t.Losses = roundMoney(t.Losses)
+		t.Net = roundMoney(t.Gains - t.Losses)
+		resp.Totals = append(resp.Totals, *t)
+	}
+	sort.Slice(resp.Totals, func(i, j int) bool { return resp.Totals[i].Currency < resp.Totals[j].Currency })
+
+	if format == "csv" {
+		writeRealisedGainsCSV(w, &resp)
+		return
+	}
+
+	JSON(w, http.StatusOK, resp)
+}
+
+// writeRealisedGainsCSV sends the report rows as a CSV download
+func writeRealisedGainsCSV(w http.ResponseWriter, resp *RealisedGainsResponse) {
+	filename := fmt.Sprintf("realised-gains-%s.csv", strings.ReplaceAll(resp.TaxYear, "/", "-"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	money := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{
+		"portfolio", "portfolio_type", "sheltered", "cost_basis_method", "symbol", "name",
+		"currency", "disposals", "quantity", "proceeds", "cost_basis", "gain",
+	})
+	for _, row := range resp.Rows {
+		_ = cw.Write([]string{
+			row.PortfolioName,
+			row.PortfolioType,
+			strconv.FormatBool(row.Sheltered),
+			row.CostBasisMethod,
+			row.Symbol,
+			row.Name,
+			row.Currency,
+			strconv.Itoa(row.Disposals),
+			strconv.FormatFloat(row.Quantity, 'f', -1, 64),
+			money(row.Proceeds),
+			money(row.CostBasis),
+			money(row.Gain),
+		})
+	}
+	cw.Flush()
+}
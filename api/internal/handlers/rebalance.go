@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+	"github.com/mark-regan/wellf/pkg/validator"
+)
+
+type RebalanceHandler struct {
+	portfolioRepo *repository.PortfolioRepository
+	assetRepo     *repository.AssetRepository
+	targetRepo    *repository.AllocationTargetRepository
+	rebalance     *services.RebalanceService
+}
+
+func NewRebalanceHandler(portfolioRepo *repository.PortfolioRepository, assetRepo *repository.AssetRepository, targetRepo *repository.AllocationTargetRepository, rebalance *services.RebalanceService) *RebalanceHandler {
+	return &RebalanceHandler{
+		portfolioRepo: portfolioRepo,
+		assetRepo:     assetRepo,
+		targetRepo:    targetRepo,
+		rebalance:     rebalance,
+	}
+}
+
+type AllocationTargetRequest struct {
+	AssetID   *uuid.UUID `json:"asset_id,omitempty"`
+	AssetType *string    `json:"asset_type,omitempty"`
+	TargetPct float64    `json:"target_pct"`
+}
+
+type AllocationTargetsRequest struct {
+	Targets []AllocationTargetRequest `json:"targets"`
+}
+
+// validate checks the request, returning an error message if it is invalid. Targets must
+// all be by asset or all by asset type, without repeats, and add up to 100%.
+func (req *AllocationTargetsRequest) validate() string {
+	if len(req.Targets) == 0 {
+		return ""
+	}
+
+	byAsset := req.Targets[0].AssetID != nil
+	seen := make(map[string]bool, len(req.Targets))
+	var total float64
+	for _, t := range req.Targets {
+		if (t.AssetID == nil) == (t.AssetType == nil) {
+			return "Each target needs either asset_id or asset_type"
+		}
+		if (t.AssetID != nil) != byAsset {
+			return "Targets must all be by asset or all by asset type"
+		}
+		if t.TargetPct <= 0 || t.TargetPct > 100 {
+			return "Target percentages must be between 0 and 100"
+		}
+
+		key := ""
+		if t.AssetID != nil {
+			key = t.AssetID.String()
+		} else {
+			if !validator.IsValidAssetType(*t.AssetType) {
+				return "Invalid asset type"
+			}
+			key = *t.AssetType
+		}
+		if seen[key] {
+			return "Each asset or asset type can only have one target"
+		}
+		seen[key] = true
+		total += t.TargetPct
+	}
+
+	if math.Abs(total-100) > 0.01 {
+		return "Target percentages must add up to 100"
+	}
+	return ""
+}
+
+// Targets returns the portfolio's target allocation
+func (h *RebalanceHandler) Targets(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.ownedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	targets, err := h.targetRepo.GetByPortfolioID(r.Context(), portfolio.ID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to get allocation targets")
+		return
+	}
+	if targets == nil {
+		targets = []*models.AllocationTarget{}
+	}
+
+	JSON(w, http.StatusOK, targets)
+}
+
+// SetTargets replaces the portfolio's target allocation. An empty list clears it.
+func (h *RebalanceHandler) SetTargets(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.ownedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	var req AllocationTargetsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	targets := make([]*models.AllocationTarget, 0, len(req.Targets))
+	for _, t := range req.Targets {
+		if t.AssetID != nil {
+			if _, err := h.assetRepo.GetByID(r.Context(), *t.AssetID); err != nil {
+				if errors.Is(err, repository.ErrAssetNotFound) {
+					Error(w, http.StatusBadRequest, "Asset not found")
+					return
+				}
+				Error(w, http.StatusInternalServerError, "Failed to verify asset")
+				return
+			}
+		}
+		targets = append(targets, &models.AllocationTarget{
+			AssetID:   t.AssetID,
+			AssetType: t.AssetType,
+			TargetPct: t.TargetPct,
+		})
+	}
+
+	if err := h.targetRepo.Replace(r.Context(), portfolio.ID, targets); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to save allocation targets")
+		return
+	}
+
+	saved, err := h.targetRepo.GetByPortfolioID(r.Context(), portfolio.ID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to get allocation targets")
+		return
+	}
+	if saved == nil {
+		saved = []*models.AllocationTarget{}
+	}
+
+	JSON(w, http.StatusOK, saved)
+}
+
+// Rebalance returns the portfolio's current weights against its targets, with the drift
+// and the buy/sell amounts needed to get back on target
+func (h *RebalanceHandler) Rebalance(w http.ResponseWriter, r *http.Request) {
+	portfolio, ok := h.ownedPortfolio(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r.Context())
+	report, err := h.rebalance.Report(r.Context(), userID, portfolio)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to build rebalance report")
+		return
+	}
+
+	JSON(w, http.StatusOK, report)
+}
+
+// ownedPortfolio loads the investment portfolio from the URL, writing an error response if
+// it is missing, not the user's or holds no investments
+func (h *RebalanceHandler) ownedPortfolio(w http.ResponseWriter, r *http.Request) (*models.Portfolio, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	portfolioID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid portfolio ID")
+		return nil, false
+	}
+
+	portfolio, err := h.portfolioRepo.GetByID(r.Context(), portfolioID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPortfolioNotFound) {
+			Error(w, http.StatusNotFound, "Portfolio not found")
+			return nil, false
+		}
+		Error(w, http.StatusInternalServerError, "Failed to get portfolio")
+		return nil, false
+	}
+
+	if portfolio.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return nil, false
+	}
+
+	switch portfolio.Type {
+	case models.PortfolioTypeCash, models.PortfolioTypeSavings, models.PortfolioTypeFixedAssets:
+		Error(w, http.StatusBadRequest, "Target allocations are only available for investment portfolios")
+		return nil, false
+	}
+
+	return portfolio, true
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+type ReminderHandler struct {
+	reminderRepo   *repository.ReminderRepository
+	webhookService *services.WebhookService
+}
+
+func NewReminderHandler(reminderRepo *repository.ReminderRepository, webhookService *services.WebhookService) *ReminderHandler {
+	return &ReminderHandler{reminderRepo: reminderRepo, webhookService: webhookService}
+}
+
+// List returns the user's reminders by due date. Completed reminders are only included
+// with include_completed=true.
+func (h *ReminderHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	includeCompleted := r.URL.Query().Get("include_completed") == "true"
+
+	reminders, err := h.reminderRepo.GetByUserID(r.Context(), userID, includeCompleted)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch reminders")
+		return
+	}
+
+	if reminders == nil {
+		reminders = []*models.Reminder{}
+	}
+
+	JSON(w, http.StatusOK, reminders)
+}
+
+type UpdateReminderRequest struct {
+	Completed         *bool   `json:"completed"`
+	Priority          *string `json:"priority"`
+	EscalateAfterDays *int    `json:"escalate_after_days"`
+}
+
+func (req *UpdateReminderRequest) validate() string {
+	if req.Completed == nil && req.Priority == nil && req.EscalateAfterDays == nil {
+		return "Nothing to update"
+	}
+	if req.Priority != nil {
+		switch *req.Priority {
+		case models.ReminderPriorityLow, models.ReminderPriorityNormal, models.ReminderPriorityHigh, models.ReminderPriorityUrgent:
+		default:
+			return "Invalid priority (use LOW, NORMAL, HIGH or URGENT)"
+		}
+	}
+	if req.EscalateAfterDays != nil && (*req.EscalateAfterDays < 0 || *req.EscalateAfterDays > 365) {
+		return "Escalate after days must be between 0 and 365"
+	}
+	return ""
+}
+
+// Update marks a reminder as completed or reopens it, and sets its priority and
+// escalation period (escalate_after_days 0 falls back to the default for its source
+// type). The text and date come from the source notes, so they are changed by editing
+// the notes instead. Completing an open reminder sends a reminder.completed event to
+// the user's webhooks.
+func (h *ReminderHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	reminderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid reminder ID")
+		return
+	}
+
+	var req UpdateReminderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	belongs, err := h.reminderRepo.BelongsToUser(r.Context(), reminderID, userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to verify ownership")
+		return
+	}
+	if !belongs {
+		Error(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	before, err := h.reminderRepo.GetByID(r.Context(), reminderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrReminderNotFound) {
+			Error(w, http.StatusNotFound, "Reminder not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch reminder")
+		return
+	}
+
+	if req.Priority != nil || req.EscalateAfterDays != nil {
+		priority, escalateAfterDays := before.Priority, before.EscalateAfterDays
+		if req.Priority != nil {
+			priority = *req.Priority
+		}
+		if req.EscalateAfterDays != nil {
+			escalateAfterDays = req.EscalateAfterDays
+			if *escalateAfterDays == 0 {
+				escalateAfterDays = nil
+			}
+		}
+		if err := h.reminderRepo.SetEscalation(r.Context(), reminderID, priority, escalateAfterDays); err != nil {
+			if errors.Is(err, repository.ErrReminderNotFound) {
+				Error(w, http.StatusNotFound, "Reminder not found")
+				return
+			}
+			Error(w, http.StatusInternalServerError, "Failed to update reminder")
+			return
+		}
+	}
+
+	if req.Completed != nil {
+		if err := h.reminderRepo.SetCompleted(r.Context(), reminderID, *req.Completed); err != nil {
+			if errors.Is(err, repository.ErrReminderNotFound) {
+				Error(w, http.StatusNotFound, "Reminder not found")
+				return
+			}
+			Error(w, http.StatusInternalServerError, "Failed to update reminder")
+			return
+		}
+	}
+
+	reminder, err := h.reminderRepo.GetByID(r.Context(), reminderID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch reminder")
+		return
+	}
+
+	if reminder.Completed && !before.Completed {
+		h.webhookService.Emit(r.Context(), userID, models.WebhookEventReminderCompleted, reminder)
+	}
+
+	JSON(w, http.StatusOK, reminder)
+}
+
+// EscalationDefaults lists the user's escalation period for each reminder source type
+func (h *ReminderHandler) EscalationDefaults(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	defaults, err := h.reminderRepo.GetEscalationDefaults(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch escalation defaults")
+		return
+	}
+	if defaults == nil {
+		defaults = []*models.ReminderEscalationDefault{}
+	}
+
+	JSON(w, http.StatusOK, defaults)
+}
+
+// SaveEscalationDefault sets how many days HIGH and URGENT reminders from a source type
+// may go uncompleted, per escalation, unless they set their own. 0 removes the default.
+func (h *ReminderHandler) SaveEscalationDefault(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sourceType := strings.ToUpper(chi.URLParam(r, "sourceType"))
+	switch sourceType {
+	case models.ReminderSourceSavingsGoal, models.ReminderSourceFixedAsset, models.ReminderSourceTransaction:
+	default:
+		Error(w, http.StatusBadRequest, "Invalid source type (use SAVINGS_GOAL, FIXED_ASSET or TRANSACTION)")
+		return
+	}
+
+	var req struct {
+		EscalateAfterDays int `json:"escalate_after_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.EscalateAfterDays < 0 || req.EscalateAfterDays > 365 {
+		Error(w, http.StatusBadRequest, "Escalate after days must be between 0 and 365")
+		return
+	}
+
+	if req.EscalateAfterDays == 0 {
+		if err := h.reminderRepo.DeleteEscalationDefault(r.Context(), userID, sourceType); err != nil {
+			Error(w, http.StatusInternalServerError, "Failed to remove escalation default")
+			return
+		}
+		NoContent(w)
+		return
+	}
+
+	d := &models.ReminderEscalationDefault{SourceType: sourceType, EscalateAfterDays: req.EscalateAfterDays}
+	if err := h.reminderRepo.SaveEscalationDefault(r.Context(), userID, d); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to save escalation default")
+		return
+	}
+
+	JSON(w, http.StatusOK, d)
+}
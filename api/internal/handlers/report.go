@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+type ReportHandler struct {
+	txRepo         *repository.TransactionRepository
+	holdingRepo    *repository.HoldingRepository
+	userRepo       *repository.UserRepository
+	portfolioRepo  *repository.PortfolioRepository
+	cashRepo       *repository.CashAccountRepository
+	movementRepo   *repository.CashMovementRepository
+	fixedAssetRepo *repository.FixedAssetRepository
+	incomeRepo     *repository.IncomeRepository
+	fx             *services.CurrencyService
+}
+
+func NewReportHandler(
+	txRepo *repository.TransactionRepository,
+	holdingRepo *repository.HoldingRepository,
+	userRepo *repository.UserRepository,
+	portfolioRepo *repository.PortfolioRepository,
+	cashRepo *repository.CashAccountRepository,
+	movementRepo *repository.CashMovementRepository,
+	fixedAssetRepo *repository.FixedAssetRepository,
+	incomeRepo *repository.IncomeRepository,
+	fx *services.CurrencyService,
+) *ReportHandler {
+	return &ReportHandler{
+		txRepo:         txRepo,
+		holdingRepo:    holdingRepo,
+		userRepo:       userRepo,
+		portfolioRepo:  portfolioRepo,
+		cashRepo:       cashRepo,
+		movementRepo:   movementRepo,
+		fixedAssetRepo: fixedAssetRepo,
+		incomeRepo:     incomeRepo,
+		fx:             fx,
+	}
+}
+
+// CashFlowMonth is one row of the cash flow statement. Income is deposits, dividends,
+// interest and recurring income paid so far; outgoings are withdrawals and fees.
+type CashFlowMonth struct {
+	Month           string  `json:"month,omitempty"` // YYYY-MM
+	Deposits        float64 `json:"deposits"`
+	Withdrawals     float64 `json:"withdrawals"`
+	Dividends       float64 `json:"dividends"`
+	Interest        float64 `json:"interest"`
+	Fees            float64 `json:"fees"`
+	RecurringIncome float64 `json:"recurring_income"`
+	Income          float64 `json:"income"`
+	Outgoings       float64 `json:"outgoings"`
+	Net             float64 `json:"net"`
+}
+
+type CashFlowResponse struct {
+	Range    string          `json:"range"`
+	From     string          `json:"from"`
+	To       string          `json:"to"`
+	Currency string          `json:"currency"`
+	Months   []CashFlowMonth `json:"months"`
+	Totals   CashFlowMonth   `json:"totals"`
+}
+
+var reportRangePattern = regexp.MustCompile(`^(\d{1,3})([my])$`)
+
+// parseReportRange converts a range such as "12m" or "2y" into a number of months
+func parseReportRange(value string) (int, bool) {
+	match := reportRangePattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0, false
+	}
+	n, _ := strconv.Atoi(match[1])
+	if match[2] == "y" {
+		n *= 12
+	}
+	if n < 1 || n > 120 {
+		return 0, false
+	}
+	return n, true
+}
+
+// CashFlow returns a month-by-month income vs outgoings statement across all portfolios,
+// in the user's base currency. Transactions are taken in their portfolio's currency
+// where converted, otherwise their own, and converted at the current rate.
+func (h *ReportHandler) CashFlow(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	rangeStr := r.URL.Query().Get("range")
+	if rangeStr == "" {
+		rangeStr = "12m"
+	}
+	months, ok := parseReportRange(rangeStr)
+	if !ok {
+		Error(w, http.StatusBadRequest, "Invalid range (use e.g. 6m, 12m or 2y, up to 10y)")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+
+	// The range covers the current month plus the preceding months
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, -(months - 1), 0)
+
+	totals, err := h.txRepo.GetMonthlyTotalsByUserID(r.Context(), userID, from)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch transactions")
+		return
+	}
+
+	// Include every month in the range, even those without activity
+	rows := make([]CashFlowMonth, months)
+	index := make(map[string]int, months)
+	for i := range rows {
+		key := from.AddDate(0, i, 0).Format("2006-01")
+		rows[i].Month = key
+		index[key] = i
+	}
+
+	conv := h.fx.NewConverter(user.BaseCurrency)
+	for _, t := range totals {
+		i, ok := index[t.Month.Format("2006-01")]
+		if !ok {
+			continue
+		}
+		total := conv.Convert(r.Context(), t.Total, t.Currency).BaseAmount
+		switch t.TransactionType {
+		case models.TransactionTypeDeposit:
+			rows[i].Deposits += total
+		case models.TransactionTypeWithdrawal:
+			rows[i].Withdrawals += total
+		case models.TransactionTypeDividend:
+			rows[i].Dividends += total
+		case models.TransactionTypeInterest:
+			rows[i].Interest += total
+		case models.TransactionTypeFee:
+			rows[i].Fees += total
+		}
+	}
+
+	// Recurring income counts on each pay date up to today, from when it started
+	incomes, err := h.incomeRepo.GetByUserID(r.Context(), userID, true)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch incomes")
+		return
+	}
+	for _, income := range incomes {
+		for _, paid := range income.PayDates(from, now) {
+			if i, ok := index[paid.Format("2006-01")]; ok {
+				rows[i].RecurringIncome += conv.Convert(r.Context(), income.Amount, income.Currency).BaseAmount
+			}
+		}
+	}
+
+	var sum CashFlowMonth
+	for i := range rows {
+		row := &rows[i]
+		row.Income = row.Deposits + row.Dividends + row.Interest + row.RecurringIncome
+		row.Outgoings = row.Withdrawals + row.Fees
+		row.Net = row.Income - row.Outgoings
+
+		sum.Deposits += row.Deposits
+		sum.Withdrawals += row.Withdrawals
+		sum.Dividends += row.Dividends
+		sum.Interest += row.Interest
+		sum.Fees += row.Fees
+		sum.RecurringIncome += row.RecurringIncome
+		sum.Income += row.Income
+		sum.Outgoings += row.Outgoings
+		sum.Net += row.Net
+	}
+
+	JSON(w, http.StatusOK, CashFlowResponse{
+		Range:    rangeStr,
+		From:     from.Format("2006-01-02"),
+		To:       to.AddDate(0, 1, -1).Format("2006-01-02"),
+		Currency: user.BaseCurrency,
+		Months:   rows,
+		Totals:   sum,
+	})
+}
@@ -0,0 +1,509 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+// savedViewSortFields lists the fields each module's lists can be sorted by
+var savedViewSortFields = map[string]map[string]bool{
+	models.SavedViewModuleHoldings: {
+		"symbol": true, "name": true, "portfolio": true, "quantity": true,
+		"value": true, "gain_loss": true, "gain_loss_pct": true,
+	},
+	models.SavedViewModuleCashAccounts: {
+		"name": true, "institution": true, "balance": true, "interest_rate": true,
+	},
+	models.SavedViewModuleFixedAssets: {
+		"name": true, "category": true, "value": true, "appreciation_pct": true,
+	},
+}
+
+type SavedViewHandler struct {
+	viewRepo       *repository.SavedViewRepository
+	holdingRepo    *repository.HoldingRepository
+	cashRepo       *repository.CashAccountRepository
+	fixedAssetRepo *repository.FixedAssetRepository
+	portfolioRepo  *repository.PortfolioRepository
+	txRepo         *repository.TransactionRepository
+}
+
+func NewSavedViewHandler(
+	viewRepo *repository.SavedViewRepository,
+	holdingRepo *repository.HoldingRepository,
+	cashRepo *repository.CashAccountRepository,
+	fixedAssetRepo *repository.FixedAssetRepository,
+	portfolioRepo *repository.PortfolioRepository,
+	txRepo *repository.TransactionRepository,
+) *SavedViewHandler {
+	return &SavedViewHandler{
+		viewRepo:       viewRepo,
+		holdingRepo:    holdingRepo,
+		cashRepo:       cashRepo,
+		fixedAssetRepo: fixedAssetRepo,
+		portfolioRepo:  portfolioRepo,
+		txRepo:         txRepo,
+	}
+}
+
+type SavedViewRequest struct {
+	Module  string                  `json:"module"`
+	Name    string                  `json:"name"`
+	Filters models.SavedViewFilters `json:"filters"`
+	Sort    *models.SavedViewSort   `json:"sort"`
+}
+
+// validate checks the request, returning an error message if it is invalid
+func (req *SavedViewRequest) validate() string {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return "Name is required"
+	}
+	fields, ok := savedViewSortFields[req.Module]
+	if !ok {
+		return "Invalid module (use holdings, cash_accounts or fixed_assets)"
+	}
+	if req.Sort != nil && !fields[req.Sort.Field] {
+		return "Invalid sort field for " + req.Module
+	}
+	if f := req.Filters; f.MinValue != nil && f.MaxValue != nil && *f.MinValue > *f.MaxValue {
+		return "Minimum value cannot exceed maximum value"
+	}
+	return ""
+}
+
+// List returns the user's saved views. Query param module limits them to one list.
+func (h *SavedViewHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	views, err := h.viewRepo.GetByUserID(r.Context(), userID, r.URL.Query().Get("module"))
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch saved views")
+		return
+	}
+
+	if views == nil {
+		views = []*models.SavedView{}
+	}
+
+	JSON(w, http.StatusOK, views)
+}
+
+func (h *SavedViewHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req SavedViewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	view := &models.SavedView{
+		UserID:  userID,
+		Module:  req.Module,
+		Name:    req.Name,
+		Filters: req.Filters,
+		Sort:    req.Sort,
+	}
+
+	if err := h.viewRepo.Create(r.Context(), view); err != nil {
+		if errors.Is(err, repository.ErrSavedViewAlreadyExists) {
+			Error(w, http.StatusConflict, "A saved view with this name already exists")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to create saved view")
+		return
+	}
+
+	JSON(w, http.StatusCreated, view)
+}
+
+func (h *SavedViewHandler) Get(w http.ResponseWriter, r *http.Request) {
+	view, ok := h.ownedView(w, r)
+	if !ok {
+		return
+	}
+
+	JSON(w, http.StatusOK, view)
+}
+
+// Update renames the view or replaces its filters and sort. The module cannot change.
+func (h *SavedViewHandler) Update(w http.ResponseWriter, r *http.Request) {
+	view, ok := h.ownedView(w, r)
+	if !ok {
+		return
+	}
+
+	var req SavedViewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Module == "" {
+		req.Module = view.Module
+	}
+	if req.Module != view.Module {
+		Error(w, http.StatusBadRequest, "Module cannot be changed")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	view.Name = req.Name
+	view.Filters = req.Filters
+	view.Sort = req.Sort
+
+	if err := h.viewRepo.Update(r.Context(), view); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrSavedViewNotFound):
+			Error(w, http.StatusNotFound, "Saved view not found")
+		case errors.Is(err, repository.ErrSavedViewAlreadyExists):
+			Error(w, http.StatusConflict, "A saved view with this name already exists")
+		default:
+			Error(w, http.StatusInternalServerError, "Failed to update saved view")
+		}
+		return
+	}
+
+	JSON(w, http.StatusOK, view)
+}
+
+func (h *SavedViewHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	view, ok := h.ownedView(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.viewRepo.Delete(r.Context(), view.ID); err != nil {
+		if errors.Is(err, repository.ErrSavedViewNotFound) {
+			Error(w, http.StatusNotFound, "Saved view not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to delete saved view")
+		return
+	}
+
+	NoContent(w)
+}
+
+// SavedViewResults is a list fetched through a saved view
+type SavedViewResults struct {
+	View  *models.SavedView `json:"view"`
+	Total int               `json:"total"`
+	Items interface{}       `json:"items"`
+}
+
+// Results returns the view's list with its filters and sort applied
+func (h *SavedViewHandler) Results(w http.ResponseWriter, r *http.Request) {
+	view, ok := h.ownedView(w, r)
+	if !ok {
+		return
+	}
+
+	var items interface{}
+	var total int
+	var err error
+
+	switch view.Module {
+	case models.SavedViewModuleHoldings:
+		var holdings []*models.HoldingWithPortfolio
+		holdings, err = h.holdingResults(r, view)
+		items, total = holdings, len(holdings)
+	case models.SavedViewModuleCashAccounts:
+		var accounts []*models.CashAccount
+		accounts, err = h.cashAccountResults(r, view)
+		items, total = accounts, len(accounts)
+	case models.SavedViewModuleFixedAssets:
+		var assets []*models.FixedAsset
+		assets, err = h.fixedAssetResults(r, view)
+		items, total = assets, len(assets)
+	default:
+		Error(w, http.StatusBadRequest, "Unsupported module")
+		return
+	}
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch "+strings.ReplaceAll(view.Module, "_", " "))
+		return
+	}
+
+	JSON(w, http.StatusOK, SavedViewResults{View: view, Total: total, Items: items})
+}
+
+func (h *SavedViewHandler) holdingResults(r *http.Request, view *models.SavedView) ([]*models.HoldingWithPortfolio, error) {
+	userID := view.UserID
+	f := view.Filters
+
+	holdings, err := h.holdingRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var dividendAssets map[uuid.UUID]bool
+	if f.HasDividends != nil {
+		dividendAssets, err = h.txRepo.GetDividendAssetIDs(r.Context(), userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := []*models.HoldingWithPortfolio{}
+	for _, holding := range holdings {
+		var symbol, name, assetType, currency string
+		if holding.Asset != nil {
+			symbol, name, assetType, currency = holding.Asset.Symbol, holding.Asset.Name, holding.Asset.AssetType, holding.Asset.Currency
+		}
+		value := 0.0
+		if holding.CurrentValue != nil {
+			value = *holding.CurrentValue
+		}
+
+		if !matchesID(f.PortfolioIDs, holding.PortfolioID) ||
+			!matchesAny(f.PortfolioTypes, holding.PortfolioType) ||
+			!matchesAny(f.AssetTypes, assetType) ||
+			!matchesAny(f.Symbols, symbol) ||
+			!matchesAny(f.Currencies, currency) ||
+			!matchesSearch(f.Search, symbol, name) ||
+			!matchesRange(f.MinValue, f.MaxValue, value) {
+			continue
+		}
+		if f.HasDividends != nil && dividendAssets[holding.AssetID] != *f.HasDividends {
+			continue
+		}
+		result = append(result, holding)
+	}
+
+	if s := view.Sort; s != nil {
+		sortBy(result, s.Descending, func(h *models.HoldingWithPortfolio) interface{} {
+			switch s.Field {
+			case "symbol", "name":
+				if h.Asset == nil {
+					return ""
+				}
+				if s.Field == "symbol" {
+					return h.Asset.Symbol
+				}
+				return h.Asset.Name
+			case "portfolio":
+				return h.PortfolioName
+			case "quantity":
+				return h.Quantity
+			case "gain_loss":
+				return derefFloat(h.GainLoss)
+			case "gain_loss_pct":
+				return derefFloat(h.GainLossPct)
+			default:
+				return derefFloat(h.CurrentValue)
+			}
+		})
+	}
+
+	return result, nil
+}
+
+func (h *SavedViewHandler) cashAccountResults(r *http.Request, view *models.SavedView) ([]*models.CashAccount, error) {
+	f := view.Filters
+
+	accounts, err := h.cashRepo.GetByUserID(r.Context(), view.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolioTypes := make(map[uuid.UUID]string)
+	if len(f.PortfolioTypes) > 0 {
+		portfolios, err := h.portfolioRepo.GetByUserID(r.Context(), view.UserID)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range portfolios {
+			portfolioTypes[p.ID] = p.Type
+		}
+	}
+
+	result := []*models.CashAccount{}
+	for _, account := range accounts {
+		if !matchesID(f.PortfolioIDs, account.PortfolioID) ||
+			(len(f.PortfolioTypes) > 0 && !matchesAny(f.PortfolioTypes, portfolioTypes[account.PortfolioID])) ||
+			!matchesAny(f.AccountTypes, account.AccountType) ||
+			!matchesAny(f.Currencies, account.Currency) ||
+			!matchesSearch(f.Search, account.AccountName, account.Institution) ||
+			!matchesRange(f.MinValue, f.MaxValue, account.Balance) {
+			continue
+		}
+		result = append(result, account)
+	}
+
+	if s := view.Sort; s != nil {
+		sortBy(result, s.Descending, func(a *models.CashAccount) interface{} {
+			switch s.Field {
+			case "name":
+				return a.AccountName
+			case "institution":
+				return a.Institution
+			case "interest_rate":
+				return derefFloat(a.InterestRate)
+			default:
+				return a.Balance
+			}
+		})
+	}
+
+	return result, nil
+}
+
+func (h *SavedViewHandler) fixedAssetResults(r *http.Request, view *models.SavedView) ([]*models.FixedAsset, error) {
+	f := view.Filters
+
+	assets, err := h.fixedAssetRepo.GetByUserID(r.Context(), view.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*models.FixedAsset{}
+	for _, asset := range assets {
+		if !matchesAny(f.Categories, asset.Category) ||
+			!matchesAny(f.Currencies, asset.Currency) ||
+			!matchesSearch(f.Search, asset.Name, asset.Description) ||
+			!matchesRange(f.MinValue, f.MaxValue, asset.CurrentValue) {
+			continue
+		}
+		result = append(result, asset)
+	}
+
+	if s := view.Sort; s != nil {
+		sortBy(result, s.Descending, func(a *models.FixedAsset) interface{} {
+			switch s.Field {
+			case "name":
+				return a.Name
+			case "category":
+				return a.Category
+			case "appreciation_pct":
+				return derefFloat(a.AppreciationPct)
+			default:
+				return a.CurrentValue
+			}
+		})
+	}
+
+	return result, nil
+}
+
+// ownedView loads the view from the URL, writing an error response if it is missing or not the user's
+func (h *SavedViewHandler) ownedView(w http.ResponseWriter, r *http.Request) (*models.SavedView, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	viewID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid view ID")
+		return nil, false
+	}
+
+	view, err := h.viewRepo.GetByID(r.Context(), viewID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSavedViewNotFound) {
+			Error(w, http.StatusNotFound, "Saved view not found")
+			return nil, false
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch saved view")
+		return nil, false
+	}
+
+	if view.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return nil, false
+	}
+
+	return view, true
+}
+
+// matchesAny reports whether value is in allowed (case-insensitive); an empty list allows everything
+func matchesAny(allowed []string, value string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesID(allowed []uuid.UUID, id uuid.UUID) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == id {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesSearch(search string, fields ...string) bool {
+	if search == "" {
+		return true
+	}
+	search = strings.ToLower(search)
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), search) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesRange(min, max *float64, value float64) bool {
+	return (min == nil || value >= *min) && (max == nil || value <= *max)
+}
+
+func derefFloat(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// sortBy stably sorts items by the string or float64 key returned by key
+func sortBy[T any](items []T, descending bool, key func(T) interface{}) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := key(items[i]), key(items[j])
+		if descending {
+			a, b = b, a
+		}
+		switch av := a.(type) {
+		case string:
+			return strings.ToLower(av) < strings.ToLower(b.(string))
+		case float64:
+			return av < b.(float64)
+		}
+		return false
+	})
+}
@@ -0,0 +1,493 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+	"github.com/mark-regan/wellf/pkg/validator"
+)
+
+// Contribution rate is measured over this window of recorded goal progress
+const (
+	goalContributionWindow = 90 * 24 * time.Hour
+	goalMinimumHistory     = 14 * 24 * time.Hour
+	averageDaysPerMonth    = 30.44
+
+	// maxGoalProjectionMonths is the furthest ahead a completion date is projected
+	maxGoalProjectionMonths = 100 * 12
+)
+
+type SavingsGoalHandler struct {
+	goalRepo      *repository.SavingsGoalRepository
+	cashRepo      *repository.CashAccountRepository
+	portfolioRepo *repository.PortfolioRepository
+	reminders     *services.ReminderService
+}
+
+func NewSavingsGoalHandler(
+	goalRepo *repository.SavingsGoalRepository,
+	cashRepo *repository.CashAccountRepository,
+	portfolioRepo *repository.PortfolioRepository,
+	reminders *services.ReminderService,
+) *SavingsGoalHandler {
+	return &SavingsGoalHandler{
+		goalRepo:      goalRepo,
+		cashRepo:      cashRepo,
+		portfolioRepo: portfolioRepo,
+		reminders:     reminders,
+	}
+}
+
+// GoalAllocationRequest links a cash account or portfolio to a goal. Percentage defaults to 100.
+type GoalAllocationRequest struct {
+	CashAccountID *uuid.UUID `json:"cash_account_id,omitempty"`
+	PortfolioID   *uuid.UUID `json:"portfolio_id,omitempty"`
+	Percentage    *float64   `json:"percentage,omitempty"`
+}
+
+// SavingsGoalRequest creates or updates a goal. On update, omitted fields are unchanged
+// and an empty target_date or notes clears it.
+type SavingsGoalRequest struct {
+	Name         string                   `json:"name"`
+	TargetAmount float64                  `json:"target_amount"`
+	Currency     string                   `json:"currency"`
+	TargetDate   *string                  `json:"target_date"`
+	Priority     int                      `json:"priority"`
+	Notes        *string                  `json:"notes"`
+	Allocations  *[]GoalAllocationRequest `json:"allocations"`
+}
+
+// targetDate parses the requested target date, nil if it is empty
+func (req *SavingsGoalRequest) targetDate() (*time.Time, bool) {
+	if req.TargetDate == nil || *req.TargetDate == "" {
+		return nil, true
+	}
+	date, err := time.Parse("2006-01-02", *req.TargetDate)
+	if err != nil {
+		return nil, false
+	}
+	return &date, true
+}
+
+func (h *SavingsGoalHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req SavingsGoalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Name == "" {
+		Error(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+	if req.TargetAmount <= 0 {
+		Error(w, http.StatusBadRequest, "Target amount must be positive")
+		return
+	}
+	if req.Currency == "" {
+		req.Currency = "GBP"
+	}
+	if !validator.IsValidCurrency(req.Currency) {
+		Error(w, http.StatusBadRequest, "Invalid currency")
+		return
+	}
+	if req.Priority < 0 {
+		Error(w, http.StatusBadRequest, "Priority cannot be negative")
+		return
+	}
+	if req.Priority == 0 {
+		req.Priority = 1
+	}
+
+	targetDate, ok := req.targetDate()
+	if !ok {
+		Error(w, http.StatusBadRequest, "Invalid target date format (use YYYY-MM-DD)")
+		return
+	}
+
+	goal := &models.SavingsGoal{
+		UserID:       userID,
+		Name:         req.Name,
+		TargetAmount: req.TargetAmount,
+		Currency:     req.Currency,
+		TargetDate:   targetDate,
+		Priority:     req.Priority,
+		Allocations:  []models.SavingsGoalAllocation{},
+	}
+	if req.Notes != nil {
+		goal.Notes = *req.Notes
+	}
+
+	if req.Allocations != nil {
+		allocations, status, msg := h.buildAllocations(r.Context(), userID, uuid.Nil, *req.Allocations)
+		if msg != "" {
+			Error(w, status, msg)
+			return
+		}
+		goal.Allocations = allocations
+	}
+
+	if err := h.goalRepo.Create(r.Context(), goal); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to create savings goal")
+		return
+	}
+	h.reminders.Sync(r.Context(), userID, models.ReminderSourceSavingsGoal, goal.ID, goal.Notes)
+
+	created, err := h.goalRepo.GetByID(r.Context(), goal.ID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch savings goal")
+		return
+	}
+	h.computeProgress(r.Context(), created)
+
+	JSON(w, http.StatusCreated, created)
+}
+
+func (h *SavingsGoalHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	goals, err := h.goalRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch savings goals")
+		return
+	}
+
+	if goals == nil {
+		goals = []*models.SavingsGoal{}
+	}
+	for _, goal := range goals {
+		h.computeProgress(r.Context(), goal)
+	}
+
+	JSON(w, http.StatusOK, goals)
+}
+
+func (h *SavingsGoalHandler) Get(w http.ResponseWriter, r *http.Request) {
+	goal, ok := h.ownedGoal(w, r)
+	if !ok {
+		return
+	}
+
+	h.computeProgress(r.Context(), goal)
+
+	JSON(w, http.StatusOK, goal)
+}
+
+func (h *SavingsGoalHandler) Update(w http.ResponseWriter, r *http.Request) {
+	goal, ok := h.ownedGoal(w, r)
+	if !ok {
+		return
+	}
+
+	var req SavingsGoalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Name != "" {
+		goal.Name = req.Name
+	}
+	if req.TargetAmount < 0 {
+		Error(w, http.StatusBadRequest, "Target amount must be positive")
+		return
+	}
+	if req.TargetAmount > 0 {
+		goal.TargetAmount = req.TargetAmount
+	}
+	if req.Currency != "" {
+		if !validator.IsValidCurrency(req.Currency) {
+			Error(w, http.StatusBadRequest, "Invalid currency")
+			return
+		}
+		goal.Currency = req.Currency
+	}
+	if req.Priority < 0 {
+		Error(w, http.StatusBadRequest, "Priority cannot be negative")
+		return
+	}
+	if req.Priority > 0 {
+		goal.Priority = req.Priority
+	}
+	if req.Notes != nil {
+		goal.Notes = *req.Notes
+	}
+	if req.TargetDate != nil {
+		targetDate, ok := req.targetDate()
+		if !ok {
+			Error(w, http.StatusBadRequest, "Invalid target date format (use YYYY-MM-DD)")
+			return
+		}
+		goal.TargetDate = targetDate
+	}
+
+	if req.Allocations != nil {
+		allocations, status, msg := h.buildAllocations(r.Context(), goal.UserID, goal.ID, *req.Allocations)
+		if msg != "" {
+			Error(w, status, msg)
+			return
+		}
+		goal.Allocations = allocations
+	}
+
+	if err := h.goalRepo.Update(r.Context(), goal); err != nil {
+		if errors.Is(err, repository.ErrSavingsGoalNotFound) {
+			Error(w, http.StatusNotFound, "Savings goal not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to update savings goal")
+		return
+	}
+	h.reminders.Sync(r.Context(), goal.UserID, models.ReminderSourceSavingsGoal, goal.ID, goal.Notes)
+
+	updated, err := h.goalRepo.GetByID(r.Context(), goal.ID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch savings goal")
+		return
+	}
+	h.computeProgress(r.Context(), updated)
+
+	JSON(w, http.StatusOK, updated)
+}
+
+func (h *SavingsGoalHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	goal, ok := h.ownedGoal(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.goalRepo.Delete(r.Context(), goal.ID); err != nil {
+		if errors.Is(err, repository.ErrSavingsGoalNotFound) {
+			Error(w, http.StatusNotFound, "Savings goal not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to delete savings goal")
+		return
+	}
+	h.reminders.RemoveSource(r.Context(), models.ReminderSourceSavingsGoal, goal.ID)
+
+	NoContent(w)
+}
+
+// GoalsSummary is the dashboard view of the user's savings goals
+type GoalsSummary struct {
+	TotalTarget   float64               `json:"total_target"`
+	TotalSaved    float64               `json:"total_saved"`
+	ActiveCount   int                   `json:"active_count"`
+	CompleteCount int                   `json:"complete_count"`
+	OffTrackCount int                   `json:"off_track_count"`
+	Goals         []*models.SavingsGoal `json:"goals"`
+}
+
+// Dashboard returns the user's incomplete goals in priority order with overall totals
+func (h *SavingsGoalHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	goals, err := h.goalRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch savings goals")
+		return
+	}
+
+	summary := GoalsSummary{Goals: []*models.SavingsGoal{}}
+	for _, goal := range goals {
+		h.computeProgress(r.Context(), goal)
+		summary.TotalTarget += goal.TargetAmount
+		summary.TotalSaved += math.Min(goal.Progress.CurrentAmount, goal.TargetAmount)
+
+		if goal.Progress.Completed {
+			summary.CompleteCount++
+			continue
+		}
+		summary.ActiveCount++
+		if goal.Progress.OnTrack != nil && !*goal.Progress.OnTrack {
+			summary.OffTrackCount++
+		}
+		summary.Goals = append(summary.Goals, goal)
+	}
+
+	JSON(w, http.StatusOK, summary)
+}
+
+// ownedGoal loads the goal from the URL, writing an error response if it is missing or not the user's
+func (h *SavingsGoalHandler) ownedGoal(w http.ResponseWriter, r *http.Request) (*models.SavingsGoal, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	goalID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid goal ID")
+		return nil, false
+	}
+
+	goal, err := h.goalRepo.GetByID(r.Context(), goalID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSavingsGoalNotFound) {
+			Error(w, http.StatusNotFound, "Savings goal not found")
+			return nil, false
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch savings goal")
+		return nil, false
+	}
+
+	if goal.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return nil, false
+	}
+
+	return goal, true
+}
+
+// buildAllocations validates the requested allocations. Each cash account or portfolio can
+// only be allocated up to 100% across all of the user's goals, like envelopes of money.
+func (h *SavingsGoalHandler) buildAllocations(ctx context.Context, userID, goalID uuid.UUID, reqs []GoalAllocationRequest) ([]models.SavingsGoalAllocation, int, string) {
+	allocated, err := h.goalRepo.GetAllocatedPercentages(ctx, userID, goalID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Failed to check existing allocations"
+	}
+
+	allocations := make([]models.SavingsGoalAllocation, 0, len(reqs))
+	seen := make(map[uuid.UUID]bool, len(reqs))
+
+	for _, req := range reqs {
+		if (req.CashAccountID == nil) == (req.PortfolioID == nil) {
+			return nil, http.StatusBadRequest, "Each allocation needs either a cash account or a portfolio"
+		}
+
+		pct := 100.0
+		if req.Percentage != nil {
+			pct = *req.Percentage
+		}
+		if pct <= 0 || pct > 100 {
+			return nil, http.StatusBadRequest, "Allocation percentage must be between 0 and 100"
+		}
+
+		var id uuid.UUID
+		var belongs bool
+		if req.CashAccountID != nil {
+			id = *req.CashAccountID
+			belongs, err = h.cashRepo.BelongsToUser(ctx, id, userID)
+		} else {
+			id = *req.PortfolioID
+			belongs, err = h.portfolioRepo.BelongsToUser(ctx, id, userID)
+		}
+		if err != nil {
+			return nil, http.StatusInternalServerError, "Failed to verify ownership"
+		}
+		if !belongs {
+			return nil, http.StatusForbidden, "Access denied"
+		}
+
+		if seen[id] {
+			return nil, http.StatusBadRequest, "Each account can only be allocated once per goal"
+		}
+		seen[id] = true
+
+		if allocated[id]+pct > 100 {
+			return nil, http.StatusBadRequest, "Account is already fully allocated to other goals"
+		}
+
+		allocations = append(allocations, models.SavingsGoalAllocation{
+			CashAccountID: req.CashAccountID,
+			PortfolioID:   req.PortfolioID,
+			Percentage:    pct,
+		})
+	}
+
+	return allocations, 0, ""
+}
+
+// computeProgress values the goal's allocations, records today's amount and projects a
+// completion date from the change in recorded amounts over recent months
+func (h *SavingsGoalHandler) computeProgress(ctx context.Context, goal *models.SavingsGoal) {
+	var current float64
+	for i := range goal.Allocations {
+		a := &goal.Allocations[i]
+		var value float64
+		if a.CashAccountID != nil {
+			if account, err := h.cashRepo.GetByID(ctx, *a.CashAccountID); err == nil {
+				value = account.Balance
+			}
+		} else if a.PortfolioID != nil {
+			if summary, err := h.portfolioRepo.GetSummary(ctx, *a.PortfolioID); err == nil {
+				value = summary.TotalValue
+			}
+		}
+		a.Value = value * a.Percentage / 100
+		current += a.Value
+	}
+
+	progress := &models.SavingsGoalProgress{
+		CurrentAmount:   current,
+		RemainingAmount: math.Max(goal.TargetAmount-current, 0),
+		Completed:       current >= goal.TargetAmount,
+	}
+	if goal.TargetAmount > 0 {
+		progress.PercentComplete = math.Min(current/goal.TargetAmount*100, 100)
+	}
+	goal.Progress = progress
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	// History is secondary to serving progress, so failures are ignored
+	_ = h.goalRepo.UpsertSnapshot(ctx, goal.ID, today, current)
+
+	since, amount, err := h.goalRepo.GetEarliestSnapshotSince(ctx, goal.ID, today.Add(-goalContributionWindow))
+	if err == nil && since != nil {
+		if elapsed := today.Sub(*since); elapsed >= goalMinimumHistory {
+			months := elapsed.Hours() / 24 / averageDaysPerMonth
+			progress.MonthlyContribution = (current - amount) / months
+		}
+	}
+
+	if progress.Completed {
+		return
+	}
+
+	// Goals more than maxGoalProjectionMonths away at the current rate get no projection
+	if progress.MonthlyContribution > 0 {
+		monthsLeft := math.Ceil(progress.RemainingAmount / progress.MonthlyContribution)
+		if monthsLeft <= maxGoalProjectionMonths {
+			projected := today.AddDate(0, int(monthsLeft), 0)
+			progress.ProjectedCompletion = &projected
+		}
+	}
+
+	if goal.TargetDate != nil {
+		monthsToTarget := goal.TargetDate.Sub(today).Hours() / 24 / averageDaysPerMonth
+		onTrack := progress.ProjectedCompletion != nil && !progress.ProjectedCompletion.After(*goal.TargetDate)
+		progress.OnTrack = &onTrack
+		if monthsToTarget > 0 {
+			required := progress.RemainingAmount / monthsToTarget
+			progress.RequiredMonthly = &required
+		}
+	}
+}
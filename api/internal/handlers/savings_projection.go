@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+const (
+	defaultProjectionMonths = 12
+	maxProjectionMonths     = 60
+)
+
+// SavingsProjectionAccount is the interest on one SAVINGS portfolio (balance from its
+// deposits and withdrawals, rate from its metadata) or one cash account. Received is
+// the interest paid so far; Accrued estimates what has built up since the last payment.
+type SavingsProjectionAccount struct {
+	Source        string     `json:"source"` // PORTFOLIO or CASH_ACCOUNT
+	PortfolioID   uuid.UUID  `json:"portfolio_id"`
+	PortfolioName string     `json:"portfolio_name"`
+	CashAccountID *uuid.UUID `json:"cash_account_id,omitempty"`
+	AccountName   string     `json:"account_name,omitempty"`
+	SavingsType   string     `json:"savings_type,omitempty"`
+	Currency      string     `json:"currency"`
+	Balance       float64    `json:"balance"`
+	InterestRate  float64    `json:"interest_rate"`
+	MaturityDate  string     `json:"maturity_date,omitempty"`
+	Matured       bool       `json:"matured"`
+	Received      float64    `json:"received"`
+	LastPaid      string     `json:"last_paid,omitempty"`
+	Accrued       float64    `json:"accrued"`
+	Projected     float64    `json:"projected"`
+	BalanceAtEnd  float64    `json:"balance_at_end"`
+}
+
+// SavingsProjectionMonth is the interest forecast for one month in one currency
+type SavingsProjectionMonth struct {
+	Month    string  `json:"month"` // YYYY-MM
+	Currency string  `json:"currency"`
+	Interest float64 `json:"interest"`
+}
+
+type SavingsProjectionTotal struct {
+	Currency  string  `json:"currency"`
+	Received  float64 `json:"received"`
+	Accrued   float64 `json:"accrued"`
+	Projected float64 `json:"projected"`
+}
+
+type SavingsProjectionResponse struct {
+	From     string                     `json:"from"`
+	To       string                     `json:"to"`
+	Accounts []SavingsProjectionAccount `json:"accounts"`
+	Months   []SavingsProjectionMonth   `json:"months"`
+	Totals   []SavingsProjectionTotal   `json:"totals"`
+}
+
+// SavingsProjection estimates interest earned to date and forecasts it for the next
+// months (default 12, up to 60) on SAVINGS portfolios and cash accounts with a rate.
+// Interest is compounded monthly at today's rate; fixed-term accounts stop earning at
+// their maturity date, as the rate they roll on to isn't known. Cash accounts in a
+// SAVINGS portfolio take its maturity date.
+func (h *ReportHandler) SavingsProjection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	months := defaultProjectionMonths
+	if v := r.URL.Query().Get("months"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxProjectionMonths {
+			Error(w, http.StatusBadRequest, "Invalid months (1-60)")
+			return
+		}
+		months = n
+	}
+
+	portfolios, err := h.portfolioRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolios")
+		return
+	}
+	portfolioByID := make(map[uuid.UUID]*models.Portfolio, len(portfolios))
+	for _, p := range portfolios {
+		portfolioByID[p.ID] = p
+	}
+
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour)
+
+	portfolioInterest, err := h.txRepo.GetInterestTotals(r.Context(), userID, time.Time{}, today)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch transactions")
+		return
+	}
+	cashInterest, err := h.movementRepo.GetInterestTotals(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch cash movements")
+		return
+	}
+	cashAccounts, err := h.cashRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch cash accounts")
+		return
+	}
+
+	var accounts []SavingsProjectionAccount
+
+	for _, p := range portfolios {
+		if p.Type != models.PortfolioTypeSavings || p.Metadata == nil || p.Metadata.InterestRate <= 0 {
+			continue
+		}
+		balance, err := h.txRepo.GetCashBalance(r.Context(), p.ID)
+		if err != nil {
+			Error(w, http.StatusInternalServerError, "Failed to fetch balance")
+			return
+		}
+		account := SavingsProjectionAccount{
+			Source:        "PORTFOLIO",
+			PortfolioID:   p.ID,
+			PortfolioName: p.Name,
+			SavingsType:   p.Metadata.SavingsType,
+			Currency:      p.Currency,
+			Balance:       balance,
+			InterestRate:  p.Metadata.InterestRate,
+		}
+		lastPaid := p.CreatedAt
+		for _, t := range portfolioInterest {
+			if t.PortfolioID == p.ID && t.Currency == p.Currency {
+				account.Received += t.Total
+				lastPaid = t.LastPaid
+				account.LastPaid = t.LastPaid.Format("2006-01-02")
+			}
+		}
+		accounts = append(accounts, account)
+		projectSavings(&accounts[len(accounts)-1], p, lastPaid, today)
+	}
+
+	for _, ca := range cashAccounts {
+		if ca.InterestRate == nil || *ca.InterestRate <= 0 {
+			continue
+		}
+		p, ok := portfolioByID[ca.PortfolioID]
+		if !ok {
+			continue
+		}
+		accountID := ca.ID
+		account := SavingsProjectionAccount{
+			Source:        "CASH_ACCOUNT",
+			PortfolioID:   p.ID,
+			PortfolioName: p.Name,
+			CashAccountID: &accountID,
+			AccountName:   ca.AccountName,
+			Currency:      ca.Currency,
+			Balance:       ca.Balance,
+			InterestRate:  *ca.InterestRate,
+		}
+		if p.Type == models.PortfolioTypeSavings && p.Metadata != nil {
+			account.SavingsType = p.Metadata.SavingsType
+		} else {
+			// Only SAVINGS portfolios carry a maturity date
+			p = nil
+		}
+		lastPaid := ca.CreatedAt
+		for _, t := range cashInterest {
+			if t.CashAccountID == ca.ID {
+				account.Received = t.Total
+				lastPaid = t.LastPaid
+				account.LastPaid = t.LastPaid.Format("2006-01-02")
+			}
+		}
+		accounts = append(accounts, account)
+		projectSavings(&accounts[len(accounts)-1], p, lastPaid, today)
+	}
+
+	// Forecast month by month, compounding each account's interest into its balance
+	start := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	end := start.AddDate(0, months, 0)
+	monthly := make(map[incomeMonthKey]float64)
+	for i := range accounts {
+		a := &accounts[i]
+		balance := a.Balance
+		stop := end
+		if a.MaturityDate != "" {
+			if maturity, err := time.Parse("2006-01-02", a.MaturityDate); err == nil && maturity.Before(stop) {
+				stop = maturity
+			}
+		}
+		for month := start; month.Before(stop) && balance > 0; month = month.AddDate(0, 1, 0) {
+			next := month.AddDate(0, 1, 0)
+			fraction := 1.0
+			if next.After(stop) {
+				fraction = stop.Sub(month).Hours() / next.Sub(month).Hours()
+			}
+			interest := balance * a.InterestRate / 100 / 12 * fraction
+			balance += interest
+			a.Projected += interest
+			monthly[incomeMonthKey{month: month.Format("2006-01"), currency: a.Currency}] += interest
+		}
+		a.BalanceAtEnd = roundMoney(balance)
+	}
+
+	resp := SavingsProjectionResponse{
+		From:     start.Format("2006-01-02"),
+		To:       end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Accounts: []SavingsProjectionAccount{},
+		Months:   []SavingsProjectionMonth{},
+		Totals:   []SavingsProjectionTotal{},
+	}
+	totals := make(map[string]*SavingsProjectionTotal)
+	for _, a := range accounts {
+		t, ok := totals[a.Currency]
+		if !ok {
+			t = &SavingsProjectionTotal{Currency: a.Currency}
+			totals[a.Currency] = t
+		}
+		t.Received += a.Received
+		t.Accrued += a.Accrued
+		t.Projected += a.Projected
+
+		a.Balance = roundMoney(a.Balance)
+		a.Received = roundMoney(a.Received)
+		a.Accrued = roundMoney(a.Accrued)
+		a.Projected = roundMoney(a.Projected)
+		resp.Accounts = append(resp.Accounts, a)
+	}
+	for _, t := range totals {
+		t.Received = roundMoney(t.Received)
+		t.Accrued = roundMoney(t.Accrued)
+		t.Projected = roundMoney(t.Projected)
+		resp.Totals = append(resp.Totals, *t)
+	}
+	for key, interest := range monthly {
+		resp.Months = append(resp.Months, SavingsProjectionMonth{Month: key.month, Currency: key.currency, Interest: roundMoney(interest)})
+	}
+
+	sort.Slice(resp.Totals, func(i, j int) bool { return resp.Totals[i].Currency < resp.Totals[j].Currency })
+	sort.Slice(resp.Months, func(i, j int) bool {
+		a, b := resp.Months[i], resp.Months[j]
+		if a.Month != b.Month {
+			return a.Month < b.Month
+		}
+		return a.Currency < b.Currency
+	})
+
+	JSON(w, http.StatusOK, resp)
+}
+
+// projectSavings sets the account's maturity from the portfolio, if it has one, and
+// estimates the interest accrued at today's balance and rate from the last payment up
+// to today or maturity, whichever comes first
+func projectSavings(a *SavingsProjectionAccount, p *models.Portfolio, lastPaid, today time.Time) {
+	until := today
+	if p != nil && p.Metadata != nil && p.Metadata.MaturityDate != "" {
+		if maturity, err := time.Parse("2006-01-02", p.Metadata.MaturityDate); err == nil {
+			a.MaturityDate = maturity.Format("2006-01-02")
+			if !maturity.After(today) {
+				a.Matured = true
+				until = maturity
+			}
+		}
+	}
+
+	if a.Balance <= 0 {
+		return
+	}
+	days := math.Max(until.Sub(lastPaid).Hours()/24, 0)
+	a.Accrued = a.Balance * a.InterestRate / 100 * days / 365
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/mark-regan/wellf/internal/config"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+type SettingsHandler struct {
+	settingsService *services.SettingsService
+}
+
+func NewSettingsHandler(settingsService *services.SettingsService) *SettingsHandler {
+	return &SettingsHandler{settingsService: settingsService}
+}
+
+// SettingsResponse shows the effective settings along with the env defaults and DB overrides
+type SettingsResponse struct {
+	Settings  config.RuntimeSettings     `json:"settings"`
+	Defaults  config.RuntimeSettings     `json:"defaults"`
+	Overrides map[string]json.RawMessage `json:"overrides"`
+}
+
+func (h *SettingsHandler) response() SettingsResponse {
+	return SettingsResponse{
+		Settings:  h.settingsService.Get(),
+		Defaults:  h.settingsService.Defaults(),
+		Overrides: h.settingsService.Overrides(),
+	}
+}
+
+// Get returns the current runtime settings
+func (h *SettingsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	JSON(w, http.StatusOK, h.response())
+}
+
+// Update overrides one or more runtime settings. Only the keys present in the body are changed.
+func (h *SettingsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.GetUserID(r.Context())
+
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(patch) == 0 {
+		Error(w, http.StatusBadRequest, "No settings provided")
+		return
+	}
+
+	if _, err := h.settingsService.Update(r.Context(), patch, userID); err != nil {
+		if errors.Is(err, services.ErrUnknownSetting) || errors.Is(err, services.ErrInvalidSetting) {
+			Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to update settings")
+		return
+	}
+
+	JSON(w, http.StatusOK, h.response())
+}
+
+// Reset removes an override so the env default applies again
+func (h *SettingsHandler) Reset(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+
+	if _, err := h.settingsService.Reset(r.Context(), key); err != nil {
+		if errors.Is(err, services.ErrUnknownSetting) {
+			Error(w, http.StatusNotFound, "Setting not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to reset setting")
+		return
+	}
+
+	JSON(w, http.StatusOK, h.response())
+}
@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+type StandingOrderHandler struct {
+	orderRepo     *repository.StandingOrderRepository
+	portfolioRepo *repository.PortfolioRepository
+	yahooService  *services.YahooService
+	orders        *services.StandingOrderService
+}
+
+func NewStandingOrderHandler(
+	orderRepo *repository.StandingOrderRepository,
+	portfolioRepo *repository.PortfolioRepository,
+	yahooService *services.YahooService,
+	orders *services.StandingOrderService,
+) *StandingOrderHandler {
+	return &StandingOrderHandler{
+		orderRepo:     orderRepo,
+		portfolioRepo: portfolioRepo,
+		yahooService:  yahooService,
+		orders:        orders,
+	}
+}
+
+type CreateStandingOrderRequest struct {
+	PortfolioID string  `json:"portfolio_id"`
+	OrderType   string  `json:"order_type"`
+	Symbol      string  `json:"symbol"`
+	Amount      float64 `json:"amount"`
+	DayOfMonth  int     `json:"day_of_month"`
+	StartDate   string  `json:"start_date"`
+	EndDate     string  `json:"end_date"`
+	Note        string  `json:"note"`
+}
+
+// validate checks the request, returning an error message if it is invalid
+func (req *CreateStandingOrderRequest) validate() string {
+	req.OrderType = strings.ToUpper(req.OrderType)
+	req.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+	req.Note = strings.TrimSpace(req.Note)
+
+	if _, err := uuid.Parse(req.PortfolioID); err != nil {
+		return "Invalid portfolio ID"
+	}
+	switch req.OrderType {
+	case models.StandingOrderBuy:
+		if req.Symbol == "" {
+			return "Symbol is required for a BUY standing order"
+		}
+	case models.StandingOrderDeposit:
+		req.Symbol = ""
+	default:
+		return "Invalid order type (use BUY or DEPOSIT)"
+	}
+	if req.StartDate != "" {
+		if _, err := time.Parse("2006-01-02", req.StartDate); err != nil {
+			return "Invalid start date format (use YYYY-MM-DD)"
+		}
+	}
+	return validateStandingOrder(req.Amount, req.DayOfMonth, req.EndDate, req.Note)
+}
+
+func validateStandingOrder(amount float64, day int, endDate, note string) string {
+	if amount <= 0 {
+		return "Amount must be positive"
+	}
+	if day < 1 || day > 28 {
+		return "Day of month must be between 1 and 28"
+	}
+	if endDate != "" {
+		if _, err := time.Parse("2006-01-02", endDate); err != nil {
+			return "Invalid end date format (use YYYY-MM-DD)"
+		}
+	}
+	if len(note) > 500 {
+		return "Note must be 500 characters or fewer"
+	}
+	return ""
+}
+
+type UpdateStandingOrderRequest struct {
+	Amount     *float64 `json:"amount"`
+	DayOfMonth *int     `json:"day_of_month"`
+	EndDate    *string  `json:"end_date"`
+	Note       *string  `json:"note"`
+	IsActive   *bool    `json:"is_active"`
+}
+
+// parseOptionalDate parses a YYYY-MM-DD date, returning nil for an empty one
+func parseOptionalDate(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	date, _ := time.Parse("2006-01-02", value)
+	return &date
+}
+
+// nextRunFrom is the earliest day the order may next run: today, or tomorrow if it
+// already ran today
+func nextRunFrom(order *models.StandingOrder) time.Time {
+	now := time.Now()
+	if order.LastRunAt != nil && order.LastRunAt.Format("2006-01-02") == now.Format("2006-01-02") {
+		return now.AddDate(0, 0, 1)
+	}
+	return now
+}
+
+func (h *StandingOrderHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	orders, err := h.orderRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch standing orders")
+		return
+	}
+
+	if orders == nil {
+		orders = []*models.StandingOrder{}
+	}
+
+	JSON(w, http.StatusOK, orders)
+}
+
+// Create sets up a standing order in the portfolio's currency. Its first run is on the
+// day of month on or after start_date, which defaults to today.
+func (h *StandingOrderHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateStandingOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	portfolioID, _ := uuid.Parse(req.PortfolioID)
+	portfolio, err := h.portfolioRepo.GetByID(r.Context(), portfolioID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPortfolioNotFound) {
+			Error(w, http.StatusNotFound, "Portfolio not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+	if portfolio.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	order := &models.StandingOrder{
+		UserID:      userID,
+		PortfolioID: portfolioID,
+		OrderType:   req.OrderType,
+		Amount:      roundMoney(req.Amount),
+		Currency:    portfolio.Currency,
+		DayOfMonth:  req.DayOfMonth,
+		EndDate:     parseOptionalDate(req.EndDate),
+		Note:        req.Note,
+		IsActive:    true,
+	}
+
+	start := time.Now()
+	if req.StartDate != "" {
+		start, _ = time.Parse("2006-01-02", req.StartDate)
+		if start.Before(time.Now()) {
+			start = time.Now()
+		}
+	}
+	order.NextRunDate = services.StandingOrderDate(order.DayOfMonth, start)
+	if order.EndDate != nil && order.NextRunDate.After(*order.EndDate) {
+		Error(w, http.StatusBadRequest, "End date is before the first run")
+		return
+	}
+
+	if req.Symbol != "" {
+		asset, err := h.yahooService.GetOrCreateAsset(r.Context(), req.Symbol)
+		if err != nil {
+			Error(w, http.StatusBadRequest, "Failed to find asset: "+err.Error())
+			return
+		}
+		order.AssetID = &asset.ID
+		order.Asset = asset
+	}
+
+	if err := h.orderRepo.Create(r.Context(), order); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to create standing order")
+		return
+	}
+
+	JSON(w, http.StatusCreated, order)
+}
+
+func (h *StandingOrderHandler) Get(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.ownedOrder(w, r)
+	if !ok {
+		return
+	}
+
+	JSON(w, http.StatusOK, order)
+}
+
+// Update changes an order's amount, day, end date or note, or pauses and resumes it.
+// Changing the day or resuming moves the next run to the next matching day from today.
+func (h *StandingOrderHandler) Update(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.ownedOrder(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateStandingOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	reschedule := false
+	if req.Amount != nil {
+		order.Amount = roundMoney(*req.Amount)
+	}
+	if req.DayOfMonth != nil && *req.DayOfMonth != order.DayOfMonth {
+		order.DayOfMonth = *req.DayOfMonth
+		reschedule = true
+	}
+	endDate := ""
+	if req.EndDate != nil {
+		endDate = *req.EndDate
+		order.EndDate = parseOptionalDate(endDate)
+	}
+	if req.Note != nil {
+		order.Note = strings.TrimSpace(*req.Note)
+	}
+	if req.IsActive != nil {
+		reschedule = reschedule || (*req.IsActive && !order.IsActive)
+		order.IsActive = *req.IsActive
+	}
+	if msg := validateStandingOrder(order.Amount, order.DayOfMonth, endDate, order.Note); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if reschedule {
+		order.NextRunDate = services.StandingOrderDate(order.DayOfMonth, nextRunFrom(order))
+	}
+	if order.IsActive && order.EndDate != nil && order.NextRunDate.After(*order.EndDate) {
+		Error(w, http.StatusBadRequest, "End date is before the next run")
+		return
+	}
+
+	if err := h.orderRepo.Update(r.Context(), order); err != nil {
+		if errors.Is(err, repository.ErrStandingOrderNotFound) {
+			Error(w, http.StatusNotFound, "Standing order not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to update standing order")
+		return
+	}
+
+	JSON(w, http.StatusOK, order)
+}
+
+func (h *StandingOrderHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.ownedOrder(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.orderRepo.Delete(r.Context(), order.ID); err != nil {
+		if errors.Is(err, repository.ErrStandingOrderNotFound) {
+			Error(w, http.StatusNotFound, "Standing order not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to delete standing order")
+		return
+	}
+
+	NoContent(w)
+}
+
+// Preview lists the order's next runs (6 by default, up to 24), with the units a buy
+// would get at the latest price
+func (h *StandingOrderHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.ownedOrder(w, r)
+	if !ok {
+		return
+	}
+
+	count := 6
+	if v := r.URL.Query().Get("runs"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > services.MaxStandingOrderPreview {
+			Error(w, http.StatusBadRequest, "Invalid runs (1-24)")
+			return
+		}
+		count = n
+	}
+
+	runs := []models.StandingOrderRun{}
+	if order.IsActive {
+		runs = h.orders.Preview(r.Context(), order, count)
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"order": order,
+		"runs":  runs,
+	})
+}
+
+// Skip moves the order's next run on a month, switching it off if that is past its end
+// date
+func (h *StandingOrderHandler) Skip(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.ownedOrder(w, r)
+	if !ok {
+		return
+	}
+	if !order.IsActive {
+		Error(w, http.StatusBadRequest, "Standing order is paused")
+		return
+	}
+
+	order.NextRunDate = services.StandingOrderDate(order.DayOfMonth, order.NextRunDate.AddDate(0, 0, 1))
+	if order.EndDate != nil && order.NextRunDate.After(*order.EndDate) {
+		order.IsActive = false
+	}
+
+	if err := h.orderRepo.Update(r.Context(), order); err != nil {
+		if errors.Is(err, repository.ErrStandingOrderNotFound) {
+			Error(w, http.StatusNotFound, "Standing order not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to skip standing order run")
+		return
+	}
+
+	JSON(w, http.StatusOK, order)
+}
+
+// ownedOrder loads the standing order from the URL, writing an error response if it is missing or not the user's
+func (h *StandingOrderHandler) ownedOrder(w http.ResponseWriter, r *http.Request) (*models.StandingOrder, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	orderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid standing order ID")
+		return nil, false
+	}
+
+	order, err := h.orderRepo.GetByID(r.Context(), orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrStandingOrderNotFound) {
+			Error(w, http.StatusNotFound, "Standing order not found")
+			return nil, false
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch standing order")
+		return nil, false
+	}
+
+	if order.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return nil, false
+	}
+
+	return order, true
+}
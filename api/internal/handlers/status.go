@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/mark-regan/wellf/internal/database"
+	"github.com/mark-regan/wellf/internal/services"
+	"github.com/mark-regan/wellf/internal/yahoo"
+)
+
+// Overall statuses reported by the status page
+const (
+	statusOperational = "operational"
+	statusDegraded    = "degraded"
+	statusDown        = "down"
+)
+
+// StatusHandler serves the public status page
+type StatusHandler struct {
+	db          *database.DB
+	redis       *database.RedisClient
+	jobs        *services.JobManager
+	yahooClient *yahoo.Client
+	startedAt   time.Time
+}
+
+func NewStatusHandler(db *database.DB, redis *database.RedisClient, jobs *services.JobManager, yahooClient *yahoo.Client) *StatusHandler {
+	return &StatusHandler{
+		db:          db,
+		redis:       redis,
+		jobs:        jobs,
+		yahooClient: yahooClient,
+		startedAt:   time.Now(),
+	}
+}
+
+type JobRunStatus struct {
+	services.JobStatus
+	Healthy bool `json:"healthy"`
+}
+
+type StatusResponse struct {
+	Status     string                        `json:"status"`
+	Timestamp  string                        `json:"timestamp"`
+	StartedAt  string                        `json:"started_at"`
+	Components map[string]string             `json:"components"`
+	Jobs       []JobRunStatus                `json:"jobs"`
+	Providers  map[string]yahoo.Availability `json:"providers"`
+}
+
+// Status summarises the instance's health for uptime monitors and status widgets. The
+// instance is down when the database is unreachable, and degraded when Redis is, an
+// upstream provider is failing, or a background job's latest run failed. Job and
+// provider history covers this process since it started; error details are not shown.
+// Responds 503 when down.
+func (h *StatusHandler) Status(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	resp := StatusResponse{
+		Status:     statusOperational,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		StartedAt:  h.startedAt.UTC().Format(time.RFC3339),
+		Components: make(map[string]string),
+		Jobs:       []JobRunStatus{},
+		Providers:  make(map[string]yahoo.Availability),
+	}
+	degrade := func() {
+		if resp.Status == statusOperational {
+			resp.Status = statusDegraded
+		}
+	}
+
+	if err := h.db.Health(ctx); err != nil {
+		resp.Components["database"] = "unhealthy"
+		resp.Status = statusDown
+	} else {
+		resp.Components["database"] = "healthy"
+	}
+
+	if err := h.redis.Health(ctx); err != nil {
+		resp.Components["redis"] = "unhealthy"
+		degrade()
+	} else {
+		resp.Components["redis"] = "healthy"
+	}
+
+	for _, job := range h.jobs.Statuses() {
+		healthy := job.Healthy()
+		if !healthy {
+			degrade()
+		}
+		resp.Jobs = append(resp.Jobs, JobRunStatus{JobStatus: job, Healthy: healthy})
+	}
+
+	yahooStatus := h.yahooClient.Availability()
+	if yahooStatus.Status == "down" {
+		degrade()
+	}
+	resp.Providers["yahoo_finance"] = yahooStatus
+
+	status := http.StatusOK
+	if resp.Status == statusDown {
+		status = http.StatusServiceUnavailable
+	}
+	JSON(w, status, resp)
+}
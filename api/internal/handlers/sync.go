@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+const (
+	defaultChangesLimit = 500
+	maxChangesLimit     = 2000
+)
+
+type SyncHandler struct {
+	syncRepo *repository.SyncRepository
+}
+
+func NewSyncHandler(syncRepo *repository.SyncRepository) *SyncHandler {
+	return &SyncHandler{syncRepo: syncRepo}
+}
+
+// ChangesResponse is one page of the change feed. Pass NextCursor as since to fetch the
+// next page; HasMore means another page is already available.
+type ChangesResponse struct {
+	Changes          []*models.SyncChange `json:"changes"`
+	NextCursor       string               `json:"next_cursor"`
+	HasMore          bool                 `json:"has_more"`
+	FullSyncRequired bool                 `json:"full_sync_required,omitempty"`
+}
+
+// Changes returns records created, updated or deleted since the cursor. Without a cursor
+// only the current cursor is returned: clients download the lists once, then poll from it.
+// Query params: since, domains (comma-separated, default all) and limit.
+func (h *SyncHandler) Changes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	domains := repository.SyncDomains
+	if v := r.URL.Query().Get("domains"); v != "" {
+		valid := make(map[string]bool, len(repository.SyncDomains))
+		for _, d := range repository.SyncDomains {
+			valid[d] = true
+		}
+		domains = nil
+		for _, d := range strings.Split(v, ",") {
+			d = strings.TrimSpace(d)
+			if !valid[d] {
+				Error(w, http.StatusBadRequest, "Invalid domain: "+d)
+				return
+			}
+			domains = append(domains, d)
+		}
+	}
+
+	limit := defaultChangesLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxChangesLimit {
+			Error(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	since := r.URL.Query().Get("since")
+	if since == "" {
+		seq, err := h.syncRepo.GetLatestSeq(r.Context(), userID)
+		if err != nil {
+			Error(w, http.StatusInternalServerError, "Failed to fetch changes")
+			return
+		}
+		JSON(w, http.StatusOK, ChangesResponse{
+			Changes:          []*models.SyncChange{},
+			NextCursor:       strconv.FormatInt(seq, 10),
+			FullSyncRequired: true,
+		})
+		return
+	}
+
+	cursor, err := strconv.ParseInt(since, 10, 64)
+	if err != nil || cursor < 0 {
+		Error(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+
+	// Fetch one extra change to know whether there is another page
+	changes, err := h.syncRepo.GetChanges(r.Context(), userID, cursor, domains, limit+1)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch changes")
+		return
+	}
+
+	resp := ChangesResponse{Changes: []*models.SyncChange{}, NextCursor: since}
+	if len(changes) > limit {
+		changes = changes[:limit]
+		resp.HasMore = true
+	}
+	if len(changes) == 0 {
+		JSON(w, http.StatusOK, resp)
+		return
+	}
+
+	// Attach the current record to each upsert, one query per domain
+	upserts := make(map[string][]uuid.UUID)
+	for _, c := range changes {
+		if c.Operation == models.SyncOperationUpsert {
+			upserts[c.Domain] = append(upserts[c.Domain], c.EntityID)
+		}
+	}
+	records := make(map[string]map[uuid.UUID]json.RawMessage, len(upserts))
+	for domain, ids := range upserts {
+		records[domain], err = h.syncRepo.GetRecords(r.Context(), domain, ids)
+		if err != nil {
+			Error(w, http.StatusInternalServerError, "Failed to fetch changes")
+			return
+		}
+	}
+
+	for _, c := range changes {
+		if c.Operation == models.SyncOperationUpsert {
+			data, ok := records[c.Domain][c.EntityID]
+			if !ok {
+				// Deleted since; a later tombstone will follow
+				continue
+			}
+			c.Data = data
+		}
+		resp.Changes = append(resp.Changes, c)
+	}
+	resp.NextCursor = strconv.FormatInt(changes[len(changes)-1].Seq, 10)
+
+	JSON(w, http.StatusOK, resp)
+}
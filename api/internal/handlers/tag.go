@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+const maxItemTags = 20
+
+// Tag names are lower case so "Holiday" and "holiday" are one tag, and hyphenated
+// rather than spaced so they sit in a URL path as they are
+var tagNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+type TagHandler struct {
+	tagRepo     *repository.TagRepository
+	txRepo      *repository.TransactionRepository
+	holdingRepo *repository.HoldingRepository
+}
+
+func NewTagHandler(tagRepo *repository.TagRepository, txRepo *repository.TransactionRepository, holdingRepo *repository.HoldingRepository) *TagHandler {
+	return &TagHandler{
+		tagRepo:     tagRepo,
+		txRepo:      txRepo,
+		holdingRepo: holdingRepo,
+	}
+}
+
+type SetItemTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+type RenameTagRequest struct {
+	Name string `json:"name"`
+}
+
+type MergeTagRequest struct {
+	Into string `json:"into"`
+}
+
+// normaliseTag lower-cases a tag name and joins its words with hyphens
+func normaliseTag(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), "-")
+}
+
+// validate normalises the tags, dropping blanks and repeats
+func (req *SetItemTagsRequest) validate() string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, t := range req.Tags {
+		t = normaliseTag(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		if !tagNamePattern.MatchString(t) {
+			return "Tags must be up to 50 letters, digits, hyphens or underscores"
+		}
+		seen[t] = true
+		tags = append(tags, t)
+	}
+	if len(tags) > maxItemTags {
+		return "An item can have at most 20 tags"
+	}
+	req.Tags = tags
+	return ""
+}
+
+// tagDomain reads the optional domain query parameter, writing the error response if it
+// isn't one that can be tagged
+func tagDomain(w http.ResponseWriter, r *http.Request) (string, bool) {
+	domain := strings.ToUpper(r.URL.Query().Get("domain"))
+	if domain != "" && !repository.IsTagDomain(domain) {
+		Error(w, http.StatusBadRequest, "Invalid domain")
+		return "", false
+	}
+	return domain, true
+}
+
+func (h *TagHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	domain, ok := tagDomain(w, r)
+	if !ok {
+		return
+	}
+
+	tags, err := h.tagRepo.GetByUserID(r.Context(), userID, domain)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch tags")
+		return
+	}
+	if tags == nil {
+		tags = []*models.Tag{}
+	}
+
+	JSON(w, http.StatusOK, tags)
+}
+
+// Items lists everything carrying a tag across all domains, newest first, or only
+// items in the domain query parameter
+func (h *TagHandler) Items(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	domain, ok := tagDomain(w, r)
+	if !ok {
+		return
+	}
+
+	tag, err := h.tagRepo.GetByName(r.Context(), userID, normaliseTag(chi.URLParam(r, "tag")))
+	if err != nil {
+		if errors.Is(err, repository.ErrTagNotFound) {
+			Error(w, http.StatusNotFound, "Tag not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch tag")
+		return
+	}
+
+	items, err := h.tagRepo.GetItems(r.Context(), userID, tag.ID, domain)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch tagged items")
+		return
+	}
+	if items == nil {
+		items = []*models.TaggedItem{}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Date.After(items[j].Date) })
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"tag":   tag,
+		"items": items,
+	})
+}
+
+// Rename changes a tag's name on every item carrying it. Renaming onto a tag that
+// already exists is refused; merge the two instead.
+func (h *TagHandler) Rename(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req RenameTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	newName := normaliseTag(req.Name)
+	if !tagNamePattern.MatchString(newName) {
+		Error(w, http.StatusBadRequest, "Tags must be up to 50 letters, digits, hyphens or underscores")
+		return
+	}
+
+	name := normaliseTag(chi.URLParam(r, "tag"))
+	if newName != name {
+		if err := h.tagRepo.Rename(r.Context(), userID, name, newName); err != nil {
+			switch {
+			case errors.Is(err, repository.ErrTagNotFound):
+				Error(w, http.StatusNotFound, "Tag not found")
+			case errors.Is(err, repository.ErrTagExists):
+				Error(w, http.StatusConflict, "A tag with that name already exists; merge them instead")
+			default:
+				Error(w, http.StatusInternalServerError, "Failed to rename tag")
+			}
+			return
+		}
+	}
+
+	tag, err := h.tagRepo.GetByName(r.Context(), userID, newName)
+	if err != nil {
+		if errors.Is(err, repository.ErrTagNotFound) {
+			Error(w, http.StatusNotFound, "Tag not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch tag")
+		return
+	}
+
+	JSON(w, http.StatusOK, tag)
+}
+
+// Merge moves every item carrying the tag on to the tag named in into, then deletes it
+func (h *TagHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req MergeTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	name := normaliseTag(chi.URLParam(r, "tag"))
+	into := normaliseTag(req.Into)
+	if into == "" || into == name {
+		Error(w, http.StatusBadRequest, "Choose a different tag to merge into")
+		return
+	}
+
+	if err := h.tagRepo.Merge(r.Context(), userID, name, into); err != nil {
+		if errors.Is(err, repository.ErrTagNotFound) {
+			Error(w, http.StatusNotFound, "Tag not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to merge tags")
+		return
+	}
+
+	tag, err := h.tagRepo.GetByName(r.Context(), userID, into)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch tag")
+		return
+	}
+
+	JSON(w, http.StatusOK, tag)
+}
+
+func (h *TagHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.tagRepo.Delete(r.Context(), userID, normaliseTag(chi.URLParam(r, "tag"))); err != nil {
+		if errors.Is(err, repository.ErrTagNotFound) {
+			Error(w, http.StatusNotFound, "Tag not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to delete tag")
+		return
+	}
+
+	NoContent(w)
+}
+
+// ownedTransaction parses the txId URL param and checks it belongs to the current user,
+// writing the error response if not
+func (h *TagHandler) ownedTransaction(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	txID, err := uuid.Parse(chi.URLParam(r, "txId"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid transaction ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	belongs, err := h.txRepo.BelongsToUser(r.Context(), txID, userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to verify ownership")
+		return uuid.Nil, uuid.Nil, false
+	}
+	if !belongs {
+		Error(w, http.StatusForbidden, "Access denied")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, txID, true
+}
+
+// ownedHolding parses the holdingId URL param and checks it belongs to the current user,
+// writing the error response if not
+func (h *TagHandler) ownedHolding(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	holdingID, err := uuid.Parse(chi.URLParam(r, "holdingId"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid holding ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	belongs, err := h.holdingRepo.BelongsToUser(r.Context(), holdingID, userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to verify ownership")
+		return uuid.Nil, uuid.Nil, false
+	}
+	if !belongs {
+		Error(w, http.StatusForbidden, "Access denied")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, holdingID, true
+}
+
+// itemTags writes the tags on an item
+func (h *TagHandler) itemTags(w http.ResponseWriter, r *http.Request, userID uuid.UUID, domain string, itemID uuid.UUID) {
+	tags, err := h.tagRepo.GetItemTags(r.Context(), userID, domain, itemID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch tags")
+		return
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+
+	JSON(w, http.StatusOK, tags)
+}
+
+// setItemTags replaces the tags on an item with those in the request body, creating any
+// that are new
+func (h *TagHandler) setItemTags(w http.ResponseWriter, r *http.Request, userID uuid.UUID, domain string, itemID uuid.UUID) {
+	var req SetItemTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := h.tagRepo.SetItemTags(r.Context(), userID, domain, itemID, req.Tags); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to save tags")
+		return
+	}
+
+	if req.Tags == nil {
+		req.Tags = []string{}
+	}
+	sort.Strings(req.Tags)
+	JSON(w, http.StatusOK, req.Tags)
+}
+
+func (h *TagHandler) TransactionTags(w http.ResponseWriter, r *http.Request) {
+	userID, txID, ok := h.ownedTransaction(w, r)
+	if !ok {
+		return
+	}
+
+	h.itemTags(w, r, userID, models.TagDomainTransaction, txID)
+}
+
+// SetTransactionTags replaces the transaction's tags, creating any that are new
+func (h *TagHandler) SetTransactionTags(w http.ResponseWriter, r *http.Request) {
+	userID, txID, ok := h.ownedTransaction(w, r)
+	if !ok {
+		return
+	}
+
+	h.setItemTags(w, r, userID, models.TagDomainTransaction, txID)
+}
+
+func (h *TagHandler) HoldingTags(w http.ResponseWriter, r *http.Request) {
+	userID, holdingID, ok := h.ownedHolding(w, r)
+	if !ok {
+		return
+	}
+
+	h.itemTags(w, r, userID, models.TagDomainHolding, holdingID)
+}
+
+// SetHoldingTags replaces the holding's tags, e.g. core, satellite or ethical, creating
+// any that are new
+func (h *TagHandler) SetHoldingTags(w http.ResponseWriter, r *http.Request) {
+	userID, holdingID, ok := h.ownedHolding(w, r)
+	if !ok {
+		return
+	}
+
+	h.setItemTags(w, r, userID, models.TagDomainHolding, holdingID)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+const (
+	taskEventsInterval = time.Second
+	taskEventsTimeout  = 10 * time.Minute
+)
+
+type TaskHandler struct {
+	taskService *services.TaskService
+}
+
+func NewTaskHandler(taskService *services.TaskService) *TaskHandler {
+	return &TaskHandler{taskService: taskService}
+}
+
+// Get returns the progress of a background task
+func (h *TaskHandler) Get(w http.ResponseWriter, r *http.Request) {
+	task, ok := h.ownedTask(w, r)
+	if !ok {
+		return
+	}
+
+	JSON(w, http.StatusOK, task)
+}
+
+// Events streams the task's progress as server-sent events until it finishes. An event
+// is sent whenever the progress changes.
+func (h *TaskHandler) Events(w http.ResponseWriter, r *http.Request) {
+	task, ok := h.ownedTask(w, r)
+	if !ok {
+		return
+	}
+
+	// The stream outlives the server's normal write timeout
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(taskEventsTimeout + 5*time.Second))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(taskEventsInterval)
+	defer ticker.Stop()
+	timeout := time.After(taskEventsTimeout)
+
+	var lastUpdate time.Time
+	for {
+		if !task.UpdatedAt.Equal(lastUpdate) {
+			data, _ := json.Marshal(task)
+			fmt.Fprintf(w, "event: progress\ndata: %s\n\n", data)
+			if err := rc.Flush(); err != nil {
+				return
+			}
+			lastUpdate = task.UpdatedAt
+		}
+		if task.Finished() {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timeout:
+			return
+		case <-ticker.C:
+		}
+
+		next, err := h.taskService.Get(r.Context(), task.ID)
+		if err != nil {
+			return
+		}
+		task = next
+	}
+}
+
+// ownedTask loads the task from the URL, writing an error response if it is missing or not the user's
+func (h *TaskHandler) ownedTask(w http.ResponseWriter, r *http.Request) (*models.Task, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	taskID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid task ID")
+		return nil, false
+	}
+
+	task, err := h.taskService.Get(r.Context(), taskID)
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			Error(w, http.StatusNotFound, "Task not found")
+			return nil, false
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch task")
+		return nil, false
+	}
+
+	// Other users' tasks are reported as missing rather than forbidden
+	if task.UserID != userID {
+		Error(w, http.StatusNotFound, "Task not found")
+		return nil, false
+	}
+
+	return task, true
+}
@@ -1,12 +1,13 @@
 package handlers
 
 import (
-	"encoding/csv"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/importer"
 	"github.com/mark-regan/wellf/internal/middleware"
 	"github.com/mark-regan/wellf/internal/models"
 	"github.com/mark-regan/wellf/internal/repository"
@@ -26,10 +28,18 @@ func formatCurrency(amount float64, currency string) string {
 }
 
 type TransactionHandler struct {
-	txRepo        *repository.TransactionRepository
-	holdingRepo   *repository.HoldingRepository
-	portfolioRepo *repository.PortfolioRepository
-	yahooService  *services.YahooService
+	txRepo         *repository.TransactionRepository
+	holdingRepo    *repository.HoldingRepository
+	portfolioRepo  *repository.PortfolioRepository
+	yahooService   *services.YahooService
+	reminders      *services.ReminderService
+	lots           *services.LotService
+	allowances     *services.AllowanceService
+	presetRepo     *repository.ImportPresetRepository
+	taskService    *services.TaskService
+	webhookService *services.WebhookService
+	fx             *services.CurrencyService
+	cache          *services.DashboardCache
 }
 
 func NewTransactionHandler(
@@ -37,12 +47,28 @@ func NewTransactionHandler(
 	holdingRepo *repository.HoldingRepository,
 	portfolioRepo *repository.PortfolioRepository,
 	yahooService *services.YahooService,
+	reminders *services.ReminderService,
+	lots *services.LotService,
+	allowances *services.AllowanceService,
+	presetRepo *repository.ImportPresetRepository,
+	taskService *services.TaskService,
+	webhookService *services.WebhookService,
+	fx *services.CurrencyService,
+	cache *services.DashboardCache,
 ) *TransactionHandler {
 	return &TransactionHandler{
-		txRepo:        txRepo,
-		holdingRepo:   holdingRepo,
-		portfolioRepo: portfolioRepo,
-		yahooService:  yahooService,
+		txRepo:         txRepo,
+		holdingRepo:    holdingRepo,
+		portfolioRepo:  portfolioRepo,
+		yahooService:   yahooService,
+		reminders:      reminders,
+		lots:           lots,
+		allowances:     allowances,
+		presetRepo:     presetRepo,
+		taskService:    taskService,
+		webhookService: webhookService,
+		fx:             fx,
+		cache:          cache,
 	}
 }
 
@@ -55,6 +81,20 @@ type CreateTransactionRequest struct {
 	Currency        string  `json:"currency"`
 	TransactionDate string  `json:"transaction_date"`
 	Notes           string  `json:"notes"`
+
+	// Optional dividend breakdown; total_amount is the net amount received
+	GrossAmount           *float64 `json:"gross_amount"`
+	WithholdingTax        *float64 `json:"withholding_tax"`
+	WithholdingTaxCountry string   `json:"withholding_tax_country"`
+}
+
+// TransactionResponse is a created transaction, with a warning when it takes an ISA, LISA
+// or JISA over its annual allowance, and the tax relief top-up added to a deposit into a
+// relief at source SIPP
+type TransactionResponse struct {
+	*models.Transaction
+	AllowanceWarning *models.AllowanceStatus `json:"allowance_warning,omitempty"`
+	TaxRelief        *models.Transaction     `json:"tax_relief,omitempty"`
 }
 
 func (h *TransactionHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -103,21 +143,10 @@ func (h *TransactionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Currency == "" {
-		req.Currency = "GBP"
-	}
-
-	tx := &models.Transaction{
-		PortfolioID:     portfolioID,
-		TransactionType: req.TransactionType,
-		TotalAmount:     req.TotalAmount,
-		Currency:        req.Currency,
-		TransactionDate: txDate,
-		Notes:           req.Notes,
-	}
+	trade := req.TransactionType == models.TransactionTypeBuy || req.TransactionType == models.TransactionTypeSell
 
 	// For buy/sell transactions, we need an asset
-	if req.TransactionType == models.TransactionTypeBuy || req.TransactionType == models.TransactionTypeSell {
+	if trade {
 		if req.Symbol == "" {
 			Error(w, http.StatusBadRequest, "Symbol is required for buy/sell transactions")
 			return
@@ -130,7 +159,58 @@ func (h *TransactionHandler) Create(w http.ResponseWriter, r *http.Request) {
 			Error(w, http.StatusBadRequest, "Price must be positive")
 			return
 		}
+	}
 
+	// For deposit/withdrawal transactions (CASH portfolios)
+	if req.TransactionType == models.TransactionTypeDeposit || req.TransactionType == models.TransactionTypeWithdrawal {
+		if req.TotalAmount <= 0 {
+			Error(w, http.StatusBadRequest, "Amount must be positive")
+			return
+		}
+	}
+
+	// For dividend transactions
+	if req.TransactionType == models.TransactionTypeDividend {
+		if (req.GrossAmount != nil && *req.GrossAmount < 0) || (req.WithholdingTax != nil && *req.WithholdingTax < 0) {
+			Error(w, http.StatusBadRequest, "Gross amount and withholding tax cannot be negative")
+			return
+		}
+		req.WithholdingTaxCountry = strings.ToUpper(strings.TrimSpace(req.WithholdingTaxCountry))
+		if req.WithholdingTaxCountry != "" && !validator.IsValidCountryCode(req.WithholdingTaxCountry) {
+			Error(w, http.StatusBadRequest, "Invalid withholding tax country (use a two-letter ISO code)")
+			return
+		}
+	}
+
+	portfolio, err := h.portfolioRepo.GetByID(r.Context(), portfolioID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+	if req.Currency == "" {
+		req.Currency = portfolio.Currency
+	}
+
+	tx := &models.Transaction{
+		PortfolioID:     portfolioID,
+		TransactionType: req.TransactionType,
+		TotalAmount:     req.TotalAmount,
+		Currency:        req.Currency,
+		TransactionDate: txDate,
+		Notes:           req.Notes,
+	}
+
+	// Amounts in another currency are also kept in the portfolio's, converted at the rate
+	// on the transaction date. This is done before holdings change in case there's no rate.
+	if trade {
+		tx.TotalAmount = req.Quantity * req.Price
+	}
+	if err := h.fx.ConvertTransaction(r.Context(), tx, portfolio.Currency); err != nil {
+		Error(w, http.StatusBadGateway, fmt.Sprintf("No %s to %s exchange rate for %s; try again later", tx.Currency, portfolio.Currency, req.TransactionDate))
+		return
+	}
+
+	if trade {
 		// Get or create asset
 		asset, err := h.yahooService.GetOrCreateAsset(r.Context(), req.Symbol)
 		if err != nil {
@@ -141,13 +221,59 @@ func (h *TransactionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		tx.AssetID = &asset.ID
 		tx.Quantity = &req.Quantity
 		tx.Price = &req.Price
-		tx.TotalAmount = req.Quantity * req.Price
+	}
 
-		// Update holdings
+	// For withdrawals, check that there's sufficient balance, which is in the portfolio's currency
+	if req.TransactionType == models.TransactionTypeWithdrawal {
+		balance, err := h.txRepo.GetCashBalance(r.Context(), portfolioID)
+		if err != nil {
+			Error(w, http.StatusInternalServerError, "Failed to check balance")
+			return
+		}
+		if balance < tx.PortfolioTotal() {
+			Error(w, http.StatusBadRequest, "Insufficient balance: you only have "+formatCurrency(balance, portfolio.Currency)+" available")
+			return
+		}
+	}
+
+	if req.TransactionType == models.TransactionTypeDividend {
+		if req.Symbol != "" {
+			asset, err := h.yahooService.GetOrCreateAsset(r.Context(), req.Symbol)
+			if err == nil {
+				tx.AssetID = &asset.ID
+			}
+		}
+		tx.GrossAmount = req.GrossAmount
+		tx.WithholdingTax = req.WithholdingTax
+		tx.WithholdingTaxCountry = req.WithholdingTaxCountry
+	}
+
+	// Contributions to ISA/LISA/JISA portfolios are checked against the annual allowance,
+	// and rejected if the portfolio enforces it
+	var allowanceWarning *models.AllowanceStatus
+	if services.IsContribution(req.TransactionType) && repository.HasContributionLimit(portfolio.Type) {
+		status, err := h.allowances.Check(r.Context(), portfolio, tx.TransactionType, tx.PortfolioTotal(), txDate)
+		if err != nil {
+			Error(w, http.StatusInternalServerError, "Failed to check allowance")
+			return
+		}
+		if status != nil && status.ExceededBy > 0 {
+			if portfolio.Metadata != nil && portfolio.Metadata.EnforceAllowance {
+				ErrorWithDetails(w, http.StatusUnprocessableEntity, "Contribution exceeds the annual allowance",
+					fmt.Sprintf("It would exceed the %s %s allowance by %s", status.TaxYear, status.Wrapper, formatCurrency(status.ExceededBy, portfolio.Currency)))
+				return
+			}
+			allowanceWarning = status
+		}
+	}
+
+	// Update holdings
+	if trade {
+		var err error
 		if req.TransactionType == models.TransactionTypeBuy {
-			err = h.holdingRepo.AddToHolding(r.Context(), portfolioID, asset.ID, req.Quantity, req.Price, &tx.TransactionDate)
+			err = h.holdingRepo.AddToHolding(r.Context(), portfolioID, *tx.AssetID, req.Quantity, tx.PortfolioUnitPrice(), &tx.TransactionDate)
 		} else {
-			err = h.holdingRepo.RemoveFromHolding(r.Context(), portfolioID, asset.ID, req.Quantity)
+			err = h.holdingRepo.RemoveFromHolding(r.Context(), portfolioID, *tx.AssetID, req.Quantity)
 		}
 
 		if err != nil {
@@ -164,56 +290,29 @@ func (h *TransactionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// For deposit/withdrawal transactions (CASH portfolios)
-	if req.TransactionType == models.TransactionTypeDeposit || req.TransactionType == models.TransactionTypeWithdrawal {
-		if req.TotalAmount <= 0 {
-			Error(w, http.StatusBadRequest, "Amount must be positive")
-			return
-		}
-
-		// For withdrawals, check that there's sufficient balance
-		if req.TransactionType == models.TransactionTypeWithdrawal {
-			balance, err := h.txRepo.GetCashBalance(r.Context(), portfolioID)
-			if err != nil {
-				Error(w, http.StatusInternalServerError, "Failed to check balance")
-				return
-			}
-			if balance < req.TotalAmount {
-				Error(w, http.StatusBadRequest, "Insufficient balance: you only have "+formatCurrency(balance, req.Currency)+" available")
-				return
-			}
-		}
-	}
-
-	// For dividend transactions
-	if req.TransactionType == models.TransactionTypeDividend {
-		if req.Symbol != "" {
-			asset, err := h.yahooService.GetOrCreateAsset(r.Context(), req.Symbol)
-			if err == nil {
-				tx.AssetID = &asset.ID
-			}
-		}
+	// A tax relief top-up is saved with its deposit, so a failed request can be retried
+	// without recording the contribution twice
+	relief := services.TaxRelief(portfolio, tx)
+	if relief != nil {
+		err = h.txRepo.CreateWithTaxRelief(r.Context(), tx, relief)
+	} else {
+		err = h.txRepo.Create(r.Context(), tx)
 	}
-
-	if err := h.txRepo.Create(r.Context(), tx); err != nil {
+	if err != nil {
 		Error(w, http.StatusInternalServerError, "Failed to create transaction")
 		return
 	}
+	h.reminders.Sync(r.Context(), userID, models.ReminderSourceTransaction, tx.ID, tx.Notes)
+	if tx.AssetID != nil && tx.Quantity != nil {
+		h.lots.Sync(r.Context(), portfolioID, *tx.AssetID)
+	}
 
 	// Track contributions for ISA/LISA/JISA portfolios
-	if req.TransactionType == models.TransactionTypeBuy || req.TransactionType == models.TransactionTypeDeposit || req.TransactionType == models.TransactionTypeTransferIn {
-		portfolio, err := h.portfolioRepo.GetByID(r.Context(), portfolioID)
-		if err == nil && repository.HasContributionLimit(portfolio.Type) {
-			// Add contribution to metadata
-			contributionAmount := tx.TotalAmount
-			if err := h.portfolioRepo.AddContribution(r.Context(), portfolioID, contributionAmount); err != nil {
-				// Log but don't fail the transaction
-				// The contribution tracking is secondary to the main transaction
-			}
-		}
+	if services.IsContribution(req.TransactionType) {
+		h.allowances.Sync(r.Context(), portfolio)
 	}
 
-	JSON(w, http.StatusCreated, tx)
+	JSON(w, http.StatusCreated, TransactionResponse{Transaction: tx, AllowanceWarning: allowanceWarning, TaxRelief: relief})
 }
 
 func (h *TransactionHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -250,8 +349,9 @@ func (h *TransactionHandler) List(w http.ResponseWriter, r *http.Request) {
 	}
 
 	offset := (page - 1) * perPage
+	tag := normaliseTag(r.URL.Query().Get("tag"))
 
-	transactions, total, err := h.txRepo.GetByPortfolioID(r.Context(), portfolioID, perPage, offset)
+	transactions, total, err := h.txRepo.GetByPortfolioID(r.Context(), portfolioID, tag, perPage, offset)
 	if err != nil {
 		Error(w, http.StatusInternalServerError, "Failed to fetch transactions")
 		return
@@ -323,8 +423,20 @@ func (h *TransactionHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tx, err := h.txRepo.GetByID(r.Context(), txID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTransactionNotFound) {
+			Error(w, http.StatusNotFound, "Transaction not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch transaction")
+		return
+	}
+
 	// Note: Deleting a transaction doesn't automatically reverse the holding changes
-	// This is intentional - the user should manually adjust holdings if needed
+	// This is intentional - the user should manually adjust holdings if needed.
+	// The lots are rebuilt, so units it bought fall back into the opening lot.
+	// A tax relief top-up on a deposit is deleted with it.
 
 	if err := h.txRepo.Delete(r.Context(), txID); err != nil {
 		if errors.Is(err, repository.ErrTransactionNotFound) {
@@ -334,18 +446,225 @@ func (h *TransactionHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		Error(w, http.StatusInternalServerError, "Failed to delete transaction")
 		return
 	}
+	h.reminders.RemoveSource(r.Context(), models.ReminderSourceTransaction, txID)
+	if tx.AssetID != nil {
+		h.lots.Sync(r.Context(), tx.PortfolioID, *tx.AssetID)
+	}
+	if services.IsContribution(tx.TransactionType) {
+		if portfolio, err := h.portfolioRepo.GetByID(r.Context(), tx.PortfolioID); err == nil {
+			h.allowances.Sync(r.Context(), portfolio)
+		}
+	}
 
 	NoContent(w)
 }
 
-type csvRow struct {
-	TransactionDate string
-	Symbol          string
-	TransactionType string
-	Quantity        float64
-	Price           float64
-	Currency        string
-	Notes           string
+// UpdateTransactionRequest edits a transaction; omitted fields are left unchanged.
+// Quantity and price only apply to BUY and SELL, total amount to the other types.
+type UpdateTransactionRequest struct {
+	Quantity        *float64 `json:"quantity"`
+	Price           *float64 `json:"price"`
+	TotalAmount     *float64 `json:"total_amount"`
+	TransactionDate *string  `json:"transaction_date"`
+	Notes           *string  `json:"notes"`
+}
+
+// Update edits a transaction. Changing a BUY or SELL recalculates the quantity and
+// average cost of its holding in the same database transaction.
+func (h *TransactionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	txID, ok := h.ownedTransaction(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	old, err := h.txRepo.GetByID(r.Context(), txID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTransactionNotFound) {
+			Error(w, http.StatusNotFound, "Transaction not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch transaction")
+		return
+	}
+	tx := *old
+	trade := (tx.TransactionType == models.TransactionTypeBuy || tx.TransactionType == models.TransactionTypeSell) &&
+		tx.AssetID != nil && tx.Quantity != nil && tx.Price != nil
+	portfolio, err := h.portfolioRepo.GetByID(r.Context(), tx.PortfolioID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolio")
+		return
+	}
+
+	if req.TransactionDate != nil {
+		txDate, err := time.Parse("2006-01-02", *req.TransactionDate)
+		if err != nil {
+			Error(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)")
+			return
+		}
+		if txDate.After(time.Now()) {
+			Error(w, http.StatusBadRequest, "Transaction date cannot be in the future")
+			return
+		}
+		tx.TransactionDate = txDate
+	}
+	if req.Notes != nil {
+		tx.Notes = *req.Notes
+	}
+
+	if trade {
+		if req.TotalAmount != nil {
+			Error(w, http.StatusBadRequest, "Total amount of a buy or sell is quantity times price")
+			return
+		}
+		if req.Quantity != nil {
+			if *req.Quantity <= 0 {
+				Error(w, http.StatusBadRequest, "Quantity must be positive")
+				return
+			}
+			tx.Quantity = req.Quantity
+		}
+		if req.Price != nil {
+			if *req.Price <= 0 {
+				Error(w, http.StatusBadRequest, "Price must be positive")
+				return
+			}
+			tx.Price = req.Price
+		}
+		tx.TotalAmount = *tx.Quantity * *tx.Price
+		if !h.convertEdited(w, r, &tx, portfolio.Currency) {
+			return
+		}
+
+		if err := h.txRepo.UpdateTrade(r.Context(), old, &tx); err != nil {
+			if errors.Is(err, repository.ErrInsufficientHoldings) {
+				Error(w, http.StatusBadRequest, "Insufficient holdings: the change would leave fewer units than have been sold")
+				return
+			}
+			if errors.Is(err, repository.ErrTransactionNotFound) {
+				Error(w, http.StatusNotFound, "Transaction not found")
+				return
+			}
+			Error(w, http.StatusInternalServerError, "Failed to update transaction")
+			return
+		}
+	} else {
+		if req.Quantity != nil || req.Price != nil {
+			Error(w, http.StatusBadRequest, "Quantity and price can only be changed on buy and sell transactions")
+			return
+		}
+		if req.TotalAmount != nil {
+			if tx.ReliefForID != nil {
+				Error(w, http.StatusBadRequest, "The amount of a tax relief top-up follows its deposit; edit the deposit instead")
+				return
+			}
+			if *req.TotalAmount < 0 {
+				Error(w, http.StatusBadRequest, "Amount cannot be negative")
+				return
+			}
+			tx.TotalAmount = *req.TotalAmount
+		}
+		if !h.convertEdited(w, r, &tx, portfolio.Currency) {
+			return
+		}
+
+		if err := h.txRepo.Update(r.Context(), &tx); err != nil {
+			if errors.Is(err, repository.ErrTransactionNotFound) {
+				Error(w, http.StatusNotFound, "Transaction not found")
+				return
+			}
+			Error(w, http.StatusInternalServerError, "Failed to update transaction")
+			return
+		}
+	}
+
+	h.reminders.Sync(r.Context(), userID, models.ReminderSourceTransaction, tx.ID, tx.Notes)
+	if trade {
+		h.lots.Sync(r.Context(), tx.PortfolioID, *tx.AssetID)
+	}
+	if services.IsContribution(tx.TransactionType) {
+		h.allowances.Sync(r.Context(), portfolio)
+		h.allowances.SyncTaxRelief(r.Context(), portfolio, &tx)
+	}
+
+	JSON(w, http.StatusOK, &tx)
+}
+
+// convertEdited converts an edited transaction's amount into the portfolio's currency at
+// the rate on its date, writing the error response if there is no rate
+func (h *TransactionHandler) convertEdited(w http.ResponseWriter, r *http.Request, tx *models.Transaction, portfolioCurrency string) bool {
+	if err := h.fx.ConvertTransaction(r.Context(), tx, portfolioCurrency); err != nil {
+		Error(w, http.StatusBadGateway, fmt.Sprintf("No %s to %s exchange rate for %s; try again later", tx.Currency, portfolioCurrency, tx.TransactionDate.Format("2006-01-02")))
+		return false
+	}
+	return true
+}
+
+type UpdateWithholdingRequest struct {
+	GrossAmount           *float64 `json:"gross_amount"`
+	WithholdingTax        *float64 `json:"withholding_tax"`
+	WithholdingTaxCountry string   `json:"withholding_tax_country"`
+}
+
+// UpdateWithholding sets the gross amount, withholding tax and country of a DIVIDEND
+// transaction. Omitted amounts are cleared; total_amount (net) is left unchanged.
+func (h *TransactionHandler) UpdateWithholding(w http.ResponseWriter, r *http.Request) {
+	txID, ok := h.ownedTransaction(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateWithholdingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if (req.GrossAmount != nil && *req.GrossAmount < 0) || (req.WithholdingTax != nil && *req.WithholdingTax < 0) {
+		Error(w, http.StatusBadRequest, "Gross amount and withholding tax cannot be negative")
+		return
+	}
+	req.WithholdingTaxCountry = strings.ToUpper(strings.TrimSpace(req.WithholdingTaxCountry))
+	if req.WithholdingTaxCountry != "" && !validator.IsValidCountryCode(req.WithholdingTaxCountry) {
+		Error(w, http.StatusBadRequest, "Invalid withholding tax country (use a two-letter ISO code)")
+		return
+	}
+
+	tx, err := h.txRepo.GetByID(r.Context(), txID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTransactionNotFound) {
+			Error(w, http.StatusNotFound, "Transaction not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch transaction")
+		return
+	}
+	if tx.TransactionType != models.TransactionTypeDividend {
+		Error(w, http.StatusBadRequest, "Withholding tax can only be set on dividend transactions")
+		return
+	}
+
+	tx.GrossAmount = req.GrossAmount
+	tx.WithholdingTax = req.WithholdingTax
+	tx.WithholdingTaxCountry = req.WithholdingTaxCountry
+
+	if err := h.txRepo.Update(r.Context(), tx); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to update transaction")
+		return
+	}
+
+	JSON(w, http.StatusOK, tx)
 }
 
 type ImportResponse struct {
@@ -355,8 +674,137 @@ type ImportResponse struct {
 	Error          string   `json:"error,omitempty"`
 	InvalidSymbols []string `json:"invalid_symbols,omitempty"`
 	RowErrors      []string `json:"row_errors,omitempty"`
+
+	// Set by broker imports and dry runs
+	Format       string             `json:"format,omitempty"`
+	DryRun       bool               `json:"dry_run,omitempty"`
+	Transactions []ImportPreviewRow `json:"transactions,omitempty"`
+	Skipped      []importer.Skipped `json:"skipped,omitempty"`
 }
 
+// ImportPreviewRow is a parsed transaction shown by a dry run. Symbol is empty when the
+// row couldn't be matched to an asset; Identifier is what a symbol mapping should key on.
+type ImportPreviewRow struct {
+	Line            int     `json:"line"`
+	TransactionDate string  `json:"transaction_date"`
+	TransactionType string  `json:"transaction_type"`
+	Symbol          string  `json:"symbol,omitempty"`
+	Identifier      string  `json:"identifier"`
+	Name            string  `json:"name,omitempty"`
+	Quantity        float64 `json:"quantity"`
+	Price           float64 `json:"price"`
+	Currency        string  `json:"currency"`
+	TotalAmount     float64 `json:"total_amount"`
+	Notes           string  `json:"notes,omitempty"`
+}
+
+// ImportFormats lists the CSV formats Import understands
+func (h *TransactionHandler) ImportFormats(w http.ResponseWriter, r *http.Request) {
+	JSON(w, http.StatusOK, importer.Formats())
+}
+
+const (
+	// maxImportSize caps an import upload, which is streamed to a temporary file
+	maxImportSize = 50 << 20
+	// asyncImportThreshold is the upload size above which imports run as a background
+	// task even if async wasn't asked for
+	asyncImportThreshold = 1 << 20
+	// importChunkSize is how many rows are imported between progress updates
+	importChunkSize = 200
+	// maxImportFieldSize caps the form fields sent alongside the file
+	maxImportFieldSize = 64 << 10
+)
+
+// importUpload is an import form whose file has been streamed to a temporary file
+type importUpload struct {
+	fields map[string]string
+	path   string
+	size   int64
+}
+
+// Remove deletes the temporary file
+func (u *importUpload) Remove() {
+	if u.path != "" {
+		os.Remove(u.path)
+	}
+}
+
+// readImportUpload streams the multipart form's "file" part to a temporary file and reads
+// the other parts as fields
+func readImportUpload(w http.ResponseWriter, r *http.Request) (*importUpload, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &importUpload{fields: make(map[string]string)}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return upload, nil
+		}
+		if err != nil {
+			upload.Remove()
+			return nil, err
+		}
+
+		if part.FormName() != "file" {
+			value, err := io.ReadAll(io.LimitReader(part, maxImportFieldSize))
+			if err != nil {
+				upload.Remove()
+				return nil, err
+			}
+			upload.fields[part.FormName()] = string(value)
+			continue
+		}
+		if upload.path != "" {
+			continue
+		}
+
+		f, err := os.CreateTemp("", "wellf-import-*")
+		if err != nil {
+			upload.Remove()
+			return nil, err
+		}
+		upload.path = f.Name()
+		upload.size, err = io.Copy(f, part)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			upload.Remove()
+			return nil, err
+		}
+	}
+}
+
+// importJob is everything needed to run an import once the upload has been read
+type importJob struct {
+	userID    uuid.UUID
+	portfolio *models.Portfolio
+	path      string
+	format    string
+	mapping   *models.ImportMapping
+	mode      string
+	symbolMap map[string]string
+	dryRun    bool
+}
+
+// importProgress reports how far an import has got
+type importProgress func(ctx context.Context, step string, current, total int)
+
+// ImportCompleted is the payload of an import.completed webhook
+type ImportCompleted struct {
+	TaskID      uuid.UUID       `json:"task_id"`
+	PortfolioID uuid.UUID       `json:"portfolio_id"`
+	Result      *ImportResponse `json:"result"`
+}
+
+// Import reads transactions from a CSV upload. Uploads over 1MB, or with async=true,
+// are imported in the background: the response is the task to poll at /tasks/{id},
+// whose result is the import report, and an import.completed webhook is sent when it
+// finishes.
 func (h *TransactionHandler) Import(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
@@ -387,297 +835,444 @@ func (h *TransactionHandler) Import(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse multipart form (max 10MB)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	upload, err := readImportUpload(w, r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			Error(w, http.StatusRequestEntityTooLarge, "File is too large (50MB maximum)")
+			return
+		}
 		Error(w, http.StatusBadRequest, "Failed to parse form data")
 		return
 	}
-
-	// Get the mode (replace or append)
-	mode := r.FormValue("mode")
-	if mode != "replace" && mode != "append" {
-		mode = "append"
-	}
-
-	// Get the file
-	file, _, err := r.FormFile("file")
-	if err != nil {
+	if upload.path == "" {
 		Error(w, http.StatusBadRequest, "No file uploaded")
 		return
 	}
-	defer file.Close()
-
-	// Parse CSV
-	reader := csv.NewReader(file)
-
-	// Read header
-	header, err := reader.Read()
-	if err != nil {
-		JSON(w, http.StatusBadRequest, ImportResponse{
-			Success: false,
-			Error:   "Failed to read CSV header",
-			Message: "The CSV file appears to be empty or malformed",
-		})
-		return
+	// The task removes the file once it is done with it
+	removeUpload := true
+	defer func() {
+		if removeUpload {
+			upload.Remove()
+		}
+	}()
+
+	job := &importJob{
+		userID:    userID,
+		portfolio: portfolio,
+		path:      upload.path,
+		format:    upload.fields["format"],
+		mode:      upload.fields["mode"],
+		symbolMap: make(map[string]string),
 	}
 
-	// Map header columns
-	colIndex := make(map[string]int)
-	for i, col := range header {
-		colIndex[strings.ToLower(strings.TrimSpace(col))] = i
+	// Get the mode (replace or append)
+	if job.mode != "replace" && job.mode != "append" {
+		job.mode = "append"
 	}
 
-	// Validate required columns
-	requiredCols := []string{"transaction_date", "symbol", "transaction_type", "quantity", "price"}
-	for _, col := range requiredCols {
-		if _, exists := colIndex[col]; !exists {
-			JSON(w, http.StatusBadRequest, ImportResponse{
-				Success: false,
-				Error:   "Missing required column: " + col,
-				Message: "Required columns: transaction_date, symbol, transaction_type, quantity, price",
-			})
+	// Optional mapping from broker identifiers (ticker, ISIN, SEDOL or name) to symbols
+	if v := upload.fields["symbols"]; v != "" {
+		var mapping map[string]string
+		if err := json.Unmarshal([]byte(v), &mapping); err != nil {
+			Error(w, http.StatusBadRequest, "Invalid symbols mapping (use a JSON object of identifier to symbol)")
 			return
 		}
+		for k, sym := range mapping {
+			job.symbolMap[strings.ToUpper(strings.TrimSpace(k))] = strings.ToUpper(strings.TrimSpace(sym))
+		}
 	}
+	job.dryRun, _ = strconv.ParseBool(upload.fields["dry_run"])
 
-	// Parse all rows and collect errors
-	var rows []csvRow
-	var rowErrors []string
-	lineNum := 1
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			JSON(w, http.StatusBadRequest, ImportResponse{
-				Success: false,
-				Error:   fmt.Sprintf("Error reading line %d: %v", lineNum+1, err),
-				Message: "CSV parsing error",
-			})
+	// A saved preset's column mapping takes precedence over the format
+	if v := upload.fields["preset_id"]; v != "" {
+		presetID, perr := uuid.Parse(v)
+		if perr != nil {
+			Error(w, http.StatusBadRequest, "Invalid preset ID")
 			return
 		}
-		lineNum++
-
-		var lineErrors []string
-
-		// Parse row
-		row := csvRow{
-			TransactionDate: strings.TrimSpace(record[colIndex["transaction_date"]]),
-			Symbol:          strings.ToUpper(strings.TrimSpace(record[colIndex["symbol"]])),
-			TransactionType: strings.ToUpper(strings.TrimSpace(record[colIndex["transaction_type"]])),
+		preset, perr := h.presetRepo.GetByID(r.Context(), presetID)
+		if perr != nil || preset.UserID != userID {
+			Error(w, http.StatusBadRequest, "Import preset not found")
+			return
 		}
+		job.mapping = &preset.Mapping
+	}
 
-		// Validate date format and not in future
-		txDate, err := time.Parse("2006-01-02", row.TransactionDate)
-		if err != nil {
-			lineErrors = append(lineErrors, "invalid date format (use YYYY-MM-DD)")
-		} else if txDate.After(time.Now()) {
-			lineErrors = append(lineErrors, "transaction date cannot be in the future")
-		}
+	async, _ := strconv.ParseBool(upload.fields["async"])
+	if !async && upload.size <= asyncImportThreshold {
+		status, resp := h.runImport(r.Context(), job, func(context.Context, string, int, int) {})
+		JSON(w, status, resp)
+		return
+	}
 
-		// Validate symbol is not empty
-		if row.Symbol == "" {
-			lineErrors = append(lineErrors, "symbol is required")
-		}
+	task, err := h.taskService.Start(r.Context(), userID, "transaction_import", func(ctx context.Context, progress *services.TaskProgress) (interface{}, error) {
+		defer upload.Remove()
+		// The request's invalidation ran before the import did
+		defer h.cache.Invalidate(context.WithoutCancel(ctx), userID)
 
-		// Validate transaction type
-		if row.TransactionType != "BUY" && row.TransactionType != "SELL" {
-			lineErrors = append(lineErrors, fmt.Sprintf("invalid transaction type '%s' (use BUY or SELL)", row.TransactionType))
+		status, resp := h.runImport(ctx, job, progress.Step)
+		if status >= http.StatusInternalServerError {
+			return nil, errors.New(resp.Error)
 		}
+		h.webhookService.Emit(ctx, userID, models.WebhookEventImportCompleted, ImportCompleted{
+			TaskID:      progress.TaskID(),
+			PortfolioID: portfolioID,
+			Result:      resp,
+		})
+		return resp, nil
+	})
+	if err != nil {
+		Error(w, http.StatusServiceUnavailable, "Unable to start import")
+		return
+	}
+	removeUpload = false
 
-		// Parse quantity
-		quantityStr := strings.TrimSpace(record[colIndex["quantity"]])
-		quantity, err := strconv.ParseFloat(quantityStr, 64)
-		if err != nil || quantity <= 0 {
-			lineErrors = append(lineErrors, "quantity must be a positive number")
-		} else {
-			row.Quantity = quantity
-		}
+	JSON(w, http.StatusAccepted, task)
+}
 
-		// Parse price
-		priceStr := strings.TrimSpace(record[colIndex["price"]])
-		price, err := strconv.ParseFloat(priceStr, 64)
-		if err != nil || price <= 0 {
-			lineErrors = append(lineErrors, "price must be a positive number")
-		} else {
-			row.Price = price
-		}
+// runImport parses the uploaded file and imports its transactions, returning the
+// response status and report
+func (h *TransactionHandler) runImport(ctx context.Context, job *importJob, progress importProgress) (int, *ImportResponse) {
+	portfolioID := job.portfolio.ID
 
-		// If there are errors for this line, add them to rowErrors
-		if len(lineErrors) > 0 {
-			rowErrors = append(rowErrors, fmt.Sprintf("Line %d: %s", lineNum, strings.Join(lineErrors, "; ")))
-			continue
-		}
+	file, err := os.Open(job.path)
+	if err != nil {
+		return http.StatusInternalServerError, &ImportResponse{Error: "Failed to read uploaded file"}
+	}
+	defer file.Close()
 
-		// Optional currency
-		if idx, exists := colIndex["currency"]; exists && idx < len(record) {
-			currency := strings.ToUpper(strings.TrimSpace(record[idx]))
-			if currency != "" {
-				row.Currency = currency
+	progress(ctx, "Reading file", 0, 0)
+	var parsed *importer.Result
+	if job.mapping != nil {
+		parsed, err = importer.ParseMapping(file, *job.mapping, job.portfolio.Currency)
+	} else {
+		parsed, err = importer.Parse(file, job.format, job.portfolio.Currency)
+	}
+	if err != nil {
+		var missing *importer.MissingColumnError
+		switch {
+		case errors.As(err, &missing):
+			return http.StatusBadRequest, &ImportResponse{
+				Success: false,
+				Error:   missing.Error(),
+				Message: "Required columns: " + strings.Join(missing.Required, ", "),
+				Format:  missing.Format,
+			}
+		case errors.Is(err, importer.ErrEmpty):
+			return http.StatusBadRequest, &ImportResponse{
+				Success: false,
+				Error:   "Failed to read CSV header",
+				Message: "The CSV file appears to be empty or malformed",
+			}
+		case errors.Is(err, importer.ErrUnknownFormat):
+			return http.StatusBadRequest, &ImportResponse{
+				Success: false,
+				Error:   "Unknown import format",
+			}
+		default:
+			return http.StatusBadRequest, &ImportResponse{
+				Success: false,
+				Error:   err.Error(),
+				Message: "CSV parsing error",
 			}
 		}
-		if row.Currency == "" {
-			row.Currency = portfolio.Currency
-		}
-
-		// Optional notes
-		if idx, exists := colIndex["notes"]; exists && idx < len(record) {
-			row.Notes = strings.TrimSpace(record[idx])
-		}
-
-		rows = append(rows, row)
 	}
+	rows := parsed.Rows
 
 	// If there were row errors, return them all
-	if len(rowErrors) > 0 {
-		JSON(w, http.StatusBadRequest, ImportResponse{
+	if len(parsed.Errors) > 0 && !job.dryRun {
+		return http.StatusBadRequest, &ImportResponse{
 			Success:   false,
 			Error:     "Validation errors found",
-			Message:   fmt.Sprintf("Found %d row(s) with errors", len(rowErrors)),
-			RowErrors: rowErrors,
-		})
-		return
+			Message:   fmt.Sprintf("Found %d row(s) with errors", len(parsed.Errors)),
+			RowErrors: parsed.Errors,
+			Format:    parsed.Format,
+		}
 	}
 
-	if len(rows) == 0 {
-		JSON(w, http.StatusBadRequest, ImportResponse{
+	if len(rows) == 0 && !job.dryRun {
+		return http.StatusBadRequest, &ImportResponse{
 			Success: false,
 			Error:   "No transactions found",
 			Message: "The CSV file contains no valid transactions",
-		})
-		return
-	}
-
-	// Collect unique symbols
-	symbolSet := make(map[string]bool)
-	for _, row := range rows {
-		symbolSet[row.Symbol] = true
-	}
-
-	// Validate all symbols against Yahoo Finance
-	var invalidSymbols []string
-	symbolToAsset := make(map[string]*models.Asset)
-
-	for symbol := range symbolSet {
-		asset, err := h.yahooService.GetOrCreateAsset(r.Context(), symbol)
-		if err != nil {
-			invalidSymbols = append(invalidSymbols, symbol)
-		} else {
-			symbolToAsset[symbol] = asset
+			Format:  parsed.Format,
+			Skipped: parsed.Skipped,
 		}
 	}
 
-	if len(invalidSymbols) > 0 {
-		sort.Strings(invalidSymbols)
-		JSON(w, http.StatusBadRequest, ImportResponse{
-			Success:        false,
-			Error:          "Invalid symbols found",
-			InvalidSymbols: invalidSymbols,
-			Message:        fmt.Sprintf("The following symbols could not be found: %s", strings.Join(invalidSymbols, ", ")),
-		})
-		return
-	}
+	// Match every row to an asset, validating symbols against Yahoo Finance
+	rowAssets, invalidSymbols := h.matchImportAssets(ctx, rows, job.symbolMap, progress)
 
 	// Sort rows by date for sell validation
-	sort.Slice(rows, func(i, j int) bool {
-		return rows[i].TransactionDate < rows[j].TransactionDate
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].TransactionDate.Before(rows[j].TransactionDate)
 	})
 
-	// Validate sell quantities - ensure we have enough holdings to sell
-	// Build initial holdings map based on mode
-	holdingsBalance := make(map[string]float64)
+	if job.dryRun {
+		preview := make([]ImportPreviewRow, 0, len(rows))
+		for _, row := range rows {
+			p := ImportPreviewRow{
+				Line:            row.Line,
+				TransactionDate: row.TransactionDate.Format("2006-01-02"),
+				TransactionType: row.TransactionType,
+				Identifier:      row.Identifier(),
+				Name:            row.Name,
+				Quantity:        row.Quantity,
+				Price:           row.Price,
+				Currency:        row.Currency,
+				TotalAmount:     roundMoney(row.Quantity * row.Price),
+				Notes:           row.Notes,
+			}
+			if asset := rowAssets[row]; asset != nil {
+				p.Symbol = asset.Symbol
+			}
+			preview = append(preview, p)
+		}
 
-	if mode == "append" {
-		// Get existing holdings for this portfolio
-		existingHoldings, err := h.holdingRepo.GetByPortfolioID(r.Context(), portfolioID)
-		if err != nil {
-			Error(w, http.StatusInternalServerError, "Failed to fetch existing holdings")
-			return
+		rowErrors := parsed.Errors
+		if len(invalidSymbols) == 0 {
+			rowErrors = append(rowErrors, h.importSellErrors(ctx, portfolioID, job.mode, rows, rowAssets)...)
+			_, rateErrors := h.importTransactions(ctx, job.portfolio, rows, rowAssets)
+			rowErrors = append(rowErrors, rateErrors...)
 		}
-		for _, h := range existingHoldings {
-			if h.Asset != nil {
-				holdingsBalance[h.Asset.Symbol] = h.Quantity
-			}
+
+		return http.StatusOK, &ImportResponse{
+			Success:        len(rowErrors) == 0 && len(invalidSymbols) == 0 && len(rows) > 0,
+			Message:        fmt.Sprintf("Parsed %d transaction(s), skipped %d row(s)", len(rows), len(parsed.Skipped)),
+			InvalidSymbols: invalidSymbols,
+			RowErrors:      rowErrors,
+			Format:         parsed.Format,
+			DryRun:         true,
+			Transactions:   preview,
+			Skipped:        parsed.Skipped,
 		}
 	}
-	// For replace mode, holdingsBalance starts empty
 
-	// Simulate the transactions chronologically to check for insufficient holdings
-	var sellErrors []string
-	for i, row := range rows {
-		if row.TransactionType == "BUY" {
-			holdingsBalance[row.Symbol] += row.Quantity
-		} else if row.TransactionType == "SELL" {
-			if holdingsBalance[row.Symbol] < row.Quantity {
-				available := holdingsBalance[row.Symbol]
-				sellErrors = append(sellErrors, fmt.Sprintf("Line %d: Cannot sell %.4f %s (only %.4f available at this point)", i+2, row.Quantity, row.Symbol, available))
-			} else {
-				holdingsBalance[row.Symbol] -= row.Quantity
-			}
+	if len(invalidSymbols) > 0 {
+		return http.StatusBadRequest, &ImportResponse{
+			Success:        false,
+			Error:          "Invalid symbols found",
+			InvalidSymbols: invalidSymbols,
+			Message:        fmt.Sprintf("The following symbols could not be found: %s", strings.Join(invalidSymbols, ", ")),
+			Format:         parsed.Format,
 		}
 	}
 
+	// Validate sell quantities - ensure we have enough holdings to sell
+	sellErrors := h.importSellErrors(ctx, portfolioID, job.mode, rows, rowAssets)
 	if len(sellErrors) > 0 {
-		JSON(w, http.StatusBadRequest, ImportResponse{
+		return http.StatusBadRequest, &ImportResponse{
 			Success:   false,
 			Error:     "Insufficient holdings for sell orders",
 			Message:   fmt.Sprintf("Found %d sell order(s) that exceed available holdings", len(sellErrors)),
 			RowErrors: sellErrors,
-		})
-		return
+			Format:    parsed.Format,
+		}
 	}
 
-	// If replace mode, delete existing transactions and holdings
-	if mode == "replace" {
-		if err := h.txRepo.DeleteByPortfolioID(r.Context(), portfolioID); err != nil {
-			Error(w, http.StatusInternalServerError, "Failed to clear existing transactions")
-			return
+	// Convert every row before anything is written, so an import never mixes currencies
+	txs, rateErrors := h.importTransactions(ctx, job.portfolio, rows, rowAssets)
+	if len(rateErrors) > 0 {
+		return http.StatusBadRequest, &ImportResponse{
+			Success:   false,
+			Error:     "Missing exchange rates",
+			Message:   fmt.Sprintf("Found %d row(s) with no exchange rate into %s; try again later", len(rateErrors), job.portfolio.Currency),
+			RowErrors: rateErrors,
+			Format:    parsed.Format,
 		}
-		if err := h.holdingRepo.DeleteByPortfolioID(r.Context(), portfolioID); err != nil {
-			Error(w, http.StatusInternalServerError, "Failed to clear existing holdings")
-			return
+	}
+
+	// Rows already sorted by date from sell validation above. They are saved in one
+	// database transaction, replacing the portfolio's in replace mode, so an import
+	// that fails or is cancelled leaves the portfolio as it was.
+	progress(ctx, "Importing transactions", 0, len(rows))
+	err = h.txRepo.Import(ctx, portfolioID, txs, job.mode == "replace", func(saved int) {
+		if saved%importChunkSize == 0 {
+			progress(ctx, "Importing transactions", saved, len(rows))
 		}
+	})
+	if err != nil {
+		var failed *repository.ImportError
+		if errors.As(err, &failed) && errors.Is(err, repository.ErrInsufficientHoldings) {
+			row := rows[failed.Index]
+			return http.StatusBadRequest, &ImportResponse{
+				Success:   false,
+				Error:     "Insufficient holdings for sell orders",
+				Message:   "No transactions were imported",
+				RowErrors: []string{fmt.Sprintf("Line %d: Cannot sell %.4f %s (not enough units held)", row.Line, row.Quantity, rowAssets[row].Symbol)},
+				Format:    parsed.Format,
+			}
+		}
+		if ctx.Err() != nil {
+			return http.StatusInternalServerError, &ImportResponse{Error: "Import interrupted; no transactions were imported"}
+		}
+		return http.StatusInternalServerError, &ImportResponse{Error: "Failed to import transactions; no transactions were imported"}
 	}
 
-	// Rows already sorted by date from sell validation above
+	assets := make(map[uuid.UUID]bool)
+	for _, asset := range rowAssets {
+		assets[asset.ID] = true
+	}
+	progress(ctx, "Updating holdings", len(rows), len(rows))
+	for assetID := range assets {
+		h.lots.Sync(ctx, portfolioID, assetID)
+	}
+	h.allowances.Sync(ctx, job.portfolio)
+
+	return http.StatusOK, &ImportResponse{
+		Success:  true,
+		Imported: len(txs),
+		Message:  fmt.Sprintf("Successfully imported %d transactions", len(txs)),
+		Format:   parsed.Format,
+		Skipped:  parsed.Skipped,
+	}
+}
 
-	// Process each row
-	imported := 0
+// importTransactions builds the rows' transactions, converting amounts in another
+// currency into the portfolio's at the rate on each row's date. It returns an error for
+// each row without a rate.
+func (h *TransactionHandler) importTransactions(ctx context.Context, portfolio *models.Portfolio, rows []*importer.Row, rowAssets map[*importer.Row]*models.Asset) ([]*models.Transaction, []string) {
+	txs := make([]*models.Transaction, 0, len(rows))
+	var rateErrors []string
 	for _, row := range rows {
-		txDate, _ := time.Parse("2006-01-02", row.TransactionDate)
-		asset := symbolToAsset[row.Symbol]
+		asset := rowAssets[row]
+		quantity, price := row.Quantity, row.Price
 
 		tx := &models.Transaction{
-			PortfolioID:     portfolioID,
+			PortfolioID:     portfolio.ID,
 			AssetID:         &asset.ID,
 			TransactionType: row.TransactionType,
-			Quantity:        &row.Quantity,
-			Price:           &row.Price,
-			TotalAmount:     row.Quantity * row.Price,
+			Quantity:        &quantity,
+			Price:           &price,
+			TotalAmount:     quantity * price,
 			Currency:        row.Currency,
-			TransactionDate: txDate,
+			TransactionDate: row.TransactionDate,
 			Notes:           row.Notes,
 		}
-
-		if err := h.txRepo.Create(r.Context(), tx); err != nil {
-			// Continue with other transactions, but log the error
+		if err := h.fx.ConvertTransaction(ctx, tx, portfolio.Currency); err != nil {
+			rateErrors = append(rateErrors, fmt.Sprintf("Line %d: No %s to %s exchange rate for %s", row.Line, row.Currency, portfolio.Currency, row.TransactionDate.Format("2006-01-02")))
 			continue
 		}
+		txs = append(txs, tx)
+	}
+	return txs, rateErrors
+}
 
-		// Update holdings
-		if row.TransactionType == models.TransactionTypeBuy {
-			h.holdingRepo.AddToHolding(r.Context(), portfolioID, asset.ID, row.Quantity, row.Price, &txDate)
-		} else {
-			h.holdingRepo.RemoveFromHolding(r.Context(), portfolioID, asset.ID, row.Quantity)
+// matchImportAssets finds the asset for each row, using the mapping for any of the row's
+// identifiers before its own ticker. Tickers of sterling trades without an exchange
+// suffix are tried on the London Stock Exchange first. It returns the identifiers that
+// couldn't be matched.
+func (h *TransactionHandler) matchImportAssets(ctx context.Context, rows []*importer.Row, symbolMap map[string]string, progress importProgress) (map[*importer.Row]*models.Asset, []string) {
+	matched := make(map[*importer.Row]*models.Asset, len(rows))
+	cache := make(map[string]*models.Asset)
+	unmatched := make(map[string]bool)
+
+	lookup := func(symbol string) *models.Asset {
+		if asset, ok := cache[symbol]; ok {
+			return asset
+		}
+		asset, err := h.yahooService.GetOrCreateAsset(ctx, symbol)
+		if err != nil {
+			asset = nil
+		}
+		cache[symbol] = asset
+		return asset
+	}
+	lookupIdentifier := func(code string) *models.Asset {
+		if code == "" {
+			return nil
+		}
+		key := "id:" + code
+		if asset, ok := cache[key]; ok {
+			return asset
+		}
+		asset, err := h.yahooService.ResolveIdentifier(ctx, code)
+		if err != nil {
+			asset = nil
+		}
+		cache[key] = asset
+		return asset
+	}
+
+	for i, row := range rows {
+		if i%importChunkSize == 0 {
+			progress(ctx, "Matching assets", i, len(rows))
+		}
+
+		var candidates []string
+		for _, id := range row.Identifiers() {
+			if sym, ok := symbolMap[strings.ToUpper(id)]; ok && sym != "" {
+				candidates = []string{sym}
+				break
+			}
+		}
+		if candidates == nil && row.Symbol != "" {
+			if row.Currency == "GBP" && !strings.Contains(row.Symbol, ".") {
+				candidates = append(candidates, row.Symbol+".L")
+			}
+			candidates = append(candidates, row.Symbol)
 		}
 
-		imported++
+		for _, symbol := range candidates {
+			if asset := lookup(symbol); asset != nil {
+				matched[row] = asset
+				break
+			}
+		}
+		// Funds often come with only an ISIN or SEDOL, which can be resolved directly
+		if matched[row] == nil && candidates == nil {
+			for _, code := range []string{row.ISIN, row.SEDOL} {
+				if asset := lookupIdentifier(code); asset != nil {
+					matched[row] = asset
+					break
+				}
+			}
+		}
+		if id := row.Identifier(); matched[row] == nil && id != "" {
+			unmatched[id] = true
+		}
 	}
 
-	JSON(w, http.StatusOK, ImportResponse{
-		Success:  true,
-		Imported: imported,
-		Message:  fmt.Sprintf("Successfully imported %d transactions", imported),
-	})
+	invalid := make([]string, 0, len(unmatched))
+	for id := range unmatched {
+		invalid = append(invalid, id)
+	}
+	sort.Strings(invalid)
+	return matched, invalid
+}
+
+// importSellErrors replays the rows, which must be sorted by date, against the portfolio's
+// holdings (none in replace mode) and reports sells of more units than are held
+func (h *TransactionHandler) importSellErrors(ctx context.Context, portfolioID uuid.UUID, mode string, rows []*importer.Row, rowAssets map[*importer.Row]*models.Asset) []string {
+	holdingsBalance := make(map[uuid.UUID]float64)
+
+	if mode == "append" {
+		existingHoldings, err := h.holdingRepo.GetByPortfolioID(ctx, portfolioID)
+		if err != nil {
+			return []string{"Failed to fetch existing holdings"}
+		}
+		for _, h := range existingHoldings {
+			holdingsBalance[h.AssetID] = h.Quantity
+		}
+	}
+
+	var sellErrors []string
+	for _, row := range rows {
+		asset := rowAssets[row]
+		if asset == nil {
+			continue
+		}
+		if row.TransactionType == models.TransactionTypeBuy {
+			holdingsBalance[asset.ID] += row.Quantity
+		} else if row.TransactionType == models.TransactionTypeSell {
+			if holdingsBalance[asset.ID] < row.Quantity {
+				available := holdingsBalance[asset.ID]
+				sellErrors = append(sellErrors, fmt.Sprintf("Line %d: Cannot sell %.4f %s (only %.4f available at this point)", row.Line, row.Quantity, asset.Symbol, available))
+			} else {
+				holdingsBalance[asset.ID] -= row.Quantity
+			}
+		}
+	}
+	return sellErrors
 }
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/exporter"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+// Export downloads the user's transactions, or one portfolio's with portfolio_id, in a
+// format another portfolio tool can import: ghostfolio (JSON), ghostfolio_csv or
+// portfolio_performance. Each portfolio becomes an account in the other tool, and the
+// X-Export-Skipped header counts transactions the format has no equivalent for.
+func (h *TransactionHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	file, ok := exporter.Formats[format]
+	if !ok {
+		Error(w, http.StatusBadRequest, "Invalid format (use ghostfolio, ghostfolio_csv or portfolio_performance)")
+		return
+	}
+
+	portfolios, err := h.portfolioRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch portfolios")
+		return
+	}
+
+	if v := r.URL.Query().Get("portfolio_id"); v != "" {
+		portfolioID, err := uuid.Parse(v)
+		if err != nil {
+			Error(w, http.StatusBadRequest, "Invalid portfolio ID")
+			return
+		}
+		var selected []*models.Portfolio
+		for _, p := range portfolios {
+			if p.ID == portfolioID {
+				selected = append(selected, p)
+			}
+		}
+		if len(selected) == 0 {
+			Error(w, http.StatusForbidden, "Access denied")
+			return
+		}
+		portfolios = selected
+	}
+
+	portfolioByID := make(map[uuid.UUID]*models.Portfolio, len(portfolios))
+	ids := make([]uuid.UUID, 0, len(portfolios))
+	accounts := make([]exporter.Account, 0, len(portfolios))
+	for _, p := range portfolios {
+		if p.Type == models.PortfolioTypeFixedAssets {
+			continue
+		}
+		portfolioByID[p.ID] = p
+		ids = append(ids, p.ID)
+		accounts = append(accounts, exporter.Account{ID: p.ID.String(), Name: p.Name, Currency: p.Currency})
+	}
+
+	txs, err := h.txRepo.GetByPortfolioIDs(r.Context(), ids)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch transactions")
+		return
+	}
+
+	activities := make([]exporter.Activity, 0, len(txs))
+	for _, tx := range txs {
+		p := portfolioByID[tx.PortfolioID]
+		a := exporter.Activity{
+			AccountID: p.ID.String(),
+			Account:   p.Name,
+			Date:      tx.TransactionDate,
+			Type:      tx.TransactionType,
+			Currency:  tx.Currency,
+			Amount:    tx.TotalAmount,
+			Note:      tx.Notes,
+		}
+		if tx.Quantity != nil {
+			a.Quantity = *tx.Quantity
+		}
+		if tx.Price != nil {
+			a.Price = *tx.Price
+		}
+		if tx.WithholdingTax != nil {
+			a.Tax = *tx.WithholdingTax
+		}
+		if tx.Asset != nil {
+			a.Symbol = tx.Asset.Symbol
+			a.Name = tx.Asset.Name
+			a.ISIN = tx.Asset.ISIN
+			a.DataSource = tx.Asset.DataSource
+		}
+		activities = append(activities, a)
+	}
+
+	var buf bytes.Buffer
+	skipped, err := exporter.Write(&buf, format, accounts, activities)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to export transactions")
+		return
+	}
+
+	filename := fmt.Sprintf("wellf-%s-%s.%s", format, time.Now().Format("2006-01-02"), file.Extension)
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("X-Export-Skipped", strconv.Itoa(skipped))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
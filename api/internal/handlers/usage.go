@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+type UsageHandler struct {
+	usageService *services.UsageService
+}
+
+func NewUsageHandler(usageService *services.UsageService) *UsageHandler {
+	return &UsageHandler{usageService: usageService}
+}
+
+// Get summarises the user's own API activity: requests per month, per module and per
+// feature, and the records they created each month. Query param months defaults to 12.
+func (h *UsageHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	months := 12
+	if v := r.URL.Query().Get("months"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > services.MaxUsageMonths {
+			Error(w, http.StatusBadRequest, "Invalid months (use 1 to 13)")
+			return
+		}
+		months = n
+	}
+
+	summary, err := h.usageService.Summary(r.Context(), userID, months)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch usage")
+		return
+	}
+
+	JSON(w, http.StatusOK, summary)
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+	"github.com/mark-regan/wellf/pkg/pdftext"
+)
+
+const maxVoucherSize = 5 << 20 // 5MB
+
+type VoucherResponse struct {
+	Voucher     *models.TransactionVoucher `json:"voucher"`
+	Parsed      services.DividendVoucher   `json:"parsed"`
+	Transaction *models.Transaction        `json:"transaction"`
+}
+
+// ownedTransaction parses the txId URL param and checks it belongs to the current user,
+// writing the error response if not
+func (h *TransactionHandler) ownedTransaction(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return uuid.Nil, false
+	}
+
+	txID, err := uuid.Parse(chi.URLParam(r, "txId"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid transaction ID")
+		return uuid.Nil, false
+	}
+
+	belongs, err := h.txRepo.BelongsToUser(r.Context(), txID, userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to verify ownership")
+		return uuid.Nil, false
+	}
+	if !belongs {
+		Error(w, http.StatusForbidden, "Access denied")
+		return uuid.Nil, false
+	}
+
+	return txID, true
+}
+
+// UploadVoucher attaches a dividend voucher PDF (multipart field "file") to a DIVIDEND
+// transaction, replacing any existing voucher. Gross, net and withholding tax amounts
+// found in the voucher are saved on the transaction; the net amount becomes its total,
+// converted into the portfolio's currency if the dividend was paid in another.
+func (h *TransactionHandler) UploadVoucher(w http.ResponseWriter, r *http.Request) {
+	txID, ok := h.ownedTransaction(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := h.txRepo.GetByID(r.Context(), txID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTransactionNotFound) {
+			Error(w, http.StatusNotFound, "Transaction not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch transaction")
+		return
+	}
+	if tx.TransactionType != models.TransactionTypeDividend {
+		Error(w, http.StatusBadRequest, "Vouchers can only be attached to dividend transactions")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxVoucherSize+1<<20)
+	if err := r.ParseMultipartForm(maxVoucherSize); err != nil {
+		Error(w, http.StatusBadRequest, "Failed to parse form data (max 5MB)")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		Error(w, http.StatusBadRequest, "No file uploaded")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxVoucherSize+1))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Failed to read file")
+		return
+	}
+	if len(data) > maxVoucherSize {
+		Error(w, http.StatusBadRequest, "File too large (max 5MB)")
+		return
+	}
+
+	text, err := pdftext.Extract(data)
+	if err != nil {
+		Error(w, http.StatusBadRequest, "File must be a PDF")
+		return
+	}
+
+	// The parsed amounts are applied, and a new net amount converted into the portfolio's
+	// currency, before anything is saved in case there's no rate
+	parsed := services.ParseDividendVoucher(text)
+	if parsed.Found() {
+		if parsed.GrossAmount != nil {
+			tx.GrossAmount = parsed.GrossAmount
+		}
+		if parsed.WithholdingTax != nil {
+			tx.WithholdingTax = parsed.WithholdingTax
+		}
+		if parsed.NetAmount != nil {
+			tx.TotalAmount = *parsed.NetAmount
+
+			portfolio, err := h.portfolioRepo.GetByID(r.Context(), tx.PortfolioID)
+			if err != nil {
+				Error(w, http.StatusInternalServerError, "Failed to fetch portfolio")
+				return
+			}
+			if !h.convertEdited(w, r, tx, portfolio.Currency) {
+				return
+			}
+		}
+	}
+
+	voucher := &models.TransactionVoucher{
+		TransactionID: txID,
+		FileName:      filepath.Base(header.Filename),
+		ContentType:   "application/pdf",
+		SizeBytes:     len(data),
+		Data:          data,
+		ExtractedText: text,
+	}
+	if err := h.txRepo.SaveVoucher(r.Context(), voucher); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to save voucher")
+		return
+	}
+
+	if parsed.Found() {
+		if err := h.txRepo.Update(r.Context(), tx); err != nil {
+			Error(w, http.StatusInternalServerError, "Failed to update transaction")
+			return
+		}
+	}
+
+	JSON(w, http.StatusCreated, VoucherResponse{
+		Voucher:     voucher,
+		Parsed:      parsed,
+		Transaction: tx,
+	})
+}
+
+// GetVoucher downloads the voucher attached to a transaction
+func (h *TransactionHandler) GetVoucher(w http.ResponseWriter, r *http.Request) {
+	txID, ok := h.ownedTransaction(w, r)
+	if !ok {
+		return
+	}
+
+	voucher, err := h.txRepo.GetVoucher(r.Context(), txID)
+	if err != nil {
+		if errors.Is(err, repository.ErrVoucherNotFound) {
+			Error(w, http.StatusNotFound, "Voucher not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch voucher")
+		return
+	}
+
+	w.Header().Set("Content-Type", voucher.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(voucher.Data)))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": voucher.FileName}))
+	w.WriteHeader(http.StatusOK)
+	w.Write(voucher.Data)
+}
+
+// DeleteVoucher removes the voucher from a transaction. Amounts already read from it
+// are kept.
+func (h *TransactionHandler) DeleteVoucher(w http.ResponseWriter, r *http.Request) {
+	txID, ok := h.ownedTransaction(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.txRepo.DeleteVoucher(r.Context(), txID); err != nil {
+		if errors.Is(err, repository.ErrVoucherNotFound) {
+			Error(w, http.StatusNotFound, "Voucher not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to delete voucher")
+		return
+	}
+
+	NoContent(w)
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+const maxWatchlistItems = 100
+
+type WatchlistHandler struct {
+	watchlistRepo *repository.WatchlistRepository
+	yahooService  *services.YahooService
+}
+
+func NewWatchlistHandler(watchlistRepo *repository.WatchlistRepository, yahooService *services.YahooService) *WatchlistHandler {
+	return &WatchlistHandler{
+		watchlistRepo: watchlistRepo,
+		yahooService:  yahooService,
+	}
+}
+
+type AddWatchlistItemRequest struct {
+	Symbol string `json:"symbol"`
+}
+
+type ReorderWatchlistRequest struct {
+	Symbols []string `json:"symbols"`
+}
+
+// parseWatchlist splits comma-separated symbols, dropping blanks and repeats
+func parseWatchlist(value string) []string {
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, s := range strings.Split(value, ",") {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		symbols = append(symbols, s)
+	}
+	if len(symbols) > maxWatchlistItems {
+		symbols = symbols[:maxWatchlistItems]
+	}
+	return symbols
+}
+
+func (h *WatchlistHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	items, err := h.watchlistRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch watchlist")
+		return
+	}
+	if items == nil {
+		items = []*models.WatchlistItem{}
+	}
+
+	JSON(w, http.StatusOK, items)
+}
+
+// Add puts a symbol at the end of the watchlist once it has been found on the market
+func (h *WatchlistHandler) Add(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req AddWatchlistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if symbol == "" {
+		Error(w, http.StatusBadRequest, "Symbol is required")
+		return
+	}
+
+	items, err := h.watchlistRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch watchlist")
+		return
+	}
+	if len(items) >= maxWatchlistItems {
+		Error(w, http.StatusBadRequest, "Watchlist is full (100 symbols)")
+		return
+	}
+
+	if _, err := h.yahooService.GetAssetDetails(r.Context(), symbol); err != nil {
+		Error(w, http.StatusBadRequest, "Symbol not found")
+		return
+	}
+
+	item := &models.WatchlistItem{UserID: userID, Symbol: symbol}
+	if err := h.watchlistRepo.Add(r.Context(), item); err != nil {
+		if errors.Is(err, repository.ErrWatchlistItemExists) {
+			Error(w, http.StatusConflict, "Symbol is already on the watchlist")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to add to watchlist")
+		return
+	}
+
+	JSON(w, http.StatusCreated, item)
+}
+
+func (h *WatchlistHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	symbol := strings.ToUpper(chi.URLParam(r, "symbol"))
+	if err := h.watchlistRepo.Delete(r.Context(), userID, symbol); err != nil {
+		if errors.Is(err, repository.ErrWatchlistItemNotFound) {
+			Error(w, http.StatusNotFound, "Symbol is not on the watchlist")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to remove from watchlist")
+		return
+	}
+
+	NoContent(w)
+}
+
+// Reorder sets the watchlist order. symbols must list every symbol on the watchlist
+// exactly once.
+func (h *WatchlistHandler) Reorder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req ReorderWatchlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	items, err := h.watchlistRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch watchlist")
+		return
+	}
+
+	onList := make(map[string]bool, len(items))
+	for _, item := range items {
+		onList[item.Symbol] = true
+	}
+	symbols := make([]string, len(req.Symbols))
+	for i, s := range req.Symbols {
+		symbols[i] = strings.ToUpper(strings.TrimSpace(s))
+		if !onList[symbols[i]] {
+			Error(w, http.StatusBadRequest, "Symbols must list every watchlist symbol exactly once")
+			return
+		}
+		delete(onList, symbols[i])
+	}
+	if len(onList) > 0 {
+		Error(w, http.StatusBadRequest, "Symbols must list every watchlist symbol exactly once")
+		return
+	}
+
+	if err := h.watchlistRepo.Reorder(r.Context(), userID, symbols); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to reorder watchlist")
+		return
+	}
+
+	items, err = h.watchlistRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch watchlist")
+		return
+	}
+	if items == nil {
+		items = []*models.WatchlistItem{}
+	}
+
+	JSON(w, http.StatusOK, items)
+}
+
+// Quotes returns live quotes for the whole watchlist in watchlist order. Symbols that
+// can't be quoted right now are left out.
+func (h *WatchlistHandler) Quotes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	items, err := h.watchlistRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch watchlist")
+		return
+	}
+	if len(items) == 0 {
+		JSON(w, http.StatusOK, []services.AssetDetails{})
+		return
+	}
+
+	symbols := make([]string, len(items))
+	for i, item := range items {
+		symbols[i] = item.Symbol
+	}
+
+	quotes, err := h.yahooService.GetQuotes(r.Context(), symbols)
+	if err != nil {
+		Error(w, http.StatusBadGateway, "Failed to fetch quotes")
+		return
+	}
+
+	bySymbol := make(map[string]services.AssetDetails, len(quotes))
+	for _, q := range quotes {
+		bySymbol[strings.ToUpper(q.Symbol)] = q
+	}
+	ordered := make([]services.AssetDetails, 0, len(quotes))
+	for _, symbol := range symbols {
+		if q, ok := bySymbol[symbol]; ok {
+			ordered = append(ordered, q)
+		}
+	}
+
+	JSON(w, http.StatusOK, ordered)
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/middleware"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/internal/services"
+)
+
+type WebhookHandler struct {
+	webhookRepo    *repository.WebhookRepository
+	webhookService *services.WebhookService
+}
+
+func NewWebhookHandler(webhookRepo *repository.WebhookRepository, webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookRepo:    webhookRepo,
+		webhookService: webhookService,
+	}
+}
+
+type WebhookRequest struct {
+	URL      string   `json:"url"`
+	Events   []string `json:"events"`
+	IsActive *bool    `json:"is_active"`
+}
+
+// validate checks the request, returning an error message if it is invalid
+func (req *WebhookRequest) validate() string {
+	req.URL = strings.TrimSpace(req.URL)
+	u, err := url.ParseRequestURI(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "URL must be an http or https URL"
+	}
+	if !services.ValidWebhookHost(u.Hostname()) {
+		return "URL must be a public address"
+	}
+	if len(req.URL) > 2048 {
+		return "URL is too long"
+	}
+	if len(req.Events) == 0 {
+		return "At least one event is required"
+	}
+	for _, event := range req.Events {
+		if !services.WebhookEvents[event] {
+			return "Unknown event: " + event
+		}
+	}
+	return ""
+}
+
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	webhooks, err := h.webhookRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch webhooks")
+		return
+	}
+
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+	if webhooks == nil {
+		webhooks = []*models.Webhook{}
+	}
+
+	JSON(w, http.StatusOK, webhooks)
+}
+
+// Create registers a webhook. The response includes the signing secret, which is not
+// shown again.
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	secret, err := services.NewWebhookSecret()
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to create webhook")
+		return
+	}
+
+	webhook := &models.Webhook{
+		UserID:   userID,
+		URL:      req.URL,
+		Secret:   secret,
+		Events:   req.Events,
+		IsActive: req.IsActive == nil || *req.IsActive,
+	}
+
+	if err := h.webhookRepo.Create(r.Context(), webhook); err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to create webhook")
+		return
+	}
+
+	JSON(w, http.StatusCreated, webhook)
+}
+
+func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := h.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.URL == "" {
+		req.URL = webhook.URL
+	}
+	if req.Events == nil {
+		req.Events = webhook.Events
+	}
+	if msg := req.validate(); msg != "" {
+		Error(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	webhook.URL = req.URL
+	webhook.Events = req.Events
+	if req.IsActive != nil {
+		webhook.IsActive = *req.IsActive
+	}
+
+	if err := h.webhookRepo.Update(r.Context(), webhook); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			Error(w, http.StatusNotFound, "Webhook not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to update webhook")
+		return
+	}
+
+	webhook.Secret = ""
+	JSON(w, http.StatusOK, webhook)
+}
+
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := h.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookRepo.Delete(r.Context(), webhook.ID); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			Error(w, http.StatusNotFound, "Webhook not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to delete webhook")
+		return
+	}
+
+	NoContent(w)
+}
+
+// Test sends a ping event to the webhook. Delivery is asynchronous; the outcome is
+// shown in the webhook's last_status and last_error, and in its delivery record.
+func (h *WebhookHandler) Test(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := h.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	h.webhookService.Send(webhook, models.WebhookEventPing, map[string]interface{}{
+		"webhook_id": webhook.ID,
+	})
+
+	JSON(w, http.StatusAccepted, map[string]string{"message": "Test event queued"})
+}
+
+// Deliveries lists the user's recent webhook deliveries, newest first. Query param
+// status filters to PENDING, DELIVERED, RETRYING or DEAD (the dead-letter list); limit
+// defaults to 50.
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	status := strings.ToUpper(r.URL.Query().Get("status"))
+	switch status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryRetrying, models.WebhookDeliveryDead:
+	default:
+		Error(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			Error(w, http.StatusBadRequest, "Invalid limit (use 1 to 500)")
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.webhookRepo.GetDeliveriesByUserID(r.Context(), userID, status, limit)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Failed to fetch webhook deliveries")
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*models.WebhookDelivery{}
+	}
+
+	JSON(w, http.StatusOK, deliveries)
+}
+
+// Redrive retries a dead delivery with a fresh deadline. The attempt is asynchronous.
+func (h *WebhookHandler) Redrive(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	deliveryID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid delivery ID")
+		return
+	}
+
+	delivery, err := h.webhookRepo.GetDelivery(r.Context(), deliveryID)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookDeliveryNotFound) {
+			Error(w, http.StatusNotFound, "Delivery not found")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch delivery")
+		return
+	}
+	if delivery.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	if err := h.webhookService.Redrive(r.Context(), delivery); err != nil {
+		if errors.Is(err, services.ErrDeliveryNotDead) {
+			Error(w, http.StatusConflict, "Only dead deliveries can be re-driven")
+			return
+		}
+		Error(w, http.StatusInternalServerError, "Failed to re-drive delivery")
+		return
+	}
+
+	JSON(w, http.StatusAccepted, delivery)
+}
+
+// ownedWebhook loads the webhook from the URL, writing an error response if it is missing or not the user's
+func (h *WebhookHandler) ownedWebhook(w http.ResponseWriter, r *http.Request) (*models.Webhook, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		Error(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	webhookID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "Invalid webhook ID")
+		return nil, false
+	}
+
+	webhook, err := h.webhookRepo.GetByID(r.Context(), webhookID)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			Error(w, http.StatusNotFound, "Webhook not found")
+			return nil, false
+		}
+		Error(w, http.StatusInternalServerError, "Failed to fetch webhook")
+		return nil, false
+	}
+
+	if webhook.UserID != userID {
+		Error(w, http.StatusForbidden, "Access denied")
+		return nil, false
+	}
+
+	return webhook, true
+}
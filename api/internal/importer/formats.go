@@ -0,0 +1,358 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Format names
+const (
+	Native              = "wellf"
+	AJBell              = "aj_bell"
+	Trading212          = "trading212"
+	Freetrade           = "freetrade"
+	HargreavesLansdown  = "hargreaves_lansdown"
+	Vanguard            = "vanguard"
+	InteractiveInvestor = "interactive_investor"
+)
+
+func init() {
+	register(&format{
+		name:     Native,
+		label:    "wellf CSV",
+		required: []string{"transaction_date", "symbol", "transaction_type", "quantity", "price"},
+		parse:    parseNative,
+	})
+	register(&format{
+		name:     Trading212,
+		label:    "Trading 212",
+		required: []string{"Action", "Time", "Ticker", "No. of shares", "Price / share"},
+		parse:    parseTrading212,
+	})
+	register(&format{
+		name:     AJBell,
+		label:    "AJ Bell",
+		required: []string{"Deal date", "Buy / Sell", "Investment", "Quantity", "Price"},
+		parse:    parseAJBell,
+	})
+	register(&format{
+		name:     Freetrade,
+		label:    "Freetrade",
+		required: []string{"Type", "Timestamp", "Buy / Sell", "Ticker", "Quantity", "Price per Share in Account Currency"},
+		parse:    parseFreetrade,
+	})
+	register(&format{
+		name:     HargreavesLansdown,
+		label:    "Hargreaves Lansdown",
+		required: []string{"Trade date", "Reference", "Description", "Unit cost (p)", "Quantity"},
+		parse:    parseHargreavesLansdown,
+	})
+	register(&format{
+		name:     Vanguard,
+		label:    "Vanguard Investor",
+		required: []string{"Date", "InvestmentName", "TransactionDetails", "Quantity", "Price"},
+		parse:    parseVanguard,
+	})
+	register(&format{
+		name:     InteractiveInvestor,
+		label:    "interactive investor",
+		required: []string{"Date", "Symbol", "Sedol", "Quantity", "Price", "Description", "Debit", "Credit"},
+		parse:    parseInteractiveInvestor,
+	})
+}
+
+// parseNative reads wellf's own format: transaction_date (YYYY-MM-DD), symbol,
+// transaction_type (BUY or SELL), quantity, price and optional currency and notes
+func parseNative(rec record, defaultCurrency string) (*Row, string, error) {
+	var errs []string
+	row := &Row{
+		Symbol:          strings.ToUpper(rec.get("symbol")),
+		TransactionType: strings.ToUpper(rec.get("transaction_type")),
+		Currency:        strings.ToUpper(rec.get("currency")),
+		Notes:           rec.get("notes"),
+	}
+
+	date, err := parseDate(rec.get("transaction_date"), "2006-01-02")
+	if err != nil {
+		errs = append(errs, "invalid date format (use YYYY-MM-DD)")
+	}
+	row.TransactionDate = date
+
+	if row.Symbol == "" {
+		errs = append(errs, "symbol is required")
+	}
+	if row.TransactionType != Buy && row.TransactionType != Sell {
+		errs = append(errs, fmt.Sprintf("invalid transaction type '%s' (use BUY or SELL)", row.TransactionType))
+	}
+
+	row.Quantity, _ = parseAmount(rec.get("quantity"))
+	row.Price, _ = parseAmount(rec.get("price"))
+	errs = append(errs, positive(row.Quantity, row.Price)...)
+
+	if len(errs) > 0 {
+		return nil, "", errors.New(strings.Join(errs, "; "))
+	}
+	if row.Currency == "" {
+		row.Currency = defaultCurrency
+	}
+	return row, "", nil
+}
+
+// parseTrading212 reads a Trading 212 history export. Actions such as "Market buy",
+// "Limit sell" or "Stop sell" are trades; deposits, dividends and interest are skipped.
+func parseTrading212(rec record, defaultCurrency string) (*Row, string, error) {
+	action := strings.ToLower(rec.get("Action"))
+	var txType string
+	switch {
+	case strings.HasSuffix(action, " buy"):
+		txType = Buy
+	case strings.HasSuffix(action, " sell"):
+		txType = Sell
+	default:
+		return nil, rec.get("Action"), nil
+	}
+
+	date, err := parseDate(rec.get("Time"), "2006-01-02 15:04:05", "2006-01-02 15:04:05.000", "2006-01-02T15:04:05Z07:00", "2006-01-02")
+	if err != nil {
+		return nil, "", err
+	}
+	quantity, _ := parseAmount(rec.get("No. of shares"))
+	price, _ := parseAmount(rec.get("Price / share"))
+	if errs := positive(quantity, price); len(errs) > 0 {
+		return nil, "", errors.New(strings.Join(errs, "; "))
+	}
+
+	currency := strings.ToUpper(rec.get("Currency (Price / share)"))
+	if currency == "" {
+		currency = defaultCurrency
+	}
+
+	return identified(&Row{
+		TransactionDate: date,
+		TransactionType: txType,
+		Symbol:          strings.ToUpper(rec.get("Ticker")),
+		Name:            rec.get("Name"),
+		ISIN:            rec.get("ISIN"),
+		Quantity:        quantity,
+		Price:           price,
+		Currency:        currency,
+		Notes:           rec.get("ID"),
+	})
+}
+
+// parseFreetrade reads a Freetrade activity export. ORDER rows are trades; top ups,
+// withdrawals, dividends and interest are skipped.
+func parseFreetrade(rec record, defaultCurrency string) (*Row, string, error) {
+	if !strings.EqualFold(rec.get("Type"), "ORDER") {
+		return nil, rec.get("Type"), nil
+	}
+
+	txType := strings.ToUpper(rec.get("Buy / Sell"))
+	if txType != Buy && txType != Sell {
+		return nil, "", fmt.Errorf("invalid order side '%s'", rec.get("Buy / Sell"))
+	}
+
+	date, err := parseDate(rec.get("Timestamp"), "2006-01-02T15:04:05.000Z07:00", "2006-01-02T15:04:05Z07:00", "2006-01-02 15:04:05", "2006-01-02")
+	if err != nil {
+		return nil, "", err
+	}
+	quantity, _ := parseAmount(rec.get("Quantity"))
+	price, _ := parseAmount(rec.get("Price per Share in Account Currency"))
+	if errs := positive(quantity, price); len(errs) > 0 {
+		return nil, "", errors.New(strings.Join(errs, "; "))
+	}
+
+	currency := strings.ToUpper(rec.get("Account Currency"))
+	if currency == "" {
+		currency = defaultCurrency
+	}
+
+	return identified(&Row{
+		TransactionDate: date,
+		TransactionType: txType,
+		Symbol:          strings.ToUpper(rec.get("Ticker")),
+		Name:            rec.get("Title"),
+		ISIN:            rec.get("ISIN"),
+		Quantity:        quantity,
+		Price:           price,
+		Currency:        currency,
+	})
+}
+
+// parseAJBell reads an AJ Bell transaction history export. Investments are identified by
+// name, with an optional Symbol or Sedol column; prices in a "GBX" currency are in pence.
+func parseAJBell(rec record, defaultCurrency string) (*Row, string, error) {
+	side := strings.ToUpper(rec.get("Buy / Sell"))
+	var txType string
+	switch {
+	case strings.HasPrefix(side, "B"):
+		txType = Buy
+	case strings.HasPrefix(side, "S"):
+		txType = Sell
+	default:
+		return nil, rec.get("Buy / Sell"), nil
+	}
+
+	date, err := parseDate(rec.get("Deal date"), "02/01/2006", "2/1/2006", "2006-01-02")
+	if err != nil {
+		return nil, "", err
+	}
+	quantity, _ := parseAmount(rec.get("Quantity"))
+	price, _ := parseAmount(rec.get("Price"))
+	quantity = math.Abs(quantity)
+	if errs := positive(quantity, price); len(errs) > 0 {
+		return nil, "", errors.New(strings.Join(errs, "; "))
+	}
+
+	currency := strings.ToUpper(rec.get("Currency"))
+	if currency == "" {
+		currency = defaultCurrency
+	}
+
+	return identified(&Row{
+		TransactionDate: date,
+		TransactionType: txType,
+		Symbol:          strings.ToUpper(rec.get("Symbol", "Ticker")),
+		Name:            rec.get("Investment"),
+		SEDOL:           strings.ToUpper(rec.get("Sedol")),
+		Quantity:        quantity,
+		Price:           price,
+		Currency:        currency,
+		Notes:           rec.get("Reference"),
+	})
+}
+
+// parseHargreavesLansdown reads a Hargreaves Lansdown capital account export. References
+// starting B are purchases and S sales; other rows and rows without units are cash
+// movements. The export has no tickers, so rows are identified by their description and
+// need a symbol mapping.
+func parseHargreavesLansdown(rec record, _ string) (*Row, string, error) {
+	ref := strings.ToUpper(rec.get("Reference"))
+	var txType string
+	switch {
+	case strings.HasPrefix(ref, "B"):
+		txType = Buy
+	case strings.HasPrefix(ref, "S"):
+		txType = Sell
+	default:
+		return nil, rec.get("Description"), nil
+	}
+
+	// Cash movements such as BACS receipts have no units
+	quantity, err := parseAmount(rec.get("Quantity"))
+	if err != nil {
+		return nil, rec.get("Description"), nil
+	}
+	quantity = math.Abs(quantity)
+
+	date, err := parseDate(rec.get("Trade date"), "02/01/2006", "2/1/2006", "02/01/06")
+	if err != nil {
+		return nil, "", err
+	}
+	pence, _ := parseAmount(rec.get("Unit cost (p)"))
+	if errs := positive(quantity, pence); len(errs) > 0 {
+		return nil, "", errors.New(strings.Join(errs, "; "))
+	}
+
+	return identified(&Row{
+		TransactionDate: date,
+		TransactionType: txType,
+		Name:            rec.get("Description"),
+		Quantity:        quantity,
+		Price:           pence,
+		Currency:        "GBX",
+		Notes:           rec.get("Reference"),
+	})
+}
+
+// parseVanguard reads the investment transactions sheet of a Vanguard Investor export.
+// Details starting "Bought" or "Sold" are trades. Funds are identified by name only.
+func parseVanguard(rec record, _ string) (*Row, string, error) {
+	details := rec.get("TransactionDetails")
+	var txType string
+	switch lower := strings.ToLower(details); {
+	case strings.HasPrefix(lower, "bought"):
+		txType = Buy
+	case strings.HasPrefix(lower, "sold"):
+		txType = Sell
+	default:
+		return nil, details, nil
+	}
+
+	date, err := parseDate(rec.get("Date"), "02/01/2006", "2/1/2006", "2006-01-02")
+	if err != nil {
+		return nil, "", err
+	}
+	quantity, _ := parseAmount(rec.get("Quantity"))
+	price, _ := parseAmount(rec.get("Price"))
+	quantity = math.Abs(quantity)
+	if errs := positive(quantity, price); len(errs) > 0 {
+		return nil, "", errors.New(strings.Join(errs, "; "))
+	}
+
+	return identified(&Row{
+		TransactionDate: date,
+		TransactionType: txType,
+		Name:            rec.get("InvestmentName"),
+		Quantity:        quantity,
+		Price:           price,
+		Currency:        "GBP",
+		Notes:           details,
+	})
+}
+
+// parseInteractiveInvestor reads an interactive investor transaction export. Rows with a
+// quantity are trades: a debit is a purchase and a credit a sale.
+func parseInteractiveInvestor(rec record, _ string) (*Row, string, error) {
+	quantity, err := parseAmount(rec.get("Quantity"))
+	if err != nil || quantity == 0 {
+		return nil, rec.get("Description"), nil
+	}
+
+	var txType string
+	switch {
+	case rec.get("Debit") != "" && rec.get("Debit") != "n/a":
+		txType = Buy
+	case rec.get("Credit") != "" && rec.get("Credit") != "n/a":
+		txType = Sell
+	default:
+		return nil, rec.get("Description"), nil
+	}
+
+	date, err := parseDate(rec.get("Date"), "02/01/2006", "2/1/2006", "2006-01-02")
+	if err != nil {
+		return nil, "", err
+	}
+	price, _ := parseAmount(rec.get("Price"))
+	quantity = math.Abs(quantity)
+	if errs := positive(quantity, price); len(errs) > 0 {
+		return nil, "", errors.New(strings.Join(errs, "; "))
+	}
+
+	symbol := strings.ToUpper(rec.get("Symbol"))
+	if symbol == "N/A" {
+		symbol = ""
+	}
+
+	return identified(&Row{
+		TransactionDate: date,
+		TransactionType: txType,
+		Symbol:          symbol,
+		Name:            rec.get("Description"),
+		SEDOL:           strings.ToUpper(rec.get("Sedol")),
+		Quantity:        quantity,
+		Price:           price,
+		Currency:        "GBP",
+		Notes:           rec.get("Reference"),
+	})
+}
+
+// identified returns the row, or an error if it has nothing to identify the investment by
+func identified(row *Row) (*Row, string, error) {
+	if len(row.Identifiers()) == 0 {
+		return nil, "", errors.New("symbol, name, ISIN or SEDOL is required")
+	}
+	return row, "", nil
+}
@@ -0,0 +1,301 @@
+// Package importer parses transaction CSV exports into buy and sell rows. The native
+// format uses wellf's own column names; broker formats adapt the exports of UK
+// investment platforms, skipping cash movements, dividends and fees.
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transaction types produced by the importer
+const (
+	Buy  = "BUY"
+	Sell = "SELL"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown import format")
+	ErrEmpty         = errors.New("the CSV file appears to be empty or malformed")
+)
+
+// Row is one parsed buy or sell. Symbol is empty for brokers whose exports only name
+// the investment; Name, ISIN and SEDOL can then be mapped to a symbol.
+type Row struct {
+	Line            int
+	TransactionDate time.Time
+	TransactionType string
+	Symbol          string
+	Name            string
+	ISIN            string
+	SEDOL           string
+	Quantity        float64
+	Price           float64
+	Currency        string
+	Notes           string
+}
+
+// Identifiers returns the values a symbol mapping can be keyed on, most specific first
+func (r *Row) Identifiers() []string {
+	var ids []string
+	for _, v := range []string{r.Symbol, r.ISIN, r.SEDOL, r.Name} {
+		if v != "" {
+			ids = append(ids, v)
+		}
+	}
+	return ids
+}
+
+// Identifier returns the most specific identifier, or "" if the row has none
+func (r *Row) Identifier() string {
+	if ids := r.Identifiers(); len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
+// Skipped is a row that was read but isn't a buy or sell
+type Skipped struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// Result is the outcome of parsing a file
+type Result struct {
+	Format  string
+	Rows    []*Row
+	Skipped []Skipped
+	Errors  []string // "Line N: ..." messages for rows that couldn't be parsed
+}
+
+// MissingColumnError reports a column the format requires that isn't in the header
+type MissingColumnError struct {
+	Format   string
+	Column   string
+	Required []string
+}
+
+func (e *MissingColumnError) Error() string {
+	return "Missing required column: " + e.Column
+}
+
+// record gives a format's parser access to a CSV line by column name
+type record struct {
+	cols   map[string]int
+	fields []string
+}
+
+// get returns the trimmed value of the first of the named columns present in the row
+func (r record) get(names ...string) string {
+	for _, name := range names {
+		if i, ok := r.cols[strings.ToLower(name)]; ok && i < len(r.fields) {
+			return strings.TrimSpace(r.fields[i])
+		}
+	}
+	return ""
+}
+
+// format describes one export layout. parse returns a nil row and a reason to skip
+// lines that aren't trades.
+type format struct {
+	name     string
+	label    string
+	required []string
+	parse    func(rec record, defaultCurrency string) (row *Row, skip string, err error)
+}
+
+var formats = map[string]*format{}
+
+func register(f *format) {
+	formats[f.name] = f
+}
+
+// Format describes an import format for clients
+type Format struct {
+	Name     string   `json:"name"`
+	Label    string   `json:"label"`
+	Required []string `json:"required_columns"`
+}
+
+// Formats lists the supported formats, native first
+func Formats() []Format {
+	list := make([]Format, 0, len(formats))
+	for _, f := range formats {
+		list = append(list, Format{Name: f.name, Label: f.label, Required: f.required})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if (list[i].Name == Native) != (list[j].Name == Native) {
+			return list[i].Name == Native
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// Parse reads a CSV export in the named format ("" or "auto" detects it from the header).
+// Rows with unparseable values are reported in Result.Errors rather than failing the parse.
+func Parse(r io.Reader, name, defaultCurrency string) (*Result, error) {
+	return parse(r, defaultCurrency, func(cols map[string]int) (*format, error) {
+		if name == "" || name == "auto" {
+			return detect(cols), nil
+		}
+		if f := formats[name]; f != nil {
+			return f, nil
+		}
+		return nil, ErrUnknownFormat
+	})
+}
+
+func parse(r io.Reader, defaultCurrency string, choose func(cols map[string]int) (*format, error)) (*Result, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, ErrEmpty
+	}
+	cols := make(map[string]int, len(header))
+	for i, col := range header {
+		col = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff")))
+		if _, dup := cols[col]; !dup {
+			cols[col] = i
+		}
+	}
+
+	f, err := choose(cols)
+	if err != nil {
+		return nil, err
+	}
+	for _, col := range f.required {
+		if _, ok := cols[strings.ToLower(col)]; !ok {
+			return nil, &MissingColumnError{Format: f.name, Column: col, Required: f.required}
+		}
+	}
+
+	result := &Result{Format: f.name}
+	line := 1
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("error reading line %d: %w", line, err)
+		}
+		if isBlank(fields) {
+			continue
+		}
+
+		row, skip, err := f.parse(record{cols: cols, fields: fields}, defaultCurrency)
+		switch {
+		case err != nil:
+			result.Errors = append(result.Errors, fmt.Sprintf("Line %d: %s", line, err.Error()))
+		case row == nil:
+			result.Skipped = append(result.Skipped, Skipped{Line: line, Reason: skip})
+		case row.TransactionDate.After(time.Now()):
+			result.Errors = append(result.Errors, fmt.Sprintf("Line %d: transaction date cannot be in the future", line))
+		default:
+			row.Line = line
+			normaliseMinorUnits(row)
+			result.Rows = append(result.Rows, row)
+		}
+	}
+
+	return result, nil
+}
+
+// detect picks the broker format whose required columns are all present, falling back
+// to the native format
+func detect(cols map[string]int) *format {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := formats[name]
+		if name == Native {
+			continue
+		}
+		matched := true
+		for _, col := range f.required {
+			if _, ok := cols[strings.ToLower(col)]; !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return f
+		}
+	}
+	return formats[Native]
+}
+
+// normaliseMinorUnits converts prices quoted in pence into pounds
+func normaliseMinorUnits(row *Row) {
+	switch row.Currency {
+	case "GBX", "GBp", "GBP_PENCE":
+		row.Price /= 100
+		row.Currency = "GBP"
+	}
+}
+
+func isBlank(fields []string) bool {
+	for _, f := range fields {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseAmount parses a number that may include a currency symbol, thousands separators
+// or accounting-style parentheses for negatives
+func parseAmount(v string) (float64, error) {
+	v = strings.TrimSpace(v)
+	negative := strings.HasPrefix(v, "(") && strings.HasSuffix(v, ")")
+	v = strings.Trim(v, "()")
+	v = strings.NewReplacer("£", "", "$", "", "€", "", ",", "", " ", "", "p", "").Replace(v)
+	if v == "" {
+		return 0, errors.New("missing value")
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		n = -n
+	}
+	return n, nil
+}
+
+// parseDate parses the date with the first matching layout
+func parseDate(v string, layouts ...string) (time.Time, error) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date '%s'", v)
+}
+
+// positive validates a trade's quantity and price
+func positive(quantity, price float64) []string {
+	var errs []string
+	if quantity <= 0 {
+		errs = append(errs, "quantity must be a positive number")
+	}
+	if price <= 0 {
+		errs = append(errs, "price must be a positive number")
+	}
+	return errs
+}
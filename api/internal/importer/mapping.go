@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+// Custom is the format name reported for imports using a saved mapping
+const Custom = "custom"
+
+// dateFormats maps the date formats a mapping can use to Go layouts
+var dateFormats = map[string][]string{
+	"YYYY-MM-DD": {"2006-01-02", "2006-01-02 15:04:05", "2006-01-02T15:04:05Z07:00"},
+	"DD/MM/YYYY": {"02/01/2006", "2/1/2006", "02/01/2006 15:04:05"},
+	"MM/DD/YYYY": {"01/02/2006", "1/2/2006", "01/02/2006 15:04:05"},
+}
+
+// ValidateMapping checks a user-defined mapping, returning an error message if it is invalid
+func ValidateMapping(m models.ImportMapping) string {
+	switch {
+	case strings.TrimSpace(m.Date) == "":
+		return "Date column is required"
+	case strings.TrimSpace(m.Type) == "":
+		return "Type column is required"
+	case strings.TrimSpace(m.Quantity) == "":
+		return "Quantity column is required"
+	case strings.TrimSpace(m.Price) == "":
+		return "Price column is required"
+	case m.Symbol == "" && m.Name == "" && m.ISIN == "":
+		return "A symbol, name or ISIN column is required"
+	}
+	if m.DateFormat != "" {
+		if _, ok := dateFormats[strings.ToUpper(m.DateFormat)]; !ok {
+			return "Invalid date format (use YYYY-MM-DD, DD/MM/YYYY or MM/DD/YYYY)"
+		}
+	}
+	return ""
+}
+
+// ParseMapping reads a CSV export using a user-defined column mapping
+func ParseMapping(r io.Reader, m models.ImportMapping, defaultCurrency string) (*Result, error) {
+	f := mappingFormat(m)
+	return parse(r, defaultCurrency, func(map[string]int) (*format, error) {
+		return f, nil
+	})
+}
+
+func mappingFormat(m models.ImportMapping) *format {
+	required := []string{m.Date, m.Type, m.Quantity, m.Price}
+	for _, col := range []string{m.Symbol, m.Name, m.ISIN} {
+		if col != "" {
+			required = append(required, col)
+		}
+	}
+
+	layouts := dateFormats["YYYY-MM-DD"]
+	if m.DateFormat != "" {
+		layouts = dateFormats[strings.ToUpper(m.DateFormat)]
+	}
+	buy := m.BuyValues
+	if len(buy) == 0 {
+		buy = []string{Buy}
+	}
+	sell := m.SellValues
+	if len(sell) == 0 {
+		sell = []string{Sell}
+	}
+
+	return &format{
+		name:     Custom,
+		required: required,
+		parse: func(rec record, defaultCurrency string) (*Row, string, error) {
+			value := rec.get(m.Type)
+			var txType string
+			switch {
+			case matchesValue(value, buy):
+				txType = Buy
+			case matchesValue(value, sell):
+				txType = Sell
+			default:
+				return nil, value, nil
+			}
+
+			date, err := parseDate(rec.get(m.Date), layouts...)
+			if err != nil {
+				return nil, "", err
+			}
+			quantity, _ := parseAmount(rec.get(m.Quantity))
+			price, _ := parseAmount(rec.get(m.Price))
+			if quantity < 0 {
+				quantity = -quantity
+			}
+			if errs := positive(quantity, price); len(errs) > 0 {
+				return nil, "", errors.New(strings.Join(errs, "; "))
+			}
+
+			row := &Row{
+				TransactionDate: date,
+				TransactionType: txType,
+				Quantity:        quantity,
+				Price:           price,
+				Currency:        defaultCurrency,
+			}
+			if m.Symbol != "" {
+				row.Symbol = strings.ToUpper(rec.get(m.Symbol))
+			}
+			if m.Name != "" {
+				row.Name = rec.get(m.Name)
+			}
+			if m.ISIN != "" {
+				row.ISIN = strings.ToUpper(rec.get(m.ISIN))
+			}
+			if row.Symbol == "" && row.Name == "" && row.ISIN == "" {
+				return nil, "", fmt.Errorf("no symbol, name or ISIN")
+			}
+			if m.Currency != "" {
+				if c := strings.ToUpper(rec.get(m.Currency)); c != "" {
+					row.Currency = c
+				}
+			}
+			if m.PriceInPence {
+				row.Currency = "GBX"
+			}
+			if m.Notes != "" {
+				row.Notes = rec.get(m.Notes)
+			}
+			return row, "", nil
+		},
+	}
+}
+
+// matchesValue reports whether v equals one of values, ignoring case
+func matchesValue(v string, values []string) bool {
+	for _, value := range values {
+		if strings.EqualFold(strings.TrimSpace(value), v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package marketdata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+const alphaVantageURL = "https://www.alphavantage.co/query"
+
+// AlphaVantageProvider prices assets from Alpha Vantage. The API has no batch quote
+// endpoint, so quotes are fetched one symbol at a time.
+type AlphaVantageProvider struct {
+	apiKey     string
+	httpClient *http.Client
+}
+
+func NewAlphaVantageProvider(apiKey string) *AlphaVantageProvider {
+	return &AlphaVantageProvider{
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (p *AlphaVantageProvider) Name() string {
+	return models.DataSourceAlphaVantage
+}
+
+type alphaVantageQuote struct {
+	GlobalQuote struct {
+		Symbol           string `json:"01. symbol"`
+		Price            string `json:"05. price"`
+		LatestTradingDay string `json:"07. latest trading day"`
+		Change           string `json:"09. change"`
+		ChangePercent    string `json:"10. change percent"`
+	} `json:"Global Quote"`
+	Note        string `json:"Note"`
+	Information string `json:"Information"`
+}
+
+func (p *AlphaVantageProvider) GetQuotes(ctx context.Context, symbols []string) ([]Quote, error) {
+	quotes := make([]Quote, 0, len(symbols))
+	var lastErr error
+	for _, symbol := range symbols {
+		var resp alphaVantageQuote
+		if err := p.get(ctx, url.Values{"function": {"GLOBAL_QUOTE"}, "symbol": {symbol}}, &resp); err != nil {
+			lastErr = err
+			continue
+		}
+		if msg := resp.Note + resp.Information; msg != "" {
+			// Rate limited; the remaining symbols would fail the same way
+			return quotes, fmt.Errorf("alpha vantage: %s", msg)
+		}
+
+		q := resp.GlobalQuote
+		price, err := strconv.ParseFloat(q.Price, 64)
+		if err != nil || price <= 0 {
+			continue
+		}
+		change, _ := strconv.ParseFloat(q.Change, 64)
+		changePct, _ := strconv.ParseFloat(strings.TrimSuffix(q.ChangePercent, "%"), 64)
+
+		quote := Quote{
+			Symbol:    symbol,
+			Name:      symbol,
+			Price:     price,
+			Change:    change,
+			ChangePct: changePct,
+		}
+		if day, err := time.Parse("2006-01-02", q.LatestTradingDay); err == nil {
+			quote.MarketTime = day.Unix()
+		}
+		quotes = append(quotes, quote)
+	}
+
+	if len(quotes) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return quotes, nil
+}
+
+func (p *AlphaVantageProvider) GetHistoricalPrice(ctx context.Context, symbol string, date time.Time) (float64, error) {
+	var resp struct {
+		Series map[string]struct {
+			Close string `json:"4. close"`
+		} `json:"Time Series (Daily)"`
+		Note        string `json:"Note"`
+		Information string `json:"Information"`
+	}
+	params := url.Values{"function": {"TIME_SERIES_DAILY"}, "symbol": {symbol}, "outputsize": {"full"}}
+	if err := p.get(ctx, params, &resp); err != nil {
+		return 0, err
+	}
+	if msg := resp.Note + resp.Information; msg != "" {
+		return 0, fmt.Errorf("alpha vantage: %s", msg)
+	}
+
+	// Use the latest close on or before the date
+	target := date.Format("2006-01-02")
+	days := make([]string, 0, len(resp.Series))
+	for day := range resp.Series {
+		if day <= target {
+			days = append(days, day)
+		}
+	}
+	if len(days) == 0 {
+		return 0, fmt.Errorf("no price data found for date: %s", target)
+	}
+	sort.Strings(days)
+
+	price, err := strconv.ParseFloat(resp.Series[days[len(days)-1]].Close, 64)
+	if err != nil || price <= 0 {
+		return 0, fmt.Errorf("no price data found for date: %s", target)
+	}
+	return price, nil
+}
+
+func (p *AlphaVantageProvider) get(ctx context.Context, params url.Values, v interface{}) error {
+	params.Set("apikey", p.apiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, alphaVantageURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package marketdata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+const coinGeckoURL = "https://api.coingecko.com/api/v3"
+
+// coinGeckoCurrencies are the quote currencies accepted in a crypto pair
+var coinGeckoCurrencies = map[string]bool{
+	"USD": true, "GBP": true, "EUR": true, "JPY": true, "CHF": true, "CAD": true, "AUD": true,
+}
+
+// coinGeckoIDs maps the most common tickers to CoinGecko coin IDs. Other tickers are
+// looked up with the search endpoint.
+var coinGeckoIDs = map[string]string{
+	"BTC":   "bitcoin",
+	"ETH":   "ethereum",
+	"USDT":  "tether",
+	"USDC":  "usd-coin",
+	"BNB":   "binancecoin",
+	"SOL":   "solana",
+	"XRP":   "ripple",
+	"ADA":   "cardano",
+	"DOGE":  "dogecoin",
+	"TRX":   "tron",
+	"DOT":   "polkadot",
+	"LTC":   "litecoin",
+	"BCH":   "bitcoin-cash",
+	"LINK":  "chainlink",
+	"AVAX":  "avalanche-2",
+	"MATIC": "matic-network",
+	"XLM":   "stellar",
+	"ATOM":  "cosmos",
+	"SHIB":  "shiba-inu",
+	"UNI":   "uniswap",
+}
+
+// CoinGeckoProvider prices crypto pairs written the way Yahoo writes them, e.g. BTC-GBP,
+// from CoinGecko. Other symbols are not supported. The public API works without a key;
+// a demo key raises the rate limit.
+type CoinGeckoProvider struct {
+	apiKey     string
+	httpClient *http.Client
+	mu         sync.RWMutex
+	ids        map[string]string
+}
+
+func NewCoinGeckoProvider(apiKey string) *CoinGeckoProvider {
+	ids := make(map[string]string, len(coinGeckoIDs))
+	for ticker, id := range coinGeckoIDs {
+		ids[ticker] = id
+	}
+	return &CoinGeckoProvider{
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		ids:        ids,
+	}
+}
+
+func (p *CoinGeckoProvider) Name() string {
+	return models.DataSourceCoinGecko
+}
+
+// Supports reports whether the symbol is a crypto pair such as BTC-GBP
+func (p *CoinGeckoProvider) Supports(symbol string) bool {
+	_, _, ok := splitCryptoPair(symbol)
+	return ok
+}
+
+// splitCryptoPair splits a symbol such as BTC-GBP into its ticker and quote currency
+func splitCryptoPair(symbol string) (ticker, currency string, ok bool) {
+	i := strings.LastIndex(symbol, "-")
+	if i <= 0 {
+		return "", "", false
+	}
+	ticker, currency = strings.ToUpper(symbol[:i]), strings.ToUpper(symbol[i+1:])
+	return ticker, currency, coinGeckoCurrencies[currency]
+}
+
+func (p *CoinGeckoProvider) GetQuotes(ctx context.Context, symbols []string) ([]Quote, error) {
+	// One request per quote currency, each covering all its coins
+	type pair struct{ symbol, id string }
+	byCurrency := make(map[string][]pair)
+	var lastErr error
+	for _, symbol := range symbols {
+		ticker, currency, ok := splitCryptoPair(symbol)
+		if !ok {
+			continue
+		}
+		id, err := p.coinID(ctx, ticker)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		byCurrency[currency] = append(byCurrency[currency], pair{symbol: symbol, id: id})
+	}
+
+	var quotes []Quote
+	for currency, pairs := range byCurrency {
+		ids := make([]string, 0, len(pairs))
+		for _, pr := range pairs {
+			ids = append(ids, pr.id)
+		}
+
+		vs := strings.ToLower(currency)
+		var resp map[string]map[string]float64
+		params := url.Values{
+			"ids":                     {strings.Join(ids, ",")},
+			"vs_currencies":           {vs},
+			"include_market_cap":      {"true"},
+			"include_24hr_change":     {"true"},
+			"include_last_updated_at": {"true"},
+		}
+		if err := p.get(ctx, "/simple/price", params, &resp); err != nil {
+			lastErr = err
+			continue
+		}
+
+		for _, pr := range pairs {
+			data, ok := resp[pr.id]
+			if !ok || data[vs] <= 0 {
+				continue
+			}
+			price := data[vs]
+			quote := Quote{
+				Symbol:     pr.symbol,
+				Name:       pr.symbol,
+				Currency:   currency,
+				QuoteType:  "CRYPTOCURRENCY",
+				Price:      price,
+				MarketTime: int64(data["last_updated_at"]),
+			}
+			if marketCap, ok := data[vs+"_market_cap"]; ok {
+				quote.MarketCap = &marketCap
+			}
+			if pct, ok := data[vs+"_24h_change"]; ok {
+				change := price - price/(1+pct/100)
+				quote.Change = change
+				quote.ChangePct = pct
+				quote.Change24h = &change
+				quote.Change24hPct = &pct
+			}
+			quotes = append(quotes, quote)
+		}
+	}
+
+	if len(quotes) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return quotes, nil
+}
+
+func (p *CoinGeckoProvider) GetHistoricalPrice(ctx context.Context, symbol string, date time.Time) (float64, error) {
+	ticker, currency, ok := splitCryptoPair(symbol)
+	if !ok {
+		return 0, fmt.Errorf("not a crypto pair: %s", symbol)
+	}
+	id, err := p.coinID(ctx, ticker)
+	if err != nil {
+		return 0, err
+	}
+
+	var resp struct {
+		MarketData struct {
+			CurrentPrice map[string]float64 `json:"current_price"`
+		} `json:"market_data"`
+	}
+	params := url.Values{"date": {date.Format("02-01-2006")}, "localization": {"false"}}
+	if err := p.get(ctx, "/coins/"+url.PathEscape(id)+"/history", params, &resp); err != nil {
+		return 0, err
+	}
+
+	price := resp.MarketData.CurrentPrice[strings.ToLower(currency)]
+	if price <= 0 {
+		return 0, fmt.Errorf("no price data found for date: %s", date.Format("2006-01-02"))
+	}
+	return price, nil
+}
+
+// GetHistory returns prices over the last days days (all history when 0). CoinGecko
+// picks the granularity: 5-minute for a day, hourly up to 90 days, daily beyond. Only
+// closing prices are available, so each candle's open, high and low equal its close.
+func (p *CoinGeckoProvider) GetHistory(ctx context.Context, symbol string, days int) ([]Candle, error) {
+	ticker, currency, ok := splitCryptoPair(symbol)
+	if !ok {
+		return nil, fmt.Errorf("not a crypto pair: %s", symbol)
+	}
+	id, err := p.coinID(ctx, ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	daysParam := "max"
+	if days > 0 {
+		daysParam = strconv.Itoa(days)
+	}
+	var resp struct {
+		Prices  [][2]float64 `json:"prices"`
+		Volumes [][2]float64 `json:"total_volumes"`
+	}
+	params := url.Values{"vs_currency": {strings.ToLower(currency)}, "days": {daysParam}}
+	if err := p.get(ctx, "/coins/"+url.PathEscape(id)+"/market_chart", params, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Prices) == 0 {
+		return nil, fmt.Errorf("no chart data for symbol: %s", symbol)
+	}
+
+	candles := make([]Candle, 0, len(resp.Prices))
+	for i, point := range resp.Prices {
+		candle := Candle{
+			Time:  time.UnixMilli(int64(point[0])),
+			Open:  point[1],
+			High:  point[1],
+			Low:   point[1],
+			Close: point[1],
+		}
+		if i < len(resp.Volumes) {
+			candle.Volume = int64(resp.Volumes[i][1])
+		}
+		candles = append(candles, candle)
+	}
+	return candles, nil
+}
+
+// coinID resolves a ticker to a CoinGecko coin ID, searching for tickers not seen
+// before and taking the highest ranked coin with that ticker
+func (p *CoinGeckoProvider) coinID(ctx context.Context, ticker string) (string, error) {
+	p.mu.RLock()
+	id, ok := p.ids[ticker]
+	p.mu.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	var resp struct {
+		Coins []struct {
+			ID     string `json:"id"`
+			Symbol string `json:"symbol"`
+		} `json:"coins"`
+	}
+	if err := p.get(ctx, "/search", url.Values{"query": {ticker}}, &resp); err != nil {
+		return "", err
+	}
+	for _, coin := range resp.Coins {
+		if strings.EqualFold(coin.Symbol, ticker) {
+			p.mu.Lock()
+			p.ids[ticker] = coin.ID
+			p.mu.Unlock()
+			return coin.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no coingecko coin for ticker: %s", ticker)
+}
+
+func (p *CoinGeckoProvider) get(ctx context.Context, path string, params url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, coinGeckoURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("x-cg-demo-api-key", p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
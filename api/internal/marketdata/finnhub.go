@@ -0,0 +1,111 @@
+package marketdata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+const finnhubURL = "https://finnhub.io/api/v1"
+
+// FinnhubProvider prices assets from Finnhub. Quotes are fetched one symbol at a time.
+type FinnhubProvider struct {
+	apiKey     string
+	httpClient *http.Client
+}
+
+func NewFinnhubProvider(apiKey string) *FinnhubProvider {
+	return &FinnhubProvider{
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (p *FinnhubProvider) Name() string {
+	return models.DataSourceFinnhub
+}
+
+func (p *FinnhubProvider) GetQuotes(ctx context.Context, symbols []string) ([]Quote, error) {
+	quotes := make([]Quote, 0, len(symbols))
+	var lastErr error
+	for _, symbol := range symbols {
+		var resp struct {
+			Current   float64 `json:"c"`
+			Change    float64 `json:"d"`
+			ChangePct float64 `json:"dp"`
+			Time      int64   `json:"t"`
+		}
+		if err := p.get(ctx, "/quote", url.Values{"symbol": {symbol}}, &resp); err != nil {
+			lastErr = err
+			continue
+		}
+		// Unknown symbols come back as all zeros
+		if resp.Current <= 0 {
+			continue
+		}
+
+		quotes = append(quotes, Quote{
+			Symbol:     symbol,
+			Name:       symbol,
+			Price:      resp.Current,
+			Change:     resp.Change,
+			ChangePct:  resp.ChangePct,
+			MarketTime: resp.Time,
+		})
+	}
+
+	if len(quotes) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return quotes, nil
+}
+
+func (p *FinnhubProvider) GetHistoricalPrice(ctx context.Context, symbol string, date time.Time) (float64, error) {
+	var resp struct {
+		Close  []float64 `json:"c"`
+		Status string    `json:"s"`
+	}
+	// A few days either side in case the date is a weekend or holiday
+	params := url.Values{
+		"symbol":     {symbol},
+		"resolution": {"D"},
+		"from":       {strconv.FormatInt(date.AddDate(0, 0, -5).Unix(), 10)},
+		"to":         {strconv.FormatInt(date.AddDate(0, 0, 1).Unix(), 10)},
+	}
+	if err := p.get(ctx, "/stock/candle", params, &resp); err != nil {
+		return 0, err
+	}
+	if resp.Status != "ok" || len(resp.Close) == 0 {
+		return 0, fmt.Errorf("no price data found for date: %s", date.Format("2006-01-02"))
+	}
+
+	return resp.Close[len(resp.Close)-1], nil
+}
+
+func (p *FinnhubProvider) get(ctx context.Context, path string, params url.Values, v interface{}) error {
+	params.Set("token", p.apiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, finnhubURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
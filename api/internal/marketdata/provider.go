@@ -0,0 +1,66 @@
+// Package marketdata prices assets from one of several upstream providers, failing
+// over to the next healthy provider when one is unavailable.
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNoProvider is returned when every provider failed or none is configured
+var ErrNoProvider = errors.New("no market data provider available")
+
+// Quote is a provider's latest price for a symbol
+type Quote struct {
+	Symbol     string
+	Name       string
+	Exchange   string
+	Currency   string
+	QuoteType  string
+	Price      float64
+	Change     float64
+	ChangePct  float64
+	MarketTime int64
+
+	// Only filled in by providers that report them, such as CoinGecko for crypto
+	MarketCap    *float64
+	Change24h    *float64
+	Change24hPct *float64
+}
+
+// Candle is one point of price history
+type Candle struct {
+	Time   time.Time
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume int64
+}
+
+// PriceProvider fetches quotes and historical closes from an upstream source. Name is
+// the data_source value that selects the provider for an asset.
+type PriceProvider interface {
+	Name() string
+
+	// GetQuotes returns quotes for as many of the symbols as the provider knows.
+	// Missing symbols are left out rather than failing the whole call.
+	GetQuotes(ctx context.Context, symbols []string) ([]Quote, error)
+
+	// GetHistoricalPrice returns the closing price nearest to date
+	GetHistoricalPrice(ctx context.Context, symbol string, date time.Time) (float64, error)
+}
+
+// SymbolFilter is implemented by providers that only price some kinds of symbol. The
+// registry doesn't send them symbols they don't support.
+type SymbolFilter interface {
+	Supports(symbol string) bool
+}
+
+// HistoryProvider is implemented by providers that serve price history
+type HistoryProvider interface {
+	// GetHistory returns prices over the last days days, or all available history
+	// when days is 0
+	GetHistory(ctx context.Context, symbol string, days int) ([]Candle, error)
+}
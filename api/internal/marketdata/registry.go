@@ -0,0 +1,294 @@
+package marketdata
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// failureThreshold is how many consecutive failures take a provider out of rotation
+	failureThreshold = 3
+
+	// cooldown is how long a provider stays out of rotation before it is tried again
+	cooldown = 5 * time.Minute
+)
+
+// ProviderHealth summarises recent calls to a provider
+type ProviderHealth struct {
+	Name                string     `json:"name"`
+	Status              string     `json:"status"` // up, down or unknown (no calls yet)
+	Available           bool       `json:"available"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+}
+
+type providerState struct {
+	provider    PriceProvider
+	failures    int
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastError   string
+	retryAt     time.Time
+}
+
+// Registry holds the configured providers in failover order and tracks their health.
+// A provider that fails failureThreshold times in a row is skipped for cooldown, unless
+// every provider is out of rotation.
+type Registry struct {
+	mu     sync.Mutex
+	states []*providerState
+	logger *slog.Logger
+}
+
+// NewRegistry creates a registry that fails over between providers in the order given
+func NewRegistry(logger *slog.Logger, providers ...PriceProvider) *Registry {
+	r := &Registry{logger: logger}
+	for _, p := range providers {
+		r.states = append(r.states, &providerState{provider: p})
+	}
+	return r
+}
+
+// Has reports whether a provider with the name is configured
+func (r *Registry) Has(name string) bool {
+	for _, state := range r.states {
+		if state.provider.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns the configured provider names in failover order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.states))
+	for _, state := range r.states {
+		names = append(names, state.provider.Name())
+	}
+	return names
+}
+
+// GetQuotes fetches quotes starting with the preferred providers in order, passing any
+// symbols a provider couldn't price on to the next. Symbols are matched case-insensitively.
+func (r *Registry) GetQuotes(ctx context.Context, preferred []string, symbols []string) ([]Quote, error) {
+	if len(symbols) == 0 {
+		return nil, nil
+	}
+
+	var quotes []Quote
+	remaining := symbols
+	var lastErr error
+	for _, state := range r.order(preferred) {
+		if ctx.Err() != nil {
+			return quotes, ctx.Err()
+		}
+
+		supported := supportedSymbols(state.provider, remaining)
+		if len(supported) == 0 {
+			continue
+		}
+
+		got, err := state.provider.GetQuotes(ctx, supported)
+		if err == nil && len(got) == 0 {
+			err = fmt.Errorf("no quotes returned for %d symbols", len(supported))
+		}
+		if err != nil {
+			r.recordFailure(state, err)
+			lastErr = err
+			if len(got) == 0 {
+				continue
+			}
+		} else {
+			r.recordSuccess(state)
+		}
+
+		priced := make(map[string]bool, len(got))
+		for _, q := range got {
+			priced[strings.ToUpper(q.Symbol)] = true
+		}
+		quotes = append(quotes, got...)
+
+		var left []string
+		for _, symbol := range remaining {
+			if !priced[strings.ToUpper(symbol)] {
+				left = append(left, symbol)
+			}
+		}
+		if remaining = left; len(remaining) == 0 {
+			return quotes, nil
+		}
+	}
+
+	if len(quotes) == 0 {
+		if lastErr == nil {
+			lastErr = ErrNoProvider
+		}
+		return nil, lastErr
+	}
+	return quotes, nil
+}
+
+// GetHistoricalPrice fetches a historical close starting with the preferred providers
+func (r *Registry) GetHistoricalPrice(ctx context.Context, preferred []string, symbol string, date time.Time) (float64, error) {
+	lastErr := ErrNoProvider
+	for _, state := range r.order(preferred) {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+
+		if len(supportedSymbols(state.provider, []string{symbol})) == 0 {
+			continue
+		}
+
+		price, err := state.provider.GetHistoricalPrice(ctx, symbol, date)
+		if err != nil {
+			r.recordFailure(state, err)
+			lastErr = err
+			continue
+		}
+		r.recordSuccess(state)
+		return price, nil
+	}
+	return 0, lastErr
+}
+
+// GetHistory fetches price history from the named provider, without failover, if it
+// serves history and supports the symbol
+func (r *Registry) GetHistory(ctx context.Context, name, symbol string, days int) ([]Candle, error) {
+	for _, state := range r.states {
+		if state.provider.Name() != name {
+			continue
+		}
+		history, ok := state.provider.(HistoryProvider)
+		if !ok || len(supportedSymbols(state.provider, []string{symbol})) == 0 {
+			break
+		}
+
+		candles, err := history.GetHistory(ctx, symbol, days)
+		if err != nil {
+			r.recordFailure(state, err)
+			return nil, err
+		}
+		r.recordSuccess(state)
+		return candles, nil
+	}
+	return nil, ErrNoProvider
+}
+
+// Health reports each provider's recent calls in failover order
+func (r *Registry) Health() []ProviderHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	health := make([]ProviderHealth, 0, len(r.states))
+	for _, state := range r.states {
+		h := ProviderHealth{
+			Name:                state.provider.Name(),
+			Status:              "unknown",
+			Available:           !now.Before(state.retryAt),
+			ConsecutiveFailures: state.failures,
+			LastError:           state.lastError,
+		}
+		if !state.lastSuccess.IsZero() {
+			success := state.lastSuccess
+			h.LastSuccessAt = &success
+			h.Status = "up"
+		}
+		if !state.lastFailure.IsZero() {
+			failure := state.lastFailure
+			h.LastFailureAt = &failure
+			if failure.After(state.lastSuccess) {
+				h.Status = "down"
+			}
+		}
+		if !h.Available {
+			retryAt := state.retryAt
+			h.RetryAt = &retryAt
+		}
+		health = append(health, h)
+	}
+	return health
+}
+
+// order returns the providers to try: the preferred ones first in the order given, then
+// the rest in failover order, with those out of rotation moved to the end
+func (r *Registry) order(preferred []string) []*providerState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var available, cooling []*providerState
+	add := func(state *providerState) {
+		if now.Before(state.retryAt) {
+			cooling = append(cooling, state)
+		} else {
+			available = append(available, state)
+		}
+	}
+	added := make(map[*providerState]bool, len(r.states))
+	for _, name := range preferred {
+		for _, state := range r.states {
+			if state.provider.Name() == name && !added[state] {
+				added[state] = true
+				add(state)
+			}
+		}
+	}
+	for _, state := range r.states {
+		if !added[state] {
+			add(state)
+		}
+	}
+	return append(available, cooling...)
+}
+
+func (r *Registry) recordSuccess(state *providerState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !state.retryAt.IsZero() {
+		r.logger.Info("market data provider recovered", "provider", state.provider.Name())
+	}
+	state.failures = 0
+	state.lastSuccess = time.Now()
+	state.lastError = ""
+	state.retryAt = time.Time{}
+}
+
+func (r *Registry) recordFailure(state *providerState, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state.failures++
+	state.lastFailure = time.Now()
+	state.lastError = err.Error()
+	if state.failures >= failureThreshold {
+		if state.retryAt.IsZero() {
+			r.logger.Warn("market data provider out of rotation", "provider", state.provider.Name(), "error", err)
+		}
+		state.retryAt = state.lastFailure.Add(cooldown)
+	}
+}
+
+// supportedSymbols returns the symbols the provider can price
+func supportedSymbols(provider PriceProvider, symbols []string) []string {
+	filter, ok := provider.(SymbolFilter)
+	if !ok {
+		return symbols
+	}
+	var supported []string
+	for _, symbol := range symbols {
+		if filter.Supports(symbol) {
+			supported = append(supported, symbol)
+		}
+	}
+	return supported
+}
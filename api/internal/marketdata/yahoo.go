@@ -0,0 +1,53 @@
+package marketdata
+
+import (
+	"context"
+	"time"
+
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/yahoo"
+)
+
+// YahooProvider prices assets from Yahoo Finance
+type YahooProvider struct {
+	client *yahoo.Client
+}
+
+func NewYahooProvider(client *yahoo.Client) *YahooProvider {
+	return &YahooProvider{client: client}
+}
+
+func (p *YahooProvider) Name() string {
+	return models.DataSourceYahoo
+}
+
+func (p *YahooProvider) GetQuotes(ctx context.Context, symbols []string) ([]Quote, error) {
+	results, err := p.client.GetQuotes(ctx, symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := make([]Quote, 0, len(results))
+	for _, q := range results {
+		name := q.LongName
+		if name == "" {
+			name = q.ShortName
+		}
+		quotes = append(quotes, Quote{
+			Symbol:     q.Symbol,
+			Name:       name,
+			Exchange:   q.Exchange,
+			Currency:   q.Currency,
+			QuoteType:  q.QuoteType,
+			Price:      q.RegularMarketPrice,
+			Change:     q.RegularMarketChange,
+			ChangePct:  q.RegularMarketChangePercent,
+			MarketTime: q.RegularMarketTime,
+		})
+	}
+	return quotes, nil
+}
+
+func (p *YahooProvider) GetHistoricalPrice(ctx context.Context, symbol string, date time.Time) (float64, error) {
+	return p.client.GetHistoricalPrice(ctx, symbol, date)
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+const APITokenKey contextKey = "api_token"
+
+// apiRoutesPrefix is stripped from request paths before they are matched to a scope
+const apiRoutesPrefix = "/api/v1/"
+
+// APITokenScopes lists the scopes an API token can be granted. A read scope allows GET
+// requests to its area of the API and a write scope everything else.
+var APITokenScopes = map[string]bool{
+	"finance:read":   true,
+	"finance:write":  true,
+	"webhooks:read":  true,
+	"webhooks:write": true,
+	"profile:read":   true,
+}
+
+// kioskPaths are the endpoints a kiosk token can read: the dashboard summary, goals and
+// markets, the hub's quick actions and the reminders agenda
+var kioskPaths = map[string]bool{
+	"dashboard/summary": true,
+	"dashboard/goals":   true,
+	"dashboard/markets": true,
+	"hub/quick-actions": true,
+	"reminders":         true,
+	"tokens/introspect": true,
+}
+
+// KioskAllowed reports whether a kiosk token can make the request
+func KioskAllowed(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	return kioskPaths[strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, apiRoutesPrefix), "/")]
+}
+
+// APITokenAuth authenticates requests made with personal API tokens, checking the
+// token's scopes and holding it to its own rate limit
+type APITokenAuth struct {
+	repo    *repository.APITokenRepository
+	limiter *RateLimiter
+	logger  *slog.Logger
+}
+
+func NewAPITokenAuth(repo *repository.APITokenRepository, redisClient redis.UniversalClient, logger *slog.Logger) *APITokenAuth {
+	return &APITokenAuth{
+		repo:    repo,
+		limiter: NewRateLimiter(redisClient, 0, time.Minute, "api_token"),
+		logger:  logger,
+	}
+}
+
+// RequiredScope returns the scope a token needs for the request. Requests with no
+// scope can't be made with a token at all, except those that are open to any token.
+func RequiredScope(r *http.Request) (scope string, open bool) {
+	path := strings.TrimPrefix(r.URL.Path, apiRoutesPrefix)
+	area, _, _ := strings.Cut(path, "/")
+
+	access := "write"
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		access = "read"
+	}
+
+	switch area {
+	case "tokens":
+		// Tokens can describe themselves but not manage other tokens
+		return "", path == "tokens/introspect" && access == "read"
+	case "batch":
+		// Each sub-request is checked on its own
+		return "", true
+	case "admin":
+		return "", false
+	case "auth", "usage", "onboarding":
+		if access == "read" {
+			return "profile:read", false
+		}
+		return "", false
+	case "webhooks":
+		return "webhooks:" + access, false
+	default:
+		return "finance:" + access, false
+	}
+}
+
+// serve authenticates the request with the API token and passes it on
+func (a *APITokenAuth) serve(w http.ResponseWriter, r *http.Request, next http.Handler, secret string) {
+	token, err := a.repo.GetByToken(r.Context(), secret)
+	if err != nil {
+		if !errors.Is(err, repository.ErrAPITokenNotFound) {
+			a.logger.Error("failed to look up api token", "error", err)
+		}
+		http.Error(w, `{"error":"Invalid token"}`, http.StatusUnauthorized)
+		return
+	}
+	if token.Expired(time.Now()) {
+		http.Error(w, `{"error":"Token has expired"}`, http.StatusUnauthorized)
+		return
+	}
+
+	if token.Kind == models.APITokenKindKiosk {
+		if !KioskAllowed(r) {
+			http.Error(w, `{"error":"This endpoint can't be used with a kiosk token"}`, http.StatusForbidden)
+			return
+		}
+	} else if scope, open := RequiredScope(r); !open {
+		if scope == "" {
+			http.Error(w, `{"error":"This endpoint can't be used with an API token"}`, http.StatusForbidden)
+			return
+		}
+		if !token.HasScope(scope) {
+			http.Error(w, `{"error":"Token is missing the `+scope+` scope"}`, http.StatusForbidden)
+			return
+		}
+	}
+
+	if err := a.repo.Touch(r.Context(), token.ID); err != nil {
+		a.logger.Warn("failed to record api token use", "token_id", token.ID, "error", err)
+	}
+
+	if info, ok := r.Context().Value(logInfoKey).(*requestLogInfo); ok {
+		info.userID = token.UserID
+	}
+
+	ctx := context.WithValue(r.Context(), UserIDKey, token.UserID)
+	ctx = context.WithValue(ctx, APITokenKey, token)
+
+	a.limiter.limitKey(w, r.WithContext(ctx), next, a.limiter.keyPrefix+":"+token.ID.String(), token.RateLimit)
+}
+
+// GetAPIToken returns the API token the request was made with, if any
+func GetAPIToken(ctx context.Context) (*models.APIToken, bool) {
+	token, ok := ctx.Value(APITokenKey).(*models.APIToken)
+	return token, ok
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// demoMaxBody caps how much of a simulated request body is echoed back
+const demoMaxBody = 1 << 20
+
+// DemoMode intercepts mutating requests and answers them with a simulated success so
+// nothing is persisted. Paths in allowed (e.g. login and token refresh) are passed
+// through, and registration is refused outright.
+func DemoMode(allowed ...string) func(http.Handler) http.Handler {
+	passThrough := make(map[string]bool, len(allowed))
+	for _, path := range allowed {
+		passThrough[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			path := strings.TrimSuffix(r.URL.Path, "/")
+			if passThrough[path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if strings.HasSuffix(path, "/auth/register") {
+				http.Error(w, `{"error":"Registration is disabled in demo mode"}`, http.StatusForbidden)
+				return
+			}
+
+			if r.Method == http.MethodDelete {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			// Echo the submitted object back so the client can carry on as if it was saved
+			var data interface{}
+			body, _ := io.ReadAll(io.LimitReader(r.Body, demoMaxBody))
+			if len(body) > 0 {
+				_ = json.Unmarshal(body, &data)
+			}
+
+			status := http.StatusOK
+			if r.Method == http.MethodPost {
+				status = http.StatusCreated
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"demo":    true,
+				"message": "Changes are not saved in demo mode",
+				"data":    data,
+			})
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// FeatureChecker reports whether a feature flag is on
+type FeatureChecker interface {
+	FeatureEnabled(name string) bool
+}
+
+// RequireFeature rejects requests while the named feature flag is off. Flags are checked
+// on each request, so they can be switched at runtime via /admin/settings.
+func RequireFeature(flags FeatureChecker, name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !flags.FeatureEnabled(name) {
+				http.Error(w, `{"error":"This feature is disabled"}`, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
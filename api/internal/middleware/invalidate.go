@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// CacheInvalidator drops a user's cached data
+type CacheInvalidator interface {
+	Invalidate(ctx context.Context, userID uuid.UUID)
+}
+
+// InvalidateOnWrite drops the user's cached data after any request that may have changed
+// it, i.e. anything but a GET, HEAD or OPTIONS. It must run after the auth middleware.
+func InvalidateOnWrite(cache CacheInvalidator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return
+			}
+			if userID, ok := GetUserID(r.Context()); ok {
+				cache.Invalidate(r.Context(), userID)
+			}
+		})
+	}
+}
@@ -3,11 +3,14 @@ package middleware
 import (
 	"context"
 	"log/slog"
+	"math/rand"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/repository"
 	"github.com/mark-regan/wellf/pkg/jwt"
 )
 
@@ -18,22 +21,75 @@ const (
 	EmailKey  contextKey = "email"
 )
 
+// LoggerConfig controls which requests are logged
+type LoggerConfig struct {
+	// SampleRate is the fraction (0-1) of requests to SampledRoutes that are logged
+	SampleRate float64
+	// SampledRoutes are high-volume route patterns, e.g. "/api/v1/health"
+	SampledRoutes []string
+	// SlowThreshold logs requests taking longer than this as warnings; zero disables
+	SlowThreshold time.Duration
+}
+
+// requestLogInfo is filled in by later middleware so the logger can report it
+type requestLogInfo struct {
+	userID uuid.UUID
+}
+
+const logInfoKey contextKey = "log_info"
+
 // Logging middleware
-func Logger(logger *slog.Logger) func(http.Handler) http.Handler {
+func Logger(logger *slog.Logger, cfg LoggerConfig) func(http.Handler) http.Handler {
+	sampled := make(map[string]bool, len(cfg.SampledRoutes))
+	for _, route := range cfg.SampledRoutes {
+		sampled[route] = true
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 
+			info := &requestLogInfo{}
+			r = r.WithContext(context.WithValue(r.Context(), logInfoKey, info))
+
 			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 			next.ServeHTTP(wrapped, r)
 
-			logger.Info("request",
+			duration := time.Since(start)
+
+			// Use the route pattern so requests to the same endpoint group together
+			route := r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if pattern := rctx.RoutePattern(); pattern != "" {
+					route = pattern
+				}
+			}
+
+			slow := cfg.SlowThreshold > 0 && duration > cfg.SlowThreshold
+
+			// Errors and slow requests are always logged
+			if sampled[route] && !slow && wrapped.statusCode < http.StatusInternalServerError &&
+				rand.Float64() >= cfg.SampleRate {
+				return
+			}
+
+			attrs := []any{
 				"method", r.Method,
-				"path", r.URL.Path,
+				"route", route,
 				"status", wrapped.statusCode,
-				"duration", time.Since(start),
+				"bytes", wrapped.bytes,
+				"duration", duration,
 				"remote_addr", r.RemoteAddr,
-			)
+			}
+			if info.userID != uuid.Nil {
+				attrs = append(attrs, "user_id", info.userID)
+			}
+
+			if slow {
+				logger.Warn("slow request", append(attrs, "threshold", cfg.SlowThreshold)...)
+				return
+			}
+			logger.Info("request", attrs...)
 		})
 	}
 }
@@ -41,6 +97,7 @@ func Logger(logger *slog.Logger) func(http.Handler) http.Handler {
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -48,6 +105,17 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (e.g. to flush streams)
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // TokenBlacklistChecker is an interface for checking if tokens are blacklisted
 type TokenBlacklistChecker interface {
 	IsBlacklisted(ctx context.Context, tokenID string) (bool, error)
@@ -60,6 +128,12 @@ func Auth(jwtManager *jwt.Manager) func(http.Handler) http.Handler {
 
 // AuthWithBlacklist is Auth middleware with optional token blacklist checking
 func AuthWithBlacklist(jwtManager *jwt.Manager, blacklist TokenBlacklistChecker) func(http.Handler) http.Handler {
+	return AuthWithAPITokens(jwtManager, blacklist, nil)
+}
+
+// AuthWithAPITokens is AuthWithBlacklist that also accepts personal API tokens when
+// apiTokens is set
+func AuthWithAPITokens(jwtManager *jwt.Manager, blacklist TokenBlacklistChecker, apiTokens *APITokenAuth) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -74,6 +148,11 @@ func AuthWithBlacklist(jwtManager *jwt.Manager, blacklist TokenBlacklistChecker)
 				return
 			}
 
+			if apiTokens != nil && strings.HasPrefix(parts[1], repository.APITokenPrefix) {
+				apiTokens.serve(w, r, next, parts[1])
+				return
+			}
+
 			claims, err := jwtManager.ValidateToken(parts[1])
 			if err != nil {
 				if err == jwt.ErrExpiredToken {
@@ -95,6 +174,11 @@ func AuthWithBlacklist(jwtManager *jwt.Manager, blacklist TokenBlacklistChecker)
 				}
 			}
 
+			// Let the request logger report who made the request
+			if info, ok := r.Context().Value(logInfoKey).(*requestLogInfo); ok {
+				info.userID = claims.UserID
+			}
+
 			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 			ctx = context.WithValue(ctx, EmailKey, claims.Email)
 
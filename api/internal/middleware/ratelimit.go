@@ -4,54 +4,64 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 type RateLimiter struct {
-	redis      *redis.Client
-	limit      int
+	redis      redis.UniversalClient
+	limit      atomic.Int64
 	window     time.Duration
 	keyPrefix  string
 }
 
-func NewRateLimiter(redisClient *redis.Client, limit int, window time.Duration, keyPrefix string) *RateLimiter {
-	return &RateLimiter{
+func NewRateLimiter(redisClient redis.UniversalClient, limit int, window time.Duration, keyPrefix string) *RateLimiter {
+	rl := &RateLimiter{
 		redis:     redisClient,
-		limit:     limit,
 		window:    window,
 		keyPrefix: keyPrefix,
 	}
+	rl.limit.Store(int64(limit))
+	return rl
+}
+
+// SetLimit changes the number of requests allowed per window without a restart
+func (rl *RateLimiter) SetLimit(limit int) {
+	rl.limit.Store(int64(limit))
 }
 
 func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-
 		// Get client identifier (user ID if authenticated, IP otherwise)
 		key := rl.getKey(r)
 
-		allowed, remaining, resetAt, err := rl.isAllowed(ctx, key)
-		if err != nil {
-			// If Redis fails, allow the request but log the error
-			next.ServeHTTP(w, r)
-			return
-		}
+		rl.limitKey(w, r, next, key, int(rl.limit.Load()))
+	})
+}
+
+// limitKey serves the request if key has made fewer than limit requests this window
+func (rl *RateLimiter) limitKey(w http.ResponseWriter, r *http.Request, next http.Handler, key string, limit int) {
+	allowed, remaining, resetAt, err := rl.isAllowed(r.Context(), key, limit)
+	if err != nil {
+		// If Redis fails, allow the request but log the error
+		next.ServeHTTP(w, r)
+		return
+	}
 
-		// Set rate limit headers
-		w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", rl.limit))
-		w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
-		w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", resetAt.Unix()))
+	// Set rate limit headers
+	w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
+	w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
+	w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", resetAt.Unix()))
 
-		if !allowed {
-			w.Header().Set("Retry-After", fmt.Sprintf("%d", int(time.Until(resetAt).Seconds())))
-			http.Error(w, `{"error":"Rate limit exceeded"}`, http.StatusTooManyRequests)
-			return
-		}
+	if !allowed {
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", int(time.Until(resetAt).Seconds())))
+		http.Error(w, `{"error":"Rate limit exceeded"}`, http.StatusTooManyRequests)
+		return
+	}
 
-		next.ServeHTTP(w, r)
-	})
+	next.ServeHTTP(w, r)
 }
 
 func (rl *RateLimiter) getKey(r *http.Request) string {
@@ -72,7 +82,7 @@ func (rl *RateLimiter) getKey(r *http.Request) string {
 	return fmt.Sprintf("%s:ip:%s", rl.keyPrefix, ip)
 }
 
-func (rl *RateLimiter) isAllowed(ctx context.Context, key string) (bool, int, time.Time, error) {
+func (rl *RateLimiter) isAllowed(ctx context.Context, key string, limit int) (bool, int, time.Time, error) {
 	now := time.Now()
 	windowStart := now.Truncate(rl.window)
 	resetAt := windowStart.Add(rl.window)
@@ -91,22 +101,22 @@ func (rl *RateLimiter) isAllowed(ctx context.Context, key string) (bool, int, ti
 	}
 
 	count := int(incrCmd.Val())
-	remaining := rl.limit - count
+	remaining := limit - count
 	if remaining < 0 {
 		remaining = 0
 	}
 
-	return count <= rl.limit, remaining, resetAt, nil
+	return count <= limit, remaining, resetAt, nil
 }
 
 // LoginRateLimiter specifically for login attempts
 type LoginRateLimiter struct {
-	redis     *redis.Client
+	redis     redis.UniversalClient
 	maxAttempts int
 	lockDuration time.Duration
 }
 
-func NewLoginRateLimiter(redisClient *redis.Client, maxAttempts int, lockDuration time.Duration) *LoginRateLimiter {
+func NewLoginRateLimiter(redisClient redis.UniversalClient, maxAttempts int, lockDuration time.Duration) *LoginRateLimiter {
 	return &LoginRateLimiter{
 		redis:        redisClient,
 		maxAttempts:  maxAttempts,
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// UsageRecorder counts a request against the user's usage statistics
+type UsageRecorder interface {
+	Record(ctx context.Context, userID uuid.UUID, feature string)
+}
+
+// TrackUsage records each authenticated request as "METHOD /route/pattern" so the user
+// can see which parts of the API they use. It must run after the auth middleware.
+func TrackUsage(recorder UsageRecorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+
+			userID, ok := GetUserID(r.Context())
+			if !ok {
+				return
+			}
+
+			// The full route pattern is only known once routing has finished
+			rctx := chi.RouteContext(r.Context())
+			if rctx == nil || rctx.RoutePattern() == "" {
+				return
+			}
+			recorder.Record(r.Context(), userID, r.Method+" "+rctx.RoutePattern())
+		})
+	}
+}
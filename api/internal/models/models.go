@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -25,8 +26,8 @@ type User struct {
 	NotifyPriceAlerts bool       `json:"notify_price_alerts"`
 	NotifyWeekly      bool       `json:"notify_weekly"`
 	NotifyMonthly     bool       `json:"notify_monthly"`
-	Watchlist         string     `json:"watchlist,omitempty"`
 	ProviderLists     string     `json:"provider_lists,omitempty"`
+	TaxJurisdiction   string     `json:"tax_jurisdiction"`
 	// Admin fields
 	IsAdmin  bool `json:"is_admin"`
 	IsLocked bool `json:"is_locked"`
@@ -102,6 +103,7 @@ type PortfolioMetadata struct {
 	Provider         string  `json:"provider,omitempty"`
 	AccountReference string  `json:"account_reference,omitempty"`
 	InterestRate     float64 `json:"interest_rate,omitempty"`
+	CostBasisMethod  string  `json:"cost_basis_method,omitempty"` // FIFO, LIFO or AVERAGE (default)
 
 	// ISA/JISA specific
 	ISAType     string `json:"isa_type,omitempty"` // STOCKS_AND_SHARES or CASH
@@ -135,6 +137,7 @@ type PortfolioMetadata struct {
 	// Contribution tracking
 	ContributionsThisYear float64 `json:"contributions_this_year,omitempty"`
 	ContributionLimit     float64 `json:"contribution_limit,omitempty"`
+	EnforceAllowance      bool    `json:"enforce_allowance,omitempty"` // reject contributions over the allowance instead of warning
 }
 
 // Asset types
@@ -146,6 +149,33 @@ const (
 	AssetTypeBond   = "BOND"
 )
 
+// Market data sources an asset can be priced from
+const (
+	DataSourceYahoo        = "YAHOO"
+	DataSourceAlphaVantage = "ALPHA_VANTAGE"
+	DataSourceFinnhub      = "FINNHUB"
+	DataSourceCoinGecko    = "COINGECKO"
+)
+
+// Price source route match types, from most to least specific
+const (
+	PriceRouteMatchSymbol    = "SYMBOL"
+	PriceRouteMatchExchange  = "EXCHANGE"
+	PriceRouteMatchAssetType = "ASSET_TYPE"
+)
+
+// PriceSourceRoute sends the assets it matches to market data providers in the order
+// listed, ahead of the asset's own data source
+type PriceSourceRoute struct {
+	ID         uuid.UUID  `json:"id"`
+	MatchType  string     `json:"match_type"`
+	MatchValue string     `json:"match_value"`
+	Sources    []string   `json:"sources"`
+	UpdatedBy  *uuid.UUID `json:"updated_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
 // Asset represents a tradeable security
 type Asset struct {
 	ID                 uuid.UUID  `json:"id"`
@@ -157,9 +187,53 @@ type Asset struct {
 	DataSource         string     `json:"data_source"`
 	LastPrice          *float64   `json:"last_price,omitempty"`
 	LastPriceUpdatedAt *time.Time `json:"last_price_updated_at,omitempty"`
+	ISIN               string     `json:"isin,omitempty"`
+	SEDOL              string     `json:"sedol,omitempty"`
 	CreatedAt          time.Time  `json:"created_at"`
 }
 
+// Corporate action types. A split and a consolidation differ only in whether the
+// ratio increases or reduces the number of units.
+const (
+	CorporateActionSplit         = "SPLIT"
+	CorporateActionConsolidation = "CONSOLIDATION"
+	CorporateActionTickerChange  = "TICKER_CHANGE"
+)
+
+// CorporateAction is a split, consolidation or ticker change recorded against an asset.
+// For splits and consolidations RatioFrom old units become RatioTo new units, e.g. 1 to 4
+// for a 4-for-1 split.
+type CorporateAction struct {
+	ID            uuid.UUID  `json:"id"`
+	AssetID       uuid.UUID  `json:"asset_id"`
+	ActionType    string     `json:"action_type"`
+	EffectiveDate time.Time  `json:"effective_date"`
+	RatioFrom     *float64   `json:"ratio_from,omitempty"`
+	RatioTo       *float64   `json:"ratio_to,omitempty"`
+	OldSymbol     *string    `json:"old_symbol,omitempty"`
+	NewSymbol     *string    `json:"new_symbol,omitempty"`
+	Notes         *string    `json:"notes,omitempty"`
+	CreatedBy     *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Ratio is the number of new units per old unit, 1 for ticker changes
+func (a *CorporateAction) Ratio() float64 {
+	if a.RatioFrom == nil || a.RatioTo == nil || *a.RatioFrom == 0 {
+		return 1
+	}
+	return *a.RatioTo / *a.RatioFrom
+}
+
+// CorporateActionResult reports what applying or reverting a corporate action changed
+type CorporateActionResult struct {
+	Action               *CorporateAction `json:"action"`
+	HoldingsAdjusted     int              `json:"holdings_adjusted"`
+	TransactionsAdjusted int              `json:"transactions_adjusted"`
+	PricesAdjusted       int              `json:"prices_adjusted"`
+	Portfolios           []uuid.UUID      `json:"-"`
+}
+
 // Holding represents a current position in a portfolio
 type Holding struct {
 	ID          uuid.UUID  `json:"id"`
@@ -168,6 +242,7 @@ type Holding struct {
 	Quantity    float64    `json:"quantity"`
 	AverageCost float64    `json:"average_cost"`
 	PurchasedAt *time.Time `json:"purchased_at,omitempty"`
+	Notes       string     `json:"notes,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 
@@ -176,6 +251,108 @@ type Holding struct {
 	CurrentValue *float64 `json:"current_value,omitempty"`
 	GainLoss     *float64 `json:"gain_loss,omitempty"`
 	GainLossPct  *float64 `json:"gain_loss_pct,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+// Cost basis methods for matching sells against lots
+const (
+	CostBasisFIFO    = "FIFO"
+	CostBasisLIFO    = "LIFO"
+	CostBasisAverage = "AVERAGE"
+)
+
+// HoldingLot is a parcel of units acquired in one transaction. Lots are rebuilt from the
+// portfolio's transactions; units the transactions don't account for are held in an
+// opening lot with no transaction.
+type HoldingLot struct {
+	ID                uuid.UUID  `json:"id"`
+	PortfolioID       uuid.UUID  `json:"portfolio_id"`
+	AssetID           uuid.UUID  `json:"asset_id"`
+	TransactionID     *uuid.UUID `json:"transaction_id,omitempty"`
+	AcquiredAt        time.Time  `json:"acquired_at"`
+	Quantity          float64    `json:"quantity"`
+	RemainingQuantity float64    `json:"remaining_quantity"`
+	UnitCost          float64    `json:"unit_cost"`
+	RealisedGain      float64    `json:"realised_gain"`
+	CreatedAt         time.Time  `json:"created_at"`
+
+	// Computed fields
+	CostBasis    float64  `json:"cost_basis"`
+	CurrentValue *float64 `json:"current_value,omitempty"`
+	GainLoss     *float64 `json:"gain_loss,omitempty"`
+	GainLossPct  *float64 `json:"gain_loss_pct,omitempty"`
+}
+
+// AllocationTarget is the share of a portfolio one asset, or every asset of one type,
+// should make up. A portfolio's targets are all by asset or all by asset type.
+type AllocationTarget struct {
+	ID          uuid.UUID  `json:"id"`
+	PortfolioID uuid.UUID  `json:"portfolio_id"`
+	AssetID     *uuid.UUID `json:"asset_id,omitempty"`
+	AssetType   *string    `json:"asset_type,omitempty"`
+	TargetPct   float64    `json:"target_pct"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// Joined fields
+	Asset *Asset `json:"asset,omitempty"`
+}
+
+// TemplateTarget is one line of a portfolio template's target allocation. Symbol is
+// kept alongside the asset ID for display.
+type TemplateTarget struct {
+	AssetID   *uuid.UUID `json:"asset_id,omitempty"`
+	Symbol    string     `json:"symbol,omitempty"`
+	AssetType *string    `json:"asset_type,omitempty"`
+	TargetPct float64    `json:"target_pct"`
+}
+
+// PortfolioTemplate is a target allocation saved for reuse, e.g. when opening an
+// account with another provider
+type PortfolioTemplate struct {
+	ID          uuid.UUID        `json:"id"`
+	UserID      uuid.UUID        `json:"user_id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Targets     []TemplateTarget `json:"targets"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// Rebalance actions
+const (
+	RebalanceBuy  = "BUY"
+	RebalanceSell = "SELL"
+	RebalanceHold = "HOLD"
+)
+
+// RebalanceLine compares one asset or asset type's current weight with its target.
+// Amounts are in the user's base currency; a positive TradeAmount is a buy.
+type RebalanceLine struct {
+	AssetID      *uuid.UUID `json:"asset_id,omitempty"`
+	AssetType    *string    `json:"asset_type,omitempty"`
+	Name         string     `json:"name"`
+	CurrentValue float64    `json:"current_value"`
+	CurrentPct   float64    `json:"current_pct"`
+	TargetPct    float64    `json:"target_pct"`
+	DriftPct     float64    `json:"drift_pct"`
+	TargetValue  float64    `json:"target_value"`
+	TradeAmount  float64    `json:"trade_amount"`
+	Action       string     `json:"action"`
+	// TradeUnits is the approximate number of units to trade, for asset targets with a price
+	TradeUnits *float64 `json:"trade_units,omitempty"`
+}
+
+// RebalanceReport is a portfolio's drift from its target allocation
+type RebalanceReport struct {
+	PortfolioID uuid.UUID       `json:"portfolio_id"`
+	Currency    string          `json:"currency"`
+	TargetBy    string          `json:"target_by"` // ASSET, ASSET_TYPE or empty when no targets are set
+	TotalValue  float64         `json:"total_value"`
+	MaxDriftPct float64         `json:"max_drift_pct"`
+	TotalBuy    float64         `json:"total_buy"`
+	TotalSell   float64         `json:"total_sell"`
+	Lines       []RebalanceLine `json:"lines"`
+	RateMissing bool            `json:"rate_missing,omitempty"`
 }
 
 // HoldingWithPortfolio includes portfolio details for aggregated views
@@ -186,6 +363,7 @@ type HoldingWithPortfolio struct {
 	Quantity    float64    `json:"quantity"`
 	AverageCost float64    `json:"average_cost"`
 	PurchasedAt *time.Time `json:"purchased_at,omitempty"`
+	Notes       string     `json:"notes,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 
@@ -196,6 +374,7 @@ type HoldingWithPortfolio struct {
 	GainLossPct   *float64 `json:"gain_loss_pct,omitempty"`
 	PortfolioName string   `json:"portfolio_name"`
 	PortfolioType string   `json:"portfolio_type"`
+	Tags          []string `json:"tags,omitempty"`
 }
 
 // Transaction types
@@ -209,6 +388,7 @@ const (
 	TransactionTypeTransferOut = "TRANSFER_OUT"
 	TransactionTypeDeposit     = "DEPOSIT"
 	TransactionTypeWithdrawal  = "WITHDRAWAL"
+	TransactionTypeTaxRelief   = "TAX_RELIEF" // government top-up on a relief at source pension contribution
 )
 
 // Transaction represents a buy, sell, or other transaction
@@ -225,8 +405,79 @@ type Transaction struct {
 	Notes           string     `json:"notes,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
 
+	// Dividend breakdown; TotalAmount is the net amount received. The country is where
+	// the tax was withheld (ISO 3166-1 alpha-2).
+	GrossAmount           *float64 `json:"gross_amount,omitempty"`
+	WithholdingTax        *float64 `json:"withholding_tax,omitempty"`
+	WithholdingTaxCountry string   `json:"withholding_tax_country,omitempty"`
+
+	// Set when the currency differs from the portfolio's: the exchange rate on the
+	// transaction date and TotalAmount converted at it into the portfolio's currency
+	FXRate          *float64 `json:"fx_rate,omitempty"`
+	PortfolioAmount *float64 `json:"portfolio_amount,omitempty"`
+
+	// Set on a TAX_RELIEF top-up: the SIPP deposit it was claimed on
+	ReliefForID *uuid.UUID `json:"relief_for_id,omitempty"`
+
 	// Joined fields
-	Asset *Asset `json:"asset,omitempty"`
+	Asset *Asset   `json:"asset,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// PortfolioTotal is the total amount in the portfolio's currency
+func (t *Transaction) PortfolioTotal() float64 {
+	if t.PortfolioAmount != nil {
+		return *t.PortfolioAmount
+	}
+	return t.TotalAmount
+}
+
+// PortfolioUnitPrice is the price per unit in the portfolio's currency, falling back to
+// the portfolio total over the quantity when there's no price
+func (t *Transaction) PortfolioUnitPrice() float64 {
+	if t.Price != nil && *t.Price > 0 {
+		if t.FXRate != nil && *t.FXRate > 0 {
+			return *t.Price * *t.FXRate
+		}
+		return *t.Price
+	}
+	if t.Quantity != nil && *t.Quantity > 0 {
+		return t.PortfolioTotal() / *t.Quantity
+	}
+	return 0
+}
+
+// TransactionVoucher is a dividend voucher (PDF) attached to a DIVIDEND transaction
+type TransactionVoucher struct {
+	ID            uuid.UUID `json:"id"`
+	TransactionID uuid.UUID `json:"transaction_id"`
+	FileName      string    `json:"file_name"`
+	ContentType   string    `json:"content_type"`
+	SizeBytes     int       `json:"size_bytes"`
+	Data          []byte    `json:"-"`
+	ExtractedText string    `json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Asset note sentiments
+const (
+	NoteSentimentBullish = "BULLISH"
+	NoteSentimentNeutral = "NEUTRAL"
+	NoteSentimentBearish = "BEARISH"
+)
+
+// AssetNote is a dated research journal entry on an asset
+type AssetNote struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Symbol    string    `json:"symbol"`
+	EntryDate time.Time `json:"entry_date"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Links     []string  `json:"links"`
+	Sentiment string    `json:"sentiment"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // Cash account types
@@ -250,6 +501,188 @@ type CashAccount struct {
 	CreatedAt    time.Time  `json:"created_at"`
 }
 
+// Cash movement types. ADJUSTMENT records the balance being set directly, including
+// the opening balance.
+const (
+	CashMovementDeposit     = "DEPOSIT"
+	CashMovementWithdrawal  = "WITHDRAWAL"
+	CashMovementInterest    = "INTEREST"
+	CashMovementTransferIn  = "TRANSFER_IN"
+	CashMovementTransferOut = "TRANSFER_OUT"
+	CashMovementAdjustment  = "ADJUSTMENT"
+)
+
+// CashMovement is one change to a cash account's balance. Amount is signed: money in
+// is positive and money out negative.
+type CashMovement struct {
+	ID            uuid.UUID  `json:"id"`
+	CashAccountID uuid.UUID  `json:"cash_account_id"`
+	MovementType  string     `json:"movement_type"`
+	Amount        float64    `json:"amount"`
+	BalanceAfter  float64    `json:"balance_after"`
+	MovementDate  time.Time  `json:"movement_date"`
+	Description   string     `json:"description,omitempty"`
+	TransferID    *uuid.UUID `json:"transfer_id,omitempty"`
+	// ExternalID is the bank's transaction ID on movements synced over Open Banking
+	ExternalID string    `json:"external_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Open Banking providers
+const (
+	BankProviderGoCardless = "GOCARDLESS"
+)
+
+// Bank connection statuses. A PENDING connection is waiting for the user to give consent
+// at their bank; EXPIRED ones need reconnecting.
+const (
+	BankConnectionPending = "PENDING"
+	BankConnectionLinked  = "LINKED"
+	BankConnectionExpired = "EXPIRED"
+	BankConnectionError   = "ERROR"
+)
+
+// BankConnection is the user's consent for an Open Banking provider to read their
+// accounts at one bank. Each account syncs into a cash account in PortfolioID.
+type BankConnection struct {
+	ID              uuid.UUID   `json:"id"`
+	UserID          uuid.UUID   `json:"user_id"`
+	PortfolioID     uuid.UUID   `json:"portfolio_id"`
+	Provider        string      `json:"provider"`
+	InstitutionID   string      `json:"institution_id"`
+	InstitutionName string      `json:"institution_name,omitempty"`
+	RequisitionID   string      `json:"-"`
+	Status          string      `json:"status"`
+	ExpiresAt       *time.Time  `json:"expires_at,omitempty"`
+	LastSyncedAt    *time.Time  `json:"last_synced_at,omitempty"`
+	LastError       string      `json:"last_error,omitempty"`
+	CashAccountIDs  []uuid.UUID `json:"cash_account_ids"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+// LinkedBankAccount is a bank account synced into a cash account, with the outcome of
+// its last sync
+type LinkedBankAccount struct {
+	ExternalAccountID string     `json:"-"`
+	CashAccountID     uuid.UUID  `json:"cash_account_id"`
+	LastSyncedAt      *time.Time `json:"last_synced_at,omitempty"`
+	LastError         string     `json:"last_error,omitempty"`
+}
+
+// Announcement severities, in increasing order of urgency
+const (
+	AnnouncementInfo     = "INFO"
+	AnnouncementWarning  = "WARNING"
+	AnnouncementCritical = "CRITICAL"
+)
+
+// Announcement is a banner set by an admin, e.g. a maintenance window or a new feature,
+// shown to every user between StartsAt and EndsAt until they dismiss it
+type Announcement struct {
+	ID          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	Message     string     `json:"message"`
+	Severity    string     `json:"severity"`
+	StartsAt    time.Time  `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+	Dismissible bool       `json:"dismissible"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// CashInterestTotal sums the INTEREST movements on a cash account
+type CashInterestTotal struct {
+	CashAccountID uuid.UUID `json:"cash_account_id"`
+	Count         int       `json:"count"`
+	Total         float64   `json:"total"`
+	LastPaid      time.Time `json:"last_paid"`
+}
+
+// MonthlyCashMovementTotal is the sum of one movement type in one currency within a
+// calendar month
+type MonthlyCashMovementTotal struct {
+	Month        time.Time `json:"month"`
+	Currency     string    `json:"currency"`
+	MovementType string    `json:"movement_type"`
+	Total        float64   `json:"total"`
+}
+
+// Tag domains: the kinds of item a tag can be put on
+const (
+	TagDomainTransaction = "TRANSACTION"
+	TagDomainHolding     = "HOLDING"
+)
+
+// Tag is a user's label that can be put on items in any domain. ItemCount is the
+// number of items carrying it, within one domain when the list is filtered.
+type Tag struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	ItemCount int       `json:"item_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TaggedItem is an item carrying a tag, with enough of the item to list it
+type TaggedItem struct {
+	Domain      string     `json:"domain"`
+	ItemID      uuid.UUID  `json:"item_id"`
+	Title       string     `json:"title"`
+	Subtitle    string     `json:"subtitle,omitempty"`
+	Date        time.Time  `json:"date"`
+	PortfolioID *uuid.UUID `json:"portfolio_id,omitempty"`
+	TaggedAt    time.Time  `json:"tagged_at"`
+}
+
+// Liability types
+const (
+	LiabilityTypeMortgage   = "MORTGAGE"
+	LiabilityTypeLoan       = "LOAN"
+	LiabilityTypeCreditCard = "CREDIT_CARD"
+)
+
+// Liability is money the user owes, subtracted from their net worth. InterestRate is an
+// annual percentage. MonthlyPayment is paid on PaymentDay each month; for a mortgage or
+// loan without one it is worked out from EndDate.
+type Liability struct {
+	ID             uuid.UUID  `json:"id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	Name           string     `json:"name"`
+	LiabilityType  string     `json:"liability_type"`
+	Lender         string     `json:"lender,omitempty"`
+	Balance        float64    `json:"balance"`
+	Currency       string     `json:"currency"`
+	InterestRate   float64    `json:"interest_rate"`
+	MonthlyPayment *float64   `json:"monthly_payment,omitempty"`
+	PaymentDay     int        `json:"payment_day"`
+	EndDate        *time.Time `json:"end_date,omitempty"`
+	Notes          string     `json:"notes,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// RepaymentScheduleRow is one monthly payment on a liability
+type RepaymentScheduleRow struct {
+	Date      string  `json:"date"`
+	Payment   float64 `json:"payment"`
+	Interest  float64 `json:"interest"`
+	Principal float64 `json:"principal"`
+	Balance   float64 `json:"balance"`
+}
+
+// RepaymentSchedule projects a liability's payments until it is paid off. PayoffDate is
+// empty when the payment doesn't cover the interest or the schedule was cut short.
+type RepaymentSchedule struct {
+	LiabilityID    uuid.UUID              `json:"liability_id"`
+	Currency       string                 `json:"currency"`
+	MonthlyPayment float64                `json:"monthly_payment"`
+	TotalInterest  float64                `json:"total_interest"`
+	TotalPaid      float64                `json:"total_paid"`
+	PayoffDate     string                 `json:"payoff_date,omitempty"`
+	Rows           []RepaymentScheduleRow `json:"rows"`
+}
+
 // Fixed asset categories
 const (
 	FixedAssetCategoryProperty    = "PROPERTY"
@@ -302,29 +735,138 @@ type ExchangeRate struct {
 	CreatedAt    time.Time `json:"created_at"`
 }
 
+// HoldingChange is a holding whose price moved beyond the catch-up threshold. Prices and
+// ValueChange are in the asset's currency.
+type HoldingChange struct {
+	HoldingID     uuid.UUID `json:"holding_id"`
+	PortfolioID   uuid.UUID `json:"portfolio_id"`
+	PortfolioName string    `json:"portfolio_name"`
+	Symbol        string    `json:"symbol"`
+	Name          string    `json:"name"`
+	Currency      string    `json:"currency"`
+	PriceThen     float64   `json:"price_then"`
+	PriceNow      float64   `json:"price_now"`
+	ChangePct     float64   `json:"change_pct"`
+	ValueChange   float64   `json:"value_change"`
+}
+
+// NetWorthChange compares net worth now with the latest snapshot at or before a time
+type NetWorthChange struct {
+	Then      float64 `json:"then"`
+	Now       float64 `json:"now"`
+	Change    float64 `json:"change"`
+	ChangePct float64 `json:"change_pct"`
+	Currency  string  `json:"currency"`
+}
+
+// DashboardChanges summarises what changed since the user last looked at the dashboard
+type DashboardChanges struct {
+	Since             time.Time       `json:"since"`
+	FirstView         bool            `json:"first_view"`
+	ThresholdPct      float64         `json:"threshold_pct"`
+	NetWorth          *NetWorthChange `json:"net_worth,omitempty"`
+	HoldingsUp        []HoldingChange `json:"holdings_up"`
+	HoldingsDown      []HoldingChange `json:"holdings_down"`
+	RemindersDue      []*Reminder     `json:"reminders_due"`
+	TransactionsAdded int             `json:"transactions_added"`
+}
+
 // Dashboard summary types
 type NetWorthSummary struct {
 	TotalNetWorth    float64            `json:"total_net_worth"`
 	Investments      float64            `json:"investments"`
 	Cash             float64            `json:"cash"`
 	FixedAssets      float64            `json:"fixed_assets"`
+	Liabilities      float64            `json:"liabilities"`
 	Currency         string             `json:"currency"`
 	ChangeDay        float64            `json:"change_day"`
 	ChangeWeek       float64            `json:"change_week"`
 	ChangeMonth      float64            `json:"change_month"`
 	ChangeYear       float64            `json:"change_year"`
 	PortfolioSummary []PortfolioSummary `json:"portfolio_summary"`
+	Items            []ValuedItem       `json:"items"`
 }
 
+// PortfolioSummary totals a portfolio's value. When Currency is set the totals have
+// been converted into it, and Items lists what was converted.
 type PortfolioSummary struct {
-	ID             uuid.UUID `json:"id"`
-	Name           string    `json:"name"`
-	Type           string    `json:"type"`
-	TotalValue     float64   `json:"total_value"`
-	TotalCost      float64   `json:"total_cost"`
-	UnrealisedGain float64   `json:"unrealised_gain"`
-	UnrealisedPct  float64   `json:"unrealised_pct"`
-	HoldingsCount  int       `json:"holdings_count"`
+	ID             uuid.UUID    `json:"id"`
+	Name           string       `json:"name"`
+	Type           string       `json:"type"`
+	Currency       string       `json:"currency,omitempty"`
+	TotalValue     float64      `json:"total_value"`
+	TotalCost      float64      `json:"total_cost"`
+	UnrealisedGain float64      `json:"unrealised_gain"`
+	UnrealisedPct  float64      `json:"unrealised_pct"`
+	HoldingsCount  int          `json:"holdings_count"`
+	Items          []ValuedItem `json:"items,omitempty"`
+	// Allowance is set for ISA, LISA and JISA portfolios
+	Allowance *AllowanceStatus `json:"allowance,omitempty"`
+	// Pension is set for SIPP portfolios
+	Pension *PensionAllowanceStatus `json:"pension,omitempty"`
+}
+
+// PensionAllowanceStatus is how much of the pension annual allowance has been used in a
+// tax year, across all SIPPs. Deposits are the member's net contributions; with relief
+// at source the gross contribution also includes the TAX_RELIEF top-ups.
+type PensionAllowanceStatus struct {
+	TaxYear          string  `json:"tax_year"`
+	Allowance        float64 `json:"allowance"`
+	NetContributed   float64 `json:"net_contributed"`
+	TaxRelief        float64 `json:"tax_relief"`
+	GrossContributed float64 `json:"gross_contributed"`
+	// PortfolioNetContributed and PortfolioGrossContributed count only this portfolio
+	PortfolioNetContributed   float64 `json:"portfolio_net_contributed"`
+	PortfolioGrossContributed float64 `json:"portfolio_gross_contributed"`
+	Remaining                 float64 `json:"remaining"`
+	ExceededBy                float64 `json:"exceeded_by,omitempty"`
+}
+
+// AllowanceStatus is how much of a tax-year subscription allowance has been used.
+// Contributed counts every portfolio sharing the allowance: all ISAs and LISAs for the
+// ISA allowance, all LISAs for the LISA allowance and a child's JISAs for theirs.
+// Remaining for a LISA is also capped by what is left of the overall ISA allowance.
+type AllowanceStatus struct {
+	TaxYear              string  `json:"tax_year"`
+	Wrapper              string  `json:"wrapper"`
+	Allowance            float64 `json:"allowance"`
+	Contributed          float64 `json:"contributed"`
+	PortfolioContributed float64 `json:"portfolio_contributed"`
+	Remaining            float64 `json:"remaining"`
+	// ExceededBy is how far contributions, including a proposed one, go over the allowance
+	ExceededBy float64 `json:"exceeded_by,omitempty"`
+}
+
+// Kinds of valued item
+const (
+	ValuedItemHolding     = "HOLDING"
+	ValuedItemCash        = "CASH"         // balance of a CASH or SAVINGS portfolio
+	ValuedItemCashAccount = "CASH_ACCOUNT" // cash account within an investment portfolio
+	ValuedItemFixedAsset  = "FIXED_ASSET"
+	ValuedItemLiability   = "LIABILITY" // value is the amount owed
+)
+
+// ConvertedAmount is an amount in its own currency and in the user's base currency
+type ConvertedAmount struct {
+	Currency     string  `json:"currency"`
+	Amount       float64 `json:"amount"`
+	BaseCurrency string  `json:"base_currency"`
+	BaseAmount   float64 `json:"base_amount"`
+	Rate         float64 `json:"rate"`
+	// RateMissing is set when no exchange rate was available; BaseAmount is then the
+	// unconverted amount
+	RateMissing bool `json:"rate_missing,omitempty"`
+}
+
+// ValuedItem is one holding, cash balance or fixed asset counted towards net worth
+type ValuedItem struct {
+	Kind        string           `json:"kind"`
+	ID          uuid.UUID        `json:"id"`
+	PortfolioID *uuid.UUID       `json:"portfolio_id,omitempty"`
+	Name        string           `json:"name"`
+	Category    string           `json:"category,omitempty"` // asset type or fixed asset category
+	Value       ConvertedAmount  `json:"value"`
+	Cost        *ConvertedAmount `json:"cost,omitempty"`
 }
 
 type AllocationItem struct {
@@ -337,4 +879,842 @@ type AssetAllocation struct {
 	ByType     []AllocationItem `json:"by_type"`
 	ByCurrency []AllocationItem `json:"by_currency"`
 	ByPortfolio []AllocationItem `json:"by_portfolio"`
+	// ByTag splits holdings by their tags, as a share of all holdings; a holding with
+	// several tags counts towards each
+	ByTag []AllocationItem `json:"by_tag"`
+}
+
+// NetWorthSnapshot records a user's valuation on a given day
+type NetWorthSnapshot struct {
+	ID              uuid.UUID             `json:"id"`
+	UserID          uuid.UUID             `json:"user_id"`
+	SnapshotDate    time.Time             `json:"snapshot_date"`
+	TotalNetWorth   float64               `json:"total_net_worth"`
+	Investments     float64               `json:"investments"`
+	Cash            float64               `json:"cash"`
+	FixedAssets     float64               `json:"fixed_assets"`
+	Liabilities     float64               `json:"liabilities"`
+	Currency        string                `json:"currency"`
+	PortfolioValues map[uuid.UUID]float64 `json:"portfolio_values"`
+	CreatedAt       time.Time             `json:"created_at"`
+	UpdatedAt       time.Time             `json:"updated_at"`
+}
+
+// BaseCurrencyChange records a switch of the user's base currency. FXRate is the rate
+// at the time of the change; snapshots are revalued at the rate on their own date.
+type BaseCurrencyChange struct {
+	ID                uuid.UUID `json:"id"`
+	UserID            uuid.UUID `json:"user_id"`
+	FromCurrency      string    `json:"from_currency"`
+	ToCurrency        string    `json:"to_currency"`
+	FXRate            float64   `json:"fx_rate"`
+	SnapshotsRevalued int       `json:"snapshots_revalued"`
+	ChangedAt         time.Time `json:"changed_at"`
+}
+
+// MonthlyTransactionTotal is the sum of one transaction type within a calendar month
+type MonthlyTransactionTotal struct {
+	Month           time.Time `json:"month"`
+	TransactionType string    `json:"transaction_type"`
+	Currency        string    `json:"currency"`
+	Total           float64   `json:"total"`
+}
+
+// SavingsGoal is a target amount funded by shares of cash accounts and portfolios
+type SavingsGoal struct {
+	ID           uuid.UUID               `json:"id"`
+	UserID       uuid.UUID               `json:"user_id"`
+	Name         string                  `json:"name"`
+	TargetAmount float64                 `json:"target_amount"`
+	Currency     string                  `json:"currency"`
+	TargetDate   *time.Time              `json:"target_date,omitempty"`
+	Priority     int                     `json:"priority"` // 1 is highest
+	Notes        string                  `json:"notes,omitempty"`
+	Allocations  []SavingsGoalAllocation `json:"allocations"`
+	CreatedAt    time.Time               `json:"created_at"`
+	UpdatedAt    time.Time               `json:"updated_at"`
+
+	// Computed fields
+	Progress *SavingsGoalProgress `json:"progress,omitempty"`
+}
+
+// SavingsGoalAllocation assigns a percentage of a cash account or portfolio to a goal
+type SavingsGoalAllocation struct {
+	ID            uuid.UUID  `json:"id"`
+	GoalID        uuid.UUID  `json:"goal_id"`
+	CashAccountID *uuid.UUID `json:"cash_account_id,omitempty"`
+	PortfolioID   *uuid.UUID `json:"portfolio_id,omitempty"`
+	Percentage    float64    `json:"percentage"`
+
+	// Computed fields
+	Name  string  `json:"name,omitempty"`
+	Value float64 `json:"value"`
+}
+
+// SavingsGoalProgress is computed from the current value of a goal's allocations
+type SavingsGoalProgress struct {
+	CurrentAmount       float64    `json:"current_amount"`
+	RemainingAmount     float64    `json:"remaining_amount"`
+	PercentComplete     float64    `json:"percent_complete"`
+	MonthlyContribution float64    `json:"monthly_contribution"`
+	ProjectedCompletion *time.Time `json:"projected_completion,omitempty"`
+	RequiredMonthly     *float64   `json:"required_monthly,omitempty"`
+	OnTrack             *bool      `json:"on_track,omitempty"`
+	Completed           bool       `json:"completed"`
+}
+
+// ForeignDividendTotal sums dividends taxed abroad for one country, currency and tax
+// wrapper. Sheltered is true for ISA, LISA, JISA and SIPP portfolios.
+type ForeignDividendTotal struct {
+	Country        string  `json:"country"`
+	Currency       string  `json:"currency"`
+	Sheltered      bool    `json:"sheltered"`
+	Count          int     `json:"count"`
+	GrossAmount    float64 `json:"gross_amount"`
+	WithholdingTax float64 `json:"withholding_tax"`
+	NetAmount      float64 `json:"net_amount"`
+}
+
+// PortfolioInterestTotal sums a portfolio's INTEREST transactions in one currency
+type PortfolioInterestTotal struct {
+	PortfolioID uuid.UUID `json:"portfolio_id"`
+	Currency    string    `json:"currency"`
+	Count       int       `json:"count"`
+	Total       float64   `json:"total"`
+	LastPaid    time.Time `json:"last_paid"`
+}
+
+// MonthlyIncomeTotal sums one portfolio's DIVIDEND or INTEREST transactions in one
+// currency for one calendar month. Gross is before tax withheld at source.
+type MonthlyIncomeTotal struct {
+	Month           time.Time `json:"month"`
+	PortfolioID     uuid.UUID `json:"portfolio_id"`
+	TransactionType string    `json:"transaction_type"`
+	Currency        string    `json:"currency"`
+	Net             float64   `json:"net"`
+	Gross           float64   `json:"gross"`
+}
+
+// MonthlyContribution is the amount paid into a portfolio during one calendar month
+type MonthlyContribution struct {
+	PortfolioID uuid.UUID `json:"portfolio_id"`
+	Month       time.Time `json:"month"`
+	Total       float64   `json:"total"`
+}
+
+// FireAssumptions are the inputs to a FIRE projection. Returns are annual and real
+// (after inflation), so the target is in today's money.
+type FireAssumptions struct {
+	Target              float64 `json:"target"`
+	StartingValue       float64 `json:"starting_value"`
+	MonthlyContribution float64 `json:"monthly_contribution"`
+	ExpectedReturn      float64 `json:"expected_return"` // % per year
+	Volatility          float64 `json:"volatility"`      // standard deviation of annual returns, %
+	Years               int     `json:"years"`
+	Simulations         int     `json:"simulations"`
+}
+
+// FireDate is when the target is reached in a given percentile of simulations; Date is
+// nil if it isn't reached within the projection
+type FireDate struct {
+	Percentile int        `json:"percentile"`
+	Date       *time.Time `json:"date"`
+	Years      *float64   `json:"years"`
+}
+
+// FireBand is the spread of simulated values at the end of one projected year
+type FireBand struct {
+	Year     int       `json:"year"`
+	Date     time.Time `json:"date"`
+	Expected float64   `json:"expected"` // with every year returning the expected return
+	P10      float64   `json:"p10"`
+	P25      float64   `json:"p25"`
+	P50      float64   `json:"p50"`
+	P75      float64   `json:"p75"`
+	P90      float64   `json:"p90"`
+}
+
+// FireProjection is a Monte Carlo projection of when investable net worth reaches the
+// user's FIRE target
+type FireProjection struct {
+	Currency     string          `json:"currency"`
+	Assumptions  FireAssumptions `json:"assumptions"`
+	Progress     float64         `json:"progress"` // starting value as a percentage of the target
+	Reached      bool            `json:"reached"`  // the target has already been reached
+	Probability  float64         `json:"probability"`
+	ExpectedDate *time.Time      `json:"expected_date"`
+	Dates        []FireDate      `json:"dates"`
+	Bands        []FireBand      `json:"bands"`
+}
+
+// TaxYearContribution is the amount paid into a portfolio during a UK tax year (6 April - 5 April)
+type TaxYearContribution struct {
+	PortfolioID uuid.UUID `json:"portfolio_id"`
+	TaxYear     string    `json:"tax_year"` // e.g. "2024/25"
+	Total       float64   `json:"total"`
+}
+
+// PensionContribution is what was paid into a pension portfolio over a period: the
+// member's deposits and the tax relief added to them
+type PensionContribution struct {
+	PortfolioID uuid.UUID `json:"portfolio_id"`
+	Net         float64   `json:"net"`
+	TaxRelief   float64   `json:"tax_relief"`
+}
+
+// PortfolioDashboard is the set of panels shown for a portfolio, chosen by its type and
+// computed server-side. Data holds each panel's own response type.
+type PortfolioDashboard struct {
+	PortfolioID uuid.UUID        `json:"portfolio_id"`
+	Type        string           `json:"type"`
+	Panels      []DashboardPanel `json:"panels"`
+}
+
+type DashboardPanel struct {
+	Name string      `json:"name"`
+	Data interface{} `json:"data"`
+}
+
+// RetirementProjection compounds a pension's value and recent contributions until the
+// target retirement age. The projection fields are left out if the user's date of birth
+// isn't set.
+type RetirementProjection struct {
+	Currency            string  `json:"currency"`
+	CurrentValue        float64 `json:"current_value"`
+	MonthlyContribution float64 `json:"monthly_contribution"` // average over the last twelve complete months
+	GrowthRate          float64 `json:"growth_rate"`          // % per year
+	RetirementAge       int     `json:"retirement_age"`
+	Age                 *int    `json:"age,omitempty"`
+	RetirementDate      string  `json:"retirement_date,omitempty"`
+	YearsRemaining      float64 `json:"years_remaining,omitempty"`
+	ProjectedValue      float64 `json:"projected_value,omitempty"`
+	TaxFreeLumpSum      float64 `json:"tax_free_lump_sum,omitempty"` // 25% of the projected value
+}
+
+// SavingsInterestPanel is the interest on a SAVINGS portfolio in its own currency.
+// Accrued estimates what has built up since the last payment.
+type SavingsInterestPanel struct {
+	Currency       string  `json:"currency"`
+	SavingsType    string  `json:"savings_type,omitempty"`
+	Balance        float64 `json:"balance"`
+	InterestRate   float64 `json:"interest_rate"`
+	AnnualInterest float64 `json:"annual_interest"` // at today's balance and rate
+	Received       float64 `json:"received"`
+	LastPaid       string  `json:"last_paid,omitempty"`
+	Accrued        float64 `json:"accrued"`
+	NoticePeriod   int     `json:"notice_period,omitempty"` // days
+	MaturityDate   string  `json:"maturity_date,omitempty"`
+	DaysToMaturity *int    `json:"days_to_maturity,omitempty"`
+	Matured        bool    `json:"matured"`
+}
+
+// CryptoWalletPanel breaks a CRYPTO portfolio down by coin, in the user's base currency
+type CryptoWalletPanel struct {
+	WalletType string           `json:"wallet_type,omitempty"`
+	WalletName string           `json:"wallet_name,omitempty"`
+	Currency   string           `json:"currency"`
+	TotalValue float64          `json:"total_value"`
+	Coins      []AllocationItem `json:"coins"`
+}
+
+// Onboarding steps
+const (
+	OnboardingStepCreatePortfolio = "create_portfolio"
+	OnboardingStepAddHolding      = "add_holding"
+	OnboardingStepAddCashAccount  = "add_cash_account"
+	OnboardingStepSetBaseCurrency = "set_base_currency"
+	OnboardingStepCreateGoal      = "create_goal"
+)
+
+// OnboardingStep is one item of the guided setup checklist
+type OnboardingStep struct {
+	Key         string     `json:"key"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// OnboardingChecklist is the user's progress through the setup steps
+type OnboardingChecklist struct {
+	Steps          []OnboardingStep `json:"steps"`
+	CompletedCount int              `json:"completed_count"`
+	TotalCount     int              `json:"total_count"`
+	Complete       bool             `json:"complete"`
+}
+
+// Reminder source types
+const (
+	ReminderSourceSavingsGoal = "SAVINGS_GOAL"
+	ReminderSourceFixedAsset  = "FIXED_ASSET"
+	ReminderSourceTransaction = "TRANSACTION"
+)
+
+// Reminder priorities. Only HIGH and URGENT reminders escalate.
+const (
+	ReminderPriorityLow    = "LOW"
+	ReminderPriorityNormal = "NORMAL"
+	ReminderPriorityHigh   = "HIGH"
+	ReminderPriorityUrgent = "URGENT"
+)
+
+// ReminderEscalationChannels are the channels an overdue reminder moves through, one
+// per escalation
+var ReminderEscalationChannels = []string{"PUSH", "EMAIL", "DIGEST"}
+
+// Reminder is created from an "@YYYY-MM-DD do X" token in a notes field and removed
+// when the token is deleted from the notes. EscalationLevel counts the escalations so
+// far and EscalationChannel is the channel of the latest one.
+type Reminder struct {
+	ID                uuid.UUID  `json:"id"`
+	UserID            uuid.UUID  `json:"user_id"`
+	SourceType        string     `json:"source_type"`
+	SourceID          uuid.UUID  `json:"source_id"`
+	DueDate           time.Time  `json:"due_date"`
+	Text              string     `json:"text"`
+	Priority          string     `json:"priority"`
+	EscalateAfterDays *int       `json:"escalate_after_days,omitempty"`
+	EscalationLevel   int        `json:"escalation_level"`
+	EscalationChannel string     `json:"escalation_channel,omitempty"`
+	EscalatedAt       *time.Time `json:"escalated_at,omitempty"`
+	Completed         bool       `json:"completed"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// ReminderEscalationDefault is how many days a user's HIGH and URGENT reminders from one
+// source type may go uncompleted, per escalation, when the reminder doesn't set its own
+type ReminderEscalationDefault struct {
+	SourceType        string    `json:"source_type"`
+	EscalateAfterDays int       `json:"escalate_after_days"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Price alert conditions
+const (
+	PriceAlertAbove     = "ABOVE"
+	PriceAlertBelow     = "BELOW"
+	PriceAlertDailyMove = "DAILY_MOVE"
+)
+
+// PriceAlert watches an asset's price. Threshold is a price in the asset's currency for
+// ABOVE and BELOW, and a percentage for DAILY_MOVE.
+type PriceAlert struct {
+	ID                 uuid.UUID  `json:"id"`
+	UserID             uuid.UUID  `json:"user_id"`
+	AssetID            uuid.UUID  `json:"asset_id"`
+	Condition          string     `json:"condition"`
+	Threshold          float64    `json:"threshold"`
+	Note               string     `json:"note,omitempty"`
+	IsActive           bool       `json:"is_active"`
+	LastTriggeredAt    *time.Time `json:"last_triggered_at,omitempty"`
+	LastTriggeredPrice *float64   `json:"last_triggered_price,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+
+	// Joined fields
+	Asset *Asset `json:"asset,omitempty"`
+}
+
+// PriceAlertTriggered is the payload of a price_alert.triggered webhook
+type PriceAlertTriggered struct {
+	Alert     *PriceAlert `json:"alert"`
+	Symbol    string      `json:"symbol"`
+	Price     float64     `json:"price"`
+	ChangePct float64     `json:"change_pct"`
+	Currency  string      `json:"currency"`
+}
+
+// Health signal keys
+const (
+	HealthSignalEmergencyFund    = "EMERGENCY_FUND"
+	HealthSignalAllowance        = "ALLOWANCE_UTILISATION"
+	HealthSignalFeeDrag          = "FEE_DRAG"
+	HealthSignalUncategorised    = "UNCATEGORISED_TRANSACTIONS"
+	HealthSignalStaleValuations  = "STALE_VALUATIONS"
+	HealthSignalOverdueReminders = "OVERDUE_REMINDERS"
+)
+
+// Health signal statuses
+const (
+	HealthStatusGood    = "GOOD"
+	HealthStatusFair    = "FAIR"
+	HealthStatusPoor    = "POOR"
+	HealthStatusUnknown = "UNKNOWN" // not enough data to score
+)
+
+// HealthSignal is one scored aspect of the user's financial hygiene. Score is 0-100 and
+// nil when the data to work it out is missing; Value is the measure it was scored on.
+type HealthSignal struct {
+	Key         string   `json:"key"`
+	Label       string   `json:"label"`
+	Status      string   `json:"status"`
+	Score       *int     `json:"score"`
+	Weight      int      `json:"weight"`
+	Value       *float64 `json:"value,omitempty"`
+	Explanation string   `json:"explanation"`
+	Action      string   `json:"action,omitempty"`
+	Items       []string `json:"items,omitempty"`
+}
+
+// HealthScore is the weighted average of the scored signals
+type HealthScore struct {
+	Score        int            `json:"score"`
+	Status       string         `json:"status"`
+	Currency     string         `json:"currency"`
+	Signals      []HealthSignal `json:"signals"`
+	CalculatedAt time.Time      `json:"calculated_at"`
+}
+
+// Standing order types
+const (
+	StandingOrderBuy     = "BUY"
+	StandingOrderDeposit = "DEPOSIT"
+)
+
+// StandingOrder invests Amount, in the portfolio's currency, on DayOfMonth each month:
+// a BUY of the asset at that day's market price or a cash DEPOSIT. It stops after
+// EndDate. LastError is why the latest run didn't go through, if it didn't.
+type StandingOrder struct {
+	ID                uuid.UUID  `json:"id"`
+	UserID            uuid.UUID  `json:"user_id"`
+	PortfolioID       uuid.UUID  `json:"portfolio_id"`
+	AssetID           *uuid.UUID `json:"asset_id,omitempty"`
+	OrderType         string     `json:"order_type"`
+	Amount            float64    `json:"amount"`
+	Currency          string     `json:"currency"`
+	DayOfMonth        int        `json:"day_of_month"`
+	NextRunDate       time.Time  `json:"next_run_date"`
+	EndDate           *time.Time `json:"end_date,omitempty"`
+	Note              string     `json:"note,omitempty"`
+	IsActive          bool       `json:"is_active"`
+	LastRunAt         *time.Time `json:"last_run_at,omitempty"`
+	LastTransactionID *uuid.UUID `json:"last_transaction_id,omitempty"`
+	LastError         string     `json:"last_error,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	// Joined fields
+	Asset *Asset `json:"asset,omitempty"`
+}
+
+// StandingOrderRun is an upcoming run of a standing order. For a BUY, Price and Quantity
+// are estimated from the latest price in the order's currency.
+type StandingOrderRun struct {
+	Date     string   `json:"date"`
+	Amount   float64  `json:"amount"`
+	Price    *float64 `json:"price,omitempty"`
+	Quantity *float64 `json:"quantity,omitempty"`
+}
+
+// StandingOrderExecuted is the payload of a standing_order.executed webhook
+type StandingOrderExecuted struct {
+	Order       *StandingOrder `json:"order"`
+	Transaction *Transaction   `json:"transaction"`
+}
+
+// Webhook events
+const (
+	WebhookEventReminderCompleted = "reminder.completed"
+	WebhookEventReminderEscalated = "reminder.escalated"
+	WebhookEventPriceAlert        = "price_alert.triggered"
+	WebhookEventImportCompleted   = "import.completed"
+	WebhookEventStandingOrder     = "standing_order.executed"
+	WebhookEventPing              = "ping"
+)
+
+// Webhook is an external URL that is sent events as signed JSON POSTs. The secret is
+// only returned when the webhook is created.
+type Webhook struct {
+	ID             uuid.UUID  `json:"id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	URL            string     `json:"url"`
+	Secret         string     `json:"secret,omitempty"`
+	Events         []string   `json:"events"`
+	IsActive       bool       `json:"is_active"`
+	LastDeliveryAt *time.Time `json:"last_delivery_at,omitempty"`
+	LastStatus     *int       `json:"last_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// WebhookEvent is the body POSTed to a webhook
+type WebhookEvent struct {
+	ID        uuid.UUID   `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliveryDelivered = "DELIVERED"
+	WebhookDeliveryRetrying  = "RETRYING"
+	WebhookDeliveryDead      = "DEAD"
+)
+
+// WebhookDelivery tracks one event sent to a webhook through its retries
+type WebhookDelivery struct {
+	ID            uuid.UUID       `json:"id"`
+	WebhookID     uuid.UUID       `json:"webhook_id"`
+	UserID        uuid.UUID       `json:"user_id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	DeadlineAt    time.Time       `json:"deadline_at"`
+	LastStatus    *int            `json:"last_status,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// MonthlyEntityCount is the number of records of one kind a user created in a month
+type MonthlyEntityCount struct {
+	Month  string `json:"month"` // YYYY-MM
+	Entity string `json:"entity"`
+	Count  int    `json:"count"`
+}
+
+// UsageCount is the number of requests made to a module or feature
+type UsageCount struct {
+	Name     string `json:"name"`
+	Requests int64  `json:"requests"`
+}
+
+// MonthlyUsage is the number of requests a user made in a month
+type MonthlyUsage struct {
+	Month    string `json:"month"` // YYYY-MM
+	Requests int64  `json:"requests"`
+}
+
+// UsageSummary describes how a user has used the API over recent months
+type UsageSummary struct {
+	From            string               `json:"from"`
+	To              string               `json:"to"`
+	TotalRequests   int64                `json:"total_requests"`
+	RequestsByMonth []MonthlyUsage       `json:"requests_by_month"`
+	Modules         []UsageCount         `json:"modules"`
+	TopFeatures     []UsageCount         `json:"top_features"`
+	EntitiesCreated []MonthlyEntityCount `json:"entities_created"`
+}
+
+// Saved view modules (the list endpoints a view can be applied to)
+const (
+	SavedViewModuleHoldings     = "holdings"
+	SavedViewModuleCashAccounts = "cash_accounts"
+	SavedViewModuleFixedAssets  = "fixed_assets"
+)
+
+// SavedViewFilters narrows a list. Filters that don't apply to the view's module are ignored.
+type SavedViewFilters struct {
+	PortfolioIDs   []uuid.UUID `json:"portfolio_ids,omitempty"`   // holdings, cash accounts
+	PortfolioTypes []string    `json:"portfolio_types,omitempty"` // holdings, cash accounts
+	AssetTypes     []string    `json:"asset_types,omitempty"`     // holdings
+	Symbols        []string    `json:"symbols,omitempty"`         // holdings
+	HasDividends   *bool       `json:"has_dividends,omitempty"`   // holdings with a DIVIDEND transaction
+	AccountTypes   []string    `json:"account_types,omitempty"`   // cash accounts
+	Categories     []string    `json:"categories,omitempty"`      // fixed assets
+	Currencies     []string    `json:"currencies,omitempty"`
+	Search         string      `json:"search,omitempty"` // case-insensitive match on name (and symbol)
+	MinValue       *float64    `json:"min_value,omitempty"`
+	MaxValue       *float64    `json:"max_value,omitempty"`
+}
+
+// SavedViewSort orders a list by one field
+type SavedViewSort struct {
+	Field      string `json:"field"`
+	Descending bool   `json:"descending"`
+}
+
+// SavedView is a named filter and sort for one list endpoint
+type SavedView struct {
+	ID        uuid.UUID        `json:"id"`
+	UserID    uuid.UUID        `json:"user_id"`
+	Module    string           `json:"module"`
+	Name      string           `json:"name"`
+	Filters   SavedViewFilters `json:"filters"`
+	Sort      *SavedViewSort   `json:"sort,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// ImportMapping maps the columns of a broker's CSV export onto transaction fields.
+// Column names are matched case-insensitively; rows whose type is in neither BuyValues
+// nor SellValues are skipped.
+type ImportMapping struct {
+	Date         string   `json:"date"`
+	DateFormat   string   `json:"date_format"` // YYYY-MM-DD, DD/MM/YYYY or MM/DD/YYYY (default YYYY-MM-DD)
+	Type         string   `json:"type"`
+	BuyValues    []string `json:"buy_values"`  // default BUY
+	SellValues   []string `json:"sell_values"` // default SELL
+	Symbol       string   `json:"symbol,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	ISIN         string   `json:"isin,omitempty"`
+	Quantity     string   `json:"quantity"`
+	Price        string   `json:"price"`
+	PriceInPence bool     `json:"price_in_pence,omitempty"`
+	Currency     string   `json:"currency,omitempty"`
+	Notes        string   `json:"notes,omitempty"`
+}
+
+// ImportPreset is a user's saved column mapping for the transaction import
+type ImportPreset struct {
+	ID        uuid.UUID     `json:"id"`
+	UserID    uuid.UUID     `json:"user_id"`
+	Name      string        `json:"name"`
+	Mapping   ImportMapping `json:"mapping"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// Sync change operations
+const (
+	SyncOperationUpsert = "UPSERT"
+	SyncOperationDelete = "DELETE"
+)
+
+// WatchlistItem is a symbol on the user's watchlist
+type WatchlistItem struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"-"`
+	Symbol    string    `json:"symbol"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// API token kinds. Kiosk tokens ignore scopes and can only read the endpoints a
+// wall-mounted display needs.
+const (
+	APITokenKindPersonal = "PERSONAL"
+	APITokenKindKiosk    = "KIOSK"
+)
+
+// APIToken is a personal access token for calling the API from other tools. Token is
+// only set when the token is created; afterwards just its prefix is known.
+type APIToken struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Name        string     `json:"name"`
+	Kind        string     `json:"kind"`
+	DeviceLabel string     `json:"device_label,omitempty"`
+	Token       string     `json:"token,omitempty"`
+	Prefix      string     `json:"prefix"`
+	Scopes      []string   `json:"scopes"`
+	RateLimit   int        `json:"rate_limit"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Expired reports whether the token's expiry has passed
+func (t *APIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// HasScope reports whether the token was granted the scope
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Change history operations
+const (
+	ChangeOperationInsert = "INSERT"
+	ChangeOperationUpdate = "UPDATE"
+	ChangeOperationDelete = "DELETE"
+)
+
+// ChangeHistoryEntry is one recorded change to a portfolio, holding or transaction.
+// Updates hold only the changed fields in Before and After; inserts have just After and
+// deletes just Before.
+type ChangeHistoryEntry struct {
+	ID            int64           `json:"id"`
+	EntityType    string          `json:"entity_type"`
+	EntityID      uuid.UUID       `json:"entity_id"`
+	Operation     string          `json:"operation"`
+	ChangedFields []string        `json:"changed_fields"`
+	Before        json.RawMessage `json:"before,omitempty"`
+	After         json.RawMessage `json:"after,omitempty"`
+	ChangedAt     time.Time       `json:"changed_at"`
+}
+
+// SyncChange is one entry of the change feed. Data holds the record as stored for
+// upserts and is empty for deletions (tombstones).
+type SyncChange struct {
+	Seq       int64           `json:"-"`
+	Domain    string          `json:"domain"`
+	EntityID  uuid.UUID       `json:"entity_id"`
+	Operation string          `json:"operation"`
+	ChangedAt time.Time       `json:"changed_at"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// Task statuses
+const (
+	TaskStatusPending   = "PENDING"
+	TaskStatusRunning   = "RUNNING"
+	TaskStatusSucceeded = "SUCCEEDED"
+	TaskStatusFailed    = "FAILED"
+)
+
+// Task tracks the progress of a long-running operation started by a user
+type Task struct {
+	ID         uuid.UUID       `json:"id"`
+	UserID     uuid.UUID       `json:"-"`
+	Kind       string          `json:"kind"` // e.g. "price_refresh"
+	Status     string          `json:"status"`
+	Step       string          `json:"step,omitempty"` // description of the current step
+	Current    int             `json:"current"`
+	Total      int             `json:"total"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the task has succeeded or failed
+func (t *Task) Finished() bool {
+	return t.Status == TaskStatusSucceeded || t.Status == TaskStatusFailed
+}
+
+// Income types
+const (
+	IncomeTypeSalary  = "SALARY"
+	IncomeTypeRental  = "RENTAL"
+	IncomeTypePension = "PENSION"
+	IncomeTypeOther   = "OTHER"
+)
+
+// Income frequencies
+const (
+	IncomeFrequencyWeekly      = "WEEKLY"
+	IncomeFrequencyFortnightly = "FORTNIGHTLY"
+	IncomeFrequencyFourWeekly  = "FOUR_WEEKLY"
+	IncomeFrequencyMonthly     = "MONTHLY"
+	IncomeFrequencyQuarterly   = "QUARTERLY"
+	IncomeFrequencyAnnually    = "ANNUALLY"
+)
+
+// Income is a recurring payment the user receives, such as a salary or rent. NextPayDate
+// anchors the schedule; payments before and after it follow the frequency, from
+// StartDate (or when the income was added) to EndDate.
+type Income struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Source      string     `json:"source"`
+	IncomeType  string     `json:"income_type"`
+	Amount      float64    `json:"amount"`
+	Currency    string     `json:"currency"`
+	Frequency   string     `json:"frequency"`
+	NextPayDate time.Time  `json:"next_pay_date"`
+	StartDate   *time.Time `json:"start_date,omitempty"`
+	EndDate     *time.Time `json:"end_date,omitempty"`
+	IsActive    bool       `json:"is_active"`
+	Notes       string     `json:"notes,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Computed fields
+	MonthlyAmount   float64    `json:"monthly_amount"`
+	UpcomingPayDate *time.Time `json:"upcoming_pay_date,omitempty"` // next payment from today
+}
+
+// PaymentsPerYear is how many times a year the income is paid
+func (i *Income) PaymentsPerYear() float64 {
+	switch i.Frequency {
+	case IncomeFrequencyWeekly:
+		return 52
+	case IncomeFrequencyFortnightly:
+		return 26
+	case IncomeFrequencyFourWeekly:
+		return 13
+	case IncomeFrequencyQuarterly:
+		return 4
+	case IncomeFrequencyAnnually:
+		return 1
+	default:
+		return 12
+	}
+}
+
+// PayDate returns the nth payment date counting from NextPayDate, which is payment 0.
+// Monthly dates keep the anchor's day, falling back to the last day of shorter months.
+func (i *Income) PayDate(n int) time.Time {
+	anchor := i.NextPayDate
+	switch i.Frequency {
+	case IncomeFrequencyWeekly:
+		return anchor.AddDate(0, 0, 7*n)
+	case IncomeFrequencyFortnightly:
+		return anchor.AddDate(0, 0, 14*n)
+	case IncomeFrequencyFourWeekly:
+		return anchor.AddDate(0, 0, 28*n)
+	}
+
+	months := n
+	switch i.Frequency {
+	case IncomeFrequencyQuarterly:
+		months = 3 * n
+	case IncomeFrequencyAnnually:
+		months = 12 * n
+	}
+	first := time.Date(anchor.Year(), anchor.Month()+time.Month(months), 1, 0, 0, 0, 0, anchor.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(anchor.Day(), lastDay)-1)
+}
+
+// PayDates returns the payment dates from from to to inclusive, starting no earlier than
+// StartDate, or the day the income was added if it has none, and stopping at EndDate
+func (i *Income) PayDates(from, to time.Time) []time.Time {
+	if i.EndDate != nil && i.EndDate.Before(to) {
+		to = *i.EndDate
+	}
+	start := time.Date(i.CreatedAt.Year(), i.CreatedAt.Month(), i.CreatedAt.Day(), 0, 0, 0, 0, from.Location())
+	if i.StartDate != nil {
+		start = *i.StartDate
+	}
+	if start.After(from) {
+		from = start
+	}
+	if from.After(to) {
+		return nil
+	}
+
+	n := 0
+	for !i.PayDate(n).Before(from) {
+		n--
+	}
+	var dates []time.Time
+	for d := i.PayDate(n); !d.After(to); d = i.PayDate(n) {
+		if !d.Before(from) {
+			dates = append(dates, d)
+		}
+		n++
+	}
+	return dates
+}
+
+// NextPayment returns the first payment on or after the date, or nil once the income
+// has ended
+func (i *Income) NextPayment(after time.Time) *time.Time {
+	n := 0
+	for i.PayDate(n).Before(after) {
+		n++
+	}
+	for !i.PayDate(n - 1).Before(after) {
+		n--
+	}
+	next := i.PayDate(n)
+	if i.EndDate != nil && next.After(*i.EndDate) {
+		return nil
+	}
+	return &next
 }
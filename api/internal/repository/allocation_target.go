@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+type AllocationTargetRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAllocationTargetRepository(pool *pgxpool.Pool) *AllocationTargetRepository {
+	return &AllocationTargetRepository{pool: pool}
+}
+
+// GetByPortfolioID returns the portfolio's targets, largest first, with the asset joined
+// for asset targets
+func (r *AllocationTargetRepository) GetByPortfolioID(ctx context.Context, portfolioID uuid.UUID) ([]*models.AllocationTarget, error) {
+	query := `
+		SELECT t.id, t.portfolio_id, t.asset_id, t.asset_type, t.target_pct, t.created_at,
+			   a.symbol, a.name, a.asset_type, a.currency, a.last_price
+		FROM allocation_targets t
+		LEFT JOIN assets a ON a.id = t.asset_id
+		WHERE t.portfolio_id = $1
+		ORDER BY t.target_pct DESC, a.symbol, t.asset_type
+	`
+
+	rows, err := r.pool.Query(ctx, query, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []*models.AllocationTarget
+	for rows.Next() {
+		var target models.AllocationTarget
+		var symbol, name, assetType, currency *string
+		var lastPrice *float64
+
+		err := rows.Scan(
+			&target.ID,
+			&target.PortfolioID,
+			&target.AssetID,
+			&target.AssetType,
+			&target.TargetPct,
+			&target.CreatedAt,
+			&symbol,
+			&name,
+			&assetType,
+			&currency,
+			&lastPrice,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if target.AssetID != nil && symbol != nil {
+			target.Asset = &models.Asset{
+				ID:        *target.AssetID,
+				Symbol:    *symbol,
+				Name:      *name,
+				AssetType: *assetType,
+				Currency:  *currency,
+				LastPrice: lastPrice,
+			}
+		}
+		targets = append(targets, &target)
+	}
+
+	return targets, rows.Err()
+}
+
+// Replace swaps the portfolio's targets for the given ones
+func (r *AllocationTargetRepository) Replace(ctx context.Context, portfolioID uuid.UUID, targets []*models.AllocationTarget) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM allocation_targets WHERE portfolio_id = $1`, portfolioID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, target := range targets {
+		target.ID = uuid.New()
+		target.PortfolioID = portfolioID
+		target.CreatedAt = now
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO allocation_targets (id, portfolio_id, asset_id, asset_type, target_pct, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`,
+			target.ID,
+			target.PortfolioID,
+			target.AssetID,
+			target.AssetType,
+			target.TargetPct,
+			target.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
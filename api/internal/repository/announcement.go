@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var ErrAnnouncementNotFound = errors.New("announcement not found")
+
+const announcementColumns = `id, title, message, severity, starts_at, ends_at, dismissible, created_by, created_at, updated_at`
+
+type AnnouncementRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAnnouncementRepository(pool *pgxpool.Pool) *AnnouncementRepository {
+	return &AnnouncementRepository{pool: pool}
+}
+
+func (r *AnnouncementRepository) Create(ctx context.Context, a *models.Announcement) error {
+	a.ID = uuid.New()
+	a.CreatedAt = time.Now()
+	a.UpdatedAt = time.Now()
+
+	query := `
+		INSERT INTO announcements (id, title, message, severity, starts_at, ends_at, dismissible, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		a.ID,
+		a.Title,
+		a.Message,
+		a.Severity,
+		a.StartsAt,
+		a.EndsAt,
+		a.Dismissible,
+		a.CreatedBy,
+		a.CreatedAt,
+		a.UpdatedAt,
+	)
+	return err
+}
+
+func (r *AnnouncementRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Announcement, error) {
+	query := `SELECT ` + announcementColumns + ` FROM announcements WHERE id = $1`
+
+	a, err := scanAnnouncement(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAnnouncementNotFound
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// GetAll returns every announcement, past, current and scheduled, latest start first
+func (r *AnnouncementRepository) GetAll(ctx context.Context) ([]*models.Announcement, error) {
+	query := `SELECT ` + announcementColumns + ` FROM announcements ORDER BY starts_at DESC, created_at DESC`
+	return r.query(ctx, query)
+}
+
+// GetActiveForUser returns the announcements showing at the given time that the user
+// hasn't dismissed, most severe first. Announcements that can't be dismissed are always
+// returned while they run.
+func (r *AnnouncementRepository) GetActiveForUser(ctx context.Context, userID uuid.UUID, at time.Time) ([]*models.Announcement, error) {
+	query := `
+		SELECT ` + announcementColumns + `
+		FROM announcements a
+		WHERE a.starts_at <= $2
+		  AND (a.ends_at IS NULL OR a.ends_at > $2)
+		  AND (NOT a.dismissible OR NOT EXISTS (
+		      SELECT 1 FROM announcement_dismissals d WHERE d.announcement_id = a.id AND d.user_id = $1
+		  ))
+		ORDER BY CASE a.severity WHEN 'CRITICAL' THEN 0 WHEN 'WARNING' THEN 1 ELSE 2 END, a.starts_at DESC
+	`
+	return r.query(ctx, query, userID, at)
+}
+
+func (r *AnnouncementRepository) Update(ctx context.Context, a *models.Announcement) error {
+	a.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE announcements
+		SET title = $2, message = $3, severity = $4, starts_at = $5, ends_at = $6, dismissible = $7, updated_at = $8
+		WHERE id = $1
+	`
+
+	result, err := r.pool.Exec(ctx, query,
+		a.ID,
+		a.Title,
+		a.Message,
+		a.Severity,
+		a.StartsAt,
+		a.EndsAt,
+		a.Dismissible,
+		a.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAnnouncementNotFound
+	}
+	return nil
+}
+
+func (r *AnnouncementRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM announcements WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAnnouncementNotFound
+	}
+	return nil
+}
+
+// Dismiss hides the announcement from the user. Dismissing twice is not an error.
+func (r *AnnouncementRepository) Dismiss(ctx context.Context, announcementID, userID uuid.UUID) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO announcement_dismissals (announcement_id, user_id, dismissed_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (announcement_id, user_id) DO NOTHING
+	`, announcementID, userID)
+	return err
+}
+
+func (r *AnnouncementRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.Announcement, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var announcements []*models.Announcement
+	for rows.Next() {
+		a, err := scanAnnouncement(rows)
+		if err != nil {
+			return nil, err
+		}
+		announcements = append(announcements, a)
+	}
+	return announcements, rows.Err()
+}
+
+func scanAnnouncement(row pgx.Row) (*models.Announcement, error) {
+	var a models.Announcement
+	err := row.Scan(
+		&a.ID,
+		&a.Title,
+		&a.Message,
+		&a.Severity,
+		&a.StartsAt,
+		&a.EndsAt,
+		&a.Dismissible,
+		&a.CreatedBy,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
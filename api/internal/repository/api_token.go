@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+// APITokenPrefix starts every API token, telling them apart from session JWTs
+const APITokenPrefix = "wlf_"
+
+// apiTokenPrefixLen is how much of the token is kept in the clear to identify it
+const apiTokenPrefixLen = 12
+
+var ErrAPITokenNotFound = errors.New("api token not found")
+
+type APITokenRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPITokenRepository(pool *pgxpool.Pool) *APITokenRepository {
+	return &APITokenRepository{pool: pool}
+}
+
+const apiTokenColumns = `id, user_id, name, kind, device_label, token_prefix, scopes, rate_limit, expires_at, last_used_at, created_at`
+
+func scanAPIToken(row pgx.Row) (*models.APIToken, error) {
+	var t models.APIToken
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Kind, &t.DeviceLabel, &t.Prefix, &t.Scopes, &t.RateLimit, &t.ExpiresAt, &t.LastUsedAt, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// hashAPIToken returns the stored form of a token
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create generates a new secret for the token and saves it. The secret is set on
+// token.Token and can't be recovered afterwards.
+func (r *APITokenRepository) Create(ctx context.Context, token *models.APIToken) error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	token.ID = uuid.New()
+	token.Token = APITokenPrefix + hex.EncodeToString(b)
+	token.Prefix = token.Token[:apiTokenPrefixLen]
+	token.CreatedAt = time.Now()
+	if token.Kind == "" {
+		token.Kind = models.APITokenKindPersonal
+	}
+
+	query := `
+		INSERT INTO api_tokens (id, user_id, name, kind, device_label, token_prefix, token_hash, scopes, rate_limit, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		token.ID,
+		token.UserID,
+		token.Name,
+		token.Kind,
+		token.DeviceLabel,
+		token.Prefix,
+		hashAPIToken(token.Token),
+		token.Scopes,
+		token.RateLimit,
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+	return err
+}
+
+// GetByToken returns the token matching a secret presented by a client
+func (r *APITokenRepository) GetByToken(ctx context.Context, secret string) (*models.APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + ` FROM api_tokens WHERE token_hash = $1`
+
+	token, err := scanAPIToken(r.pool.QueryRow(ctx, query, hashAPIToken(secret)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPITokenNotFound
+		}
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// GetByUserID returns the user's tokens, newest first
+func (r *APITokenRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + ` FROM api_tokens WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*models.APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// CountByUserID returns how many tokens the user has
+func (r *APITokenRepository) CountByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM api_tokens WHERE user_id = $1`, userID).Scan(&count)
+	return count, err
+}
+
+// Delete revokes one of the user's tokens
+func (r *APITokenRepository) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM api_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// Touch records that the token was used. The time is only updated once a minute so
+// busy tokens don't write on every request.
+func (r *APITokenRepository) Touch(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE api_tokens SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`
+
+	_, err := r.pool.Exec(ctx, query, id)
+	return err
+}
@@ -26,8 +26,8 @@ func NewAssetRepository(pool *pgxpool.Pool) *AssetRepository {
 
 func (r *AssetRepository) Create(ctx context.Context, asset *models.Asset) error {
 	query := `
-		INSERT INTO assets (id, symbol, name, asset_type, exchange, currency, data_source, last_price, last_price_updated_at, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO assets (id, symbol, name, asset_type, exchange, currency, data_source, last_price, last_price_updated_at, isin, sedol, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), NULLIF($11, ''), $12)
 	`
 
 	asset.ID = uuid.New()
@@ -53,6 +53,8 @@ func (r *AssetRepository) Create(ctx context.Context, asset *models.Asset) error
 		asset.DataSource,
 		asset.LastPrice,
 		lastPriceUpdatedAt,
+		asset.ISIN,
+		asset.SEDOL,
 		asset.CreatedAt,
 	)
 
@@ -68,7 +70,7 @@ func (r *AssetRepository) Create(ctx context.Context, asset *models.Asset) error
 
 func (r *AssetRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Asset, error) {
 	query := `
-		SELECT id, symbol, name, asset_type, exchange, currency, data_source, last_price, last_price_updated_at, created_at
+		SELECT id, symbol, name, asset_type, exchange, currency, data_source, last_price, last_price_updated_at, COALESCE(isin, ''), COALESCE(sedol, ''), created_at
 		FROM assets
 		WHERE id = $1
 	`
@@ -84,6 +86,8 @@ func (r *AssetRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.As
 		&asset.DataSource,
 		&asset.LastPrice,
 		&asset.LastPriceUpdatedAt,
+		&asset.ISIN,
+		&asset.SEDOL,
 		&asset.CreatedAt,
 	)
 
@@ -99,7 +103,7 @@ func (r *AssetRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.As
 
 func (r *AssetRepository) GetBySymbol(ctx context.Context, symbol string) (*models.Asset, error) {
 	query := `
-		SELECT id, symbol, name, asset_type, exchange, currency, data_source, last_price, last_price_updated_at, created_at
+		SELECT id, symbol, name, asset_type, exchange, currency, data_source, last_price, last_price_updated_at, COALESCE(isin, ''), COALESCE(sedol, ''), created_at
 		FROM assets
 		WHERE symbol = $1
 	`
@@ -115,6 +119,8 @@ func (r *AssetRepository) GetBySymbol(ctx context.Context, symbol string) (*mode
 		&asset.DataSource,
 		&asset.LastPrice,
 		&asset.LastPriceUpdatedAt,
+		&asset.ISIN,
+		&asset.SEDOL,
 		&asset.CreatedAt,
 	)
 
@@ -191,9 +197,63 @@ func (r *AssetRepository) UpdatePrices(ctx context.Context, prices map[string]fl
 	return tx.Commit(ctx)
 }
 
+// UpdateDataSource sets the provider the asset is priced from
+func (r *AssetRepository) UpdateDataSource(ctx context.Context, symbol, dataSource string) error {
+	result, err := r.pool.Exec(ctx, `UPDATE assets SET data_source = $2 WHERE symbol = $1`, symbol, dataSource)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrAssetNotFound
+	}
+
+	return nil
+}
+
+// GetBySymbols returns the known assets among the symbols, keyed by symbol
+func (r *AssetRepository) GetBySymbols(ctx context.Context, symbols []string) (map[string]*models.Asset, error) {
+	query := `
+		SELECT id, symbol, name, asset_type, exchange, currency, data_source, last_price, last_price_updated_at, COALESCE(isin, ''), COALESCE(sedol, ''), created_at
+		FROM assets
+		WHERE symbol = ANY($1)
+	`
+
+	rows, err := r.pool.Query(ctx, query, symbols)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assets := make(map[string]*models.Asset, len(symbols))
+	for rows.Next() {
+		var asset models.Asset
+		err := rows.Scan(
+			&asset.ID,
+			&asset.Symbol,
+			&asset.Name,
+			&asset.AssetType,
+			&asset.Exchange,
+			&asset.Currency,
+			&asset.DataSource,
+			&asset.LastPrice,
+			&asset.LastPriceUpdatedAt,
+			&asset.ISIN,
+			&asset.SEDOL,
+			&asset.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		assets[asset.Symbol] = &asset
+	}
+
+	return assets, rows.Err()
+}
+
 func (r *AssetRepository) GetAll(ctx context.Context) ([]*models.Asset, error) {
 	query := `
-		SELECT id, symbol, name, asset_type, exchange, currency, data_source, last_price, last_price_updated_at, created_at
+		SELECT id, symbol, name, asset_type, exchange, currency, data_source, last_price, last_price_updated_at, COALESCE(isin, ''), COALESCE(sedol, ''), created_at
 		FROM assets
 		ORDER BY symbol
 	`
@@ -217,6 +277,8 @@ func (r *AssetRepository) GetAll(ctx context.Context) ([]*models.Asset, error) {
 			&a.DataSource,
 			&a.LastPrice,
 			&a.LastPriceUpdatedAt,
+			&a.ISIN,
+			&a.SEDOL,
 			&a.CreatedAt,
 		)
 		if err != nil {
@@ -230,7 +292,7 @@ func (r *AssetRepository) GetAll(ctx context.Context) ([]*models.Asset, error) {
 
 func (r *AssetRepository) GetHeldAssets(ctx context.Context, userID uuid.UUID) ([]*models.Asset, error) {
 	query := `
-		SELECT DISTINCT a.id, a.symbol, a.name, a.asset_type, a.exchange, a.currency, a.data_source, a.last_price, a.last_price_updated_at, a.created_at
+		SELECT DISTINCT a.id, a.symbol, a.name, a.asset_type, a.exchange, a.currency, a.data_source, a.last_price, a.last_price_updated_at, COALESCE(a.isin, ''), COALESCE(a.sedol, ''), a.created_at
 		FROM assets a
 		INNER JOIN holdings h ON h.asset_id = a.id
 		INNER JOIN portfolios p ON p.id = h.portfolio_id
@@ -257,6 +319,8 @@ func (r *AssetRepository) GetHeldAssets(ctx context.Context, userID uuid.UUID) (
 			&a.DataSource,
 			&a.LastPrice,
 			&a.LastPriceUpdatedAt,
+			&a.ISIN,
+			&a.SEDOL,
 			&a.CreatedAt,
 		)
 		if err != nil {
@@ -267,3 +331,109 @@ func (r *AssetRepository) GetHeldAssets(ctx context.Context, userID uuid.UUID) (
 
 	return assets, rows.Err()
 }
+
+// GetAllHeldSymbols returns the symbols held by any user
+func (r *AssetRepository) GetAllHeldSymbols(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT DISTINCT a.symbol
+		FROM assets a
+		INNER JOIN holdings h ON h.asset_id = a.id
+		WHERE h.quantity > 0
+		ORDER BY a.symbol
+	`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var symbols []string
+	for rows.Next() {
+		var symbol string
+		if err := rows.Scan(&symbol); err != nil {
+			return nil, err
+		}
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols, rows.Err()
+}
+
+// GetByIdentifier returns the asset with the ISIN or SEDOL, preferring a fund when one
+// ISIN is listed on several exchanges
+func (r *AssetRepository) GetByIdentifier(ctx context.Context, isin, sedol string) (*models.Asset, error) {
+	query := `
+		SELECT id, symbol, name, asset_type, exchange, currency, data_source, last_price, last_price_updated_at, COALESCE(isin, ''), COALESCE(sedol, ''), created_at
+		FROM assets
+		WHERE isin = NULLIF($1, '') OR sedol = NULLIF($2, '')
+		ORDER BY asset_type = 'FUND' DESC, created_at
+		LIMIT 1
+	`
+
+	var asset models.Asset
+	err := r.pool.QueryRow(ctx, query, isin, sedol).Scan(
+		&asset.ID,
+		&asset.Symbol,
+		&asset.Name,
+		&asset.AssetType,
+		&asset.Exchange,
+		&asset.Currency,
+		&asset.DataSource,
+		&asset.LastPrice,
+		&asset.LastPriceUpdatedAt,
+		&asset.ISIN,
+		&asset.SEDOL,
+		&asset.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAssetNotFound
+		}
+		return nil, err
+	}
+
+	return &asset, nil
+}
+
+// UpdateIdentifiers records the asset's ISIN and SEDOL. Empty values leave the stored
+// ones unchanged.
+func (r *AssetRepository) UpdateIdentifiers(ctx context.Context, id uuid.UUID, isin, sedol string) error {
+	query := `
+		UPDATE assets
+		SET isin = COALESCE(NULLIF($2, ''), isin), sedol = COALESCE(NULLIF($3, ''), sedol)
+		WHERE id = $1
+	`
+
+	result, err := r.pool.Exec(ctx, query, id, isin, sedol)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrAssetNotFound
+	}
+
+	return nil
+}
+
+// GetSymbolsByType returns the symbols of every asset of the type
+func (r *AssetRepository) GetSymbolsByType(ctx context.Context, assetType string) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `SELECT symbol FROM assets WHERE asset_type = $1 ORDER BY symbol`, assetType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var symbols []string
+	for rows.Next() {
+		var symbol string
+		if err := rows.Scan(&symbol); err != nil {
+			return nil, err
+		}
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols, rows.Err()
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var ErrAssetNoteNotFound = errors.New("asset note not found")
+
+type AssetNoteRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAssetNoteRepository(pool *pgxpool.Pool) *AssetNoteRepository {
+	return &AssetNoteRepository{pool: pool}
+}
+
+func (r *AssetNoteRepository) Create(ctx context.Context, note *models.AssetNote) error {
+	note.ID = uuid.New()
+	note.CreatedAt = time.Now()
+	note.UpdatedAt = time.Now()
+
+	query := `
+		INSERT INTO asset_notes (id, user_id, symbol, entry_date, title, body, links, sentiment, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		note.ID,
+		note.UserID,
+		note.Symbol,
+		note.EntryDate,
+		note.Title,
+		note.Body,
+		note.Links,
+		note.Sentiment,
+		note.CreatedAt,
+		note.UpdatedAt,
+	)
+	return err
+}
+
+func (r *AssetNoteRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.AssetNote, error) {
+	query := `
+		SELECT id, user_id, symbol, entry_date, title, body, links, sentiment, created_at, updated_at
+		FROM asset_notes
+		WHERE id = $1
+	`
+
+	note, err := scanAssetNote(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAssetNoteNotFound
+		}
+		return nil, err
+	}
+
+	return note, nil
+}
+
+// GetBySymbol returns the user's journal for an asset, newest entry first
+func (r *AssetNoteRepository) GetBySymbol(ctx context.Context, userID uuid.UUID, symbol string) ([]*models.AssetNote, error) {
+	query := `
+		SELECT id, user_id, symbol, entry_date, title, body, links, sentiment, created_at, updated_at
+		FROM asset_notes
+		WHERE user_id = $1 AND symbol = $2
+		ORDER BY entry_date DESC, created_at DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, symbol)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []*models.AssetNote
+	for rows.Next() {
+		note, err := scanAssetNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
+
+// GetLatest returns the user's most recent note on an asset, or nil if there are none
+func (r *AssetNoteRepository) GetLatest(ctx context.Context, userID uuid.UUID, symbol string) (*models.AssetNote, error) {
+	query := `
+		SELECT id, user_id, symbol, entry_date, title, body, links, sentiment, created_at, updated_at
+		FROM asset_notes
+		WHERE user_id = $1 AND symbol = $2
+		ORDER BY entry_date DESC, created_at DESC
+		LIMIT 1
+	`
+
+	note, err := scanAssetNote(r.pool.QueryRow(ctx, query, userID, symbol))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return note, nil
+}
+
+func (r *AssetNoteRepository) Update(ctx context.Context, note *models.AssetNote) error {
+	note.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE asset_notes
+		SET entry_date = $2, title = $3, body = $4, links = $5, sentiment = $6, updated_at = $7
+		WHERE id = $1
+	`
+
+	result, err := r.pool.Exec(ctx, query,
+		note.ID,
+		note.EntryDate,
+		note.Title,
+		note.Body,
+		note.Links,
+		note.Sentiment,
+		note.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAssetNoteNotFound
+	}
+
+	return nil
+}
+
+func (r *AssetNoteRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM asset_notes WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAssetNoteNotFound
+	}
+	return nil
+}
+
+func scanAssetNote(row pgx.Row) (*models.AssetNote, error) {
+	var note models.AssetNote
+
+	err := row.Scan(
+		&note.ID,
+		&note.UserID,
+		&note.Symbol,
+		&note.EntryDate,
+		&note.Title,
+		&note.Body,
+		&note.Links,
+		&note.Sentiment,
+		&note.CreatedAt,
+		&note.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &note, nil
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var ErrBankConnectionNotFound = errors.New("bank connection not found")
+
+const bankConnectionColumns = `
+	c.id, c.user_id, c.portfolio_id, c.provider, c.institution_id, COALESCE(c.institution_name, ''),
+	c.requisition_id, c.status, c.expires_at, c.last_synced_at, COALESCE(c.last_error, ''),
+	ARRAY(SELECT a.cash_account_id FROM bank_connection_accounts a WHERE a.connection_id = c.id),
+	c.created_at, c.updated_at`
+
+type BankConnectionRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewBankConnectionRepository(pool *pgxpool.Pool) *BankConnectionRepository {
+	return &BankConnectionRepository{pool: pool}
+}
+
+func (r *BankConnectionRepository) Create(ctx context.Context, conn *models.BankConnection) error {
+	if conn.ID == uuid.Nil {
+		conn.ID = uuid.New()
+	}
+	conn.CreatedAt = time.Now()
+	conn.UpdatedAt = time.Now()
+	if conn.CashAccountIDs == nil {
+		conn.CashAccountIDs = []uuid.UUID{}
+	}
+
+	query := `
+		INSERT INTO bank_connections (id, user_id, portfolio_id, provider, institution_id, institution_name, requisition_id, status, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		conn.ID,
+		conn.UserID,
+		conn.PortfolioID,
+		conn.Provider,
+		conn.InstitutionID,
+		conn.InstitutionName,
+		conn.RequisitionID,
+		conn.Status,
+		conn.ExpiresAt,
+		conn.CreatedAt,
+		conn.UpdatedAt,
+	)
+	return err
+}
+
+func (r *BankConnectionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.BankConnection, error) {
+	query := `SELECT ` + bankConnectionColumns + ` FROM bank_connections c WHERE c.id = $1`
+
+	conn, err := scanBankConnection(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrBankConnectionNotFound
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// GetByUserID returns the user's connections, oldest first
+func (r *BankConnectionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.BankConnection, error) {
+	query := `SELECT ` + bankConnectionColumns + ` FROM bank_connections c WHERE c.user_id = $1 ORDER BY c.created_at`
+	return r.query(ctx, query, userID)
+}
+
+// GetDue returns linked connections whose last sync attempt, failed or not, was before
+// the given time. Failures wait as long as successes, as banks limit reads per day.
+func (r *BankConnectionRepository) GetDue(ctx context.Context, attemptedBefore time.Time) ([]*models.BankConnection, error) {
+	query := `
+		SELECT ` + bankConnectionColumns + `
+		FROM bank_connections c
+		WHERE c.status = 'LINKED' AND c.updated_at < $1
+		ORDER BY c.updated_at
+	`
+	return r.query(ctx, query, attemptedBefore)
+}
+
+// UpdateStatus saves the connection's status, expiry and the outcome of its last sync
+func (r *BankConnectionRepository) UpdateStatus(ctx context.Context, conn *models.BankConnection) error {
+	conn.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE bank_connections
+		SET status = $2, expires_at = $3, last_synced_at = $4, last_error = NULLIF($5, ''), updated_at = $6
+		WHERE id = $1
+	`
+
+	result, err := r.pool.Exec(ctx, query,
+		conn.ID,
+		conn.Status,
+		conn.ExpiresAt,
+		conn.LastSyncedAt,
+		conn.LastError,
+		conn.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrBankConnectionNotFound
+	}
+	return nil
+}
+
+func (r *BankConnectionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM bank_connections WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrBankConnectionNotFound
+	}
+	return nil
+}
+
+// LinkAccount records that the cash account is synced from the bank account
+func (r *BankConnectionRepository) LinkAccount(ctx context.Context, connectionID, cashAccountID uuid.UUID, externalAccountID string) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO bank_connection_accounts (cash_account_id, connection_id, external_account_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (connection_id, external_account_id) DO NOTHING
+	`, cashAccountID, connectionID, externalAccountID)
+	return err
+}
+
+// GetLinkedAccounts maps the connection's bank account IDs to the cash accounts they
+// sync into
+func (r *BankConnectionRepository) GetLinkedAccounts(ctx context.Context, connectionID uuid.UUID) (map[string]*models.LinkedBankAccount, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT external_account_id, cash_account_id, last_synced_at, COALESCE(last_error, '')
+		FROM bank_connection_accounts
+		WHERE connection_id = $1
+	`, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	linked := make(map[string]*models.LinkedBankAccount)
+	for rows.Next() {
+		var a models.LinkedBankAccount
+		if err := rows.Scan(&a.ExternalAccountID, &a.CashAccountID, &a.LastSyncedAt, &a.LastError); err != nil {
+			return nil, err
+		}
+		linked[a.ExternalAccountID] = &a
+	}
+	return linked, rows.Err()
+}
+
+// UpdateAccountSync records the outcome of syncing a linked account. A nil syncedAt
+// keeps the time of its last successful sync.
+func (r *BankConnectionRepository) UpdateAccountSync(ctx context.Context, cashAccountID uuid.UUID, syncedAt *time.Time, lastError string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE bank_connection_accounts
+		SET last_synced_at = COALESCE($2, last_synced_at), last_error = NULLIF($3, '')
+		WHERE cash_account_id = $1
+	`, cashAccountID, syncedAt, lastError)
+	return err
+}
+
+func (r *BankConnectionRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.BankConnection, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conns []*models.BankConnection
+	for rows.Next() {
+		conn, err := scanBankConnection(rows)
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, conn)
+	}
+	return conns, rows.Err()
+}
+
+func scanBankConnection(row pgx.Row) (*models.BankConnection, error) {
+	var conn models.BankConnection
+	err := row.Scan(
+		&conn.ID,
+		&conn.UserID,
+		&conn.PortfolioID,
+		&conn.Provider,
+		&conn.InstitutionID,
+		&conn.InstitutionName,
+		&conn.RequisitionID,
+		&conn.Status,
+		&conn.ExpiresAt,
+		&conn.LastSyncedAt,
+		&conn.LastError,
+		&conn.CashAccountIDs,
+		&conn.CreatedAt,
+		&conn.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if conn.CashAccountIDs == nil {
+		conn.CashAccountIDs = []uuid.UUID{}
+	}
+	return &conn, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,6 +24,7 @@ func NewCashAccountRepository(pool *pgxpool.Pool) *CashAccountRepository {
 	return &CashAccountRepository{pool: pool}
 }
 
+// Create adds the account, recording any opening balance in its ledger
 func (r *CashAccountRepository) Create(ctx context.Context, account *models.CashAccount) error {
 	query := `
 		INSERT INTO cash_accounts (id, portfolio_id, account_name, account_type, institution, balance, currency, interest_rate, last_updated, created_at)
@@ -33,7 +35,13 @@ func (r *CashAccountRepository) Create(ctx context.Context, account *models.Cash
 	account.CreatedAt = time.Now()
 	account.LastUpdated = time.Now()
 
-	_, err := r.pool.Exec(ctx, query,
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, query,
 		account.ID,
 		account.PortfolioID,
 		account.AccountName,
@@ -45,8 +53,25 @@ func (r *CashAccountRepository) Create(ctx context.Context, account *models.Cash
 		account.LastUpdated,
 		account.CreatedAt,
 	)
+	if err != nil {
+		return err
+	}
+
+	if account.Balance != 0 {
+		err = insertCashMovement(ctx, tx, &models.CashMovement{
+			CashAccountID: account.ID,
+			MovementType:  models.CashMovementAdjustment,
+			Amount:        account.Balance,
+			BalanceAfter:  account.Balance,
+			MovementDate:  account.CreatedAt,
+			Description:   "Opening balance",
+		})
+		if err != nil {
+			return err
+		}
+	}
 
-	return err
+	return tx.Commit(ctx)
 }
 
 func (r *CashAccountRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.CashAccount, error) {
@@ -157,6 +182,8 @@ func (r *CashAccountRepository) GetByUserID(ctx context.Context, userID uuid.UUI
 	return accounts, rows.Err()
 }
 
+// Update saves the account. A change to the balance is recorded in its ledger as an
+// adjustment.
 func (r *CashAccountRepository) Update(ctx context.Context, account *models.CashAccount) error {
 	query := `
 		UPDATE cash_accounts
@@ -166,7 +193,18 @@ func (r *CashAccountRepository) Update(ctx context.Context, account *models.Cash
 
 	account.LastUpdated = time.Now()
 
-	result, err := r.pool.Exec(ctx, query,
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	previous, _, err := lockCashAccount(ctx, tx, account.ID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(ctx, query,
 		account.ID,
 		account.AccountName,
 		account.AccountType,
@@ -185,7 +223,21 @@ func (r *CashAccountRepository) Update(ctx context.Context, account *models.Cash
 		return ErrCashAccountNotFound
 	}
 
-	return nil
+	if change := math.Round((account.Balance-previous)*100) / 100; change != 0 {
+		err = insertCashMovement(ctx, tx, &models.CashMovement{
+			CashAccountID: account.ID,
+			MovementType:  models.CashMovementAdjustment,
+			Amount:        change,
+			BalanceAfter:  account.Balance,
+			MovementDate:  account.LastUpdated,
+			Description:   "Balance updated",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
 }
 
 func (r *CashAccountRepository) Delete(ctx context.Context, id uuid.UUID) error {
@@ -0,0 +1,360 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var (
+	ErrInsufficientCash     = errors.New("insufficient cash")
+	ErrCashCurrencyMismatch = errors.New("cash accounts are in different currencies")
+)
+
+const cashMovementColumns = `id, cash_account_id, movement_type, amount, balance_after, movement_date, COALESCE(description, ''), transfer_id, COALESCE(external_id, ''), created_at`
+
+// CashMovementRepository keeps the ledger of cash account balance changes. Every
+// movement updates the account balance in the same database transaction.
+type CashMovementRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCashMovementRepository(pool *pgxpool.Pool) *CashMovementRepository {
+	return &CashMovementRepository{pool: pool}
+}
+
+// Record applies a movement to its account's balance. Movements that would take the
+// balance below zero are refused with ErrInsufficientCash.
+func (r *CashMovementRepository) Record(ctx context.Context, m *models.CashMovement) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	balance, _, err := lockCashAccount(ctx, tx, m.CashAccountID)
+	if err != nil {
+		return err
+	}
+	if err := applyCashMovement(ctx, tx, m, balance); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Transfer moves money between two accounts in the same currency, recording a
+// TRANSFER_OUT on one and a TRANSFER_IN on the other with a shared transfer ID
+func (r *CashMovementRepository) Transfer(ctx context.Context, out, in *models.CashMovement) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the accounts in a fixed order so concurrent transfers can't deadlock
+	first, second := out.CashAccountID, in.CashAccountID
+	if second.String() < first.String() {
+		first, second = second, first
+	}
+	balances := make(map[uuid.UUID]float64, 2)
+	currencies := make(map[uuid.UUID]string, 2)
+	for _, id := range []uuid.UUID{first, second} {
+		balance, currency, err := lockCashAccount(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		balances[id], currencies[id] = balance, currency
+	}
+	if currencies[out.CashAccountID] != currencies[in.CashAccountID] {
+		return ErrCashCurrencyMismatch
+	}
+
+	transferID := uuid.New()
+	out.TransferID, in.TransferID = &transferID, &transferID
+	if err := applyCashMovement(ctx, tx, out, balances[out.CashAccountID]); err != nil {
+		return err
+	}
+	if err := applyCashMovement(ctx, tx, in, balances[in.CashAccountID]); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ApplyBankSync records movements synced from the account's bank that aren't already on
+// it, oldest first, then adjusts the balance to the one the bank reports. On the first
+// sync the adjustment is the opening balance, dated before the first movement; later it
+// covers anything the movements don't explain, such as pending payments. Bank balances
+// may be overdrawn. Returns how many movements were new.
+func (r *CashMovementRepository) ApplyBankSync(ctx context.Context, accountID uuid.UUID, movements []*models.CashMovement, bankBalance float64) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	balance, _, err := lockCashAccount(ctx, tx, accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	var synced bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM cash_movements WHERE cash_account_id = $1 AND external_id IS NOT NULL)
+	`, accountID).Scan(&synced)
+	if err != nil {
+		return 0, err
+	}
+
+	externalIDs := make([]string, 0, len(movements))
+	for _, m := range movements {
+		externalIDs = append(externalIDs, m.ExternalID)
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT external_id FROM cash_movements WHERE cash_account_id = $1 AND external_id = ANY($2)
+	`, accountID, externalIDs)
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool, len(movements))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		seen[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var fresh []*models.CashMovement
+	total := balance
+	for _, m := range movements {
+		if seen[m.ExternalID] {
+			continue
+		}
+		seen[m.ExternalID] = true
+		m.CashAccountID = accountID
+		fresh = append(fresh, m)
+		total += m.Amount
+	}
+	sort.SliceStable(fresh, func(i, j int) bool { return fresh[i].MovementDate.Before(fresh[j].MovementDate) })
+
+	if diff := math.Round((bankBalance-total)*100) / 100; diff != 0 {
+		adjustment := &models.CashMovement{
+			CashAccountID: accountID,
+			MovementType:  models.CashMovementAdjustment,
+			Amount:        diff,
+			MovementDate:  time.Now(),
+			Description:   "Balance reconciled with bank",
+		}
+		if !synced {
+			adjustment.Description = "Opening balance from bank"
+			if len(fresh) > 0 {
+				adjustment.MovementDate = fresh[0].MovementDate.AddDate(0, 0, -1)
+			}
+			fresh = append([]*models.CashMovement{adjustment}, fresh...)
+		} else {
+			fresh = append(fresh, adjustment)
+		}
+	}
+
+	imported := 0
+	for _, m := range fresh {
+		balance = math.Round((balance+m.Amount)*100) / 100
+		m.BalanceAfter = balance
+		if err := insertCashMovement(ctx, tx, m); err != nil {
+			return 0, err
+		}
+		if m.ExternalID != "" {
+			imported++
+		}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE cash_accounts SET balance = $2, last_updated = $3 WHERE id = $1`, accountID, balance, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	return imported, tx.Commit(ctx)
+}
+
+// GetByAccountID returns a page of an account's movements, newest first, and the total
+// number of movements
+func (r *CashMovementRepository) GetByAccountID(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]*models.CashMovement, int, error) {
+	var total int
+	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM cash_movements WHERE cash_account_id = $1`, accountID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+cashMovementColumns+`
+		FROM cash_movements
+		WHERE cash_account_id = $1
+		ORDER BY movement_date DESC, created_at DESC
+		LIMIT $2 OFFSET $3
+	`, accountID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var movements []*models.CashMovement
+	for rows.Next() {
+		m, err := scanCashMovement(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		movements = append(movements, m)
+	}
+
+	return movements, total, rows.Err()
+}
+
+// GetMonthlyTotalsByUserID sums the user's movements by month, currency and type from
+// the given date
+func (r *CashMovementRepository) GetMonthlyTotalsByUserID(ctx context.Context, userID uuid.UUID, from time.Time) ([]*models.MonthlyCashMovementTotal, error) {
+	query := `
+		SELECT date_trunc('month', m.movement_date)::date AS month, COALESCE(ca.currency, 'GBP'), m.movement_type, SUM(m.amount)
+		FROM cash_movements m
+		JOIN cash_accounts ca ON ca.id = m.cash_account_id
+		JOIN portfolios p ON p.id = ca.portfolio_id
+		WHERE p.user_id = $1 AND m.movement_date >= $2
+		GROUP BY month, ca.currency, m.movement_type
+		ORDER BY month
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*models.MonthlyCashMovementTotal
+	for rows.Next() {
+		var t models.MonthlyCashMovementTotal
+		if err := rows.Scan(&t.Month, &t.Currency, &t.MovementType, &t.Total); err != nil {
+			return nil, err
+		}
+		totals = append(totals, &t)
+	}
+
+	return totals, rows.Err()
+}
+
+// GetInterestTotals sums the INTEREST movements on each of the user's cash accounts
+func (r *CashMovementRepository) GetInterestTotals(ctx context.Context, userID uuid.UUID) ([]*models.CashInterestTotal, error) {
+	query := `
+		SELECT m.cash_account_id, COUNT(*), SUM(m.amount), MAX(m.movement_date)
+		FROM cash_movements m
+		JOIN cash_accounts ca ON ca.id = m.cash_account_id
+		JOIN portfolios p ON p.id = ca.portfolio_id
+		WHERE p.user_id = $1 AND m.movement_type = 'INTEREST'
+		GROUP BY m.cash_account_id
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*models.CashInterestTotal
+	for rows.Next() {
+		var t models.CashInterestTotal
+		if err := rows.Scan(&t.CashAccountID, &t.Count, &t.Total, &t.LastPaid); err != nil {
+			return nil, err
+		}
+		totals = append(totals, &t)
+	}
+
+	return totals, rows.Err()
+}
+
+// lockCashAccount locks an account row for the rest of the transaction and returns its
+// balance and currency
+func lockCashAccount(ctx context.Context, tx pgx.Tx, accountID uuid.UUID) (float64, string, error) {
+	var balance float64
+	var currency string
+	err := tx.QueryRow(ctx, `
+		SELECT balance, COALESCE(currency, 'GBP') FROM cash_accounts WHERE id = $1 FOR UPDATE
+	`, accountID).Scan(&balance, &currency)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, "", ErrCashAccountNotFound
+		}
+		return 0, "", err
+	}
+	return balance, currency, nil
+}
+
+// applyCashMovement adds the movement to a locked account's balance and records it.
+// Only adjustments may leave the balance below zero.
+func applyCashMovement(ctx context.Context, tx pgx.Tx, m *models.CashMovement, balance float64) error {
+	m.BalanceAfter = math.Round((balance+m.Amount)*100) / 100
+	if m.BalanceAfter < 0 && m.Amount < 0 && m.MovementType != models.CashMovementAdjustment {
+		return ErrInsufficientCash
+	}
+
+	now := time.Now()
+	_, err := tx.Exec(ctx, `UPDATE cash_accounts SET balance = $2, last_updated = $3 WHERE id = $1`, m.CashAccountID, m.BalanceAfter, now)
+	if err != nil {
+		return err
+	}
+	return insertCashMovement(ctx, tx, m)
+}
+
+// insertCashMovement records a movement whose BalanceAfter is already set
+func insertCashMovement(ctx context.Context, tx pgx.Tx, m *models.CashMovement) error {
+	m.ID = uuid.New()
+	m.CreatedAt = time.Now()
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO cash_movements (id, cash_account_id, movement_type, amount, balance_after, movement_date, description, transfer_id, external_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10)
+	`,
+		m.ID,
+		m.CashAccountID,
+		m.MovementType,
+		m.Amount,
+		m.BalanceAfter,
+		m.MovementDate,
+		m.Description,
+		m.TransferID,
+		m.ExternalID,
+		m.CreatedAt,
+	)
+	return err
+}
+
+func scanCashMovement(row pgx.Row) (*models.CashMovement, error) {
+	var m models.CashMovement
+	err := row.Scan(
+		&m.ID,
+		&m.CashAccountID,
+		&m.MovementType,
+		&m.Amount,
+		&m.BalanceAfter,
+		&m.MovementDate,
+		&m.Description,
+		&m.TransferID,
+		&m.ExternalID,
+		&m.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
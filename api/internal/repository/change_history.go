@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/pkg/fieldcrypt"
+)
+
+// History entity types, the tables whose changes are recorded in change_history
+const (
+	HistoryPortfolios   = "portfolios"
+	HistoryHoldings     = "holdings"
+	HistoryTransactions = "transactions"
+)
+
+type ChangeHistoryRepository struct {
+	pool   *pgxpool.Pool
+	cipher *fieldcrypt.Cipher
+}
+
+func NewChangeHistoryRepository(pool *pgxpool.Pool, cipher *fieldcrypt.Cipher) *ChangeHistoryRepository {
+	return &ChangeHistoryRepository{pool: pool, cipher: cipher}
+}
+
+// GetByEntity returns the user's recorded changes to one record, newest first
+func (r *ChangeHistoryRepository) GetByEntity(ctx context.Context, userID uuid.UUID, entityType string, entityID uuid.UUID, limit int) ([]*models.ChangeHistoryEntry, error) {
+	query := `
+		SELECT id, entity_type, entity_id, operation, changed_fields, before, after, changed_at
+		FROM change_history
+		WHERE user_id = $1 AND entity_type = $2 AND entity_id = $3
+		ORDER BY id DESC
+		LIMIT $4
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, entityType, entityID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.ChangeHistoryEntry
+	for rows.Next() {
+		var e models.ChangeHistoryEntry
+		var before, after []byte
+		if err := rows.Scan(&e.ID, &e.EntityType, &e.EntityID, &e.Operation, &e.ChangedFields, &before, &after, &e.ChangedAt); err != nil {
+			return nil, err
+		}
+		if before != nil {
+			e.Before = json.RawMessage(before)
+		}
+		if after != nil {
+			e.After = json.RawMessage(after)
+		}
+		if entityType == HistoryPortfolios && !r.decryptPortfolioEntry(userID, &e) {
+			continue
+		}
+		entries = append(entries, &e)
+	}
+
+	return entries, rows.Err()
+}
+
+// decryptPortfolioEntry decrypts the account reference in the entry's metadata. The
+// reference is re-encrypted on every save, so a metadata change that turns out to be
+// only a new ciphertext is dropped, and with it the entry if nothing else changed.
+func (r *ChangeHistoryRepository) decryptPortfolioEntry(userID uuid.UUID, e *models.ChangeHistoryEntry) bool {
+	before := r.decryptMetadata(userID, e.Before)
+	after := r.decryptMetadata(userID, e.After)
+
+	if before != nil && after != nil && bytes.Equal(before["metadata"], after["metadata"]) {
+		delete(before, "metadata")
+		delete(after, "metadata")
+		fields := e.ChangedFields[:0]
+		for _, f := range e.ChangedFields {
+			if f != "metadata" {
+				fields = append(fields, f)
+			}
+		}
+		e.ChangedFields = fields
+		if len(fields) == 0 {
+			return false
+		}
+	}
+
+	if before != nil {
+		e.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		e.After, _ = json.Marshal(after)
+	}
+	return true
+}
+
+// decryptMetadata returns the row's fields with the metadata account reference
+// decrypted, or nil if there is no metadata to decrypt
+func (r *ChangeHistoryRepository) decryptMetadata(userID uuid.UUID, row json.RawMessage) map[string]json.RawMessage {
+	var fields map[string]json.RawMessage
+	if len(row) == 0 || json.Unmarshal(row, &fields) != nil || fields["metadata"] == nil {
+		return nil
+	}
+
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(fields["metadata"], &metadata); err != nil || metadata == nil {
+		return fields
+	}
+	if ref, ok := metadata["account_reference"].(string); ok {
+		plain, err := r.cipher.Decrypt(userID, ref)
+		if err != nil {
+			plain = ""
+		}
+		metadata["account_reference"] = plain
+	}
+	fields["metadata"], _ = json.Marshal(metadata)
+	return fields
+}
@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var (
+	ErrCorporateActionNotFound  = errors.New("corporate action not found")
+	ErrCorporateActionNotLatest = errors.New("only the latest corporate action on an asset can be reverted")
+	ErrSymbolTaken              = errors.New("an asset with this symbol already exists")
+)
+
+type CorporateActionRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCorporateActionRepository(pool *pgxpool.Pool) *CorporateActionRepository {
+	return &CorporateActionRepository{pool: pool}
+}
+
+// Apply records the action and adjusts the asset's data in one transaction. Splits and
+// consolidations rescale the units held before the effective date in every holding,
+// the quantity and price of earlier transactions, and stored prices, leaving total cost
+// unchanged. Ticker changes rename the asset and its research notes.
+func (r *CorporateActionRepository) Apply(ctx context.Context, action *models.CorporateAction) (*models.CorporateActionResult, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	action.ID = uuid.New()
+	action.CreatedAt = time.Now()
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO corporate_actions (id, asset_id, action_type, effective_date, ratio_from, ratio_to, old_symbol, new_symbol, notes, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`,
+		action.ID,
+		action.AssetID,
+		action.ActionType,
+		action.EffectiveDate,
+		action.RatioFrom,
+		action.RatioTo,
+		action.OldSymbol,
+		action.NewSymbol,
+		action.Notes,
+		action.CreatedBy,
+		action.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.CorporateActionResult{Action: action}
+	if action.ActionType == models.CorporateActionTickerChange {
+		err = renameAsset(ctx, tx, action.AssetID, *action.OldSymbol, *action.NewSymbol)
+	} else {
+		err = rescaleAsset(ctx, tx, action.AssetID, action.EffectiveDate, action.Ratio(), result)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Revert undoes the action and deletes it. Only the latest action on an asset can be
+// reverted, as later ones were applied on top of it.
+func (r *CorporateActionRepository) Revert(ctx context.Context, action *models.CorporateAction) (*models.CorporateActionResult, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var latest uuid.UUID
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM corporate_actions
+		WHERE asset_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+		FOR UPDATE
+	`, action.AssetID).Scan(&latest)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCorporateActionNotFound
+		}
+		return nil, err
+	}
+	if latest != action.ID {
+		return nil, ErrCorporateActionNotLatest
+	}
+
+	result := &models.CorporateActionResult{Action: action}
+	if action.ActionType == models.CorporateActionTickerChange {
+		err = renameAsset(ctx, tx, action.AssetID, *action.NewSymbol, *action.OldSymbol)
+	} else {
+		err = rescaleAsset(ctx, tx, action.AssetID, action.EffectiveDate, 1/action.Ratio(), result)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM corporate_actions WHERE id = $1`, action.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// rescaleAsset multiplies units before the effective date by ratio and divides prices by
+// it. Units a holding gained on or after the date are already in the new units, so only
+// the remainder is rescaled; the holding's total cost is kept.
+func rescaleAsset(ctx context.Context, tx pgx.Tx, assetID uuid.UUID, effective time.Time, ratio float64, result *models.CorporateActionResult) error {
+	rows, err := tx.Query(ctx, `
+		WITH later AS (
+			SELECT h.id, COALESCE(SUM(
+				CASE
+					WHEN t.transaction_type IN ('BUY', 'TRANSFER_IN') THEN t.quantity
+					WHEN t.transaction_type IN ('SELL', 'TRANSFER_OUT') THEN -t.quantity
+					ELSE 0
+				END), 0) AS quantity
+			FROM holdings h
+			LEFT JOIN transactions t ON t.portfolio_id = h.portfolio_id AND t.asset_id = h.asset_id
+				AND t.transaction_date >= $3 AND t.quantity IS NOT NULL
+			WHERE h.asset_id = $1
+			GROUP BY h.id
+		), rescaled AS (
+			SELECT h.id, GREATEST(h.quantity - l.quantity, 0) * $2 + LEAST(l.quantity, h.quantity) AS quantity
+			FROM holdings h
+			JOIN later l ON l.id = h.id
+		)
+		UPDATE holdings h
+		SET quantity = r.quantity,
+			average_cost = CASE WHEN r.quantity > 0 THEN h.quantity * h.average_cost / r.quantity ELSE h.average_cost END,
+			updated_at = NOW()
+		FROM rescaled r
+		WHERE h.id = r.id
+		RETURNING h.portfolio_id
+	`, assetID, ratio, effective)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var portfolioID uuid.UUID
+		if err := rows.Scan(&portfolioID); err != nil {
+			rows.Close()
+			return err
+		}
+		result.Portfolios = append(result.Portfolios, portfolioID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	result.HoldingsAdjusted = len(result.Portfolios)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE transactions
+		SET quantity = quantity * $2, price = price / $2
+		WHERE asset_id = $1 AND transaction_date < $3 AND quantity IS NOT NULL
+	`, assetID, ratio, effective)
+	if err != nil {
+		return err
+	}
+	result.TransactionsAdjusted = int(tag.RowsAffected())
+
+	tag, err = tx.Exec(ctx, `
+		UPDATE price_history
+		SET open_price = open_price / $2,
+			high_price = high_price / $2,
+			low_price = low_price / $2,
+			close_price = close_price / $2,
+			volume = ROUND(volume * $2::NUMERIC)::BIGINT
+		WHERE asset_id = $1 AND price_date < $3
+	`, assetID, ratio, effective)
+	if err != nil {
+		return err
+	}
+	result.PricesAdjusted = int(tag.RowsAffected())
+
+	// A last price fetched before the split is in the old units too
+	_, err = tx.Exec(ctx, `
+		UPDATE assets
+		SET last_price = last_price / $2
+		WHERE id = $1 AND last_price_updated_at < $3
+	`, assetID, ratio, effective)
+	return err
+}
+
+// renameAsset changes the asset's symbol and moves research notes kept on the old one
+func renameAsset(ctx context.Context, tx pgx.Tx, assetID uuid.UUID, from, to string) error {
+	_, err := tx.Exec(ctx, `UPDATE assets SET symbol = $2 WHERE id = $1`, assetID, to)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrSymbolTaken
+		}
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE asset_notes SET symbol = $2 WHERE symbol = $1`, from, to)
+	return err
+}
+
+func (r *CorporateActionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.CorporateAction, error) {
+	query := `
+		SELECT id, asset_id, action_type, effective_date, ratio_from, ratio_to, old_symbol, new_symbol, notes, created_by, created_at
+		FROM corporate_actions
+		WHERE id = $1
+	`
+
+	action, err := scanCorporateAction(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCorporateActionNotFound
+		}
+		return nil, err
+	}
+
+	return action, nil
+}
+
+// GetByAssetID returns the asset's corporate actions, most recent first
+func (r *CorporateActionRepository) GetByAssetID(ctx context.Context, assetID uuid.UUID) ([]*models.CorporateAction, error) {
+	query := `
+		SELECT id, asset_id, action_type, effective_date, ratio_from, ratio_to, old_symbol, new_symbol, notes, created_by, created_at
+		FROM corporate_actions
+		WHERE asset_id = $1
+		ORDER BY effective_date DESC, created_at DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, assetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var actions []*models.CorporateAction
+	for rows.Next() {
+		action, err := scanCorporateAction(rows)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+
+	return actions, rows.Err()
+}
+
+func scanCorporateAction(row pgx.Row) (*models.CorporateAction, error) {
+	var action models.CorporateAction
+	err := row.Scan(
+		&action.ID,
+		&action.AssetID,
+		&action.ActionType,
+		&action.EffectiveDate,
+		&action.RatioFrom,
+		&action.RatioTo,
+		&action.OldSymbol,
+		&action.NewSymbol,
+		&action.Notes,
+		&action.CreatedBy,
+		&action.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+type CurrencyChangeRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCurrencyChangeRepository(pool *pgxpool.Pool) *CurrencyChangeRepository {
+	return &CurrencyChangeRepository{pool: pool}
+}
+
+// Apply switches the user's base currency in one transaction: the revalued snapshots
+// and FIRE target are saved, the user updated and the change recorded
+func (r *CurrencyChangeRepository) Apply(ctx context.Context, change *models.BaseCurrencyChange, snapshots []*models.NetWorthSnapshot, fireTarget *float64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	for _, s := range snapshots {
+		valuesJSON, err := json.Marshal(s.PortfolioValues)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE net_worth_snapshots
+			SET total_net_worth = $2, investments = $3, cash = $4, fixed_assets = $5, liabilities = $6, currency = $7, portfolio_values = $8, updated_at = $9
+			WHERE id = $1
+		`,
+			s.ID,
+			s.TotalNetWorth,
+			s.Investments,
+			s.Cash,
+			s.FixedAssets,
+			s.Liabilities,
+			s.Currency,
+			valuesJSON,
+			now,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.Exec(ctx, `
+		UPDATE users SET base_currency = $2, fire_target = $3, updated_at = $4 WHERE id = $1
+	`, change.UserID, change.ToCurrency, fireTarget, now)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	change.ID = uuid.New()
+	change.ChangedAt = now
+	_, err = tx.Exec(ctx, `
+		INSERT INTO base_currency_changes (id, user_id, from_currency, to_currency, fx_rate, snapshots_revalued, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`,
+		change.ID,
+		change.UserID,
+		change.FromCurrency,
+		change.ToCurrency,
+		change.FXRate,
+		change.SnapshotsRevalued,
+		change.ChangedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetByUserID returns the user's base currency changes, most recent first
+func (r *CurrencyChangeRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.BaseCurrencyChange, error) {
+	query := `
+		SELECT id, user_id, from_currency, to_currency, fx_rate, snapshots_revalued, changed_at
+		FROM base_currency_changes
+		WHERE user_id = $1
+		ORDER BY changed_at DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*models.BaseCurrencyChange
+	for rows.Next() {
+		var c models.BaseCurrencyChange
+		err := rows.Scan(
+			&c.ID,
+			&c.UserID,
+			&c.FromCurrency,
+			&c.ToCurrency,
+			&c.FXRate,
+			&c.SnapshotsRevalued,
+			&c.ChangedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, &c)
+	}
+
+	return changes, rows.Err()
+}
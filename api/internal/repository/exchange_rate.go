@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var ErrExchangeRateNotFound = errors.New("exchange rate not found")
+
+type ExchangeRateRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewExchangeRateRepository(pool *pgxpool.Pool) *ExchangeRateRepository {
+	return &ExchangeRateRepository{pool: pool}
+}
+
+// GetLatest returns the most recent stored rate converting from into to
+func (r *ExchangeRateRepository) GetLatest(ctx context.Context, from, to string) (*models.ExchangeRate, error) {
+	query := `
+		SELECT id, from_currency, to_currency, rate, rate_date, created_at
+		FROM exchange_rates
+		WHERE from_currency = $1 AND to_currency = $2
+		ORDER BY rate_date DESC
+		LIMIT 1
+	`
+
+	var rate models.ExchangeRate
+	err := r.pool.QueryRow(ctx, query, from, to).Scan(
+		&rate.ID,
+		&rate.FromCurrency,
+		&rate.ToCurrency,
+		&rate.Rate,
+		&rate.RateDate,
+		&rate.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrExchangeRateNotFound
+		}
+		return nil, err
+	}
+
+	return &rate, nil
+}
+
+// GetOn returns the most recent stored rate converting from into to on or before date
+func (r *ExchangeRateRepository) GetOn(ctx context.Context, from, to string, date time.Time) (*models.ExchangeRate, error) {
+	query := `
+		SELECT id, from_currency, to_currency, rate, rate_date, created_at
+		FROM exchange_rates
+		WHERE from_currency = $1 AND to_currency = $2 AND rate_date <= $3
+		ORDER BY rate_date DESC
+		LIMIT 1
+	`
+
+	var rate models.ExchangeRate
+	err := r.pool.QueryRow(ctx, query, from, to, date).Scan(
+		&rate.ID,
+		&rate.FromCurrency,
+		&rate.ToCurrency,
+		&rate.Rate,
+		&rate.RateDate,
+		&rate.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrExchangeRateNotFound
+		}
+		return nil, err
+	}
+
+	return &rate, nil
+}
+
+// Upsert stores the rate for its date, replacing any earlier rate for the same day
+func (r *ExchangeRateRepository) Upsert(ctx context.Context, rate *models.ExchangeRate) error {
+	query := `
+		INSERT INTO exchange_rates (id, from_currency, to_currency, rate, rate_date, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (from_currency, to_currency, rate_date) DO UPDATE
+		SET rate = EXCLUDED.rate
+		RETURNING id, created_at
+	`
+
+	rate.ID = uuid.New()
+	rate.CreatedAt = time.Now()
+
+	return r.pool.QueryRow(ctx, query,
+		rate.ID,
+		rate.FromCurrency,
+		rate.ToCurrency,
+		rate.Rate,
+		rate.RateDate,
+		rate.CreatedAt,
+	).Scan(&rate.ID, &rate.CreatedAt)
+}
+
+// UpsertMany stores the rates in one transaction, replacing earlier rates for the same
+// pair and day
+func (r *ExchangeRateRepository) UpsertMany(ctx context.Context, rates []*models.ExchangeRate) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	for _, rate := range rates {
+		rate.ID = uuid.New()
+		rate.CreatedAt = now
+		_, err := tx.Exec(ctx, `
+			INSERT INTO exchange_rates (id, from_currency, to_currency, rate, rate_date, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (from_currency, to_currency, rate_date) DO UPDATE
+			SET rate = EXCLUDED.rate
+		`,
+			rate.ID,
+			rate.FromCurrency,
+			rate.ToCurrency,
+			rate.Rate,
+			rate.RateDate,
+			rate.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetLatestFrom returns the most recent rate from the currency into each other currency
+func (r *ExchangeRateRepository) GetLatestFrom(ctx context.Context, from string) ([]*models.ExchangeRate, error) {
+	query := `
+		SELECT DISTINCT ON (to_currency) id, from_currency, to_currency, rate, rate_date, created_at
+		FROM exchange_rates
+		WHERE from_currency = $1
+		ORDER BY to_currency, rate_date DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []*models.ExchangeRate
+	for rows.Next() {
+		var rate models.ExchangeRate
+		err := rows.Scan(
+			&rate.ID,
+			&rate.FromCurrency,
+			&rate.ToCurrency,
+			&rate.Rate,
+			&rate.RateDate,
+			&rate.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, &rate)
+	}
+
+	return rates, rows.Err()
+}
@@ -17,6 +17,23 @@ var (
 	ErrInsufficientHoldings = errors.New("insufficient holdings")
 )
 
+// holdingTags selects the names of a holding's tags
+const holdingTags = `ARRAY(SELECT tg.name FROM tag_links l JOIN tags tg ON tg.id = l.tag_id
+			         WHERE l.domain = 'HOLDING' AND l.item_id = h.id ORDER BY tg.name)`
+
+// holdingPriceCurrency is the major currency of the asset's price, taking prices quoted
+// in pence as pounds and an unset currency as the portfolio's
+const holdingPriceCurrency = `CASE WHEN a.currency IN ('GBp', 'GBX') THEN 'GBP'
+			         ELSE UPPER(COALESCE(NULLIF(TRIM(a.currency), ''), p.currency)) END`
+
+// holdingFXRate selects the rate converting the asset's price into the portfolio's
+// currency from the latest stored exchange rate, or NULL when none has been stored
+const holdingFXRate = `(CASE WHEN a.currency IN ('GBp', 'GBX') THEN 0.01 ELSE 1 END *
+			   CASE WHEN ` + holdingPriceCurrency + ` = p.currency THEN 1
+			   ELSE (SELECT er.rate FROM exchange_rates er
+			         WHERE er.from_currency = ` + holdingPriceCurrency + ` AND er.to_currency = p.currency
+			         ORDER BY er.rate_date DESC LIMIT 1) END)::float8`
+
 type HoldingRepository struct {
 	pool *pgxpool.Pool
 }
@@ -58,15 +75,19 @@ func (r *HoldingRepository) Create(ctx context.Context, holding *models.Holding)
 
 func (r *HoldingRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Holding, error) {
 	query := `
-		SELECT h.id, h.portfolio_id, h.asset_id, h.quantity, h.average_cost, h.purchased_at, h.created_at, h.updated_at,
-			   a.id, a.symbol, a.name, a.asset_type, a.exchange, a.currency, a.data_source, a.last_price, a.last_price_updated_at, a.created_at
+		SELECT h.id, h.portfolio_id, h.asset_id, h.quantity, h.average_cost, h.purchased_at, COALESCE(h.notes, ''), h.created_at, h.updated_at,
+			   a.id, a.symbol, a.name, a.asset_type, a.exchange, a.currency, a.data_source, a.last_price, a.last_price_updated_at, a.created_at,
+			   ` + holdingTags + `,
+			   ` + holdingFXRate + `
 		FROM holdings h
 		JOIN assets a ON a.id = h.asset_id
+		JOIN portfolios p ON p.id = h.portfolio_id
 		WHERE h.id = $1
 	`
 
 	var holding models.Holding
 	var asset models.Asset
+	var fxRate *float64
 
 	err := r.pool.QueryRow(ctx, query, id).Scan(
 		&holding.ID,
@@ -75,6 +96,7 @@ func (r *HoldingRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 		&holding.Quantity,
 		&holding.AverageCost,
 		&holding.PurchasedAt,
+		&holding.Notes,
 		&holding.CreatedAt,
 		&holding.UpdatedAt,
 		&asset.ID,
@@ -87,6 +109,8 @@ func (r *HoldingRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 		&asset.LastPrice,
 		&asset.LastPriceUpdatedAt,
 		&asset.CreatedAt,
+		&holding.Tags,
+		&fxRate,
 	)
 
 	if err != nil {
@@ -97,17 +121,20 @@ func (r *HoldingRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 	}
 
 	holding.Asset = &asset
-	r.calculateHoldingValues(&holding)
+	r.calculateHoldingValues(&holding, fxRate)
 
 	return &holding, nil
 }
 
 func (r *HoldingRepository) GetByPortfolioID(ctx context.Context, portfolioID uuid.UUID) ([]*models.Holding, error) {
 	query := `
-		SELECT h.id, h.portfolio_id, h.asset_id, h.quantity, h.average_cost, h.purchased_at, h.created_at, h.updated_at,
-			   a.id, a.symbol, a.name, a.asset_type, a.exchange, a.currency, a.data_source, a.last_price, a.last_price_updated_at, a.created_at
+		SELECT h.id, h.portfolio_id, h.asset_id, h.quantity, h.average_cost, h.purchased_at, COALESCE(h.notes, ''), h.created_at, h.updated_at,
+			   a.id, a.symbol, a.name, a.asset_type, a.exchange, a.currency, a.data_source, a.last_price, a.last_price_updated_at, a.created_at,
+			   ` + holdingTags + `,
+			   ` + holdingFXRate + `
 		FROM holdings h
 		JOIN assets a ON a.id = h.asset_id
+		JOIN portfolios p ON p.id = h.portfolio_id
 		WHERE h.portfolio_id = $1
 		ORDER BY a.symbol
 	`
@@ -122,6 +149,7 @@ func (r *HoldingRepository) GetByPortfolioID(ctx context.Context, portfolioID uu
 	for rows.Next() {
 		var holding models.Holding
 		var asset models.Asset
+		var fxRate *float64
 
 		err := rows.Scan(
 			&holding.ID,
@@ -130,6 +158,7 @@ func (r *HoldingRepository) GetByPortfolioID(ctx context.Context, portfolioID uu
 			&holding.Quantity,
 			&holding.AverageCost,
 			&holding.PurchasedAt,
+			&holding.Notes,
 			&holding.CreatedAt,
 			&holding.UpdatedAt,
 			&asset.ID,
@@ -142,13 +171,15 @@ func (r *HoldingRepository) GetByPortfolioID(ctx context.Context, portfolioID uu
 			&asset.LastPrice,
 			&asset.LastPriceUpdatedAt,
 			&asset.CreatedAt,
+			&holding.Tags,
+			&fxRate,
 		)
 		if err != nil {
 			return nil, err
 		}
 
 		holding.Asset = &asset
-		r.calculateHoldingValues(&holding)
+		r.calculateHoldingValues(&holding, fxRate)
 		holdings = append(holdings, &holding)
 	}
 
@@ -157,7 +188,7 @@ func (r *HoldingRepository) GetByPortfolioID(ctx context.Context, portfolioID uu
 
 func (r *HoldingRepository) GetByPortfolioAndAsset(ctx context.Context, portfolioID, assetID uuid.UUID) (*models.Holding, error) {
 	query := `
-		SELECT id, portfolio_id, asset_id, quantity, average_cost, purchased_at, created_at, updated_at
+		SELECT id, portfolio_id, asset_id, quantity, average_cost, purchased_at, COALESCE(notes, ''), created_at, updated_at
 		FROM holdings
 		WHERE portfolio_id = $1 AND asset_id = $2
 	`
@@ -170,6 +201,7 @@ func (r *HoldingRepository) GetByPortfolioAndAsset(ctx context.Context, portfoli
 		&holding.Quantity,
 		&holding.AverageCost,
 		&holding.PurchasedAt,
+		&holding.Notes,
 		&holding.CreatedAt,
 		&holding.UpdatedAt,
 	)
@@ -211,6 +243,20 @@ func (r *HoldingRepository) Update(ctx context.Context, holding *models.Holding)
 	return nil
 }
 
+// SetNotes replaces the holding's notes
+func (r *HoldingRepository) SetNotes(ctx context.Context, id uuid.UUID, notes string) error {
+	result, err := r.pool.Exec(ctx, `UPDATE holdings SET notes = $2, updated_at = $3 WHERE id = $1`, id, notes, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrHoldingNotFound
+	}
+
+	return nil
+}
+
 func (r *HoldingRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM holdings WHERE id = $1`
 
@@ -282,12 +328,15 @@ func (r *HoldingRepository) RemoveFromHolding(ctx context.Context, portfolioID,
 	return r.Update(ctx, existing)
 }
 
-func (r *HoldingRepository) calculateHoldingValues(holding *models.Holding) {
-	if holding.Asset == nil || holding.Asset.LastPrice == nil {
+// calculateHoldingValues values the holding in its portfolio's currency, which its
+// average cost is held in, converting the asset's price at fxRate. Nothing is set when
+// the asset has no price or no rate is stored.
+func (r *HoldingRepository) calculateHoldingValues(holding *models.Holding, fxRate *float64) {
+	if holding.Asset == nil || holding.Asset.LastPrice == nil || fxRate == nil {
 		return
 	}
 
-	currentValue := holding.Quantity * *holding.Asset.LastPrice
+	currentValue := holding.Quantity * *holding.Asset.LastPrice * *fxRate
 	holding.CurrentValue = &currentValue
 
 	costBasis := holding.Quantity * holding.AverageCost
@@ -316,8 +365,10 @@ func (r *HoldingRepository) BelongsToUser(ctx context.Context, holdingID, userID
 
 func (r *HoldingRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.HoldingWithPortfolio, error) {
 	query := `
-		SELECT h.id, h.portfolio_id, h.asset_id, h.quantity, h.average_cost, h.purchased_at, h.created_at, h.updated_at,
+		SELECT h.id, h.portfolio_id, h.asset_id, h.quantity, h.average_cost, h.purchased_at, COALESCE(h.notes, ''), h.created_at, h.updated_at,
 			   a.id, a.symbol, a.name, a.asset_type, a.exchange, a.currency, a.data_source, a.last_price, a.last_price_updated_at, a.created_at,
+			   ` + holdingTags + `,
+			   ` + holdingFXRate + `,
 			   p.name, p.type
 		FROM holdings h
 		JOIN assets a ON a.id = h.asset_id
@@ -336,6 +387,7 @@ func (r *HoldingRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (
 	for rows.Next() {
 		var holding models.HoldingWithPortfolio
 		var asset models.Asset
+		var fxRate *float64
 
 		err := rows.Scan(
 			&holding.ID,
@@ -344,6 +396,7 @@ func (r *HoldingRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (
 			&holding.Quantity,
 			&holding.AverageCost,
 			&holding.PurchasedAt,
+			&holding.Notes,
 			&holding.CreatedAt,
 			&holding.UpdatedAt,
 			&asset.ID,
@@ -356,6 +409,8 @@ func (r *HoldingRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (
 			&asset.LastPrice,
 			&asset.LastPriceUpdatedAt,
 			&asset.CreatedAt,
+			&holding.Tags,
+			&fxRate,
 			&holding.PortfolioName,
 			&holding.PortfolioType,
 		)
@@ -364,19 +419,19 @@ func (r *HoldingRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (
 		}
 
 		holding.Asset = &asset
-		r.calculateHoldingWithPortfolioValues(&holding)
+		r.calculateHoldingWithPortfolioValues(&holding, fxRate)
 		holdings = append(holdings, &holding)
 	}
 
 	return holdings, rows.Err()
 }
 
-func (r *HoldingRepository) calculateHoldingWithPortfolioValues(holding *models.HoldingWithPortfolio) {
-	if holding.Asset == nil || holding.Asset.LastPrice == nil {
+func (r *HoldingRepository) calculateHoldingWithPortfolioValues(holding *models.HoldingWithPortfolio, fxRate *float64) {
+	if holding.Asset == nil || holding.Asset.LastPrice == nil || fxRate == nil {
 		return
 	}
 
-	currentValue := holding.Quantity * *holding.Asset.LastPrice
+	currentValue := holding.Quantity * *holding.Asset.LastPrice * *fxRate
 	holding.CurrentValue = &currentValue
 
 	costBasis := holding.Quantity * holding.AverageCost
@@ -388,3 +443,40 @@ func (r *HoldingRepository) calculateHoldingWithPortfolioValues(holding *models.
 		holding.GainLossPct = &gainLossPct
 	}
 }
+
+// BulkUpdate applies quantity/average cost updates and removals to holdings in a single
+// transaction. If any holding is not found in the portfolio, nothing is changed.
+func (r *HoldingRepository) BulkUpdate(ctx context.Context, portfolioID uuid.UUID, updates []*models.Holding, removals []uuid.UUID) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	for _, holding := range updates {
+		result, err := tx.Exec(ctx, `
+			UPDATE holdings SET quantity = $3, average_cost = $4, updated_at = $5
+			WHERE id = $1 AND portfolio_id = $2
+		`, holding.ID, portfolioID, holding.Quantity, holding.AverageCost, now)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return ErrHoldingNotFound
+		}
+		holding.UpdatedAt = now
+	}
+
+	for _, id := range removals {
+		result, err := tx.Exec(ctx, `DELETE FROM holdings WHERE id = $1 AND portfolio_id = $2`, id, portfolioID)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return ErrHoldingNotFound
+		}
+	}
+
+	return tx.Commit(ctx)
+}
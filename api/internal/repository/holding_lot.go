@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+type HoldingLotRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewHoldingLotRepository(pool *pgxpool.Pool) *HoldingLotRepository {
+	return &HoldingLotRepository{pool: pool}
+}
+
+// Replace swaps the lots for an asset in a portfolio for the given ones
+func (r *HoldingLotRepository) Replace(ctx context.Context, portfolioID, assetID uuid.UUID, lots []*models.HoldingLot) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM holding_lots WHERE portfolio_id = $1 AND asset_id = $2`, portfolioID, assetID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, lot := range lots {
+		lot.ID = uuid.New()
+		lot.PortfolioID = portfolioID
+		lot.AssetID = assetID
+		lot.CreatedAt = now
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO holding_lots (id, portfolio_id, asset_id, transaction_id, acquired_at, quantity, remaining_quantity, unit_cost, realised_gain, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`,
+			lot.ID,
+			lot.PortfolioID,
+			lot.AssetID,
+			lot.TransactionID,
+			lot.AcquiredAt,
+			lot.Quantity,
+			lot.RemainingQuantity,
+			lot.UnitCost,
+			lot.RealisedGain,
+			lot.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetByPortfolioAndAsset returns the asset's lots in the portfolio, oldest first,
+// including fully sold ones
+func (r *HoldingLotRepository) GetByPortfolioAndAsset(ctx context.Context, portfolioID, assetID uuid.UUID) ([]*models.HoldingLot, error) {
+	query := `
+		SELECT id, portfolio_id, asset_id, transaction_id, acquired_at, quantity, remaining_quantity, unit_cost, realised_gain, created_at
+		FROM holding_lots
+		WHERE portfolio_id = $1 AND asset_id = $2
+		ORDER BY acquired_at, created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, portfolioID, assetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lots []*models.HoldingLot
+	for rows.Next() {
+		var lot models.HoldingLot
+		err := rows.Scan(
+			&lot.ID,
+			&lot.PortfolioID,
+			&lot.AssetID,
+			&lot.TransactionID,
+			&lot.AcquiredAt,
+			&lot.Quantity,
+			&lot.RemainingQuantity,
+			&lot.UnitCost,
+			&lot.RealisedGain,
+			&lot.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		lots = append(lots, &lot)
+	}
+
+	return lots, rows.Err()
+}
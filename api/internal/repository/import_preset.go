@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var (
+	ErrImportPresetNotFound      = errors.New("import preset not found")
+	ErrImportPresetAlreadyExists = errors.New("import preset with this name already exists")
+)
+
+type ImportPresetRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewImportPresetRepository(pool *pgxpool.Pool) *ImportPresetRepository {
+	return &ImportPresetRepository{pool: pool}
+}
+
+func (r *ImportPresetRepository) Create(ctx context.Context, preset *models.ImportPreset) error {
+	mappingJSON, err := json.Marshal(preset.Mapping)
+	if err != nil {
+		return err
+	}
+
+	preset.ID = uuid.New()
+	preset.CreatedAt = time.Now()
+	preset.UpdatedAt = time.Now()
+
+	query := `
+		INSERT INTO import_presets (id, user_id, name, mapping, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err = r.pool.Exec(ctx, query,
+		preset.ID,
+		preset.UserID,
+		preset.Name,
+		mappingJSON,
+		preset.CreatedAt,
+		preset.UpdatedAt,
+	)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrImportPresetAlreadyExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *ImportPresetRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ImportPreset, error) {
+	query := `
+		SELECT id, user_id, name, mapping, created_at, updated_at
+		FROM import_presets
+		WHERE id = $1
+	`
+
+	preset, err := scanImportPreset(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrImportPresetNotFound
+		}
+		return nil, err
+	}
+
+	return preset, nil
+}
+
+func (r *ImportPresetRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.ImportPreset, error) {
+	query := `
+		SELECT id, user_id, name, mapping, created_at, updated_at
+		FROM import_presets
+		WHERE user_id = $1
+		ORDER BY name
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var presets []*models.ImportPreset
+	for rows.Next() {
+		preset, err := scanImportPreset(rows)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, preset)
+	}
+
+	return presets, rows.Err()
+}
+
+func (r *ImportPresetRepository) Update(ctx context.Context, preset *models.ImportPreset) error {
+	mappingJSON, err := json.Marshal(preset.Mapping)
+	if err != nil {
+		return err
+	}
+
+	preset.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE import_presets
+		SET name = $2, mapping = $3, updated_at = $4
+		WHERE id = $1
+	`
+
+	result, err := r.pool.Exec(ctx, query, preset.ID, preset.Name, mappingJSON, preset.UpdatedAt)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrImportPresetAlreadyExists
+		}
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrImportPresetNotFound
+	}
+
+	return nil
+}
+
+func (r *ImportPresetRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM import_presets WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrImportPresetNotFound
+	}
+	return nil
+}
+
+func scanImportPreset(row pgx.Row) (*models.ImportPreset, error) {
+	var preset models.ImportPreset
+	var mappingJSON []byte
+
+	err := row.Scan(
+		&preset.ID,
+		&preset.UserID,
+		&preset.Name,
+		&mappingJSON,
+		&preset.CreatedAt,
+		&preset.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(mappingJSON, &preset.Mapping); err != nil {
+		return nil, err
+	}
+
+	return &preset, nil
+}
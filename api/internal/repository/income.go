@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var ErrIncomeNotFound = errors.New("income not found")
+
+const incomeColumns = `
+	id, user_id, source, income_type, amount, currency, frequency, next_pay_date, start_date, end_date,
+	is_active, COALESCE(notes, ''), created_at, updated_at`
+
+type IncomeRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewIncomeRepository(pool *pgxpool.Pool) *IncomeRepository {
+	return &IncomeRepository{pool: pool}
+}
+
+func (r *IncomeRepository) Create(ctx context.Context, income *models.Income) error {
+	income.ID = uuid.New()
+	income.CreatedAt = time.Now()
+	income.UpdatedAt = time.Now()
+
+	query := `
+		INSERT INTO incomes (id, user_id, source, income_type, amount, currency, frequency, next_pay_date, start_date, end_date, is_active, notes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		income.ID,
+		income.UserID,
+		income.Source,
+		income.IncomeType,
+		income.Amount,
+		income.Currency,
+		income.Frequency,
+		income.NextPayDate,
+		income.StartDate,
+		income.EndDate,
+		income.IsActive,
+		income.Notes,
+		income.CreatedAt,
+		income.UpdatedAt,
+	)
+	return err
+}
+
+func (r *IncomeRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Income, error) {
+	query := `SELECT ` + incomeColumns + ` FROM incomes WHERE id = $1`
+
+	income, err := scanIncome(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrIncomeNotFound
+		}
+		return nil, err
+	}
+
+	return income, nil
+}
+
+// GetByUserID returns the user's incomes by next pay date, optionally only active ones
+func (r *IncomeRepository) GetByUserID(ctx context.Context, userID uuid.UUID, activeOnly bool) ([]*models.Income, error) {
+	query := `
+		SELECT ` + incomeColumns + `
+		FROM incomes
+		WHERE user_id = $1 AND (is_active OR NOT $2)
+		ORDER BY next_pay_date, source
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incomes []*models.Income
+	for rows.Next() {
+		income, err := scanIncome(rows)
+		if err != nil {
+			return nil, err
+		}
+		incomes = append(incomes, income)
+	}
+
+	return incomes, rows.Err()
+}
+
+func (r *IncomeRepository) Update(ctx context.Context, income *models.Income) error {
+	income.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE incomes
+		SET source = $2, income_type = $3, amount = $4, currency = $5, frequency = $6, next_pay_date = $7,
+			start_date = $8, end_date = $9, is_active = $10, notes = $11, updated_at = $12
+		WHERE id = $1
+	`
+
+	result, err := r.pool.Exec(ctx, query,
+		income.ID,
+		income.Source,
+		income.IncomeType,
+		income.Amount,
+		income.Currency,
+		income.Frequency,
+		income.NextPayDate,
+		income.StartDate,
+		income.EndDate,
+		income.IsActive,
+		income.Notes,
+		income.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrIncomeNotFound
+	}
+
+	return nil
+}
+
+func (r *IncomeRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM incomes WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrIncomeNotFound
+	}
+	return nil
+}
+
+func scanIncome(row pgx.Row) (*models.Income, error) {
+	var income models.Income
+	err := row.Scan(
+		&income.ID,
+		&income.UserID,
+		&income.Source,
+		&income.IncomeType,
+		&income.Amount,
+		&income.Currency,
+		&income.Frequency,
+		&income.NextPayDate,
+		&income.StartDate,
+		&income.EndDate,
+		&income.IsActive,
+		&income.Notes,
+		&income.CreatedAt,
+		&income.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &income, nil
+}
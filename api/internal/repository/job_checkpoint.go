@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrCheckpointNotFound = errors.New("checkpoint not found")
+)
+
+type JobCheckpointRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewJobCheckpointRepository(pool *pgxpool.Pool) *JobCheckpointRepository {
+	return &JobCheckpointRepository{pool: pool}
+}
+
+// Get returns the saved state for a job
+func (r *JobCheckpointRepository) Get(ctx context.Context, jobName string) (json.RawMessage, error) {
+	query := `SELECT state FROM job_checkpoints WHERE job_name = $1`
+
+	var state []byte
+	err := r.pool.QueryRow(ctx, query, jobName).Scan(&state)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCheckpointNotFound
+		}
+		return nil, err
+	}
+
+	return json.RawMessage(state), nil
+}
+
+// Save creates or replaces the saved state for a job
+func (r *JobCheckpointRepository) Save(ctx context.Context, jobName string, state json.RawMessage) error {
+	query := `
+		INSERT INTO job_checkpoints (job_name, state, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (job_name) DO UPDATE
+		SET state = EXCLUDED.state, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.pool.Exec(ctx, query, jobName, []byte(state), time.Now())
+	return err
+}
+
+// Delete removes the saved state once a job has completed
+func (r *JobCheckpointRepository) Delete(ctx context.Context, jobName string) error {
+	query := `DELETE FROM job_checkpoints WHERE job_name = $1`
+
+	_, err := r.pool.Exec(ctx, query, jobName)
+	return err
+}
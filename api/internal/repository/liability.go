@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var (
+	ErrLiabilityNotFound = errors.New("liability not found")
+)
+
+const liabilityColumns = `id, user_id, name, liability_type, COALESCE(lender, ''), balance, currency, interest_rate, monthly_payment, payment_day, end_date, COALESCE(notes, ''), created_at, updated_at`
+
+type LiabilityRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewLiabilityRepository(pool *pgxpool.Pool) *LiabilityRepository {
+	return &LiabilityRepository{pool: pool}
+}
+
+func scanLiability(row pgx.Row) (*models.Liability, error) {
+	var l models.Liability
+	err := row.Scan(
+		&l.ID,
+		&l.UserID,
+		&l.Name,
+		&l.LiabilityType,
+		&l.Lender,
+		&l.Balance,
+		&l.Currency,
+		&l.InterestRate,
+		&l.MonthlyPayment,
+		&l.PaymentDay,
+		&l.EndDate,
+		&l.Notes,
+		&l.CreatedAt,
+		&l.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *LiabilityRepository) Create(ctx context.Context, l *models.Liability) error {
+	query := `
+		INSERT INTO liabilities (id, user_id, name, liability_type, lender, balance, currency, interest_rate, monthly_payment, payment_day, end_date, notes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	l.ID = uuid.New()
+	l.CreatedAt = time.Now()
+	l.UpdatedAt = l.CreatedAt
+
+	_, err := r.pool.Exec(ctx, query,
+		l.ID,
+		l.UserID,
+		l.Name,
+		l.LiabilityType,
+		l.Lender,
+		l.Balance,
+		l.Currency,
+		l.InterestRate,
+		l.MonthlyPayment,
+		l.PaymentDay,
+		l.EndDate,
+		l.Notes,
+		l.CreatedAt,
+		l.UpdatedAt,
+	)
+
+	return err
+}
+
+func (r *LiabilityRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Liability, error) {
+	query := `SELECT ` + liabilityColumns + ` FROM liabilities WHERE id = $1`
+
+	l, err := scanLiability(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrLiabilityNotFound
+		}
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// GetByUserID returns the user's liabilities, largest balance first
+func (r *LiabilityRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Liability, error) {
+	query := `SELECT ` + liabilityColumns + ` FROM liabilities WHERE user_id = $1 ORDER BY balance DESC`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var liabilities []*models.Liability
+	for rows.Next() {
+		l, err := scanLiability(rows)
+		if err != nil {
+			return nil, err
+		}
+		liabilities = append(liabilities, l)
+	}
+
+	return liabilities, rows.Err()
+}
+
+func (r *LiabilityRepository) Update(ctx context.Context, l *models.Liability) error {
+	query := `
+		UPDATE liabilities
+		SET name = $2, liability_type = $3, lender = $4, balance = $5, currency = $6, interest_rate = $7, monthly_payment = $8, payment_day = $9, end_date = $10, notes = $11, updated_at = $12
+		WHERE id = $1
+	`
+
+	l.UpdatedAt = time.Now()
+
+	result, err := r.pool.Exec(ctx, query,
+		l.ID,
+		l.Name,
+		l.LiabilityType,
+		l.Lender,
+		l.Balance,
+		l.Currency,
+		l.InterestRate,
+		l.MonthlyPayment,
+		l.PaymentDay,
+		l.EndDate,
+		l.Notes,
+		l.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrLiabilityNotFound
+	}
+
+	return nil
+}
+
+func (r *LiabilityRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM liabilities WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrLiabilityNotFound
+	}
+
+	return nil
+}
+
+func (r *LiabilityRepository) BelongsToUser(ctx context.Context, liabilityID, userID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM liabilities WHERE id = $1 AND user_id = $2)`
+
+	var exists bool
+	err := r.pool.QueryRow(ctx, query, liabilityID, userID).Scan(&exists)
+	return exists, err
+}
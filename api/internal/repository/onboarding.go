@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+type OnboardingRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewOnboardingRepository(pool *pgxpool.Pool) *OnboardingRepository {
+	return &OnboardingRepository{pool: pool}
+}
+
+// GetCompletedSteps returns when each recorded step was completed, keyed by step
+func (r *OnboardingRepository) GetCompletedSteps(ctx context.Context, userID uuid.UUID) (map[string]time.Time, error) {
+	query := `SELECT step, completed_at FROM user_onboarding_steps WHERE user_id = $1`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	steps := make(map[string]time.Time)
+	for rows.Next() {
+		var step string
+		var completedAt time.Time
+		if err := rows.Scan(&step, &completedAt); err != nil {
+			return nil, err
+		}
+		steps[step] = completedAt
+	}
+
+	return steps, rows.Err()
+}
+
+// CompleteStep records a step as completed. Completing a step twice keeps the first time.
+func (r *OnboardingRepository) CompleteStep(ctx context.Context, userID uuid.UUID, step string) error {
+	query := `
+		INSERT INTO user_onboarding_steps (user_id, step, completed_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, step) DO NOTHING
+	`
+
+	_, err := r.pool.Exec(ctx, query, userID, step, time.Now())
+	return err
+}
+
+// GetObservedSteps returns the steps that can be inferred from the user's existing data.
+// The fixed assets portfolio created at registration does not count as a portfolio.
+func (r *OnboardingRepository) GetObservedSteps(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	query := `
+		SELECT
+			EXISTS(SELECT 1 FROM portfolios WHERE user_id = $1 AND type <> $2),
+			EXISTS(SELECT 1 FROM holdings h JOIN portfolios p ON p.id = h.portfolio_id WHERE p.user_id = $1),
+			EXISTS(SELECT 1 FROM cash_accounts ca JOIN portfolios p ON p.id = ca.portfolio_id WHERE p.user_id = $1),
+			EXISTS(SELECT 1 FROM savings_goals WHERE user_id = $1)
+	`
+
+	var hasPortfolio, hasHolding, hasCashAccount, hasGoal bool
+	err := r.pool.QueryRow(ctx, query, userID, models.PortfolioTypeFixedAssets).Scan(
+		&hasPortfolio,
+		&hasHolding,
+		&hasCashAccount,
+		&hasGoal,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []string
+	if hasPortfolio {
+		steps = append(steps, models.OnboardingStepCreatePortfolio)
+	}
+	if hasHolding {
+		steps = append(steps, models.OnboardingStepAddHolding)
+	}
+	if hasCashAccount {
+		steps = append(steps, models.OnboardingStepAddCashAccount)
+	}
+	if hasGoal {
+		steps = append(steps, models.OnboardingStepCreateGoal)
+	}
+
+	return steps, nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/pkg/fieldcrypt"
 )
 
 var (
@@ -17,12 +18,15 @@ var (
 	ErrPortfolioAlreadyExists = errors.New("portfolio with this name already exists")
 )
 
+// PortfolioRepository stores portfolios. Sensitive metadata (the account reference) is
+// encrypted with the user's key when a cipher is configured.
 type PortfolioRepository struct {
-	pool *pgxpool.Pool
+	pool   *pgxpool.Pool
+	cipher *fieldcrypt.Cipher
 }
 
-func NewPortfolioRepository(pool *pgxpool.Pool) *PortfolioRepository {
-	return &PortfolioRepository{pool: pool}
+func NewPortfolioRepository(pool *pgxpool.Pool, cipher *fieldcrypt.Cipher) *PortfolioRepository {
+	return &PortfolioRepository{pool: pool, cipher: cipher}
 }
 
 func (r *PortfolioRepository) Create(ctx context.Context, portfolio *models.Portfolio) error {
@@ -36,15 +40,9 @@ func (r *PortfolioRepository) Create(ctx context.Context, portfolio *models.Port
 	portfolio.UpdatedAt = time.Now()
 	portfolio.IsActive = true
 
-	var metadataJSON []byte
-	var err error
-	if portfolio.Metadata != nil {
-		metadataJSON, err = json.Marshal(portfolio.Metadata)
-		if err != nil {
-			return err
-		}
-	} else {
-		metadataJSON = []byte("{}")
+	metadataJSON, err := r.encodeMetadata(portfolio.UserID, portfolio.Metadata)
+	if err != nil {
+		return err
 	}
 
 	_, err = r.pool.Exec(ctx, query,
@@ -99,12 +97,7 @@ func (r *PortfolioRepository) GetByID(ctx context.Context, id uuid.UUID) (*model
 		return nil, err
 	}
 
-	if len(metadataJSON) > 0 && string(metadataJSON) != "{}" {
-		var metadata models.PortfolioMetadata
-		if err := json.Unmarshal(metadataJSON, &metadata); err == nil {
-			portfolio.Metadata = &metadata
-		}
-	}
+	portfolio.Metadata = r.decodeMetadata(portfolio.UserID, metadataJSON)
 
 	return &portfolio, nil
 }
@@ -143,12 +136,7 @@ func (r *PortfolioRepository) GetByUserID(ctx context.Context, userID uuid.UUID)
 			return nil, err
 		}
 
-		if len(metadataJSON) > 0 && string(metadataJSON) != "{}" {
-			var metadata models.PortfolioMetadata
-			if err := json.Unmarshal(metadataJSON, &metadata); err == nil {
-				p.Metadata = &metadata
-			}
-		}
+		p.Metadata = r.decodeMetadata(p.UserID, metadataJSON)
 
 		portfolios = append(portfolios, &p)
 	}
@@ -165,15 +153,9 @@ func (r *PortfolioRepository) Update(ctx context.Context, portfolio *models.Port
 
 	portfolio.UpdatedAt = time.Now()
 
-	var metadataJSON []byte
-	var err error
-	if portfolio.Metadata != nil {
-		metadataJSON, err = json.Marshal(portfolio.Metadata)
-		if err != nil {
-			return err
-		}
-	} else {
-		metadataJSON = []byte("{}")
+	metadataJSON, err := r.encodeMetadata(portfolio.UserID, portfolio.Metadata)
+	if err != nil {
+		return err
 	}
 
 	result, err := r.pool.Exec(ctx, query,
@@ -264,7 +246,8 @@ func (r *PortfolioRepository) GetSummary(ctx context.Context, portfolioID uuid.U
 		return &summary, nil
 	}
 
-	// For CASH and SAVINGS portfolios, calculate balance from DEPOSIT/WITHDRAWAL transactions
+	// For CASH and SAVINGS portfolios, calculate balance from DEPOSIT/WITHDRAWAL transactions,
+	// using the converted amount of those in another currency
 	if portfolio.Type == models.PortfolioTypeCash || portfolio.Type == models.PortfolioTypeSavings {
 		query := `
 			SELECT
@@ -273,8 +256,8 @@ func (r *PortfolioRepository) GetSummary(ctx context.Context, portfolioID uuid.U
 				p.type,
 				COALESCE(
 					SUM(CASE
-						WHEN t.transaction_type = 'DEPOSIT' THEN t.total_amount
-						WHEN t.transaction_type = 'WITHDRAWAL' THEN -t.total_amount
+						WHEN t.transaction_type = 'DEPOSIT' THEN COALESCE(t.portfolio_amount, t.total_amount)
+						WHEN t.transaction_type = 'WITHDRAWAL' THEN -COALESCE(t.portfolio_amount, t.total_amount)
 						ELSE 0
 					END), 0
 				) as total_value,
@@ -359,23 +342,33 @@ func (r *PortfolioRepository) BelongsToUser(ctx context.Context, portfolioID, us
 	return exists, err
 }
 
-// AddContribution adds an amount to the contributions_this_year in portfolio metadata
-func (r *PortfolioRepository) AddContribution(ctx context.Context, portfolioID uuid.UUID, amount float64) error {
+// SetContributions stores the contributions made this tax year and the allowance in
+// portfolio metadata
+func (r *PortfolioRepository) SetContributions(ctx context.Context, portfolioID uuid.UUID, contributed, limit float64) error {
 	query := `
 		UPDATE portfolios
-		SET metadata = jsonb_set(
-			COALESCE(metadata, '{}'::jsonb),
-			'{contributions_this_year}',
-			to_jsonb(COALESCE((metadata->>'contributions_this_year')::numeric, 0) + $2)
+		SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object(
+			'contributions_this_year', $2::numeric,
+			'contribution_limit', $3::numeric
 		),
 		updated_at = NOW()
 		WHERE id = $1
 	`
 
-	_, err := r.pool.Exec(ctx, query, portfolioID, amount)
+	_, err := r.pool.Exec(ctx, query, portfolioID, contributed, limit)
 	return err
 }
 
+// IsTaxSheltered returns true if income and gains inside the portfolio type are free of UK tax
+func IsTaxSheltered(portfolioType string) bool {
+	switch portfolioType {
+	case models.PortfolioTypeISA, models.PortfolioTypeLISA, models.PortfolioTypeJISA, models.PortfolioTypeSIPP:
+		return true
+	default:
+		return false
+	}
+}
+
 // HasContributionLimit returns true if the portfolio type has contribution limits
 func HasContributionLimit(portfolioType string) bool {
 	switch portfolioType {
@@ -385,3 +378,101 @@ func HasContributionLimit(portfolioType string) bool {
 		return false
 	}
 }
+
+// encodeMetadata marshals metadata for storage, encrypting the account reference
+func (r *PortfolioRepository) encodeMetadata(userID uuid.UUID, metadata *models.PortfolioMetadata) ([]byte, error) {
+	if metadata == nil {
+		return []byte("{}"), nil
+	}
+
+	stored := *metadata
+	ref, err := r.cipher.Encrypt(userID, stored.AccountReference)
+	if err != nil {
+		return nil, err
+	}
+	stored.AccountReference = ref
+
+	return json.Marshal(stored)
+}
+
+// decodeMetadata unmarshals stored metadata, decrypting the account reference. A
+// reference that can't be decrypted is left blank rather than failing the read.
+func (r *PortfolioRepository) decodeMetadata(userID uuid.UUID, metadataJSON []byte) *models.PortfolioMetadata {
+	if len(metadataJSON) == 0 || string(metadataJSON) == "{}" {
+		return nil
+	}
+
+	var metadata models.PortfolioMetadata
+	if err := json.Unmarshal(metadataJSON, &metadata); err != nil {
+		return nil
+	}
+
+	ref, err := r.cipher.Decrypt(userID, metadata.AccountReference)
+	if err != nil {
+		ref = ""
+	}
+	metadata.AccountReference = ref
+
+	return &metadata
+}
+
+// EncryptExisting encrypts account references stored before encryption was enabled,
+// returning how many portfolios were updated
+func (r *PortfolioRepository) EncryptExisting(ctx context.Context) (int, error) {
+	if !r.cipher.Enabled() {
+		return 0, errors.New("field encryption key not configured")
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, user_id, metadata
+		FROM portfolios
+		WHERE metadata->>'account_reference' <> '' AND metadata->>'account_reference' NOT LIKE 'enc:%'
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	type pending struct {
+		id       uuid.UUID
+		userID   uuid.UUID
+		metadata []byte
+	}
+	var todo []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.userID, &p.metadata); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		todo = append(todo, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, p := range todo {
+		// Patch only the reference so metadata fields unknown to this version survive
+		var raw map[string]interface{}
+		if err := json.Unmarshal(p.metadata, &raw); err != nil {
+			return updated, err
+		}
+		ref, _ := raw["account_reference"].(string)
+		enc, err := r.cipher.Encrypt(p.userID, ref)
+		if err != nil {
+			return updated, err
+		}
+
+		_, err = r.pool.Exec(ctx,
+			`UPDATE portfolios SET metadata = jsonb_set(metadata, '{account_reference}', to_jsonb($2::text)) WHERE id = $1`,
+			p.id, enc,
+		)
+		if err != nil {
+			return updated, err
+		}
+		updated++
+	}
+
+	return updated, nil
+}
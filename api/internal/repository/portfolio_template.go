@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var (
+	ErrPortfolioTemplateNotFound      = errors.New("portfolio template not found")
+	ErrPortfolioTemplateAlreadyExists = errors.New("portfolio template with this name already exists")
+)
+
+type PortfolioTemplateRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPortfolioTemplateRepository(pool *pgxpool.Pool) *PortfolioTemplateRepository {
+	return &PortfolioTemplateRepository{pool: pool}
+}
+
+func (r *PortfolioTemplateRepository) Create(ctx context.Context, template *models.PortfolioTemplate) error {
+	targetsJSON, err := json.Marshal(template.Targets)
+	if err != nil {
+		return err
+	}
+
+	template.ID = uuid.New()
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = time.Now()
+
+	query := `
+		INSERT INTO portfolio_templates (id, user_id, name, description, targets, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err = r.pool.Exec(ctx, query,
+		template.ID,
+		template.UserID,
+		template.Name,
+		template.Description,
+		targetsJSON,
+		template.CreatedAt,
+		template.UpdatedAt,
+	)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrPortfolioTemplateAlreadyExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *PortfolioTemplateRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.PortfolioTemplate, error) {
+	query := `
+		SELECT id, user_id, name, description, targets, created_at, updated_at
+		FROM portfolio_templates
+		WHERE id = $1
+	`
+
+	template, err := scanPortfolioTemplate(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPortfolioTemplateNotFound
+		}
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func (r *PortfolioTemplateRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.PortfolioTemplate, error) {
+	query := `
+		SELECT id, user_id, name, description, targets, created_at, updated_at
+		FROM portfolio_templates
+		WHERE user_id = $1
+		ORDER BY name
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*models.PortfolioTemplate
+	for rows.Next() {
+		template, err := scanPortfolioTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+func (r *PortfolioTemplateRepository) Update(ctx context.Context, template *models.PortfolioTemplate) error {
+	targetsJSON, err := json.Marshal(template.Targets)
+	if err != nil {
+		return err
+	}
+
+	template.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE portfolio_templates
+		SET name = $2, description = $3, targets = $4, updated_at = $5
+		WHERE id = $1
+	`
+
+	result, err := r.pool.Exec(ctx, query, template.ID, template.Name, template.Description, targetsJSON, template.UpdatedAt)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrPortfolioTemplateAlreadyExists
+		}
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPortfolioTemplateNotFound
+	}
+
+	return nil
+}
+
+func (r *PortfolioTemplateRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM portfolio_templates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPortfolioTemplateNotFound
+	}
+	return nil
+}
+
+func scanPortfolioTemplate(row pgx.Row) (*models.PortfolioTemplate, error) {
+	var template models.PortfolioTemplate
+	var targetsJSON []byte
+
+	err := row.Scan(
+		&template.ID,
+		&template.UserID,
+		&template.Name,
+		&template.Description,
+		&targetsJSON,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(targetsJSON, &template.Targets); err != nil {
+		return nil, err
+	}
+
+	return &template, nil
+}
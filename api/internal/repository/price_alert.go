@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var ErrPriceAlertNotFound = errors.New("price alert not found")
+
+const priceAlertColumns = `
+	pa.id, pa.user_id, pa.asset_id, pa.condition, pa.threshold, COALESCE(pa.note, ''), pa.is_active,
+	pa.last_triggered_at, pa.last_triggered_price, pa.created_at, pa.updated_at,
+	a.symbol, a.name, a.exchange, a.currency, a.last_price`
+
+type PriceAlertRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPriceAlertRepository(pool *pgxpool.Pool) *PriceAlertRepository {
+	return &PriceAlertRepository{pool: pool}
+}
+
+func (r *PriceAlertRepository) Create(ctx context.Context, alert *models.PriceAlert) error {
+	alert.ID = uuid.New()
+	alert.CreatedAt = time.Now()
+	alert.UpdatedAt = time.Now()
+
+	query := `
+		INSERT INTO price_alerts (id, user_id, asset_id, condition, threshold, note, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		alert.ID,
+		alert.UserID,
+		alert.AssetID,
+		alert.Condition,
+		alert.Threshold,
+		alert.Note,
+		alert.IsActive,
+		alert.CreatedAt,
+		alert.UpdatedAt,
+	)
+	return err
+}
+
+func (r *PriceAlertRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.PriceAlert, error) {
+	query := `
+		SELECT ` + priceAlertColumns + `
+		FROM price_alerts pa
+		JOIN assets a ON a.id = pa.asset_id
+		WHERE pa.id = $1
+	`
+
+	alert, err := scanPriceAlert(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPriceAlertNotFound
+		}
+		return nil, err
+	}
+
+	return alert, nil
+}
+
+// GetByUserID returns the user's alerts by symbol
+func (r *PriceAlertRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.PriceAlert, error) {
+	query := `
+		SELECT ` + priceAlertColumns + `
+		FROM price_alerts pa
+		JOIN assets a ON a.id = pa.asset_id
+		WHERE pa.user_id = $1
+		ORDER BY a.symbol, pa.created_at
+	`
+
+	return r.query(ctx, query, userID)
+}
+
+// GetActive returns every user's active alerts, for evaluation
+func (r *PriceAlertRepository) GetActive(ctx context.Context) ([]*models.PriceAlert, error) {
+	query := `
+		SELECT ` + priceAlertColumns + `
+		FROM price_alerts pa
+		JOIN assets a ON a.id = pa.asset_id
+		WHERE pa.is_active
+		ORDER BY a.symbol
+	`
+
+	return r.query(ctx, query)
+}
+
+func (r *PriceAlertRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.PriceAlert, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alerts []*models.PriceAlert
+	for rows.Next() {
+		alert, err := scanPriceAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, rows.Err()
+}
+
+func (r *PriceAlertRepository) Update(ctx context.Context, alert *models.PriceAlert) error {
+	alert.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE price_alerts
+		SET threshold = $2, note = $3, is_active = $4, updated_at = $5
+		WHERE id = $1
+	`
+
+	result, err := r.pool.Exec(ctx, query, alert.ID, alert.Threshold, alert.Note, alert.IsActive, alert.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPriceAlertNotFound
+	}
+
+	return nil
+}
+
+// MarkTriggered records that the alert fired at a price, switching it off if deactivate
+// is set
+func (r *PriceAlertRepository) MarkTriggered(ctx context.Context, id uuid.UUID, price float64, triggeredAt time.Time, deactivate bool) error {
+	query := `
+		UPDATE price_alerts
+		SET last_triggered_at = $2, last_triggered_price = $3, is_active = is_active AND NOT $4, updated_at = $2
+		WHERE id = $1
+	`
+
+	_, err := r.pool.Exec(ctx, query, id, triggeredAt, price, deactivate)
+	return err
+}
+
+func (r *PriceAlertRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM price_alerts WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPriceAlertNotFound
+	}
+	return nil
+}
+
+func scanPriceAlert(row pgx.Row) (*models.PriceAlert, error) {
+	var alert models.PriceAlert
+	var asset models.Asset
+	var exchange *string
+
+	err := row.Scan(
+		&alert.ID,
+		&alert.UserID,
+		&alert.AssetID,
+		&alert.Condition,
+		&alert.Threshold,
+		&alert.Note,
+		&alert.IsActive,
+		&alert.LastTriggeredAt,
+		&alert.LastTriggeredPrice,
+		&alert.CreatedAt,
+		&alert.UpdatedAt,
+		&asset.Symbol,
+		&asset.Name,
+		&exchange,
+		&asset.Currency,
+		&asset.LastPrice,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	asset.ID = alert.AssetID
+	if exchange != nil {
+		asset.Exchange = *exchange
+	}
+	alert.Asset = &asset
+
+	return &alert, nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var ErrPriceSourceRouteNotFound = errors.New("price source route not found")
+
+const priceSourceRouteColumns = `id, match_type, match_value, sources, updated_by, created_at, updated_at`
+
+type PriceSourceRouteRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPriceSourceRouteRepository(pool *pgxpool.Pool) *PriceSourceRouteRepository {
+	return &PriceSourceRouteRepository{pool: pool}
+}
+
+// GetAll returns every route, ordered by match type and value
+func (r *PriceSourceRouteRepository) GetAll(ctx context.Context) ([]*models.PriceSourceRoute, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+priceSourceRouteColumns+`
+		FROM price_source_routes
+		ORDER BY match_type, match_value
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var routes []*models.PriceSourceRoute
+	for rows.Next() {
+		route, err := scanPriceSourceRoute(rows)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+
+	return routes, rows.Err()
+}
+
+// Save creates the route, or replaces the sources of the route with the same match
+func (r *PriceSourceRouteRepository) Save(ctx context.Context, route *models.PriceSourceRoute) error {
+	now := time.Now()
+	row := r.pool.QueryRow(ctx, `
+		INSERT INTO price_source_routes (id, match_type, match_value, sources, updated_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		ON CONFLICT (match_type, match_value) DO UPDATE
+		SET sources = EXCLUDED.sources, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+		RETURNING `+priceSourceRouteColumns,
+		uuid.New(), route.MatchType, route.MatchValue, route.Sources, route.UpdatedBy, now,
+	)
+
+	saved, err := scanPriceSourceRoute(row)
+	if err != nil {
+		return err
+	}
+	*route = *saved
+	return nil
+}
+
+func (r *PriceSourceRouteRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM price_source_routes WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrPriceSourceRouteNotFound
+	}
+
+	return nil
+}
+
+func scanPriceSourceRoute(row pgx.Row) (*models.PriceSourceRoute, error) {
+	var route models.PriceSourceRoute
+	err := row.Scan(
+		&route.ID,
+		&route.MatchType,
+		&route.MatchValue,
+		&route.Sources,
+		&route.UpdatedBy,
+		&route.CreatedAt,
+		&route.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &route, nil
+}
@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var (
+	ErrReminderNotFound = errors.New("reminder not found")
+)
+
+type ReminderRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewReminderRepository(pool *pgxpool.Pool) *ReminderRepository {
+	return &ReminderRepository{pool: pool}
+}
+
+const reminderColumns = `id, user_id, source_type, source_id, due_date, text, priority, escalate_after_days, escalation_level, escalated_at, completed_at, created_at`
+
+func scanReminder(row pgx.Row) (*models.Reminder, error) {
+	var reminder models.Reminder
+	err := row.Scan(
+		&reminder.ID,
+		&reminder.UserID,
+		&reminder.SourceType,
+		&reminder.SourceID,
+		&reminder.DueDate,
+		&reminder.Text,
+		&reminder.Priority,
+		&reminder.EscalateAfterDays,
+		&reminder.EscalationLevel,
+		&reminder.EscalatedAt,
+		&reminder.CompletedAt,
+		&reminder.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	reminder.Completed = reminder.CompletedAt != nil
+	if reminder.EscalationLevel > 0 {
+		reminder.EscalationChannel = models.ReminderEscalationChannels[min(reminder.EscalationLevel, len(models.ReminderEscalationChannels))-1]
+	}
+	return &reminder, nil
+}
+
+func (r *ReminderRepository) Create(ctx context.Context, reminder *models.Reminder) error {
+	reminder.ID = uuid.New()
+	reminder.CreatedAt = time.Now()
+	if reminder.Priority == "" {
+		reminder.Priority = models.ReminderPriorityNormal
+	}
+
+	query := `
+		INSERT INTO reminders (id, user_id, source_type, source_id, due_date, text, priority, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		reminder.ID,
+		reminder.UserID,
+		reminder.SourceType,
+		reminder.SourceID,
+		reminder.DueDate,
+		reminder.Text,
+		reminder.Priority,
+		reminder.CreatedAt,
+	)
+	return err
+}
+
+func (r *ReminderRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Reminder, error) {
+	query := `SELECT ` + reminderColumns + ` FROM reminders WHERE id = $1`
+
+	reminder, err := scanReminder(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrReminderNotFound
+		}
+		return nil, err
+	}
+	return reminder, nil
+}
+
+// GetBySource returns the reminders parsed from one record's notes
+func (r *ReminderRepository) GetBySource(ctx context.Context, sourceType string, sourceID uuid.UUID) ([]*models.Reminder, error) {
+	query := `SELECT ` + reminderColumns + ` FROM reminders WHERE source_type = $1 AND source_id = $2`
+
+	rows, err := r.pool.Query(ctx, query, sourceType, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []*models.Reminder
+	for rows.Next() {
+		reminder, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, rows.Err()
+}
+
+// GetByUserID returns the user's reminders by due date. Reminders whose source record
+// has since been deleted (e.g. with its portfolio) are skipped.
+func (r *ReminderRepository) GetByUserID(ctx context.Context, userID uuid.UUID, includeCompleted bool) ([]*models.Reminder, error) {
+	query := `
+		SELECT ` + reminderColumns + `
+		FROM reminders r
+		WHERE r.user_id = $1
+		  AND ($2 OR r.completed_at IS NULL)
+		  AND CASE r.source_type
+				WHEN 'SAVINGS_GOAL' THEN EXISTS(SELECT 1 FROM savings_goals WHERE id = r.source_id)
+				WHEN 'FIXED_ASSET' THEN EXISTS(SELECT 1 FROM fixed_assets WHERE id = r.source_id)
+				WHEN 'TRANSACTION' THEN EXISTS(SELECT 1 FROM transactions WHERE id = r.source_id)
+				ELSE TRUE
+			  END
+		ORDER BY r.due_date, r.created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, includeCompleted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []*models.Reminder
+	for rows.Next() {
+		reminder, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, rows.Err()
+}
+
+// SetCompleted marks a reminder as done, or reopens it
+func (r *ReminderRepository) SetCompleted(ctx context.Context, id uuid.UUID, completed bool) error {
+	var completedAt *time.Time
+	if completed {
+		now := time.Now()
+		completedAt = &now
+	}
+
+	result, err := r.pool.Exec(ctx, `UPDATE reminders SET completed_at = $2 WHERE id = $1`, id, completedAt)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrReminderNotFound
+	}
+	return nil
+}
+
+// SetEscalation sets a reminder's priority and how many days it may go uncompleted
+// before each escalation; nil falls back to the user's default for the source type
+func (r *ReminderRepository) SetEscalation(ctx context.Context, id uuid.UUID, priority string, escalateAfterDays *int) error {
+	result, err := r.pool.Exec(ctx, `UPDATE reminders SET priority = $2, escalate_after_days = $3 WHERE id = $1`, id, priority, escalateAfterDays)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrReminderNotFound
+	}
+	return nil
+}
+
+// GetEscalationDue returns the open HIGH and URGENT reminders due another escalation on
+// day: each escalation comes a full period (the reminder's own, or the user's default
+// for its source type) after the due date or the one before it
+func (r *ReminderRepository) GetEscalationDue(ctx context.Context, day time.Time) ([]*models.Reminder, error) {
+	query := `
+		SELECT r.id, r.user_id, r.source_type, r.source_id, r.due_date, r.text, r.priority, r.escalate_after_days, r.escalation_level, r.escalated_at, r.completed_at, r.created_at
+		FROM reminders r
+		LEFT JOIN reminder_escalation_defaults d ON d.user_id = r.user_id AND d.source_type = r.source_type
+		WHERE r.completed_at IS NULL
+		  AND r.priority IN ('HIGH', 'URGENT')
+		  AND r.escalation_level < $2
+		  AND COALESCE(r.escalate_after_days, d.escalate_after_days) > 0
+		  AND r.due_date + COALESCE(r.escalate_after_days, d.escalate_after_days) * (r.escalation_level + 1) <= $1
+		ORDER BY r.due_date
+	`
+
+	rows, err := r.pool.Query(ctx, query, day, len(models.ReminderEscalationChannels))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []*models.Reminder
+	for rows.Next() {
+		reminder, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, rows.Err()
+}
+
+// Escalate raises a reminder to the next escalation level and URGENT priority. It
+// returns false if the reminder has been escalated or completed meanwhile.
+func (r *ReminderRepository) Escalate(ctx context.Context, reminder *models.Reminder) (bool, error) {
+	now := time.Now()
+	result, err := r.pool.Exec(ctx, `
+		UPDATE reminders
+		SET escalation_level = escalation_level + 1, priority = $3, escalated_at = $4
+		WHERE id = $1 AND escalation_level = $2 AND completed_at IS NULL
+	`, reminder.ID, reminder.EscalationLevel, models.ReminderPriorityUrgent, now)
+	if err != nil {
+		return false, err
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	reminder.EscalationLevel++
+	reminder.EscalationChannel = models.ReminderEscalationChannels[reminder.EscalationLevel-1]
+	reminder.Priority = models.ReminderPriorityUrgent
+	reminder.EscalatedAt = &now
+	return true, nil
+}
+
+// GetEscalationDefaults returns the user's escalation period for each source type
+func (r *ReminderRepository) GetEscalationDefaults(ctx context.Context, userID uuid.UUID) ([]*models.ReminderEscalationDefault, error) {
+	query := `
+		SELECT source_type, escalate_after_days, updated_at
+		FROM reminder_escalation_defaults
+		WHERE user_id = $1
+		ORDER BY source_type
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var defaults []*models.ReminderEscalationDefault
+	for rows.Next() {
+		var d models.ReminderEscalationDefault
+		if err := rows.Scan(&d.SourceType, &d.EscalateAfterDays, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		defaults = append(defaults, &d)
+	}
+
+	return defaults, rows.Err()
+}
+
+// SaveEscalationDefault sets the user's escalation period for a source type
+func (r *ReminderRepository) SaveEscalationDefault(ctx context.Context, userID uuid.UUID, d *models.ReminderEscalationDefault) error {
+	d.UpdatedAt = time.Now()
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO reminder_escalation_defaults (user_id, source_type, escalate_after_days, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, source_type) DO UPDATE
+		SET escalate_after_days = EXCLUDED.escalate_after_days, updated_at = EXCLUDED.updated_at
+	`, userID, d.SourceType, d.EscalateAfterDays, d.UpdatedAt)
+	return err
+}
+
+// DeleteEscalationDefault stops the source type's reminders escalating unless they set
+// their own period
+func (r *ReminderRepository) DeleteEscalationDefault(ctx context.Context, userID uuid.UUID, sourceType string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM reminder_escalation_defaults WHERE user_id = $1 AND source_type = $2`, userID, sourceType)
+	return err
+}
+
+func (r *ReminderRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM reminders WHERE id = $1`, id)
+	return err
+}
+
+// DeleteBySource removes every reminder belonging to a record
+func (r *ReminderRepository) DeleteBySource(ctx context.Context, sourceType string, sourceID uuid.UUID) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM reminders WHERE source_type = $1 AND source_id = $2`, sourceType, sourceID)
+	return err
+}
+
+func (r *ReminderRepository) BelongsToUser(ctx context.Context, reminderID, userID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM reminders WHERE id = $1 AND user_id = $2)`
+
+	var exists bool
+	err := r.pool.QueryRow(ctx, query, reminderID, userID).Scan(&exists)
+	return exists, err
+}
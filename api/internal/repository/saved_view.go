@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var (
+	ErrSavedViewNotFound      = errors.New("saved view not found")
+	ErrSavedViewAlreadyExists = errors.New("saved view with this name already exists")
+)
+
+type SavedViewRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewSavedViewRepository(pool *pgxpool.Pool) *SavedViewRepository {
+	return &SavedViewRepository{pool: pool}
+}
+
+func (r *SavedViewRepository) Create(ctx context.Context, view *models.SavedView) error {
+	filtersJSON, sortJSON, err := marshalViewSettings(view)
+	if err != nil {
+		return err
+	}
+
+	view.ID = uuid.New()
+	view.CreatedAt = time.Now()
+	view.UpdatedAt = time.Now()
+
+	query := `
+		INSERT INTO saved_views (id, user_id, module, name, filters, sort, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err = r.pool.Exec(ctx, query,
+		view.ID,
+		view.UserID,
+		view.Module,
+		view.Name,
+		filtersJSON,
+		sortJSON,
+		view.CreatedAt,
+		view.UpdatedAt,
+	)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrSavedViewAlreadyExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *SavedViewRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.SavedView, error) {
+	query := `
+		SELECT id, user_id, module, name, filters, sort, created_at, updated_at
+		FROM saved_views
+		WHERE id = $1
+	`
+
+	view, err := scanSavedView(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSavedViewNotFound
+		}
+		return nil, err
+	}
+
+	return view, nil
+}
+
+// GetByUserID returns the user's views, optionally limited to one module
+func (r *SavedViewRepository) GetByUserID(ctx context.Context, userID uuid.UUID, module string) ([]*models.SavedView, error) {
+	query := `
+		SELECT id, user_id, module, name, filters, sort, created_at, updated_at
+		FROM saved_views
+		WHERE user_id = $1 AND ($2 = '' OR module = $2)
+		ORDER BY module, name
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, module)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []*models.SavedView
+	for rows.Next() {
+		view, err := scanSavedView(rows)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+
+	return views, rows.Err()
+}
+
+func (r *SavedViewRepository) Update(ctx context.Context, view *models.SavedView) error {
+	filtersJSON, sortJSON, err := marshalViewSettings(view)
+	if err != nil {
+		return err
+	}
+
+	view.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE saved_views
+		SET name = $2, filters = $3, sort = $4, updated_at = $5
+		WHERE id = $1
+	`
+
+	result, err := r.pool.Exec(ctx, query, view.ID, view.Name, filtersJSON, sortJSON, view.UpdatedAt)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrSavedViewAlreadyExists
+		}
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrSavedViewNotFound
+	}
+
+	return nil
+}
+
+func (r *SavedViewRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM saved_views WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrSavedViewNotFound
+	}
+	return nil
+}
+
+func marshalViewSettings(view *models.SavedView) ([]byte, []byte, error) {
+	filtersJSON, err := json.Marshal(view.Filters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var sortJSON []byte
+	if view.Sort != nil {
+		sortJSON, err = json.Marshal(view.Sort)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return filtersJSON, sortJSON, nil
+}
+
+func scanSavedView(row pgx.Row) (*models.SavedView, error) {
+	var view models.SavedView
+	var filtersJSON, sortJSON []byte
+
+	err := row.Scan(
+		&view.ID,
+		&view.UserID,
+		&view.Module,
+		&view.Name,
+		&filtersJSON,
+		&sortJSON,
+		&view.CreatedAt,
+		&view.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(filtersJSON, &view.Filters); err != nil {
+		return nil, err
+	}
+	if len(sortJSON) > 0 {
+		var sort models.SavedViewSort
+		if err := json.Unmarshal(sortJSON, &sort); err != nil {
+			return nil, err
+		}
+		view.Sort = &sort
+	}
+
+	return &view, nil
+}
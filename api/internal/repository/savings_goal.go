@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var (
+	ErrSavingsGoalNotFound = errors.New("savings goal not found")
+)
+
+type SavingsGoalRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewSavingsGoalRepository(pool *pgxpool.Pool) *SavingsGoalRepository {
+	return &SavingsGoalRepository{pool: pool}
+}
+
+func (r *SavingsGoalRepository) Create(ctx context.Context, goal *models.SavingsGoal) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	goal.ID = uuid.New()
+	goal.CreatedAt = time.Now()
+	goal.UpdatedAt = time.Now()
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO savings_goals (id, user_id, name, target_amount, currency, target_date, priority, notes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`,
+		goal.ID,
+		goal.UserID,
+		goal.Name,
+		goal.TargetAmount,
+		goal.Currency,
+		goal.TargetDate,
+		goal.Priority,
+		goal.Notes,
+		goal.CreatedAt,
+		goal.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := insertGoalAllocations(ctx, tx, goal); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *SavingsGoalRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.SavingsGoal, error) {
+	query := `
+		SELECT id, user_id, name, target_amount, currency, target_date, priority, COALESCE(notes, ''), created_at, updated_at
+		FROM savings_goals
+		WHERE id = $1
+	`
+
+	var goal models.SavingsGoal
+	err := r.pool.QueryRow(ctx, query, id).Scan(
+		&goal.ID,
+		&goal.UserID,
+		&goal.Name,
+		&goal.TargetAmount,
+		&goal.Currency,
+		&goal.TargetDate,
+		&goal.Priority,
+		&goal.Notes,
+		&goal.CreatedAt,
+		&goal.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSavingsGoalNotFound
+		}
+		return nil, err
+	}
+
+	if err := r.loadAllocations(ctx, []*models.SavingsGoal{&goal}); err != nil {
+		return nil, err
+	}
+
+	return &goal, nil
+}
+
+// GetByUserID returns the user's goals ordered by priority, then target date
+func (r *SavingsGoalRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.SavingsGoal, error) {
+	query := `
+		SELECT id, user_id, name, target_amount, currency, target_date, priority, COALESCE(notes, ''), created_at, updated_at
+		FROM savings_goals
+		WHERE user_id = $1
+		ORDER BY priority, target_date NULLS LAST, created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var goals []*models.SavingsGoal
+	for rows.Next() {
+		var goal models.SavingsGoal
+		err := rows.Scan(
+			&goal.ID,
+			&goal.UserID,
+			&goal.Name,
+			&goal.TargetAmount,
+			&goal.Currency,
+			&goal.TargetDate,
+			&goal.Priority,
+			&goal.Notes,
+			&goal.CreatedAt,
+			&goal.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, &goal)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadAllocations(ctx, goals); err != nil {
+		return nil, err
+	}
+
+	return goals, nil
+}
+
+// Update saves the goal and replaces its allocations
+func (r *SavingsGoalRepository) Update(ctx context.Context, goal *models.SavingsGoal) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	goal.UpdatedAt = time.Now()
+
+	result, err := tx.Exec(ctx, `
+		UPDATE savings_goals
+		SET name = $2, target_amount = $3, currency = $4, target_date = $5, priority = $6, notes = $7, updated_at = $8
+		WHERE id = $1
+	`,
+		goal.ID,
+		goal.Name,
+		goal.TargetAmount,
+		goal.Currency,
+		goal.TargetDate,
+		goal.Priority,
+		goal.Notes,
+		goal.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrSavingsGoalNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM savings_goal_allocations WHERE goal_id = $1`, goal.ID); err != nil {
+		return err
+	}
+	if err := insertGoalAllocations(ctx, tx, goal); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *SavingsGoalRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM savings_goals WHERE id = $1`
+
+	result, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrSavingsGoalNotFound
+	}
+
+	return nil
+}
+
+func (r *SavingsGoalRepository) BelongsToUser(ctx context.Context, goalID, userID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM savings_goals WHERE id = $1 AND user_id = $2)`
+
+	var exists bool
+	err := r.pool.QueryRow(ctx, query, goalID, userID).Scan(&exists)
+	return exists, err
+}
+
+// GetAllocatedPercentages returns how much of each cash account and portfolio is already
+// allocated to the user's goals, ignoring excludeGoalID (the goal being edited)
+func (r *SavingsGoalRepository) GetAllocatedPercentages(ctx context.Context, userID, excludeGoalID uuid.UUID) (map[uuid.UUID]float64, error) {
+	query := `
+		SELECT COALESCE(a.cash_account_id, a.portfolio_id), SUM(a.percentage)
+		FROM savings_goal_allocations a
+		JOIN savings_goals g ON g.id = a.goal_id
+		WHERE g.user_id = $1 AND g.id <> $2
+		GROUP BY COALESCE(a.cash_account_id, a.portfolio_id)
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, excludeGoalID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allocated := make(map[uuid.UUID]float64)
+	for rows.Next() {
+		var id uuid.UUID
+		var pct float64
+		if err := rows.Scan(&id, &pct); err != nil {
+			return nil, err
+		}
+		allocated[id] = pct
+	}
+
+	return allocated, rows.Err()
+}
+
+// UpsertSnapshot records the goal's amount for the given day
+func (r *SavingsGoalRepository) UpsertSnapshot(ctx context.Context, goalID uuid.UUID, date time.Time, amount float64) error {
+	query := `
+		INSERT INTO savings_goal_snapshots (goal_id, snapshot_date, amount)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (goal_id, snapshot_date) DO UPDATE SET amount = EXCLUDED.amount
+	`
+
+	_, err := r.pool.Exec(ctx, query, goalID, date, amount)
+	return err
+}
+
+// GetEarliestSnapshotSince returns the oldest recorded amount on or after since, if any
+func (r *SavingsGoalRepository) GetEarliestSnapshotSince(ctx context.Context, goalID uuid.UUID, since time.Time) (*time.Time, float64, error) {
+	query := `
+		SELECT snapshot_date, amount
+		FROM savings_goal_snapshots
+		WHERE goal_id = $1 AND snapshot_date >= $2
+		ORDER BY snapshot_date ASC
+		LIMIT 1
+	`
+
+	var date time.Time
+	var amount float64
+	err := r.pool.QueryRow(ctx, query, goalID, since).Scan(&date, &amount)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+
+	return &date, amount, nil
+}
+
+// loadAllocations fills in the allocations for the given goals
+func (r *SavingsGoalRepository) loadAllocations(ctx context.Context, goals []*models.SavingsGoal) error {
+	if len(goals) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(goals))
+	byID := make(map[uuid.UUID]*models.SavingsGoal, len(goals))
+	for i, g := range goals {
+		ids[i] = g.ID
+		byID[g.ID] = g
+		g.Allocations = []models.SavingsGoalAllocation{}
+	}
+
+	query := `
+		SELECT a.id, a.goal_id, a.cash_account_id, a.portfolio_id, a.percentage,
+			   COALESCE(ca.account_name, p.name, '')
+		FROM savings_goal_allocations a
+		LEFT JOIN cash_accounts ca ON ca.id = a.cash_account_id
+		LEFT JOIN portfolios p ON p.id = a.portfolio_id
+		WHERE a.goal_id = ANY($1)
+	`
+
+	rows, err := r.pool.Query(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.SavingsGoalAllocation
+		if err := rows.Scan(&a.ID, &a.GoalID, &a.CashAccountID, &a.PortfolioID, &a.Percentage, &a.Name); err != nil {
+			return err
+		}
+		byID[a.GoalID].Allocations = append(byID[a.GoalID].Allocations, a)
+	}
+
+	return rows.Err()
+}
+
+func insertGoalAllocations(ctx context.Context, tx pgx.Tx, goal *models.SavingsGoal) error {
+	for i := range goal.Allocations {
+		a := &goal.Allocations[i]
+		a.ID = uuid.New()
+		a.GoalID = goal.ID
+		_, err := tx.Exec(ctx, `
+			INSERT INTO savings_goal_allocations (id, goal_id, cash_account_id, portfolio_id, percentage)
+			VALUES ($1, $2, $3, $4, $5)
+		`, a.ID, a.GoalID, a.CashAccountID, a.PortfolioID, a.Percentage)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrSettingNotFound = errors.New("setting not found")
+)
+
+type SettingsRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewSettingsRepository(pool *pgxpool.Pool) *SettingsRepository {
+	return &SettingsRepository{pool: pool}
+}
+
+// GetAll returns every persisted setting override keyed by setting name
+func (r *SettingsRepository) GetAll(ctx context.Context) (map[string]json.RawMessage, error) {
+	query := `SELECT key, value FROM app_settings`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make(map[string]json.RawMessage)
+	for rows.Next() {
+		var key string
+		var value []byte
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		settings[key] = json.RawMessage(value)
+	}
+
+	return settings, rows.Err()
+}
+
+// SaveAll creates or replaces the setting overrides in one database transaction. The
+// values of keys in merge are JSON objects merged into the stored object rather than
+// replacing it, so concurrent updates to different entries keep each other's changes.
+func (r *SettingsRepository) SaveAll(ctx context.Context, values map[string]json.RawMessage, merge map[string]bool, updatedBy uuid.UUID) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	for key, value := range values {
+		set := `EXCLUDED.value`
+		if merge[key] {
+			set = `app_settings.value || EXCLUDED.value`
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO app_settings (key, value, updated_by, updated_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (key) DO UPDATE
+			SET value = `+set+`, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+		`, key, []byte(value), updatedBy, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Delete removes a setting override so the env default applies again
+func (r *SettingsRepository) Delete(ctx context.Context, key string) error {
+	query := `DELETE FROM app_settings WHERE key = $1`
+
+	result, err := r.pool.Exec(ctx, query, key)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrSettingNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+type SnapshotRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewSnapshotRepository(pool *pgxpool.Pool) *SnapshotRepository {
+	return &SnapshotRepository{pool: pool}
+}
+
+// Upsert records the snapshot for the user and date, replacing any earlier snapshot for the same day
+func (r *SnapshotRepository) Upsert(ctx context.Context, snapshot *models.NetWorthSnapshot) error {
+	query := `
+		INSERT INTO net_worth_snapshots (id, user_id, snapshot_date, total_net_worth, investments, cash, fixed_assets, liabilities, currency, portfolio_values, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (user_id, snapshot_date) DO UPDATE
+		SET total_net_worth = EXCLUDED.total_net_worth,
+			investments = EXCLUDED.investments,
+			cash = EXCLUDED.cash,
+			fixed_assets = EXCLUDED.fixed_assets,
+			liabilities = EXCLUDED.liabilities,
+			currency = EXCLUDED.currency,
+			portfolio_values = EXCLUDED.portfolio_values,
+			updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at
+	`
+
+	now := time.Now()
+	snapshot.ID = uuid.New()
+	snapshot.CreatedAt = now
+	snapshot.UpdatedAt = now
+
+	if snapshot.PortfolioValues == nil {
+		snapshot.PortfolioValues = make(map[uuid.UUID]float64)
+	}
+	valuesJSON, err := json.Marshal(snapshot.PortfolioValues)
+	if err != nil {
+		return err
+	}
+
+	return r.pool.QueryRow(ctx, query,
+		snapshot.ID,
+		snapshot.UserID,
+		snapshot.SnapshotDate,
+		snapshot.TotalNetWorth,
+		snapshot.Investments,
+		snapshot.Cash,
+		snapshot.FixedAssets,
+		snapshot.Liabilities,
+		snapshot.Currency,
+		valuesJSON,
+		snapshot.CreatedAt,
+		snapshot.UpdatedAt,
+	).Scan(&snapshot.ID, &snapshot.CreatedAt)
+}
+
+// GetByUserIDInRange returns the user's snapshots between from and to (inclusive), oldest first
+func (r *SnapshotRepository) GetByUserIDInRange(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]*models.NetWorthSnapshot, error) {
+	query := `
+		SELECT id, user_id, snapshot_date, total_net_worth, investments, cash, fixed_assets, liabilities, currency, portfolio_values, created_at, updated_at
+		FROM net_worth_snapshots
+		WHERE user_id = $1 AND snapshot_date >= $2 AND snapshot_date <= $3
+		ORDER BY snapshot_date ASC
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []*models.NetWorthSnapshot
+	for rows.Next() {
+		var s models.NetWorthSnapshot
+		var valuesJSON []byte
+		err := rows.Scan(
+			&s.ID,
+			&s.UserID,
+			&s.SnapshotDate,
+			&s.TotalNetWorth,
+			&s.Investments,
+			&s.Cash,
+			&s.FixedAssets,
+			&s.Liabilities,
+			&s.Currency,
+			&valuesJSON,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		s.PortfolioValues = make(map[uuid.UUID]float64)
+		if len(valuesJSON) > 0 {
+			_ = json.Unmarshal(valuesJSON, &s.PortfolioValues)
+		}
+
+		snapshots = append(snapshots, &s)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// GetEarliestDate returns the date of the user's first snapshot, or nil if none exist
+func (r *SnapshotRepository) GetEarliestDate(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	query := `SELECT MIN(snapshot_date) FROM net_worth_snapshots WHERE user_id = $1`
+
+	var earliest *time.Time
+	err := r.pool.QueryRow(ctx, query, userID).Scan(&earliest)
+	return earliest, err
+}
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var ErrStandingOrderNotFound = errors.New("standing order not found")
+
+const standingOrderColumns = `
+	so.id, so.user_id, so.portfolio_id, so.asset_id, so.order_type, so.amount, so.currency, so.day_of_month,
+	so.next_run_date, so.end_date, COALESCE(so.note, ''), so.is_active, so.last_run_at, so.last_transaction_id,
+	COALESCE(so.last_error, ''), so.created_at, so.updated_at,
+	a.symbol, a.name, a.exchange, a.currency, a.last_price`
+
+type StandingOrderRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewStandingOrderRepository(pool *pgxpool.Pool) *StandingOrderRepository {
+	return &StandingOrderRepository{pool: pool}
+}
+
+func (r *StandingOrderRepository) Create(ctx context.Context, order *models.StandingOrder) error {
+	order.ID = uuid.New()
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+
+	query := `
+		INSERT INTO standing_orders (id, user_id, portfolio_id, asset_id, order_type, amount, currency, day_of_month, next_run_date, end_date, note, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		order.ID,
+		order.UserID,
+		order.PortfolioID,
+		order.AssetID,
+		order.OrderType,
+		order.Amount,
+		order.Currency,
+		order.DayOfMonth,
+		order.NextRunDate,
+		order.EndDate,
+		order.Note,
+		order.IsActive,
+		order.CreatedAt,
+		order.UpdatedAt,
+	)
+	return err
+}
+
+func (r *StandingOrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.StandingOrder, error) {
+	query := `
+		SELECT ` + standingOrderColumns + `
+		FROM standing_orders so
+		LEFT JOIN assets a ON a.id = so.asset_id
+		WHERE so.id = $1
+	`
+
+	order, err := scanStandingOrder(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrStandingOrderNotFound
+		}
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// GetByUserID returns the user's standing orders, next to run first
+func (r *StandingOrderRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.StandingOrder, error) {
+	query := `
+		SELECT ` + standingOrderColumns + `
+		FROM standing_orders so
+		LEFT JOIN assets a ON a.id = so.asset_id
+		WHERE so.user_id = $1
+		ORDER BY so.is_active DESC, so.next_run_date, so.created_at
+	`
+
+	return r.query(ctx, query, userID)
+}
+
+// GetDue returns every user's active standing orders due to run on or before the date
+func (r *StandingOrderRepository) GetDue(ctx context.Context, date time.Time) ([]*models.StandingOrder, error) {
+	query := `
+		SELECT ` + standingOrderColumns + `
+		FROM standing_orders so
+		LEFT JOIN assets a ON a.id = so.asset_id
+		WHERE so.is_active AND so.next_run_date <= $1
+		ORDER BY so.next_run_date
+	`
+
+	return r.query(ctx, query, date)
+}
+
+func (r *StandingOrderRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.StandingOrder, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*models.StandingOrder
+	for rows.Next() {
+		order, err := scanStandingOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
+
+// Update saves the order's schedule and details as edited by the user
+func (r *StandingOrderRepository) Update(ctx context.Context, order *models.StandingOrder) error {
+	order.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE standing_orders
+		SET amount = $2, day_of_month = $3, next_run_date = $4, end_date = $5, note = $6, is_active = $7, updated_at = $8
+		WHERE id = $1
+	`
+
+	result, err := r.pool.Exec(ctx, query,
+		order.ID,
+		order.Amount,
+		order.DayOfMonth,
+		order.NextRunDate,
+		order.EndDate,
+		order.Note,
+		order.IsActive,
+		order.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrStandingOrderNotFound
+	}
+
+	return nil
+}
+
+// RecordRun saves the outcome of a run: the transaction it created, or why it failed,
+// and when it runs next. The order is switched off when nextRunDate is past its end date.
+func (r *StandingOrderRepository) RecordRun(ctx context.Context, order *models.StandingOrder) error {
+	query := `
+		UPDATE standing_orders
+		SET next_run_date = $2, is_active = is_active AND (end_date IS NULL OR $2 <= end_date),
+			last_run_at = $3, last_transaction_id = COALESCE($4, last_transaction_id), last_error = $5, updated_at = $3
+		WHERE id = $1
+		RETURNING is_active
+	`
+
+	return r.pool.QueryRow(ctx, query,
+		order.ID,
+		order.NextRunDate,
+		order.LastRunAt,
+		order.LastTransactionID,
+		order.LastError,
+	).Scan(&order.IsActive)
+}
+
+func (r *StandingOrderRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM standing_orders WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrStandingOrderNotFound
+	}
+	return nil
+}
+
+func scanStandingOrder(row pgx.Row) (*models.StandingOrder, error) {
+	var order models.StandingOrder
+	var symbol, name, exchange, currency *string
+	var lastPrice *float64
+
+	err := row.Scan(
+		&order.ID,
+		&order.UserID,
+		&order.PortfolioID,
+		&order.AssetID,
+		&order.OrderType,
+		&order.Amount,
+		&order.Currency,
+		&order.DayOfMonth,
+		&order.NextRunDate,
+		&order.EndDate,
+		&order.Note,
+		&order.IsActive,
+		&order.LastRunAt,
+		&order.LastTransactionID,
+		&order.LastError,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+		&symbol,
+		&name,
+		&exchange,
+		&currency,
+		&lastPrice,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.AssetID != nil && symbol != nil {
+		asset := &models.Asset{ID: *order.AssetID, Symbol: *symbol, LastPrice: lastPrice}
+		if name != nil {
+			asset.Name = *name
+		}
+		if exchange != nil {
+			asset.Exchange = *exchange
+		}
+		if currency != nil {
+			asset.Currency = *currency
+		}
+		order.Asset = asset
+	}
+
+	return &order, nil
+}
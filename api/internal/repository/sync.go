@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+// SyncDomains are the tables whose changes are recorded in sync_changes
+var SyncDomains = []string{
+	"portfolios",
+	"holdings",
+	"transactions",
+	"cash_accounts",
+	"fixed_assets",
+	"savings_goals",
+	"reminders",
+	"saved_views",
+}
+
+// syncSettleDelay holds back the newest changes so a write that is still committing
+// with a lower sequence number isn't skipped by a cursor that has moved past it
+const syncSettleDelay = 5 * time.Second
+
+type SyncRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewSyncRepository(pool *pgxpool.Pool) *SyncRepository {
+	return &SyncRepository{pool: pool}
+}
+
+// GetLatestSeq returns the cursor position for a client that has just downloaded everything
+func (r *SyncRepository) GetLatestSeq(ctx context.Context, userID uuid.UUID) (int64, error) {
+	query := `SELECT COALESCE(MAX(seq), 0) FROM sync_changes WHERE user_id = $1 AND changed_at <= $2`
+
+	var seq int64
+	err := r.pool.QueryRow(ctx, query, userID, time.Now().Add(-syncSettleDelay)).Scan(&seq)
+	return seq, err
+}
+
+// GetChanges returns the latest change per entity after the since cursor, oldest first.
+// Entities changed several times appear once with their last operation.
+func (r *SyncRepository) GetChanges(ctx context.Context, userID uuid.UUID, since int64, domains []string, limit int) ([]*models.SyncChange, error) {
+	query := `
+		SELECT seq, domain, entity_id, operation, changed_at
+		FROM (
+			SELECT DISTINCT ON (domain, entity_id) seq, domain, entity_id, operation, changed_at
+			FROM sync_changes
+			WHERE user_id = $1 AND seq > $2 AND domain = ANY($3) AND changed_at <= $4
+			ORDER BY domain, entity_id, seq DESC
+		) latest
+		ORDER BY seq
+		LIMIT $5
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, since, domains, time.Now().Add(-syncSettleDelay), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*models.SyncChange
+	for rows.Next() {
+		var c models.SyncChange
+		if err := rows.Scan(&c.Seq, &c.Domain, &c.EntityID, &c.Operation, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, &c)
+	}
+
+	return changes, rows.Err()
+}
+
+// GetRecords returns the stored rows of one domain as JSON, keyed by ID. domain must be
+// one of SyncDomains as it is used as the table name.
+func (r *SyncRepository) GetRecords(ctx context.Context, domain string, ids []uuid.UUID) (map[uuid.UUID]json.RawMessage, error) {
+	query := `SELECT id, to_jsonb(t) FROM ` + domain + ` t WHERE id = ANY($1)`
+
+	rows, err := r.pool.Query(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make(map[uuid.UUID]json.RawMessage, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		records[id] = json.RawMessage(data)
+	}
+
+	return records, rows.Err()
+}
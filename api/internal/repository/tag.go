@@ -0,0 +1,297 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("tag already exists")
+)
+
+// taggedItemQueries list the items in each domain carrying a tag. Each takes the user
+// and tag ID and returns the columns of a models.TaggedItem after the domain.
+var taggedItemQueries = map[string]string{
+	models.TagDomainTransaction: `
+		SELECT t.id, t.transaction_type || COALESCE(' ' || a.symbol, ''), p.name, t.transaction_date, t.portfolio_id, l.created_at
+		FROM tag_links l
+		JOIN transactions t ON t.id = l.item_id
+		JOIN portfolios p ON p.id = t.portfolio_id
+		LEFT JOIN assets a ON a.id = t.asset_id
+		WHERE l.tag_id = $2 AND l.domain = 'TRANSACTION' AND p.user_id = $1
+		ORDER BY t.transaction_date DESC, t.created_at DESC
+	`,
+	models.TagDomainHolding: `
+		SELECT h.id, a.symbol, p.name, l.created_at, h.portfolio_id, l.created_at
+		FROM tag_links l
+		JOIN holdings h ON h.id = l.item_id
+		JOIN portfolios p ON p.id = h.portfolio_id
+		JOIN assets a ON a.id = h.asset_id
+		WHERE l.tag_id = $2 AND l.domain = 'HOLDING' AND p.user_id = $1
+		ORDER BY a.symbol, p.name
+	`,
+}
+
+// IsTagDomain reports whether items in domain can be tagged
+func IsTagDomain(domain string) bool {
+	_, ok := taggedItemQueries[domain]
+	return ok
+}
+
+type TagRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewTagRepository(pool *pgxpool.Pool) *TagRepository {
+	return &TagRepository{pool: pool}
+}
+
+// GetByUserID returns the user's tags by name with the number of items carrying each,
+// counting only items in domain unless it is empty
+func (r *TagRepository) GetByUserID(ctx context.Context, userID uuid.UUID, domain string) ([]*models.Tag, error) {
+	query := `
+		SELECT tg.id, tg.name, COUNT(l.item_id), tg.created_at
+		FROM tags tg
+		LEFT JOIN tag_links l ON l.tag_id = tg.id AND ($2 = '' OR l.domain = $2)
+		WHERE tg.user_id = $1
+		GROUP BY tg.id
+		ORDER BY tg.name
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, domain)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []*models.Tag
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.ItemCount, &tag.CreatedAt); err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (r *TagRepository) GetByName(ctx context.Context, userID uuid.UUID, name string) (*models.Tag, error) {
+	query := `
+		SELECT tg.id, tg.name, (SELECT COUNT(*) FROM tag_links l WHERE l.tag_id = tg.id), tg.created_at
+		FROM tags tg
+		WHERE tg.user_id = $1 AND tg.name = $2
+	`
+
+	var tag models.Tag
+	err := r.pool.QueryRow(ctx, query, userID, name).Scan(&tag.ID, &tag.Name, &tag.ItemCount, &tag.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTagNotFound
+		}
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+// GetItemTags returns the names of the tags on an item
+func (r *TagRepository) GetItemTags(ctx context.Context, userID uuid.UUID, domain string, itemID uuid.UUID) ([]string, error) {
+	query := `
+		SELECT tg.name
+		FROM tag_links l
+		JOIN tags tg ON tg.id = l.tag_id
+		WHERE tg.user_id = $1 AND l.domain = $2 AND l.item_id = $3
+		ORDER BY tg.name
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, domain, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// GetDomainTags returns the names of the tags on each of the user's tagged items in
+// domain, by item ID
+func (r *TagRepository) GetDomainTags(ctx context.Context, userID uuid.UUID, domain string) (map[uuid.UUID][]string, error) {
+	query := `
+		SELECT l.item_id, tg.name
+		FROM tag_links l
+		JOIN tags tg ON tg.id = l.tag_id
+		WHERE tg.user_id = $1 AND l.domain = $2
+		ORDER BY tg.name
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, domain)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[uuid.UUID][]string)
+	for rows.Next() {
+		var itemID uuid.UUID
+		var name string
+		if err := rows.Scan(&itemID, &name); err != nil {
+			return nil, err
+		}
+		tags[itemID] = append(tags[itemID], name)
+	}
+
+	return tags, rows.Err()
+}
+
+// SetItemTags replaces the tags on an item with names, creating any tags the user
+// doesn't have yet
+func (r *TagRepository) SetItemTags(ctx context.Context, userID uuid.UUID, domain string, itemID uuid.UUID, names []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM tag_links l
+		USING tags tg
+		WHERE tg.id = l.tag_id AND tg.user_id = $1 AND l.domain = $2 AND l.item_id = $3
+	`, userID, domain, itemID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, name := range names {
+		var tagID uuid.UUID
+		err := tx.QueryRow(ctx, `
+			INSERT INTO tags (id, user_id, name, created_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+			RETURNING id
+		`, uuid.New(), userID, name, now).Scan(&tagID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO tag_links (tag_id, domain, item_id, created_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT DO NOTHING
+		`, tagID, domain, itemID, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Rename changes a tag's name, keeping its items. Renaming onto another existing tag
+// returns ErrTagExists; Merge combines them instead.
+func (r *TagRepository) Rename(ctx context.Context, userID uuid.UUID, name, newName string) error {
+	result, err := r.pool.Exec(ctx, `UPDATE tags SET name = $3 WHERE user_id = $1 AND name = $2`, userID, name, newName)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrTagExists
+		}
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+// Merge moves every item tagged name on to the tag into and deletes name
+func (r *TagRepository) Merge(ctx context.Context, userID uuid.UUID, name, into string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var fromID, intoID uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT id FROM tags WHERE user_id = $1 AND name = $2 FOR UPDATE`, userID, name).Scan(&fromID)
+	if err == nil {
+		err = tx.QueryRow(ctx, `SELECT id FROM tags WHERE user_id = $1 AND name = $2 FOR UPDATE`, userID, into).Scan(&intoID)
+	}
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrTagNotFound
+		}
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO tag_links (tag_id, domain, item_id, created_at)
+		SELECT $2, domain, item_id, created_at FROM tag_links WHERE tag_id = $1
+		ON CONFLICT DO NOTHING
+	`, fromID, intoID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM tags WHERE id = $1`, fromID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Delete removes the tag from the user's tags and from every item carrying it
+func (r *TagRepository) Delete(ctx context.Context, userID uuid.UUID, name string) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM tags WHERE user_id = $1 AND name = $2`, userID, name)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+// GetItems returns the items carrying a tag, from every domain unless domain is set
+func (r *TagRepository) GetItems(ctx context.Context, userID, tagID uuid.UUID, domain string) ([]*models.TaggedItem, error) {
+	var items []*models.TaggedItem
+	for d, query := range taggedItemQueries {
+		if domain != "" && d != domain {
+			continue
+		}
+
+		rows, err := r.pool.Query(ctx, query, userID, tagID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			item := models.TaggedItem{Domain: d}
+			if err := rows.Scan(&item.ItemID, &item.Title, &item.Subtitle, &item.Date, &item.PortfolioID, &item.TaggedAt); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			items = append(items, &item)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
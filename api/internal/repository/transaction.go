@@ -3,16 +3,19 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/pkg/taxyear"
 )
 
 var (
 	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrVoucherNotFound     = errors.New("voucher not found")
 )
 
 type TransactionRepository struct {
@@ -23,16 +26,171 @@ func NewTransactionRepository(pool *pgxpool.Pool) *TransactionRepository {
 	return &TransactionRepository{pool: pool}
 }
 
+const insertTransactionQuery = `
+	INSERT INTO transactions (id, portfolio_id, asset_id, transaction_type, quantity, price, total_amount, currency, transaction_date, notes, gross_amount, withholding_tax, withholding_tax_country, fx_rate, portfolio_amount, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+`
+
 func (r *TransactionRepository) Create(ctx context.Context, tx *models.Transaction) error {
-	query := `
-		INSERT INTO transactions (id, portfolio_id, asset_id, transaction_type, quantity, price, total_amount, currency, transaction_date, notes, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-	`
+	_, err := r.pool.Exec(ctx, insertTransactionQuery, newTransactionArgs(tx)...)
+	return err
+}
+
+// CreateBuy records a BUY and adds its units to the holding, at the unit price in the
+// portfolio's currency, in one database transaction so neither is saved without the other
+func (r *TransactionRepository) CreateBuy(ctx context.Context, buy *models.Transaction) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := addBuyToHolding(ctx, tx, buy); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, insertTransactionQuery, newTransactionArgs(buy)...); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// CreateBedAndISA records a bed and ISA in one database transaction: the sale's units
+// are taken from the GIA holding and the repurchase's added to the ISA holding, and the
+// sale, the cash subscribed and the repurchase are saved. ErrInsufficientHoldings is
+// returned if the GIA holds fewer units than are sold.
+func (r *TransactionRepository) CreateBedAndISA(ctx context.Context, sell, transfer, buy *models.Transaction) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := removeSellFromHolding(ctx, tx, sell); err != nil {
+		return err
+	}
+
+	if err := addBuyToHolding(ctx, tx, buy); err != nil {
+		return err
+	}
+
+	for _, t := range []*models.Transaction{sell, transfer, buy} {
+		if _, err := tx.Exec(ctx, insertTransactionQuery, newTransactionArgs(t)...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// addBuyToHolding adds a BUY's units to its holding, creating the holding if needed, at
+// the unit price in the portfolio's currency
+func addBuyToHolding(ctx context.Context, tx pgx.Tx, buy *models.Transaction) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO holdings (id, portfolio_id, asset_id, quantity, average_cost, purchased_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		ON CONFLICT (portfolio_id, asset_id) DO UPDATE
+		SET average_cost = (holdings.quantity * COALESCE(holdings.average_cost, 0) + EXCLUDED.quantity * EXCLUDED.average_cost)
+		        / (holdings.quantity + EXCLUDED.quantity),
+		    quantity = holdings.quantity + EXCLUDED.quantity,
+		    updated_at = EXCLUDED.updated_at
+	`, uuid.New(), buy.PortfolioID, *buy.AssetID, *buy.Quantity, buy.PortfolioUnitPrice(), buy.TransactionDate, time.Now())
+	return err
+}
+
+// removeSellFromHolding takes a SELL's units out of its holding, removing the holding
+// if none are left. ErrInsufficientHoldings is returned if fewer units are held.
+func removeSellFromHolding(ctx context.Context, tx pgx.Tx, sell *models.Transaction) error {
+	var (
+		holdingID uuid.UUID
+		quantity  float64
+	)
+	err := tx.QueryRow(ctx, `
+		SELECT id, quantity FROM holdings
+		WHERE portfolio_id = $1 AND asset_id = $2
+		FOR UPDATE
+	`, sell.PortfolioID, *sell.AssetID).Scan(&holdingID, &quantity)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInsufficientHoldings
+		}
+		return err
+	}
+
+	const epsilon = 1e-9
+	quantity -= *sell.Quantity
+	switch {
+	case quantity < -epsilon:
+		return ErrInsufficientHoldings
+	case quantity <= epsilon:
+		_, err = tx.Exec(ctx, `DELETE FROM holdings WHERE id = $1`, holdingID)
+	default:
+		_, err = tx.Exec(ctx, `UPDATE holdings SET quantity = $2, updated_at = $3 WHERE id = $1`, holdingID, quantity, time.Now())
+	}
+	return err
+}
+
+// Import records imported BUY and SELL transactions, which must be sorted by date, and
+// applies each to its holding in one database transaction, so an import that fails or
+// is cancelled saves nothing. With replace, the portfolio's transactions and holdings
+// are deleted first. progress is called with the number saved after each transaction.
+// ImportError is returned if a transaction can't be applied.
+func (r *TransactionRepository) Import(ctx context.Context, portfolioID uuid.UUID, txs []*models.Transaction, replace bool, progress func(saved int)) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if replace {
+		if _, err := tx.Exec(ctx, `DELETE FROM transactions WHERE portfolio_id = $1`, portfolioID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM holdings WHERE portfolio_id = $1`, portfolioID); err != nil {
+			return err
+		}
+	}
+
+	for i, t := range txs {
+		if t.TransactionType == models.TransactionTypeBuy {
+			err = addBuyToHolding(ctx, tx, t)
+		} else {
+			err = removeSellFromHolding(ctx, tx, t)
+		}
+		if err == nil {
+			_, err = tx.Exec(ctx, insertTransactionQuery, newTransactionArgs(t)...)
+		}
+		if err != nil {
+			return &ImportError{Index: i, Err: err}
+		}
+		progress(i + 1)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ImportError is the transaction an import failed on, by its index in the import
+type ImportError struct {
+	Index int
+	Err   error
+}
 
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("transaction %d: %v", e.Index, e.Err)
+}
+
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
+
+// newTransactionArgs sets the transaction's ID and creation time and returns the
+// arguments for insertTransactionQuery
+func newTransactionArgs(tx *models.Transaction) []interface{} {
 	tx.ID = uuid.New()
 	tx.CreatedAt = time.Now()
 
-	_, err := r.pool.Exec(ctx, query,
+	return []interface{}{
 		tx.ID,
 		tx.PortfolioID,
 		tx.AssetID,
@@ -43,15 +201,18 @@ func (r *TransactionRepository) Create(ctx context.Context, tx *models.Transacti
 		tx.Currency,
 		tx.TransactionDate,
 		tx.Notes,
+		tx.GrossAmount,
+		tx.WithholdingTax,
+		tx.WithholdingTaxCountry,
+		tx.FXRate,
+		tx.PortfolioAmount,
 		tx.CreatedAt,
-	)
-
-	return err
+	}
 }
 
 func (r *TransactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Transaction, error) {
 	query := `
-		SELECT t.id, t.portfolio_id, t.asset_id, t.transaction_type, t.quantity, t.price, t.total_amount, t.currency, t.transaction_date, t.notes, t.created_at,
+		SELECT t.id, t.portfolio_id, t.asset_id, t.transaction_type, t.quantity, t.price, t.total_amount, t.currency, t.transaction_date, t.notes, t.gross_amount, t.withholding_tax, t.withholding_tax_country, t.fx_rate, t.portfolio_amount, t.relief_for_id, t.created_at,
 			   a.id, a.symbol, a.name, a.asset_type, a.exchange, a.currency, a.data_source, a.last_price, a.last_price_updated_at, a.created_at
 		FROM transactions t
 		LEFT JOIN assets a ON a.id = t.asset_id
@@ -75,6 +236,12 @@ func (r *TransactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*mod
 		&tx.Currency,
 		&tx.TransactionDate,
 		&tx.Notes,
+		&tx.GrossAmount,
+		&tx.WithholdingTax,
+		&tx.WithholdingTaxCountry,
+		&tx.FXRate,
+		&tx.PortfolioAmount,
+		&tx.ReliefForID,
 		&tx.CreatedAt,
 		&assetID,
 		&assetSymbol,
@@ -126,25 +293,34 @@ func (r *TransactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*mod
 	return &tx, nil
 }
 
-func (r *TransactionRepository) GetByPortfolioID(ctx context.Context, portfolioID uuid.UUID, limit, offset int) ([]*models.Transaction, int, error) {
-	countQuery := `SELECT COUNT(*) FROM transactions WHERE portfolio_id = $1`
+// GetByPortfolioID returns a page of the portfolio's transactions, newest first, with
+// their tags. A non-empty tag limits them to transactions carrying it.
+func (r *TransactionRepository) GetByPortfolioID(ctx context.Context, portfolioID uuid.UUID, tag string, limit, offset int) ([]*models.Transaction, int, error) {
+	tagFilter := `($2 = '' OR EXISTS (
+		SELECT 1 FROM tag_links l JOIN tags tg ON tg.id = l.tag_id
+		WHERE l.domain = 'TRANSACTION' AND l.item_id = t.id AND tg.name = $2
+	))`
+
+	countQuery := `SELECT COUNT(*) FROM transactions t WHERE t.portfolio_id = $1 AND ` + tagFilter
 	var total int
-	err := r.pool.QueryRow(ctx, countQuery, portfolioID).Scan(&total)
+	err := r.pool.QueryRow(ctx, countQuery, portfolioID, tag).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	query := `
-		SELECT t.id, t.portfolio_id, t.asset_id, t.transaction_type, t.quantity, t.price, t.total_amount, t.currency, t.transaction_date, t.notes, t.created_at,
-			   a.symbol, a.name
+		SELECT t.id, t.portfolio_id, t.asset_id, t.transaction_type, t.quantity, t.price, t.total_amount, t.currency, t.transaction_date, t.notes, t.gross_amount, t.withholding_tax, t.withholding_tax_country, t.fx_rate, t.portfolio_amount, t.created_at,
+			   a.symbol, a.name,
+			   ARRAY(SELECT tg.name FROM tag_links l JOIN tags tg ON tg.id = l.tag_id
+			         WHERE l.domain = 'TRANSACTION' AND l.item_id = t.id ORDER BY tg.name)
 		FROM transactions t
 		LEFT JOIN assets a ON a.id = t.asset_id
-		WHERE t.portfolio_id = $1
+		WHERE t.portfolio_id = $1 AND ` + tagFilter + `
 		ORDER BY t.transaction_date DESC, t.created_at DESC
-		LIMIT $2 OFFSET $3
+		LIMIT $3 OFFSET $4
 	`
 
-	rows, err := r.pool.Query(ctx, query, portfolioID, limit, offset)
+	rows, err := r.pool.Query(ctx, query, portfolioID, tag, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -166,9 +342,15 @@ func (r *TransactionRepository) GetByPortfolioID(ctx context.Context, portfolioI
 			&tx.Currency,
 			&tx.TransactionDate,
 			&tx.Notes,
+			&tx.GrossAmount,
+			&tx.WithholdingTax,
+			&tx.WithholdingTaxCountry,
+			&tx.FXRate,
+			&tx.PortfolioAmount,
 			&tx.CreatedAt,
 			&assetSymbol,
 			&assetName,
+			&tx.Tags,
 		)
 		if err != nil {
 			return nil, 0, err
@@ -190,7 +372,8 @@ func (r *TransactionRepository) GetByPortfolioID(ctx context.Context, portfolioI
 func (r *TransactionRepository) Update(ctx context.Context, tx *models.Transaction) error {
 	query := `
 		UPDATE transactions
-		SET asset_id = $2, transaction_type = $3, quantity = $4, price = $5, total_amount = $6, currency = $7, transaction_date = $8, notes = $9
+		SET asset_id = $2, transaction_type = $3, quantity = $4, price = $5, total_amount = $6, currency = $7, transaction_date = $8, notes = $9,
+			gross_amount = $10, withholding_tax = $11, withholding_tax_country = $12, fx_rate = $13, portfolio_amount = $14
 		WHERE id = $1
 	`
 
@@ -204,6 +387,11 @@ func (r *TransactionRepository) Update(ctx context.Context, tx *models.Transacti
 		tx.Currency,
 		tx.TransactionDate,
 		tx.Notes,
+		tx.GrossAmount,
+		tx.WithholdingTax,
+		tx.WithholdingTaxCountry,
+		tx.FXRate,
+		tx.PortfolioAmount,
 	)
 
 	if err != nil {
@@ -217,6 +405,98 @@ func (r *TransactionRepository) Update(ctx context.Context, tx *models.Transacti
 	return nil
 }
 
+// UpdateTrade saves an edited BUY or SELL and moves its holding from the effect of the
+// old transaction to the effect of the new one, in one transaction. A buy adds its units
+// at its price to the average cost; a sell takes units out at the average cost. The
+// holding is removed if no units are left, and ErrInsufficientHoldings is returned if
+// the edit would leave fewer than none or sell more than is held.
+func (r *TransactionRepository) UpdateTrade(ctx context.Context, old, updated *models.Transaction) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		holdingID             uuid.UUID
+		quantity, averageCost float64
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT id, quantity, average_cost FROM holdings
+		WHERE portfolio_id = $1 AND asset_id = $2
+		FOR UPDATE
+	`, old.PortfolioID, *old.AssetID).Scan(&holdingID, &quantity, &averageCost)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	found := err == nil
+
+	cost := quantity * averageCost
+	if old.TransactionType == models.TransactionTypeBuy {
+		quantity -= *old.Quantity
+		cost -= *old.Quantity * old.PortfolioUnitPrice()
+	} else {
+		quantity += *old.Quantity
+		cost += *old.Quantity * averageCost
+	}
+
+	if updated.TransactionType == models.TransactionTypeBuy {
+		quantity += *updated.Quantity
+		cost += *updated.Quantity * updated.PortfolioUnitPrice()
+	} else {
+		if quantity < *updated.Quantity {
+			return ErrInsufficientHoldings
+		}
+		if quantity > 0 {
+			cost -= *updated.Quantity * cost / quantity
+		}
+		quantity -= *updated.Quantity
+	}
+
+	const epsilon = 1e-9
+	now := time.Now()
+	switch {
+	case quantity < -epsilon:
+		return ErrInsufficientHoldings
+	case quantity <= epsilon:
+		if found {
+			if _, err := tx.Exec(ctx, `DELETE FROM holdings WHERE id = $1`, holdingID); err != nil {
+				return err
+			}
+		}
+	default:
+		averageCost = max(cost, 0) / quantity
+		if found {
+			_, err = tx.Exec(ctx, `
+				UPDATE holdings SET quantity = $2, average_cost = $3, updated_at = $4
+				WHERE id = $1
+			`, holdingID, quantity, averageCost, now)
+		} else {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO holdings (id, portfolio_id, asset_id, quantity, average_cost, purchased_at, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+			`, uuid.New(), old.PortfolioID, *old.AssetID, quantity, averageCost, updated.TransactionDate, now)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.Exec(ctx, `
+		UPDATE transactions
+		SET quantity = $2, price = $3, total_amount = $4, transaction_date = $5, notes = $6, fx_rate = $7, portfolio_amount = $8
+		WHERE id = $1
+	`, updated.ID, updated.Quantity, updated.Price, updated.TotalAmount, updated.TransactionDate, updated.Notes, updated.FXRate, updated.PortfolioAmount)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTransactionNotFound
+	}
+
+	return tx.Commit(ctx)
+}
+
 func (r *TransactionRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM transactions WHERE id = $1`
 
@@ -234,7 +514,7 @@ func (r *TransactionRepository) Delete(ctx context.Context, id uuid.UUID) error
 
 func (r *TransactionRepository) GetByAssetID(ctx context.Context, assetID uuid.UUID) ([]*models.Transaction, error) {
 	query := `
-		SELECT id, portfolio_id, asset_id, transaction_type, quantity, price, total_amount, currency, transaction_date, notes, created_at
+		SELECT id, portfolio_id, asset_id, transaction_type, quantity, price, total_amount, currency, transaction_date, notes, gross_amount, withholding_tax, withholding_tax_country, fx_rate, portfolio_amount, created_at
 		FROM transactions
 		WHERE asset_id = $1
 		ORDER BY transaction_date DESC
@@ -260,6 +540,56 @@ func (r *TransactionRepository) GetByAssetID(ctx context.Context, assetID uuid.U
 			&tx.Currency,
 			&tx.TransactionDate,
 			&tx.Notes,
+			&tx.GrossAmount,
+			&tx.WithholdingTax,
+			&tx.WithholdingTaxCountry,
+			&tx.FXRate,
+			&tx.PortfolioAmount,
+			&tx.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, &tx)
+	}
+
+	return transactions, rows.Err()
+}
+
+// GetByPortfolioAndAsset returns the asset's transactions in the portfolio, oldest first
+func (r *TransactionRepository) GetByPortfolioAndAsset(ctx context.Context, portfolioID, assetID uuid.UUID) ([]*models.Transaction, error) {
+	query := `
+		SELECT id, portfolio_id, asset_id, transaction_type, quantity, price, total_amount, currency, transaction_date, notes, gross_amount, withholding_tax, withholding_tax_country, fx_rate, portfolio_amount, created_at
+		FROM transactions
+		WHERE portfolio_id = $1 AND asset_id = $2
+		ORDER BY transaction_date ASC, created_at ASC
+	`
+
+	rows, err := r.pool.Query(ctx, query, portfolioID, assetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*models.Transaction
+	for rows.Next() {
+		var tx models.Transaction
+		err := rows.Scan(
+			&tx.ID,
+			&tx.PortfolioID,
+			&tx.AssetID,
+			&tx.TransactionType,
+			&tx.Quantity,
+			&tx.Price,
+			&tx.TotalAmount,
+			&tx.Currency,
+			&tx.TransactionDate,
+			&tx.Notes,
+			&tx.GrossAmount,
+			&tx.WithholdingTax,
+			&tx.WithholdingTaxCountry,
+			&tx.FXRate,
+			&tx.PortfolioAmount,
 			&tx.CreatedAt,
 		)
 		if err != nil {
@@ -285,18 +615,19 @@ func (r *TransactionRepository) BelongsToUser(ctx context.Context, transactionID
 	return exists, err
 }
 
-// GetCashBalance calculates the current cash balance for a portfolio from DEPOSIT/WITHDRAWAL transactions
+// GetCashBalance calculates the current cash balance for a portfolio, in its currency, from
+// DEPOSIT/WITHDRAWAL transactions, counting tax relief top-ups as deposits
 func (r *TransactionRepository) GetCashBalance(ctx context.Context, portfolioID uuid.UUID) (float64, error) {
 	query := `
 		SELECT COALESCE(
 			SUM(CASE
-				WHEN transaction_type = 'DEPOSIT' THEN total_amount
-				WHEN transaction_type = 'WITHDRAWAL' THEN -total_amount
+				WHEN transaction_type IN ('DEPOSIT', 'TAX_RELIEF') THEN COALESCE(portfolio_amount, total_amount)
+				WHEN transaction_type = 'WITHDRAWAL' THEN -COALESCE(portfolio_amount, total_amount)
 				ELSE 0
 			END), 0
 		) as balance
 		FROM transactions
-		WHERE portfolio_id = $1 AND transaction_type IN ('DEPOSIT', 'WITHDRAWAL')
+		WHERE portfolio_id = $1 AND transaction_type IN ('DEPOSIT', 'WITHDRAWAL', 'TAX_RELIEF')
 	`
 
 	var balance float64
@@ -352,3 +683,554 @@ func (r *TransactionRepository) GetPortfoliosWithData(ctx context.Context, portf
 
 	return hasData, rows.Err()
 }
+
+// GetMonthlyTotalsByUserID sums the user's cash flow transactions (deposits, withdrawals,
+// dividends, interest and fees) per calendar month from the given date onwards
+func (r *TransactionRepository) GetMonthlyTotalsByUserID(ctx context.Context, userID uuid.UUID, from time.Time) ([]*models.MonthlyTransactionTotal, error) {
+	query := `
+		SELECT date_trunc('month', t.transaction_date)::date AS month, t.transaction_type,
+			CASE WHEN t.portfolio_amount IS NOT NULL THEN p.currency ELSE COALESCE(NULLIF(t.currency, ''), p.currency) END AS currency,
+			SUM(COALESCE(t.portfolio_amount, t.total_amount))
+		FROM transactions t
+		JOIN portfolios p ON p.id = t.portfolio_id
+		WHERE p.user_id = $1
+			AND t.transaction_date >= $2
+			AND t.transaction_type IN ('DEPOSIT', 'WITHDRAWAL', 'DIVIDEND', 'INTEREST', 'FEE')
+		GROUP BY month, t.transaction_type, currency
+		ORDER BY month
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*models.MonthlyTransactionTotal
+	for rows.Next() {
+		var t models.MonthlyTransactionTotal
+		if err := rows.Scan(&t.Month, &t.TransactionType, &t.Currency, &t.Total); err != nil {
+			return nil, err
+		}
+		totals = append(totals, &t)
+	}
+
+	return totals, rows.Err()
+}
+
+// CountCreatedSince counts the transactions added to the user's portfolios after a time
+func (r *TransactionRepository) CountCreatedSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM transactions t
+		JOIN portfolios p ON p.id = t.portfolio_id
+		WHERE p.user_id = $1 AND t.created_at > $2
+	`
+
+	var count int
+	err := r.pool.QueryRow(ctx, query, userID, since).Scan(&count)
+	return count, err
+}
+
+// CountUntaggedSince counts the user's transactions dated on or after since, and how
+// many of those have no tags
+func (r *TransactionRepository) CountUntaggedSince(ctx context.Context, userID uuid.UUID, since time.Time) (total, untagged int, err error) {
+	query := `
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE NOT EXISTS(SELECT 1 FROM tag_links l WHERE l.domain = 'TRANSACTION' AND l.item_id = t.id))
+		FROM transactions t
+		JOIN portfolios p ON p.id = t.portfolio_id
+		WHERE p.user_id = $1 AND t.transaction_date >= $2
+	`
+
+	err = r.pool.QueryRow(ctx, query, userID, since).Scan(&total, &untagged)
+	return total, untagged, err
+}
+
+// ukTaxYearStart selects the first day of the UK tax year containing t
+var ukTaxYearStart = fmt.Sprintf(
+	`make_date(EXTRACT(YEAR FROM t.transaction_date)::int - CASE WHEN t.transaction_date < make_date(EXTRACT(YEAR FROM t.transaction_date)::int, %[1]d, %[2]d) THEN 1 ELSE 0 END, %[1]d, %[2]d)`,
+	int(taxyear.UK.StartMonth), taxyear.UK.StartDay,
+)
+
+// contributionFilter selects the transactions t that pay into a portfolio: deposits,
+// transfers in and tax relief, plus buys in portfolios that record no cash paid in (a
+// deposit or a transfer in of cash) in the same tax year, as otherwise the buys invest
+// cash already counted
+var contributionFilter = `
+	t.transaction_type IN ('BUY', 'DEPOSIT', 'TRANSFER_IN', 'TAX_RELIEF')
+	AND (t.transaction_type <> 'BUY' OR NOT EXISTS (
+		SELECT 1 FROM transactions c
+		WHERE c.portfolio_id = t.portfolio_id
+			AND (c.transaction_type = 'DEPOSIT' OR (c.transaction_type = 'TRANSFER_IN' AND c.asset_id IS NULL))
+			AND c.transaction_date >= ` + ukTaxYearStart + `
+			AND c.transaction_date < ` + ukTaxYearStart + ` + INTERVAL '1 year'
+	))`
+
+// HasCashContributions reports whether the portfolio records cash paid in during the UK
+// tax year containing date, in which case its buys that year don't count as contributions
+func (r *TransactionRepository) HasCashContributions(ctx context.Context, portfolioID uuid.UUID, date time.Time) (bool, error) {
+	year := taxyear.UK.Of(date)
+
+	var exists bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM transactions
+			WHERE portfolio_id = $1
+				AND (transaction_type = 'DEPOSIT' OR (transaction_type = 'TRANSFER_IN' AND asset_id IS NULL))
+				AND transaction_date BETWEEN $2 AND $3
+		)
+	`, portfolioID, year.From(), year.To()).Scan(&exists)
+	return exists, err
+}
+
+// GetContributionsByTaxYear sums contributions (see contributionFilter, matching
+// contributions_this_year tracking, plus tax relief so pensions are gross) per portfolio
+// and UK tax year
+func (r *TransactionRepository) GetContributionsByTaxYear(ctx context.Context, portfolioIDs []uuid.UUID) ([]*models.TaxYearContribution, error) {
+	if len(portfolioIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT portfolio_id, tax_year_start, SUM(amount)
+		FROM (
+			SELECT t.portfolio_id, COALESCE(t.portfolio_amount, t.total_amount) AS amount,
+				EXTRACT(YEAR FROM t.transaction_date)::int -
+				CASE WHEN t.transaction_date < make_date(EXTRACT(YEAR FROM t.transaction_date)::int, $2, $3) THEN 1 ELSE 0 END AS tax_year_start
+			FROM transactions t
+			WHERE t.portfolio_id = ANY($1) AND ` + contributionFilter + `
+		) AS t
+		GROUP BY portfolio_id, tax_year_start
+		ORDER BY tax_year_start
+	`
+
+	rows, err := r.pool.Query(ctx, query, portfolioIDs, int(taxyear.UK.StartMonth), taxyear.UK.StartDay)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contributions []*models.TaxYearContribution
+	for rows.Next() {
+		var c models.TaxYearContribution
+		var startYear int
+		if err := rows.Scan(&c.PortfolioID, &startYear, &c.Total); err != nil {
+			return nil, err
+		}
+		c.TaxYear = taxyear.Label(startYear)
+		contributions = append(contributions, &c)
+	}
+
+	return contributions, rows.Err()
+}
+
+// GetDividendAssetIDs returns the assets that have paid the user a dividend in any portfolio
+func (r *TransactionRepository) GetDividendAssetIDs(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]bool, error) {
+	query := `
+		SELECT DISTINCT t.asset_id
+		FROM transactions t
+		JOIN portfolios p ON p.id = t.portfolio_id
+		WHERE p.user_id = $1 AND t.transaction_type = 'DIVIDEND' AND t.asset_id IS NOT NULL
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assetIDs := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		assetIDs[id] = true
+	}
+
+	return assetIDs, rows.Err()
+}
+
+// SaveVoucher attaches a dividend voucher to a transaction, replacing any existing one
+func (r *TransactionRepository) SaveVoucher(ctx context.Context, v *models.TransactionVoucher) error {
+	query := `
+		INSERT INTO transaction_vouchers (id, transaction_id, file_name, content_type, size_bytes, data, extracted_text, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (transaction_id) DO UPDATE SET
+			id = EXCLUDED.id, file_name = EXCLUDED.file_name, content_type = EXCLUDED.content_type,
+			size_bytes = EXCLUDED.size_bytes, data = EXCLUDED.data, extracted_text = EXCLUDED.extracted_text,
+			created_at = EXCLUDED.created_at
+	`
+
+	v.ID = uuid.New()
+	v.CreatedAt = time.Now()
+
+	_, err := r.pool.Exec(ctx, query,
+		v.ID,
+		v.TransactionID,
+		v.FileName,
+		v.ContentType,
+		v.SizeBytes,
+		v.Data,
+		v.ExtractedText,
+		v.CreatedAt,
+	)
+	return err
+}
+
+// GetVoucher returns the voucher attached to a transaction, including its file contents
+func (r *TransactionRepository) GetVoucher(ctx context.Context, transactionID uuid.UUID) (*models.TransactionVoucher, error) {
+	query := `
+		SELECT id, transaction_id, file_name, content_type, size_bytes, data, COALESCE(extracted_text, ''), created_at
+		FROM transaction_vouchers
+		WHERE transaction_id = $1
+	`
+
+	var v models.TransactionVoucher
+	err := r.pool.QueryRow(ctx, query, transactionID).Scan(
+		&v.ID,
+		&v.TransactionID,
+		&v.FileName,
+		&v.ContentType,
+		&v.SizeBytes,
+		&v.Data,
+		&v.ExtractedText,
+		&v.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrVoucherNotFound
+		}
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+func (r *TransactionRepository) DeleteVoucher(ctx context.Context, transactionID uuid.UUID) error {
+	query := `DELETE FROM transaction_vouchers WHERE transaction_id = $1`
+
+	result, err := r.pool.Exec(ctx, query, transactionID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrVoucherNotFound
+	}
+
+	return nil
+}
+
+// GetForeignDividendTotals sums the user's DIVIDEND transactions with a withholding tax
+// country between from and to (inclusive) per country, currency and tax wrapper. Gross
+// defaults to net plus tax when it was not recorded.
+func (r *TransactionRepository) GetForeignDividendTotals(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]*models.ForeignDividendTotal, error) {
+	query := `
+		SELECT t.withholding_tax_country, t.currency,
+			p.type IN ('ISA', 'LISA', 'JISA', 'SIPP') AS sheltered,
+			COUNT(*),
+			SUM(COALESCE(t.gross_amount, t.total_amount + COALESCE(t.withholding_tax, 0))),
+			SUM(COALESCE(t.withholding_tax, 0)),
+			SUM(t.total_amount)
+		FROM transactions t
+		JOIN portfolios p ON p.id = t.portfolio_id
+		WHERE p.user_id = $1
+			AND t.transaction_type = 'DIVIDEND'
+			AND t.withholding_tax_country <> ''
+			AND t.transaction_date BETWEEN $2 AND $3
+		GROUP BY t.withholding_tax_country, t.currency, sheltered
+		ORDER BY t.withholding_tax_country, t.currency, sheltered
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*models.ForeignDividendTotal
+	for rows.Next() {
+		var t models.ForeignDividendTotal
+		if err := rows.Scan(&t.Country, &t.Currency, &t.Sheltered, &t.Count, &t.GrossAmount, &t.WithholdingTax, &t.NetAmount); err != nil {
+			return nil, err
+		}
+		totals = append(totals, &t)
+	}
+
+	return totals, rows.Err()
+}
+
+// GetInterestTotals sums the user's INTEREST transactions between from and to
+// (inclusive) per portfolio and currency
+func (r *TransactionRepository) GetInterestTotals(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]*models.PortfolioInterestTotal, error) {
+	query := `
+		SELECT t.portfolio_id, t.currency, COUNT(*), SUM(t.total_amount), MAX(t.transaction_date)
+		FROM transactions t
+		JOIN portfolios p ON p.id = t.portfolio_id
+		WHERE p.user_id = $1
+			AND t.transaction_type = 'INTEREST'
+			AND t.transaction_date BETWEEN $2 AND $3
+		GROUP BY t.portfolio_id, t.currency
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*models.PortfolioInterestTotal
+	for rows.Next() {
+		var t models.PortfolioInterestTotal
+		if err := rows.Scan(&t.PortfolioID, &t.Currency, &t.Count, &t.Total, &t.LastPaid); err != nil {
+			return nil, err
+		}
+		totals = append(totals, &t)
+	}
+
+	return totals, rows.Err()
+}
+
+// GetMonthlyIncomeTotals sums the user's DIVIDEND and INTEREST transactions between
+// from and to (inclusive) per calendar month, portfolio and currency
+func (r *TransactionRepository) GetMonthlyIncomeTotals(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]*models.MonthlyIncomeTotal, error) {
+	query := `
+		SELECT date_trunc('month', t.transaction_date)::date AS month, t.portfolio_id, t.transaction_type, t.currency,
+			   SUM(t.total_amount), SUM(COALESCE(t.gross_amount, t.total_amount))
+		FROM transactions t
+		JOIN portfolios p ON p.id = t.portfolio_id
+		WHERE p.user_id = $1
+			AND t.transaction_type IN ('DIVIDEND', 'INTEREST')
+			AND t.transaction_date BETWEEN $2 AND $3
+		GROUP BY month, t.portfolio_id, t.transaction_type, t.currency
+		ORDER BY month
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*models.MonthlyIncomeTotal
+	for rows.Next() {
+		var t models.MonthlyIncomeTotal
+		if err := rows.Scan(&t.Month, &t.PortfolioID, &t.TransactionType, &t.Currency, &t.Net, &t.Gross); err != nil {
+			return nil, err
+		}
+		totals = append(totals, &t)
+	}
+
+	return totals, rows.Err()
+}
+
+// GetMonthlyContributions sums contributions (see contributionFilter) per portfolio and
+// calendar month from the given date onwards
+func (r *TransactionRepository) GetMonthlyContributions(ctx context.Context, portfolioIDs []uuid.UUID, from time.Time) ([]*models.MonthlyContribution, error) {
+	if len(portfolioIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT t.portfolio_id, date_trunc('month', t.transaction_date)::date AS month, SUM(COALESCE(t.portfolio_amount, t.total_amount))
+		FROM transactions t
+		WHERE t.portfolio_id = ANY($1)
+			AND t.transaction_date >= $2
+			AND ` + contributionFilter + `
+		GROUP BY t.portfolio_id, month
+		ORDER BY month
+	`
+
+	rows, err := r.pool.Query(ctx, query, portfolioIDs, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contributions []*models.MonthlyContribution
+	for rows.Next() {
+		var c models.MonthlyContribution
+		if err := rows.Scan(&c.PortfolioID, &c.Month, &c.Total); err != nil {
+			return nil, err
+		}
+		contributions = append(contributions, &c)
+	}
+
+	return contributions, rows.Err()
+}
+
+// GetPensionContributions sums the deposits and tax relief paid into each portfolio
+// between from and to inclusive
+func (r *TransactionRepository) GetPensionContributions(ctx context.Context, portfolioIDs []uuid.UUID, from, to time.Time) ([]*models.PensionContribution, error) {
+	if len(portfolioIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT portfolio_id,
+			COALESCE(SUM(COALESCE(portfolio_amount, total_amount)) FILTER (WHERE transaction_type = 'DEPOSIT'), 0),
+			COALESCE(SUM(COALESCE(portfolio_amount, total_amount)) FILTER (WHERE transaction_type = 'TAX_RELIEF'), 0)
+		FROM transactions
+		WHERE portfolio_id = ANY($1)
+			AND transaction_date BETWEEN $2 AND $3
+			AND transaction_type IN ('DEPOSIT', 'TAX_RELIEF')
+		GROUP BY portfolio_id
+	`
+
+	rows, err := r.pool.Query(ctx, query, portfolioIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contributions []*models.PensionContribution
+	for rows.Next() {
+		var c models.PensionContribution
+		if err := rows.Scan(&c.PortfolioID, &c.Net, &c.TaxRelief); err != nil {
+			return nil, err
+		}
+		contributions = append(contributions, &c)
+	}
+
+	return contributions, rows.Err()
+}
+
+const insertTaxReliefQuery = `
+	INSERT INTO transactions (id, portfolio_id, transaction_type, total_amount, currency, transaction_date, notes, fx_rate, portfolio_amount, relief_for_id, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+`
+
+// CreateTaxRelief records a TAX_RELIEF transaction as the top-up on the contribution
+func (r *TransactionRepository) CreateTaxRelief(ctx context.Context, relief *models.Transaction, contributionID uuid.UUID) error {
+	_, err := r.pool.Exec(ctx, insertTaxReliefQuery, newTaxReliefArgs(relief, contributionID)...)
+	return err
+}
+
+// CreateWithTaxRelief records a contribution and the TAX_RELIEF top-up on it in one
+// database transaction, so neither is saved without the other
+func (r *TransactionRepository) CreateWithTaxRelief(ctx context.Context, contribution, relief *models.Transaction) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, insertTransactionQuery, newTransactionArgs(contribution)...); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, insertTaxReliefQuery, newTaxReliefArgs(relief, contribution.ID)...); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// newTaxReliefArgs sets the relief's ID, type and creation time and returns the
+// arguments for insertTaxReliefQuery
+func newTaxReliefArgs(relief *models.Transaction, contributionID uuid.UUID) []interface{} {
+	relief.ID = uuid.New()
+	relief.CreatedAt = time.Now()
+	relief.TransactionType = models.TransactionTypeTaxRelief
+	relief.ReliefForID = &contributionID
+
+	return []interface{}{
+		relief.ID,
+		relief.PortfolioID,
+		relief.TransactionType,
+		relief.TotalAmount,
+		relief.Currency,
+		relief.TransactionDate,
+		relief.Notes,
+		relief.FXRate,
+		relief.PortfolioAmount,
+		contributionID,
+		relief.CreatedAt,
+	}
+}
+
+// UpdateTaxRelief sets the amounts and date of the top-up on the contribution to the
+// relief's, returning ErrTransactionNotFound if it has none
+func (r *TransactionRepository) UpdateTaxRelief(ctx context.Context, contributionID uuid.UUID, relief *models.Transaction) error {
+	query := `
+		UPDATE transactions
+		SET total_amount = $2, transaction_date = $3, fx_rate = $4, portfolio_amount = $5
+		WHERE relief_for_id = $1
+	`
+
+	result, err := r.pool.Exec(ctx, query, contributionID, relief.TotalAmount, relief.TransactionDate, relief.FXRate, relief.PortfolioAmount)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrTransactionNotFound
+	}
+
+	return nil
+}
+
+// GetByPortfolioIDs returns all transactions in the portfolios, oldest first, with the
+// asset's symbol, name, type, exchange, currency, ISIN and data source joined
+func (r *TransactionRepository) GetByPortfolioIDs(ctx context.Context, portfolioIDs []uuid.UUID) ([]*models.Transaction, error) {
+	query := `
+		SELECT t.id, t.portfolio_id, t.asset_id, t.transaction_type, t.quantity, t.price, t.total_amount, t.currency, t.transaction_date, t.notes, t.gross_amount, t.withholding_tax, t.withholding_tax_country, t.fx_rate, t.portfolio_amount, t.created_at,
+			   a.symbol, a.name, COALESCE(a.asset_type, ''), COALESCE(a.exchange, ''), COALESCE(a.currency, ''), COALESCE(a.isin, ''), COALESCE(a.data_source, '')
+		FROM transactions t
+		LEFT JOIN assets a ON a.id = t.asset_id
+		WHERE t.portfolio_id = ANY($1)
+		ORDER BY t.transaction_date ASC, t.created_at ASC
+	`
+
+	rows, err := r.pool.Query(ctx, query, portfolioIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*models.Transaction
+	for rows.Next() {
+		var tx models.Transaction
+		var assetSymbol, assetName *string
+		var asset models.Asset
+
+		err := rows.Scan(
+			&tx.ID,
+			&tx.PortfolioID,
+			&tx.AssetID,
+			&tx.TransactionType,
+			&tx.Quantity,
+			&tx.Price,
+			&tx.TotalAmount,
+			&tx.Currency,
+			&tx.TransactionDate,
+			&tx.Notes,
+			&tx.GrossAmount,
+			&tx.WithholdingTax,
+			&tx.WithholdingTaxCountry,
+			&tx.FXRate,
+			&tx.PortfolioAmount,
+			&tx.CreatedAt,
+			&assetSymbol,
+			&assetName,
+			&asset.AssetType,
+			&asset.Exchange,
+			&asset.Currency,
+			&asset.ISIN,
+			&asset.DataSource,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if assetSymbol != nil && assetName != nil {
+			asset.Symbol, asset.Name = *assetSymbol, *assetName
+			tx.Asset = &asset
+		}
+
+		transactions = append(transactions, &tx)
+	}
+
+	return transactions, rows.Err()
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+type UsageRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewUsageRepository(pool *pgxpool.Pool) *UsageRepository {
+	return &UsageRepository{pool: pool}
+}
+
+// GetEntitiesCreatedByMonth counts the records the user created per month since the given date
+func (r *UsageRepository) GetEntitiesCreatedByMonth(ctx context.Context, userID uuid.UUID, since time.Time) ([]models.MonthlyEntityCount, error) {
+	query := `
+		WITH created AS (
+			SELECT 'portfolios' AS entity, p.created_at FROM portfolios p
+			WHERE p.user_id = $1 AND p.type <> 'FIXED_ASSETS'
+			UNION ALL
+			SELECT 'holdings', h.created_at FROM holdings h JOIN portfolios p ON p.id = h.portfolio_id
+			WHERE p.user_id = $1
+			UNION ALL
+			SELECT 'transactions', t.created_at FROM transactions t JOIN portfolios p ON p.id = t.portfolio_id
+			WHERE p.user_id = $1
+			UNION ALL
+			SELECT 'cash_accounts', ca.created_at FROM cash_accounts ca JOIN portfolios p ON p.id = ca.portfolio_id
+			WHERE p.user_id = $1
+			UNION ALL
+			SELECT 'fixed_assets', fa.created_at FROM fixed_assets fa
+			WHERE fa.user_id = $1
+			UNION ALL
+			SELECT 'savings_goals', g.created_at FROM savings_goals g
+			WHERE g.user_id = $1
+			UNION ALL
+			SELECT 'reminders', rm.created_at FROM reminders rm
+			WHERE rm.user_id = $1
+		)
+		SELECT TO_CHAR(DATE_TRUNC('month', created_at), 'YYYY-MM') AS month, entity, COUNT(*)
+		FROM created
+		WHERE created_at >= $2
+		GROUP BY month, entity
+		ORDER BY month, entity
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []models.MonthlyEntityCount
+	for rows.Next() {
+		var c models.MonthlyEntityCount
+		if err := rows.Scan(&c.Month, &c.Entity, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
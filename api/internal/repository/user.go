@@ -9,6 +9,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/pkg/taxyear"
 )
 
 var (
@@ -26,7 +27,7 @@ func NewUserRepository(pool *pgxpool.Pool) *UserRepository {
 
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	query := `
-		INSERT INTO users (id, email, password_hash, display_name, base_currency, date_format, locale, fire_target, fire_enabled, theme, phone_number, date_of_birth, notify_email, notify_price_alerts, notify_weekly, notify_monthly, watchlist, provider_lists, is_admin, is_locked, created_at, updated_at)
+		INSERT INTO users (id, email, password_hash, display_name, base_currency, date_format, locale, fire_target, fire_enabled, theme, phone_number, date_of_birth, notify_email, notify_price_alerts, notify_weekly, notify_monthly, provider_lists, tax_jurisdiction, is_admin, is_locked, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
 	`
 
@@ -42,6 +43,9 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	if user.Theme == "" {
 		user.Theme = "system"
 	}
+	if user.TaxJurisdiction == "" {
+		user.TaxJurisdiction = taxyear.CodeUK
+	}
 	// Default notification preferences
 	user.NotifyEmail = true
 
@@ -62,8 +66,8 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 		user.NotifyPriceAlerts,
 		user.NotifyWeekly,
 		user.NotifyMonthly,
-		user.Watchlist,
 		user.ProviderLists,
+		user.TaxJurisdiction,
 		user.IsAdmin,
 		user.IsLocked,
 		user.CreatedAt,
@@ -85,7 +89,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 		SELECT id, email, password_hash, display_name, base_currency, date_format, locale, fire_target, fire_enabled,
 			COALESCE(theme, 'system'), COALESCE(phone_number, ''), date_of_birth,
 			COALESCE(notify_email, true), COALESCE(notify_price_alerts, false), COALESCE(notify_weekly, false), COALESCE(notify_monthly, false),
-			COALESCE(watchlist, ''), COALESCE(provider_lists, ''), COALESCE(is_admin, false), COALESCE(is_locked, false),
+			COALESCE(provider_lists, ''), COALESCE(tax_jurisdiction, 'UK'), COALESCE(is_admin, false), COALESCE(is_locked, false),
 			created_at, updated_at, last_login_at
 		FROM users
 		WHERE id = $1
@@ -109,8 +113,8 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 		&user.NotifyPriceAlerts,
 		&user.NotifyWeekly,
 		&user.NotifyMonthly,
-		&user.Watchlist,
 		&user.ProviderLists,
+		&user.TaxJurisdiction,
 		&user.IsAdmin,
 		&user.IsLocked,
 		&user.CreatedAt,
@@ -133,7 +137,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 		SELECT id, email, password_hash, display_name, base_currency, date_format, locale, fire_target, fire_enabled,
 			COALESCE(theme, 'system'), COALESCE(phone_number, ''), date_of_birth,
 			COALESCE(notify_email, true), COALESCE(notify_price_alerts, false), COALESCE(notify_weekly, false), COALESCE(notify_monthly, false),
-			COALESCE(watchlist, ''), COALESCE(provider_lists, ''), COALESCE(is_admin, false), COALESCE(is_locked, false),
+			COALESCE(provider_lists, ''), COALESCE(tax_jurisdiction, 'UK'), COALESCE(is_admin, false), COALESCE(is_locked, false),
 			created_at, updated_at, last_login_at
 		FROM users
 		WHERE email = $1
@@ -157,8 +161,8 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 		&user.NotifyPriceAlerts,
 		&user.NotifyWeekly,
 		&user.NotifyMonthly,
-		&user.Watchlist,
 		&user.ProviderLists,
+		&user.TaxJurisdiction,
 		&user.IsAdmin,
 		&user.IsLocked,
 		&user.CreatedAt,
@@ -179,7 +183,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 	query := `
 		UPDATE users
-		SET display_name = $2, base_currency = $3, date_format = $4, locale = $5, fire_target = $6, fire_enabled = $7, theme = $8, phone_number = $9, date_of_birth = $10, notify_email = $11, notify_price_alerts = $12, notify_weekly = $13, notify_monthly = $14, watchlist = $15, provider_lists = $16, updated_at = $17
+		SET display_name = $2, base_currency = $3, date_format = $4, locale = $5, fire_target = $6, fire_enabled = $7, theme = $8, phone_number = $9, date_of_birth = $10, notify_email = $11, notify_price_alerts = $12, notify_weekly = $13, notify_monthly = $14, provider_lists = $15, tax_jurisdiction = $16, updated_at = $17
 		WHERE id = $1
 	`
 
@@ -200,8 +204,8 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 		user.NotifyPriceAlerts,
 		user.NotifyWeekly,
 		user.NotifyMonthly,
-		user.Watchlist,
 		user.ProviderLists,
+		user.TaxJurisdiction,
 		user.UpdatedAt,
 	)
 
@@ -246,6 +250,25 @@ func (r *UserRepository) UpdateLastLogin(ctx context.Context, id uuid.UUID) erro
 	return err
 }
 
+// GetLastDashboardView returns when the user last caught up on dashboard changes, nil if
+// they never have
+func (r *UserRepository) GetLastDashboardView(ctx context.Context, id uuid.UUID) (*time.Time, error) {
+	var viewedAt *time.Time
+	err := r.pool.QueryRow(ctx, `SELECT last_dashboard_view_at FROM users WHERE id = $1`, id).Scan(&viewedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return viewedAt, nil
+}
+
+func (r *UserRepository) SetLastDashboardView(ctx context.Context, id uuid.UUID, viewedAt time.Time) error {
+	_, err := r.pool.Exec(ctx, `UPDATE users SET last_dashboard_view_at = $2 WHERE id = $1`, id, viewedAt)
+	return err
+}
+
 func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM users WHERE id = $1`
 
@@ -258,7 +281,13 @@ func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 		return ErrUserNotFound
 	}
 
-	return nil
+	// The sync change log and change history have no foreign key to users, so clear
+	// them explicitly
+	if _, err := r.pool.Exec(ctx, `DELETE FROM sync_changes WHERE user_id = $1`, id); err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx, `DELETE FROM change_history WHERE user_id = $1`, id)
+	return err
 }
 
 func (r *UserRepository) EmailExists(ctx context.Context, email string) (bool, error) {
@@ -296,7 +325,7 @@ func (r *UserRepository) List(ctx context.Context) ([]models.User, error) {
 		SELECT id, email, password_hash, display_name, base_currency, date_format, locale, fire_target, fire_enabled,
 			COALESCE(theme, 'system'), COALESCE(phone_number, ''), date_of_birth,
 			COALESCE(notify_email, true), COALESCE(notify_price_alerts, false), COALESCE(notify_weekly, false), COALESCE(notify_monthly, false),
-			COALESCE(watchlist, ''), COALESCE(provider_lists, ''), COALESCE(is_admin, false), COALESCE(is_locked, false),
+			COALESCE(provider_lists, ''), COALESCE(tax_jurisdiction, 'UK'), COALESCE(is_admin, false), COALESCE(is_locked, false),
 			created_at, updated_at, last_login_at
 		FROM users
 		ORDER BY created_at DESC
@@ -328,8 +357,8 @@ func (r *UserRepository) List(ctx context.Context) ([]models.User, error) {
 			&user.NotifyPriceAlerts,
 			&user.NotifyWeekly,
 			&user.NotifyMonthly,
-			&user.Watchlist,
 			&user.ProviderLists,
+			&user.TaxJurisdiction,
 			&user.IsAdmin,
 			&user.IsLocked,
 			&user.CreatedAt,
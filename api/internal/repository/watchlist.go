@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var (
+	ErrWatchlistItemNotFound = errors.New("watchlist item not found")
+	ErrWatchlistItemExists   = errors.New("symbol already on watchlist")
+)
+
+type WatchlistRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewWatchlistRepository(pool *pgxpool.Pool) *WatchlistRepository {
+	return &WatchlistRepository{pool: pool}
+}
+
+// GetByUserID returns the user's watchlist in order
+func (r *WatchlistRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.WatchlistItem, error) {
+	query := `
+		SELECT id, user_id, symbol, position, created_at
+		FROM watchlist_items
+		WHERE user_id = $1
+		ORDER BY position, created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.WatchlistItem
+	for rows.Next() {
+		var item models.WatchlistItem
+		if err := rows.Scan(&item.ID, &item.UserID, &item.Symbol, &item.Position, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+
+	return items, rows.Err()
+}
+
+// Add puts the symbol at the end of the user's watchlist
+func (r *WatchlistRepository) Add(ctx context.Context, item *models.WatchlistItem) error {
+	item.ID = uuid.New()
+	item.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO watchlist_items (id, user_id, symbol, position, created_at)
+		VALUES ($1, $2, $3, (SELECT COALESCE(MAX(position) + 1, 0) FROM watchlist_items WHERE user_id = $2), $4)
+		RETURNING position
+	`
+
+	err := r.pool.QueryRow(ctx, query, item.ID, item.UserID, item.Symbol, item.CreatedAt).Scan(&item.Position)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrWatchlistItemExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *WatchlistRepository) Delete(ctx context.Context, userID uuid.UUID, symbol string) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM watchlist_items WHERE user_id = $1 AND symbol = $2`, userID, symbol)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrWatchlistItemNotFound
+	}
+	return nil
+}
+
+// Reorder sets each symbol's position to its index in symbols
+func (r *WatchlistRepository) Reorder(ctx context.Context, userID uuid.UUID, symbols []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for i, symbol := range symbols {
+		if _, err := tx.Exec(ctx, `UPDATE watchlist_items SET position = $3 WHERE user_id = $1 AND symbol = $2`, userID, symbol, i); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Replace swaps the user's whole watchlist for symbols, in that order
+func (r *WatchlistRepository) Replace(ctx context.Context, userID uuid.UUID, symbols []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM watchlist_items WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for i, symbol := range symbols {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO watchlist_items (id, user_id, symbol, position, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (user_id, symbol) DO NOTHING
+		`, uuid.New(), userID, symbol, i, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
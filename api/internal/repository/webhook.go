@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+type WebhookRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookRepository(pool *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{pool: pool}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	webhook.ID = uuid.New()
+	webhook.CreatedAt = time.Now()
+	webhook.UpdatedAt = time.Now()
+
+	query := `
+		INSERT INTO webhooks (id, user_id, url, secret, events, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		webhook.ID,
+		webhook.UserID,
+		webhook.URL,
+		webhook.Secret,
+		webhook.Events,
+		webhook.IsActive,
+		webhook.CreatedAt,
+		webhook.UpdatedAt,
+	)
+	return err
+}
+
+// GetByID returns the webhook including its secret
+func (r *WebhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	query := `
+		SELECT id, user_id, url, secret, events, is_active, last_delivery_at, last_status, last_error, created_at, updated_at
+		FROM webhooks
+		WHERE id = $1
+	`
+
+	webhook, err := scanWebhook(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// GetByUserID returns the user's webhooks, oldest first
+func (r *WebhookRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Webhook, error) {
+	query := `
+		SELECT id, user_id, url, secret, events, is_active, last_delivery_at, last_status, last_error, created_at, updated_at
+		FROM webhooks
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	return r.query(ctx, query, userID)
+}
+
+// GetActiveForEvent returns the user's active webhooks subscribed to the event
+func (r *WebhookRepository) GetActiveForEvent(ctx context.Context, userID uuid.UUID, event string) ([]*models.Webhook, error) {
+	query := `
+		SELECT id, user_id, url, secret, events, is_active, last_delivery_at, last_status, last_error, created_at, updated_at
+		FROM webhooks
+		WHERE user_id = $1 AND is_active AND $2 = ANY(events)
+	`
+
+	return r.query(ctx, query, userID, event)
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, webhook *models.Webhook) error {
+	webhook.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE webhooks
+		SET url = $2, events = $3, is_active = $4, updated_at = $5
+		WHERE id = $1
+	`
+
+	result, err := r.pool.Exec(ctx, query, webhook.ID, webhook.URL, webhook.Events, webhook.IsActive, webhook.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// RecordDelivery saves the outcome of the latest delivery attempt. status is nil when
+// no response was received.
+func (r *WebhookRepository) RecordDelivery(ctx context.Context, id uuid.UUID, status *int, deliveryErr string) error {
+	query := `
+		UPDATE webhooks
+		SET last_delivery_at = $2, last_status = $3, last_error = $4
+		WHERE id = $1
+	`
+
+	_, err := r.pool.Exec(ctx, query, id, time.Now(), status, deliveryErr)
+	return err
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.Webhook, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func scanWebhook(row pgx.Row) (*models.Webhook, error) {
+	var webhook models.Webhook
+
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		&webhook.Secret,
+		&webhook.Events,
+		&webhook.IsActive,
+		&webhook.LastDeliveryAt,
+		&webhook.LastStatus,
+		&webhook.LastError,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+var ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+
+const webhookDeliveryColumns = `id, webhook_id, user_id, event, payload, status, attempts, next_attempt_at, deadline_at, last_status, last_error, created_at, delivered_at`
+
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, d *models.WebhookDelivery) error {
+	d.ID = uuid.New()
+	d.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO webhook_deliveries (id, webhook_id, user_id, event, payload, status, attempts, next_attempt_at, deadline_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		d.ID,
+		d.WebhookID,
+		d.UserID,
+		d.Event,
+		[]byte(d.Payload),
+		d.Status,
+		d.Attempts,
+		d.NextAttemptAt,
+		d.DeadlineAt,
+		d.CreatedAt,
+	)
+	return err
+}
+
+func (r *WebhookRepository) GetDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+
+	d, err := scanWebhookDelivery(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// GetDeliveriesByUserID returns the user's most recent deliveries, optionally only
+// those with one status
+func (r *WebhookRepository) GetDeliveriesByUserID(ctx context.Context, userID uuid.UUID, status string, limit int) ([]*models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE user_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// ClaimDueDeliveries returns retries that are due, pushing their next attempt back by
+// lease so another instance (or a slow attempt) doesn't pick them up again
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'RETRYING' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + webhookDeliveryColumns
+
+	rows, err := r.pool.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// UpdateDelivery saves a delivery's retry state
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, d *models.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, deadline_at = $5, last_status = $6, last_error = $7, delivered_at = $8
+		WHERE id = $1
+	`
+
+	result, err := r.pool.Exec(ctx, query,
+		d.ID,
+		d.Status,
+		d.Attempts,
+		d.NextAttemptAt,
+		d.DeadlineAt,
+		d.LastStatus,
+		d.LastError,
+		d.DeliveredAt,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrWebhookDeliveryNotFound
+	}
+
+	return nil
+}
+
+func scanWebhookDelivery(row pgx.Row) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload []byte
+
+	err := row.Scan(
+		&d.ID,
+		&d.WebhookID,
+		&d.UserID,
+		&d.Event,
+		&payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.DeadlineAt,
+		&d.LastStatus,
+		&d.LastError,
+		&d.CreatedAt,
+		&d.DeliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Payload = payload
+	return &d, nil
+}
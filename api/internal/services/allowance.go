@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/pkg/taxyear"
+)
+
+// Annual subscription allowances (2024/25)
+const (
+	ISAAnnualAllowance  = 20000 // shared by all ISAs including LISAs
+	LISAAnnualAllowance = 4000
+	JISAAnnualAllowance = 9000  // per child
+	SIPPAnnualAllowance = 60000 // pension annual allowance, measured on gross contributions
+)
+
+// SIPPBasicRateRelief is the top-up claimed on a relief at source contribution: basic
+// rate relief of 20% of the gross contribution is 25% of the net amount paid in
+const SIPPBasicRateRelief = 0.25
+
+// AnnualAllowance returns the subscription allowance for the portfolio type, or 0 if it has none
+func AnnualAllowance(portfolioType string) float64 {
+	switch portfolioType {
+	case models.PortfolioTypeISA:
+		return ISAAnnualAllowance
+	case models.PortfolioTypeLISA:
+		return LISAAnnualAllowance
+	case models.PortfolioTypeJISA:
+		return JISAAnnualAllowance
+	default:
+		return 0
+	}
+}
+
+// AllowanceService tracks contributions towards ISA, LISA and JISA allowances per UK tax
+// year, counting buys, deposits and transfers in. It also adds the tax relief top-ups on
+// relief at source SIPP contributions and tracks them against the pension allowance.
+type AllowanceService struct {
+	portfolioRepo *repository.PortfolioRepository
+	txRepo        *repository.TransactionRepository
+	logger        *slog.Logger
+}
+
+func NewAllowanceService(portfolioRepo *repository.PortfolioRepository, txRepo *repository.TransactionRepository, logger *slog.Logger) *AllowanceService {
+	return &AllowanceService{
+		portfolioRepo: portfolioRepo,
+		txRepo:        txRepo,
+		logger:        logger,
+	}
+}
+
+// IsContribution returns true if the transaction type counts towards an allowance
+func IsContribution(txType string) bool {
+	switch txType {
+	case models.TransactionTypeBuy, models.TransactionTypeDeposit, models.TransactionTypeTransferIn:
+		return true
+	default:
+		return false
+	}
+}
+
+// Status returns the portfolio's allowance for the tax year, or nil if its type has none
+func (s *AllowanceService) Status(ctx context.Context, portfolio *models.Portfolio, taxYear string) (*models.AllowanceStatus, error) {
+	if !repository.HasContributionLimit(portfolio.Type) {
+		return nil, nil
+	}
+
+	portfolios, err := s.portfolioRepo.GetByUserID(ctx, portfolio.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every ISA and LISA shares the ISA allowance; JISAs share the child's
+	var sharing []*models.Portfolio
+	for _, p := range portfolios {
+		switch portfolio.Type {
+		case models.PortfolioTypeISA, models.PortfolioTypeLISA:
+			if p.Type == models.PortfolioTypeISA || p.Type == models.PortfolioTypeLISA {
+				sharing = append(sharing, p)
+			}
+		case models.PortfolioTypeJISA:
+			if p.Type == models.PortfolioTypeJISA && childName(p) == childName(portfolio) {
+				sharing = append(sharing, p)
+			}
+		}
+	}
+
+	portfolioIDs := make([]uuid.UUID, 0, len(sharing))
+	types := make(map[uuid.UUID]string, len(sharing))
+	for _, p := range sharing {
+		portfolioIDs = append(portfolioIDs, p.ID)
+		types[p.ID] = p.Type
+	}
+
+	contributions, err := s.txRepo.GetContributionsByTaxYear(ctx, portfolioIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var total, lisa, own float64
+	for _, c := range contributions {
+		if c.TaxYear != taxYear {
+			continue
+		}
+		total += c.Total
+		if types[c.PortfolioID] == models.PortfolioTypeLISA {
+			lisa += c.Total
+		}
+		if c.PortfolioID == portfolio.ID {
+			own += c.Total
+		}
+	}
+
+	status := &models.AllowanceStatus{
+		TaxYear:              taxYear,
+		Wrapper:              portfolio.Type,
+		Allowance:            AnnualAllowance(portfolio.Type),
+		Contributed:          roundPence(total),
+		PortfolioContributed: roundPence(own),
+	}
+	status.Remaining = math.Max(status.Allowance-total, 0)
+	if portfolio.Type == models.PortfolioTypeLISA {
+		status.Contributed = roundPence(lisa)
+		status.Remaining = math.Min(math.Max(LISAAnnualAllowance-lisa, 0), math.Max(ISAAnnualAllowance-total, 0))
+	}
+	status.Remaining = roundPence(status.Remaining)
+	status.ExceededBy = roundPence(math.Max(status.Contributed-status.Allowance, 0))
+
+	return status, nil
+}
+
+// Check returns the allowance status as it would be after a transaction of the type for
+// amount on date, or nil if it isn't a contribution: a buy in a portfolio that records
+// cash paid in during the same tax year. ExceededBy is set if the contribution would take it over the allowance.
+func (s *AllowanceService) Check(ctx context.Context, portfolio *models.Portfolio, txType string, amount float64, date time.Time) (*models.AllowanceStatus, error) {
+	if txType == models.TransactionTypeBuy {
+		funded, err := s.txRepo.HasCashContributions(ctx, portfolio.ID, date)
+		if err != nil || funded {
+			return nil, err
+		}
+	}
+
+	status, err := s.Status(ctx, portfolio, taxyear.UKOf(date))
+	if err != nil || status == nil {
+		return status, err
+	}
+
+	over := amount - status.Remaining
+	status.Contributed = roundPence(status.Contributed + amount)
+	status.PortfolioContributed = roundPence(status.PortfolioContributed + amount)
+	status.Remaining = roundPence(math.Max(status.Remaining-amount, 0))
+	if over > 0 {
+		status.ExceededBy = roundPence(over)
+	}
+	return status, nil
+}
+
+// Sync stores the portfolio's contributions for the current tax year and its allowance in
+// its metadata. Failures are logged, as contribution tracking is secondary to the change
+// that triggered it.
+func (s *AllowanceService) Sync(ctx context.Context, portfolio *models.Portfolio) {
+	status, err := s.Status(ctx, portfolio, taxyear.UKOf(time.Now()))
+	if err != nil {
+		s.logger.Error("failed to calculate allowance", "portfolio_id", portfolio.ID, "error", err)
+		return
+	}
+	if status == nil {
+		return
+	}
+
+	if err := s.portfolioRepo.SetContributions(ctx, portfolio.ID, status.PortfolioContributed, status.Allowance); err != nil {
+		s.logger.Error("failed to store contributions", "portfolio_id", portfolio.ID, "error", err)
+	}
+}
+
+// HasReliefAtSource returns true if basic rate tax relief is claimed on the portfolio's
+// contributions and added to it, as opposed to relief given through payroll
+func HasReliefAtSource(p *models.Portfolio) bool {
+	return p.Type == models.PortfolioTypeSIPP && p.Metadata != nil && p.Metadata.TaxReliefType == models.SIPPReliefAtSource
+}
+
+// TaxRelief returns the government top-up on a deposit into a relief at source SIPP,
+// or nil if the deposit doesn't attract relief. Transfers in from other pensions don't.
+func TaxRelief(portfolio *models.Portfolio, contribution *models.Transaction) *models.Transaction {
+	if !HasReliefAtSource(portfolio) || contribution.TransactionType != models.TransactionTypeDeposit {
+		return nil
+	}
+	relief := taxReliefOn(contribution)
+	if relief.TotalAmount <= 0 {
+		return nil
+	}
+	return relief
+}
+
+// AddTaxRelief records the top-up on a contribution that has already been saved,
+// returning nil if it doesn't attract relief
+func (s *AllowanceService) AddTaxRelief(ctx context.Context, portfolio *models.Portfolio, contribution *models.Transaction) (*models.Transaction, error) {
+	relief := TaxRelief(portfolio, contribution)
+	if relief == nil {
+		return nil, nil
+	}
+
+	if err := s.txRepo.CreateTaxRelief(ctx, relief, contribution.ID); err != nil {
+		return nil, err
+	}
+	return relief, nil
+}
+
+// SyncTaxRelief keeps the top-up on an edited deposit in line with its amount and date.
+// A top-up the user deleted is not recreated. Failures are logged, as with Sync.
+func (s *AllowanceService) SyncTaxRelief(ctx context.Context, portfolio *models.Portfolio, contribution *models.Transaction) {
+	if !HasReliefAtSource(portfolio) || contribution.TransactionType != models.TransactionTypeDeposit {
+		return
+	}
+	err := s.txRepo.UpdateTaxRelief(ctx, contribution.ID, taxReliefOn(contribution))
+	if err != nil && !errors.Is(err, repository.ErrTransactionNotFound) {
+		s.logger.Error("failed to update tax relief", "transaction_id", contribution.ID, "error", err)
+	}
+}
+
+// taxReliefOn is the top-up on the contribution, converted into the portfolio's
+// currency at the contribution's rate
+func taxReliefOn(contribution *models.Transaction) *models.Transaction {
+	relief := &models.Transaction{
+		PortfolioID:     contribution.PortfolioID,
+		TotalAmount:     roundPence(contribution.TotalAmount * SIPPBasicRateRelief),
+		Currency:        contribution.Currency,
+		TransactionDate: contribution.TransactionDate,
+		Notes:           fmt.Sprintf("Basic rate tax relief on %.2f %s contribution", contribution.TotalAmount, contribution.Currency),
+	}
+	if contribution.FXRate != nil {
+		rate := *contribution.FXRate
+		amount := roundPence(relief.TotalAmount * rate)
+		relief.FXRate, relief.PortfolioAmount = &rate, &amount
+	}
+	return relief
+}
+
+// PensionStatus returns the pension annual allowance used in the UK tax year across all
+// the user's SIPPs, or nil if the portfolio isn't a SIPP
+func (s *AllowanceService) PensionStatus(ctx context.Context, portfolio *models.Portfolio, year taxyear.Year) (*models.PensionAllowanceStatus, error) {
+	if portfolio.Type != models.PortfolioTypeSIPP {
+		return nil, nil
+	}
+
+	portfolios, err := s.portfolioRepo.GetByUserID(ctx, portfolio.UserID)
+	if err != nil {
+		return nil, err
+	}
+	var portfolioIDs []uuid.UUID
+	for _, p := range portfolios {
+		if p.Type == models.PortfolioTypeSIPP {
+			portfolioIDs = append(portfolioIDs, p.ID)
+		}
+	}
+
+	contributions, err := s.txRepo.GetPensionContributions(ctx, portfolioIDs, year.From(), year.To())
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.PensionAllowanceStatus{
+		TaxYear:   year.String(),
+		Allowance: SIPPAnnualAllowance,
+	}
+	for _, c := range contributions {
+		status.NetContributed += c.Net
+		status.TaxRelief += c.TaxRelief
+		if c.PortfolioID == portfolio.ID {
+			status.PortfolioNetContributed += c.Net
+			status.PortfolioGrossContributed += c.Net + c.TaxRelief
+		}
+	}
+	status.GrossContributed = roundPence(status.NetContributed + status.TaxRelief)
+	status.NetContributed = roundPence(status.NetContributed)
+	status.TaxRelief = roundPence(status.TaxRelief)
+	status.PortfolioNetContributed = roundPence(status.PortfolioNetContributed)
+	status.PortfolioGrossContributed = roundPence(status.PortfolioGrossContributed)
+	status.Remaining = roundPence(math.Max(status.Allowance-status.GrossContributed, 0))
+	status.ExceededBy = roundPence(math.Max(status.GrossContributed-status.Allowance, 0))
+
+	return status, nil
+}
+
+// childName identifies the child a JISA belongs to; unnamed JISAs are treated as one child
+func childName(p *models.Portfolio) string {
+	if p.Metadata == nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(p.Metadata.ChildName))
+}
@@ -248,6 +248,52 @@ func (s *AuthService) Logout(ctx context.Context, accessToken string) error {
 	return nil
 }
 
+// EnsureDemoUser creates the shared demo user if it doesn't exist. The password is random
+// because demo sessions are issued by DemoLogin rather than the login form.
+func (s *AuthService) EnsureDemoUser(ctx context.Context, email string) (*models.User, error) {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(uuid.NewString()), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	user = &models.User{
+		Email:        email,
+		PasswordHash: string(hashedPassword),
+		DisplayName:  "Demo User",
+		BaseCurrency: "GBP",
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	_ = s.ensureFixedAssetsPortfolio(ctx, user.ID, user.BaseCurrency)
+
+	return user, nil
+}
+
+// DemoLogin issues tokens for the shared demo user without a password
+func (s *AuthService) DemoLogin(ctx context.Context, email string) (*AuthTokens, *models.User, error) {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.generateTokens(user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tokens, user, nil
+}
+
 // GetTokenBlacklist returns the token blacklist service
 func (s *AuthService) GetTokenBlacklist() *TokenBlacklist {
 	return s.tokenBlacklist
@@ -0,0 +1,442 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/openbanking"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/pkg/validator"
+)
+
+const (
+	bankSyncJob           = "bank_sync"
+	bankSyncCheckInterval = time.Hour
+	// bankHistoryDays is how much transaction history is imported on connecting
+	bankHistoryDays = 90
+	// bankAccessDays is how long consent lasts before the user has to reconnect
+	bankAccessDays = 90
+	// bankSyncOverlapDays re-reads a few days before the last sync, as banks can book
+	// transactions a few days after they are made
+	bankSyncOverlapDays = 5
+	maxBankDescription  = 255
+)
+
+var (
+	ErrOpenBankingDisabled  = errors.New("open banking is not configured")
+	ErrBankConsentPending   = errors.New("bank consent has not been given yet")
+	ErrBankConsentRefused   = errors.New("bank consent was refused")
+	ErrBankConnectionClosed = errors.New("bank connection needs reconnecting")
+)
+
+// BankSyncService connects cash accounts to banks over Open Banking and keeps their
+// balances and movements in sync. The provider client is nil when Open Banking isn't
+// configured, which disables the service.
+type BankSyncService struct {
+	connRepo      *repository.BankConnectionRepository
+	portfolioRepo *repository.PortfolioRepository
+	cashRepo      *repository.CashAccountRepository
+	movementRepo  *repository.CashMovementRepository
+	cache         *DashboardCache
+	client        *openbanking.Client
+	redirectURL   string
+	interval      time.Duration
+	jobs          *JobManager
+	logger        *slog.Logger
+}
+
+func NewBankSyncService(
+	connRepo *repository.BankConnectionRepository,
+	portfolioRepo *repository.PortfolioRepository,
+	cashRepo *repository.CashAccountRepository,
+	movementRepo *repository.CashMovementRepository,
+	cache *DashboardCache,
+	client *openbanking.Client,
+	redirectURL string,
+	interval time.Duration,
+	jobs *JobManager,
+	logger *slog.Logger,
+) *BankSyncService {
+	return &BankSyncService{
+		connRepo:      connRepo,
+		portfolioRepo: portfolioRepo,
+		cashRepo:      cashRepo,
+		movementRepo:  movementRepo,
+		cache:         cache,
+		client:        client,
+		redirectURL:   redirectURL,
+		interval:      interval,
+		jobs:          jobs,
+		logger:        logger,
+	}
+}
+
+// Enabled reports whether Open Banking is configured
+func (s *BankSyncService) Enabled() bool {
+	return s.client != nil
+}
+
+// Institutions lists the banks that can be connected in the country
+func (s *BankSyncService) Institutions(ctx context.Context, country string) ([]openbanking.Institution, error) {
+	if !s.Enabled() {
+		return nil, ErrOpenBankingDisabled
+	}
+	return s.client.Institutions(ctx, country)
+}
+
+// Connect starts a connection to the bank for the portfolio, returning it as PENDING with
+// the link the user follows to give consent
+func (s *BankSyncService) Connect(ctx context.Context, portfolio *models.Portfolio, institutionID string) (*models.BankConnection, string, error) {
+	if !s.Enabled() {
+		return nil, "", ErrOpenBankingDisabled
+	}
+
+	institution, err := s.client.Institution(ctx, institutionID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	conn := &models.BankConnection{
+		ID:              uuid.New(),
+		UserID:          portfolio.UserID,
+		PortfolioID:     portfolio.ID,
+		Provider:        models.BankProviderGoCardless,
+		InstitutionID:   institution.ID,
+		InstitutionName: institution.Name,
+		Status:          models.BankConnectionPending,
+	}
+	history := bankHistoryDays
+	if institution.TransactionTotalDays > 0 && institution.TransactionTotalDays < history {
+		history = institution.TransactionTotalDays
+	}
+	requisition, err := s.client.CreateRequisition(ctx, institution.ID, conn.ID.String(), s.redirectURL, history, bankAccessDays)
+	if err != nil {
+		return nil, "", err
+	}
+	conn.RequisitionID = requisition.ID
+
+	if err := s.connRepo.Create(ctx, conn); err != nil {
+		_ = s.client.DeleteRequisition(ctx, requisition.ID)
+		return nil, "", err
+	}
+	return conn, requisition.Link, nil
+}
+
+// Complete finishes a connection once the user has given consent: each account it
+// covers gets a cash account in the connection's portfolio and is synced. Sync failures
+// are recorded on the connection rather than returned.
+func (s *BankSyncService) Complete(ctx context.Context, conn *models.BankConnection) error {
+	if !s.Enabled() {
+		return ErrOpenBankingDisabled
+	}
+
+	requisition, err := s.client.Requisition(ctx, conn.RequisitionID)
+	if err != nil {
+		return err
+	}
+	switch requisition.Status {
+	case openbanking.RequisitionLinked:
+	case openbanking.RequisitionRejected:
+		conn.Status = models.BankConnectionError
+		conn.LastError = ErrBankConsentRefused.Error()
+		if err := s.connRepo.UpdateStatus(ctx, conn); err != nil {
+			return err
+		}
+		return ErrBankConsentRefused
+	case openbanking.RequisitionExpired, openbanking.RequisitionSuspended:
+		conn.Status = models.BankConnectionExpired
+		if err := s.connRepo.UpdateStatus(ctx, conn); err != nil {
+			return err
+		}
+		return ErrBankConnectionClosed
+	default:
+		return ErrBankConsentPending
+	}
+
+	if err := s.linkAccounts(ctx, conn, requisition.Accounts); err != nil {
+		return err
+	}
+
+	if conn.Status != models.BankConnectionLinked {
+		expires := time.Now().AddDate(0, 0, bankAccessDays)
+		conn.Status = models.BankConnectionLinked
+		conn.ExpiresAt = &expires
+		conn.LastError = ""
+	}
+	if err := s.Sync(ctx, conn); err != nil {
+		s.logger.Warn("initial bank sync failed", "connection_id", conn.ID, "error", err)
+	}
+
+	updated, err := s.connRepo.GetByID(ctx, conn.ID)
+	if err != nil {
+		return err
+	}
+	*conn = *updated
+	return nil
+}
+
+// linkAccounts creates a cash account for each bank account not yet linked. Accounts in
+// currencies that aren't supported are skipped.
+func (s *BankSyncService) linkAccounts(ctx context.Context, conn *models.BankConnection, accountIDs []string) error {
+	linked, err := s.connRepo.GetLinkedAccounts(ctx, conn.ID)
+	if err != nil {
+		return err
+	}
+	portfolio, err := s.portfolioRepo.GetByID(ctx, conn.PortfolioID)
+	if err != nil {
+		return err
+	}
+	accountType := models.CashAccountTypeCurrent
+	if portfolio.Type == models.PortfolioTypeSavings {
+		accountType = models.CashAccountTypeSavings
+	}
+
+	for _, externalID := range accountIDs {
+		if _, ok := linked[externalID]; ok {
+			continue
+		}
+		details, err := s.client.AccountDetails(ctx, externalID)
+		if err != nil {
+			return err
+		}
+		currency := details.Currency
+		if currency == "" {
+			currency = portfolio.Currency
+		}
+		if !validator.IsValidCurrency(currency) {
+			s.logger.Warn("skipping bank account in unsupported currency", "connection_id", conn.ID, "currency", currency)
+			continue
+		}
+
+		name := details.Name
+		if name == "" {
+			name = details.Product
+		}
+		if name == "" {
+			name = conn.InstitutionName
+		}
+		account := &models.CashAccount{
+			PortfolioID: conn.PortfolioID,
+			AccountName: truncate(name, 100),
+			AccountType: accountType,
+			Institution: truncate(conn.InstitutionName, 100),
+			Currency:    currency,
+		}
+		if err := s.cashRepo.Create(ctx, account); err != nil {
+			return err
+		}
+		if err := s.connRepo.LinkAccount(ctx, conn.ID, account.ID, externalID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync imports the booked transactions on each linked account since its last sync (or
+// the last 90 days) and brings the balance into line with the bank's. The outcome is
+// recorded on the connection and its accounts; an account that fails doesn't stop the
+// others, and consent that has expired marks the connection EXPIRED.
+func (s *BankSyncService) Sync(ctx context.Context, conn *models.BankConnection) error {
+	if !s.Enabled() {
+		return ErrOpenBankingDisabled
+	}
+	switch conn.Status {
+	case models.BankConnectionLinked:
+	case models.BankConnectionPending:
+		return ErrBankConsentPending
+	default:
+		return ErrBankConnectionClosed
+	}
+
+	synced, failed, err := s.syncAccounts(ctx, conn)
+	now := time.Now()
+	if synced > 0 || (err == nil && len(failed) == 0) {
+		conn.LastSyncedAt = &now
+	}
+	switch {
+	case errors.Is(err, openbanking.ErrAccessExpired):
+		conn.Status = models.BankConnectionExpired
+		conn.LastError = err.Error()
+		err = ErrBankConnectionClosed
+	case err != nil:
+		conn.LastError = strings.Join(append(failed, err.Error()), "; ")
+	default:
+		conn.LastError = strings.Join(failed, "; ")
+		if synced == 0 && len(failed) > 0 {
+			err = errors.New(conn.LastError)
+		}
+	}
+
+	if updateErr := s.connRepo.UpdateStatus(ctx, conn); updateErr != nil {
+		return updateErr
+	}
+	return err
+}
+
+// syncAccounts syncs each linked account, returning how many synced and why the others
+// failed. Each account's outcome is recorded on its link. The error is one that stops
+// the whole connection, such as expired consent.
+func (s *BankSyncService) syncAccounts(ctx context.Context, conn *models.BankConnection) (int, []string, error) {
+	if conn.ExpiresAt != nil && time.Now().After(*conn.ExpiresAt) {
+		return 0, nil, openbanking.ErrAccessExpired
+	}
+
+	linked, err := s.connRepo.GetLinkedAccounts(ctx, conn.ID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	synced := 0
+	var failed []string
+	for externalID, link := range linked {
+		account, err := s.cashRepo.GetByID(ctx, link.CashAccountID)
+		if err != nil {
+			if errors.Is(err, repository.ErrCashAccountNotFound) {
+				continue
+			}
+			return synced, failed, err
+		}
+
+		err = s.syncAccount(ctx, conn, account, externalID, link.LastSyncedAt)
+		switch {
+		case err == nil:
+			synced++
+			now := time.Now()
+			err = s.connRepo.UpdateAccountSync(ctx, account.ID, &now, "")
+		case errors.Is(err, openbanking.ErrAccessExpired), errors.Is(err, openbanking.ErrInvalidCredentials), ctx.Err() != nil:
+			return synced, failed, err
+		default:
+			failed = append(failed, fmt.Sprintf("%s: %v", account.AccountName, err))
+			s.logger.Warn("bank account sync failed", "connection_id", conn.ID, "cash_account_id", account.ID, "error", err)
+			err = s.connRepo.UpdateAccountSync(ctx, account.ID, nil, err.Error())
+		}
+		if err != nil {
+			return synced, failed, err
+		}
+	}
+	return synced, failed, nil
+}
+
+// syncAccount imports the bank account's transactions since lastSynced, less a few days'
+// overlap, into the cash account and sets its balance to the bank's
+func (s *BankSyncService) syncAccount(ctx context.Context, conn *models.BankConnection, account *models.CashAccount, externalID string, lastSynced *time.Time) error {
+	from := time.Now().AddDate(0, 0, -bankHistoryDays)
+	if lastSynced != nil {
+		from = lastSynced.AddDate(0, 0, -bankSyncOverlapDays)
+	}
+
+	txs, err := s.client.Transactions(ctx, externalID, from)
+	if err != nil {
+		return err
+	}
+	balance, currency, err := s.client.Balance(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	if currency != "" && currency != account.Currency {
+		return fmt.Errorf("bank reports %s balance for %s account", currency, account.Currency)
+	}
+
+	movements := make([]*models.CashMovement, 0, len(txs))
+	for _, t := range txs {
+		if t.Currency != "" && t.Currency != account.Currency {
+			continue
+		}
+		movements = append(movements, bankMovement(t))
+	}
+
+	imported, err := s.movementRepo.ApplyBankSync(ctx, account.ID, movements, balance)
+	if err != nil {
+		return err
+	}
+	s.cache.Invalidate(ctx, conn.UserID)
+	if imported > 0 {
+		s.logger.Info("synced bank transactions", "connection_id", conn.ID, "cash_account_id", account.ID, "imported", imported)
+	}
+	return nil
+}
+
+// bankMovement turns a bank transaction into a cash movement. Money in whose description
+// mentions interest is recorded as INTEREST so it counts in the interest reports.
+func bankMovement(t openbanking.Transaction) *models.CashMovement {
+	m := &models.CashMovement{
+		MovementType: models.CashMovementWithdrawal,
+		Amount:       roundPence(t.Amount),
+		MovementDate: t.Date,
+		Description:  truncate(strings.TrimSpace(t.Description), maxBankDescription),
+		ExternalID:   t.ID,
+	}
+	if t.Amount > 0 {
+		m.MovementType = models.CashMovementDeposit
+		if strings.Contains(strings.ToLower(t.Description), "interest") {
+			m.MovementType = models.CashMovementInterest
+		}
+	}
+	return m
+}
+
+// Disconnect withdraws consent at the provider and removes the connection. Its cash
+// accounts stay, to be updated by hand.
+func (s *BankSyncService) Disconnect(ctx context.Context, conn *models.BankConnection) error {
+	if s.Enabled() {
+		if err := s.client.DeleteRequisition(ctx, conn.RequisitionID); err != nil {
+			s.logger.Warn("failed to delete bank requisition", "connection_id", conn.ID, "error", err)
+		}
+	}
+	return s.connRepo.Delete(ctx, conn.ID)
+}
+
+// Run syncs connections that are due every hour until ctx is cancelled. Banks limit how
+// often accounts can be read, so each connection syncs once per interval.
+func (s *BankSyncService) Run(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(bankSyncCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.jobs.Run(bankSyncJob, s.syncDue); errors.Is(err, ErrShuttingDown) {
+			s.logger.Info("skipping bank sync during shutdown")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *BankSyncService) syncDue(ctx context.Context) error {
+	conns, err := s.connRepo.GetDue(ctx, time.Now().Add(-s.interval))
+	if err != nil {
+		return err
+	}
+
+	for _, conn := range conns {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := s.Sync(ctx, conn); err != nil {
+			s.logger.Warn("bank sync failed", "connection_id", conn.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/pkg/validator"
+)
+
+var (
+	ErrInvalidCurrency = errors.New("invalid currency")
+	ErrSameCurrency    = errors.New("currency is already the base currency")
+	ErrFXUnavailable   = errors.New("exchange rates unavailable")
+)
+
+// fxHistoryPeriod is how far back daily FX rates are fetched to revalue snapshots.
+// Older snapshots use the earliest rate available.
+const fxHistoryPeriod = "10y"
+
+// BaseCurrencyService changes a user's base currency, converting the values that were
+// recorded in the old one so reports stay consistent across the switch
+type BaseCurrencyService struct {
+	userRepo     *repository.UserRepository
+	snapshotRepo *repository.SnapshotRepository
+	changeRepo   *repository.CurrencyChangeRepository
+	yahooService *YahooService
+}
+
+func NewBaseCurrencyService(
+	userRepo *repository.UserRepository,
+	snapshotRepo *repository.SnapshotRepository,
+	changeRepo *repository.CurrencyChangeRepository,
+	yahooService *YahooService,
+) *BaseCurrencyService {
+	return &BaseCurrencyService{
+		userRepo:     userRepo,
+		snapshotRepo: snapshotRepo,
+		changeRepo:   changeRepo,
+		yahooService: yahooService,
+	}
+}
+
+// Change switches the user's base currency. Net worth snapshots are converted at the
+// rate on their own date and the FIRE target at today's rate, all in one transaction;
+// if any rate can't be fetched nothing is changed.
+func (s *BaseCurrencyService) Change(ctx context.Context, userID uuid.UUID, currency string) (*models.BaseCurrencyChange, error) {
+	if !validator.IsValidCurrency(currency) {
+		return nil, ErrInvalidCurrency
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.BaseCurrency == currency {
+		return nil, ErrSameCurrency
+	}
+
+	snapshots, err := s.snapshotRepo.GetByUserIDInRange(ctx, userID, time.Time{}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshots are normally in the old base currency, but convert each from its own
+	rates := make(map[string][]PriceHistory)
+	rates[user.BaseCurrency] = nil
+	for _, snap := range snapshots {
+		if snap.Currency != "" && snap.Currency != currency {
+			rates[snap.Currency] = nil
+		}
+	}
+	for from := range rates {
+		history, err := s.yahooService.fetchHistory(ctx, fxSymbol(from, currency), fxHistoryPeriod, "1d")
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s/%s", ErrFXUnavailable, from, currency)
+		}
+		// Yahoo reports missing days as zero closes
+		valid := history[:0]
+		for _, h := range history {
+			if h.Close > 0 {
+				valid = append(valid, h)
+			}
+		}
+		history = valid
+		if len(history) == 0 {
+			return nil, fmt.Errorf("%w: %s/%s", ErrFXUnavailable, from, currency)
+		}
+		sort.Slice(history, func(i, j int) bool { return history[i].Date.Before(history[j].Date) })
+		rates[from] = history
+	}
+
+	revalued := make([]*models.NetWorthSnapshot, 0, len(snapshots))
+	for _, snap := range snapshots {
+		from := snap.Currency
+		if from == "" {
+			from = user.BaseCurrency
+		}
+		if from == currency {
+			continue
+		}
+		rate := closeOn(rates[from], snap.SnapshotDate)
+		snap.TotalNetWorth = convertMoney(snap.TotalNetWorth, rate)
+		snap.Investments = convertMoney(snap.Investments, rate)
+		snap.Cash = convertMoney(snap.Cash, rate)
+		snap.FixedAssets = convertMoney(snap.FixedAssets, rate)
+		snap.Liabilities = convertMoney(snap.Liabilities, rate)
+		for id, v := range snap.PortfolioValues {
+			snap.PortfolioValues[id] = convertMoney(v, rate)
+		}
+		snap.Currency = currency
+		revalued = append(revalued, snap)
+	}
+
+	current := rates[user.BaseCurrency]
+	rate := current[len(current)-1].Close
+	if price, err := s.yahooService.GetPrice(ctx, fxSymbol(user.BaseCurrency, currency)); err == nil && price > 0 {
+		rate = price
+	}
+
+	fireTarget := user.FireTarget
+	if fireTarget != nil {
+		converted := convertMoney(*fireTarget, rate)
+		fireTarget = &converted
+	}
+
+	change := &models.BaseCurrencyChange{
+		UserID:            userID,
+		FromCurrency:      user.BaseCurrency,
+		ToCurrency:        currency,
+		FXRate:            rate,
+		SnapshotsRevalued: len(revalued),
+	}
+	if err := s.changeRepo.Apply(ctx, change, revalued, fireTarget); err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// History returns the user's base currency changes, most recent first
+func (s *BaseCurrencyService) History(ctx context.Context, userID uuid.UUID) ([]*models.BaseCurrencyChange, error) {
+	return s.changeRepo.GetByUserID(ctx, userID)
+}
+
+// fxSymbol is Yahoo's symbol for the rate converting from into to, e.g. GBPEUR=X
+func fxSymbol(from, to string) string {
+	return from + to + "=X"
+}
+
+// rateOn returns the last close on or before date, or the earliest if date precedes
+// the history. history must be sorted oldest first and not empty.
+func closeOn(history []PriceHistory, date time.Time) float64 {
+	end := date.AddDate(0, 0, 1)
+	i := sort.Search(len(history), func(i int) bool { return !history[i].Date.Before(end) })
+	if i == 0 {
+		return history[0].Close
+	}
+	return history[i-1].Close
+}
+
+func convertMoney(v, rate float64) float64 {
+	return math.Round(v*rate*100) / 100
+}
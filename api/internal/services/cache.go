@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+
+	"github.com/mark-regan/wellf/internal/database"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+var ErrUnknownCacheNamespace = errors.New("unknown cache namespace")
+
+// cacheNamespaces groups the Redis cache key prefixes operators can clear together
+var cacheNamespaces = map[string][]string{
+	"quotes":    {"yahoo:quote:", "yahoo:price:", "yahoo:historical:"},
+	"history":   {"yahoo:history:", "yahoo:sparkline:"},
+	"search":    {"yahoo:search:"},
+	"dashboard": {dashboardCachePrefix},
+}
+
+// CacheService clears cached market data so stale or bad entries can be dropped
+// without restarting the API or flushing all of Redis. Rate limits, sessions and
+// tasks live outside the cache namespace and are never touched.
+type CacheService struct {
+	redis        *database.RedisClient
+	assetRepo    *repository.AssetRepository
+	yahooService *YahooService
+	logger       *slog.Logger
+}
+
+func NewCacheService(redis *database.RedisClient, assetRepo *repository.AssetRepository, yahooService *YahooService, logger *slog.Logger) *CacheService {
+	return &CacheService{
+		redis:        redis,
+		assetRepo:    assetRepo,
+		yahooService: yahooService,
+		logger:       logger,
+	}
+}
+
+// CacheNamespaces returns the names accepted by Invalidate
+func CacheNamespaces() []string {
+	names := make([]string, 0, len(cacheNamespaces))
+	for name := range cacheNamespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Invalidate removes every cached entry in the namespace and returns how many went
+func (s *CacheService) Invalidate(ctx context.Context, namespace string) (int, error) {
+	prefixes, ok := cacheNamespaces[namespace]
+	if !ok {
+		return 0, ErrUnknownCacheNamespace
+	}
+
+	total := 0
+	for _, prefix := range prefixes {
+		n, err := s.redis.DeletePrefix(ctx, prefix)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	s.logger.Info("cache namespace invalidated", "namespace", namespace, "keys", total)
+	return total, nil
+}
+
+// Rebuild clears the whole cache, then refetches quotes for every asset and sparklines
+// for held ones so the first requests afterwards don't all miss
+func (s *CacheService) Rebuild(ctx context.Context, progress *TaskProgress) (interface{}, error) {
+	progress.Step(ctx, "Clearing cache", 0, 0)
+	cleared, err := s.redis.DeletePrefix(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("cache cleared for rebuild", "keys", cleared)
+
+	assets, err := s.assetRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	symbols := make([]string, len(assets))
+	for i, a := range assets {
+		symbols[i] = a.Symbol
+	}
+
+	for start := 0; start < len(symbols); start += priceRefreshBatchSize {
+		end := min(start+priceRefreshBatchSize, len(symbols))
+		progress.Step(ctx, "Refetching quotes", start, len(symbols))
+		if err := s.yahooService.RefreshPrices(ctx, symbols[start:end]); err != nil {
+			return nil, err
+		}
+	}
+
+	progress.Step(ctx, "Warming sparklines", len(symbols), len(symbols))
+	held, err := s.assetRepo.GetAllHeldSymbols(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.yahooService.WarmSparklines(ctx, held)
+
+	return map[string]int{"cleared": cleared, "quotes": len(symbols), "sparklines": len(held)}, nil
+}
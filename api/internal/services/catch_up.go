@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+const (
+	// DefaultCatchUpThresholdPct is how far a holding's price must move to be reported
+	DefaultCatchUpThresholdPct = 5.0
+
+	// catchUpFirstViewDays is how far back a first catch-up looks
+	catchUpFirstViewDays = 7
+)
+
+// CatchUpService summarises what changed since the user last looked at the dashboard
+type CatchUpService struct {
+	userRepo     *repository.UserRepository
+	holdingRepo  *repository.HoldingRepository
+	snapshotRepo *repository.SnapshotRepository
+	reminderRepo *repository.ReminderRepository
+	txRepo       *repository.TransactionRepository
+	netWorth     *NetWorthService
+	yahoo        *YahooService
+}
+
+func NewCatchUpService(
+	userRepo *repository.UserRepository,
+	holdingRepo *repository.HoldingRepository,
+	snapshotRepo *repository.SnapshotRepository,
+	reminderRepo *repository.ReminderRepository,
+	txRepo *repository.TransactionRepository,
+	netWorth *NetWorthService,
+	yahoo *YahooService,
+) *CatchUpService {
+	return &CatchUpService{
+		userRepo:     userRepo,
+		holdingRepo:  holdingRepo,
+		snapshotRepo: snapshotRepo,
+		reminderRepo: reminderRepo,
+		txRepo:       txRepo,
+		netWorth:     netWorth,
+		yahoo:        yahoo,
+	}
+}
+
+// LastViewed returns when the user last caught up, or a week ago with firstView set if
+// they never have
+func (s *CatchUpService) LastViewed(ctx context.Context, userID uuid.UUID) (since time.Time, firstView bool, err error) {
+	viewedAt, err := s.userRepo.GetLastDashboardView(ctx, userID)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if viewedAt == nil {
+		return time.Now().AddDate(0, 0, -catchUpFirstViewDays), true, nil
+	}
+	return *viewedAt, false, nil
+}
+
+// MarkViewed records that the user has caught up to now
+func (s *CatchUpService) MarkViewed(ctx context.Context, userID uuid.UUID) error {
+	return s.userRepo.SetLastDashboardView(ctx, userID, time.Now())
+}
+
+// Changes reports the change in net worth since the given time, holdings whose price
+// moved by at least thresholdPct, reminders that fell due and how many transactions
+// were added. Holdings whose earlier price can't be fetched are left out.
+func (s *CatchUpService) Changes(ctx context.Context, userID uuid.UUID, since time.Time, thresholdPct float64) (*models.DashboardChanges, error) {
+	changes := &models.DashboardChanges{
+		Since:        since,
+		ThresholdPct: thresholdPct,
+		HoldingsUp:   []models.HoldingChange{},
+		HoldingsDown: []models.HoldingChange{},
+		RemindersDue: []*models.Reminder{},
+	}
+
+	summary, err := s.netWorth.Summary(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sinceDay := startOfDay(since)
+	snapshots, err := s.snapshotRepo.GetByUserIDInRange(ctx, userID, sinceDay.AddDate(0, 0, -catchUpFirstViewDays), sinceDay)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) > 0 {
+		base := snapshots[len(snapshots)-1]
+		change := &models.NetWorthChange{
+			Then:     base.TotalNetWorth,
+			Now:      summary.TotalNetWorth,
+			Change:   roundPence(summary.TotalNetWorth - base.TotalNetWorth),
+			Currency: summary.Currency,
+		}
+		if base.TotalNetWorth != 0 {
+			change.ChangePct = roundPct(change.Change / math.Abs(base.TotalNetWorth) * 100)
+		}
+		changes.NetWorth = change
+	}
+
+	holdings, err := s.holdingRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	pricesThen := make(map[string]float64)
+	for _, h := range holdings {
+		if h.Asset == nil || h.Asset.LastPrice == nil || h.Quantity <= 0 {
+			continue
+		}
+		symbol := h.Asset.Symbol
+		then, ok := pricesThen[symbol]
+		if !ok {
+			then, err = s.yahoo.GetHistoricalPrice(ctx, symbol, sinceDay)
+			if err != nil {
+				then = 0
+			}
+			pricesThen[symbol] = then
+		}
+		if then <= 0 {
+			continue
+		}
+
+		now := *h.Asset.LastPrice
+		pct := (now - then) / then * 100
+		if math.Abs(pct) < thresholdPct {
+			continue
+		}
+		change := models.HoldingChange{
+			HoldingID:     h.ID,
+			PortfolioID:   h.PortfolioID,
+			PortfolioName: h.PortfolioName,
+			Symbol:        symbol,
+			Name:          h.Asset.Name,
+			Currency:      h.Asset.Currency,
+			PriceThen:     then,
+			PriceNow:      now,
+			ChangePct:     roundPct(pct),
+			ValueChange:   roundPence(h.Quantity * (now - then)),
+		}
+		if pct > 0 {
+			changes.HoldingsUp = append(changes.HoldingsUp, change)
+		} else {
+			changes.HoldingsDown = append(changes.HoldingsDown, change)
+		}
+	}
+	sort.Slice(changes.HoldingsUp, func(i, j int) bool {
+		return changes.HoldingsUp[i].ChangePct > changes.HoldingsUp[j].ChangePct
+	})
+	sort.Slice(changes.HoldingsDown, func(i, j int) bool {
+		return changes.HoldingsDown[i].ChangePct < changes.HoldingsDown[j].ChangePct
+	})
+
+	reminders, err := s.reminderRepo.GetByUserID(ctx, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, reminder := range reminders {
+		if reminder.DueDate.After(sinceDay) && !reminder.DueDate.After(now) {
+			changes.RemindersDue = append(changes.RemindersDue, reminder)
+		}
+	}
+
+	changes.TransactionsAdded, err = s.txRepo.CountCreatedSince(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+// minorUnits maps the minor-unit currencies Yahoo quotes some listings in (e.g. LSE
+// prices in pence) to their currency and the factor converting into it
+var minorUnits = map[string]struct {
+	currency string
+	factor   float64
+}{
+	"GBp": {"GBP", 0.01},
+	"GBX": {"GBP", 0.01},
+}
+
+// CurrencyService converts amounts between currencies. Rates are stored in
+// exchange_rates and refreshed from Yahoo at most once a day per pair; if Yahoo is
+// unavailable the latest stored rate is used.
+type CurrencyService struct {
+	rateRepo     *repository.ExchangeRateRepository
+	yahooService *YahooService
+	logger       *slog.Logger
+}
+
+func NewCurrencyService(rateRepo *repository.ExchangeRateRepository, yahooService *YahooService, logger *slog.Logger) *CurrencyService {
+	return &CurrencyService{
+		rateRepo:     rateRepo,
+		yahooService: yahooService,
+		logger:       logger,
+	}
+}
+
+// fxRateMaxAgeDays is how old a stored rate may be before a live quote is fetched. Reference
+// rates are not published at weekends or on holidays, so the latest one can be a few days old.
+const fxRateMaxAgeDays = 4
+
+// Rate returns the rate converting from into to
+func (s *CurrencyService) Rate(ctx context.Context, from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	today := startOfDay(time.Now())
+	stored, err := s.rateRepo.GetLatest(ctx, from, to)
+	if err != nil && !errors.Is(err, repository.ErrExchangeRateNotFound) {
+		return 0, err
+	}
+	if stored != nil && !stored.RateDate.Before(today.AddDate(0, 0, -fxRateMaxAgeDays)) {
+		return stored.Rate, nil
+	}
+
+	rate, err := s.yahooService.GetPrice(ctx, fxSymbol(from, to))
+	if err == nil && rate > 0 {
+		if err := s.rateRepo.Upsert(ctx, &models.ExchangeRate{
+			FromCurrency: from,
+			ToCurrency:   to,
+			Rate:         rate,
+			RateDate:     today,
+		}); err != nil {
+			s.logger.Warn("failed to store exchange rate", "from", from, "to", to, "error", err)
+		}
+		return rate, nil
+	}
+
+	if stored != nil {
+		return stored.Rate, nil
+	}
+	return 0, ErrFXUnavailable
+}
+
+// RateOn returns the rate converting from into to on date. A stored rate from up to
+// fxRateMaxAgeDays before is used if there is one; otherwise recent dates take the
+// current rate and older ones Yahoo's close for the day, which is then stored. Minor
+// units such as GBp are converted into their major currency.
+func (s *CurrencyService) RateOn(ctx context.Context, from, to string, date time.Time) (float64, error) {
+	factor := 1.0
+	if unit, ok := minorUnits[from]; ok {
+		from, factor = unit.currency, unit.factor
+	}
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return factor, nil
+	}
+
+	date = startOfDay(date)
+	stored, err := s.rateRepo.GetOn(ctx, from, to, date)
+	if err != nil && !errors.Is(err, repository.ErrExchangeRateNotFound) {
+		return 0, err
+	}
+	if stored != nil && !stored.RateDate.Before(date.AddDate(0, 0, -fxRateMaxAgeDays)) {
+		return stored.Rate * factor, nil
+	}
+
+	if !date.Before(startOfDay(time.Now()).AddDate(0, 0, -fxRateMaxAgeDays)) {
+		rate, err := s.Rate(ctx, from, to)
+		if err != nil {
+			return 0, err
+		}
+		return rate * factor, nil
+	}
+
+	rate, err := s.yahooService.GetHistoricalPrice(ctx, fxSymbol(from, to), date)
+	if err != nil || rate <= 0 {
+		return 0, ErrFXUnavailable
+	}
+	if err := s.rateRepo.Upsert(ctx, &models.ExchangeRate{
+		FromCurrency: from,
+		ToCurrency:   to,
+		Rate:         rate,
+		RateDate:     date,
+	}); err != nil {
+		s.logger.Warn("failed to store exchange rate", "from", from, "to", to, "error", err)
+	}
+	return rate * factor, nil
+}
+
+// ConvertTransaction sets the transaction's FX rate and portfolio amount when its
+// currency differs from the portfolio's, converting at the rate on its date, and clears
+// them when it doesn't
+func (s *CurrencyService) ConvertTransaction(ctx context.Context, tx *models.Transaction, portfolioCurrency string) error {
+	if tx.Currency == "" || tx.Currency == portfolioCurrency {
+		tx.FXRate, tx.PortfolioAmount = nil, nil
+		return nil
+	}
+
+	rate, err := s.RateOn(ctx, tx.Currency, portfolioCurrency, tx.TransactionDate)
+	if err != nil {
+		return err
+	}
+	amount := roundPence(tx.TotalAmount * rate)
+	tx.FXRate, tx.PortfolioAmount = &rate, &amount
+	return nil
+}
+
+// NewConverter returns a converter into base that looks each rate up once
+func (s *CurrencyService) NewConverter(base string) *CurrencyConverter {
+	return &CurrencyConverter{
+		service: s,
+		base:    base,
+		rates:   make(map[string]float64),
+	}
+}
+
+// CurrencyConverter converts amounts into one base currency, remembering the rates it
+// has looked up. It is not safe for concurrent use.
+type CurrencyConverter struct {
+	service *CurrencyService
+	base    string
+	rates   map[string]float64 // 0 when unavailable
+}
+
+// Base is the currency amounts are converted into
+func (c *CurrencyConverter) Base() string {
+	return c.base
+}
+
+// Convert converts amount from currency into the base currency. Minor units such as
+// GBp are converted into their major currency first, and an empty currency is taken
+// to be the base.
+func (c *CurrencyConverter) Convert(ctx context.Context, amount float64, currency string) models.ConvertedAmount {
+	result := models.ConvertedAmount{
+		Currency:     currency,
+		Amount:       amount,
+		BaseCurrency: c.base,
+	}
+
+	from, factor := currency, 1.0
+	if unit, ok := minorUnits[currency]; ok {
+		from, factor = unit.currency, unit.factor
+	}
+	from = strings.ToUpper(from)
+	if from == "" {
+		from = c.base
+		result.Currency = c.base
+	}
+
+	rate, ok := c.rates[from]
+	if !ok {
+		var err error
+		rate, err = c.service.Rate(ctx, from, c.base)
+		if err != nil {
+			c.service.logger.Warn("exchange rate unavailable", "from", from, "to", c.base, "error", err)
+			rate = 0
+		}
+		c.rates[from] = rate
+	}
+
+	if rate == 0 {
+		result.RateMissing = true
+		result.Rate = factor
+		result.BaseAmount = roundPence(amount * factor)
+		return result
+	}
+
+	result.Rate = rate * factor
+	result.BaseAmount = roundPence(amount * factor * rate)
+	return result
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/database"
+)
+
+const dashboardCachePrefix = "dashboard:"
+
+// Dashboard views whose computed responses are cached
+const (
+	DashboardViewSummary    = "summary"
+	DashboardViewAllocation = "allocation"
+)
+
+var dashboardViews = []string{DashboardViewSummary, DashboardViewAllocation}
+
+// DashboardCache keeps each user's computed dashboard summary and allocation in Redis
+// for a short while, as building them takes a query per portfolio plus price and
+// exchange rate lookups. Writes drop the user's entries so changes show straight away,
+// whether made through the API or by a background job; the TTL bounds how stale exchange
+// rates can get. A zero TTL disables the cache.
+type DashboardCache struct {
+	redis *database.RedisClient
+	ttl   time.Duration
+}
+
+func NewDashboardCache(redis *database.RedisClient, ttl time.Duration) *DashboardCache {
+	return &DashboardCache{redis: redis, ttl: ttl}
+}
+
+func dashboardCacheKey(userID uuid.UUID, view string) string {
+	return fmt.Sprintf("%s%s:%s", dashboardCachePrefix, userID, view)
+}
+
+// Get decodes the user's cached view into dest, reporting whether there was one
+func (c *DashboardCache) Get(ctx context.Context, userID uuid.UUID, view string, dest interface{}) bool {
+	if c.ttl <= 0 {
+		return false
+	}
+	cached, err := c.redis.Get(ctx, dashboardCacheKey(userID, view))
+	if err != nil || cached == "" {
+		return false
+	}
+	return json.Unmarshal([]byte(cached), dest) == nil
+}
+
+// Set caches the user's view. Failures are ignored as the cache only saves work.
+func (c *DashboardCache) Set(ctx context.Context, userID uuid.UUID, view string, value interface{}) {
+	if c.ttl <= 0 {
+		return
+	}
+	if data, err := json.Marshal(value); err == nil {
+		_ = c.redis.Set(ctx, dashboardCacheKey(userID, view), string(data), c.ttl)
+	}
+}
+
+// Invalidate drops the user's cached views
+func (c *DashboardCache) Invalidate(ctx context.Context, userID uuid.UUID) {
+	if c.ttl <= 0 {
+		return
+	}
+	keys := make([]string, len(dashboardViews))
+	for i, view := range dashboardViews {
+		keys[i] = dashboardCacheKey(userID, view)
+	}
+	_ = c.redis.Delete(ctx, keys...)
+}
+
+// InvalidateAll drops every user's cached views, e.g. after prices change
+func (c *DashboardCache) InvalidateAll(ctx context.Context) {
+	if c.ttl <= 0 {
+		return
+	}
+	_, _ = c.redis.DeletePrefix(ctx, dashboardCachePrefix)
+}
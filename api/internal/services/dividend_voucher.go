@@ -0,0 +1,75 @@
+package services
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DividendVoucher holds the amounts read from a dividend voucher. An amount missing
+// from the voucher is derived from the other two where possible.
+type DividendVoucher struct {
+	GrossAmount    *float64 `json:"gross_amount,omitempty"`
+	NetAmount      *float64 `json:"net_amount,omitempty"`
+	WithholdingTax *float64 `json:"withholding_tax,omitempty"`
+}
+
+// Found reports whether any amount was read from the voucher
+func (v DividendVoucher) Found() bool {
+	return v.GrossAmount != nil || v.NetAmount != nil || v.WithholdingTax != nil
+}
+
+// Label, then up to 40 non-digit characters (possibly across a line break), then a
+// money amount with exactly two decimal places
+const voucherAmount = `([^0-9]{0,40}?)(\d{1,3}(?:,\d{3})+\.\d{2}|\d+\.\d{2})\b`
+
+var (
+	voucherGrossPattern = regexp.MustCompile(`(?i)\bgross\b` + voucherAmount)
+	voucherNetPattern   = regexp.MustCompile(`(?i)\bnet\b` + voucherAmount)
+	voucherTaxPattern   = regexp.MustCompile(`(?i)(?:withholding\s+tax|tax\s+withheld|tax\s+deducted|foreign\s+tax|\bwht\b|\bnrwt\b)` + voucherAmount)
+)
+
+// ParseDividendVoucher reads the gross, net and withholding tax amounts from the text
+// of a dividend voucher. Per-share rates (e.g. "Gross rate per share") are ignored.
+func ParseDividendVoucher(text string) DividendVoucher {
+	v := DividendVoucher{
+		GrossAmount:    findVoucherAmount(voucherGrossPattern, text),
+		NetAmount:      findVoucherAmount(voucherNetPattern, text),
+		WithholdingTax: findVoucherAmount(voucherTaxPattern, text),
+	}
+
+	switch {
+	case v.GrossAmount != nil && v.NetAmount != nil && v.WithholdingTax == nil:
+		if tax := roundPence(*v.GrossAmount - *v.NetAmount); tax >= 0 {
+			v.WithholdingTax = &tax
+		}
+	case v.GrossAmount != nil && v.NetAmount == nil && v.WithholdingTax != nil:
+		net := roundPence(*v.GrossAmount - *v.WithholdingTax)
+		v.NetAmount = &net
+	case v.GrossAmount == nil && v.NetAmount != nil && v.WithholdingTax != nil:
+		gross := roundPence(*v.NetAmount + *v.WithholdingTax)
+		v.GrossAmount = &gross
+	}
+
+	return v
+}
+
+func findVoucherAmount(pattern *regexp.Regexp, text string) *float64 {
+	for _, m := range pattern.FindAllStringSubmatch(text, -1) {
+		gap := strings.ToLower(m[1])
+		if strings.Contains(gap, "per share") || strings.Contains(gap, "rate") {
+			continue
+		}
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(m[2], ",", ""), 64)
+		if err != nil {
+			continue
+		}
+		return &amount
+	}
+	return nil
+}
+
+func roundPence(v float64) float64 {
+	return math.Round(v*100) / 100
+}
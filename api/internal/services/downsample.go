@@ -0,0 +1,56 @@
+package services
+
+import "math"
+
+// DownsampleLTTB reduces history to at most threshold points using the
+// Largest-Triangle-Three-Buckets algorithm on closing prices. The first and last
+// points are always kept; each bucket in between keeps the point forming the largest
+// triangle with its neighbours, which preserves peaks and troughs.
+func DownsampleLTTB(history []PriceHistory, threshold int) []PriceHistory {
+	if threshold >= len(history) || threshold < 3 {
+		return history
+	}
+
+	sampled := make([]PriceHistory, 0, threshold)
+	sampled = append(sampled, history[0])
+
+	bucketSize := float64(len(history)-2) / float64(threshold-2)
+	a := 0 // index of the previously selected point
+
+	for i := 0; i < threshold-2; i++ {
+		// Average of the next bucket, used as the third triangle vertex
+		nextStart := int(math.Floor(float64(i+1)*bucketSize)) + 1
+		nextEnd := int(math.Floor(float64(i+2)*bucketSize)) + 1
+		if nextEnd > len(history) {
+			nextEnd = len(history)
+		}
+		var avgX, avgY float64
+		for j := nextStart; j < nextEnd; j++ {
+			avgX += float64(history[j].Date.Unix())
+			avgY += history[j].Close
+		}
+		if n := float64(nextEnd - nextStart); n > 0 {
+			avgX /= n
+			avgY /= n
+		}
+
+		start := int(math.Floor(float64(i)*bucketSize)) + 1
+		end := int(math.Floor(float64(i+1)*bucketSize)) + 1
+
+		ax, ay := float64(history[a].Date.Unix()), history[a].Close
+		maxArea := -1.0
+		selected := start
+		for j := start; j < end; j++ {
+			area := math.Abs((ax-avgX)*(history[j].Close-ay) - (ax-float64(history[j].Date.Unix()))*(avgY-ay))
+			if area > maxArea {
+				maxArea = area
+				selected = j
+			}
+		}
+
+		sampled = append(sampled, history[selected])
+		a = selected
+	}
+
+	return append(sampled, history[len(history)-1])
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+// Default FIRE projection assumptions: a 5% real return with 15% volatility, roughly a
+// global equity portfolio after inflation
+const (
+	DefaultFireReturn      = 5.0
+	DefaultFireVolatility  = 15.0
+	DefaultFireYears       = 40
+	DefaultFireSimulations = 1000
+
+	fireContributionMonths = 12
+)
+
+// firePercentiles are the percentiles reported for FIRE dates. The 10th percentile is the
+// date reached in the luckiest tenth of simulations.
+var firePercentiles = []int{10, 25, 50, 75, 90}
+
+// FireService projects when a user reaches their FIRE target
+type FireService struct {
+	userRepo      *repository.UserRepository
+	portfolioRepo *repository.PortfolioRepository
+	txRepo        *repository.TransactionRepository
+	netWorth      *NetWorthService
+	currency      *CurrencyService
+}
+
+func NewFireService(
+	userRepo *repository.UserRepository,
+	portfolioRepo *repository.PortfolioRepository,
+	txRepo *repository.TransactionRepository,
+	netWorth *NetWorthService,
+	currency *CurrencyService,
+) *FireService {
+	return &FireService{
+		userRepo:      userRepo,
+		portfolioRepo: portfolioRepo,
+		txRepo:        txRepo,
+		netWorth:      netWorth,
+		currency:      currency,
+	}
+}
+
+// Defaults returns the assumptions for the user, in their base currency: their FIRE target,
+// investable net worth (investments and cash, not fixed assets) as the starting value, and
+// the average monthly contribution over the last twelve complete months
+func (s *FireService) Defaults(ctx context.Context, userID uuid.UUID) (models.FireAssumptions, string, error) {
+	a := models.FireAssumptions{
+		ExpectedReturn: DefaultFireReturn,
+		Volatility:     DefaultFireVolatility,
+		Years:          DefaultFireYears,
+		Simulations:    DefaultFireSimulations,
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return a, "", err
+	}
+	if user.FireTarget != nil {
+		a.Target = *user.FireTarget
+	}
+
+	summary, err := s.netWorth.Summary(ctx, userID)
+	if err != nil {
+		return a, "", err
+	}
+	a.StartingValue = roundPence(summary.Investments + summary.Cash)
+
+	portfolios, err := s.portfolioRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return a, "", err
+	}
+	currencies := make(map[uuid.UUID]string, len(portfolios))
+	var ids []uuid.UUID
+	for _, p := range portfolios {
+		if p.Type == models.PortfolioTypeFixedAssets {
+			continue
+		}
+		currencies[p.ID] = p.Currency
+		ids = append(ids, p.ID)
+	}
+
+	thisMonth := startOfDay(time.Now())
+	thisMonth = thisMonth.AddDate(0, 0, 1-thisMonth.Day())
+	from := thisMonth.AddDate(0, -fireContributionMonths, 0)
+	monthly, err := s.txRepo.GetMonthlyContributions(ctx, ids, from)
+	if err != nil {
+		return a, "", err
+	}
+
+	conv := s.currency.NewConverter(user.BaseCurrency)
+	var total float64
+	for _, c := range monthly {
+		if !c.Month.Before(thisMonth) {
+			continue
+		}
+		total += conv.Convert(ctx, c.Total, currencies[c.PortfolioID]).BaseAmount
+	}
+	a.MonthlyContribution = roundPence(total / fireContributionMonths)
+
+	return a, conv.Base(), nil
+}
+
+// ProjectFire runs a Monte Carlo simulation of monthly returns, drawn from a log-normal
+// distribution whose median annual return is ExpectedReturn, with the contribution added at
+// the end of each month. The seed makes the projection repeatable.
+func ProjectFire(a models.FireAssumptions, currency string, start time.Time, seed int64) *models.FireProjection {
+	p := &models.FireProjection{
+		Currency:    currency,
+		Assumptions: a,
+		Dates:       []models.FireDate{},
+		Bands:       []models.FireBand{},
+	}
+	if a.Target > 0 {
+		p.Progress = math.Round(a.StartingValue/a.Target*10000) / 100
+	}
+
+	if a.StartingValue >= a.Target {
+		p.Reached = true
+		p.Probability = 100
+		p.ExpectedDate = &start
+		zero := 0.0
+		for _, pct := range firePercentiles {
+			p.Dates = append(p.Dates, models.FireDate{Percentile: pct, Date: &start, Years: &zero})
+		}
+		return p
+	}
+
+	months := a.Years * 12
+	mu := math.Log(1+a.ExpectedReturn/100) / 12
+	sd := a.Volatility / 100 / math.Sqrt(12)
+	growth := math.Pow(1+a.ExpectedReturn/100, 1.0/12)
+	rng := rand.New(rand.NewSource(seed))
+
+	// Months taken to reach the target per simulation; months+1 if it never is
+	reachedAt := make([]int, a.Simulations)
+	// Simulated values at the end of each year
+	yearEnd := make([][]float64, a.Years)
+	for y := range yearEnd {
+		yearEnd[y] = make([]float64, a.Simulations)
+	}
+
+	reached := 0
+	for sim := 0; sim < a.Simulations; sim++ {
+		value := a.StartingValue
+		reachedAt[sim] = months + 1
+		for m := 1; m <= months; m++ {
+			value = value*math.Exp(mu+sd*rng.NormFloat64()) + a.MonthlyContribution
+			if reachedAt[sim] > months && value >= a.Target {
+				reachedAt[sim] = m
+				reached++
+			}
+			if m%12 == 0 {
+				yearEnd[m/12-1][sim] = value
+			}
+		}
+	}
+	p.Probability = math.Round(float64(reached)/float64(a.Simulations)*10000) / 100
+
+	expected := a.StartingValue
+	expectedMonth := 0
+	for m := 1; m <= months; m++ {
+		expected = expected*growth + a.MonthlyContribution
+		if expectedMonth == 0 && expected >= a.Target {
+			expectedMonth = m
+			d := start.AddDate(0, m, 0)
+			p.ExpectedDate = &d
+		}
+		if m%12 == 0 {
+			y := m / 12
+			values := yearEnd[y-1]
+			sort.Float64s(values)
+			p.Bands = append(p.Bands, models.FireBand{
+				Year:     y,
+				Date:     start.AddDate(y, 0, 0),
+				Expected: roundPence(expected),
+				P10:      roundPence(percentile(values, 10)),
+				P25:      roundPence(percentile(values, 25)),
+				P50:      roundPence(percentile(values, 50)),
+				P75:      roundPence(percentile(values, 75)),
+				P90:      roundPence(percentile(values, 90)),
+			})
+		}
+	}
+
+	sort.Ints(reachedAt)
+	for _, pct := range firePercentiles {
+		fd := models.FireDate{Percentile: pct}
+		idx := int(math.Ceil(float64(pct)/100*float64(len(reachedAt)))) - 1
+		if m := reachedAt[max(idx, 0)]; m <= months {
+			d := start.AddDate(0, m, 0)
+			years := math.Round(float64(m)/12*10) / 10
+			fd.Date, fd.Years = &d, &years
+		}
+		p.Dates = append(p.Dates, fd)
+	}
+
+	return p
+}
+
+// FireSeed derives a stable simulation seed from the user so projections don't change
+// between requests unless the inputs do
+func FireSeed(userID uuid.UUID) int64 {
+	return int64(binary.BigEndian.Uint64(userID[:8]))
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []float64, pct int) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(float64(pct)/100*float64(len(sorted)))) - 1
+	return sorted[max(idx, 0)]
+}
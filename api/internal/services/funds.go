@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/pkg/validator"
+)
+
+const (
+	fundPriceJob = "fund_prices"
+
+	// fundPriceFetchHour is the UTC hour after which the day's fund prices are fetched.
+	// Most UK OEICs and unit trusts value at noon and publish during the afternoon.
+	fundPriceFetchHour = 18
+
+	fundPriceCheckInterval = time.Hour
+)
+
+var (
+	ErrInvalidIdentifier  = errors.New("not a valid ISIN or SEDOL")
+	ErrIdentifierNotFound = errors.New("no asset found for identifier")
+)
+
+// FundIdentifier reads an ISIN or SEDOL, returning the ISIN and, for UK ISINs, the
+// SEDOL inside it. A SEDOL is turned into its UK ISIN.
+func FundIdentifier(code string) (isin, sedol string, ok bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	switch {
+	case validator.IsValidISIN(code):
+		if strings.HasPrefix(code, "GB00") && validator.IsValidSEDOL(code[4:11]) {
+			sedol = code[4:11]
+		}
+		return code, sedol, true
+	case validator.IsValidSEDOL(code):
+		return validator.SEDOLToISIN(code), code, true
+	default:
+		return "", "", false
+	}
+}
+
+// ResolveIdentifier returns the asset for an ISIN or SEDOL, searching Yahoo by ISIN
+// for one not seen before and recording the identifiers on the asset it finds. Funds
+// are preferred when an ISIN matches several listings.
+func (s *YahooService) ResolveIdentifier(ctx context.Context, code string) (*models.Asset, error) {
+	isin, sedol, ok := FundIdentifier(code)
+	if !ok {
+		return nil, ErrInvalidIdentifier
+	}
+
+	asset, err := s.assetRepo.GetByIdentifier(ctx, isin, sedol)
+	if err == nil {
+		return asset, nil
+	}
+	if !errors.Is(err, repository.ErrAssetNotFound) {
+		return nil, err
+	}
+
+	results, err := s.Search(ctx, isin)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrIdentifierNotFound
+	}
+	symbol := results[0].Symbol
+	for _, r := range results {
+		if r.QuoteType == "MUTUALFUND" {
+			symbol = r.Symbol
+			break
+		}
+	}
+
+	asset, err = s.GetOrCreateAsset(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.assetRepo.UpdateIdentifiers(ctx, asset.ID, isin, sedol); err != nil {
+		s.logger.Error("failed to record asset identifiers", "symbol", asset.Symbol, "isin", isin, "error", err)
+	}
+	if asset.ISIN == "" {
+		asset.ISIN = isin
+	}
+	if asset.SEDOL == "" {
+		asset.SEDOL = sedol
+	}
+
+	return asset, nil
+}
+
+// fundPriceCheckpoint records the last day fund prices were fetched
+type fundPriceCheckpoint struct {
+	FetchedOn string `json:"fetched_on"`
+}
+
+// FundPriceFetcher stores the daily price (NAV) of every FUND asset once a day. Fund
+// quotes often lack a live price, so a fund the providers can't quote is valued at its
+// latest daily close instead.
+type FundPriceFetcher struct {
+	assetRepo      *repository.AssetRepository
+	checkpointRepo *repository.JobCheckpointRepository
+	yahooService   *YahooService
+	dashboardCache *DashboardCache
+	jobs           *JobManager
+	logger         *slog.Logger
+}
+
+func NewFundPriceFetcher(
+	assetRepo *repository.AssetRepository,
+	checkpointRepo *repository.JobCheckpointRepository,
+	yahooService *YahooService,
+	dashboardCache *DashboardCache,
+	jobs *JobManager,
+	logger *slog.Logger,
+) *FundPriceFetcher {
+	return &FundPriceFetcher{
+		assetRepo:      assetRepo,
+		checkpointRepo: checkpointRepo,
+		yahooService:   yahooService,
+		dashboardCache: dashboardCache,
+		jobs:           jobs,
+		logger:         logger,
+	}
+}
+
+// Run checks hourly until ctx is cancelled, fetching once a day after fundPriceFetchHour
+func (f *FundPriceFetcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(fundPriceCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		f.runIfDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *FundPriceFetcher) runIfDue(ctx context.Context) {
+	now := time.Now().UTC()
+	if now.Hour() < fundPriceFetchHour {
+		return
+	}
+	today := now.Format("2006-01-02")
+
+	state, err := f.checkpointRepo.Get(ctx, fundPriceJob)
+	if err != nil && !errors.Is(err, repository.ErrCheckpointNotFound) {
+		f.logger.Error("failed to read fund price checkpoint", "error", err)
+		return
+	}
+	var cp fundPriceCheckpoint
+	if err == nil {
+		_ = json.Unmarshal(state, &cp)
+	}
+	if cp.FetchedOn == today {
+		return
+	}
+
+	err = f.jobs.Run(fundPriceJob, func(ctx context.Context) error {
+		return f.fetch(ctx, today)
+	})
+	if errors.Is(err, ErrShuttingDown) {
+		f.logger.Info("skipping fund price fetch during shutdown")
+	}
+}
+
+func (f *FundPriceFetcher) fetch(ctx context.Context, today string) error {
+	symbols, err := f.assetRepo.GetSymbolsByType(ctx, models.AssetTypeFund)
+	if err != nil {
+		return err
+	}
+
+	updated := 0
+	for start := 0; start < len(symbols); start += priceRefreshBatchSize {
+		end := min(start+priceRefreshBatchSize, len(symbols))
+		prices := f.fundPrices(ctx, symbols[start:end])
+		if err := f.assetRepo.UpdatePrices(ctx, prices); err != nil {
+			return err
+		}
+		updated += len(prices)
+	}
+	if updated > 0 {
+		f.dashboardCache.InvalidateAll(ctx)
+	}
+
+	state, err := json.Marshal(fundPriceCheckpoint{FetchedOn: today})
+	if err != nil {
+		return err
+	}
+	if err := f.checkpointRepo.Save(ctx, fundPriceJob, state); err != nil {
+		return err
+	}
+
+	f.logger.Info("fund prices updated", "funds", len(symbols), "priced", updated)
+	return nil
+}
+
+// fundPrices quotes the funds, falling back to the latest daily close for those
+// without a quote
+func (f *FundPriceFetcher) fundPrices(ctx context.Context, symbols []string) map[string]float64 {
+	prices := make(map[string]float64, len(symbols))
+
+	quotes, err := f.yahooService.fetchQuotes(ctx, symbols)
+	if err != nil {
+		f.logger.Warn("fund quotes failed, using daily closes", "error", err)
+	}
+	for _, q := range quotes {
+		if q.Price > 0 {
+			prices[q.Symbol] = q.Price
+		}
+	}
+
+	for _, symbol := range symbols {
+		if _, ok := prices[symbol]; ok {
+			continue
+		}
+		history, err := f.yahooService.fetchHistory(ctx, symbol, "5d", "1d")
+		if err != nil {
+			f.logger.Warn("no price for fund", "symbol", symbol, "error", err)
+			continue
+		}
+		for i := len(history) - 1; i >= 0; i-- {
+			if history[i].Close > 0 {
+				prices[symbol] = history[i].Close
+				break
+			}
+		}
+	}
+
+	return prices
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/pkg/validator"
+)
+
+const (
+	fxRateJob = "fx_rates"
+
+	// ecbDailyURL serves the ECB's euro reference rates, published around 16:00 CET on
+	// TARGET working days
+	ecbDailyURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+
+	fxRateCheckInterval = time.Hour
+)
+
+// fxRateCheckpoint records the last day the rates were fetched
+type fxRateCheckpoint struct {
+	FetchedOn string `json:"fetched_on"`
+	RateDate  string `json:"rate_date"`
+}
+
+// FXRateFetcher pulls the ECB reference rates once a day and stores the rate between
+// every pair of supported currencies in exchange_rates
+type FXRateFetcher struct {
+	rateRepo       *repository.ExchangeRateRepository
+	checkpointRepo *repository.JobCheckpointRepository
+	jobs           *JobManager
+	client         *http.Client
+	logger         *slog.Logger
+}
+
+func NewFXRateFetcher(
+	rateRepo *repository.ExchangeRateRepository,
+	checkpointRepo *repository.JobCheckpointRepository,
+	jobs *JobManager,
+	logger *slog.Logger,
+) *FXRateFetcher {
+	return &FXRateFetcher{
+		rateRepo:       rateRepo,
+		checkpointRepo: checkpointRepo,
+		jobs:           jobs,
+		client:         &http.Client{Timeout: 30 * time.Second},
+		logger:         logger,
+	}
+}
+
+// Run fetches the rates at start-up and then hourly until ctx is cancelled, skipping
+// checks once today's fetch has succeeded
+func (f *FXRateFetcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(fxRateCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		f.runIfDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *FXRateFetcher) runIfDue(ctx context.Context) {
+	today := time.Now().UTC().Format("2006-01-02")
+
+	state, err := f.checkpointRepo.Get(ctx, fxRateJob)
+	if err != nil && !errors.Is(err, repository.ErrCheckpointNotFound) {
+		f.logger.Error("failed to read fx rate checkpoint", "error", err)
+		return
+	}
+	var cp fxRateCheckpoint
+	if err == nil {
+		_ = json.Unmarshal(state, &cp)
+	}
+	if cp.FetchedOn == today {
+		return
+	}
+
+	err = f.jobs.Run(fxRateJob, func(ctx context.Context) error {
+		return f.fetch(ctx, today)
+	})
+	if errors.Is(err, ErrShuttingDown) {
+		f.logger.Info("skipping fx rate fetch during shutdown")
+	}
+}
+
+// fetch stores the latest reference rates. Runs before the ECB publishes today's rates
+// store the previous working day's again, which is harmless.
+func (f *FXRateFetcher) fetch(ctx context.Context, today string) error {
+	rateDate, eurRates, err := f.fetchECB(ctx)
+	if err != nil {
+		return err
+	}
+
+	currencies := validator.Currencies()
+	var rates []*models.ExchangeRate
+	for _, from := range currencies {
+		for _, to := range currencies {
+			if from == to {
+				continue
+			}
+			fromEUR, ok1 := eurRates[from]
+			toEUR, ok2 := eurRates[to]
+			if !ok1 || !ok2 || fromEUR == 0 {
+				continue
+			}
+			rates = append(rates, &models.ExchangeRate{
+				FromCurrency: from,
+				ToCurrency:   to,
+				Rate:         toEUR / fromEUR,
+				RateDate:     rateDate,
+			})
+		}
+	}
+	if len(rates) == 0 {
+		return errors.New("no supported currencies in ECB rates")
+	}
+
+	if err := f.rateRepo.UpsertMany(ctx, rates); err != nil {
+		return err
+	}
+
+	state, err := json.Marshal(fxRateCheckpoint{FetchedOn: today, RateDate: rateDate.Format("2006-01-02")})
+	if err != nil {
+		return err
+	}
+	if err := f.checkpointRepo.Save(ctx, fxRateJob, state); err != nil {
+		return err
+	}
+
+	f.logger.Info("exchange rates updated", "rate_date", rateDate.Format("2006-01-02"), "pairs", len(rates))
+	return nil
+}
+
+// ecbEnvelope is the shape of the ECB daily reference rates document
+type ecbEnvelope struct {
+	Cube struct {
+		Cube struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string  `xml:"currency,attr"`
+				Rate     float64 `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+// fetchECB returns the date of the latest reference rates and the value of one euro in
+// each currency, including EUR itself
+func (f *FXRateFetcher) fetchECB(ctx context.Context) (time.Time, map[string]float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ecbDailyURL, nil)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, nil, fmt.Errorf("ECB rates: unexpected status %d", resp.StatusCode)
+	}
+
+	var doc ecbEnvelope
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&doc); err != nil {
+		return time.Time{}, nil, fmt.Errorf("ECB rates: %w", err)
+	}
+
+	rateDate, err := time.Parse("2006-01-02", doc.Cube.Cube.Time)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("ECB rates: invalid date %q", doc.Cube.Cube.Time)
+	}
+
+	rates := map[string]float64{"EUR": 1}
+	for _, r := range doc.Cube.Cube.Rates {
+		if r.Rate > 0 {
+			rates[strings.ToUpper(r.Currency)] = r.Rate
+		}
+	}
+
+	return rateDate, rates, nil
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/pkg/taxyear"
+)
+
+// Health score thresholds
+const (
+	emergencyFundTargetMonths = 6
+	feeDragGoodPct            = 0.2 // fees at or below this % of investments a year score full marks
+	feeDragPoorPct            = 1.0 // and at or above this, nothing
+	fixedAssetValuationDays   = 365
+	priceStaleDays            = 7
+	overdueReminderPenalty    = 20
+
+	healthGoodScore = 80
+	healthFairScore = 50
+)
+
+// HealthService scores the user's financial hygiene from data they already keep. Each
+// signal is scored 0-100 with an explanation and a suggested action, and the overall
+// score is their weighted average; signals without enough data are left out of it.
+type HealthService struct {
+	portfolioRepo  *repository.PortfolioRepository
+	txRepo         *repository.TransactionRepository
+	incomeRepo     *repository.IncomeRepository
+	fixedAssetRepo *repository.FixedAssetRepository
+	assetRepo      *repository.AssetRepository
+	reminderRepo   *repository.ReminderRepository
+	netWorth       *NetWorthService
+	fire           *FireService
+	allowances     *AllowanceService
+	fx             *CurrencyService
+}
+
+func NewHealthService(
+	portfolioRepo *repository.PortfolioRepository,
+	txRepo *repository.TransactionRepository,
+	incomeRepo *repository.IncomeRepository,
+	fixedAssetRepo *repository.FixedAssetRepository,
+	assetRepo *repository.AssetRepository,
+	reminderRepo *repository.ReminderRepository,
+	netWorth *NetWorthService,
+	fire *FireService,
+	allowances *AllowanceService,
+	fx *CurrencyService,
+) *HealthService {
+	return &HealthService{
+		portfolioRepo:  portfolioRepo,
+		txRepo:         txRepo,
+		incomeRepo:     incomeRepo,
+		fixedAssetRepo: fixedAssetRepo,
+		assetRepo:      assetRepo,
+		reminderRepo:   reminderRepo,
+		netWorth:       netWorth,
+		fire:           fire,
+		allowances:     allowances,
+		fx:             fx,
+	}
+}
+
+// healthStatus grades a score
+func healthStatus(score int) string {
+	switch {
+	case score >= healthGoodScore:
+		return models.HealthStatusGood
+	case score >= healthFairScore:
+		return models.HealthStatusFair
+	default:
+		return models.HealthStatusPoor
+	}
+}
+
+// scoreSignal sets the signal's score, clamped to 0-100, its status and the value it
+// was scored on
+func scoreSignal(signal *models.HealthSignal, score, value float64) {
+	n := int(math.Round(math.Max(0, math.Min(100, score))))
+	signal.Score = &n
+	signal.Status = healthStatus(n)
+	v := math.Round(value*100) / 100
+	signal.Value = &v
+}
+
+// Score works out the user's health score as of now
+func (s *HealthService) Score(ctx context.Context, userID uuid.UUID) (*models.HealthScore, error) {
+	now := time.Now()
+	summary, err := s.netWorth.Summary(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.HealthScore{Currency: summary.Currency, CalculatedAt: now}
+	signals := []func(context.Context, uuid.UUID, *models.NetWorthSummary, time.Time) (models.HealthSignal, error){
+		s.emergencyFund,
+		s.allowanceUtilisation,
+		s.feeDrag,
+		s.uncategorised,
+		s.staleValuations,
+		s.overdueReminders,
+	}
+
+	var weighted, weights int
+	for _, signal := range signals {
+		sig, err := signal(ctx, userID, summary, now)
+		if err != nil {
+			return nil, err
+		}
+		if sig.Score != nil {
+			weighted += *sig.Score * sig.Weight
+			weights += sig.Weight
+		} else {
+			sig.Status = models.HealthStatusUnknown
+		}
+		result.Signals = append(result.Signals, sig)
+	}
+
+	result.Status = models.HealthStatusUnknown
+	if weights > 0 {
+		result.Score = int(math.Round(float64(weighted) / float64(weights)))
+		result.Status = healthStatus(result.Score)
+	}
+	return result, nil
+}
+
+// emergencyFund scores cash against six months of spending. Spending isn't recorded, so
+// it is estimated as monthly income less the average monthly amount saved into
+// portfolios over the last year.
+func (s *HealthService) emergencyFund(ctx context.Context, userID uuid.UUID, summary *models.NetWorthSummary, now time.Time) (models.HealthSignal, error) {
+	signal := models.HealthSignal{Key: models.HealthSignalEmergencyFund, Label: "Emergency fund", Weight: 25}
+
+	incomes, err := s.incomeRepo.GetByUserID(ctx, userID, true)
+	if err != nil {
+		return signal, err
+	}
+	conv := s.fx.NewConverter(summary.Currency)
+	var income float64
+	for _, i := range incomes {
+		if i.EndDate != nil && i.EndDate.Before(now) {
+			continue
+		}
+		income += conv.Convert(ctx, i.Amount, i.Currency).BaseAmount * i.PaymentsPerYear() / 12
+	}
+	if income <= 0 {
+		signal.Explanation = "Monthly spending can't be estimated without any income recorded."
+		signal.Action = "Add your salary and other regular income so your emergency fund can be measured."
+		return signal, nil
+	}
+
+	assumptions, _, err := s.fire.Defaults(ctx, userID)
+	if err != nil {
+		return signal, err
+	}
+	spending := income - assumptions.MonthlyContribution
+	if spending <= 0 {
+		spending = income
+	}
+	spending = roundPence(spending)
+
+	months := summary.Cash / spending
+	scoreSignal(&signal, months/emergencyFundTargetMonths*100, months)
+	signal.Explanation = fmt.Sprintf("Cash of %.2f %s covers %.1f months of estimated spending of %.2f a month (income less what you save).",
+		summary.Cash, summary.Currency, months, spending)
+	if months < emergencyFundTargetMonths {
+		signal.Action = fmt.Sprintf("Build up easy-access cash by %.2f %s to cover %d months.",
+			roundPence(spending*emergencyFundTargetMonths-summary.Cash), summary.Currency, emergencyFundTargetMonths)
+	}
+	return signal, nil
+}
+
+// allowanceUtilisation scores ISA contributions this tax year against the share of the
+// allowance the year so far would use up if paid in evenly
+func (s *HealthService) allowanceUtilisation(ctx context.Context, userID uuid.UUID, _ *models.NetWorthSummary, now time.Time) (models.HealthSignal, error) {
+	signal := models.HealthSignal{Key: models.HealthSignalAllowance, Label: "ISA allowance", Weight: 15}
+
+	portfolios, err := s.portfolioRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return signal, err
+	}
+	var isa *models.Portfolio
+	for _, p := range portfolios {
+		if p.Type == models.PortfolioTypeISA || p.Type == models.PortfolioTypeLISA {
+			isa = p
+			break
+		}
+	}
+	if isa == nil {
+		signal.Explanation = "You have no ISA or Lifetime ISA portfolio."
+		signal.Action = "Open an ISA so savings and investments grow free of tax."
+		return signal, nil
+	}
+
+	year := taxyear.UK.Of(now)
+	// Status of an ISA counts contributions to every ISA and LISA against the shared allowance
+	status, err := s.allowances.Status(ctx, &models.Portfolio{ID: isa.ID, UserID: userID, Type: models.PortfolioTypeISA}, year.String())
+	if err != nil {
+		return signal, err
+	}
+
+	elapsed := math.Min(now.Sub(year.From()).Hours()/year.Next().From().Sub(year.From()).Hours(), 1)
+	used := status.Contributed / status.Allowance
+	scoreSignal(&signal, used/math.Max(elapsed, 1.0/12)*100, used*100)
+	signal.Explanation = fmt.Sprintf("You have used %.0f%% of the %s ISA allowance, %.0f%% of the way through the tax year.",
+		used*100, year.String(), elapsed*100)
+	if status.Remaining > 0 {
+		signal.Action = fmt.Sprintf("%.2f GBP of allowance is left until %s and can't be carried over.",
+			status.Remaining, year.To().Format("2 January 2006"))
+	}
+	return signal, nil
+}
+
+// feeDrag scores fees paid over the last year as a percentage of investments
+func (s *HealthService) feeDrag(ctx context.Context, userID uuid.UUID, summary *models.NetWorthSummary, now time.Time) (models.HealthSignal, error) {
+	signal := models.HealthSignal{Key: models.HealthSignalFeeDrag, Label: "Fee drag", Weight: 15}
+
+	if summary.Investments <= 0 {
+		signal.Explanation = "You have no investments for fees to be measured against."
+		return signal, nil
+	}
+
+	totals, err := s.txRepo.GetMonthlyTotalsByUserID(ctx, userID, startOfDay(now).AddDate(-1, 0, 0))
+	if err != nil {
+		return signal, err
+	}
+	var fees float64
+	for _, t := range totals {
+		if t.TransactionType == models.TransactionTypeFee {
+			fees += math.Abs(t.Total)
+		}
+	}
+
+	pct := fees / summary.Investments * 100
+	scoreSignal(&signal, (feeDragPoorPct-pct)/(feeDragPoorPct-feeDragGoodPct)*100, pct)
+	signal.Explanation = fmt.Sprintf("Fees recorded over the last year came to %.2f, %.2f%% of your investments.", roundPence(fees), pct)
+	if pct > feeDragGoodPct {
+		signal.Action = "Compare platform and fund charges; a cheaper platform or index funds could cut what you pay."
+	}
+	if fees == 0 {
+		signal.Action = "Record platform and fund fees as FEE transactions so their cost is counted."
+	}
+	return signal, nil
+}
+
+// uncategorised scores the share of the last year's transactions that have been tagged
+func (s *HealthService) uncategorised(ctx context.Context, userID uuid.UUID, _ *models.NetWorthSummary, now time.Time) (models.HealthSignal, error) {
+	signal := models.HealthSignal{Key: models.HealthSignalUncategorised, Label: "Uncategorised transactions", Weight: 10}
+
+	total, untagged, err := s.txRepo.CountUntaggedSince(ctx, userID, startOfDay(now).AddDate(-1, 0, 0))
+	if err != nil {
+		return signal, err
+	}
+	if total == 0 {
+		signal.Explanation = "You have no transactions in the last year."
+		return signal, nil
+	}
+
+	scoreSignal(&signal, float64(total-untagged)/float64(total)*100, float64(untagged))
+	signal.Explanation = fmt.Sprintf("%d of your %d transactions in the last year have no tags.", untagged, total)
+	if untagged > 0 {
+		signal.Action = "Tag your transactions so they can be grouped and reported on."
+	}
+	return signal, nil
+}
+
+// staleValuations scores the share of valuations that are up to date: fixed assets
+// valued within the last year and held assets priced within the last week
+func (s *HealthService) staleValuations(ctx context.Context, userID uuid.UUID, _ *models.NetWorthSummary, now time.Time) (models.HealthSignal, error) {
+	signal := models.HealthSignal{Key: models.HealthSignalStaleValuations, Label: "Stale valuations", Weight: 15}
+
+	fixedAssets, err := s.fixedAssetRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return signal, err
+	}
+	assets, err := s.assetRepo.GetHeldAssets(ctx, userID)
+	if err != nil {
+		return signal, err
+	}
+
+	total := len(fixedAssets) + len(assets)
+	if total == 0 {
+		signal.Explanation = "You have no holdings or fixed assets to value."
+		return signal, nil
+	}
+
+	staleFixed := 0
+	for _, fa := range fixedAssets {
+		valued := fa.UpdatedAt
+		if fa.ValuationDate != nil {
+			valued = *fa.ValuationDate
+		}
+		if now.Sub(valued) > fixedAssetValuationDays*24*time.Hour {
+			staleFixed++
+			signal.Items = append(signal.Items, fa.Name)
+		}
+	}
+	stalePrices := 0
+	for _, a := range assets {
+		if a.LastPriceUpdatedAt == nil || now.Sub(*a.LastPriceUpdatedAt) > priceStaleDays*24*time.Hour {
+			stalePrices++
+			signal.Items = append(signal.Items, a.Symbol)
+		}
+	}
+
+	stale := staleFixed + stalePrices
+	scoreSignal(&signal, float64(total-stale)/float64(total)*100, float64(stale))
+	signal.Explanation = fmt.Sprintf("%d fixed assets not valued in the last year and %d holdings without a price in the last week.", staleFixed, stalePrices)
+	if staleFixed > 0 {
+		signal.Action = "Update the value of your property, vehicles and other fixed assets."
+	} else if stalePrices > 0 {
+		signal.Action = "Check the price source of holdings that are no longer updating."
+	}
+	return signal, nil
+}
+
+// overdueReminders loses points for each open reminder past its due date
+func (s *HealthService) overdueReminders(ctx context.Context, userID uuid.UUID, _ *models.NetWorthSummary, now time.Time) (models.HealthSignal, error) {
+	signal := models.HealthSignal{Key: models.HealthSignalOverdueReminders, Label: "Overdue reminders", Weight: 20}
+
+	reminders, err := s.reminderRepo.GetByUserID(ctx, userID, false)
+	if err != nil {
+		return signal, err
+	}
+
+	today := startOfDay(now)
+	for _, r := range reminders {
+		if r.DueDate.Before(today) {
+			signal.Items = append(signal.Items, r.Text)
+		}
+	}
+
+	overdue := len(signal.Items)
+	scoreSignal(&signal, float64(100-overdue*overdueReminderPenalty), float64(overdue))
+	signal.Explanation = fmt.Sprintf("%d reminders are past their due date.", overdue)
+	if overdue > 0 {
+		signal.Action = "Complete or reschedule your overdue reminders."
+	}
+	return signal, nil
+}
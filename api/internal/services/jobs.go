@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrShuttingDown = errors.New("shutting down, not accepting new jobs")
+)
+
+// JobStatus is the outcome of a job's most recent runs since the process started
+type JobStatus struct {
+	Name          string     `json:"name"`
+	LastStartedAt *time.Time `json:"last_started_at,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+	LastError     string     `json:"-"`
+}
+
+// Healthy reports whether the job's latest finished run succeeded
+func (s JobStatus) Healthy() bool {
+	return s.LastFailureAt == nil || (s.LastSuccessAt != nil && s.LastSuccessAt.After(*s.LastFailureAt))
+}
+
+// JobManager tracks in-flight background jobs so shutdown can drain them
+// instead of killing them mid-way
+type JobManager struct {
+	logger   *slog.Logger
+	mu       sync.Mutex
+	draining bool
+	wg       sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+	statuses map[string]*JobStatus
+}
+
+func NewJobManager(logger *slog.Logger) *JobManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &JobManager{
+		logger:   logger,
+		ctx:      ctx,
+		cancel:   cancel,
+		statuses: make(map[string]*JobStatus),
+	}
+}
+
+// Statuses returns the last run of each job, by name. Jobs named "kind:detail" are
+// grouped under their kind.
+func (m *JobManager) Statuses() []JobStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(m.statuses))
+	for _, s := range m.statuses {
+		statuses = append(statuses, *s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// record notes that a job started or, once finished, its outcome
+func (m *JobManager) record(name string, finished bool, err error) {
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		name = name[:i]
+	}
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.statuses[name]
+	if !ok {
+		s = &JobStatus{Name: name}
+		m.statuses[name] = s
+	}
+	switch {
+	case !finished:
+		s.LastStartedAt = &now
+	case err != nil:
+		s.LastFailureAt = &now
+		s.LastError = err.Error()
+	default:
+		s.LastSuccessAt = &now
+	}
+}
+
+// Run executes fn as a tracked job, returning ErrShuttingDown once shutdown has begun.
+// The context passed to fn is cancelled if shutdown runs out of time; jobs should
+// checkpoint their progress and return when that happens.
+func (m *JobManager) Run(name string, fn func(ctx context.Context) error) error {
+	if err := m.register(); err != nil {
+		return err
+	}
+	defer m.wg.Done()
+
+	return m.run(name, fn)
+}
+
+// Go is like Run but executes fn in a new goroutine, returning as soon as the job is
+// registered (or ErrShuttingDown if it can't be)
+func (m *JobManager) Go(name string, fn func(ctx context.Context) error) error {
+	if err := m.register(); err != nil {
+		return err
+	}
+
+	go func() {
+		defer m.wg.Done()
+		_ = m.run(name, fn)
+	}()
+	return nil
+}
+
+func (m *JobManager) register() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.draining {
+		return ErrShuttingDown
+	}
+	m.wg.Add(1)
+	return nil
+}
+
+func (m *JobManager) run(name string, fn func(ctx context.Context) error) (err error) {
+	start := time.Now()
+	m.record(name, false, nil)
+	// A panicking job is recorded as failed rather than taking the server down
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+			m.record(name, true, err)
+			m.logger.Error("job panicked", "job", name, "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+	err = fn(m.ctx)
+	m.record(name, true, err)
+	if err != nil {
+		m.logger.Error("job failed", "job", name, "error", err, "duration", time.Since(start))
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops new jobs from starting and waits for in-flight jobs to finish.
+// If ctx expires first, running jobs are cancelled and Shutdown waits for them to checkpoint.
+func (m *JobManager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.draining = true
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		m.cancel()
+		return nil
+	case <-ctx.Done():
+		m.logger.Warn("job drain timed out, checkpointing in-flight jobs")
+		m.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
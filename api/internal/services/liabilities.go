@@ -0,0 +1,96 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/mark-regan/wellf/internal/models"
+)
+
+// MaxRepaymentMonths caps a repayment schedule at 50 years
+const MaxRepaymentMonths = 600
+
+// nextPaymentDate is the first payment day of the liability after from
+func nextPaymentDate(l *models.Liability, from time.Time) time.Time {
+	day := l.PaymentDay
+	if day < 1 {
+		day = 1
+	}
+	next := time.Date(from.Year(), from.Month(), day, 0, 0, 0, 0, time.UTC)
+	if !next.After(startOfDay(from)) {
+		next = next.AddDate(0, 1, 0)
+	}
+	return next
+}
+
+// LiabilityMonthlyPayment is the liability's monthly payment. Without one set, a
+// mortgage or loan with an end date is assumed to be repaid in equal payments by then;
+// otherwise it is zero.
+func LiabilityMonthlyPayment(l *models.Liability, from time.Time) float64 {
+	if l.MonthlyPayment != nil {
+		return *l.MonthlyPayment
+	}
+	if l.LiabilityType == models.LiabilityTypeCreditCard || l.EndDate == nil || l.Balance <= 0 {
+		return 0
+	}
+
+	months := 0
+	for d := nextPaymentDate(l, from); !d.After(*l.EndDate); d = d.AddDate(0, 1, 0) {
+		months++
+	}
+	if months == 0 {
+		return l.Balance
+	}
+
+	rate := l.InterestRate / 100 / 12
+	if rate == 0 {
+		return roundPence(l.Balance / float64(months))
+	}
+	return roundPence(l.Balance * rate / (1 - math.Pow(1+rate, -float64(months))))
+}
+
+// RepaymentSchedule projects the liability's monthly payments from its next payment day
+// after from, for up to maxMonths. Interest is charged monthly at a twelfth of the
+// annual rate and the last payment is cut to what is left, or raised to clear pence left
+// over from rounding.
+func RepaymentSchedule(l *models.Liability, from time.Time, maxMonths int) *models.RepaymentSchedule {
+	payment := LiabilityMonthlyPayment(l, from)
+	schedule := &models.RepaymentSchedule{
+		LiabilityID:    l.ID,
+		Currency:       l.Currency,
+		MonthlyPayment: payment,
+		Rows:           []models.RepaymentScheduleRow{},
+	}
+
+	rate := l.InterestRate / 100 / 12
+	balance := l.Balance
+	date := nextPaymentDate(l, from)
+	for i := 0; i < maxMonths && balance > 0 && payment > 0; i++ {
+		interest := roundPence(balance * rate)
+		paid := math.Min(payment, roundPence(balance+interest))
+		if left := roundPence(balance + interest - paid); left > 0 && left < payment/100 {
+			// Rounding leaves a few pence over; clear them with this payment
+			paid = roundPence(paid + left)
+		}
+		balance = roundPence(balance + interest - paid)
+
+		schedule.Rows = append(schedule.Rows, models.RepaymentScheduleRow{
+			Date:      date.Format("2006-01-02"),
+			Payment:   paid,
+			Interest:  interest,
+			Principal: roundPence(paid - interest),
+			Balance:   balance,
+		})
+		schedule.TotalInterest += interest
+		schedule.TotalPaid += paid
+
+		if balance <= 0 {
+			schedule.PayoffDate = date.Format("2006-01-02")
+		}
+		date = date.AddDate(0, 1, 0)
+	}
+
+	schedule.TotalInterest = roundPence(schedule.TotalInterest)
+	schedule.TotalPaid = roundPence(schedule.TotalPaid)
+	return schedule
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+// lotEpsilon absorbs rounding in the DECIMAL(20, 8) quantities
+const lotEpsilon = 1e-8
+
+// LotService keeps each holding's purchase lots in step with its transactions
+type LotService struct {
+	lotRepo       *repository.HoldingLotRepository
+	holdingRepo   *repository.HoldingRepository
+	txRepo        *repository.TransactionRepository
+	portfolioRepo *repository.PortfolioRepository
+	logger        *slog.Logger
+}
+
+func NewLotService(
+	lotRepo *repository.HoldingLotRepository,
+	holdingRepo *repository.HoldingRepository,
+	txRepo *repository.TransactionRepository,
+	portfolioRepo *repository.PortfolioRepository,
+	logger *slog.Logger,
+) *LotService {
+	return &LotService{
+		lotRepo:       lotRepo,
+		holdingRepo:   holdingRepo,
+		txRepo:        txRepo,
+		portfolioRepo: portfolioRepo,
+		logger:        logger,
+	}
+}
+
+// CostBasisMethod returns the portfolio's cost basis method, AVERAGE if unset
+func CostBasisMethod(portfolio *models.Portfolio) string {
+	if portfolio.Metadata != nil && portfolio.Metadata.CostBasisMethod != "" {
+		return portfolio.Metadata.CostBasisMethod
+	}
+	return models.CostBasisAverage
+}
+
+// Sync rebuilds the asset's lots after its holding or transactions changed. Failures are
+// logged rather than returned; the lots are secondary to the change itself.
+func (s *LotService) Sync(ctx context.Context, portfolioID, assetID uuid.UUID) {
+	if _, _, err := s.Rebuild(ctx, portfolioID, assetID); err != nil {
+		s.logger.Warn("failed to rebuild holding lots", "portfolio_id", portfolioID, "asset_id", assetID, "error", err)
+	}
+}
+
+// SyncPortfolio rebuilds the lots of every holding in the portfolio, e.g. after its cost
+// basis method changed
+func (s *LotService) SyncPortfolio(ctx context.Context, portfolioID uuid.UUID) {
+	holdings, err := s.holdingRepo.GetByPortfolioID(ctx, portfolioID)
+	if err != nil {
+		s.logger.Warn("failed to list holdings for lot rebuild", "portfolio_id", portfolioID, "error", err)
+		return
+	}
+	for _, h := range holdings {
+		s.Sync(ctx, portfolioID, h.AssetID)
+	}
+}
+
+// Rebuild recomputes and stores the asset's lots, returning them with the cost basis
+// method used
+func (s *LotService) Rebuild(ctx context.Context, portfolioID, assetID uuid.UUID) ([]*models.HoldingLot, string, error) {
+	lots, method, err := s.Build(ctx, portfolioID, assetID)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.lotRepo.Replace(ctx, portfolioID, assetID, lots); err != nil {
+		return nil, "", err
+	}
+
+	return lots, method, nil
+}
+
+// Build recomputes the asset's lots from its holding and transactions without storing
+// them, returning them with the cost basis method used
+func (s *LotService) Build(ctx context.Context, portfolioID, assetID uuid.UUID) ([]*models.HoldingLot, string, error) {
+	portfolio, err := s.portfolioRepo.GetByID(ctx, portfolioID)
+	if err != nil {
+		return nil, "", err
+	}
+	method := CostBasisMethod(portfolio)
+
+	holding, err := s.holdingRepo.GetByPortfolioAndAsset(ctx, portfolioID, assetID)
+	if err != nil && !errors.Is(err, repository.ErrHoldingNotFound) {
+		return nil, "", err
+	}
+
+	txs, err := s.txRepo.GetByPortfolioAndAsset(ctx, portfolioID, assetID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	lots := BuildLots(txs, holding, method)
+	for _, lot := range lots {
+		lot.PortfolioID = portfolioID
+		lot.AssetID = assetID
+	}
+
+	return lots, method, nil
+}
+
+// BuildLots replays the transactions into lots. BUY and TRANSFER_IN open a lot; SELL and
+// TRANSFER_OUT draw units from the open lots: oldest first for FIFO, newest first for
+// LIFO, and pro rata across all of them for AVERAGE, which realises gains against the
+// pooled average cost. Only SELLs realise gains.
+//
+// The holding is the record of how many units are held, so any units it has that the
+// transactions don't account for (holdings entered directly, or deleted transactions)
+// go in an opening lot at the holding's average cost, ahead of the transaction lots.
+func BuildLots(txs []*models.Transaction, holding *models.Holding, method string) []*models.HoldingLot {
+	lots, _ := replayLots(txs, holding, method)
+	return lots
+}
+
+// Disposal is a SELL matched against the lots it drew from. Units sold beyond what the
+// lots held are left out of Quantity, Proceeds and CostBasis.
+type Disposal struct {
+	Transaction *models.Transaction
+	Quantity    float64
+	Proceeds    float64
+	CostBasis   float64
+	Gain        float64
+}
+
+// BuildDisposals replays the transactions as BuildLots does and returns each SELL with
+// the gain it realised, in date order
+func BuildDisposals(txs []*models.Transaction, holding *models.Holding, method string) []Disposal {
+	_, disposals := replayLots(txs, holding, method)
+	return disposals
+}
+
+func replayLots(txs []*models.Transaction, holding *models.Holding, method string) ([]*models.HoldingLot, []Disposal) {
+	var net float64
+	for _, tx := range txs {
+		if tx.Quantity == nil {
+			continue
+		}
+		switch tx.TransactionType {
+		case models.TransactionTypeBuy, models.TransactionTypeTransferIn:
+			net += *tx.Quantity
+		case models.TransactionTypeSell, models.TransactionTypeTransferOut:
+			net -= *tx.Quantity
+		}
+	}
+
+	var lots []*models.HoldingLot
+	var disposals []Disposal
+
+	if holding != nil && holding.Quantity-net > lotEpsilon {
+		opening := holding.Quantity - net
+		lot := &models.HoldingLot{
+			Quantity:          opening,
+			RemainingQuantity: opening,
+			UnitCost:          holding.AverageCost,
+		}
+		switch {
+		case holding.PurchasedAt != nil:
+			lot.AcquiredAt = startOfDay(*holding.PurchasedAt)
+		case len(txs) > 0:
+			lot.AcquiredAt = txs[0].TransactionDate
+		default:
+			lot.AcquiredAt = startOfDay(holding.CreatedAt)
+		}
+		lots = append(lots, lot)
+	}
+
+	for _, tx := range txs {
+		if tx.Quantity == nil || *tx.Quantity <= 0 {
+			continue
+		}
+		qty := *tx.Quantity
+		price := tx.PortfolioUnitPrice()
+
+		switch tx.TransactionType {
+		case models.TransactionTypeBuy, models.TransactionTypeTransferIn:
+			txID := tx.ID
+			lots = append(lots, &models.HoldingLot{
+				TransactionID:     &txID,
+				AcquiredAt:        tx.TransactionDate,
+				Quantity:          qty,
+				RemainingQuantity: qty,
+				UnitCost:          price,
+			})
+		case models.TransactionTypeSell, models.TransactionTypeTransferOut:
+			realise := tx.TransactionType == models.TransactionTypeSell
+			units, cost := disposeLots(lots, qty, price, realise, method)
+			if realise {
+				d := Disposal{
+					Transaction: tx,
+					Quantity:    units,
+					Proceeds:    roundPence(units * price),
+					CostBasis:   roundPence(cost),
+				}
+				d.Gain = roundPence(d.Proceeds - d.CostBasis)
+				disposals = append(disposals, d)
+			}
+		}
+	}
+
+	for _, lot := range lots {
+		lot.RealisedGain = roundPence(lot.RealisedGain)
+		if lot.RemainingQuantity < lotEpsilon {
+			lot.RemainingQuantity = 0
+		}
+	}
+
+	return lots, disposals
+}
+
+// disposeLots draws qty units from the open lots, returning the units drawn and their
+// cost. Units sold beyond what the lots hold are ignored.
+func disposeLots(lots []*models.HoldingLot, qty, price float64, realise bool, method string) (drawn, cost float64) {
+	take := func(lot *models.HoldingLot, units float64) {
+		lot.RemainingQuantity -= units
+		drawn += units
+		cost += units * lot.UnitCost
+		if realise {
+			lot.RealisedGain += units * (price - lot.UnitCost)
+		}
+	}
+
+	switch method {
+	case models.CostBasisFIFO, models.CostBasisLIFO:
+		for i := range lots {
+			lot := lots[i]
+			if method == models.CostBasisLIFO {
+				lot = lots[len(lots)-1-i]
+			}
+			if qty < lotEpsilon {
+				return drawn, cost
+			}
+			units := math.Min(qty, lot.RemainingQuantity)
+			if units <= 0 {
+				continue
+			}
+			take(lot, units)
+			qty -= units
+		}
+	default:
+		var open float64
+		for _, lot := range lots {
+			open += lot.RemainingQuantity
+		}
+		if open < lotEpsilon {
+			return 0, 0
+		}
+		share := math.Min(qty/open, 1)
+		for _, lot := range lots {
+			if lot.RemainingQuantity > 0 {
+				take(lot, lot.RemainingQuantity*share)
+			}
+		}
+	}
+	return drawn, cost
+}
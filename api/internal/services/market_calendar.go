@@ -0,0 +1,298 @@
+package services
+
+import (
+	"strings"
+	"time"
+)
+
+// Market identifies an exchange calendar. Several Yahoo exchange codes share one
+// calendar (e.g. NYSE and NASDAQ).
+type Market struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Timezone string `json:"timezone"`
+	location *time.Location
+	open     time.Duration // session open, as an offset from local midnight
+	close    time.Duration
+	holidays func(year int) []time.Time
+	always   bool // e.g. crypto, which trades around the clock
+}
+
+// MarketStatus is whether a market is open now and when it next changes state
+type MarketStatus struct {
+	Market    string     `json:"market"`
+	Name      string     `json:"name"`
+	Open      bool       `json:"open"`
+	Reason    string     `json:"reason,omitempty"` // WEEKEND, HOLIDAY or OUTSIDE_HOURS when closed
+	NextOpen  *time.Time `json:"next_open,omitempty"`
+	NextClose *time.Time `json:"next_close,omitempty"`
+}
+
+// MarketCalendar knows the regular trading sessions and public holidays of the
+// exchanges assets are listed on. Half days and one-off closures are not modelled.
+type MarketCalendar struct {
+	markets  map[string]*Market
+	exchange map[string]string // Yahoo exchange code -> market code
+}
+
+func NewMarketCalendar() *MarketCalendar {
+	london := loadLocation("Europe/London")
+	newYork := loadLocation("America/New_York")
+
+	markets := map[string]*Market{
+		"LSE": {
+			Code: "LSE", Name: "London Stock Exchange", Timezone: "Europe/London", location: london,
+			open: 8 * time.Hour, close: 16*time.Hour + 30*time.Minute, holidays: ukMarketHolidays,
+		},
+		"US": {
+			Code: "US", Name: "NYSE / NASDAQ", Timezone: "America/New_York", location: newYork,
+			open: 9*time.Hour + 30*time.Minute, close: 16 * time.Hour, holidays: usMarketHolidays,
+		},
+		"CRYPTO": {
+			Code: "CRYPTO", Name: "Crypto", Timezone: "UTC", location: time.UTC, always: true,
+		},
+	}
+
+	exchange := map[string]string{
+		"LSE": "LSE", "IOB": "LSE", "AQS": "LSE",
+		"NYQ": "US", "NYS": "US", "NMS": "US", "NGM": "US", "NCM": "US", "NAS": "US",
+		"ASE": "US", "PCX": "US", "BTS": "US", "PNK": "US", "NYSE": "US", "NASDAQ": "US",
+		"CCC": "CRYPTO", "CCY": "CRYPTO",
+	}
+
+	return &MarketCalendar{markets: markets, exchange: exchange}
+}
+
+func loadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// MarketFor returns the calendar for a Yahoo exchange code (or market code), if known
+func (c *MarketCalendar) MarketFor(exchange string) (*Market, bool) {
+	code := strings.ToUpper(strings.TrimSpace(exchange))
+	if m, ok := c.markets[code]; ok {
+		return m, true
+	}
+	m, ok := c.markets[c.exchange[code]]
+	return m, ok
+}
+
+// IsTradingDay reports whether the market trades on the calendar day containing t
+// (in the market's own timezone)
+func (m *Market) IsTradingDay(t time.Time) bool {
+	if m.always {
+		return true
+	}
+	local := t.In(m.location)
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		return false
+	}
+	return !m.isHoliday(local)
+}
+
+func (m *Market) isHoliday(local time.Time) bool {
+	for _, h := range m.holidays(local.Year()) {
+		if h.Month() == local.Month() && h.Day() == local.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOpen reports whether the market is in its regular session at t
+func (m *Market) IsOpen(t time.Time) bool {
+	if m.always {
+		return true
+	}
+	if !m.IsTradingDay(t) {
+		return false
+	}
+	local := t.In(m.location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, m.location)
+	return !local.Before(midnight.Add(m.open)) && local.Before(midnight.Add(m.close))
+}
+
+// Status returns whether the market is open at t and when it next opens or closes
+func (m *Market) Status(t time.Time) MarketStatus {
+	status := MarketStatus{Market: m.Code, Name: m.Name}
+	if m.always {
+		status.Open = true
+		return status
+	}
+
+	local := t.In(m.location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, m.location)
+
+	if m.IsOpen(t) {
+		status.Open = true
+		closeAt := midnight.Add(m.close)
+		status.NextClose = &closeAt
+		return status
+	}
+
+	switch {
+	case local.Weekday() == time.Saturday || local.Weekday() == time.Sunday:
+		status.Reason = "WEEKEND"
+	case m.isHoliday(local):
+		status.Reason = "HOLIDAY"
+	default:
+		status.Reason = "OUTSIDE_HOURS"
+	}
+
+	// Today's session if it hasn't started yet, otherwise the next trading day's
+	day := midnight
+	if !m.IsTradingDay(t) || !local.Before(midnight.Add(m.open)) {
+		day = m.nextTradingDay(midnight)
+	}
+	openAt := day.Add(m.open)
+	status.NextOpen = &openAt
+	return status
+}
+
+// nextTradingDay returns local midnight of the first trading day after day
+func (m *Market) nextTradingDay(day time.Time) time.Time {
+	for {
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, m.location)
+		if m.IsTradingDay(day) {
+			return day
+		}
+	}
+}
+
+// TradingDaysBetween counts trading days after from up to and including to
+func (m *Market) TradingDaysBetween(from, to time.Time) int {
+	if !to.After(from) {
+		return 0
+	}
+	days := 0
+	day := from.In(m.location)
+	end := to.In(m.location)
+	for {
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, m.location)
+		if day.After(end) {
+			return days
+		}
+		if m.IsTradingDay(day) {
+			days++
+		}
+	}
+}
+
+// AddTradingDays returns the date n trading days after t, e.g. T+2 settlement
+func (m *Market) AddTradingDays(t time.Time, n int) time.Time {
+	day := t.In(m.location)
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, m.location)
+	for i := 0; i < n; i++ {
+		day = m.nextTradingDay(day)
+	}
+	return day
+}
+
+// ukMarketHolidays returns the LSE closures for England and Wales bank holidays
+func ukMarketHolidays(year int) []time.Time {
+	easter := easterSunday(year)
+	return []time.Time{
+		weekdayOnOrAfter(date(year, time.January, 1)),
+		easter.AddDate(0, 0, -2), // Good Friday
+		easter.AddDate(0, 0, 1),  // Easter Monday
+		nthWeekday(year, time.May, time.Monday, 1),
+		lastWeekday(year, time.May, time.Monday),
+		lastWeekday(year, time.August, time.Monday),
+		weekdayOnOrAfter(date(year, time.December, 25)),
+		boxingDay(year),
+	}
+}
+
+// usMarketHolidays returns the NYSE holidays, observed on the nearest weekday
+func usMarketHolidays(year int) []time.Time {
+	holidays := []time.Time{
+		nthWeekday(year, time.January, time.Monday, 3),  // Martin Luther King Jr. Day
+		nthWeekday(year, time.February, time.Monday, 3), // Washington's Birthday
+		easterSunday(year).AddDate(0, 0, -2),            // Good Friday
+		lastWeekday(year, time.May, time.Monday),        // Memorial Day
+		nearestWeekday(date(year, time.July, 4)),
+		nthWeekday(year, time.September, time.Monday, 1),  // Labor Day
+		nthWeekday(year, time.November, time.Thursday, 4), // Thanksgiving
+		nearestWeekday(date(year, time.December, 25)),
+	}
+	// New Year's Day falling on a Saturday is not observed on the Friday before
+	if newYear := date(year, time.January, 1); newYear.Weekday() != time.Saturday {
+		holidays = append(holidays, nearestWeekday(newYear))
+	}
+	if year >= 2022 {
+		holidays = append(holidays, nearestWeekday(date(year, time.June, 19))) // Juneteenth
+	}
+	return holidays
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// easterSunday uses the anonymous Gregorian algorithm
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return date(year, time.Month(month), day)
+}
+
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	d := date(year, month, 1)
+	for d.Weekday() != weekday {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d.AddDate(0, 0, 7*(n-1))
+}
+
+func lastWeekday(year int, month time.Month, weekday time.Weekday) time.Time {
+	d := date(year, month+1, 0)
+	for d.Weekday() != weekday {
+		d = d.AddDate(0, 0, -1)
+	}
+	return d
+}
+
+// weekdayOnOrAfter moves a weekend date to the following Monday (UK substitute days)
+func weekdayOnOrAfter(d time.Time) time.Time {
+	for d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d
+}
+
+// boxingDay is 26 December, moved past the weekend and the Christmas substitute day
+func boxingDay(year int) time.Time {
+	d := date(year, time.December, 26)
+	switch d.Weekday() {
+	case time.Saturday, time.Sunday:
+		return d.AddDate(0, 0, 2)
+	case time.Monday:
+		// Christmas was on Sunday and is observed today, so Boxing Day moves to Tuesday
+		return d.AddDate(0, 0, 1)
+	}
+	return d
+}
+
+// nearestWeekday observes Saturday holidays on Friday and Sunday holidays on Monday
+func nearestWeekday(d time.Time) time.Time {
+	switch d.Weekday() {
+	case time.Saturday:
+		return d.AddDate(0, 0, -1)
+	case time.Sunday:
+		return d.AddDate(0, 0, 1)
+	}
+	return d
+}
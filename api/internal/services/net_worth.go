@@ -0,0 +1,433 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+const (
+	netWorthSnapshotJob = "net_worth_snapshots"
+
+	// netWorthSnapshotHour is the hour (UTC) after which the daily snapshots are taken,
+	// once the London and New York markets have closed
+	netWorthSnapshotHour = 22
+)
+
+// NetWorthService values each user's portfolios, cash and fixed assets less their
+// liabilities, and records a daily snapshot of the totals so growth can be charted
+// without recomputing history
+type NetWorthService struct {
+	userRepo       *repository.UserRepository
+	portfolioRepo  *repository.PortfolioRepository
+	holdingRepo    *repository.HoldingRepository
+	cashRepo       *repository.CashAccountRepository
+	fixedAssetRepo *repository.FixedAssetRepository
+	liabilityRepo  *repository.LiabilityRepository
+	snapshotRepo   *repository.SnapshotRepository
+	checkpointRepo *repository.JobCheckpointRepository
+	currency       *CurrencyService
+	jobs           *JobManager
+	logger         *slog.Logger
+}
+
+func NewNetWorthService(
+	userRepo *repository.UserRepository,
+	portfolioRepo *repository.PortfolioRepository,
+	holdingRepo *repository.HoldingRepository,
+	cashRepo *repository.CashAccountRepository,
+	fixedAssetRepo *repository.FixedAssetRepository,
+	liabilityRepo *repository.LiabilityRepository,
+	snapshotRepo *repository.SnapshotRepository,
+	checkpointRepo *repository.JobCheckpointRepository,
+	currency *CurrencyService,
+	jobs *JobManager,
+	logger *slog.Logger,
+) *NetWorthService {
+	return &NetWorthService{
+		userRepo:       userRepo,
+		portfolioRepo:  portfolioRepo,
+		holdingRepo:    holdingRepo,
+		cashRepo:       cashRepo,
+		fixedAssetRepo: fixedAssetRepo,
+		liabilityRepo:  liabilityRepo,
+		snapshotRepo:   snapshotRepo,
+		checkpointRepo: checkpointRepo,
+		currency:       currency,
+		jobs:           jobs,
+		logger:         logger,
+	}
+}
+
+// Summary values the user's net worth now, in their base currency. Each holding, cash
+// balance, fixed asset and liability is converted from its own currency and listed in
+// Items; liabilities are subtracted from the total. The
+// change figures compare the total with the latest snapshot on or before a day, a
+// week, a month and a year ago.
+func (s *NetWorthService) Summary(ctx context.Context, userID uuid.UUID) (*models.NetWorthSummary, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolios, err := s.portfolioRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	conv := s.currency.NewConverter(user.BaseCurrency)
+	summary := &models.NetWorthSummary{
+		Currency: user.BaseCurrency,
+		Items:    []models.ValuedItem{},
+	}
+
+	// Fixed assets are owned by the user rather than a portfolio; the FIXED_ASSETS
+	// portfolio just presents them
+	fixedItems, err := s.valueFixedAssets(ctx, conv, userID)
+	if err != nil {
+		fixedItems = nil
+	}
+	for _, item := range fixedItems {
+		summary.FixedAssets += item.Value.BaseAmount
+	}
+
+	for _, p := range portfolios {
+		ps, items, err := s.valuePortfolio(ctx, conv, p, fixedItems)
+		if err != nil {
+			continue
+		}
+		switch p.Type {
+		case models.PortfolioTypeCash, models.PortfolioTypeSavings:
+			// CASH and SAVINGS portfolio values go to cash, not investments
+			summary.Cash += ps.TotalValue
+		case models.PortfolioTypeFixedAssets:
+			// Counted in FixedAssets above
+		default:
+			summary.Investments += ps.TotalValue
+		}
+		if p.Type != models.PortfolioTypeFixedAssets {
+			summary.Items = append(summary.Items, items...)
+		}
+		summary.PortfolioSummary = append(summary.PortfolioSummary, *ps)
+	}
+
+	// Cash accounts within investment portfolios
+	accounts, err := s.cashRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		accounts = nil
+	}
+	for _, a := range accounts {
+		portfolioID := a.PortfolioID
+		item := models.ValuedItem{
+			Kind:        models.ValuedItemCashAccount,
+			ID:          a.ID,
+			PortfolioID: &portfolioID,
+			Name:        a.AccountName,
+			Category:    a.AccountType,
+			Value:       conv.Convert(ctx, a.Balance, a.Currency),
+		}
+		summary.Cash += item.Value.BaseAmount
+		summary.Items = append(summary.Items, item)
+	}
+
+	summary.Items = append(summary.Items, fixedItems...)
+
+	liabilities, err := s.liabilityRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		liabilities = nil
+	}
+	for _, l := range liabilities {
+		item := models.ValuedItem{
+			Kind:     models.ValuedItemLiability,
+			ID:       l.ID,
+			Name:     l.Name,
+			Category: l.LiabilityType,
+			Value:    conv.Convert(ctx, l.Balance, l.Currency),
+		}
+		summary.Liabilities += item.Value.BaseAmount
+		summary.Items = append(summary.Items, item)
+	}
+
+	summary.Investments = roundPence(summary.Investments)
+	summary.Cash = roundPence(summary.Cash)
+	summary.FixedAssets = roundPence(summary.FixedAssets)
+	summary.Liabilities = roundPence(summary.Liabilities)
+	summary.TotalNetWorth = roundPence(summary.Investments + summary.Cash + summary.FixedAssets - summary.Liabilities)
+
+	today := startOfDay(time.Now())
+	snapshots, err := s.snapshotRepo.GetByUserIDInRange(ctx, userID, today.AddDate(-1, 0, -7), today.AddDate(0, 0, -1))
+	if err == nil {
+		change := func(since time.Time) float64 {
+			var base *models.NetWorthSnapshot
+			for _, snap := range snapshots {
+				if snap.SnapshotDate.After(since) {
+					break
+				}
+				base = snap
+			}
+			if base == nil {
+				return 0
+			}
+			return roundPence(summary.TotalNetWorth - base.TotalNetWorth)
+		}
+		summary.ChangeDay = change(today.AddDate(0, 0, -1))
+		summary.ChangeWeek = change(today.AddDate(0, 0, -7))
+		summary.ChangeMonth = change(today.AddDate(0, -1, 0))
+		summary.ChangeYear = change(today.AddDate(-1, 0, 0))
+	}
+
+	return summary, nil
+}
+
+// PortfolioSummary values one of the user's portfolios in their base currency, listing
+// the converted items
+func (s *NetWorthService) PortfolioSummary(ctx context.Context, userID uuid.UUID, portfolio *models.Portfolio) (*models.PortfolioSummary, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	conv := s.currency.NewConverter(user.BaseCurrency)
+
+	var fixedItems []models.ValuedItem
+	if portfolio.Type == models.PortfolioTypeFixedAssets {
+		fixedItems, err = s.valueFixedAssets(ctx, conv, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ps, items, err := s.valuePortfolio(ctx, conv, portfolio, fixedItems)
+	if err != nil {
+		return nil, err
+	}
+	ps.Items = items
+	if ps.Items == nil {
+		ps.Items = []models.ValuedItem{}
+	}
+	return ps, nil
+}
+
+// valueFixedAssets converts the user's fixed assets, with their purchase price as cost
+func (s *NetWorthService) valueFixedAssets(ctx context.Context, conv *CurrencyConverter, userID uuid.UUID) ([]models.ValuedItem, error) {
+	fixedAssets, err := s.fixedAssetRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.ValuedItem, 0, len(fixedAssets))
+	for _, fa := range fixedAssets {
+		item := models.ValuedItem{
+			Kind:     models.ValuedItemFixedAsset,
+			ID:       fa.ID,
+			Name:     fa.Name,
+			Category: fa.Category,
+			Value:    conv.Convert(ctx, fa.CurrentValue, fa.Currency),
+		}
+		if fa.PurchasePrice != nil {
+			cost := conv.Convert(ctx, *fa.PurchasePrice, fa.Currency)
+			item.Cost = &cost
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// valuePortfolio totals a portfolio in the converter's base currency. Investment
+// portfolios are valued holding by holding at the last price (or cost if there is
+// none), CASH and SAVINGS portfolios by their balance, and the FIXED_ASSETS portfolio
+// by the given fixed asset items.
+func (s *NetWorthService) valuePortfolio(ctx context.Context, conv *CurrencyConverter, p *models.Portfolio, fixedItems []models.ValuedItem) (*models.PortfolioSummary, []models.ValuedItem, error) {
+	ps := &models.PortfolioSummary{
+		ID:       p.ID,
+		Name:     p.Name,
+		Type:     p.Type,
+		Currency: conv.Base(),
+	}
+	var items []models.ValuedItem
+
+	switch p.Type {
+	case models.PortfolioTypeCash, models.PortfolioTypeSavings:
+		balance, err := s.portfolioRepo.GetSummary(ctx, p.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		portfolioID := p.ID
+		item := models.ValuedItem{
+			Kind:        models.ValuedItemCash,
+			ID:          p.ID,
+			PortfolioID: &portfolioID,
+			Name:        p.Name,
+			Value:       conv.Convert(ctx, balance.TotalValue, p.Currency),
+		}
+		ps.TotalValue = item.Value.BaseAmount
+		items = append(items, item)
+
+	case models.PortfolioTypeFixedAssets:
+		for _, item := range fixedItems {
+			ps.TotalValue += item.Value.BaseAmount
+			if item.Cost != nil {
+				ps.TotalCost += item.Cost.BaseAmount
+			}
+			ps.HoldingsCount++
+		}
+		items = fixedItems
+
+	default:
+		holdings, err := s.holdingRepo.GetByPortfolioID(ctx, p.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, h := range holdings {
+			// average cost is held in the portfolio's currency and prices in the asset's
+			price, priceCurrency := h.AverageCost, p.Currency
+			var name, category string
+			if h.Asset != nil {
+				if h.Asset.LastPrice != nil {
+					price, priceCurrency = *h.Asset.LastPrice, h.Asset.Currency
+				}
+				name, category = h.Asset.Symbol, h.Asset.AssetType
+			}
+			portfolioID := p.ID
+			cost := conv.Convert(ctx, h.Quantity*h.AverageCost, p.Currency)
+			item := models.ValuedItem{
+				Kind:        models.ValuedItemHolding,
+				ID:          h.ID,
+				PortfolioID: &portfolioID,
+				Name:        name,
+				Category:    category,
+				Value:       conv.Convert(ctx, h.Quantity*price, priceCurrency),
+				Cost:        &cost,
+			}
+			ps.TotalValue += item.Value.BaseAmount
+			ps.TotalCost += cost.BaseAmount
+			ps.HoldingsCount++
+			items = append(items, item)
+		}
+	}
+
+	ps.TotalValue = roundPence(ps.TotalValue)
+	ps.TotalCost = roundPence(ps.TotalCost)
+	if p.Type != models.PortfolioTypeCash && p.Type != models.PortfolioTypeSavings {
+		ps.UnrealisedGain = roundPence(ps.TotalValue - ps.TotalCost)
+		if ps.TotalCost > 0 {
+			ps.UnrealisedPct = ps.UnrealisedGain / ps.TotalCost * 100
+		}
+	}
+
+	return ps, items, nil
+}
+
+// RecordSnapshot stores today's valuation for the user, replacing any earlier one
+func (s *NetWorthService) RecordSnapshot(ctx context.Context, userID uuid.UUID, summary *models.NetWorthSummary) error {
+	portfolioValues := make(map[uuid.UUID]float64, len(summary.PortfolioSummary))
+	for _, ps := range summary.PortfolioSummary {
+		portfolioValues[ps.ID] = ps.TotalValue
+	}
+
+	return s.snapshotRepo.Upsert(ctx, &models.NetWorthSnapshot{
+		UserID:          userID,
+		SnapshotDate:    startOfDay(time.Now()),
+		TotalNetWorth:   summary.TotalNetWorth,
+		Investments:     summary.Investments,
+		Cash:            summary.Cash,
+		FixedAssets:     summary.FixedAssets,
+		Liabilities:     summary.Liabilities,
+		Currency:        summary.Currency,
+		PortfolioValues: portfolioValues,
+	})
+}
+
+// netWorthSnapshotCheckpoint records the last day snapshots were taken for every user
+type netWorthSnapshotCheckpoint struct {
+	Date string `json:"date"`
+}
+
+// Run takes the daily snapshots for all users each evening until ctx is cancelled.
+// The day of the last complete run is checkpointed so restarts don't repeat it.
+func (s *NetWorthService) Run(ctx context.Context) {
+	ticker := time.NewTicker(15 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		s.runIfDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *NetWorthService) runIfDue(ctx context.Context) {
+	now := time.Now().UTC()
+	if now.Hour() < netWorthSnapshotHour {
+		return
+	}
+	today := now.Format("2006-01-02")
+
+	state, err := s.checkpointRepo.Get(ctx, netWorthSnapshotJob)
+	if err != nil && !errors.Is(err, repository.ErrCheckpointNotFound) {
+		s.logger.Error("failed to read snapshot checkpoint", "error", err)
+		return
+	}
+	var cp netWorthSnapshotCheckpoint
+	if err == nil {
+		_ = json.Unmarshal(state, &cp)
+	}
+	if cp.Date == today {
+		return
+	}
+
+	err = s.jobs.Run(netWorthSnapshotJob, func(ctx context.Context) error {
+		return s.recordAll(ctx, today)
+	})
+	if errors.Is(err, ErrShuttingDown) {
+		s.logger.Info("skipping net worth snapshots during shutdown")
+	}
+}
+
+// recordAll snapshots every user's net worth. Failures for one user are logged and
+// don't stop the others; an interrupted run is repeated in full on the next tick.
+func (s *NetWorthService) recordAll(ctx context.Context, today string) error {
+	users, err := s.userRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	recorded := 0
+	for _, u := range users {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		summary, err := s.Summary(ctx, u.ID)
+		if err == nil {
+			err = s.RecordSnapshot(ctx, u.ID, summary)
+		}
+		if err != nil {
+			s.logger.Warn("failed to record net worth snapshot", "user_id", u.ID, "error", err)
+			continue
+		}
+		recorded++
+	}
+
+	state, err := json.Marshal(netWorthSnapshotCheckpoint{Date: today})
+	if err != nil {
+		return err
+	}
+	if err := s.checkpointRepo.Save(ctx, netWorthSnapshotJob, state); err != nil {
+		return err
+	}
+	s.logger.Info("net worth snapshots recorded", "users", recorded)
+	return nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
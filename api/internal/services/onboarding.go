@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+// onboardingSteps is the checklist shown to new users, in display order
+var onboardingSteps = []models.OnboardingStep{
+	{Key: models.OnboardingStepSetBaseCurrency, Title: "Set your base currency", Description: "Choose the currency totals and reports are shown in."},
+	{Key: models.OnboardingStepCreatePortfolio, Title: "Create a portfolio", Description: "Add an ISA, SIPP, GIA or other account you hold."},
+	{Key: models.OnboardingStepAddHolding, Title: "Add a holding", Description: "Record an investment held in one of your portfolios."},
+	{Key: models.OnboardingStepAddCashAccount, Title: "Add a cash account", Description: "Track savings and current account balances."},
+	{Key: models.OnboardingStepCreateGoal, Title: "Set a savings goal", Description: "Put money aside for something you are saving towards."},
+}
+
+// OnboardingService tracks which setup steps each user has completed. Most steps are
+// observed from the user's data; others are recorded by the handlers that perform them.
+type OnboardingService struct {
+	repo   *repository.OnboardingRepository
+	logger *slog.Logger
+}
+
+func NewOnboardingService(repo *repository.OnboardingRepository, logger *slog.Logger) *OnboardingService {
+	return &OnboardingService{repo: repo, logger: logger}
+}
+
+// CompleteStep records a step as done. Failures are logged rather than returned so
+// callers never fail a request because of onboarding bookkeeping.
+func (s *OnboardingService) CompleteStep(ctx context.Context, userID uuid.UUID, step string) {
+	if err := s.repo.CompleteStep(ctx, userID, step); err != nil {
+		s.logger.Warn("failed to record onboarding step", "user_id", userID, "step", step, "error", err)
+	}
+}
+
+// Checklist returns the user's onboarding progress, recording any steps newly observed
+// in their data so a step stays complete even if the data is later removed
+func (s *OnboardingService) Checklist(ctx context.Context, userID uuid.UUID) (*models.OnboardingChecklist, error) {
+	completed, err := s.repo.GetCompletedSteps(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	observed, err := s.repo.GetObservedSteps(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range observed {
+		if _, ok := completed[step]; ok {
+			continue
+		}
+		if err := s.repo.CompleteStep(ctx, userID, step); err != nil {
+			return nil, err
+		}
+		completed[step] = time.Now()
+	}
+
+	checklist := &models.OnboardingChecklist{
+		Steps:      make([]models.OnboardingStep, len(onboardingSteps)),
+		TotalCount: len(onboardingSteps),
+	}
+	for i, step := range onboardingSteps {
+		if completedAt, ok := completed[step.Key]; ok {
+			step.Completed = true
+			step.CompletedAt = &completedAt
+			checklist.CompletedCount++
+		}
+		checklist.Steps[i] = step
+	}
+	checklist.Complete = checklist.CompletedCount == checklist.TotalCount
+
+	return checklist, nil
+}
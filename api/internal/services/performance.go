@@ -0,0 +1,491 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+// Return calculation methods
+const (
+	PerformanceMethodTWR  = "twr"
+	PerformanceMethodXIRR = "xirr"
+)
+
+var (
+	ErrInvalidPerformanceMethod = errors.New("invalid performance method")
+	ErrInvalidPerformancePeriod = errors.New("invalid performance period")
+)
+
+// performancePeriods maps each period to the price history range fetched for it
+var performancePeriods = map[string]string{
+	"1m":  "1mo",
+	"3m":  "3mo",
+	"6m":  "6mo",
+	"ytd": "1y",
+	"1y":  "1y",
+	"3y":  "5y",
+	"5y":  "5y",
+	"max": "max",
+}
+
+// PerformanceResult is the return of a set of holdings over a period. Values are the
+// market value of the holdings; cash and cash accounts are not included.
+type PerformanceResult struct {
+	Method      string  `json:"method"`
+	Period      string  `json:"period"`
+	Currency    string  `json:"currency"`
+	From        string  `json:"from"`
+	To          string  `json:"to"`
+	StartValue  float64 `json:"start_value"`
+	EndValue    float64 `json:"end_value"`
+	NetInvested float64 `json:"net_invested"` // purchases and transfers in, less sales and transfers out
+	Income      float64 `json:"income"`       // dividends and interest
+	Fees        float64 `json:"fees"`
+	Gain        float64 `json:"gain"`
+	// ReturnPct covers the whole period; AnnualisedPct is the equivalent yearly rate.
+	// Both are nil when nothing was invested during the period.
+	ReturnPct     *float64 `json:"return_pct"`
+	AnnualisedPct *float64 `json:"annualised_pct"`
+}
+
+// PerformanceService calculates time-weighted (TWR) and money-weighted (XIRR) returns
+// from transaction and price history
+type PerformanceService struct {
+	holdingRepo  *repository.HoldingRepository
+	txRepo       *repository.TransactionRepository
+	yahooService *YahooService
+	currency     *CurrencyService
+}
+
+func NewPerformanceService(holdingRepo *repository.HoldingRepository, txRepo *repository.TransactionRepository, yahooService *YahooService, currency *CurrencyService) *PerformanceService {
+	return &PerformanceService{
+		holdingRepo:  holdingRepo,
+		txRepo:       txRepo,
+		yahooService: yahooService,
+		currency:     currency,
+	}
+}
+
+// performanceFlow is the money moving into (positive) or out of the holdings on one day
+type performanceFlow struct {
+	date     time.Time
+	amount   float64
+	quantity map[string]float64 // change in units per symbol
+}
+
+// Calculate returns the combined performance of the portfolios over the period (1m, 3m,
+// 6m, ytd, 1y, 3y, 5y or max). Holdings are valued at historical closes, with units
+// reconstructed backwards from the current holdings using the transactions since, so
+// holdings entered without transactions are treated as held throughout.
+//
+// Purchases, transfers in and fees count as money invested; sales, transfers out,
+// dividends and interest as money returned. TWR chains the return between each day
+// with flows, so it measures the investments regardless of when money was added;
+// XIRR is the annual rate at which the flows and end value balance the start value.
+// Flows and values are in currency: flows are converted from each portfolio's currency,
+// using the converted amount of transactions made in another, and holdings are valued
+// at each day's close converted at that day's rate. ErrFXUnavailable is returned if a
+// rate can't be found.
+func (s *PerformanceService) Calculate(ctx context.Context, portfolios []*models.Portfolio, currency, method, period string) (*PerformanceResult, error) {
+	if method != PerformanceMethodTWR && method != PerformanceMethodXIRR {
+		return nil, ErrInvalidPerformanceMethod
+	}
+	historyRange, ok := performancePeriods[period]
+	if !ok {
+		return nil, ErrInvalidPerformancePeriod
+	}
+
+	portfolioIDs := make([]uuid.UUID, 0, len(portfolios))
+	portfolioCurrencies := make(map[uuid.UUID]string, len(portfolios))
+	for _, p := range portfolios {
+		portfolioIDs = append(portfolioIDs, p.ID)
+		portfolioCurrencies[p.ID] = p.Currency
+	}
+
+	quantities := make(map[string]float64)
+	fallbackPrices := make(map[string]float64)
+	assetCurrencies := make(map[string]string)
+	var earliest time.Time
+	for _, id := range portfolioIDs {
+		holdings, err := s.holdingRepo.GetByPortfolioID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range holdings {
+			quantities[h.Asset.Symbol] += h.Quantity
+			assetCurrencies[h.Asset.Symbol] = h.Asset.Currency
+			if h.Asset.LastPrice != nil {
+				fallbackPrices[h.Asset.Symbol] = *h.Asset.LastPrice
+			}
+			if h.PurchasedAt != nil && (earliest.IsZero() || h.PurchasedAt.Before(earliest)) {
+				earliest = *h.PurchasedAt
+			}
+		}
+	}
+
+	var transactions []*models.Transaction
+	if len(portfolioIDs) > 0 {
+		var err error
+		transactions, err = s.txRepo.GetByPortfolioIDs(ctx, portfolioIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, tx := range transactions {
+		if tx.Asset == nil {
+			continue
+		}
+		if _, ok := assetCurrencies[tx.Asset.Symbol]; !ok {
+			assetCurrencies[tx.Asset.Symbol] = tx.Asset.Currency
+		}
+		if tx.Price != nil && *tx.Price > 0 {
+			if _, ok := fallbackPrices[tx.Asset.Symbol]; !ok {
+				fallbackPrices[tx.Asset.Symbol] = *tx.Price
+			}
+		}
+	}
+	if len(transactions) > 0 && (earliest.IsZero() || transactions[0].TransactionDate.Before(earliest)) {
+		earliest = transactions[0].TransactionDate
+	}
+
+	now := time.Now().UTC()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	start := performanceStart(period, end, earliest)
+
+	prices := make(map[string][]PriceHistory)
+	symbols := make(map[string]bool)
+	for symbol := range quantities {
+		symbols[symbol] = true
+	}
+	for _, tx := range transactions {
+		if tx.Asset != nil {
+			symbols[tx.Asset.Symbol] = true
+		}
+	}
+	for symbol := range symbols {
+		history, err := s.yahooService.GetHistory(ctx, symbol, historyRange)
+		if err != nil {
+			continue
+		}
+		valid := make([]PriceHistory, 0, len(history))
+		for _, h := range history {
+			if h.Close > 0 {
+				valid = append(valid, h)
+			}
+		}
+		sort.Slice(valid, func(i, j int) bool { return valid[i].Date.Before(valid[j].Date) })
+		if len(valid) > 0 {
+			prices[symbol] = valid
+		}
+	}
+
+	// Rates into currency are looked up once per currency and day
+	rates := make(map[string]float64)
+	rate := func(from string, date time.Time) (float64, error) {
+		if from == "" {
+			return 1, nil
+		}
+		key := from + date.Format("2006-01-02")
+		if r, ok := rates[key]; ok {
+			return r, nil
+		}
+		r, err := s.currency.RateOn(ctx, from, currency, date)
+		if err != nil {
+			return 0, err
+		}
+		rates[key] = r
+		return r, nil
+	}
+
+	value := func(units map[string]float64, date time.Time) (float64, error) {
+		total := 0.0
+		for symbol, q := range units {
+			if q <= 0 {
+				continue
+			}
+			price := fallbackPrices[symbol]
+			if history, ok := prices[symbol]; ok {
+				price = closeOn(history, date)
+			}
+			r, err := rate(assetCurrencies[symbol], date)
+			if err != nil {
+				return 0, err
+			}
+			total += q * price * r
+		}
+		return total, nil
+	}
+
+	result := &PerformanceResult{
+		Method:   method,
+		Period:   period,
+		Currency: currency,
+		From:     start.Format("2006-01-02"),
+		To:       end.Format("2006-01-02"),
+	}
+
+	// Flows within the period, one per day, and the units held at the start
+	var flows []performanceFlow
+	units := make(map[string]float64, len(quantities))
+	for symbol, q := range quantities {
+		units[symbol] = q
+	}
+	for _, tx := range transactions {
+		amount, symbol, quantity, counted := classifyPerformanceTransaction(tx)
+		if !counted {
+			continue
+		}
+		if !tx.TransactionDate.After(start) {
+			continue
+		}
+		if symbol != "" {
+			units[symbol] -= quantity
+		}
+		if tx.TransactionDate.After(end) {
+			continue
+		}
+		r, err := rate(portfolioCurrencies[tx.PortfolioID], tx.TransactionDate)
+		if err != nil {
+			return nil, err
+		}
+		amount *= r
+		// Transfers recorded without a value are valued at the day's close
+		if amount == 0 && symbol != "" && quantity != 0 {
+			transferValue, err := value(map[string]float64{symbol: math.Abs(quantity)}, tx.TransactionDate)
+			if err != nil {
+				return nil, err
+			}
+			amount = math.Copysign(transferValue, quantity)
+		}
+
+		switch tx.TransactionType {
+		case models.TransactionTypeDividend, models.TransactionTypeInterest:
+			result.Income += -amount
+		case models.TransactionTypeFee:
+			result.Fees += amount
+		default:
+			result.NetInvested += amount
+		}
+
+		if n := len(flows); n == 0 || !flows[n-1].date.Equal(tx.TransactionDate) {
+			flows = append(flows, performanceFlow{date: tx.TransactionDate, quantity: make(map[string]float64)})
+		}
+		flow := &flows[len(flows)-1]
+		flow.amount += amount
+		if symbol != "" {
+			flow.quantity[symbol] += quantity
+		}
+	}
+
+	startValue, err := value(units, start)
+	if err != nil {
+		return nil, err
+	}
+	result.StartValue = roundPence(startValue)
+
+	// Chain the sub-period returns, with each day's flows at the end of the day
+	growth := 1.0
+	invested := false
+	marketValue := startValue
+	xirrFlows := []xirrCashFlow{}
+	if startValue > 0 {
+		xirrFlows = append(xirrFlows, xirrCashFlow{date: start, amount: -startValue})
+	}
+	for _, flow := range flows {
+		for symbol, q := range flow.quantity {
+			units[symbol] += q
+		}
+		endOfDay, err := value(units, flow.date)
+		if err != nil {
+			return nil, err
+		}
+		if marketValue > 0 {
+			growth *= (endOfDay - flow.amount) / marketValue
+			invested = true
+		}
+		marketValue = endOfDay
+		xirrFlows = append(xirrFlows, xirrCashFlow{date: flow.date, amount: -flow.amount})
+	}
+	endValue, err := value(units, end)
+	if err != nil {
+		return nil, err
+	}
+	if marketValue > 0 {
+		growth *= endValue / marketValue
+		invested = true
+	}
+	xirrFlows = append(xirrFlows, xirrCashFlow{date: end, amount: endValue})
+
+	result.EndValue = roundPence(endValue)
+	netFlows := result.NetInvested - result.Income + result.Fees
+	result.Gain = roundPence(endValue - startValue - netFlows)
+	result.NetInvested = roundPence(result.NetInvested)
+	result.Income = roundPence(result.Income)
+	result.Fees = roundPence(result.Fees)
+
+	if !invested {
+		return result, nil
+	}
+
+	years := end.Sub(start).Hours() / 24 / 365
+	var periodReturn, annualised float64
+	switch method {
+	case PerformanceMethodTWR:
+		periodReturn = growth - 1
+		annualised = periodReturn
+		if years > 0 && growth > 0 {
+			annualised = math.Pow(growth, 1/years) - 1
+		}
+	case PerformanceMethodXIRR:
+		rate, ok := xirr(xirrFlows)
+		if !ok {
+			return result, nil
+		}
+		annualised = rate
+		periodReturn = math.Pow(1+rate, years) - 1
+	}
+
+	periodPct := math.Round(periodReturn*10000) / 100
+	annualisedPct := math.Round(annualised*10000) / 100
+	result.ReturnPct = &periodPct
+	result.AnnualisedPct = &annualisedPct
+
+	return result, nil
+}
+
+// performanceStart returns the first day of the period ending at end. For max it is the
+// earliest transaction or purchase date.
+func performanceStart(period string, end, earliest time.Time) time.Time {
+	switch period {
+	case "1m":
+		return end.AddDate(0, -1, 0)
+	case "3m":
+		return end.AddDate(0, -3, 0)
+	case "6m":
+		return end.AddDate(0, -6, 0)
+	case "ytd":
+		return time.Date(end.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	case "3y":
+		return end.AddDate(-3, 0, 0)
+	case "5y":
+		return end.AddDate(-5, 0, 0)
+	case "max":
+		if !earliest.IsZero() && earliest.Before(end) {
+			e := earliest.UTC()
+			// The day before, so flows on the first day fall inside the period
+			return time.Date(e.Year(), e.Month(), e.Day()-1, 0, 0, 0, 0, time.UTC)
+		}
+	}
+	return end.AddDate(-1, 0, 0)
+}
+
+// classifyPerformanceTransaction returns the money a transaction moves into the holdings
+// (negative when paid out) and its change in units. Cash-only transactions such as
+// deposits and withdrawals are not counted.
+func classifyPerformanceTransaction(tx *models.Transaction) (amount float64, symbol string, quantity float64, counted bool) {
+	if tx.Asset != nil && tx.Quantity != nil {
+		symbol = tx.Asset.Symbol
+		quantity = *tx.Quantity
+	}
+
+	total := tx.PortfolioTotal()
+	switch tx.TransactionType {
+	case models.TransactionTypeBuy:
+		return total, symbol, quantity, true
+	case models.TransactionTypeSell:
+		return -total, symbol, -quantity, true
+	case models.TransactionTypeTransferIn:
+		if symbol == "" {
+			return 0, "", 0, false
+		}
+		return total, symbol, quantity, true
+	case models.TransactionTypeTransferOut:
+		if symbol == "" {
+			return 0, "", 0, false
+		}
+		return -total, symbol, -quantity, true
+	case models.TransactionTypeDividend, models.TransactionTypeInterest:
+		return -total, "", 0, true
+	case models.TransactionTypeFee:
+		return total, "", 0, true
+	}
+	return 0, "", 0, false
+}
+
+// xirrCashFlow is a dated flow from the investor's point of view (negative when invested)
+type xirrCashFlow struct {
+	date   time.Time
+	amount float64
+}
+
+// xirr solves for the annual rate at which the flows' net present value is zero, using
+// Newton's method and falling back to bisection. ok is false if there is no solution,
+// e.g. when all flows have the same sign.
+func xirr(flows []xirrCashFlow) (float64, bool) {
+	var hasPositive, hasNegative bool
+	for _, f := range flows {
+		hasPositive = hasPositive || f.amount > 0
+		hasNegative = hasNegative || f.amount < 0
+	}
+	if !hasPositive || !hasNegative {
+		return 0, false
+	}
+
+	first := flows[0].date
+	npv := func(rate float64) (float64, float64) {
+		value, derivative := 0.0, 0.0
+		for _, f := range flows {
+			years := f.date.Sub(first).Hours() / 24 / 365
+			discount := math.Pow(1+rate, years)
+			value += f.amount / discount
+			derivative -= years * f.amount / (discount * (1 + rate))
+		}
+		return value, derivative
+	}
+
+	rate := 0.1
+	for i := 0; i < 50; i++ {
+		value, derivative := npv(rate)
+		if math.Abs(value) < 1e-7 {
+			return rate, true
+		}
+		if derivative == 0 {
+			break
+		}
+		next := rate - value/derivative
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-rate) < 1e-10 {
+			return next, true
+		}
+		rate = next
+	}
+
+	low, high := -0.9999, 100.0
+	lowValue, _ := npv(low)
+	highValue, _ := npv(high)
+	if lowValue*highValue > 0 {
+		return 0, false
+	}
+	for i := 0; i < 200; i++ {
+		mid := (low + high) / 2
+		midValue, _ := npv(mid)
+		if math.Abs(midValue) < 1e-7 || high-low < 1e-10 {
+			return mid, true
+		}
+		if lowValue*midValue < 0 {
+			high = mid
+		} else {
+			low, lowValue = mid, midValue
+		}
+	}
+	return (low + high) / 2, true
+}
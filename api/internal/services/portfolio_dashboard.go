@@ -0,0 +1,327 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/mark-regan/wellf/pkg/taxyear"
+)
+
+// Portfolio dashboard panels
+const (
+	PanelSummary              = "summary"
+	PanelAllowance            = "allowance"
+	PanelPensionAllowance     = "pension_allowance"
+	PanelRetirementProjection = "retirement_projection"
+	PanelRebalance            = "rebalance"
+	PanelInterest             = "interest"
+	PanelWallets              = "wallets"
+)
+
+const (
+	// DefaultRetirementAge is used when a SIPP has no target retirement age: the state
+	// pension age for most people retiring in the next couple of decades
+	DefaultRetirementAge = 67
+	// DefaultRetirementGrowthRate is the real return assumed for retirement projections
+	DefaultRetirementGrowthRate = DefaultFireReturn
+
+	retirementContributionMonths = 12
+	pensionTaxFreeShare          = 0.25
+)
+
+var investmentPortfolioTypes = []string{
+	models.PortfolioTypeGIA,
+	models.PortfolioTypeISA,
+	models.PortfolioTypeSIPP,
+	models.PortfolioTypeLISA,
+	models.PortfolioTypeJISA,
+	models.PortfolioTypeCrypto,
+}
+
+// PanelRequest is what a panel is computed from. Summary is the portfolio valued in the
+// user's base currency, worked out once for all the panels.
+type PanelRequest struct {
+	UserID     uuid.UUID
+	Portfolio  *models.Portfolio
+	Summary    *models.PortfolioSummary
+	GrowthRate float64
+	Now        time.Time
+}
+
+// PanelFunc computes a panel's data. A nil result leaves the panel out, e.g. a rebalance
+// panel for a portfolio without targets.
+type PanelFunc func(ctx context.Context, req PanelRequest) (interface{}, error)
+
+type registeredPanel struct {
+	name  string
+	build PanelFunc
+}
+
+// PortfolioDashboardService builds the dashboard for a portfolio from the panels
+// registered for its type, in the order they were registered
+type PortfolioDashboardService struct {
+	panels     map[string][]registeredPanel
+	netWorth   *NetWorthService
+	allowances *AllowanceService
+	rebalance  *RebalanceService
+	txRepo     *repository.TransactionRepository
+	userRepo   *repository.UserRepository
+	currency   *CurrencyService
+}
+
+func NewPortfolioDashboardService(
+	netWorth *NetWorthService,
+	allowances *AllowanceService,
+	rebalance *RebalanceService,
+	txRepo *repository.TransactionRepository,
+	userRepo *repository.UserRepository,
+	currency *CurrencyService,
+) *PortfolioDashboardService {
+	s := &PortfolioDashboardService{
+		panels:     make(map[string][]registeredPanel),
+		netWorth:   netWorth,
+		allowances: allowances,
+		rebalance:  rebalance,
+		txRepo:     txRepo,
+		userRepo:   userRepo,
+		currency:   currency,
+	}
+
+	s.Register(PanelSummary, s.summaryPanel, append(investmentPortfolioTypes,
+		models.PortfolioTypeSavings, models.PortfolioTypeCash, models.PortfolioTypeFixedAssets)...)
+	s.Register(PanelAllowance, s.allowancePanel,
+		models.PortfolioTypeISA, models.PortfolioTypeLISA, models.PortfolioTypeJISA)
+	s.Register(PanelPensionAllowance, s.pensionAllowancePanel, models.PortfolioTypeSIPP)
+	s.Register(PanelRetirementProjection, s.retirementPanel, models.PortfolioTypeSIPP)
+	s.Register(PanelInterest, s.interestPanel, models.PortfolioTypeSavings)
+	s.Register(PanelWallets, s.walletsPanel, models.PortfolioTypeCrypto)
+	s.Register(PanelRebalance, s.rebalancePanel, investmentPortfolioTypes...)
+
+	return s
+}
+
+// Register adds a panel to the dashboards of the given portfolio types
+func (s *PortfolioDashboardService) Register(name string, build PanelFunc, portfolioTypes ...string) {
+	for _, t := range portfolioTypes {
+		s.panels[t] = append(s.panels[t], registeredPanel{name: name, build: build})
+	}
+}
+
+// Build computes the panels registered for the portfolio's type
+func (s *PortfolioDashboardService) Build(ctx context.Context, userID uuid.UUID, portfolio *models.Portfolio, growthRate float64) (*models.PortfolioDashboard, error) {
+	summary, err := s.netWorth.PortfolioSummary(ctx, userID, portfolio)
+	if err != nil {
+		return nil, err
+	}
+
+	req := PanelRequest{
+		UserID:     userID,
+		Portfolio:  portfolio,
+		Summary:    summary,
+		GrowthRate: growthRate,
+		Now:        time.Now(),
+	}
+	dashboard := &models.PortfolioDashboard{
+		PortfolioID: portfolio.ID,
+		Type:        portfolio.Type,
+		Panels:      []models.DashboardPanel{},
+	}
+	for _, panel := range s.panels[portfolio.Type] {
+		data, err := panel.build(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("%s panel: %w", panel.name, err)
+		}
+		if data == nil {
+			continue
+		}
+		dashboard.Panels = append(dashboard.Panels, models.DashboardPanel{Name: panel.name, Data: data})
+	}
+
+	return dashboard, nil
+}
+
+func (s *PortfolioDashboardService) summaryPanel(ctx context.Context, req PanelRequest) (interface{}, error) {
+	return req.Summary, nil
+}
+
+func (s *PortfolioDashboardService) allowancePanel(ctx context.Context, req PanelRequest) (interface{}, error) {
+	status, err := s.allowances.Status(ctx, req.Portfolio, taxyear.UKOf(req.Now))
+	if err != nil || status == nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (s *PortfolioDashboardService) pensionAllowancePanel(ctx context.Context, req PanelRequest) (interface{}, error) {
+	status, err := s.allowances.PensionStatus(ctx, req.Portfolio, taxyear.UK.Current())
+	if err != nil || status == nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (s *PortfolioDashboardService) rebalancePanel(ctx context.Context, req PanelRequest) (interface{}, error) {
+	report, err := s.rebalance.Report(ctx, req.UserID, req.Portfolio)
+	if err != nil || report.TargetBy == "" {
+		return nil, err
+	}
+	return report, nil
+}
+
+// retirementPanel projects the pension to the target retirement age, compounding its
+// value and the average monthly contribution (tax relief included) at the growth rate
+func (s *PortfolioDashboardService) retirementPanel(ctx context.Context, req PanelRequest) (interface{}, error) {
+	user, err := s.userRepo.GetByID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	thisMonth := startOfDay(req.Now)
+	thisMonth = thisMonth.AddDate(0, 0, 1-thisMonth.Day())
+	monthly, err := s.txRepo.GetMonthlyContributions(ctx, []uuid.UUID{req.Portfolio.ID}, thisMonth.AddDate(0, -retirementContributionMonths, 0))
+	if err != nil {
+		return nil, err
+	}
+	conv := s.currency.NewConverter(user.BaseCurrency)
+	var contributed float64
+	for _, c := range monthly {
+		if c.Month.Before(thisMonth) {
+			contributed += conv.Convert(ctx, c.Total, req.Portfolio.Currency).BaseAmount
+		}
+	}
+
+	p := &models.RetirementProjection{
+		Currency:            req.Summary.Currency,
+		CurrentValue:        req.Summary.TotalValue,
+		MonthlyContribution: roundPence(contributed / retirementContributionMonths),
+		GrowthRate:          req.GrowthRate,
+		RetirementAge:       DefaultRetirementAge,
+	}
+	if req.Portfolio.Metadata != nil && req.Portfolio.Metadata.TargetRetirementAge > 0 {
+		p.RetirementAge = req.Portfolio.Metadata.TargetRetirementAge
+	}
+	if user.DateOfBirth == nil {
+		return p, nil
+	}
+
+	dob := *user.DateOfBirth
+	age := req.Now.Year() - dob.Year()
+	if req.Now.Before(dob.AddDate(age, 0, 0)) {
+		age--
+	}
+	p.Age = &age
+	retirement := dob.AddDate(p.RetirementAge, 0, 0)
+	p.RetirementDate = retirement.Format("2006-01-02")
+
+	years := math.Max(retirement.Sub(req.Now).Hours()/24/365.25, 0)
+	rate := req.GrowthRate / 100
+	annual := p.MonthlyContribution * 12
+	value := p.CurrentValue * math.Pow(1+rate, years)
+	if rate == 0 {
+		value += annual * years
+	} else {
+		value += annual * (math.Pow(1+rate, years) - 1) / rate
+	}
+	p.YearsRemaining = math.Round(years*10) / 10
+	p.ProjectedValue = roundPence(value)
+	p.TaxFreeLumpSum = roundPence(value * pensionTaxFreeShare)
+
+	return p, nil
+}
+
+// interestPanel reports the interest paid into a savings portfolio and estimates what has
+// accrued since, up to its maturity date if it has passed
+func (s *PortfolioDashboardService) interestPanel(ctx context.Context, req PanelRequest) (interface{}, error) {
+	p := req.Portfolio
+	balance, err := s.txRepo.GetCashBalance(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	today := startOfDay(req.Now)
+	totals, err := s.txRepo.GetInterestTotals(ctx, req.UserID, time.Time{}, today)
+	if err != nil {
+		return nil, err
+	}
+
+	panel := &models.SavingsInterestPanel{
+		Currency: p.Currency,
+		Balance:  roundPence(balance),
+	}
+	if p.Metadata != nil {
+		panel.SavingsType = p.Metadata.SavingsType
+		panel.InterestRate = p.Metadata.InterestRate
+		panel.NoticePeriod = p.Metadata.NoticePeriod
+	}
+	lastPaid := startOfDay(p.CreatedAt)
+	for _, t := range totals {
+		if t.PortfolioID == p.ID && t.Currency == p.Currency {
+			panel.Received += t.Total
+			lastPaid = t.LastPaid
+			panel.LastPaid = t.LastPaid.Format("2006-01-02")
+		}
+	}
+	panel.Received = roundPence(panel.Received)
+
+	until := today
+	if p.Metadata != nil && p.Metadata.MaturityDate != "" {
+		if maturity, err := time.Parse("2006-01-02", p.Metadata.MaturityDate); err == nil {
+			panel.MaturityDate = maturity.Format("2006-01-02")
+			days := int(math.Ceil(maturity.Sub(today).Hours() / 24))
+			if days <= 0 {
+				panel.Matured = true
+				until = maturity
+				days = 0
+			}
+			panel.DaysToMaturity = &days
+		}
+	}
+
+	if balance > 0 && panel.InterestRate > 0 {
+		if !panel.Matured {
+			panel.AnnualInterest = roundPence(balance * panel.InterestRate / 100)
+		}
+		days := math.Max(until.Sub(lastPaid).Hours()/24, 0)
+		panel.Accrued = roundPence(balance * panel.InterestRate / 100 * days / 365)
+	}
+
+	return panel, nil
+}
+
+// walletsPanel breaks the crypto portfolio's value down by coin, largest first
+func (s *PortfolioDashboardService) walletsPanel(ctx context.Context, req PanelRequest) (interface{}, error) {
+	panel := &models.CryptoWalletPanel{
+		Currency:   req.Summary.Currency,
+		TotalValue: req.Summary.TotalValue,
+		Coins:      []models.AllocationItem{},
+	}
+	if req.Portfolio.Metadata != nil {
+		panel.WalletType = req.Portfolio.Metadata.WalletType
+		panel.WalletName = req.Portfolio.Metadata.WalletName
+	}
+
+	byCoin := make(map[string]float64)
+	for _, item := range req.Summary.Items {
+		byCoin[item.Name] += item.Value.BaseAmount
+	}
+	for name, value := range byCoin {
+		coin := models.AllocationItem{Name: name, Value: roundPence(value)}
+		if panel.TotalValue > 0 {
+			coin.Percentage = roundPct(value / panel.TotalValue * 100)
+		}
+		panel.Coins = append(panel.Coins, coin)
+	}
+	sort.Slice(panel.Coins, func(i, j int) bool {
+		if panel.Coins[i].Value != panel.Coins[j].Value {
+			return panel.Coins[i].Value > panel.Coins[j].Value
+		}
+		return panel.Coins[i].Name < panel.Coins[j].Name
+	})
+
+	return panel, nil
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+const (
+	priceAlertJob        = "price_alerts"
+	priceAlertInterval   = 5 * time.Minute
+	priceAlertBatchSize  = 50
+	maxDailyMoveAlertPct = 100
+)
+
+// IsValidPriceAlert checks an alert's condition and threshold, returning an error message
+// if they are invalid
+func IsValidPriceAlert(condition string, threshold float64) string {
+	switch condition {
+	case models.PriceAlertAbove, models.PriceAlertBelow:
+		if threshold <= 0 {
+			return "Threshold must be a positive price"
+		}
+	case models.PriceAlertDailyMove:
+		if threshold <= 0 || threshold > maxDailyMoveAlertPct {
+			return "Threshold must be a percentage between 0 and 100"
+		}
+	default:
+		return "Invalid condition (use ABOVE, BELOW or DAILY_MOVE)"
+	}
+	return ""
+}
+
+// PriceAlertService evaluates active price alerts against live quotes every few minutes
+// and sends triggered ones to the user's webhooks if they have price alert notifications
+// switched on. Level alerts switch off once triggered; daily move alerts trigger at most
+// once per day.
+type PriceAlertService struct {
+	alertRepo *repository.PriceAlertRepository
+	userRepo  *repository.UserRepository
+	yahoo     *YahooService
+	calendar  *MarketCalendar
+	webhooks  *WebhookService
+	jobs      *JobManager
+	logger    *slog.Logger
+}
+
+func NewPriceAlertService(
+	alertRepo *repository.PriceAlertRepository,
+	userRepo *repository.UserRepository,
+	yahoo *YahooService,
+	calendar *MarketCalendar,
+	webhooks *WebhookService,
+	jobs *JobManager,
+	logger *slog.Logger,
+) *PriceAlertService {
+	return &PriceAlertService{
+		alertRepo: alertRepo,
+		userRepo:  userRepo,
+		yahoo:     yahoo,
+		calendar:  calendar,
+		webhooks:  webhooks,
+		jobs:      jobs,
+		logger:    logger,
+	}
+}
+
+// Run evaluates the alerts until ctx is cancelled
+func (s *PriceAlertService) Run(ctx context.Context) {
+	ticker := time.NewTicker(priceAlertInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.jobs.Run(priceAlertJob, s.evaluate); errors.Is(err, ErrShuttingDown) {
+				s.logger.Info("skipping price alerts during shutdown")
+			}
+		}
+	}
+}
+
+// evaluate checks every active alert on an asset whose market trades today
+func (s *PriceAlertService) evaluate(ctx context.Context) error {
+	alerts, err := s.alertRepo.GetActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	bySymbol := make(map[string][]*models.PriceAlert)
+	var symbols []string
+	for _, alert := range alerts {
+		if market, ok := s.calendar.MarketFor(alert.Asset.Exchange); ok && !market.IsTradingDay(now) {
+			continue
+		}
+		symbol := alert.Asset.Symbol
+		if _, seen := bySymbol[symbol]; !seen {
+			symbols = append(symbols, symbol)
+		}
+		bySymbol[symbol] = append(bySymbol[symbol], alert)
+	}
+
+	notify := make(map[uuid.UUID]bool)
+	triggered := 0
+	for start := 0; start < len(symbols); start += priceAlertBatchSize {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		end := min(start+priceAlertBatchSize, len(symbols))
+		quotes, err := s.yahoo.GetQuotes(ctx, symbols[start:end])
+		if err != nil {
+			s.logger.Warn("failed to fetch quotes for price alerts", "error", err)
+			continue
+		}
+
+		for i := range quotes {
+			quote := &quotes[i]
+			for _, alert := range bySymbol[quote.Symbol] {
+				if !alertTriggered(alert, quote, now) {
+					continue
+				}
+				deactivate := alert.Condition != models.PriceAlertDailyMove
+				if err := s.alertRepo.MarkTriggered(ctx, alert.ID, quote.Price, now, deactivate); err != nil {
+					s.logger.Error("failed to record price alert", "alert_id", alert.ID, "error", err)
+					continue
+				}
+				triggered++
+
+				alert.LastTriggeredAt = &now
+				alert.LastTriggeredPrice = &quote.Price
+				alert.IsActive = !deactivate
+				s.deliver(ctx, notify, alert, quote)
+			}
+		}
+	}
+
+	if triggered > 0 {
+		s.logger.Info("price alerts triggered", "count", triggered)
+	}
+	return nil
+}
+
+// alertTriggered reports whether the quote meets the alert's condition
+func alertTriggered(alert *models.PriceAlert, quote *AssetDetails, now time.Time) bool {
+	if quote.Price <= 0 {
+		return false
+	}
+	switch alert.Condition {
+	case models.PriceAlertAbove:
+		return quote.Price >= alert.Threshold
+	case models.PriceAlertBelow:
+		return quote.Price <= alert.Threshold
+	case models.PriceAlertDailyMove:
+		if last := alert.LastTriggeredAt; last != nil && startOfDay(*last).Equal(startOfDay(now)) {
+			return false
+		}
+		return math.Abs(quote.ChangePct) >= alert.Threshold
+	}
+	return false
+}
+
+// deliver sends the triggered alert to the user's webhooks if they want price alert
+// notifications. notify caches each user's preference for the run.
+func (s *PriceAlertService) deliver(ctx context.Context, notify map[uuid.UUID]bool, alert *models.PriceAlert, quote *AssetDetails) {
+	wants, ok := notify[alert.UserID]
+	if !ok {
+		user, err := s.userRepo.GetByID(ctx, alert.UserID)
+		if err != nil {
+			s.logger.Warn("failed to look up user for price alert", "user_id", alert.UserID, "error", err)
+			return
+		}
+		wants = user.NotifyPriceAlerts
+		notify[alert.UserID] = wants
+	}
+	if !wants {
+		return
+	}
+
+	s.webhooks.Emit(ctx, alert.UserID, models.WebhookEventPriceAlert, models.PriceAlertTriggered{
+		Alert:     alert,
+		Symbol:    quote.Symbol,
+		Price:     quote.Price,
+		ChangePct: quote.ChangePct,
+		Currency:  quote.Currency,
+	})
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+const (
+	priceRefreshJob       = "price_refresh"
+	priceRefreshBatchSize = 50
+)
+
+// priceRefreshCheckpoint records the symbols still to be refreshed when a run is interrupted
+type priceRefreshCheckpoint struct {
+	Remaining []string `json:"remaining"`
+}
+
+// PriceRefresher periodically refreshes the prices of all known assets.
+// The interval can be changed at runtime; an interval of zero pauses refreshing.
+type PriceRefresher struct {
+	assetRepo      *repository.AssetRepository
+	checkpointRepo *repository.JobCheckpointRepository
+	yahooService   *YahooService
+	calendar       *MarketCalendar
+	dashboardCache *DashboardCache
+	jobs           *JobManager
+	logger         *slog.Logger
+	intervalCh     chan time.Duration
+}
+
+func NewPriceRefresher(
+	assetRepo *repository.AssetRepository,
+	checkpointRepo *repository.JobCheckpointRepository,
+	yahooService *YahooService,
+	calendar *MarketCalendar,
+	dashboardCache *DashboardCache,
+	jobs *JobManager,
+	logger *slog.Logger,
+) *PriceRefresher {
+	return &PriceRefresher{
+		assetRepo:      assetRepo,
+		checkpointRepo: checkpointRepo,
+		yahooService:   yahooService,
+		calendar:       calendar,
+		dashboardCache: dashboardCache,
+		jobs:           jobs,
+		logger:         logger,
+		intervalCh:     make(chan time.Duration, 1),
+	}
+}
+
+// SetInterval changes how often prices are refreshed
+func (p *PriceRefresher) SetInterval(interval time.Duration) {
+	// Only the latest interval matters, so drop any pending update
+	select {
+	case <-p.intervalCh:
+	default:
+	}
+	p.intervalCh <- interval
+}
+
+// Run refreshes prices until the context is cancelled. A run interrupted by a previous
+// shutdown is resumed as soon as refreshing is enabled.
+func (p *PriceRefresher) Run(ctx context.Context) {
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+
+	stop := func() {
+		if ticker != nil {
+			ticker.Stop()
+			ticker, tick = nil, nil
+		}
+	}
+	defer stop()
+
+	_, err := p.checkpointRepo.Get(ctx, priceRefreshJob)
+	resume := err == nil
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case interval := <-p.intervalCh:
+			stop()
+			if interval > 0 {
+				ticker = time.NewTicker(interval)
+				tick = ticker.C
+			}
+			p.logger.Info("price refresh interval updated", "interval", interval.String())
+			if resume && interval > 0 {
+				resume = false
+				p.runJob()
+			}
+		case <-tick:
+			p.runJob()
+		}
+	}
+}
+
+func (p *PriceRefresher) runJob() {
+	if err := p.jobs.Run(priceRefreshJob, p.refresh); errors.Is(err, ErrShuttingDown) {
+		p.logger.Info("skipping price refresh during shutdown")
+	}
+}
+
+// refresh updates prices in batches, saving the remaining symbols if ctx is cancelled part way
+func (p *PriceRefresher) refresh(ctx context.Context) error {
+	symbols, resumed, err := p.pendingSymbols(ctx)
+	if err != nil {
+		return err
+	}
+	if len(symbols) == 0 {
+		return nil
+	}
+	if resumed {
+		p.logger.Info("resuming interrupted price refresh", "remaining", len(symbols))
+	}
+
+	// Dashboards are valued at the stored prices, so drop them once any are refreshed,
+	// even if the run is interrupted
+	refreshed := false
+	defer func() {
+		if refreshed {
+			p.dashboardCache.InvalidateAll(context.WithoutCancel(ctx))
+		}
+	}()
+
+	for start := 0; start < len(symbols); start += priceRefreshBatchSize {
+		if ctx.Err() != nil {
+			return p.checkpoint(symbols[start:])
+		}
+
+		end := min(start+priceRefreshBatchSize, len(symbols))
+		if err := p.yahooService.RefreshPrices(ctx, symbols[start:end]); err != nil {
+			if ctx.Err() != nil {
+				return p.checkpoint(symbols[start:])
+			}
+			return err
+		}
+		refreshed = true
+	}
+
+	if err := p.checkpointRepo.Delete(ctx, priceRefreshJob); err != nil {
+		p.logger.Error("failed to clear price refresh checkpoint", "error", err)
+	}
+	p.logger.Info("scheduled price refresh completed", "count", len(symbols))
+
+	// Keep intraday sparklines warm for held symbols
+	held, err := p.assetRepo.GetAllHeldSymbols(ctx)
+	if err != nil {
+		p.logger.Error("failed to list held symbols for sparklines", "error", err)
+		return nil
+	}
+	p.yahooService.WarmSparklines(ctx, held)
+	return nil
+}
+
+// pendingSymbols returns the symbols left over from an interrupted run, or every known asset
+// whose market trades today. Assets on exchanges without a calendar are always refreshed.
+func (p *PriceRefresher) pendingSymbols(ctx context.Context) ([]string, bool, error) {
+	state, err := p.checkpointRepo.Get(ctx, priceRefreshJob)
+	if err == nil {
+		var cp priceRefreshCheckpoint
+		if err := json.Unmarshal(state, &cp); err == nil && len(cp.Remaining) > 0 {
+			return cp.Remaining, true, nil
+		}
+	} else if !errors.Is(err, repository.ErrCheckpointNotFound) {
+		return nil, false, err
+	}
+
+	assets, err := p.assetRepo.GetAll(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := time.Now()
+	symbols := make([]string, 0, len(assets))
+	for _, a := range assets {
+		if market, ok := p.calendar.MarketFor(a.Exchange); ok && !market.IsTradingDay(now) {
+			continue
+		}
+		symbols = append(symbols, a.Symbol)
+	}
+	return symbols, false, nil
+}
+
+func (p *PriceRefresher) checkpoint(remaining []string) error {
+	state, err := json.Marshal(priceRefreshCheckpoint{Remaining: remaining})
+	if err != nil {
+		return err
+	}
+
+	// The job context has been cancelled, so save with a short independent deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := p.checkpointRepo.Save(ctx, priceRefreshJob, state); err != nil {
+		return err
+	}
+	p.logger.Info("price refresh checkpointed", "remaining", len(remaining))
+	return nil
+}
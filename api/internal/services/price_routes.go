@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+// PriceRouteService keeps the price source routing table in the database and applies
+// it to the quote service
+type PriceRouteService struct {
+	repo   *repository.PriceSourceRouteRepository
+	yahoo  *YahooService
+	logger *slog.Logger
+}
+
+func NewPriceRouteService(repo *repository.PriceSourceRouteRepository, yahoo *YahooService, logger *slog.Logger) *PriceRouteService {
+	return &PriceRouteService{
+		repo:   repo,
+		yahoo:  yahoo,
+		logger: logger,
+	}
+}
+
+// List returns the stored routes
+func (s *PriceRouteService) List(ctx context.Context) ([]*models.PriceSourceRoute, error) {
+	return s.repo.GetAll(ctx)
+}
+
+// Save creates or replaces a route and applies it
+func (s *PriceRouteService) Save(ctx context.Context, route *models.PriceSourceRoute) error {
+	if err := s.repo.Save(ctx, route); err != nil {
+		return err
+	}
+	s.reload(ctx)
+	return nil
+}
+
+// Delete removes a route and applies the change
+func (s *PriceRouteService) Delete(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.reload(ctx)
+	return nil
+}
+
+// Reload fetches the routes from the database and applies them
+func (s *PriceRouteService) Reload(ctx context.Context) error {
+	routes, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	s.yahoo.SetPriceRoutes(routes)
+	return nil
+}
+
+// reload applies the stored routes, leaving it to Watch to retry if that fails
+func (s *PriceRouteService) reload(ctx context.Context) {
+	if err := s.Reload(ctx); err != nil {
+		s.logger.Error("failed to reload price source routes", "error", err)
+	}
+}
+
+// Watch periodically reloads the routes so changes made on another instance are picked up
+func (s *PriceRouteService) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Reload(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error("failed to reload price source routes", "error", err)
+			}
+		}
+	}
+}
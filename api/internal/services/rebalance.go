@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+// Allocation target kinds
+const (
+	TargetByAsset     = "ASSET"
+	TargetByAssetType = "ASSET_TYPE"
+)
+
+// RebalanceService compares portfolios with their target allocation
+type RebalanceService struct {
+	targetRepo  *repository.AllocationTargetRepository
+	holdingRepo *repository.HoldingRepository
+	userRepo    *repository.UserRepository
+	currency    *CurrencyService
+}
+
+func NewRebalanceService(
+	targetRepo *repository.AllocationTargetRepository,
+	holdingRepo *repository.HoldingRepository,
+	userRepo *repository.UserRepository,
+	currency *CurrencyService,
+) *RebalanceService {
+	return &RebalanceService{
+		targetRepo:  targetRepo,
+		holdingRepo: holdingRepo,
+		userRepo:    userRepo,
+		currency:    currency,
+	}
+}
+
+// TargetKind returns whether the targets are by asset or by asset type, or "" if there are none
+func TargetKind(targets []*models.AllocationTarget) string {
+	if len(targets) == 0 {
+		return ""
+	}
+	if targets[0].AssetID != nil {
+		return TargetByAsset
+	}
+	return TargetByAssetType
+}
+
+// rebalanceEntry accumulates one line of the report
+type rebalanceEntry struct {
+	line      models.RebalanceLine
+	basePrice float64
+}
+
+// Report values the portfolio's holdings in the user's base currency at their last price
+// (or average cost if there is none) and works out the trades that would bring each asset
+// or asset type back to its target weight. Holdings without a target have a target of zero.
+func (s *RebalanceService) Report(ctx context.Context, userID uuid.UUID, portfolio *models.Portfolio) (*models.RebalanceReport, error) {
+	targets, err := s.targetRepo.GetByPortfolioID(ctx, portfolio.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Compare(ctx, userID, portfolio, targets)
+}
+
+// Compare builds the same report against the given targets instead of the portfolio's
+// own, e.g. to see how far a portfolio is from a model portfolio. Asset targets should
+// have their asset joined for its symbol and price.
+func (s *RebalanceService) Compare(ctx context.Context, userID uuid.UUID, portfolio *models.Portfolio, targets []*models.AllocationTarget) (*models.RebalanceReport, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	conv := s.currency.NewConverter(user.BaseCurrency)
+
+	holdings, err := s.holdingRepo.GetByPortfolioID(ctx, portfolio.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.RebalanceReport{
+		PortfolioID: portfolio.ID,
+		Currency:    conv.Base(),
+		TargetBy:    TargetKind(targets),
+		Lines:       []models.RebalanceLine{},
+	}
+
+	byAsset := report.TargetBy == TargetByAsset
+	entries := make(map[string]*rebalanceEntry)
+	entryFor := func(asset *models.Asset, assetID uuid.UUID, assetType string) *rebalanceEntry {
+		key := assetType
+		if byAsset {
+			key = assetID.String()
+		}
+		if e, ok := entries[key]; ok {
+			return e
+		}
+		e := &rebalanceEntry{}
+		if byAsset {
+			id := assetID
+			e.line.AssetID = &id
+			if asset != nil {
+				e.line.Name = asset.Symbol
+			}
+		} else {
+			t := assetType
+			e.line.AssetType = &t
+			e.line.Name = assetType
+		}
+		entries[key] = e
+		return e
+	}
+
+	for _, h := range holdings {
+		if h.Asset == nil {
+			continue
+		}
+		price := h.AverageCost
+		if h.Asset.LastPrice != nil {
+			price = *h.Asset.LastPrice
+		}
+		value := conv.Convert(ctx, h.Quantity*price, h.Asset.Currency)
+		unit := conv.Convert(ctx, price, h.Asset.Currency)
+		if value.RateMissing {
+			report.RateMissing = true
+		}
+
+		e := entryFor(h.Asset, h.AssetID, h.Asset.AssetType)
+		e.line.CurrentValue += value.BaseAmount
+		e.basePrice = unit.BaseAmount
+		report.TotalValue += value.BaseAmount
+	}
+
+	for _, t := range targets {
+		var e *rebalanceEntry
+		if t.AssetID != nil {
+			e = entryFor(t.Asset, *t.AssetID, "")
+			if e.basePrice == 0 && t.Asset != nil && t.Asset.LastPrice != nil {
+				e.basePrice = conv.Convert(ctx, *t.Asset.LastPrice, t.Asset.Currency).BaseAmount
+			}
+		} else if t.AssetType != nil {
+			e = entryFor(nil, uuid.Nil, *t.AssetType)
+		} else {
+			continue
+		}
+		e.line.TargetPct += t.TargetPct
+	}
+
+	report.TotalValue = roundPence(report.TotalValue)
+	if len(targets) == 0 {
+		return report, nil
+	}
+
+	for _, e := range entries {
+		line := e.line
+		line.CurrentValue = roundPence(line.CurrentValue)
+		if report.TotalValue > 0 {
+			line.CurrentPct = roundPct(line.CurrentValue / report.TotalValue * 100)
+		}
+		line.DriftPct = roundPct(line.CurrentPct - line.TargetPct)
+		line.TargetValue = roundPence(report.TotalValue * line.TargetPct / 100)
+		line.TradeAmount = roundPence(line.TargetValue - line.CurrentValue)
+
+		switch {
+		case line.TradeAmount > 0:
+			line.Action = models.RebalanceBuy
+			report.TotalBuy += line.TradeAmount
+		case line.TradeAmount < 0:
+			line.Action = models.RebalanceSell
+			report.TotalSell -= line.TradeAmount
+		default:
+			line.Action = models.RebalanceHold
+		}
+		if byAsset && e.basePrice > 0 && line.TradeAmount != 0 {
+			units := math.Round(line.TradeAmount/e.basePrice*10000) / 10000
+			line.TradeUnits = &units
+		}
+
+		if math.Abs(line.DriftPct) > math.Abs(report.MaxDriftPct) {
+			report.MaxDriftPct = line.DriftPct
+		}
+		report.Lines = append(report.Lines, line)
+	}
+
+	report.TotalBuy = roundPence(report.TotalBuy)
+	report.TotalSell = roundPence(report.TotalSell)
+
+	// Largest drift first
+	sort.Slice(report.Lines, func(i, j int) bool {
+		di, dj := math.Abs(report.Lines[i].DriftPct), math.Abs(report.Lines[j].DriftPct)
+		if di != dj {
+			return di > dj
+		}
+		return report.Lines[i].Name < report.Lines[j].Name
+	})
+
+	return report, nil
+}
+
+func roundPct(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+// reminderTokenPattern matches "@2025-05-01 do X"; the text runs to the end of the line
+// or the next token
+var reminderTokenPattern = regexp.MustCompile(`@(\d{4}-\d{2}-\d{2})[ \t]+([^@\r\n]+)`)
+
+const maxReminderText = 500
+
+// ReminderToken is one reminder found in a notes field
+type ReminderToken struct {
+	DueDate time.Time
+	Text    string
+}
+
+// ParseReminderTokens extracts the reminder tokens from the given notes. Tokens with
+// invalid dates are ignored, and duplicates are returned once.
+func ParseReminderTokens(notes ...string) []ReminderToken {
+	var tokens []ReminderToken
+	seen := make(map[string]bool)
+
+	for _, text := range notes {
+		for _, match := range reminderTokenPattern.FindAllStringSubmatch(text, -1) {
+			due, err := time.Parse("2006-01-02", match[1])
+			if err != nil {
+				continue
+			}
+			body := strings.TrimSpace(match[2])
+			if body == "" {
+				continue
+			}
+			body = truncate(body, maxReminderText)
+
+			key := reminderKey(due, body)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			tokens = append(tokens, ReminderToken{DueDate: due, Text: body})
+		}
+	}
+
+	return tokens
+}
+
+func reminderKey(due time.Time, text string) string {
+	return due.Format("2006-01-02") + " " + text
+}
+
+const (
+	reminderEscalationJob      = "reminder_escalations"
+	reminderEscalationInterval = time.Hour
+)
+
+// ReminderService keeps reminders in step with the notes they were parsed from and
+// escalates HIGH and URGENT reminders left uncompleted
+type ReminderService struct {
+	repo     *repository.ReminderRepository
+	webhooks *WebhookService
+	jobs     *JobManager
+	logger   *slog.Logger
+}
+
+func NewReminderService(repo *repository.ReminderRepository, webhooks *WebhookService, jobs *JobManager, logger *slog.Logger) *ReminderService {
+	return &ReminderService{repo: repo, webhooks: webhooks, jobs: jobs, logger: logger}
+}
+
+// Sync reconciles a record's reminders with the tokens in its notes: new tokens become
+// reminders and reminders whose token was deleted are removed. Unchanged reminders keep
+// their completion state. Failures are logged rather than returned so saving the record
+// itself never fails because of a reminder.
+func (s *ReminderService) Sync(ctx context.Context, userID uuid.UUID, sourceType string, sourceID uuid.UUID, notes ...string) {
+	if err := s.sync(ctx, userID, sourceType, sourceID, notes); err != nil {
+		s.logger.Warn("failed to sync reminders", "source_type", sourceType, "source_id", sourceID, "error", err)
+	}
+}
+
+func (s *ReminderService) sync(ctx context.Context, userID uuid.UUID, sourceType string, sourceID uuid.UUID, notes []string) error {
+	existing, err := s.repo.GetBySource(ctx, sourceType, sourceID)
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]ReminderToken)
+	for _, token := range ParseReminderTokens(notes...) {
+		wanted[reminderKey(token.DueDate, token.Text)] = token
+	}
+
+	for _, reminder := range existing {
+		key := reminderKey(reminder.DueDate, reminder.Text)
+		if _, ok := wanted[key]; ok {
+			delete(wanted, key)
+			continue
+		}
+		if err := s.repo.Delete(ctx, reminder.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, token := range wanted {
+		reminder := &models.Reminder{
+			UserID:     userID,
+			SourceType: sourceType,
+			SourceID:   sourceID,
+			DueDate:    token.DueDate,
+			Text:       token.Text,
+		}
+		if err := s.repo.Create(ctx, reminder); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveSource deletes the reminders of a record that has been deleted
+func (s *ReminderService) RemoveSource(ctx context.Context, sourceType string, sourceID uuid.UUID) {
+	if err := s.repo.DeleteBySource(ctx, sourceType, sourceID); err != nil {
+		s.logger.Warn("failed to remove reminders", "source_type", sourceType, "source_id", sourceID, "error", err)
+	}
+}
+
+// Run escalates overdue reminders every hour until ctx is cancelled
+func (s *ReminderService) Run(ctx context.Context) {
+	ticker := time.NewTicker(reminderEscalationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.jobs.Run(reminderEscalationJob, s.escalate); errors.Is(err, ErrShuttingDown) {
+				s.logger.Info("skipping reminder escalations during shutdown")
+			}
+		}
+	}
+}
+
+// escalate moves each reminder due an escalation to its next channel and URGENT
+// priority, and sends a reminder.escalated event naming the channel so the user's
+// notifier can deliver it there
+func (s *ReminderService) escalate(ctx context.Context) error {
+	reminders, err := s.repo.GetEscalationDue(ctx, startOfDay(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	escalated := 0
+	for _, reminder := range reminders {
+		if ctx.Err() != nil {
+			return nil
+		}
+		ok, err := s.repo.Escalate(ctx, reminder)
+		if err != nil {
+			s.logger.Warn("failed to escalate reminder", "reminder_id", reminder.ID, "error", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		s.webhooks.Emit(ctx, reminder.UserID, models.WebhookEventReminderEscalated, reminder)
+		escalated++
+	}
+
+	if escalated > 0 {
+		s.logger.Info("reminders escalated", "count", escalated)
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/config"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+var (
+	ErrUnknownSetting = errors.New("unknown setting")
+	ErrInvalidSetting = errors.New("invalid setting value")
+)
+
+const featureFlagsKey = "feature_flags"
+
+// SettingsService holds the current runtime settings, layering database overrides
+// on top of the env defaults and notifying listeners when values change
+type SettingsService struct {
+	repo      *repository.SettingsRepository
+	defaults  config.RuntimeSettings
+	logger    *slog.Logger
+	updateMu  sync.Mutex
+	mu        sync.RWMutex
+	current   config.RuntimeSettings
+	overrides map[string]json.RawMessage
+	listeners []func(config.RuntimeSettings)
+}
+
+func NewSettingsService(repo *repository.SettingsRepository, defaults config.RuntimeSettings, logger *slog.Logger) *SettingsService {
+	return &SettingsService{
+		repo:      repo,
+		defaults:  defaults.Clone(),
+		logger:    logger,
+		current:   defaults.Clone(),
+		overrides: make(map[string]json.RawMessage),
+	}
+}
+
+// OnChange registers a listener that is called with the new settings whenever they change
+func (s *SettingsService) OnChange(fn func(config.RuntimeSettings)) {
+	s.mu.Lock()
+	s.listeners = append(s.listeners, fn)
+	current := s.current.Clone()
+	s.mu.Unlock()
+
+	fn(current)
+}
+
+// Get returns a copy of the current settings
+func (s *SettingsService) Get() config.RuntimeSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.Clone()
+}
+
+// Defaults returns a copy of the env-provided defaults
+func (s *SettingsService) Defaults() config.RuntimeSettings {
+	return s.defaults.Clone()
+}
+
+// Overrides returns the keys currently overridden in the database
+func (s *SettingsService) Overrides() map[string]json.RawMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	overrides := make(map[string]json.RawMessage, len(s.overrides))
+	for k, v := range s.overrides {
+		overrides[k] = v
+	}
+	return overrides
+}
+
+// FeatureEnabled reports whether the named feature flag is on
+func (s *SettingsService) FeatureEnabled(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.FeatureFlags[name]
+}
+
+// Reload fetches overrides from the database and applies them
+func (s *SettingsService) Reload(ctx context.Context) error {
+	overrides, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	settings, err := s.merge(overrides)
+	if err != nil {
+		return err
+	}
+
+	s.apply(settings, overrides)
+	return nil
+}
+
+// Watch periodically reloads settings so changes made on another instance are picked up
+func (s *SettingsService) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Reload(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error("failed to reload runtime settings", "error", err)
+			}
+		}
+	}
+}
+
+// Update persists the given overrides and applies them immediately.
+// Feature flags are merged into the existing flags rather than replacing them.
+func (s *SettingsService) Update(ctx context.Context, patch map[string]json.RawMessage, updatedBy uuid.UUID) (config.RuntimeSettings, error) {
+	known := settingKeys(s.defaults)
+
+	// Updates on this instance run one at a time so each reload sees the one before it
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+
+	s.mu.RLock()
+	overrides := make(map[string]json.RawMessage, len(s.overrides)+len(patch))
+	for k, v := range s.overrides {
+		overrides[k] = v
+	}
+	s.mu.RUnlock()
+
+	for key, value := range patch {
+		if !known[key] {
+			return config.RuntimeSettings{}, fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+		}
+		if key == featureFlagsKey {
+			flags := make(map[string]bool)
+			if existing, ok := overrides[key]; ok {
+				_ = json.Unmarshal(existing, &flags)
+			}
+			var changes map[string]bool
+			if err := json.Unmarshal(value, &changes); err != nil {
+				return config.RuntimeSettings{}, fmt.Errorf("%w: feature_flags: %v", ErrInvalidSetting, err)
+			}
+			for flag, enabled := range changes {
+				flags[flag] = enabled
+			}
+			value, _ = json.Marshal(flags)
+		}
+		overrides[key] = value
+	}
+
+	if _, err := s.merge(overrides); err != nil {
+		return config.RuntimeSettings{}, err
+	}
+
+	// Flags are merged in the database too, in case another instance changed them since
+	// they were last loaded
+	if err := s.repo.SaveAll(ctx, patch, map[string]bool{featureFlagsKey: true}, updatedBy); err != nil {
+		return config.RuntimeSettings{}, err
+	}
+
+	if err := s.Reload(ctx); err != nil {
+		return config.RuntimeSettings{}, err
+	}
+	return s.Get(), nil
+}
+
+// Reset removes an override so the env default applies again
+func (s *SettingsService) Reset(ctx context.Context, key string) (config.RuntimeSettings, error) {
+	if !settingKeys(s.defaults)[key] {
+		return config.RuntimeSettings{}, fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+	}
+
+	if err := s.repo.Delete(ctx, key); err != nil && !errors.Is(err, repository.ErrSettingNotFound) {
+		return config.RuntimeSettings{}, err
+	}
+
+	if err := s.Reload(ctx); err != nil {
+		return config.RuntimeSettings{}, err
+	}
+	return s.Get(), nil
+}
+
+// merge layers the overrides on top of the defaults and validates the result
+func (s *SettingsService) merge(overrides map[string]json.RawMessage) (config.RuntimeSettings, error) {
+	fields := make(map[string]json.RawMessage)
+	data, err := json.Marshal(s.defaults)
+	if err != nil {
+		return config.RuntimeSettings{}, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return config.RuntimeSettings{}, err
+	}
+
+	for key, value := range overrides {
+		if _, known := fields[key]; !known {
+			continue
+		}
+		if key == featureFlagsKey {
+			flags := s.defaults.Clone().FeatureFlags
+			var overridden map[string]bool
+			if err := json.Unmarshal(value, &overridden); err != nil {
+				return config.RuntimeSettings{}, fmt.Errorf("%w: feature_flags: %v", ErrInvalidSetting, err)
+			}
+			for flag, enabled := range overridden {
+				flags[flag] = enabled
+			}
+			value, _ = json.Marshal(flags)
+		}
+		fields[key] = value
+	}
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return config.RuntimeSettings{}, err
+	}
+
+	var settings config.RuntimeSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return config.RuntimeSettings{}, fmt.Errorf("%w: %v", ErrInvalidSetting, err)
+	}
+	if settings.FeatureFlags == nil {
+		settings.FeatureFlags = make(map[string]bool)
+	}
+	if err := settings.Validate(); err != nil {
+		return config.RuntimeSettings{}, fmt.Errorf("%w: %v", ErrInvalidSetting, err)
+	}
+
+	return settings, nil
+}
+
+// apply swaps in the new settings and notifies listeners if anything changed
+func (s *SettingsService) apply(settings config.RuntimeSettings, overrides map[string]json.RawMessage) {
+	s.mu.Lock()
+	changed := !reflect.DeepEqual(s.current, settings)
+	s.current = settings
+	s.overrides = overrides
+	listeners := append([]func(config.RuntimeSettings){}, s.listeners...)
+	s.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	s.logger.Info("runtime settings applied", "overrides", len(overrides))
+	for _, fn := range listeners {
+		fn(settings.Clone())
+	}
+}
+
+// settingKeys returns the JSON names of all runtime settings
+func settingKeys(defaults config.RuntimeSettings) map[string]bool {
+	fields := make(map[string]json.RawMessage)
+	data, _ := json.Marshal(defaults)
+	_ = json.Unmarshal(data, &fields)
+
+	keys := make(map[string]bool, len(fields))
+	for key := range fields {
+		keys[key] = true
+	}
+	return keys
+}
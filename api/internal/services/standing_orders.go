@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+const (
+	standingOrderJob      = "standing_orders"
+	standingOrderInterval = time.Hour
+	// standingOrderRetryDays is how long a run that failed for want of a price or
+	// exchange rate is retried before it is given up for the month
+	standingOrderRetryDays = 7
+	// MaxStandingOrderPreview caps how many upcoming runs can be previewed
+	MaxStandingOrderPreview = 24
+)
+
+// errRetryRun marks a run that failed for a reason that may clear up by the next tick
+var errRetryRun = errors.New("will retry")
+
+// StandingOrderDate is the first day of the month matching day on or after from
+func StandingOrderDate(day int, from time.Time) time.Time {
+	from = startOfDay(from)
+	date := time.Date(from.Year(), from.Month(), day, 0, 0, 0, 0, time.UTC)
+	if date.Before(from) {
+		date = date.AddDate(0, 1, 0)
+	}
+	return date
+}
+
+// StandingOrderService turns due standing orders into transactions. Buys run on a
+// trading day of the asset's market at that day's price; a run missed while the server
+// was down is made once, not once per missed month.
+type StandingOrderService struct {
+	orderRepo     *repository.StandingOrderRepository
+	txRepo        *repository.TransactionRepository
+	portfolioRepo *repository.PortfolioRepository
+	yahoo         *YahooService
+	fx            *CurrencyService
+	calendar      *MarketCalendar
+	lots          *LotService
+	allowances    *AllowanceService
+	webhooks      *WebhookService
+	cache         *DashboardCache
+	jobs          *JobManager
+	logger        *slog.Logger
+}
+
+func NewStandingOrderService(
+	orderRepo *repository.StandingOrderRepository,
+	txRepo *repository.TransactionRepository,
+	portfolioRepo *repository.PortfolioRepository,
+	yahoo *YahooService,
+	fx *CurrencyService,
+	calendar *MarketCalendar,
+	lots *LotService,
+	allowances *AllowanceService,
+	webhooks *WebhookService,
+	cache *DashboardCache,
+	jobs *JobManager,
+	logger *slog.Logger,
+) *StandingOrderService {
+	return &StandingOrderService{
+		orderRepo:     orderRepo,
+		txRepo:        txRepo,
+		portfolioRepo: portfolioRepo,
+		yahoo:         yahoo,
+		fx:            fx,
+		calendar:      calendar,
+		lots:          lots,
+		allowances:    allowances,
+		webhooks:      webhooks,
+		cache:         cache,
+		jobs:          jobs,
+		logger:        logger,
+	}
+}
+
+// Run executes due standing orders every hour until ctx is cancelled
+func (s *StandingOrderService) Run(ctx context.Context) {
+	ticker := time.NewTicker(standingOrderInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.jobs.Run(standingOrderJob, s.execute); errors.Is(err, ErrShuttingDown) {
+				s.logger.Info("skipping standing orders during shutdown")
+			}
+		}
+	}
+}
+
+// execute runs each due order and records the outcome. Runs that can't get a price or
+// exchange rate stay due and are retried for a week; other failures give up the month.
+func (s *StandingOrderService) execute(ctx context.Context) error {
+	now := time.Now()
+	today := startOfDay(now)
+	orders, err := s.orderRepo.GetDue(ctx, today)
+	if err != nil {
+		return err
+	}
+
+	executed := 0
+	for _, order := range orders {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if order.Asset != nil {
+			if market, ok := s.calendar.MarketFor(order.Asset.Exchange); ok && !market.IsTradingDay(now) {
+				continue
+			}
+		}
+
+		tx, err := s.runOrder(ctx, order, today)
+		order.LastRunAt = &now
+		order.LastTransactionID = nil
+		order.LastError = ""
+		if err != nil {
+			order.LastError = err.Error()
+			s.logger.Warn("standing order failed", "order_id", order.ID, "error", err)
+		} else {
+			order.LastTransactionID = &tx.ID
+			executed++
+		}
+		if err == nil || !errors.Is(err, errRetryRun) || today.After(order.NextRunDate.AddDate(0, 0, standingOrderRetryDays)) {
+			order.NextRunDate = StandingOrderDate(order.DayOfMonth, today.AddDate(0, 0, 1))
+		}
+
+		if err := s.orderRepo.RecordRun(ctx, order); err != nil {
+			s.logger.Error("failed to record standing order run", "order_id", order.ID, "error", err)
+			continue
+		}
+		if tx != nil {
+			s.webhooks.Emit(ctx, order.UserID, models.WebhookEventStandingOrder, models.StandingOrderExecuted{
+				Order:       order,
+				Transaction: tx,
+			})
+		}
+	}
+
+	if executed > 0 {
+		s.logger.Info("standing orders executed", "count", executed)
+	}
+	return nil
+}
+
+// unitPrice is the asset's latest price in the order's currency
+func (s *StandingOrderService) unitPrice(ctx context.Context, order *models.StandingOrder) (float64, error) {
+	price, err := s.yahoo.GetPrice(ctx, order.Asset.Symbol)
+	if err != nil || price <= 0 {
+		return 0, fmt.Errorf("no price for %s: %w", order.Asset.Symbol, errRetryRun)
+	}
+	converted := s.fx.NewConverter(order.Currency).Convert(ctx, price, order.Asset.Currency)
+	if converted.RateMissing {
+		return 0, fmt.Errorf("no %s to %s exchange rate: %w", order.Asset.Currency, order.Currency, errRetryRun)
+	}
+	return price * converted.Rate, nil
+}
+
+// runOrder records the order's transaction for the date, buying as many units as the
+// amount covers to a millionth of a unit
+func (s *StandingOrderService) runOrder(ctx context.Context, order *models.StandingOrder, date time.Time) (*models.Transaction, error) {
+	portfolio, err := s.portfolioRepo.GetByID(ctx, order.PortfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("portfolio unavailable: %w", errRetryRun)
+	}
+
+	note := "Standing order"
+	if order.Note != "" {
+		note += ": " + order.Note
+	}
+	tx := &models.Transaction{
+		PortfolioID:     order.PortfolioID,
+		TransactionType: order.OrderType,
+		TotalAmount:     order.Amount,
+		Currency:        order.Currency,
+		TransactionDate: date,
+		Notes:           note,
+	}
+
+	if order.OrderType == models.StandingOrderBuy {
+		if order.Asset == nil {
+			return nil, errors.New("asset no longer exists")
+		}
+		price, err := s.unitPrice(ctx, order)
+		if err != nil {
+			return nil, err
+		}
+		quantity := math.Floor(order.Amount/price*1e6) / 1e6
+		if quantity <= 0 {
+			return nil, errors.New("amount is too small to buy any units")
+		}
+		tx.AssetID = order.AssetID
+		tx.Quantity = &quantity
+		tx.Price = &price
+		tx.TotalAmount = roundPence(quantity * price)
+	}
+
+	if err := s.fx.ConvertTransaction(ctx, tx, portfolio.Currency); err != nil {
+		return nil, fmt.Errorf("no %s to %s exchange rate: %w", tx.Currency, portfolio.Currency, errRetryRun)
+	}
+
+	if IsContribution(tx.TransactionType) && repository.HasContributionLimit(portfolio.Type) {
+		status, err := s.allowances.Check(ctx, portfolio, tx.TransactionType, tx.PortfolioTotal(), date)
+		if err != nil {
+			return nil, fmt.Errorf("allowance unavailable: %w", errRetryRun)
+		}
+		if status != nil && status.ExceededBy > 0 && portfolio.Metadata != nil && portfolio.Metadata.EnforceAllowance {
+			return nil, fmt.Errorf("would exceed the %s %s allowance by %.2f %s", status.TaxYear, status.Wrapper, status.ExceededBy, portfolio.Currency)
+		}
+	}
+
+	// A buy's holding and transaction are saved together, so a failed run can be
+	// retried without adding the units twice
+	if tx.AssetID != nil {
+		err = s.txRepo.CreateBuy(ctx, tx)
+	} else {
+		err = s.txRepo.Create(ctx, tx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record transaction: %w", errRetryRun)
+	}
+	s.cache.Invalidate(ctx, order.UserID)
+
+	if tx.AssetID != nil {
+		s.lots.Sync(ctx, tx.PortfolioID, *tx.AssetID)
+	}
+	if IsContribution(tx.TransactionType) {
+		s.allowances.Sync(ctx, portfolio)
+	}
+	if _, err := s.allowances.AddTaxRelief(ctx, portfolio, tx); err != nil {
+		s.logger.Error("failed to add tax relief", "transaction_id", tx.ID, "error", err)
+	}
+
+	return tx, nil
+}
+
+// Preview lists the order's next runs, up to count and stopping at its end date. Buys
+// are estimated at the latest price, and left without an estimate if there is none.
+func (s *StandingOrderService) Preview(ctx context.Context, order *models.StandingOrder, count int) []models.StandingOrderRun {
+	var price float64
+	if order.OrderType == models.StandingOrderBuy && order.Asset != nil {
+		price, _ = s.unitPrice(ctx, order)
+	}
+
+	runs := []models.StandingOrderRun{}
+	date := order.NextRunDate
+	for len(runs) < count && (order.EndDate == nil || !date.After(*order.EndDate)) {
+		run := models.StandingOrderRun{Date: date.Format("2006-01-02"), Amount: order.Amount}
+		if price > 0 {
+			quantity := math.Floor(order.Amount/price*1e6) / 1e6
+			unitPrice := price
+			run.Price = &unitPrice
+			run.Quantity = &quantity
+			run.Amount = roundPence(quantity * price)
+		}
+		runs = append(runs, run)
+		date = StandingOrderDate(order.DayOfMonth, date.AddDate(0, 0, 1))
+	}
+
+	return runs
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// taskTTL is how long a task's progress can be polled after it was last updated
+const taskTTL = 24 * time.Hour
+
+var (
+	ErrTaskNotFound = errors.New("task not found")
+)
+
+// TaskFunc does the work of a task, reporting progress as it goes. The value it returns
+// is stored as the task's result.
+type TaskFunc func(ctx context.Context, progress *TaskProgress) (interface{}, error)
+
+// TaskService runs long operations in the background and keeps their progress in Redis
+// so clients can poll it instead of waiting on a blocking request
+type TaskService struct {
+	redis  redis.UniversalClient
+	jobs   *JobManager
+	logger *slog.Logger
+}
+
+func NewTaskService(redisClient redis.UniversalClient, jobs *JobManager, logger *slog.Logger) *TaskService {
+	return &TaskService{redis: redisClient, jobs: jobs, logger: logger}
+}
+
+func taskKey(id uuid.UUID) string {
+	return "task:" + id.String()
+}
+
+// Start records a new task for the user and runs fn in the background. Tasks are drained
+// like other jobs on shutdown; a task cut short is marked as failed.
+func (s *TaskService) Start(ctx context.Context, userID uuid.UUID, kind string, fn TaskFunc) (*models.Task, error) {
+	now := time.Now().UTC()
+	task := &models.Task{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Kind:      kind,
+		Status:    models.TaskStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.save(ctx, task); err != nil {
+		return nil, err
+	}
+
+	progress := &TaskProgress{service: s, task: task}
+	err := s.jobs.Go("task:"+kind, func(jobCtx context.Context) error {
+		progress.update(jobCtx, func(t *models.Task) { t.Status = models.TaskStatusRunning })
+		// Mark a panicking task as failed, then let the job manager recover and log it
+		defer func() {
+			if r := recover(); r != nil {
+				progress.finish(fmt.Errorf("task panicked: %v", r), nil)
+				panic(r)
+			}
+		}()
+
+		result, err := fn(jobCtx, progress)
+		progress.finish(err, result)
+		return err
+	})
+	if err != nil {
+		progress.finish(err, nil)
+		return nil, err
+	}
+
+	return progress.snapshot(), nil
+}
+
+// Get returns a task's current progress
+func (s *TaskService) Get(ctx context.Context, id uuid.UUID) (*models.Task, error) {
+	data, err := s.redis.Get(ctx, taskKey(id)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+
+	var stored struct {
+		models.Task
+		UserID uuid.UUID `json:"user_id"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	task := stored.Task
+	task.UserID = stored.UserID
+	return &task, nil
+}
+
+func (s *TaskService) save(ctx context.Context, task *models.Task) error {
+	// UserID is hidden from API responses but needed for ownership checks
+	data, err := json.Marshal(struct {
+		*models.Task
+		UserID uuid.UUID `json:"user_id"`
+	}{task, task.UserID})
+	if err != nil {
+		return err
+	}
+	return s.redis.Set(ctx, taskKey(task.ID), data, taskTTL).Err()
+}
+
+// TaskProgress is handed to a running task to report its progress
+type TaskProgress struct {
+	service *TaskService
+	mu      sync.Mutex
+	task    *models.Task
+}
+
+// Step records the current step, e.g. Step(ctx, "Refreshing prices", 50, 200)
+func (p *TaskProgress) Step(ctx context.Context, description string, current, total int) {
+	p.update(ctx, func(t *models.Task) {
+		t.Step = description
+		t.Current = current
+		t.Total = total
+	})
+}
+
+// TaskID returns the ID of the task being run
+func (p *TaskProgress) TaskID() uuid.UUID {
+	return p.task.ID
+}
+
+func (p *TaskProgress) finish(err error, result interface{}) {
+	// Record the outcome even if the job's context was cancelled by shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p.update(ctx, func(t *models.Task) {
+		now := time.Now().UTC()
+		t.FinishedAt = &now
+		if err != nil {
+			t.Status = models.TaskStatusFailed
+			t.Error = err.Error()
+			return
+		}
+		t.Status = models.TaskStatusSucceeded
+		if result != nil {
+			if data, err := json.Marshal(result); err == nil {
+				t.Result = data
+			}
+		}
+	})
+}
+
+func (p *TaskProgress) update(ctx context.Context, fn func(t *models.Task)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fn(p.task)
+	p.task.UpdatedAt = time.Now().UTC()
+	if err := p.service.save(ctx, p.task); err != nil {
+		p.service.logger.Warn("failed to save task progress", "task_id", p.task.ID, "error", err)
+	}
+}
+
+func (p *TaskProgress) snapshot() *models.Task {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	task := *p.task
+	return &task
+}
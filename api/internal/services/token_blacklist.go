@@ -10,11 +10,11 @@ import (
 
 // TokenBlacklist manages blacklisted tokens in Redis
 type TokenBlacklist struct {
-	redis *redis.Client
+	redis redis.UniversalClient
 }
 
 // NewTokenBlacklist creates a new token blacklist service
-func NewTokenBlacklist(redisClient *redis.Client) *TokenBlacklist {
+func NewTokenBlacklist(redisClient redis.UniversalClient) *TokenBlacklist {
 	return &TokenBlacklist{
 		redis: redisClient,
 	}
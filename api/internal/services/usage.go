@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// MaxUsageMonths is how long monthly request counters are kept
+	MaxUsageMonths    = 13
+	usageTopFeatures  = 10
+	usageCounterTTL   = (MaxUsageMonths + 1) * 31 * 24 * time.Hour
+	usageRoutesPrefix = "/api/v1/"
+)
+
+// UsageService keeps per-user request counters in Redis, one hash per month keyed by
+// "METHOD /route/pattern", and combines them with entity counts from the database
+type UsageService struct {
+	redis  redis.UniversalClient
+	repo   *repository.UsageRepository
+	logger *slog.Logger
+}
+
+func NewUsageService(redisClient redis.UniversalClient, repo *repository.UsageRepository, logger *slog.Logger) *UsageService {
+	return &UsageService{redis: redisClient, repo: repo, logger: logger}
+}
+
+func usageKey(userID uuid.UUID, month time.Time) string {
+	return fmt.Sprintf("usage:%s:%s", userID, month.Format("2006-01"))
+}
+
+// Record counts one request to a feature. Errors are logged and otherwise ignored.
+func (s *UsageService) Record(ctx context.Context, userID uuid.UUID, feature string) {
+	key := usageKey(userID, time.Now().UTC())
+
+	pipe := s.redis.Pipeline()
+	pipe.HIncrBy(ctx, key, feature, 1)
+	pipe.Expire(ctx, key, usageCounterTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Warn("failed to record usage", "user_id", userID, "error", err)
+	}
+}
+
+// Summary returns the user's usage over the current month and the preceding months
+func (s *UsageService) Summary(ctx context.Context, userID uuid.UUID, months int) (*models.UsageSummary, error) {
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, -(months - 1), 0)
+
+	summary := &models.UsageSummary{
+		From:            from.Format("2006-01-02"),
+		To:              to.AddDate(0, 1, -1).Format("2006-01-02"),
+		RequestsByMonth: make([]models.MonthlyUsage, 0, months),
+		Modules:         []models.UsageCount{},
+		TopFeatures:     []models.UsageCount{},
+	}
+
+	features := make(map[string]int64)
+	modules := make(map[string]int64)
+	for i := 0; i < months; i++ {
+		month := from.AddDate(0, i, 0)
+		counts, err := s.redis.HGetAll(ctx, usageKey(userID, month)).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		var total int64
+		for feature, value := range counts {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			total += n
+			features[feature] += n
+			modules[usageModule(feature)] += n
+		}
+
+		summary.RequestsByMonth = append(summary.RequestsByMonth, models.MonthlyUsage{
+			Month:    month.Format("2006-01"),
+			Requests: total,
+		})
+		summary.TotalRequests += total
+	}
+
+	summary.Modules = sortedUsage(modules, 0)
+	summary.TopFeatures = sortedUsage(features, usageTopFeatures)
+
+	entities, err := s.repo.GetEntitiesCreatedByMonth(ctx, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	summary.EntitiesCreated = entities
+	if summary.EntitiesCreated == nil {
+		summary.EntitiesCreated = []models.MonthlyEntityCount{}
+	}
+
+	return summary, nil
+}
+
+// usageModule maps "GET /api/v1/portfolios/{id}/holdings" to "portfolios"
+func usageModule(feature string) string {
+	_, path, _ := strings.Cut(feature, " ")
+	path = strings.TrimPrefix(path, usageRoutesPrefix)
+	module, _, _ := strings.Cut(path, "/")
+	if module == "" {
+		return "other"
+	}
+	return module
+}
+
+// sortedUsage orders counts from most to least used, keeping at most limit entries (0 for all)
+func sortedUsage(counts map[string]int64, limit int) []models.UsageCount {
+	result := make([]models.UsageCount, 0, len(counts))
+	for name, n := range counts {
+		result = append(result, models.UsageCount{Name: name, Requests: n})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Requests != result[j].Requests {
+			return result[i].Requests > result[j].Requests
+		}
+		return result[i].Name < result[j].Name
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark-regan/wellf/internal/models"
+	"github.com/mark-regan/wellf/internal/repository"
+)
+
+const (
+	webhookTimeout = 10 * time.Second
+
+	// webhookDeliverySLA is how long a delivery keeps retrying before it is dead-lettered
+	webhookDeliverySLA = 24 * time.Hour
+
+	webhookRetryInterval = 30 * time.Second
+	webhookRetryBatch    = 50
+	webhookRetryLease    = 5 * time.Minute
+)
+
+// webhookRetrySchedule is the wait before each retry. A delivery still failing after the
+// last one, or whose next retry would fall past its deadline, is marked DEAD.
+var webhookRetrySchedule = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	30 * time.Minute,
+	2 * time.Hour,
+	6 * time.Hour,
+	12 * time.Hour,
+}
+
+var (
+	// ErrDeliveryNotDead is returned when re-driving a delivery that hasn't failed
+	ErrDeliveryNotDead = errors.New("only dead deliveries can be re-driven")
+
+	// ErrWebhookDestination is returned when a webhook's host resolves to an address
+	// that isn't public, such as the server itself or its private network
+	ErrWebhookDestination = errors.New("webhook destination is not a public address")
+)
+
+// sharedAddressSpace is the carrier-grade NAT range, which netip doesn't count as private
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// WebhookEvents lists the events a webhook can subscribe to
+var WebhookEvents = map[string]bool{
+	models.WebhookEventReminderCompleted: true,
+	models.WebhookEventReminderEscalated: true,
+	models.WebhookEventPriceAlert:        true,
+	models.WebhookEventStandingOrder:     true,
+	models.WebhookEventImportCompleted:   true,
+}
+
+// WebhookService delivers events to the user's webhooks in the background. Each body is
+// signed with the webhook's secret: X-Wellf-Signature is "sha256=" followed by the hex
+// HMAC-SHA256 of the body.
+type WebhookService struct {
+	repo   *repository.WebhookRepository
+	jobs   *JobManager
+	client *http.Client
+	logger *slog.Logger
+}
+
+func NewWebhookService(repo *repository.WebhookRepository, jobs *JobManager, logger *slog.Logger) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		jobs:   jobs,
+		client: newWebhookClient(),
+		logger: logger,
+	}
+}
+
+// newWebhookClient returns a client that only connects to public addresses and doesn't
+// follow redirects. The address is checked after the host is resolved, so a name that
+// resolves to a private address is refused too.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil || !publicAddr(ip) {
+				return ErrWebhookDestination
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicAddr reports whether ip is a unicast address outside the loopback, private,
+// link-local and shared ranges
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// ValidWebhookHost reports whether a webhook URL's host may be used. Hosts given as an
+// address must be public and localhost is refused; names are checked when delivered.
+func ValidWebhookHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return publicAddr(ip)
+	}
+	return true
+}
+
+// NewWebhookSecret returns a random signing secret
+func NewWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Emit sends the event to the user's active webhooks subscribed to it. Delivery happens
+// in the background, so failures are logged and recorded on the webhook rather than
+// returned.
+func (s *WebhookService) Emit(ctx context.Context, userID uuid.UUID, event string, data interface{}) {
+	webhooks, err := s.repo.GetActiveForEvent(ctx, userID, event)
+	if err != nil {
+		s.logger.Warn("failed to look up webhooks", "user_id", userID, "event", event, "error", err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		s.Send(webhook, event, data)
+	}
+}
+
+// Send records a delivery of one event to a webhook and makes the first attempt in the
+// background. Failed attempts are retried by Run.
+func (s *WebhookService) Send(webhook *models.Webhook, event string, data interface{}) {
+	body, err := json.Marshal(models.WebhookEvent{
+		ID:        uuid.New(),
+		Event:     event,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		s.logger.Error("failed to encode webhook event", "event", event, "error", err)
+		return
+	}
+
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		UserID:        webhook.UserID,
+		Event:         event,
+		Payload:       body,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+		DeadlineAt:    now.Add(webhookDeliverySLA),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.repo.CreateDelivery(ctx, delivery); err != nil {
+		s.logger.Error("failed to record webhook delivery", "webhook_id", webhook.ID, "event", event, "error", err)
+		return
+	}
+
+	s.attemptInBackground(webhook, delivery)
+}
+
+// Redrive resets a dead delivery with a fresh deadline and retries it straight away
+func (s *WebhookService) Redrive(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if delivery.Status != models.WebhookDeliveryDead {
+		return ErrDeliveryNotDead
+	}
+
+	webhook, err := s.repo.GetByID(ctx, delivery.WebhookID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+	delivery.DeadlineAt = now.Add(webhookDeliverySLA)
+	if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+		return err
+	}
+
+	s.attemptInBackground(webhook, delivery)
+	return nil
+}
+
+func (s *WebhookService) attemptInBackground(webhook *models.Webhook, delivery *models.WebhookDelivery) {
+	err := s.jobs.Go("webhook:"+delivery.Event, func(ctx context.Context) error {
+		return s.attempt(ctx, webhook, delivery)
+	})
+	if err != nil {
+		// Left PENDING with a due attempt time; the queue isn't polled for those, so
+		// schedule it as a retry for the next start-up.
+		delivery.Status = models.WebhookDeliveryRetrying
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.repo.UpdateDelivery(ctx, delivery)
+		s.logger.Info("deferring webhook delivery during shutdown", "webhook_id", webhook.ID, "event", delivery.Event)
+	}
+}
+
+// Run retries due deliveries until ctx is cancelled
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		s.retryDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *WebhookService) retryDue(ctx context.Context) {
+	deliveries, err := s.repo.ClaimDueDeliveries(ctx, webhookRetryBatch, webhookRetryLease)
+	if err != nil {
+		s.logger.Error("failed to claim webhook retries", "error", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		webhook, err := s.repo.GetByID(ctx, delivery.WebhookID)
+		if err != nil {
+			s.logger.Warn("failed to load webhook for retry", "webhook_id", delivery.WebhookID, "error", err)
+			continue
+		}
+		if !webhook.IsActive {
+			delivery.Status = models.WebhookDeliveryDead
+			delivery.NextAttemptAt = nil
+			delivery.LastError = "webhook is disabled"
+			if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+				s.logger.Warn("failed to update webhook delivery", "delivery_id", delivery.ID, "error", err)
+			}
+			continue
+		}
+
+		d := delivery
+		err = s.jobs.Run("webhook_retry", func(ctx context.Context) error {
+			return s.attempt(ctx, webhook, d)
+		})
+		if errors.Is(err, ErrShuttingDown) {
+			return
+		}
+	}
+}
+
+// attempt makes one delivery attempt and schedules the next retry, or dead-letters the
+// delivery if it failed permanently, ran out of retries or would miss its deadline
+func (s *WebhookService) attempt(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) error {
+	status, postErr := s.post(ctx, webhook, delivery.Event, delivery.Payload)
+	now := time.Now()
+
+	delivery.Attempts++
+	delivery.LastStatus = status
+	delivery.LastError = ""
+	if postErr != nil {
+		delivery.LastError = postErr.Error()
+	}
+
+	switch {
+	case postErr == nil:
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case permanentWebhookFailure(status) || delivery.Attempts > len(webhookRetrySchedule):
+		delivery.Status = models.WebhookDeliveryDead
+		delivery.NextAttemptAt = nil
+	default:
+		next := now.Add(webhookRetrySchedule[delivery.Attempts-1])
+		if next.After(delivery.DeadlineAt) {
+			delivery.Status = models.WebhookDeliveryDead
+			delivery.NextAttemptAt = nil
+		} else {
+			delivery.Status = models.WebhookDeliveryRetrying
+			delivery.NextAttemptAt = &next
+		}
+	}
+
+	recordCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.repo.UpdateDelivery(recordCtx, delivery); err != nil {
+		s.logger.Warn("failed to update webhook delivery", "delivery_id", delivery.ID, "error", err)
+	}
+	if err := s.repo.RecordDelivery(recordCtx, webhook.ID, status, delivery.LastError); err != nil {
+		s.logger.Warn("failed to record webhook delivery", "webhook_id", webhook.ID, "error", err)
+	}
+
+	if delivery.Status == models.WebhookDeliveryDead {
+		s.logger.Warn("webhook delivery dead-lettered", "delivery_id", delivery.ID, "webhook_id", webhook.ID, "attempts", delivery.Attempts, "error", delivery.LastError)
+	}
+	if postErr != nil {
+		return fmt.Errorf("webhook %s: %w", webhook.ID, postErr)
+	}
+	return nil
+}
+
+// permanentWebhookFailure reports whether a response means retrying won't help: a 4xx
+// other than request timeout or rate limiting
+func permanentWebhookFailure(status *int) bool {
+	if status == nil {
+		return false
+	}
+	code := *status
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
+
+// post makes one delivery attempt. Any 2xx response counts as delivered; redirects are
+// not followed.
+func (s *WebhookService) post(ctx context.Context, webhook *models.Webhook, event string, body []byte) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write(body)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wellf-webhooks")
+	req.Header.Set("X-Wellf-Event", event)
+	req.Header.Set("X-Wellf-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	status := resp.StatusCode
+	if status < 200 || status > 299 {
+		return &status, fmt.Errorf("unexpected status %d", status)
+	}
+	return &status, nil
+}
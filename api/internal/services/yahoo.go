@@ -5,43 +5,80 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/mark-regan/wellf/internal/database"
+	"github.com/mark-regan/wellf/internal/marketdata"
 	"github.com/mark-regan/wellf/internal/models"
 	"github.com/mark-regan/wellf/internal/repository"
 	"github.com/mark-regan/wellf/internal/yahoo"
 )
 
+// sparklineClosedTTL is how long sparklines are cached outside market hours
+const sparklineClosedTTL = time.Hour
+
+// YahooService serves search, charts and quotes. Search and charts come from Yahoo
+// Finance; quotes and historical prices go through the market data providers, starting
+// with the providers routed to each asset and failing over to the others.
 type YahooService struct {
-	client    *yahoo.Client
-	assetRepo *repository.AssetRepository
-	redis     *database.RedisClient
-	cacheTTL  time.Duration
-	logger    *slog.Logger
+	client         *yahoo.Client
+	providers      *marketdata.Registry
+	assetRepo      *repository.AssetRepository
+	redis          *database.RedisClient
+	logger         *slog.Logger
+	mu             sync.RWMutex
+	cacheTTL       time.Duration
+	searchCacheTTL time.Duration
+	routes         map[string][]string // priceRouteKey -> providers in order
 }
 
 func NewYahooService(
 	client *yahoo.Client,
+	providers *marketdata.Registry,
 	assetRepo *repository.AssetRepository,
 	redis *database.RedisClient,
 	cacheTTL time.Duration,
 	logger *slog.Logger,
 ) *YahooService {
 	return &YahooService{
-		client:    client,
-		assetRepo: assetRepo,
-		redis:     redis,
-		cacheTTL:  cacheTTL,
-		logger:    logger,
+		client:         client,
+		providers:      providers,
+		assetRepo:      assetRepo,
+		redis:          redis,
+		logger:         logger,
+		cacheTTL:       cacheTTL,
+		searchCacheTTL: 5 * time.Minute,
 	}
 }
 
+// SetCacheTTLs updates the quote and search cache TTLs at runtime
+func (s *YahooService) SetCacheTTLs(quoteTTL, searchTTL time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cacheTTL = quoteTTL
+	s.searchCacheTTL = searchTTL
+}
+
+func (s *YahooService) quoteTTL() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cacheTTL
+}
+
+func (s *YahooService) searchTTL() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.searchCacheTTL
+}
+
 type AssetSearchResult struct {
 	Symbol    string `json:"symbol"`
 	Name      string `json:"name"`
 	Exchange  string `json:"exchange"`
 	QuoteType string `json:"quote_type"`
+	ISIN      string `json:"isin,omitempty"` // set when searching by ISIN or SEDOL
 }
 
 func (s *YahooService) Search(ctx context.Context, term string) ([]AssetSearchResult, error) {
@@ -55,30 +92,55 @@ func (s *YahooService) Search(ctx context.Context, term string) ([]AssetSearchRe
 		}
 	}
 
+	// Funds without a searchable ticker can be found by ISIN or SEDOL. Yahoo resolves
+	// ISINs, so a SEDOL is searched as its UK ISIN, and a fund already known by either
+	// identifier is listed first.
+	query := term
+	results := []AssetSearchResult{}
+	isin, sedol, isIdentifier := FundIdentifier(term)
+	if isIdentifier {
+		query = isin
+		if asset, err := s.assetRepo.GetByIdentifier(ctx, isin, sedol); err == nil {
+			results = append(results, AssetSearchResult{
+				Symbol:    asset.Symbol,
+				Name:      asset.Name,
+				Exchange:  asset.Exchange,
+				QuoteType: asset.AssetType,
+				ISIN:      isin,
+			})
+		}
+	}
+
 	// Fetch from Yahoo Finance
-	result, err := s.client.Search(ctx, term)
+	result, err := s.client.Search(ctx, query)
 	if err != nil {
 		s.logger.Error("yahoo search failed", "error", err, "term", term)
 		return nil, err
 	}
 
-	results := make([]AssetSearchResult, 0, len(result.Quotes))
 	for _, q := range result.Quotes {
+		if len(results) > 0 && results[0].Symbol == q.Symbol {
+			continue
+		}
 		name := q.LongName
 		if name == "" {
 			name = q.ShortName
 		}
-		results = append(results, AssetSearchResult{
+		match := AssetSearchResult{
 			Symbol:    q.Symbol,
 			Name:      name,
 			Exchange:  q.Exchange,
 			QuoteType: q.QuoteType,
-		})
+		}
+		if isIdentifier {
+			match.ISIN = isin
+		}
+		results = append(results, match)
 	}
 
 	// Cache results
 	if data, err := json.Marshal(results); err == nil {
-		_ = s.redis.Set(ctx, cacheKey, string(data), 5*time.Minute)
+		_ = s.redis.Set(ctx, cacheKey, string(data), s.searchTTL())
 	}
 
 	return results, nil
@@ -94,6 +156,14 @@ type AssetDetails struct {
 	Change     float64 `json:"change"`
 	ChangePct  float64 `json:"change_pct"`
 	MarketTime int64   `json:"market_time"`
+
+	// Only reported by some providers, such as CoinGecko for crypto
+	MarketCap    *float64 `json:"market_cap,omitempty"`
+	Change24h    *float64 `json:"change_24h,omitempty"`
+	Change24hPct *float64 `json:"change_24h_pct,omitempty"`
+
+	// Recent closing prices, only filled in when a sparkline is requested
+	Sparkline []float64 `json:"sparkline,omitempty"`
 }
 
 func (s *YahooService) GetAssetDetails(ctx context.Context, symbol string) (*AssetDetails, error) {
@@ -107,42 +177,25 @@ func (s *YahooService) GetAssetDetails(ctx context.Context, symbol string) (*Ass
 		}
 	}
 
-	// Fetch from Yahoo Finance
-	quote, err := s.client.GetQuote(ctx, symbol)
+	quotes, err := s.fetchQuotes(ctx, []string{symbol})
 	if err != nil {
-		s.logger.Error("yahoo quote failed", "error", err, "symbol", symbol)
+		s.logger.Error("market data quote failed", "error", err, "symbol", symbol)
 		return nil, err
 	}
 
-	if len(quote.QuoteResponse.Result) == 0 {
+	if len(quotes) == 0 {
 		return nil, fmt.Errorf("no quote data for symbol: %s", symbol)
 	}
 
-	q := quote.QuoteResponse.Result[0]
-	name := q.LongName
-	if name == "" {
-		name = q.ShortName
-	}
-
-	details := &AssetDetails{
-		Symbol:     q.Symbol,
-		Name:       name,
-		Exchange:   q.Exchange,
-		Currency:   q.Currency,
-		QuoteType:  q.QuoteType,
-		Price:      q.RegularMarketPrice,
-		Change:     q.RegularMarketChange,
-		ChangePct:  q.RegularMarketChangePercent,
-		MarketTime: q.RegularMarketTime,
-	}
+	details := &quotes[0]
 
 	// Cache result
 	if data, err := json.Marshal(details); err == nil {
-		_ = s.redis.Set(ctx, cacheKey, string(data), s.cacheTTL)
+		_ = s.redis.Set(ctx, cacheKey, string(data), s.quoteTTL())
 	}
 
 	// Update asset in database if it exists
-	_ = s.assetRepo.UpdatePrice(ctx, symbol, q.RegularMarketPrice)
+	_ = s.assetRepo.UpdatePrice(ctx, symbol, details.Price)
 
 	return details, nil
 }
@@ -171,7 +224,7 @@ func (s *YahooService) GetPrice(ctx context.Context, symbol string) (float64, er
 
 	// Cache price
 	if data, err := json.Marshal(details.Price); err == nil {
-		_ = s.redis.Set(ctx, cacheKey, string(data), s.cacheTTL)
+		_ = s.redis.Set(ctx, cacheKey, string(data), s.quoteTTL())
 	}
 
 	return details.Price, nil
@@ -182,20 +235,20 @@ func (s *YahooService) RefreshPrices(ctx context.Context, symbols []string) erro
 		return nil
 	}
 
-	quotes, err := s.client.GetQuotes(ctx, symbols)
+	quotes, err := s.fetchQuotes(ctx, symbols)
 	if err != nil {
-		s.logger.Error("yahoo quotes refresh failed", "error", err)
+		s.logger.Error("market data quotes refresh failed", "error", err)
 		return err
 	}
 
 	prices := make(map[string]float64)
 	for _, q := range quotes {
-		prices[q.Symbol] = q.RegularMarketPrice
+		prices[q.Symbol] = q.Price
 
 		// Cache individual price
 		cacheKey := fmt.Sprintf("yahoo:price:%s", q.Symbol)
-		if data, err := json.Marshal(q.RegularMarketPrice); err == nil {
-			_ = s.redis.Set(ctx, cacheKey, string(data), s.cacheTTL)
+		if data, err := json.Marshal(q.Price); err == nil {
+			_ = s.redis.Set(ctx, cacheKey, string(data), s.quoteTTL())
 		}
 	}
 
@@ -209,49 +262,141 @@ func (s *YahooService) GetQuotes(ctx context.Context, symbols []string) ([]Asset
 		return []AssetDetails{}, nil
 	}
 
-	quotes, err := s.client.GetQuotes(ctx, symbols)
+	quotes, err := s.fetchQuotes(ctx, symbols)
 	if err != nil {
-		s.logger.Error("yahoo quotes failed", "error", err, "symbols", symbols)
+		s.logger.Error("market data quotes failed", "error", err, "symbols", symbols)
 		return nil, err
 	}
 
-	results := make([]AssetDetails, 0, len(quotes))
-	for _, q := range quotes {
-		name := q.LongName
-		if name == "" {
-			name = q.ShortName
+	for i := range quotes {
+		// Cache individual quote
+		cacheKey := fmt.Sprintf("yahoo:quote:%s", quotes[i].Symbol)
+		if data, err := json.Marshal(quotes[i]); err == nil {
+			_ = s.redis.Set(ctx, cacheKey, string(data), s.quoteTTL())
 		}
+	}
 
-		results = append(results, AssetDetails{
-			Symbol:     q.Symbol,
-			Name:       name,
-			Exchange:   q.Exchange,
-			Currency:   q.Currency,
-			QuoteType:  q.QuoteType,
-			Price:      q.RegularMarketPrice,
-			Change:     q.RegularMarketChange,
-			ChangePct:  q.RegularMarketChangePercent,
-			MarketTime: q.RegularMarketTime,
-		})
+	return quotes, nil
+}
 
-		// Cache individual quote
-		cacheKey := fmt.Sprintf("yahoo:quote:%s", q.Symbol)
-		details := AssetDetails{
-			Symbol:     q.Symbol,
-			Name:       name,
-			Exchange:   q.Exchange,
-			Currency:   q.Currency,
-			QuoteType:  q.QuoteType,
-			Price:      q.RegularMarketPrice,
-			Change:     q.RegularMarketChange,
-			ChangePct:  q.RegularMarketChangePercent,
-			MarketTime: q.RegularMarketTime,
+// SetPriceRoutes replaces the routing table used to choose each asset's providers
+func (s *YahooService) SetPriceRoutes(routes []*models.PriceSourceRoute) {
+	table := make(map[string][]string, len(routes))
+	for _, route := range routes {
+		if len(route.Sources) > 0 {
+			table[priceRouteKey(route.MatchType, route.MatchValue)] = route.Sources
 		}
-		if data, err := json.Marshal(details); err == nil {
-			_ = s.redis.Set(ctx, cacheKey, string(data), s.cacheTTL)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes = table
+}
+
+func priceRouteKey(matchType, value string) string {
+	return matchType + ":" + strings.ToUpper(value)
+}
+
+// dataSources returns the providers the asset is priced from, in order: those of the
+// route for its symbol, else its exchange, else its asset type, else its own data
+// source. Crypto assets left on Yahoo go to CoinGecko, which resolves crypto pairs more
+// reliably. Symbols not yet stored use Yahoo.
+func (s *YahooService) dataSources(asset *models.Asset) []string {
+	if asset == nil {
+		return []string{models.DataSourceYahoo}
+	}
+
+	if sources := s.routedSources(asset); sources != nil {
+		return sources
+	}
+	if asset.DataSource == "" {
+		return []string{models.DataSourceYahoo}
+	}
+	if asset.AssetType == models.AssetTypeCrypto && asset.DataSource == models.DataSourceYahoo && s.providers.Has(models.DataSourceCoinGecko) {
+		return []string{models.DataSourceCoinGecko}
+	}
+	return []string{asset.DataSource}
+}
+
+// routedSources returns the providers of the most specific route matching the asset,
+// or nil if none does
+func (s *YahooService) routedSources(asset *models.Asset) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range []string{
+		priceRouteKey(models.PriceRouteMatchSymbol, asset.Symbol),
+		priceRouteKey(models.PriceRouteMatchExchange, asset.Exchange),
+		priceRouteKey(models.PriceRouteMatchAssetType, asset.AssetType),
+	} {
+		if sources, ok := s.routes[key]; ok {
+			return sources
+		}
+	}
+	return nil
+}
+
+// fetchQuotes prices the symbols through the market data providers, grouped by each
+// asset's providers. Details a provider doesn't return, such as the name and
+// currency, are filled in from the stored asset.
+func (s *YahooService) fetchQuotes(ctx context.Context, symbols []string) ([]AssetDetails, error) {
+	assets, err := s.assetRepo.GetBySymbols(ctx, symbols)
+	if err != nil {
+		s.logger.Warn("failed to look up asset data sources", "error", err)
+		assets = map[string]*models.Asset{}
+	}
+
+	bySource := make(map[string][]string)
+	var sources [][]string
+	for _, symbol := range symbols {
+		chain := s.dataSources(assets[symbol])
+		key := strings.Join(chain, ",")
+		if _, seen := bySource[key]; !seen {
+			sources = append(sources, chain)
+		}
+		bySource[key] = append(bySource[key], symbol)
+	}
+
+	results := make([]AssetDetails, 0, len(symbols))
+	var lastErr error
+	for _, source := range sources {
+		quotes, err := s.providers.GetQuotes(ctx, source, bySource[strings.Join(source, ",")])
+		if err != nil {
+			lastErr = err
+		}
+		for _, q := range quotes {
+			details := AssetDetails{
+				Symbol:       q.Symbol,
+				Name:         q.Name,
+				Exchange:     q.Exchange,
+				Currency:     q.Currency,
+				QuoteType:    q.QuoteType,
+				Price:        q.Price,
+				Change:       q.Change,
+				ChangePct:    q.ChangePct,
+				MarketTime:   q.MarketTime,
+				MarketCap:    q.MarketCap,
+				Change24h:    q.Change24h,
+				Change24hPct: q.Change24hPct,
+			}
+			if asset, ok := assets[q.Symbol]; ok {
+				if details.Name == "" || details.Name == details.Symbol {
+					details.Name = asset.Name
+				}
+				if details.Exchange == "" {
+					details.Exchange = asset.Exchange
+				}
+				if details.Currency == "" {
+					details.Currency = asset.Currency
+				}
+			}
+			results = append(results, details)
 		}
 	}
 
+	if len(results) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
 	return results, nil
 }
 
@@ -289,6 +434,153 @@ func (s *YahooService) GetHistory(ctx context.Context, symbol string, period str
 		interval = "1wk"
 	}
 
+	history, err := s.fetchHistory(ctx, symbol, period, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache result
+	if data, err := json.Marshal(history); err == nil {
+		ttl := s.quoteTTL()
+		if period == "1d" {
+			ttl = 1 * time.Minute
+		}
+		_ = s.redis.Set(ctx, cacheKey, string(data), ttl)
+	}
+
+	return history, nil
+}
+
+// GetChartHistory returns at most points prices for the period, downsampled with
+// LTTB from daily closes (intraday for periods under three months) so the shape of
+// long ranges survives. Results are cached like GetHistory.
+func (s *YahooService) GetChartHistory(ctx context.Context, symbol string, period string, points int) ([]PriceHistory, error) {
+	cacheKey := fmt.Sprintf("yahoo:history:%s:%s:%d", symbol, period, points)
+	cached, err := s.redis.Get(ctx, cacheKey)
+	if err == nil && cached != "" {
+		var history []PriceHistory
+		if err := json.Unmarshal([]byte(cached), &history); err == nil {
+			return history, nil
+		}
+	}
+
+	interval := "1d"
+	switch period {
+	case "1d":
+		interval = "5m"
+	case "5d":
+		interval = "15m"
+	case "1mo":
+		interval = "1h"
+	}
+
+	history, err := s.fetchHistory(ctx, symbol, period, interval)
+	if err != nil {
+		return nil, err
+	}
+	history = DownsampleLTTB(history, points)
+
+	if data, err := json.Marshal(history); err == nil {
+		ttl := s.quoteTTL()
+		if period == "1d" {
+			ttl = 1 * time.Minute
+		}
+		_ = s.redis.Set(ctx, cacheKey, string(data), ttl)
+	}
+
+	return history, nil
+}
+
+// GetSparkline returns recent closing prices for symbol over period (1d at 15-minute
+// intervals or 5d hourly). Points are cached for the quote TTL while the market is
+// open and for sparklineClosedTTL once it has closed.
+func (s *YahooService) GetSparkline(ctx context.Context, symbol string, period string) ([]float64, error) {
+	cacheKey := fmt.Sprintf("yahoo:sparkline:%s:%s", symbol, period)
+	cached, err := s.redis.Get(ctx, cacheKey)
+	if err == nil && cached != "" {
+		var points []float64
+		if err := json.Unmarshal([]byte(cached), &points); err == nil {
+			return points, nil
+		}
+	}
+
+	interval := "15m"
+	if period == "5d" {
+		interval = "1h"
+	}
+
+	chart, err := s.client.GetChart(ctx, symbol, period, interval)
+	if err != nil {
+		return nil, err
+	}
+	if len(chart.Chart.Result) == 0 || len(chart.Chart.Result[0].Indicators.Quote) == 0 {
+		return nil, fmt.Errorf("no chart data for symbol: %s", symbol)
+	}
+
+	result := chart.Chart.Result[0]
+	points := make([]float64, 0, len(result.Timestamp))
+	for _, c := range result.Indicators.Quote[0].Close {
+		// Missing intervals come back as null, which decodes to zero
+		if c != 0 {
+			points = append(points, c)
+		}
+	}
+
+	ttl := sparklineClosedTTL
+	session := result.Meta.CurrentTradingPeriod.Regular
+	if now := time.Now().Unix(); now >= session.Start && now < session.End {
+		ttl = s.quoteTTL()
+	}
+	if data, err := json.Marshal(points); err == nil {
+		_ = s.redis.Set(ctx, cacheKey, string(data), ttl)
+	}
+
+	return points, nil
+}
+
+// WarmSparklines caches the intraday sparkline for each symbol so quote requests
+// for held assets don't wait on Yahoo
+func (s *YahooService) WarmSparklines(ctx context.Context, symbols []string) {
+	for _, symbol := range symbols {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := s.GetSparkline(ctx, symbol, "1d"); err != nil {
+			s.logger.Warn("failed to cache sparkline", "symbol", symbol, "error", err)
+		}
+	}
+}
+
+// historyDays is the number of days each chart period covers, 0 for all history
+var historyDays = map[string]int{
+	"1d": 1, "5d": 5, "1mo": 30, "3mo": 90, "6mo": 180, "1y": 365, "5y": 1825, "max": 0,
+}
+
+// fetchHistory loads the chart for symbol without caching. Crypto assets priced from
+// CoinGecko use its history, falling back to Yahoo if it fails; everything else comes
+// from Yahoo.
+func (s *YahooService) fetchHistory(ctx context.Context, symbol, period, interval string) ([]PriceHistory, error) {
+	if days, ok := historyDays[period]; ok {
+		if asset, err := s.assetRepo.GetBySymbol(ctx, symbol); err == nil && s.dataSources(asset)[0] == models.DataSourceCoinGecko {
+			candles, err := s.providers.GetHistory(ctx, models.DataSourceCoinGecko, symbol, days)
+			if err == nil {
+				history := make([]PriceHistory, 0, len(candles))
+				for _, c := range candles {
+					history = append(history, PriceHistory{
+						Date:   c.Time,
+						Open:   c.Open,
+						High:   c.High,
+						Low:    c.Low,
+						Close:  c.Close,
+						Volume: c.Volume,
+					})
+				}
+				return history, nil
+			}
+			s.logger.Warn("coingecko history failed, using yahoo", "symbol", symbol, "error", err)
+		}
+	}
+
 	chart, err := s.client.GetChart(ctx, symbol, period, interval)
 	if err != nil {
 		return nil, err
@@ -332,15 +624,6 @@ func (s *YahooService) GetHistory(ctx context.Context, symbol string, period str
 		history = append(history, h)
 	}
 
-	// Cache result
-	if data, err := json.Marshal(history); err == nil {
-		ttl := s.cacheTTL
-		if period == "1d" {
-			ttl = 1 * time.Minute
-		}
-		_ = s.redis.Set(ctx, cacheKey, string(data), ttl)
-	}
-
 	return history, nil
 }
 
@@ -358,6 +641,10 @@ func (s *YahooService) GetOrCreateAsset(ctx context.Context, symbol string) (*mo
 	}
 
 	assetType := mapQuoteTypeToAssetType(details.QuoteType)
+	dataSource := models.DataSourceYahoo
+	if assetType == models.AssetTypeCrypto && s.providers.Has(models.DataSourceCoinGecko) {
+		dataSource = models.DataSourceCoinGecko
+	}
 
 	asset := &models.Asset{
 		Symbol:     details.Symbol,
@@ -365,7 +652,7 @@ func (s *YahooService) GetOrCreateAsset(ctx context.Context, symbol string) (*mo
 		AssetType:  assetType,
 		Exchange:   details.Exchange,
 		Currency:   details.Currency,
-		DataSource: "YAHOO",
+		DataSource: dataSource,
 		LastPrice:  &details.Price,
 	}
 
@@ -389,10 +676,14 @@ func (s *YahooService) GetHistoricalPrice(ctx context.Context, symbol string, da
 		}
 	}
 
-	// Fetch from Yahoo Finance
-	price, err := s.client.GetHistoricalPrice(ctx, symbol, date)
+	var asset *models.Asset
+	if found, err := s.assetRepo.GetBySymbol(ctx, symbol); err == nil {
+		asset = found
+	}
+
+	price, err := s.providers.GetHistoricalPrice(ctx, s.dataSources(asset), symbol, date)
 	if err != nil {
-		s.logger.Error("yahoo historical price failed", "error", err, "symbol", symbol, "date", date)
+		s.logger.Error("market data historical price failed", "error", err, "symbol", symbol, "date", date)
 		return 0, err
 	}
 
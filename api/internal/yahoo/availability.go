@@ -0,0 +1,65 @@
+package yahoo
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Availability summarises recent requests to Yahoo Finance
+type Availability struct {
+	Status        string     `json:"status"` // up, down or unknown (no requests yet)
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+}
+
+// availabilityTransport records the outcome of every request made through it. Network
+// errors, 5xx and 429 responses count as failures.
+type availabilityTransport struct {
+	base        http.RoundTripper
+	mu          sync.Mutex
+	lastSuccess time.Time
+	lastFailure time.Time
+}
+
+func (t *availabilityTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+
+	failed := err != nil || resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	now := time.Now()
+
+	t.mu.Lock()
+	if failed {
+		t.lastFailure = now
+	} else {
+		t.lastSuccess = now
+	}
+	t.mu.Unlock()
+
+	return resp, err
+}
+
+func (t *availabilityTransport) availability() Availability {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	a := Availability{Status: "unknown"}
+	if !t.lastSuccess.IsZero() {
+		success := t.lastSuccess
+		a.LastSuccessAt = &success
+		a.Status = "up"
+	}
+	if !t.lastFailure.IsZero() {
+		failure := t.lastFailure
+		a.LastFailureAt = &failure
+		if failure.After(t.lastSuccess) {
+			a.Status = "down"
+		}
+	}
+	return a
+}
+
+// Availability reports whether Yahoo Finance answered the most recent request
+func (c *Client) Availability() Availability {
+	return c.transport.availability()
+}
@@ -26,15 +26,19 @@ type Client struct {
 	crumb      string
 	crumbMu    sync.RWMutex
 	userAgent  string
+	transport  *availabilityTransport
 }
 
 func NewClient() *Client {
 	jar, _ := cookiejar.New(nil)
+	transport := &availabilityTransport{base: http.DefaultTransport}
 	return &Client{
 		httpClient: &http.Client{
-			Timeout: 15 * time.Second,
-			Jar:     jar,
+			Timeout:   15 * time.Second,
+			Jar:       jar,
+			Transport: transport,
 		},
+		transport: transport,
 		userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
 	}
 }
@@ -180,6 +184,16 @@ type ChartMeta struct {
 	RegularMarketTime  int64   `json:"regularMarketTime"`
 	PreviousClose      float64 `json:"previousClose"`
 	ChartPreviousClose float64 `json:"chartPreviousClose"`
+
+	CurrentTradingPeriod struct {
+		Regular TradingPeriod `json:"regular"`
+	} `json:"currentTradingPeriod"`
+}
+
+// TradingPeriod is a trading session as Unix timestamps
+type TradingPeriod struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
 }
 
 type ChartIndicators struct {
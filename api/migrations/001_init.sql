@@ -206,6 +206,20 @@ BEGIN
     IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'is_locked') THEN
         ALTER TABLE users ADD COLUMN is_locked BOOLEAN DEFAULT false;
     END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'last_dashboard_view_at') THEN
+        ALTER TABLE users ADD COLUMN last_dashboard_view_at TIMESTAMPTZ;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'tax_jurisdiction') THEN
+        ALTER TABLE users ADD COLUMN tax_jurisdiction VARCHAR(2) NOT NULL DEFAULT 'UK';
+    END IF;
+
+    -- Assets table columns (identifiers used to find UK funds that have no ticker)
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'assets' AND column_name = 'isin') THEN
+        ALTER TABLE assets ADD COLUMN isin VARCHAR(12);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'assets' AND column_name = 'sedol') THEN
+        ALTER TABLE assets ADD COLUMN sedol VARCHAR(7);
+    END IF;
 
     -- Holdings table columns
     IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'holdings' AND column_name = 'purchased_at') THEN
@@ -216,4 +230,778 @@ BEGIN
     IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'portfolios' AND column_name = 'metadata') THEN
         ALTER TABLE portfolios ADD COLUMN metadata JSONB DEFAULT '{}';
     END IF;
+
+    -- Transactions table columns (dividend gross amount and tax withheld; total_amount is net)
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'transactions' AND column_name = 'gross_amount') THEN
+        ALTER TABLE transactions ADD COLUMN gross_amount DECIMAL(20, 2);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'transactions' AND column_name = 'withholding_tax') THEN
+        ALTER TABLE transactions ADD COLUMN withholding_tax DECIMAL(20, 2);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'transactions' AND column_name = 'withholding_tax_country') THEN
+        ALTER TABLE transactions ADD COLUMN withholding_tax_country VARCHAR(2) NOT NULL DEFAULT '';
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_assets_isin ON assets(isin) WHERE isin IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_assets_sedol ON assets(sedol) WHERE sedol IS NOT NULL;
+
+-- Net worth snapshots (daily valuations for performance charts)
+CREATE TABLE IF NOT EXISTS net_worth_snapshots (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    snapshot_date DATE NOT NULL,
+    total_net_worth DECIMAL(20, 2) NOT NULL DEFAULT 0,
+    investments DECIMAL(20, 2) NOT NULL DEFAULT 0,
+    cash DECIMAL(20, 2) NOT NULL DEFAULT 0,
+    fixed_assets DECIMAL(20, 2) NOT NULL DEFAULT 0,
+    currency CHAR(3) DEFAULT 'GBP',
+    portfolio_values JSONB DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(user_id, snapshot_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_net_worth_snapshots_user_date ON net_worth_snapshots(user_id, snapshot_date DESC);
+
+-- Runtime settings overrides (env config provides the defaults)
+CREATE TABLE IF NOT EXISTS app_settings (
+    key VARCHAR(100) PRIMARY KEY,
+    value JSONB NOT NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Resumable state for background jobs interrupted by shutdown
+CREATE TABLE IF NOT EXISTS job_checkpoints (
+    job_name VARCHAR(100) PRIMARY KEY,
+    state JSONB NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Savings goals
+CREATE TABLE IF NOT EXISTS savings_goals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    target_amount DECIMAL(20, 2) NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'GBP',
+    target_date DATE,
+    priority INTEGER NOT NULL DEFAULT 1,
+    notes TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_savings_goals_user ON savings_goals(user_id);
+
+-- Envelope allocations: a share of a cash account or portfolio set aside for a goal
+CREATE TABLE IF NOT EXISTS savings_goal_allocations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    goal_id UUID NOT NULL REFERENCES savings_goals(id) ON DELETE CASCADE,
+    cash_account_id UUID REFERENCES cash_accounts(id) ON DELETE CASCADE,
+    portfolio_id UUID REFERENCES portfolios(id) ON DELETE CASCADE,
+    percentage DECIMAL(5, 2) NOT NULL DEFAULT 100,
+    CHECK ((cash_account_id IS NULL) <> (portfolio_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_savings_goal_allocations_goal ON savings_goal_allocations(goal_id);
+
+-- Daily progress per goal, used to estimate the recent contribution rate
+CREATE TABLE IF NOT EXISTS savings_goal_snapshots (
+    goal_id UUID NOT NULL REFERENCES savings_goals(id) ON DELETE CASCADE,
+    snapshot_date DATE NOT NULL,
+    amount DECIMAL(20, 2) NOT NULL,
+    PRIMARY KEY (goal_id, snapshot_date)
+);
+
+-- Onboarding checklist steps, recorded the first time the API observes each one
+CREATE TABLE IF NOT EXISTS user_onboarding_steps (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    step VARCHAR(50) NOT NULL,
+    completed_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, step)
+);
+
+-- Reminders parsed from "@YYYY-MM-DD text" tokens in notes fields
+CREATE TABLE IF NOT EXISTS reminders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source_type VARCHAR(30) NOT NULL,
+    source_id UUID NOT NULL,
+    due_date DATE NOT NULL,
+    text VARCHAR(500) NOT NULL,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminders_user_due ON reminders(user_id, due_date);
+CREATE INDEX IF NOT EXISTS idx_reminders_source ON reminders(source_type, source_id);
+
+-- Saved filter/sort views for list endpoints
+CREATE TABLE IF NOT EXISTS saved_views (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    module VARCHAR(30) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    filters JSONB NOT NULL DEFAULT '{}',
+    sort JSONB,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(user_id, module, name)
+);
+
+-- Change log for incremental client sync, written by triggers so cascaded and bulk
+-- changes are captured too. user_id has no foreign key so deleting a user (which
+-- cascades to their records) doesn't trip over the log.
+CREATE TABLE IF NOT EXISTS sync_changes (
+    seq BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    domain VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    operation VARCHAR(10) NOT NULL,
+    changed_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_changes_user_seq ON sync_changes(user_id, seq);
+
+-- TG_ARGV[0] is 'portfolio' for tables owned through portfolio_id, otherwise user_id is used.
+-- Rows deleted along with their portfolio have no owner left to look up; the portfolio's
+-- own tombstone covers them.
+CREATE OR REPLACE FUNCTION record_sync_change() RETURNS TRIGGER AS $$
+DECLARE
+    rec RECORD;
+    owner UUID;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := OLD;
+    ELSE
+        rec := NEW;
+    END IF;
+
+    IF TG_ARGV[0] = 'portfolio' THEN
+        SELECT user_id INTO owner FROM portfolios WHERE id = rec.portfolio_id;
+    ELSE
+        owner := rec.user_id;
+    END IF;
+
+    IF owner IS NOT NULL AND EXISTS (SELECT 1 FROM users WHERE id = owner) THEN
+        INSERT INTO sync_changes (user_id, domain, entity_id, operation)
+        VALUES (owner, TG_TABLE_NAME, rec.id, CASE WHEN TG_OP = 'DELETE' THEN 'DELETE' ELSE 'UPSERT' END);
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS sync_changes_portfolios ON portfolios;
+CREATE TRIGGER sync_changes_portfolios AFTER INSERT OR UPDATE OR DELETE ON portfolios
+    FOR EACH ROW EXECUTE FUNCTION record_sync_change('user');
+DROP TRIGGER IF EXISTS sync_changes_holdings ON holdings;
+CREATE TRIGGER sync_changes_holdings AFTER INSERT OR UPDATE OR DELETE ON holdings
+    FOR EACH ROW EXECUTE FUNCTION record_sync_change('portfolio');
+DROP TRIGGER IF EXISTS sync_changes_transactions ON transactions;
+CREATE TRIGGER sync_changes_transactions AFTER INSERT OR UPDATE OR DELETE ON transactions
+    FOR EACH ROW EXECUTE FUNCTION record_sync_change('portfolio');
+DROP TRIGGER IF EXISTS sync_changes_cash_accounts ON cash_accounts;
+CREATE TRIGGER sync_changes_cash_accounts AFTER INSERT OR UPDATE OR DELETE ON cash_accounts
+    FOR EACH ROW EXECUTE FUNCTION record_sync_change('portfolio');
+DROP TRIGGER IF EXISTS sync_changes_fixed_assets ON fixed_assets;
+CREATE TRIGGER sync_changes_fixed_assets AFTER INSERT OR UPDATE OR DELETE ON fixed_assets
+    FOR EACH ROW EXECUTE FUNCTION record_sync_change('user');
+DROP TRIGGER IF EXISTS sync_changes_savings_goals ON savings_goals;
+CREATE TRIGGER sync_changes_savings_goals AFTER INSERT OR UPDATE OR DELETE ON savings_goals
+    FOR EACH ROW EXECUTE FUNCTION record_sync_change('user');
+DROP TRIGGER IF EXISTS sync_changes_reminders ON reminders;
+CREATE TRIGGER sync_changes_reminders AFTER INSERT OR UPDATE OR DELETE ON reminders
+    FOR EACH ROW EXECUTE FUNCTION record_sync_change('user');
+DROP TRIGGER IF EXISTS sync_changes_saved_views ON saved_views;
+CREATE TRIGGER sync_changes_saved_views AFTER INSERT OR UPDATE OR DELETE ON saved_views
+    FOR EACH ROW EXECUTE FUNCTION record_sync_change('user');
+
+-- Dividend vouchers attached to DIVIDEND transactions (one per transaction)
+CREATE TABLE IF NOT EXISTS transaction_vouchers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID NOT NULL UNIQUE REFERENCES transactions(id) ON DELETE CASCADE,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    data BYTEA NOT NULL,
+    extracted_text TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Research journal entries per asset, keyed by symbol so notes can be kept on assets
+-- that aren't held yet
+CREATE TABLE IF NOT EXISTS asset_notes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    symbol VARCHAR(20) NOT NULL,
+    entry_date DATE NOT NULL,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    body TEXT NOT NULL DEFAULT '',
+    links TEXT[] NOT NULL DEFAULT '{}',
+    sentiment VARCHAR(10) NOT NULL DEFAULT 'NEUTRAL',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_asset_notes_user_symbol ON asset_notes(user_id, symbol, entry_date DESC);
+
+-- Base currency changes, with the FX rate used to revalue the user's snapshots
+CREATE TABLE IF NOT EXISTS base_currency_changes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_currency CHAR(3) NOT NULL,
+    to_currency CHAR(3) NOT NULL,
+    fx_rate DECIMAL(20, 8) NOT NULL,
+    snapshots_revalued INTEGER NOT NULL DEFAULT 0,
+    changed_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_base_currency_changes_user ON base_currency_changes(user_id, changed_at DESC);
+
+-- Outgoing webhooks notifying external automations of events such as completed reminders
+CREATE TABLE IF NOT EXISTS webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    last_delivery_at TIMESTAMPTZ,
+    last_status INTEGER,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks(user_id);
+
+-- Every webhook event sent, with its retry state. Deliveries that fail permanently or
+-- run out of retries before their deadline are left DEAD for inspection and re-driving.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ,
+    deadline_at TIMESTAMPTZ NOT NULL,
+    last_status INTEGER,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_user ON webhook_deliveries(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'RETRYING';
+
+-- Purchase lots per holding, rebuilt from BUY/SELL/transfer transactions using the
+-- portfolio's cost basis method
+CREATE TABLE IF NOT EXISTS holding_lots (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    portfolio_id UUID NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    asset_id UUID NOT NULL REFERENCES assets(id),
+    transaction_id UUID REFERENCES transactions(id) ON DELETE CASCADE,
+    acquired_at DATE NOT NULL,
+    quantity DECIMAL(20, 8) NOT NULL,
+    remaining_quantity DECIMAL(20, 8) NOT NULL,
+    unit_cost DECIMAL(20, 8) NOT NULL,
+    realised_gain DECIMAL(20, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_holding_lots_portfolio_asset ON holding_lots(portfolio_id, asset_id, acquired_at);
+
+-- Target allocation per portfolio, either by asset or by asset type
+CREATE TABLE IF NOT EXISTS allocation_targets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    portfolio_id UUID NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    asset_id UUID REFERENCES assets(id) ON DELETE CASCADE,
+    asset_type VARCHAR(20),
+    target_pct DECIMAL(7, 4) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK ((asset_id IS NULL) <> (asset_type IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_allocation_targets_portfolio ON allocation_targets(portfolio_id);
+
+-- User-defined column mappings for the transaction CSV import
+CREATE TABLE IF NOT EXISTS import_presets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    mapping JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(user_id, name)
+);
+
+-- Corporate actions applied to an asset: splits and consolidations (ratio_from old units
+-- become ratio_to new units) and ticker changes
+CREATE TABLE IF NOT EXISTS corporate_actions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    asset_id UUID NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+    action_type VARCHAR(20) NOT NULL,
+    effective_date DATE NOT NULL,
+    ratio_from DECIMAL(20, 8),
+    ratio_to DECIMAL(20, 8),
+    old_symbol VARCHAR(20),
+    new_symbol VARCHAR(20),
+    notes TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_corporate_actions_asset ON corporate_actions(asset_id, effective_date DESC);
+
+-- Reusable target allocations saved from a portfolio
+CREATE TABLE IF NOT EXISTS portfolio_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    targets JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(user_id, name)
+);
+
+-- Price alerts on assets: price above or below a level, or a daily move of at least a
+-- percentage. Level alerts switch off once triggered; daily move alerts trigger at most
+-- once a day.
+CREATE TABLE IF NOT EXISTS price_alerts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    asset_id UUID NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+    condition VARCHAR(20) NOT NULL,
+    threshold DECIMAL(20, 8) NOT NULL,
+    note TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    last_triggered_at TIMESTAMPTZ,
+    last_triggered_price DECIMAL(20, 8),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_alerts_user ON price_alerts(user_id);
+CREATE INDEX IF NOT EXISTS idx_price_alerts_active ON price_alerts(asset_id) WHERE is_active;
+
+-- Recurring income such as salary or rent. next_pay_date anchors the payment schedule.
+CREATE TABLE IF NOT EXISTS incomes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source VARCHAR(255) NOT NULL,
+    income_type VARCHAR(20) NOT NULL DEFAULT 'OTHER',
+    amount DECIMAL(18, 2) NOT NULL,
+    frequency VARCHAR(20) NOT NULL,
+    next_pay_date DATE NOT NULL,
+    end_date DATE,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    notes TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_incomes_user ON incomes(user_id);
+
+-- Change history (audit trail) of financial records, written by triggers so bulk and
+-- cascaded changes are captured too. Updates keep only the fields that changed;
+-- inserts keep the new row and deletes the old one. Like sync_changes, user_id has no
+-- foreign key so the history outlives deleted records.
+CREATE TABLE IF NOT EXISTS change_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    operation VARCHAR(10) NOT NULL,
+    changed_fields TEXT[] NOT NULL DEFAULT '{}',
+    before JSONB,
+    after JSONB,
+    changed_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_change_history_entity ON change_history(entity_type, entity_id, id DESC);
+
+-- TG_ARGV[0] is 'portfolio' for tables owned through portfolio_id, otherwise user_id is
+-- used. Updates that only touch updated_at are not recorded.
+CREATE OR REPLACE FUNCTION record_change_history() RETURNS TRIGGER AS $$
+DECLARE
+    rec RECORD;
+    owner UUID;
+    old_row JSONB;
+    new_row JSONB;
+    fields TEXT[];
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := OLD;
+    ELSE
+        rec := NEW;
+    END IF;
+
+    IF TG_ARGV[0] = 'portfolio' THEN
+        SELECT user_id INTO owner FROM portfolios WHERE id = rec.portfolio_id;
+    ELSE
+        owner := rec.user_id;
+    END IF;
+    IF owner IS NULL OR NOT EXISTS (SELECT 1 FROM users WHERE id = owner) THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO change_history (user_id, entity_type, entity_id, operation, after)
+        VALUES (owner, TG_TABLE_NAME, rec.id, TG_OP, to_jsonb(NEW));
+    ELSIF TG_OP = 'DELETE' THEN
+        INSERT INTO change_history (user_id, entity_type, entity_id, operation, before)
+        VALUES (owner, TG_TABLE_NAME, rec.id, TG_OP, to_jsonb(OLD));
+    ELSE
+        old_row := to_jsonb(OLD);
+        new_row := to_jsonb(NEW);
+        SELECT array_agg(key ORDER BY key) INTO fields
+        FROM jsonb_object_keys(new_row) AS key
+        WHERE key <> 'updated_at' AND new_row -> key IS DISTINCT FROM old_row -> key;
+
+        IF fields IS NOT NULL THEN
+            INSERT INTO change_history (user_id, entity_type, entity_id, operation, changed_fields, before, after)
+            SELECT owner, TG_TABLE_NAME, rec.id, TG_OP, fields,
+                jsonb_object_agg(f, old_row -> f), jsonb_object_agg(f, new_row -> f)
+            FROM unnest(fields) AS f;
+        END IF;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS change_history_portfolios ON portfolios;
+CREATE TRIGGER change_history_portfolios AFTER INSERT OR UPDATE OR DELETE ON portfolios
+    FOR EACH ROW EXECUTE FUNCTION record_change_history('user');
+DROP TRIGGER IF EXISTS change_history_holdings ON holdings;
+CREATE TRIGGER change_history_holdings AFTER INSERT OR UPDATE OR DELETE ON holdings
+    FOR EACH ROW EXECUTE FUNCTION record_change_history('portfolio');
+DROP TRIGGER IF EXISTS change_history_transactions ON transactions;
+CREATE TRIGGER change_history_transactions AFTER INSERT OR UPDATE OR DELETE ON transactions
+    FOR EACH ROW EXECUTE FUNCTION record_change_history('portfolio');
+
+-- Watchlist symbols in the user's chosen order
+CREATE TABLE IF NOT EXISTS watchlist_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    symbol VARCHAR(20) NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(user_id, symbol)
+);
+
+CREATE INDEX IF NOT EXISTS idx_watchlist_items_user ON watchlist_items(user_id, position);
+
+-- Move watchlists kept as a comma-separated string on the user into watchlist_items.
+-- The string is cleared once copied, so this only acts on watchlists not yet moved.
+INSERT INTO watchlist_items (user_id, symbol, position)
+SELECT u.id, UPPER(TRIM(s.symbol)), MIN(s.ord)::int - 1
+FROM users u, unnest(string_to_array(u.watchlist, ',')) WITH ORDINALITY AS s(symbol, ord)
+WHERE COALESCE(u.watchlist, '') <> '' AND TRIM(s.symbol) <> ''
+GROUP BY u.id, UPPER(TRIM(s.symbol))
+ON CONFLICT (user_id, symbol) DO NOTHING;
+UPDATE users SET watchlist = '' WHERE COALESCE(watchlist, '') <> '';
+
+-- Personal API tokens for third-party tools. Only a hash of the token is kept; scopes
+-- limit what the token can reach and rate_limit is its requests per minute.
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_prefix VARCHAR(16) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    rate_limit INTEGER NOT NULL DEFAULT 60,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens(user_id);
+
+-- Kiosk tokens are read-only tokens for a wall-mounted display, limited to a few
+-- dashboard endpoints and labelled with the device they are on
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'api_tokens' AND column_name = 'kind') THEN
+        ALTER TABLE api_tokens ADD COLUMN kind VARCHAR(20) NOT NULL DEFAULT 'PERSONAL';
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'api_tokens' AND column_name = 'device_label') THEN
+        ALTER TABLE api_tokens ADD COLUMN device_label VARCHAR(100) NOT NULL DEFAULT '';
+    END IF;
+END $$;
+
+-- Which market data providers price which assets, in fallback order. A route matches a
+-- symbol, an exchange or an asset type; the most specific match wins.
+CREATE TABLE IF NOT EXISTS price_source_routes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    match_type VARCHAR(20) NOT NULL,
+    match_value VARCHAR(50) NOT NULL,
+    sources TEXT[] NOT NULL DEFAULT '{}',
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(match_type, match_value)
+);
+
+-- Ledger of every change to a cash account's balance. amount is signed (money in is
+-- positive) and balance_after is the account balance once it was applied. The two
+-- sides of a transfer share a transfer_id.
+CREATE TABLE IF NOT EXISTS cash_movements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    cash_account_id UUID NOT NULL REFERENCES cash_accounts(id) ON DELETE CASCADE,
+    movement_type VARCHAR(20) NOT NULL,
+    amount DECIMAL(20, 2) NOT NULL,
+    balance_after DECIMAL(20, 2) NOT NULL,
+    movement_date DATE NOT NULL,
+    description VARCHAR(255),
+    transfer_id UUID,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_cash_movements_account ON cash_movements(cash_account_id, movement_date DESC, created_at DESC);
+
+-- Tags shared across domains. A link names the domain (e.g. TRANSACTION) and the id of
+-- the item in it; items have no foreign key here, so each tagged table removes its
+-- links on delete through remove_tag_links.
+CREATE TABLE IF NOT EXISTS tags (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS tag_links (
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    domain VARCHAR(30) NOT NULL,
+    item_id UUID NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (tag_id, domain, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tag_links_item ON tag_links(domain, item_id);
+
+-- TG_ARGV[0] is the tag domain of the table
+CREATE OR REPLACE FUNCTION remove_tag_links() RETURNS TRIGGER AS $$
+BEGIN
+    DELETE FROM tag_links WHERE domain = TG_ARGV[0] AND item_id = OLD.id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tag_links_transactions ON transactions;
+CREATE TRIGGER tag_links_transactions AFTER DELETE ON transactions
+    FOR EACH ROW EXECUTE FUNCTION remove_tag_links('TRANSACTION');
+
+-- Mortgages, loans and credit cards, subtracted from net worth. interest_rate is an
+-- annual percentage; monthly_payment is taken on payment_day each month.
+CREATE TABLE IF NOT EXISTS liabilities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    liability_type VARCHAR(20) NOT NULL,
+    lender VARCHAR(255),
+    balance DECIMAL(20, 2) NOT NULL DEFAULT 0,
+    currency CHAR(3) DEFAULT 'GBP',
+    interest_rate DECIMAL(8, 4) NOT NULL DEFAULT 0,
+    monthly_payment DECIMAL(20, 2),
+    payment_day INTEGER NOT NULL DEFAULT 1,
+    end_date DATE,
+    notes TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_liabilities_user ON liabilities(user_id);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'net_worth_snapshots' AND column_name = 'liabilities') THEN
+        ALTER TABLE net_worth_snapshots ADD COLUMN liabilities DECIMAL(20, 2) NOT NULL DEFAULT 0;
+    END IF;
+END $$;
+
+-- Reminder escalation. An open HIGH or URGENT reminder not completed within
+-- escalate_after_days of its due date moves to the next channel (PUSH, EMAIL, then
+-- DIGEST) and is raised to URGENT, once per period. The period falls back to the
+-- user's default for the reminder's source type.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'reminders' AND column_name = 'priority') THEN
+        ALTER TABLE reminders ADD COLUMN priority VARCHAR(10) NOT NULL DEFAULT 'NORMAL';
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'reminders' AND column_name = 'escalate_after_days') THEN
+        ALTER TABLE reminders ADD COLUMN escalate_after_days INTEGER;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'reminders' AND column_name = 'escalation_level') THEN
+        ALTER TABLE reminders ADD COLUMN escalation_level INTEGER NOT NULL DEFAULT 0;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'reminders' AND column_name = 'escalated_at') THEN
+        ALTER TABLE reminders ADD COLUMN escalated_at TIMESTAMPTZ;
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS reminder_escalation_defaults (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source_type VARCHAR(30) NOT NULL,
+    escalate_after_days INTEGER NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, source_type)
+);
+
+-- Standing orders invest a fixed amount into a portfolio on a day of each month: a BUY
+-- of an asset at the day's market price, or a cash DEPOSIT. next_run_date is the next
+-- day it runs; skipping moves it on a month.
+CREATE TABLE IF NOT EXISTS standing_orders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    portfolio_id UUID NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    asset_id UUID REFERENCES assets(id) ON DELETE CASCADE,
+    order_type VARCHAR(10) NOT NULL,
+    amount DECIMAL(20, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    day_of_month INTEGER NOT NULL,
+    next_run_date DATE NOT NULL,
+    end_date DATE,
+    note TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    last_run_at TIMESTAMPTZ,
+    last_transaction_id UUID REFERENCES transactions(id) ON DELETE SET NULL,
+    last_error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_standing_orders_user ON standing_orders(user_id);
+CREATE INDEX IF NOT EXISTS idx_standing_orders_due ON standing_orders(next_run_date) WHERE is_active;
+
+-- Notes and tags on holdings
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'holdings' AND column_name = 'notes') THEN
+        ALTER TABLE holdings ADD COLUMN notes TEXT;
+    END IF;
+END $$;
+
+DROP TRIGGER IF EXISTS tag_links_holdings ON holdings;
+CREATE TRIGGER tag_links_holdings AFTER DELETE ON holdings
+    FOR EACH ROW EXECUTE FUNCTION remove_tag_links('HOLDING');
+
+-- SIPP relief at source: the government top-up is a TAX_RELIEF transaction linked to
+-- the contribution it was claimed on
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'transactions' AND column_name = 'relief_for_id') THEN
+        ALTER TABLE transactions ADD COLUMN relief_for_id UUID REFERENCES transactions(id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_relief_for ON transactions(relief_for_id) WHERE relief_for_id IS NOT NULL;
+
+-- Transactions in a currency other than their portfolio's keep the rate on their date
+-- and their amount converted into the portfolio's currency
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'transactions' AND column_name = 'fx_rate') THEN
+        ALTER TABLE transactions ADD COLUMN fx_rate DECIMAL(20, 10);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'transactions' AND column_name = 'portfolio_amount') THEN
+        ALTER TABLE transactions ADD COLUMN portfolio_amount DECIMAL(20, 2);
+    END IF;
+END $$;
+
+-- Open Banking connections. requisition_id is the provider's record of the user's
+-- consent; each account it gives access to syncs into a cash account in portfolio_id.
+CREATE TABLE IF NOT EXISTS bank_connections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    portfolio_id UUID NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    provider VARCHAR(30) NOT NULL,
+    institution_id VARCHAR(100) NOT NULL,
+    institution_name VARCHAR(255),
+    requisition_id VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    expires_at TIMESTAMPTZ,
+    last_synced_at TIMESTAMPTZ,
+    last_error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_bank_connections_user ON bank_connections(user_id);
+
+-- Cash accounts kept in sync with a bank account. Disconnecting leaves the cash
+-- accounts in place to be updated by hand.
+CREATE TABLE IF NOT EXISTS bank_connection_accounts (
+    cash_account_id UUID PRIMARY KEY REFERENCES cash_accounts(id) ON DELETE CASCADE,
+    connection_id UUID NOT NULL REFERENCES bank_connections(id) ON DELETE CASCADE,
+    external_account_id VARCHAR(255) NOT NULL,
+    UNIQUE(connection_id, external_account_id)
+);
+
+-- Bank transaction IDs on synced movements, so each is imported once
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'cash_movements' AND column_name = 'external_id') THEN
+        ALTER TABLE cash_movements ADD COLUMN external_id VARCHAR(255);
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cash_movements_external ON cash_movements(cash_account_id, external_id) WHERE external_id IS NOT NULL;
+
+-- Admin announcements shown as banners between starts_at and ends_at (open-ended when NULL)
+CREATE TABLE IF NOT EXISTS announcements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    title VARCHAR(200) NOT NULL,
+    message TEXT NOT NULL,
+    severity VARCHAR(20) NOT NULL DEFAULT 'INFO',
+    starts_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ends_at TIMESTAMPTZ,
+    dismissible BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_announcements_window ON announcements(starts_at, ends_at);
+
+CREATE TABLE IF NOT EXISTS announcement_dismissals (
+    announcement_id UUID NOT NULL REFERENCES announcements(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    dismissed_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (announcement_id, user_id)
+);
+
+-- Outcome of each linked account's last sync, so one failing account doesn't hold back
+-- the others. Existing links start from their connection's last sync.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'bank_connection_accounts' AND column_name = 'last_synced_at') THEN
+        ALTER TABLE bank_connection_accounts ADD COLUMN last_synced_at TIMESTAMPTZ;
+        ALTER TABLE bank_connection_accounts ADD COLUMN last_error TEXT;
+        UPDATE bank_connection_accounts a SET last_synced_at = c.last_synced_at
+        FROM bank_connections c WHERE c.id = a.connection_id;
+    END IF;
+END $$;
+
+-- Incomes are paid in their own currency, and from start_date (or when they were added)
+-- rather than indefinitely into the past. Existing incomes were in the user's base
+-- currency.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'incomes' AND column_name = 'currency') THEN
+        ALTER TABLE incomes ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'GBP';
+        UPDATE incomes i SET currency = COALESCE(u.base_currency, 'GBP')
+        FROM users u WHERE u.id = i.user_id;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'incomes' AND column_name = 'start_date') THEN
+        ALTER TABLE incomes ADD COLUMN start_date DATE;
+    END IF;
 END $$;
@@ -0,0 +1,112 @@
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/hkdf"
+)
+
+// prefix marks an encrypted value so plaintext written before encryption was enabled
+// can still be read
+const prefix = "enc:v1:"
+
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// Cipher encrypts sensitive strings with AES-256-GCM under a key derived per user from
+// a master key, so one user's values can't be decrypted with another user's key. A nil
+// Cipher leaves values untouched.
+type Cipher struct {
+	masterKey []byte
+}
+
+// New returns a Cipher for the master key, or nil if the key is empty
+func New(masterKey string) *Cipher {
+	if masterKey == "" {
+		return nil
+	}
+	return &Cipher{masterKey: []byte(masterKey)}
+}
+
+// Enabled reports whether values are encrypted
+func (c *Cipher) Enabled() bool {
+	return c != nil
+}
+
+// IsEncrypted reports whether value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt encrypts value for the user. Empty and already encrypted values are returned
+// as they are.
+func (c *Cipher) Encrypt(userID uuid.UUID, value string) (string, error) {
+	if c == nil || value == "" || IsEncrypted(value) {
+		return value, nil
+	}
+
+	aead, err := c.aead(userID)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), userID[:])
+	return prefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt. Values without the encrypted prefix are returned as they
+// are.
+func (c *Cipher) Decrypt(userID uuid.UUID, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if c == nil {
+		return "", errors.New("field encryption key not configured")
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, prefix))
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	aead, err := c.aead(userID)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], userID[:])
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plain), nil
+}
+
+// aead derives the user's key from the master key with HKDF-SHA256
+func (c *Cipher) aead(userID uuid.UUID) (cipher.AEAD, error) {
+	key := make([]byte, 32)
+	kdf := hkdf.New(sha256.New, c.masterKey, userID[:], []byte("wellf field encryption"))
+	if _, err := io.ReadFull(kdf, key); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
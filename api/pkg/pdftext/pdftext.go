@@ -0,0 +1,319 @@
+// Package pdftext extracts plain text from simple text-based PDFs such as broker
+// statements and dividend vouchers. Only uncompressed and FlateDecode content
+// streams are read, and strings are decoded as Latin-1, so scanned documents and
+// fonts with custom encodings yield little or no text.
+package pdftext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotPDF = errors.New("not a PDF file")
+)
+
+// maxDecodedSize caps the content decoded from one document, so a small compressed
+// stream can't inflate without bound. Text past the cap is not extracted.
+const maxDecodedSize = 32 << 20 // 32MB
+
+// Extract returns the text shown by the document's content streams, one line per
+// positioned run of text. Decoding stops once maxDecodedSize bytes have been read.
+func Extract(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return "", ErrNotPDF
+	}
+
+	var out strings.Builder
+	rest := data
+	budget := int64(maxDecodedSize)
+	for budget > 0 {
+		i := bytes.Index(rest, []byte("stream"))
+		if i < 0 {
+			break
+		}
+		if i >= 3 && string(rest[i-3:i]) == "end" {
+			rest = rest[i+len("stream"):]
+			continue
+		}
+
+		// The stream dictionary sits between the object header and the stream keyword
+		dict := rest[:i]
+		if j := bytes.LastIndex(dict, []byte("obj")); j >= 0 {
+			dict = dict[j:]
+		}
+
+		start := i + len("stream")
+		if start < len(rest) && rest[start] == '\r' {
+			start++
+		}
+		if start < len(rest) && rest[start] == '\n' {
+			start++
+		}
+		end := bytes.Index(rest[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		raw := rest[start : start+end]
+		rest = rest[start+end+len("endstream"):]
+
+		content, ok := decodeStream(dict, raw, budget)
+		if !ok {
+			continue
+		}
+		budget -= int64(len(content))
+		showText(content, &out)
+	}
+
+	// Tidy up whitespace and drop empty lines
+	var lines []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// decodeStream returns up to limit bytes of the decoded contents of a stream, skipping
+// images, embedded fonts and filters other than FlateDecode
+func decodeStream(dict, raw []byte, limit int64) ([]byte, bool) {
+	if bytes.Contains(dict, []byte("/Image")) || bytes.Contains(dict, []byte("/Length1")) ||
+		bytes.Contains(dict, []byte("/XRef")) {
+		return nil, false
+	}
+	if !bytes.Contains(dict, []byte("/Filter")) {
+		if int64(len(raw)) > limit {
+			raw = raw[:limit]
+		}
+		return raw, true
+	}
+	for _, filter := range []string{"/DCTDecode", "/JPXDecode", "/CCITTFaxDecode", "/JBIG2Decode", "/ASCII85Decode", "/ASCIIHexDecode", "/LZWDecode", "/RunLengthDecode"} {
+		if bytes.Contains(dict, []byte(filter)) {
+			return nil, false
+		}
+	}
+	if !bytes.Contains(dict, []byte("/FlateDecode")) {
+		return nil, false
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, false
+	}
+	defer zr.Close()
+
+	// Keep whatever inflated cleanly; streams with a bad length are common
+	content, _ := io.ReadAll(io.LimitReader(zr, limit))
+	return content, len(content) > 0
+}
+
+// showText writes the operands of the text showing operators (Tj, TJ, ' and ") to
+// out, starting a new line whenever the text position moves
+func showText(content []byte, out *strings.Builder) {
+	var pending []string
+	newline := func() {
+		s := out.String()
+		if len(s) > 0 && s[len(s)-1] != '\n' {
+			out.WriteByte('\n')
+		}
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, n := literalString(content[i:])
+			pending = append(pending, s)
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i += 2
+		case c == '<':
+			s, n := hexString(content[i:])
+			pending = append(pending, s)
+			i += n
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case isSpace(c) || isDelimiter(c):
+			i++
+		default:
+			j := i
+			for j < len(content) && !isSpace(content[j]) && !isDelimiter(content[j]) {
+				j++
+			}
+			token := string(content[i:j])
+			i = j
+
+			switch token {
+			case "Tj", "TJ":
+				out.WriteString(strings.Join(pending, ""))
+				out.WriteByte(' ')
+				pending = nil
+			case "'", "\"":
+				newline()
+				out.WriteString(strings.Join(pending, ""))
+				out.WriteByte(' ')
+				pending = nil
+			case "Td", "TD", "T*", "Tm", "BT", "ET":
+				newline()
+				pending = nil
+			default:
+				// Large negative kerning inside a TJ array is a word gap
+				if n, err := strconv.ParseFloat(token, 64); err == nil {
+					if len(pending) > 0 && n < -200 {
+						pending = append(pending, " ")
+					}
+					continue
+				}
+				pending = nil
+			}
+		}
+	}
+	newline()
+}
+
+// literalString decodes a (...) string starting at b[0], returning the text and
+// the number of bytes consumed
+func literalString(b []byte) (string, int) {
+	var s []byte
+	depth := 0
+	i := 0
+	for i < len(b) {
+		c := b[i]
+		switch c {
+		case '(':
+			depth++
+			if depth > 1 {
+				s = append(s, c)
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return latin1(s), i + 1
+			}
+			s = append(s, c)
+		case '\\':
+			i++
+			if i >= len(b) {
+				break
+			}
+			switch e := b[i]; e {
+			case 'n':
+				s = append(s, '\n')
+			case 'r':
+				s = append(s, '\r')
+			case 't':
+				s = append(s, '\t')
+			case 'b', 'f':
+			case '\r':
+				if i+1 < len(b) && b[i+1] == '\n' {
+					i++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					j := i
+					for j < len(b) && j < i+3 && b[j] >= '0' && b[j] <= '7' {
+						v = v*8 + int(b[j]-'0')
+						j++
+					}
+					s = append(s, byte(v))
+					i = j - 1
+				} else {
+					s = append(s, e)
+				}
+			}
+		default:
+			s = append(s, c)
+		}
+		i++
+	}
+	return latin1(s), len(b)
+}
+
+// hexString decodes a <...> string starting at b[0]. Strings that do not decode to
+// readable text (e.g. glyph IDs of embedded fonts) are dropped.
+func hexString(b []byte) (string, int) {
+	end := bytes.IndexByte(b, '>')
+	if end < 0 {
+		return "", len(b)
+	}
+
+	var digits []byte
+	for _, c := range b[1:end] {
+		if _, ok := hexValue(c); ok {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	s := make([]byte, len(digits)/2)
+	for i := range s {
+		hi, _ := hexValue(digits[2*i])
+		lo, _ := hexValue(digits[2*i+1])
+		s[i] = hi<<4 | lo
+	}
+
+	// Two-byte strings with a zero high byte are usually UTF-16BE
+	if len(s) > 0 && len(s)%2 == 0 {
+		utf16 := true
+		for i := 0; i < len(s); i += 2 {
+			if s[i] != 0 {
+				utf16 = false
+				break
+			}
+		}
+		if utf16 {
+			narrow := make([]byte, 0, len(s)/2)
+			for i := 1; i < len(s); i += 2 {
+				narrow = append(narrow, s[i])
+			}
+			s = narrow
+		}
+	}
+
+	for _, c := range s {
+		if c < 0x20 && !isSpace(c) {
+			return "", end + 1
+		}
+	}
+	return latin1(s), end + 1
+}
+
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+func latin1(b []byte) string {
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+	}
+	return string(r)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
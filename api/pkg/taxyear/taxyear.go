@@ -0,0 +1,170 @@
+// Package taxyear works out tax year boundaries and labels. The UK tax year runs from
+// 6 April to 5 April and is written 2024/25; other jurisdictions start their year on
+// a different day, and those using the calendar year write it as 2024.
+package taxyear
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Jurisdiction codes accepted as a user's tax jurisdiction
+const (
+	CodeUK = "UK"
+	CodeIE = "IE"
+	CodeUS = "US"
+	CodeCA = "CA"
+	CodeAU = "AU"
+	CodeNZ = "NZ"
+)
+
+// Jurisdiction is a tax authority's year start
+type Jurisdiction struct {
+	Code       string
+	StartMonth time.Month
+	StartDay   int
+}
+
+var (
+	UK = Jurisdiction{Code: CodeUK, StartMonth: time.April, StartDay: 6}
+	IE = Jurisdiction{Code: CodeIE, StartMonth: time.January, StartDay: 1}
+	US = Jurisdiction{Code: CodeUS, StartMonth: time.January, StartDay: 1}
+	CA = Jurisdiction{Code: CodeCA, StartMonth: time.January, StartDay: 1}
+	AU = Jurisdiction{Code: CodeAU, StartMonth: time.July, StartDay: 1}
+	NZ = Jurisdiction{Code: CodeNZ, StartMonth: time.April, StartDay: 1}
+)
+
+var jurisdictions = map[string]Jurisdiction{
+	CodeUK: UK,
+	CodeIE: IE,
+	CodeUS: US,
+	CodeCA: CA,
+	CodeAU: AU,
+	CodeNZ: NZ,
+}
+
+// For returns the jurisdiction with the given code (case-insensitive). Unknown codes
+// fall back to the UK.
+func For(code string) Jurisdiction {
+	if j, ok := jurisdictions[strings.ToUpper(code)]; ok {
+		return j
+	}
+	return UK
+}
+
+// IsValid reports whether code is a supported jurisdiction
+func IsValid(code string) bool {
+	_, ok := jurisdictions[strings.ToUpper(code)]
+	return ok
+}
+
+// Codes returns the supported jurisdiction codes
+func Codes() []string {
+	return []string{CodeUK, CodeIE, CodeUS, CodeCA, CodeAU, CodeNZ}
+}
+
+// calendar reports whether the year matches the calendar year
+func (j Jurisdiction) calendar() bool {
+	return j.StartMonth == time.January && j.StartDay == 1
+}
+
+// Year is the tax year that starts in StartYear
+type Year struct {
+	Jurisdiction Jurisdiction
+	StartYear    int
+}
+
+// From returns the first day of the tax year
+func (y Year) From() time.Time {
+	return time.Date(y.StartYear, y.Jurisdiction.StartMonth, y.Jurisdiction.StartDay, 0, 0, 0, 0, time.UTC)
+}
+
+// To returns the last day of the tax year
+func (y Year) To() time.Time {
+	return y.From().AddDate(1, 0, -1)
+}
+
+// Contains reports whether t falls within the tax year
+func (y Year) Contains(t time.Time) bool {
+	return y.Jurisdiction.Of(t) == y
+}
+
+func (y Year) Next() Year {
+	return Year{Jurisdiction: y.Jurisdiction, StartYear: y.StartYear + 1}
+}
+
+func (y Year) Prev() Year {
+	return Year{Jurisdiction: y.Jurisdiction, StartYear: y.StartYear - 1}
+}
+
+// String returns the tax year's label, e.g. 2024/25, or 2024 for calendar years
+func (y Year) String() string {
+	if y.Jurisdiction.calendar() {
+		return strconv.Itoa(y.StartYear)
+	}
+	return Label(y.StartYear)
+}
+
+// Of returns the tax year containing t, judged by t's calendar date
+func (j Jurisdiction) Of(t time.Time) Year {
+	start := t.Year()
+	if t.Month() < j.StartMonth || (t.Month() == j.StartMonth && t.Day() < j.StartDay) {
+		start--
+	}
+	return Year{Jurisdiction: j, StartYear: start}
+}
+
+// Current returns the tax year containing today
+func (j Jurisdiction) Current() Year {
+	return j.Of(time.Now())
+}
+
+var (
+	splitPattern    = regexp.MustCompile(`^(\d{4})/(\d{2})$`)
+	calendarPattern = regexp.MustCompile(`^(\d{4})$`)
+)
+
+// Parse reads a tax year label in the jurisdiction's format. An empty value means the
+// current tax year.
+func (j Jurisdiction) Parse(value string) (Year, bool) {
+	if value == "" {
+		return j.Current(), true
+	}
+	if j.calendar() {
+		match := calendarPattern.FindStringSubmatch(value)
+		if match == nil {
+			return Year{}, false
+		}
+		start, _ := strconv.Atoi(match[1])
+		return Year{Jurisdiction: j, StartYear: start}, true
+	}
+	match := splitPattern.FindStringSubmatch(value)
+	if match == nil {
+		return Year{}, false
+	}
+	start, _ := strconv.Atoi(match[1])
+	end, _ := strconv.Atoi(match[2])
+	if (start+1)%100 != end {
+		return Year{}, false
+	}
+	return Year{Jurisdiction: j, StartYear: start}, true
+}
+
+// Example returns a sample label for error messages, e.g. 2024/25
+func (j Jurisdiction) Example() string {
+	return Year{Jurisdiction: j, StartYear: 2024}.String()
+}
+
+// Label formats a split tax year starting in startYear, e.g. 2024/25
+func Label(startYear int) string {
+	return fmt.Sprintf("%d/%02d", startYear, (startYear+1)%100)
+}
+
+// UKOf returns the UK tax year label containing t, e.g. 2024/25. ISA, LISA, JISA and
+// SIPP allowances always follow the UK tax year whatever the user's jurisdiction.
+func UKOf(t time.Time) string {
+	return UK.Of(t).String()
+}
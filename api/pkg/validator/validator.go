@@ -2,6 +2,8 @@ package validator
 
 import (
 	"regexp"
+	"sort"
+	"strconv"
 	"unicode"
 
 	"github.com/go-playground/validator/v10"
@@ -77,6 +79,16 @@ func IsValidCurrency(currency string) bool {
 	return validCurrencies[currency]
 }
 
+// Currencies returns the supported currency codes, sorted
+func Currencies() []string {
+	currencies := make([]string, 0, len(validCurrencies))
+	for c := range validCurrencies {
+		currencies = append(currencies, c)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
 // Portfolio type validation
 var validPortfolioTypes = map[string]bool{
 	"GIA": true, "ISA": true, "SIPP": true, "LISA": true,
@@ -124,7 +136,8 @@ func IsValidAssetType(assetType string) bool {
 	return validAssetTypes[assetType]
 }
 
-// Transaction type validation
+// Transaction type validation. TAX_RELIEF is left out: top-ups are only recorded with
+// the SIPP deposit they are claimed on.
 var validTransactionTypes = map[string]bool{
 	"BUY": true, "SELL": true, "DIVIDEND": true, "INTEREST": true,
 	"FEE": true, "TRANSFER_IN": true, "TRANSFER_OUT": true,
@@ -134,3 +147,89 @@ var validTransactionTypes = map[string]bool{
 func IsValidTransactionType(txType string) bool {
 	return validTransactionTypes[txType]
 }
+
+// Asset note sentiment validation
+var validNoteSentiments = map[string]bool{
+	"BULLISH": true, "NEUTRAL": true, "BEARISH": true,
+}
+
+func IsValidNoteSentiment(sentiment string) bool {
+	return validNoteSentiments[sentiment]
+}
+
+// Cost basis method validation
+var validCostBasisMethods = map[string]bool{
+	"FIFO": true, "LIFO": true, "AVERAGE": true,
+}
+
+func IsValidCostBasisMethod(method string) bool {
+	return validCostBasisMethods[method]
+}
+
+// Country code validation (ISO 3166-1 alpha-2, e.g. US)
+var countryCodeRegex = regexp.MustCompile(`^[A-Z]{2}$`)
+
+func IsValidCountryCode(code string) bool {
+	return countryCodeRegex.MatchString(code)
+}
+
+// ISIN validation (ISO 6166, e.g. GB00B3X7QG63), including the check digit
+var isinRegex = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{9}[0-9]$`)
+
+func IsValidISIN(isin string) bool {
+	return isinRegex.MatchString(isin) && luhnSum(isinDigits(isin))%10 == 0
+}
+
+// SEDOL validation (London Stock Exchange, e.g. B3X7QG6), including the check digit
+var sedolRegex = regexp.MustCompile(`^[0-9BCDFGHJKLMNPQRSTVWXYZ]{6}[0-9]$`)
+
+func IsValidSEDOL(sedol string) bool {
+	if !sedolRegex.MatchString(sedol) {
+		return false
+	}
+	weights := []int{1, 3, 1, 7, 3, 9, 1}
+	sum := 0
+	for i, c := range sedol {
+		sum += alphanumValue(c) * weights[i]
+	}
+	return sum%10 == 0
+}
+
+// SEDOLToISIN returns the UK ISIN for a valid SEDOL: GB00, the SEDOL and a check digit
+func SEDOLToISIN(sedol string) string {
+	body := "GB00" + sedol
+	check := (10 - luhnSum(isinDigits(body+"0"))%10) % 10
+	return body + string(rune('0'+check))
+}
+
+// isinDigits expands letters to two digits (A=10 ... Z=35) as the ISIN check digit requires
+func isinDigits(s string) string {
+	var digits []byte
+	for _, c := range s {
+		digits = append(digits, []byte(strconv.Itoa(alphanumValue(c)))...)
+	}
+	return string(digits)
+}
+
+func alphanumValue(c rune) int {
+	if c >= 'A' && c <= 'Z' {
+		return int(c-'A') + 10
+	}
+	return int(c - '0')
+}
+
+// luhnSum doubles every second digit from the right and sums the digits
+func luhnSum(digits string) int {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum
+}